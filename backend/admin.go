@@ -0,0 +1,88 @@
+package main
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+
+	"code-mafia-backend/config"
+)
+
+// requireAdmin guards operator endpoints with the ADMIN_TOKEN bearer token.
+// When no token is configured the admin API is disabled entirely.
+func requireAdmin(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token := config.AppConfig.AdminToken
+		if token == "" {
+			http.NotFound(w, r)
+			return
+		}
+
+		provided := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(provided), []byte(token)) != 1 {
+			writeJSON(w, http.StatusUnauthorized, map[string]interface{}{
+				"error": "unauthorized",
+			})
+			return
+		}
+
+		next(w, r)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, payload interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(payload)
+}
+
+func (h *Hub) handleAdminReload(w http.ResponseWriter, r *http.Request) {
+	changed, err := h.reloadConfig()
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"reloaded": true,
+		"changed":  changed,
+	})
+}
+
+// reloadConfig swaps in fresh tunables and pushes the ones rooms cache
+// into every live room, without touching game state.
+func (h *Hub) reloadConfig() ([]string, error) {
+	changed, err := config.Reload()
+	if err != nil {
+		log.Printf("❌ Config reload rejected: %v", err)
+		return nil, err
+	}
+
+	if dir := config.AppConfig.TasksDir; dir != "" {
+		reloadTaskLibrary(dir)
+	}
+
+	h.mu.RLock()
+	rooms := make([]*Room, 0, len(h.rooms))
+	for _, room := range h.rooms {
+		rooms = append(rooms, room)
+	}
+	h.mu.RUnlock()
+
+	for _, room := range rooms {
+		room := room
+		room.post(func() {
+			room.sabotageCooldownSec = room.sabotageCooldown()
+			if room.gameState.Phase == PhaseLobby {
+				room.broadcastSettings()
+			}
+		})
+	}
+
+	log.Printf("🔄 Applied reloaded config to %d rooms", len(rooms))
+	return changed, nil
+}
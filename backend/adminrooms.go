@@ -0,0 +1,350 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// reasonAdminEnded ends a game an operator called off. It names no winner,
+// so nobody's rating or season record moves.
+const reasonAdminEnded = "ADMIN_ENDED"
+
+// maxAnnouncementLength caps operator announcements at a chat message's
+// worth of text.
+const maxAnnouncementLength = 500
+
+func writeRoomNotFound(w http.ResponseWriter) {
+	writeJSON(w, http.StatusNotFound, map[string]interface{}{
+		"error": "room not found",
+	})
+}
+
+// adminRoomSummary is one room in the admin room list.
+type adminRoomSummary struct {
+	ID            string    `json:"id"`
+	Phase         GamePhase `json:"phase"`
+	Stage         int       `json:"stage"`
+	Players       int       `json:"players"`
+	Connected     int       `json:"connected"`
+	Spectators    int       `json:"spectators"`
+	Ranked        bool      `json:"ranked"`
+	TimerDeadline time.Time `json:"timerDeadline"`
+}
+
+// handleAdminListRooms lists the rooms live on this instance, busiest first.
+func (h *Hub) handleAdminListRooms(w http.ResponseWriter, r *http.Request) {
+	rooms := make([]adminRoomSummary, 0)
+	for _, room := range h.allRooms() {
+		summary := adminRoomSummary{ID: room.ID}
+		ok := room.do(func() {
+			summary.Phase = room.gameState.Phase
+			summary.Stage = room.gameState.CurrentStage
+			summary.Players = len(room.players)
+			summary.Spectators = len(room.spectators)
+			summary.Ranked = room.ranked
+			summary.TimerDeadline = room.gameState.TimerDeadline
+			for _, player := range room.players {
+				if !player.Disconnected {
+					summary.Connected++
+				}
+			}
+		})
+		if ok {
+			rooms = append(rooms, summary)
+		}
+	}
+
+	sort.Slice(rooms, func(i, j int) bool {
+		if rooms[i].Players != rooms[j].Players {
+			return rooms[i].Players > rooms[j].Players
+		}
+		return rooms[i].ID < rooms[j].ID
+	})
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"rooms": rooms,
+		"count": len(rooms),
+	})
+}
+
+// handleAdminGetRoom dumps everything a room knows, roles and votes
+// included, for working out why a game went wrong.
+func (h *Hub) handleAdminGetRoom(w http.ResponseWriter, r *http.Request) {
+	room := h.getRoom(mux.Vars(r)["roomId"])
+	if room == nil {
+		writeRoomNotFound(w)
+		return
+	}
+
+	// Marshalled on the room goroutine, since the maps are live.
+	var dump json.RawMessage
+	room.do(func() {
+		taskIDs := make([]string, 0, len(room.tasks))
+		for _, task := range room.tasks {
+			taskIDs = append(taskIDs, task.ID)
+		}
+
+		dump, _ = json.Marshal(map[string]interface{}{
+			"id":         room.ID,
+			"gameState":  room.gameState,
+			"players":    room.players,
+			"tasks":      taskIDs,
+			"ranked":     room.ranked,
+			"clients":    len(room.clients),
+			"spectators": len(room.spectators),
+			"observers":  len(room.observers),
+			"test": map[string]interface{}{
+				"running":  room.testRunning,
+				"runnerID": room.testRunner,
+				"runner":   room.testRunnerName,
+			},
+			"voting": map[string]interface{}{
+				"active":   room.votingActive,
+				"deadline": room.votingDeadline,
+				"votes":    room.votes,
+				"meetings": len(room.meetings),
+			},
+			"sabotage": map[string]interface{}{
+				"active":  room.sabotageActive,
+				"type":    room.sabotageType,
+				"endTime": room.sabotageEndTime,
+				"last":    room.lastSabotageTime,
+			},
+			"mutedUntil": room.mutedUntil,
+		})
+	})
+	if dump == nil {
+		writeRoomNotFound(w)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, dump)
+}
+
+// handleAdminEndRoom ends a room's game with no winner.
+func (h *Hub) handleAdminEndRoom(w http.ResponseWriter, r *http.Request) {
+	room := h.getRoom(mux.Vars(r)["roomId"])
+	if room == nil {
+		writeRoomNotFound(w)
+		return
+	}
+
+	var phase GamePhase
+	ok := room.do(func() {
+		phase = room.gameState.Phase
+		if phase == PhaseLobby || phase == PhaseEnd {
+			return
+		}
+		room.audit("ADMIN_END", "", "", map[string]interface{}{"phase": string(phase)})
+		room.endGame(reasonAdminEnded)
+	})
+	if !ok {
+		writeRoomNotFound(w)
+		return
+	}
+	if phase == PhaseLobby || phase == PhaseEnd {
+		writeJSON(w, http.StatusConflict, map[string]interface{}{
+			"error": "no game in progress",
+			"phase": phase,
+		})
+		return
+	}
+
+	log.Printf("🛑 Admin ended the game in room %s (was %s)", room.ID, phase)
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"ended":  true,
+		"reason": reasonAdminEnded,
+	})
+}
+
+// handleAdminAdvanceRoom moves a stuck room on as if its current phase had
+// finished: the reveal ends, the stage counts as fixed, or the votes are
+// counted.
+func (h *Hub) handleAdminAdvanceRoom(w http.ResponseWriter, r *http.Request) {
+	room := h.getRoom(mux.Vars(r)["roomId"])
+	if room == nil {
+		writeRoomNotFound(w)
+		return
+	}
+
+	var from GamePhase
+	var reason string
+	ok := room.do(func() {
+		from = room.gameState.Phase
+		reason = room.forceAdvance()
+	})
+	if !ok {
+		writeRoomNotFound(w)
+		return
+	}
+	if reason != "" {
+		writeJSON(w, http.StatusConflict, map[string]interface{}{
+			"error": reason,
+			"phase": from,
+		})
+		return
+	}
+
+	log.Printf("⏩ Admin advanced room %s past %s", room.ID, from)
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"advanced": true,
+		"from":     from,
+	})
+}
+
+// forceAdvance ends the current phase early. It returns why it couldn't,
+// or "" once it has. Runs on the room goroutine.
+func (r *Room) forceAdvance() string {
+	phase := r.gameState.Phase
+	switch phase {
+	case PhaseRoleReveal:
+		r.audit("ADMIN_ADVANCE", "", "", map[string]interface{}{"phase": string(phase)})
+		r.beginTasks()
+	case PhaseTask1, PhaseTask2, PhaseTask3:
+		stage := r.gameState.CurrentStage
+		if r.gameState.TasksComplete[stage] {
+			return "stage already complete, the next one is starting"
+		}
+		r.audit("ADMIN_ADVANCE", "", "", map[string]interface{}{"phase": string(phase)})
+		r.cancelTests("An operator skipped this stage")
+		r.advanceStage(stage)
+	case PhaseDiscussion:
+		if !r.votingActive {
+			return "votes are already being counted"
+		}
+		r.audit("ADMIN_ADVANCE", "", "", map[string]interface{}{"phase": string(phase)})
+		r.tallyVotes()
+	default:
+		return "no game in progress"
+	}
+	return ""
+}
+
+// cancelTests drops the test run in progress, if any, and tells the room
+// why. Runs on the room goroutine.
+func (r *Room) cancelTests(reason string) {
+	if !r.testRunning {
+		return
+	}
+
+	r.testRunning = false
+	r.testRunner = ""
+	r.testRunnerName = ""
+	r.codeSnapshot = ""
+	r.saveToRedis()
+
+	cancelMsg := Message{
+		Type: "TEST_CANCELLED",
+		Data: map[string]interface{}{
+			"reason": reason,
+		},
+	}
+	data, _ := json.Marshal(cancelMsg)
+	r.emit(data)
+}
+
+// handleAdminKickPlayer takes a player out of a room for good: mid-game
+// they don't get a seat held for them, they are out as if their reconnect
+// window had run out.
+func (h *Hub) handleAdminKickPlayer(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	room := h.getRoom(vars["roomId"])
+	if room == nil {
+		writeRoomNotFound(w)
+		return
+	}
+
+	var req struct {
+		Note string `json:"note"`
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]interface{}{
+				"error": "invalid JSON body",
+			})
+			return
+		}
+	}
+
+	playerID := vars["playerId"]
+	var username string
+	room.do(func() {
+		player := room.players[playerID]
+		if player == nil {
+			return
+		}
+		username = player.Username
+
+		message := "You were removed from the game by an operator"
+		if req.Note != "" {
+			message += ": " + req.Note
+		}
+		room.audit("ADMIN_KICK", player.ID, player.Username, map[string]interface{}{"note": req.Note})
+		room.kick(player, "KICKED", message)
+	})
+	if username == "" {
+		writeJSON(w, http.StatusNotFound, map[string]interface{}{
+			"error": "player not found",
+		})
+		return
+	}
+
+	log.Printf("👢 Admin kicked %s from room %s", username, room.ID)
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"kicked":   true,
+		"playerId": playerID,
+		"username": username,
+	})
+}
+
+// handleAdminAnnounce sends a message from the operators to every room on
+// this instance.
+func (h *Hub) handleAdminAnnounce(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Message string `json:"message"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{
+			"error": "invalid JSON body",
+		})
+		return
+	}
+
+	text := strings.TrimSpace(req.Message)
+	if text == "" || len(text) > maxAnnouncementLength {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{
+			"error": fmt.Sprintf("message must be 1 to %d characters", maxAnnouncementLength),
+		})
+		return
+	}
+
+	announcement := Message{
+		Type: "ANNOUNCEMENT",
+		Data: map[string]interface{}{
+			"message": text,
+		},
+	}
+	data, _ := json.Marshal(announcement)
+
+	rooms := h.allRooms()
+	for _, room := range rooms {
+		room := room
+		room.post(func() {
+			room.emit(data)
+			room.audit("ADMIN_ANNOUNCEMENT", "", "", map[string]interface{}{"message": text})
+		})
+	}
+
+	log.Printf("📢 Admin announcement sent to %d rooms: %s", len(rooms), text)
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"sent":  true,
+		"rooms": len(rooms),
+	})
+}
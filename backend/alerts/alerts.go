@@ -0,0 +1,158 @@
+package alerts
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	KindTranslationDown = "translation_subscription_down"
+	KindRedisReconnects = "redis_reconnect_storm"
+	KindRoomStuck       = "room_stuck_in_phase"
+	KindPanicRecovered  = "panic_recovered"
+)
+
+type Alert struct {
+	Kind    string    `json:"kind"`
+	Message string    `json:"message"`
+	Host    string    `json:"host"`
+	Time    time.Time `json:"time"`
+}
+
+type Notifier interface {
+	Notify(alert Alert) error
+}
+
+// WebhookNotifier posts the raw alert as JSON to an arbitrary endpoint.
+type WebhookNotifier struct {
+	URL    string
+	Client *http.Client
+}
+
+func (n *WebhookNotifier) Notify(alert Alert) error {
+	body, err := json.Marshal(alert)
+	if err != nil {
+		return fmt.Errorf("failed to marshal alert: %w", err)
+	}
+	return post(n.Client, n.URL, body)
+}
+
+// SlackNotifier posts a formatted message to a Slack incoming webhook.
+type SlackNotifier struct {
+	URL    string
+	Client *http.Client
+}
+
+func (n *SlackNotifier) Notify(alert Alert) error {
+	text := fmt.Sprintf("🚨 *%s* on `%s`\n%s", alert.Kind, alert.Host, alert.Message)
+	body, err := json.Marshal(map[string]string{"text": text})
+	if err != nil {
+		return fmt.Errorf("failed to marshal slack alert: %w", err)
+	}
+	return post(n.Client, n.URL, body)
+}
+
+func post(client *http.Client, url string, body []byte) error {
+	resp, err := client.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("alert delivery failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("alert delivery failed: status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+var (
+	mu        sync.Mutex
+	notifiers []Notifier
+	lastSent  = make(map[string]time.Time)
+	cooldown  = 5 * time.Minute
+	hostname  string
+)
+
+func Init(webhookURL, slackURL string, cooldownPeriod time.Duration) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	client := &http.Client{Timeout: 5 * time.Second}
+	notifiers = nil
+
+	if webhookURL != "" {
+		notifiers = append(notifiers, &WebhookNotifier{URL: webhookURL, Client: client})
+	}
+	if slackURL != "" {
+		notifiers = append(notifiers, &SlackNotifier{URL: slackURL, Client: client})
+	}
+	if cooldownPeriod > 0 {
+		cooldown = cooldownPeriod
+	}
+
+	hostname, _ = os.Hostname()
+
+	if len(notifiers) == 0 {
+		log.Println("Alerting disabled - no alert webhooks configured")
+		return
+	}
+	log.Printf("Alerting enabled with %d notifier(s)", len(notifiers))
+}
+
+// Register adds a custom notifier alongside the configured webhooks.
+func Register(n Notifier) {
+	mu.Lock()
+	defer mu.Unlock()
+	notifiers = append(notifiers, n)
+}
+
+// Fire sends an alert to every notifier. Repeated alerts with the same kind
+// and key are suppressed until the cooldown passes so a flapping condition
+// doesn't flood the channel.
+func Fire(kind, key, format string, args ...interface{}) {
+	message := fmt.Sprintf(format, args...)
+	log.Printf("🚨 ALERT [%s] %s", kind, message)
+
+	mu.Lock()
+	dedupKey := kind + ":" + key
+	if last, ok := lastSent[dedupKey]; ok && time.Since(last) < cooldown {
+		mu.Unlock()
+		return
+	}
+	now := time.Now()
+	for k, sent := range lastSent {
+		// Past the cooldown an entry suppresses nothing; keys like room
+		// IDs would otherwise pile up forever.
+		if now.Sub(sent) >= cooldown {
+			delete(lastSent, k)
+		}
+	}
+	lastSent[dedupKey] = now
+	targets := append([]Notifier(nil), notifiers...)
+	mu.Unlock()
+
+	if len(targets) == 0 {
+		return
+	}
+
+	alert := Alert{
+		Kind:    kind,
+		Message: message,
+		Host:    hostname,
+		Time:    time.Now(),
+	}
+
+	go func() {
+		for _, n := range targets {
+			if err := n.Notify(alert); err != nil {
+				log.Printf("Failed to deliver alert %s: %v", kind, err)
+			}
+		}
+	}()
+}
@@ -0,0 +1,48 @@
+package alerts
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+type recordingNotifier struct {
+	sent chan Alert
+}
+
+func (n *recordingNotifier) Notify(alert Alert) error {
+	n.sent <- alert
+	return nil
+}
+
+func TestFireSuppressesRepeatsAndForgetsOldOnes(t *testing.T) {
+	n := &recordingNotifier{sent: make(chan Alert, 10)}
+	mu.Lock()
+	notifiers = []Notifier{n}
+	cooldown = time.Hour
+	lastSent = make(map[string]time.Time)
+	for i := 0; i < 100; i++ {
+		lastSent[fmt.Sprintf("stuck:room-%d", i)] = time.Now().Add(-2 * time.Hour)
+	}
+	mu.Unlock()
+
+	Fire("stuck", "room-a", "first")
+	Fire("stuck", "room-a", "repeat")
+
+	select {
+	case <-n.sent:
+	case <-time.After(time.Second):
+		t.Fatalf("first alert wasn't delivered")
+	}
+	select {
+	case alert := <-n.sent:
+		t.Fatalf("repeat within the cooldown was delivered: %s", alert.Message)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(lastSent) != 1 {
+		t.Fatalf("lastSent holds %d entries, want only the live one", len(lastSent))
+	}
+}
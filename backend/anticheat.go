@@ -0,0 +1,202 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+	"time"
+
+	"code-mafia-backend/config"
+)
+
+const (
+	cheatRoleLeak       = "ROLE_LEAK"
+	cheatMassDeletion   = "MASS_DELETION"
+	cheatLockstepVoting = "LOCKSTEP_VOTING"
+
+	// A stage's code must have been at least this long before losing most
+	// of it counts as vandalism rather than starting over on a short task.
+	massDeletionMinChars = 200
+	massDeletionKeepPct  = 20
+
+	lockstepWindow = 2 * time.Second
+)
+
+// cheatHostWarningsFlag makes detections also warn the room's host. They
+// are always written to the audit log.
+const cheatHostWarningsFlag = "cheat_warnings"
+
+var (
+	roleWordsRe = regexp.MustCompile(`\b(impost[eo]r|imp|traitor|saboteur|the killer)\b`)
+
+	// Things people don't type into a game chat but do copy out of Discord,
+	// a stream or another tab: links, timestamps, quoted lines and
+	// multi-line transcripts.
+	pastedMarkersRe = regexp.MustCompile(`(?im)(https?://|discord\.gg/|twitch\.tv/|^\s*>|\[\d{1,2}:\d{2}(:\d{2})?( ?[ap]m)?\]|\btoday at \d{1,2}:\d{2}|\bsaid:)`)
+)
+
+// cheatWatch is the evidence the detectors keep about a room. Like the
+// rest of the room it is only touched from the room goroutine.
+type cheatWatch struct {
+	// longestCode is the most code seen submitted for each stage.
+	longestCode map[int]int
+
+	votes map[string]watchedVote
+}
+
+func (w *cheatWatch) reset() {
+	w.longestCode = nil
+	w.votes = nil
+}
+
+type watchedVote struct {
+	targetID    string
+	fingerprint string
+	at          time.Time
+}
+
+// flagCheat records a finding and, if enabled, tells the host. The host is
+// only told who and what, never the evidence, since a role leak's evidence
+// is the leaked role.
+func (r *Room) flagCheat(detection, playerID, username string, details map[string]interface{}) {
+	if details == nil {
+		details = make(map[string]interface{})
+	}
+	details["detection"] = detection
+	r.audit("CHEAT_SUSPECTED", playerID, username, details)
+	incMetric("cheat_detections_total")
+	log.Printf("🚩 Room %s: %s suspected from %s", r.ID, detection, username)
+
+	if !config.Current().FeatureEnabled(cheatHostWarningsFlag) {
+		return
+	}
+
+	var host *Client
+	for _, p := range r.players {
+		if p.IsHost && p.ID != playerID {
+			host = r.clientFor(p.ID)
+			break
+		}
+	}
+
+	if host != nil {
+		host.sendMessage(Message{
+			Type: "CHEAT_WARNING",
+			Data: map[string]interface{}{
+				"detection": detection,
+				"playerId":  playerID,
+				"username":  username,
+				"message":   cheatWarningText(detection, username),
+			},
+		})
+	}
+}
+
+func cheatWarningText(detection, username string) string {
+	switch detection {
+	case cheatRoleLeak:
+		return username + " may be sharing role information from outside the game"
+	case cheatMassDeletion:
+		return username + " ran tests after most of the code was deleted"
+	case cheatLockstepVoting:
+		return username + " is voting in lockstep with another client on the same network"
+	}
+	return username + " was flagged for suspicious behavior"
+}
+
+// checkRoleLeak flags chat that names an impostor and looks pasted from
+// somewhere else. Accusations typed during a game are how it is played, so
+// naming alone is never enough.
+func (r *Room) checkRoleLeak(playerID, username, text string) {
+	phase := r.gameState.Phase
+	if phase == PhaseLobby || phase == PhaseEnd || r.gameState.isImpostor(playerID) {
+		return
+	}
+
+	lower := strings.ToLower(text)
+	if !roleWordsRe.MatchString(lower) || !r.namesImpostor(lower) {
+		return
+	}
+	if !pastedMarkersRe.MatchString(text) && strings.Count(text, "\n") < 2 {
+		return
+	}
+
+	r.flagCheat(cheatRoleLeak, playerID, username, map[string]interface{}{
+		"textLength": len(text),
+	})
+}
+
+func (r *Room) namesImpostor(lower string) bool {
+	for _, id := range r.gameState.ImposterIDs {
+		impostor := r.players[id]
+		if impostor == nil {
+			continue
+		}
+		name := regexp.QuoteMeta(strings.ToLower(impostor.Username))
+		if regexp.MustCompile(`(^|\W)` + name + `($|\W)`).MatchString(lower) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkMassDeletion flags a test run whose code is a fraction of the
+// longest code anyone submitted for that stage.
+func (r *Room) checkMassDeletion(playerID, username string, stage int, code string) {
+	if r.cheats.longestCode == nil {
+		r.cheats.longestCode = make(map[int]int)
+	}
+	longest := r.cheats.longestCode[stage]
+	if len(code) > longest {
+		r.cheats.longestCode[stage] = len(code)
+	}
+
+	if longest < massDeletionMinChars || len(code)*100 >= longest*massDeletionKeepPct {
+		return
+	}
+
+	r.flagCheat(cheatMassDeletion, playerID, username, map[string]interface{}{
+		"stage":         stage,
+		"codeLength":    len(code),
+		"longestLength": longest,
+	})
+}
+
+// checkLockstepVoting flags a vote cast for the same target, within moments,
+// as a vote from another player with the same IP and user agent: one person
+// playing several seats.
+func (r *Room) checkLockstepVoting(voterID, username, targetID string) {
+	client := r.clientFor(voterID)
+	if client == nil || client.IP == "" {
+		return
+	}
+
+	vote := watchedVote{
+		targetID:    targetID,
+		fingerprint: fmt.Sprintf("%s|%s", client.IP, client.UserAgent),
+		at:          time.Now(),
+	}
+
+	if r.cheats.votes == nil {
+		r.cheats.votes = make(map[string]watchedVote)
+	}
+	var partners []string
+	for otherID, other := range r.cheats.votes {
+		if otherID != voterID && other.fingerprint == vote.fingerprint &&
+			other.targetID == vote.targetID && vote.at.Sub(other.at) <= lockstepWindow {
+			partners = append(partners, otherID)
+		}
+	}
+	r.cheats.votes[voterID] = vote
+
+	if len(partners) == 0 {
+		return
+	}
+
+	r.flagCheat(cheatLockstepVoting, voterID, username, map[string]interface{}{
+		"targetId": targetID,
+		"with":     partners,
+		"ip":       client.IP,
+	})
+}
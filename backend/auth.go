@@ -0,0 +1,188 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"code-mafia-backend/auth"
+	"code-mafia-backend/config"
+	"code-mafia-backend/database"
+
+	"github.com/google/uuid"
+)
+
+// Guests get prefixed IDs once accounts are enabled, so a guest can't claim
+// an account's identity by passing its user ID in ?userId=.
+const guestIDPrefix = "anon-"
+
+// authenticateRequest validates the Supabase access token sent with a
+// handshake, either as ?token= (browsers can't set headers on WebSockets)
+// or as a Bearer header. It returns nil claims for anonymous requests.
+func authenticateRequest(r *http.Request) (*auth.Claims, error) {
+	token := r.URL.Query().Get("token")
+	if token == "" {
+		token = strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	}
+	if token == "" {
+		return nil, nil
+	}
+
+	if tokenVerifier == nil {
+		debugf("Ignoring access token - neither SUPABASE_JWT_SECRET nor SUPABASE_JWKS_URL is set")
+		return nil, nil
+	}
+
+	return tokenVerifier.Verify(token)
+}
+
+// tokenVerifier checks access tokens; it is nil while accounts are
+// disabled.
+var tokenVerifier *auth.Verifier
+
+func initAuth() {
+	cfg := config.AppConfig
+	if cfg.SupabaseJWTSecret == "" && cfg.SupabaseJWKSURL == "" {
+		tokenVerifier = nil
+		log.Printf("🔓 Accounts disabled - everyone plays as a guest")
+		return
+	}
+
+	tokenVerifier = &auth.Verifier{Secret: []byte(cfg.SupabaseJWTSecret)}
+	if cfg.SupabaseJWKSURL != "" {
+		tokenVerifier.Keys = auth.NewKeySet(cfg.SupabaseJWKSURL)
+	}
+	log.Printf("🔐 Accounts enabled (JWT secret: %v, JWKS: %v)", cfg.SupabaseJWTSecret != "", cfg.SupabaseJWKSURL != "")
+}
+
+func authEnabled() bool {
+	return tokenVerifier != nil
+}
+
+// guestTokensEnabled reports whether guests can be issued tokens to claim
+// their matches with later. The tokens are signed with the JWT secret.
+func guestTokensEnabled() bool {
+	return config.AppConfig.SupabaseJWTSecret != ""
+}
+
+// resolvePlayerID picks the identity for a connection: the account ID for
+// signed-in players, otherwise the guest ID the client is reconnecting with.
+func resolvePlayerID(claims *auth.Claims, requestedID string) string {
+	if claims != nil {
+		return claims.Subject
+	}
+	if !authEnabled() {
+		return requestedID
+	}
+	if strings.HasPrefix(requestedID, guestIDPrefix) {
+		return requestedID
+	}
+	if requestedID != "" {
+		log.Printf("Guest asked for non-guest id %s - issuing a new one", requestedID)
+	}
+	return ""
+}
+
+func newGuestID() string {
+	if authEnabled() {
+		return guestIDPrefix + uuid.New().String()
+	}
+	return uuid.New().String()
+}
+
+func guestToken(guestID string) string {
+	return auth.SignGuest(guestID, []byte(config.AppConfig.SupabaseJWTSecret))
+}
+
+// ownsGuestID reports whether the request carries, as ?guestToken=, the
+// token guestID was given in INIT.
+func ownsGuestID(r *http.Request, guestID string) bool {
+	if !guestTokensEnabled() {
+		return false
+	}
+	id, err := auth.VerifyGuest(r.URL.Query().Get("guestToken"), []byte(config.AppConfig.SupabaseJWTSecret))
+	return err == nil && id == guestID
+}
+
+// verifiedPlayerID returns the player a connection to roomID has shown it
+// is: the signed-in account, or the ?userId= it sent the session token or
+// guest token of. It returns "" for anyone else, since player IDs are
+// visible to everyone in the room.
+func verifiedPlayerID(r *http.Request, claims *auth.Claims, roomID string) string {
+	if claims != nil {
+		return claims.Subject
+	}
+	query := r.URL.Query()
+	userID := query.Get("userId")
+	if userID == "" {
+		return ""
+	}
+	if validSession(userID, roomID, query.Get("session")) || ownsGuestID(r, userID) {
+		return userID
+	}
+	return ""
+}
+
+// handleClaimGuest moves a guest's history onto the signed-in account. The
+// guest proves ownership with the guestToken it was given in INIT.
+func handleClaimGuest(w http.ResponseWriter, r *http.Request) {
+	if !authEnabled() {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]interface{}{
+			"error": "accounts are disabled on this server",
+		})
+		return
+	}
+	if !guestTokensEnabled() {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]interface{}{
+			"error": "guest matches can't be claimed on this server",
+		})
+		return
+	}
+
+	claims, err := authenticateRequest(r)
+	if err != nil || claims == nil {
+		writeJSON(w, http.StatusUnauthorized, map[string]interface{}{
+			"error": "a valid access token is required",
+		})
+		return
+	}
+
+	var req struct {
+		GuestToken string `json:"guestToken"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.GuestToken == "" {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{
+			"error": "guestToken is required",
+		})
+		return
+	}
+
+	guestID, err := auth.VerifyGuest(req.GuestToken, []byte(config.AppConfig.SupabaseJWTSecret))
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{
+			"error": "invalid guest token",
+		})
+		return
+	}
+
+	moved, err := database.ClaimGuest(guestID, claims.Subject)
+	if errors.Is(err, database.ErrGuestAlreadyClaimed) {
+		writeJSON(w, http.StatusConflict, map[string]interface{}{
+			"error": err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	log.Printf("🔗 Guest %s claimed by %s (%d matches)", guestID, claims.Subject, moved)
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"guestId":      guestID,
+		"accountId":    claims.Subject,
+		"matchesMoved": moved,
+	})
+}
@@ -0,0 +1,175 @@
+// Package auth validates Supabase access tokens. Older projects sign them
+// as HS256 JWTs with the project's JWT secret, so no network call is
+// needed; projects on asymmetric signing keys sign them with RS256 or
+// ES256 and publish the public keys as a JWKS.
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"strings"
+	"time"
+)
+
+var (
+	ErrMalformedToken   = errors.New("malformed token")
+	ErrUnsupportedAlg   = errors.New("unsupported signing algorithm")
+	ErrInvalidSignature = errors.New("invalid token signature")
+	ErrExpiredToken     = errors.New("token expired")
+	ErrInvalidAudience  = errors.New("token is not for authenticated users")
+	ErrMissingSubject   = errors.New("token has no subject")
+)
+
+// leeway absorbs clock drift between this server and Supabase.
+const leeway = 30 * time.Second
+
+type Claims struct {
+	Subject      string                 `json:"sub"`
+	Email        string                 `json:"email"`
+	Role         string                 `json:"role"`
+	Audience     audience               `json:"aud"`
+	ExpiresAt    int64                  `json:"exp"`
+	IssuedAt     int64                  `json:"iat"`
+	UserMetadata map[string]interface{} `json:"user_metadata"`
+}
+
+// audience accepts both the string and array forms of the aud claim.
+type audience []string
+
+func (a *audience) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*a = audience{single}
+		return nil
+	}
+	var many []string
+	if err := json.Unmarshal(data, &many); err != nil {
+		return err
+	}
+	*a = many
+	return nil
+}
+
+func (a audience) contains(value string) bool {
+	for _, v := range a {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// DisplayName returns the name the user picked at sign-up, if any.
+func (c *Claims) DisplayName() string {
+	for _, key := range []string{"username", "user_name", "full_name", "name"} {
+		if name, ok := c.UserMetadata[key].(string); ok && name != "" {
+			return name
+		}
+	}
+	return ""
+}
+
+// Verifier checks tokens against the project's JWT secret, its published
+// signing keys, or both, depending on which are set.
+type Verifier struct {
+	Secret []byte
+	Keys   *KeySet
+}
+
+// Verify checks the token's signature, expiry and audience and returns its
+// claims.
+func (v *Verifier) Verify(token string) (*Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, ErrMalformedToken
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, ErrMalformedToken
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, ErrMalformedToken
+	}
+	signed := []byte(parts[0] + "." + parts[1])
+
+	switch {
+	case header.Alg == "HS256" && len(v.Secret) > 0:
+		mac := hmac.New(sha256.New, v.Secret)
+		mac.Write(signed)
+		if !hmac.Equal(signature, mac.Sum(nil)) {
+			return nil, ErrInvalidSignature
+		}
+
+	case (header.Alg == "RS256" || header.Alg == "ES256") && v.Keys != nil:
+		key, err := v.Keys.key(header.Kid)
+		if err != nil {
+			return nil, err
+		}
+		if !verifySignature(header.Alg, key, signed, signature) {
+			return nil, ErrInvalidSignature
+		}
+
+	default:
+		return nil, ErrUnsupportedAlg
+	}
+
+	var claims Claims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, ErrMalformedToken
+	}
+
+	if claims.ExpiresAt == 0 || time.Now().Add(-leeway).Unix() > claims.ExpiresAt {
+		return nil, ErrExpiredToken
+	}
+	if !claims.Audience.contains("authenticated") {
+		return nil, ErrInvalidAudience
+	}
+	if claims.Subject == "" {
+		return nil, ErrMissingSubject
+	}
+
+	return &claims, nil
+}
+
+// verifySignature checks an RS256 or ES256 signature. The key has to be
+// of the type the algorithm names, so a token can't pick how it is checked.
+func verifySignature(alg string, key crypto.PublicKey, signed, signature []byte) bool {
+	digest := sha256.Sum256(signed)
+
+	switch alg {
+	case "RS256":
+		pub, ok := key.(*rsa.PublicKey)
+		return ok && rsa.VerifyPKCS1v15(pub, crypto.SHA256, digest[:], signature) == nil
+
+	case "ES256":
+		pub, ok := key.(*ecdsa.PublicKey)
+		if !ok || len(signature) != 64 {
+			return false
+		}
+		r := new(big.Int).SetBytes(signature[:32])
+		s := new(big.Int).SetBytes(signature[32:])
+		return ecdsa.Verify(pub, digest[:], r, s)
+	}
+	return false
+}
+
+func decodeSegment(segment string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
@@ -0,0 +1,47 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"strings"
+)
+
+var ErrInvalidGuestToken = errors.New("invalid guest token")
+
+// SignGuest issues the token a guest keeps locally to prove, later, that a
+// guest ID was theirs. Player IDs are visible to everyone in a room, so the
+// ID alone can't be used for that.
+func SignGuest(guestID string, secret []byte) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(guestID)) + "." +
+		base64.RawURLEncoding.EncodeToString(guestMAC(guestID, secret))
+}
+
+// VerifyGuest returns the guest ID a token was issued for.
+func VerifyGuest(token string, secret []byte) (string, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 2 {
+		return "", ErrInvalidGuestToken
+	}
+
+	id, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return "", ErrInvalidGuestToken
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return "", ErrInvalidGuestToken
+	}
+
+	if !hmac.Equal(signature, guestMAC(string(id), secret)) {
+		return "", ErrInvalidGuestToken
+	}
+	return string(id), nil
+}
+
+func guestMAC(guestID string, secret []byte) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte("guest:" + guestID))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"code-mafia-backend/database"
+)
+
+func TestVerifiedPlayerIDNeedsProof(t *testing.T) {
+	session := database.PlayerSession{Token: "secret", RoomID: "VERIFY"}
+	if err := database.SavePlayerSession("anon-editor", session, sessionTTL); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"?userId=anon-editor", ""},
+		{"?userId=anon-editor&session=wrong", ""},
+		{"?userId=anon-editor&session=secret", "anon-editor"},
+		{"?userId=anon-other&session=secret", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest("GET", "/yjs"+tt.query, nil)
+		if got := verifiedPlayerID(r, nil, "VERIFY"); got != tt.want {
+			t.Errorf("verifiedPlayerID(%q) = %q, want %q", tt.query, got, tt.want)
+		}
+	}
+}
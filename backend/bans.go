@@ -0,0 +1,123 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+
+	"code-mafia-backend/database"
+
+	"github.com/gorilla/mux"
+)
+
+// findBan checks the caller's IP and identity against the global ban list.
+// Redis errors fail open so an outage doesn't lock every player out.
+func findBan(ip, userID string) *database.Ban {
+	if ban, err := database.GetBan(database.BanKindIP, ip); err != nil {
+		log.Printf("Ban lookup failed for ip %s: %v", ip, err)
+	} else if ban != nil {
+		return ban
+	}
+
+	if ban, err := database.GetBan(database.BanKindUser, userID); err != nil {
+		log.Printf("Ban lookup failed for user %s: %v", userID, err)
+	} else if ban != nil {
+		return ban
+	}
+
+	return nil
+}
+
+func banPayload(ban *database.Ban) map[string]interface{} {
+	payload := map[string]interface{}{
+		"reason":    "BANNED",
+		"message":   "You have been banned from this server",
+		"banReason": ban.Reason,
+	}
+	if ban.ExpiresAt != nil {
+		payload["expiresAt"] = ban.ExpiresAt
+	}
+	return payload
+}
+
+// rejectBanned keeps banned IPs and accounts away from the REST API. An
+// invalid token is left for the handler to reject; only the IP is checked.
+func rejectBanned(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userID := ""
+		if claims, err := authenticateRequest(r); err == nil && claims != nil {
+			userID = claims.Subject
+		}
+		if ban := findBan(clientIP(r), userID); ban != nil {
+			writeJSON(w, http.StatusForbidden, banPayload(ban))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func handleAdminListBans(w http.ResponseWriter, r *http.Request) {
+	bans, err := database.ListBans()
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"bans": bans,
+	})
+}
+
+func handleAdminCreateBan(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Kind            string `json:"kind"`
+		Value           string `json:"value"`
+		Reason          string `json:"reason"`
+		DurationMinutes int    `json:"durationMinutes"`
+		CreatedBy       string `json:"createdBy"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Value == "" {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{
+			"error": "kind, value and reason are required",
+		})
+		return
+	}
+
+	ban := database.Ban{
+		Kind:      req.Kind,
+		Value:     req.Value,
+		Reason:    req.Reason,
+		CreatedBy: req.CreatedBy,
+		CreatedAt: time.Now(),
+	}
+	if req.DurationMinutes > 0 {
+		expiresAt := ban.CreatedAt.Add(time.Duration(req.DurationMinutes) * time.Minute)
+		ban.ExpiresAt = &expiresAt
+	}
+
+	if err := database.SaveBan(ban); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	log.Printf("🔨 Banned %s %s: %s (expires: %v)", ban.Kind, ban.Value, ban.Reason, ban.ExpiresAt)
+	writeJSON(w, http.StatusCreated, ban)
+}
+
+func handleAdminDeleteBan(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	if err := database.DeleteBan(vars["kind"], vars["value"]); err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	log.Printf("🔓 Unbanned %s %s", vars["kind"], vars["value"])
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"code-mafia-backend/auth"
+	"code-mafia-backend/database"
+)
+
+// signTestToken makes an HS256 access token for subject.
+func signTestToken(t *testing.T, secret []byte, subject string) string {
+	t.Helper()
+	claims, err := json.Marshal(map[string]interface{}{
+		"sub": subject,
+		"aud": "authenticated",
+		"exp": time.Now().Add(time.Hour).Unix(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	signed := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`)) +
+		"." + base64.RawURLEncoding.EncodeToString(claims)
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(signed))
+	return signed + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func TestRejectBannedChecksAccounts(t *testing.T) {
+	secret := []byte("test-secret")
+	previous := tokenVerifier
+	tokenVerifier = &auth.Verifier{Secret: secret}
+	defer func() { tokenVerifier = previous }()
+
+	ban := database.Ban{Kind: database.BanKindUser, Value: "banned-user", Reason: "test", CreatedAt: time.Now()}
+	if err := database.SaveBan(ban); err != nil {
+		t.Fatal(err)
+	}
+	defer database.DeleteBan(ban.Kind, ban.Value)
+
+	handler := rejectBanned(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		subject string
+		want    int
+	}{
+		{"banned-user", http.StatusForbidden},
+		{"other-user", http.StatusOK},
+		{"", http.StatusOK},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest("GET", "/api/profile", nil)
+		if tt.subject != "" {
+			r.Header.Set("Authorization", "Bearer "+signTestToken(t, secret, tt.subject))
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		if w.Code != tt.want {
+			t.Errorf("request as %q = %d, want %d", tt.subject, w.Code, tt.want)
+		}
+	}
+}
@@ -0,0 +1,311 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"code-mafia-backend/config"
+	"code-mafia-backend/database"
+
+	"github.com/google/uuid"
+)
+
+// Chat lines are rebuilt by the server before anyone sees them: the text
+// is cleaned and capped, and the line gets its ID and timestamp here. A
+// CHAT waits for its translations in the room, and what the translation
+// sidecar sends back only contributes the translations; everything else
+// comes from the server's copy.
+
+// chatTranslationWait is how long a line waits for its translations
+// before it goes out without them.
+const chatTranslationWait = 5 * time.Second
+
+// maxTranslationRunes caps each translation of a line, which may run
+// longer than the original.
+const maxTranslationRunes = 1000
+
+// chatLine is a chat message as the server keeps it.
+type chatLine struct {
+	ID        string
+	PlayerID  string
+	Username  string
+	Text      string
+	Timestamp int64
+
+	timer *time.Timer
+}
+
+// cleanChatText makes text fit to show: invalid UTF-8, control and
+// invisible formatting characters (text direction overrides, say) are
+// dropped, and each run of whitespace becomes one line break if it had
+// any, which checkRoleLeak looks for, or one space. It returns "" when
+// nothing is left.
+func cleanChatText(text string) string {
+	text = strings.ToValidUTF8(text, "")
+
+	var b strings.Builder
+	var gap rune
+	for _, r := range text {
+		switch {
+		case r == '\n':
+			gap = '\n'
+			continue
+		case unicode.IsSpace(r):
+			if gap == 0 {
+				gap = ' '
+			}
+			continue
+		case unicode.IsControl(r):
+			continue
+		// Zero width joiners hold emoji sequences together.
+		case unicode.Is(unicode.Cf, r) && r != '\u200d':
+			continue
+		}
+		if gap != 0 && b.Len() > 0 {
+			b.WriteRune(gap)
+		}
+		gap = 0
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// chatText cleans a line a player sent and runs it through the word
+// filter, telling them when it is too long or turned away. It reports
+// false when there is nothing to send.
+func (c *Client) chatText(text string) (string, bool) {
+	text = cleanChatText(text)
+	if text == "" {
+		return "", false
+	}
+	if max := config.Current().ChatMaxLength; utf8.RuneCountInString(text) > max {
+		c.sendError(fmt.Sprintf("Messages can be at most %d characters", max))
+		return "", false
+	}
+	return c.filterChat(text)
+}
+
+// chatRefusal says why player can't talk to the room right now, or returns
+// "". Runs on the room goroutine.
+func (r *Room) chatRefusal(player *Player) string {
+	switch {
+	case player.IsEliminated:
+		return "Ghosts can only talk to other ghosts"
+	case r.isMuted(player.ID):
+		return "You are muted"
+	case r.chatSilenced():
+		return "Radio silence - chat opens during discussions"
+	case r.isFrozen(player.ID):
+		return "Communications jammed - wait for the freeze to end"
+	}
+	if wait := r.slowModeWait(player.ID); wait > 0 {
+		return fmt.Sprintf("Slow mode is on - wait %d seconds", int(wait.Seconds())+1)
+	}
+	return ""
+}
+
+// newChatLine holds a player's line until its translations arrive, or
+// chatTranslationWait passes. Runs on the room goroutine.
+func (r *Room) newChatLine(playerID, username, text string) *chatLine {
+	line := &chatLine{
+		ID:        uuid.New().String(),
+		PlayerID:  playerID,
+		Username:  username,
+		Text:      text,
+		Timestamp: time.Now().UnixMilli(),
+	}
+	r.chatPending[line.ID] = line
+
+	id := line.ID
+	line.timer = r.schedule(chatTranslationWait, func() {
+		if _, ok := r.chatPending[id]; ok {
+			log.Printf("⚠️ Chat message %s wasn't translated in time - sending it as is", id)
+			r.deliverChat(id, nil)
+		}
+	})
+	return line
+}
+
+// deliverChat sends a held line to the room with whatever translations
+// came for it. Translations are cleaned like the original and keyed by
+// locale code; each reader only gets the one in their language, while
+// CHAT_HISTORY keeps them all and picks again for each reader. Runs on the
+// room goroutine.
+func (r *Room) deliverChat(messageID string, translations map[string]string) {
+	line, ok := r.chatPending[messageID]
+	if !ok {
+		return
+	}
+	delete(r.chatPending, messageID)
+	delete(r.translationFailures, "chat:"+messageID)
+	line.timer.Stop()
+
+	clean := make(map[string]string, len(translations))
+	for locale, text := range translations {
+		if !validLocale(locale) {
+			continue
+		}
+		text = cleanChatText(text)
+		if text == "" {
+			continue
+		}
+		if runes := []rune(text); len(runes) > maxTranslationRunes {
+			text = string(runes[:maxTranslationRunes])
+		}
+		clean[locale] = text
+	}
+	if _, ok := clean["en"]; !ok {
+		clean["en"] = line.Text
+	}
+
+	message := func(translations map[string]string) []byte {
+		data, _ := json.Marshal(Message{
+			Type: "CHAT",
+			Data: map[string]interface{}{
+				"messageId":    line.ID,
+				"username":     line.Username,
+				"text":         line.Text,
+				"playerId":     line.PlayerID,
+				"translations": translations,
+				"timestamp":    line.Timestamp,
+				"system":       false,
+			},
+		})
+		return data
+	}
+
+	byLocale := make(map[string][]byte)
+	for client := range r.clients {
+		locale := r.language(client.PlayerID)
+		data, ok := byLocale[locale]
+		if !ok {
+			data = message(translationFor(clean, locale))
+			byLocale[locale] = data
+		}
+		client.enqueue(data)
+	}
+	if len(r.spectators)+len(r.observers) > 0 {
+		data := message(translationFor(clean, defaultLanguage))
+		for s := range r.spectators {
+			r.enqueueSpectator(s, data)
+		}
+		for client := range r.observers {
+			client.enqueue(data)
+		}
+	}
+	r.recordChat(message(clean))
+}
+
+// defaultLanguage is who a player is reading as when they haven't said.
+const defaultLanguage = "en"
+
+// language is the locale playerID reads chat in. Runs on the room
+// goroutine.
+func (r *Room) language(playerID string) string {
+	if player := r.players[playerID]; player != nil && player.Language != "" {
+		return player.Language
+	}
+	return defaultLanguage
+}
+
+// translationFor picks the translation for locale out of translations,
+// settling for the same language's ("pt" for "pt-BR"). The result is
+// keyed by locale, and empty when there is none.
+func translationFor(translations map[string]string, locale string) map[string]string {
+	if text, ok := translations[locale]; ok {
+		return map[string]string{locale: text}
+	}
+	base, _, _ := strings.Cut(locale, "-")
+	if text, ok := translations[base]; ok {
+		return map[string]string{locale: text}
+	}
+	return map[string]string{}
+}
+
+// chatMessagePrefix starts every marshalled CHAT message.
+var chatMessagePrefix = []byte(`{"type":"CHAT",`)
+
+// recordChat keeps a broadcast CHAT message for replay to players who join
+// or reconnect later. Runs on the room goroutine.
+func (r *Room) recordChat(message []byte) {
+	if !bytes.HasPrefix(message, chatMessagePrefix) {
+		return
+	}
+	keep := config.Current().ChatHistorySize
+	if keep == 0 {
+		return
+	}
+
+	var msg struct {
+		Data json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(message, &msg); err != nil {
+		return
+	}
+	if err := database.AppendChatLog(r.ID, msg.Data, keep); err != nil {
+		log.Printf("Failed to record chat in room %s: %v", r.ID, err)
+	}
+}
+
+// sendChatHistory sends c the room's latest chat, translations and all, in
+// one CHAT_HISTORY. Runs on the room goroutine.
+func (r *Room) sendChatHistory(c *Client) {
+	limit := config.Current().ChatHistorySize
+	if limit == 0 {
+		return
+	}
+
+	lines, err := database.ChatLog(r.ID, limit)
+	if err != nil {
+		log.Printf("Failed to load chat history for room %s: %v", r.ID, err)
+		return
+	}
+	locale := r.language(c.PlayerID)
+	messages := make([]json.RawMessage, 0, len(lines))
+	for _, m := range lines {
+		messages = append(messages, localizeChat(json.RawMessage(m), locale))
+	}
+
+	c.sendMessage(Message{
+		Type: "CHAT_HISTORY",
+		Data: map[string]interface{}{"messages": messages},
+	})
+}
+
+// localizeChat trims a recorded CHAT's translations to locale's.
+func localizeChat(data json.RawMessage, locale string) json.RawMessage {
+	var line map[string]json.RawMessage
+	if err := json.Unmarshal(data, &line); err != nil {
+		return data
+	}
+	var translations map[string]string
+	if err := json.Unmarshal(line["translations"], &translations); err != nil || translations == nil {
+		return data
+	}
+
+	line["translations"], _ = json.Marshal(translationFor(translations, locale))
+	localized, err := json.Marshal(line)
+	if err != nil {
+		return data
+	}
+	return localized
+}
+
+// validLocale accepts codes like "de" and "pt-BR".
+func validLocale(locale string) bool {
+	if locale == "" || len(locale) > 8 {
+		return false
+	}
+	for _, r := range locale {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r == '-') {
+			return false
+		}
+	}
+	return true
+}
@@ -4,11 +4,13 @@ import (
 	"encoding/json"
 	"log"
 	"net/http"
+	"sync"
+	"sync/atomic"
 	"time"
 
+	"code-mafia-backend/config"
 	"code-mafia-backend/database"
 
-	"github.com/google/uuid"
 	"github.com/gorilla/websocket"
 )
 
@@ -22,9 +24,7 @@ const (
 var upgrader = websocket.Upgrader{
 	ReadBufferSize:  1024,
 	WriteBufferSize: 1024,
-	CheckOrigin: func(r *http.Request) bool {
-		return true
-	},
+	CheckOrigin:     checkOrigin,
 }
 
 type Client struct {
@@ -34,6 +34,73 @@ type Client struct {
 	RoomID   string
 	PlayerID string
 	Username string
+	ConnID   string
+
+	// Authenticated is set when PlayerID is a Supabase account ID.
+	Authenticated bool
+
+	// Protocol is the protocol version the client speaks. See messages.go.
+	Protocol int
+
+	// Resumed is set when the client presented the player's session token,
+	// which lets it back into a game already under way.
+	Resumed bool
+
+	// Proven is set when the connection has shown PlayerID is its own: it
+	// is signed in, presented the guest's token, or was given a new ID.
+	// Only proven connections are issued session tokens.
+	Proven bool
+
+	// Observer is set on a connection made with ?spectate=1, which
+	// watches without playing. See observers.go.
+	Observer bool
+
+	// IP and UserAgent fingerprint the connection for anti-cheat.
+	IP        string
+	UserAgent string
+
+	// relayed is set on a local connection to a room another instance
+	// runs. remote is set on the owner's stand-in for such a connection,
+	// naming the instance it is relayed through; its messages arrive on
+	// inbox. See cluster.go.
+	relayed bool
+	remote  string
+	inbox   chan []byte
+
+	// registered is closed once the hub has placed the client, before
+	// which its messages have nowhere to go.
+	registered chan struct{}
+
+	// refused is set, before registered is closed, on a connection the hub
+	// turned away. Its messages are dropped while it waits to be hung up.
+	refused bool
+
+	// done is closed to hang up. send is never closed, so any goroutine
+	// may still write to it after the room has let the client go.
+	done      chan struct{}
+	closeOnce sync.Once
+
+	// buckets hold the connection's message budgets. See allowMessage.
+	buckets map[string]*tokenBucket
+
+	// lastSeen is when the client last sent a message, in Unix
+	// nanoseconds. See janitor.go.
+	lastSeen atomic.Int64
+
+	// sendMu guards what enqueue does when send is full: the overflow the
+	// grow policy holds, which overflowReady wakes the write pump for,
+	// and what the player has been told. See slowclient.go.
+	sendMu          sync.Mutex
+	overflow        [][]byte
+	overflowReady   chan struct{}
+	drops           int
+	lastSlowWarning time.Time
+	closeReason     string
+}
+
+// close tells writePump to hang up. It is safe to call more than once.
+func (c *Client) close() {
+	c.closeOnce.Do(func() { close(c.done) })
 }
 
 type Message struct {
@@ -42,14 +109,72 @@ type Message struct {
 }
 
 func serveWs(hub *Hub, w http.ResponseWriter, r *http.Request) {
-	conn, err := upgrader.Upgrade(w, r, nil)
+	conn, err := upgrade(w, r, gameSocket)
 	if err != nil {
 		log.Println(err)
 		return
 	}
 
 	roomID := r.URL.Query().Get("room")
-	userID := r.URL.Query().Get("userId")
+	connID := requestIDFromContext(r.Context())
+
+	protocol := clientProtocol(r)
+	if protocol < minProtocolVersion {
+		log.Printf("📦 Rejected client on protocol %d (conn=%s)", protocol, connID)
+		rejectConnection(conn, Message{
+			Type: "ERROR_ACCESS_DENIED",
+			Data: map[string]interface{}{
+				"reason":  "OUTDATED_CLIENT",
+				"message": "This version of the game is out of date - refresh the page to update",
+			},
+		})
+		return
+	}
+
+	claims, err := authenticateRequest(r)
+	if err != nil {
+		log.Printf("🔒 Rejected invalid access token (conn=%s): %v", connID, err)
+		rejectConnection(conn, Message{
+			Type: "ERROR_ACCESS_DENIED",
+			Data: map[string]interface{}{
+				"reason":  "INVALID_TOKEN",
+				"message": "Your session is invalid or has expired - please sign in again",
+			},
+		})
+		return
+	}
+	if claims == nil && config.AppConfig.RequireAuth {
+		rejectConnection(conn, Message{
+			Type: "ERROR_ACCESS_DENIED",
+			Data: map[string]interface{}{
+				"reason":  "AUTH_REQUIRED",
+				"message": "Sign in to play on this server",
+			},
+		})
+		return
+	}
+
+	if !hub.roomKnown(roomID) {
+		log.Printf("🚪 Rejected connection to unknown room %q (conn=%s)", roomID, connID)
+		rejectConnection(conn, Message{
+			Type: "ERROR_ACCESS_DENIED",
+			Data: map[string]interface{}{
+				"reason":  "ROOM_NOT_FOUND",
+				"message": "That room doesn't exist - check the code or create a new room",
+			},
+		})
+		return
+	}
+
+	userID := resolvePlayerID(claims, r.URL.Query().Get("userId"))
+
+	// Browsers can't read the body of a refused handshake, so banned
+	// clients are upgraded, told why, and disconnected.
+	if ban := findBan(clientIP(r), userID); ban != nil {
+		log.Printf("🔨 Rejected banned %s %s (conn=%s): %s", ban.Kind, ban.Value, connID, ban.Reason)
+		rejectConnection(conn, Message{Type: "ERROR_ACCESS_DENIED", Data: banPayload(ban)})
+		return
+	}
 
 	var playerID string
 	var isReconnect bool
@@ -61,52 +186,95 @@ func serveWs(hub *Hub, w http.ResponseWriter, r *http.Request) {
 		if err == nil {
 			playerID = userID
 			isReconnect = true
-			log.Printf("♻️  User %s RECONNECTED to room %s", existingPlayer.Username, roomID)
-
-			existingPlayer.IsAlive = true
-			existingPlayer.IsEliminated = false
-			database.SavePlayer(roomID, existingPlayer)
+			log.Printf("♻️  User %s RECONNECTED to room %s (conn=%s)", existingPlayer.Username, roomID, connID)
 		} else {
 			playerID = userID
 		}
 	} else {
-		playerID = uuid.New().String()
+		playerID = newGuestID()
 	}
 
+	observer := r.URL.Query().Get("spectate") == "1"
+	resumed := !observer && validSession(userID, roomID, r.URL.Query().Get("session"))
+	proven := claims != nil || userID == "" || ownsGuestID(r, userID)
+
 	client := &Client{
 		hub:      hub,
 		conn:     conn,
-		send:     make(chan []byte, 256),
+		send:     make(chan []byte, config.AppConfig.SendBufferSize),
+		done:     make(chan struct{}),
 		RoomID:   roomID,
 		PlayerID: playerID,
+		ConnID:   connID,
+
+		Authenticated: claims != nil,
+		Protocol:      protocol,
+		Resumed:       resumed,
+		Proven:        proven,
+		Observer:      observer,
+		registered:    make(chan struct{}),
+		overflowReady: make(chan struct{}, 1),
+
+		IP:        clientIP(r),
+		UserAgent: r.UserAgent(),
 	}
+	client.touch()
 
 	client.hub.register <- client
 
+	initPayload := map[string]interface{}{
+		"playerID":    playerID,
+		"roomID":      roomID,
+		"isReconnect": isReconnect,
+
+		"authenticated": claims != nil,
+		"observer":      observer,
+
+		"protocolVersion": protocolVersion,
+		"outdated":        protocol < protocolVersion,
+	}
+	// Guests keep this token so they can claim their matches after
+	// signing up.
+	if claims == nil && guestTokensEnabled() {
+		initPayload["guestToken"] = guestToken(playerID)
+	}
+
 	initMsg := Message{
 		Type: "INIT",
-		Data: map[string]interface{}{
-			"playerID":    playerID,
-			"roomID":      roomID,
-			"isReconnect": isReconnect,
-		},
+		Data: initPayload,
 	}
 	initData, _ := json.Marshal(initMsg)
 	client.send <- initData
 
-	log.Printf("Client %s initialized for room %s (reconnect: %v)", playerID, roomID, isReconnect)
+	log.Printf("Client %s initialized for room %s (conn=%s, reconnect: %v)", playerID, roomID, connID, isReconnect)
 
 	go client.writePump()
 	go client.readPump()
 }
 
+// rejectConnection sends a final message to a connection that never joined
+// the hub and closes it.
+func rejectConnection(conn *websocket.Conn, msg Message) {
+	data, _ := json.Marshal(msg)
+	conn.SetWriteDeadline(time.Now().Add(writeWait))
+	conn.WriteMessage(websocket.TextMessage, data)
+	conn.WriteMessage(websocket.CloseMessage,
+		websocket.FormatCloseMessage(websocket.ClosePolicyViolation, "access denied"))
+	conn.Close()
+}
+
 func serveYjs(hub *Hub, w http.ResponseWriter, r *http.Request) {
-	conn, err := upgrader.Upgrade(w, r, nil)
+	conn, err := upgrade(w, r, yjsSocket)
 	if err != nil {
 		log.Printf("Yjs WebSocket upgrade error: %v", err)
 		return
 	}
 
+	if ban := findBan(clientIP(r), ""); ban != nil {
+		rejectConnection(conn, Message{Type: "ERROR_ACCESS_DENIED", Data: banPayload(ban)})
+		return
+	}
+
 	hub.handleYjsConnection(w, r, conn)
 }
 
@@ -122,15 +290,22 @@ func (c *Client) readPump() {
 		return nil
 	})
 
+	<-c.registered
+
 	for {
 		_, message, err := c.conn.ReadMessage()
 		if err != nil {
 			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
-				log.Printf("error: %v", err)
+				log.Printf("error (conn=%s): %v", c.ConnID, err)
 			}
 			break
 		}
+		addMetric(gameSocket.rawIn, int64(len(message)))
 
+		if c.relayed {
+			c.hub.cluster.forward(c, message)
+			continue
+		}
 		c.handleMessage(message)
 	}
 }
@@ -144,26 +319,20 @@ func (c *Client) writePump() {
 
 	for {
 		select {
-		case message, ok := <-c.send:
+		case <-c.done:
 			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
-			if !ok {
-				c.conn.WriteMessage(websocket.CloseMessage, []byte{})
-				return
-			}
-
-			w, err := c.conn.NextWriter(websocket.TextMessage)
-			if err != nil {
+			c.conn.WriteMessage(websocket.CloseMessage, c.closeMessage())
+			return
+		case <-c.overflowReady:
+			if err := c.writeBatch(c.takeQueued()); err != nil {
 				return
 			}
-			w.Write(message)
-
-			n := len(c.send)
-			for i := 0; i < n; i++ {
-				w.Write([]byte{'\n'})
-				w.Write(<-c.send)
+		case message := <-c.send:
+			batch := [][]byte{message}
+			for n := len(c.send); n > 0; n-- {
+				batch = append(batch, <-c.send)
 			}
-
-			if err := w.Close(); err != nil {
+			if err := c.writeBatch(batch); err != nil {
 				return
 			}
 		case <-ticker.C:
@@ -175,10 +344,47 @@ func (c *Client) writePump() {
 	}
 }
 
+// writeBatch sends queued messages as one frame, a line each.
+func (c *Client) writeBatch(batch [][]byte) error {
+	size := len(batch) - 1
+	for _, message := range batch {
+		size += len(message)
+	}
+	prepareWrite(c.conn, gameSocket, size)
+
+	c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+	w, err := c.conn.NextWriter(websocket.TextMessage)
+	if err != nil {
+		return err
+	}
+	for i, message := range batch {
+		if i > 0 {
+			w.Write([]byte{'\n'})
+		}
+		w.Write(message)
+	}
+	return w.Close()
+}
+
 func (c *Client) handleMessage(message []byte) {
-	var msg Message
-	if err := json.Unmarshal(message, &msg); err != nil {
-		log.Printf("Error unmarshaling message: %v", err)
+	defer recoverPanic("handleMessage")
+
+	if c.refused {
+		return
+	}
+
+	var msg inboundMessage
+	if err := json.Unmarshal(message, &msg); err != nil || msg.Type == "" {
+		log.Printf("Error unmarshaling message (conn=%s): %v", c.ConnID, err)
+		incMetric("invalid_messages_total")
+		c.sendError("Messages must be JSON objects with a type and data")
+		return
+	}
+	c.touch()
+
+	if ok, retryAfter := c.allowMessage(msg.Type); !ok {
+		debugf("⏳ Rate limited %s on %s (conn=%s)", c.PlayerID, msg.Type, c.ConnID)
+		c.sendRateLimited(msg.Type, retryAfter)
 		return
 	}
 
@@ -188,141 +394,425 @@ func (c *Client) handleMessage(message []byte) {
 		return
 	}
 
+	if c.Observer {
+		c.handleObserverMessage(room, msg)
+		return
+	}
+
+	// Everything that reads or changes the room is posted to the room
+	// goroutine, which handles one message at a time in arrival order.
 	switch msg.Type {
 	case "JOIN":
-		data, ok := msg.Data.(map[string]interface{})
-		if !ok {
+		var req joinRequest
+		if !c.decodeRequest(msg, &req) {
 			return
 		}
 
-		username, _ := data["username"].(string)
+		username, reason := c.checkUsername(req.Username)
+		if reason != "" {
+			c.sendError(reason)
+			return
+		}
 		c.Username = username
 
-		room.addPlayer(c.PlayerID, username)
-		room.broadcastPlayerList()
+		language := req.Language
+		if !validLocale(language) {
+			language = ""
+		}
 
-		room.mu.RLock()
-		player := room.players[c.PlayerID]
-		room.mu.RUnlock()
+		log.Printf("👤 JOIN %s as %s in room %s (conn=%s)", c.PlayerID, username, c.RoomID, c.ConnID)
 
-		if player != nil {
-			selfMsg := Message{
-				Type: "SELF",
-				Data: player,
-			}
-			payload, _ := json.Marshal(selfMsg)
-			c.send <- payload
+		var cosmetics *Cosmetics
+		if c.Authenticated {
+			cosmetics = loadCosmetics(c.PlayerID)
 		}
 
-	case "SABOTAGE":
-		room.mu.RLock()
-		player := room.players[c.PlayerID]
-		room.mu.RUnlock()
+		room.post(func() {
+			// Connections the room turned away or kicked are on their
+			// way out.
+			if !room.clients[c] {
+				return
+			}
+			room.issueSession(c)
+			room.addPlayer(c.PlayerID, username)
+			if player := room.players[c.PlayerID]; player != nil && language != "" {
+				player.Language = language
+			}
+			if c.Authenticated {
+				room.setCosmetics(c.PlayerID, cosmetics)
+			}
+			room.broadcastPlayerList()
+
+			if player := room.players[c.PlayerID]; player != nil {
+				c.sendMessage(Message{
+					Type: "SELF",
+					Data: player,
+				})
+			}
+			c.sendMessage(room.settingsMessage())
+			room.sendChatHistory(c)
 
-		if player == nil || player.IsEliminated || player.Role != "IMPOSTER" {
-			c.sendError("Cannot sabotage")
+			if c.Resumed && room.gameState.Phase != PhaseLobby {
+				room.resync(c)
+			}
+		})
+
+	case "SABOTAGE":
+		var req sabotageRequest
+		if !c.decodeRequest(msg, &req) {
 			return
 		}
+		sabotageType := req.Type
 
-		data, ok := msg.Data.(map[string]interface{})
-		if !ok {
+		room.post(func() {
+			player := room.players[c.PlayerID]
+			if player == nil || player.IsEliminated || player.Role != "IMPOSTER" {
+				c.sendError("Cannot sabotage")
+				return
+			}
+
+			room.handleSabotage(c.PlayerID, sabotageType)
+		})
+
+	case "REPAIR":
+		room.post(func() {
+			if reason := room.handleRepair(c.PlayerID); reason != "" {
+				c.sendError(reason)
+			}
+		})
+
+	case "DETECTIVE_QUERY":
+		var req targetRequest
+		if !c.decodeRequest(msg, &req) {
 			return
 		}
+		targetID := req.TargetID
 
-		sabotageType, _ := data["type"].(string)
-		room.handleSabotage(c.PlayerID, sabotageType)
+		room.post(func() {
+			if reason := room.handleDetectiveQuery(c.PlayerID, targetID); reason != "" {
+				c.sendError(reason)
+			}
+		})
 
 	case "START_GAME":
-		room.mu.RLock()
-		player := room.players[c.PlayerID]
-		room.mu.RUnlock()
+		room.post(func() {
+			player := room.players[c.PlayerID]
+			if player == nil || !player.IsHost {
+				c.sendError("Only host can start game")
+				return
+			}
+
+			room.startGame()
+		})
+
+	case "REMATCH":
+		room.post(func() { room.rematch(c) })
 
-		if player == nil || !player.IsHost {
-			c.sendError("Only host can start game")
+	case "ADD_BOT":
+		room.post(func() {
+			if err := room.addPracticeBot(c); err != nil {
+				c.sendError(err.Error())
+			}
+		})
+
+	case "SET_RANKED":
+		var req setRankedRequest
+		if !c.decodeRequest(msg, &req) {
 			return
 		}
+		ranked := req.Ranked
 
-		room.startGame()
+		room.post(func() {
+			player := room.players[c.PlayerID]
+			if player == nil || !player.IsHost {
+				c.sendError("Only host can change ranked mode")
+				return
+			}
 
-	case "RUN_TESTS":
-		room.mu.RLock()
-		player := room.players[c.PlayerID]
-		room.mu.RUnlock()
+			if err := room.setRanked(ranked); err != nil {
+				c.sendError(err.Error())
+			}
+		})
 
-		if player == nil || player.IsEliminated {
-			c.sendError("Cannot run tests")
+	case "ROOM_SETTINGS":
+		var update settingsUpdate
+		if !c.decodeRequest(msg, &update) {
 			return
 		}
 
-		data, ok := msg.Data.(map[string]interface{})
-		if !ok {
+		room.post(func() {
+			player := room.players[c.PlayerID]
+			if player == nil || !player.IsHost {
+				c.sendError("Only host can change the settings")
+				return
+			}
+
+			if err := room.updateSettings(update); err != nil {
+				c.sendError(err.Error())
+			}
+		})
+
+	case "SET_SPECTATOR_DELAY":
+		var req spectatorDelayRequest
+		if !c.decodeRequest(msg, &req) {
 			return
 		}
+		seconds := req.Seconds
 
-		code, _ := data["code"].(string)
-		room.handleRunTests(c.PlayerID, code)
+		room.post(func() {
+			player := room.players[c.PlayerID]
+			if player == nil || !player.IsHost {
+				c.sendError("Only host can change the spectator delay")
+				return
+			}
+
+			if err := room.setSpectatorDelay(seconds); err != nil {
+				c.sendError(err.Error())
+			}
+		})
+
+	case "RUN_TESTS":
+		var req codeRequest
+		if !c.decodeRequest(msg, &req) {
+			return
+		}
+		code := req.Code
+
+		room.post(func() {
+			player := room.players[c.PlayerID]
+			if player == nil || player.IsEliminated {
+				c.sendError("Cannot run tests")
+				return
+			}
+			if room.isFrozen(c.PlayerID) {
+				c.sendError("Systems jammed - wait for the freeze to end")
+				return
+			}
+
+			room.handleRunTests(c.PlayerID, code)
+		})
+
+	case "HINT":
+		var req codeRequest
+		if !c.decodeRequest(msg, &req) {
+			return
+		}
+		code := req.Code
+
+		room.post(func() {
+			if reason := room.requestHint(c.PlayerID, code); reason != "" {
+				c.sendError(reason)
+			}
+		})
 
 	case "CHAT":
-		room.mu.RLock()
-		player := room.players[c.PlayerID]
-		room.mu.RUnlock()
+		var req textRequest
+		if !c.decodeRequest(msg, &req) {
+			return
+		}
+
+		text, ok := c.chatText(req.Text)
+		if !ok {
+			return
+		}
 
-		if player != nil && !player.IsEliminated {
-			data, ok := msg.Data.(map[string]interface{})
-			if !ok {
+		room.post(func() {
+			player := room.players[c.PlayerID]
+			if player == nil {
+				return
+			}
+			if reason := room.chatRefusal(player); reason != "" {
+				c.sendError(reason)
 				return
 			}
 
-			text, ok := data["text"].(string)
-			if !ok || text == "" {
+			room.stopTyping(c.PlayerID)
+			room.noteChat(c.PlayerID)
+
+			// The line goes out once the translation service has seen it,
+			// or chatTranslationWait passes.
+			line := room.newChatLine(c.PlayerID, player.Username, text)
+			go c.hub.handleChatMessage(c.RoomID, *line)
+		})
+
+	case "QUICK_CHAT":
+		var req quickChatRequest
+		if !c.decodeRequest(msg, &req) {
+			return
+		}
+
+		preset, ok := findQuickChat(req.PresetID)
+		if !ok {
+			c.sendError("Unknown quick chat preset")
+			return
+		}
+
+		room.post(func() {
+			player := room.players[c.PlayerID]
+			if player == nil {
+				return
+			}
+			if reason := room.chatRefusal(player); reason != "" {
+				c.sendError(reason)
 				return
 			}
 
-			// 🔥 REMOVED: Don't broadcast immediately
-			// room.broadcast <- message
+			room.stopTyping(c.PlayerID)
+			room.noteChat(c.PlayerID)
+			room.sendQuickChat(player, preset)
+		})
 
-			// 🔥 NEW: Only trigger translation pipeline
-			// Translation service will broadcast when ready
-			go c.hub.handleChatMessage(
-				c.RoomID,
-				c.PlayerID,
-				c.Username,
-				text,
-			)
+	case "IMPOSTOR_CHAT":
+		var req textRequest
+		if !c.decodeRequest(msg, &req) {
+			return
 		}
 
-	case "EMERGENCY":
-		room.mu.RLock()
-		player := room.players[c.PlayerID]
-		room.mu.RUnlock()
+		text, ok := c.chatText(req.Text)
+		if !ok {
+			return
+		}
+
+		room.post(func() {
+			if reason := room.handleImpostorChat(c.PlayerID, text); reason != "" {
+				c.sendError(reason)
+			}
+		})
 
-		if player == nil || player.IsEliminated {
-			c.sendError("Cannot call meeting")
+	case "TYPING_START":
+		room.post(func() { room.startTyping(c.PlayerID) })
+
+	case "TYPING_STOP":
+		room.post(func() { room.stopTyping(c.PlayerID) })
+
+	case "GHOST_CHAT":
+		var req textRequest
+		if !c.decodeRequest(msg, &req) {
 			return
 		}
 
-		room.startDiscussion()
+		text, ok := c.chatText(req.Text)
+		if !ok {
+			return
+		}
+
+		room.post(func() { room.handleGhostChat(c, text) })
+
+	case "EMERGENCY":
+		room.post(func() {
+			if reason, retryAfter := room.takeEmergency(c.PlayerID); reason != "" {
+				c.sendEmergencyUnavailable(reason, retryAfter, room.emergencyCallsLeft(c.PlayerID))
+				return
+			}
+
+			room.startDiscussion()
+		})
 
 	case "VOTE":
-		room.mu.RLock()
-		player := room.players[c.PlayerID]
-		room.mu.RUnlock()
+		var req targetRequest
+		if !c.decodeRequest(msg, &req) {
+			return
+		}
+		targetID := req.TargetID
+
+		room.post(func() {
+			if reason := room.handleVote(c.PlayerID, targetID); reason != "" {
+				c.sendMessage(Message{
+					Type: "VOTE_REJECTED",
+					Data: map[string]interface{}{
+						"reason":   reason,
+						"message":  voteRejectionMessages[reason],
+						"targetID": targetID,
+					},
+				})
+			}
+		})
 
-		if player == nil || player.IsEliminated {
-			c.sendError("Cannot vote")
+	case "KICK_PLAYER", "BAN_PLAYER":
+		var req targetRequest
+		if !c.decodeRequest(msg, &req) {
 			return
 		}
 
-		data, ok := msg.Data.(map[string]interface{})
-		if !ok {
+		targetID := req.TargetID
+		room.post(func() { room.removeByHost(c, targetID, msg.Type == "BAN_PLAYER") })
+
+	case "MUTE_PLAYER":
+		var req muteRequest
+		if !c.decodeRequest(msg, &req) {
+			return
+		}
+
+		room.post(func() { room.muteByHost(c, req.TargetID, req.Muted) })
+
+	case "SLOW_MODE":
+		var req slowModeRequest
+		if !c.decodeRequest(msg, &req) {
+			return
+		}
+
+		room.post(func() { room.setSlowMode(c, req.Seconds) })
+
+	case "REPORT_PLAYER":
+		var req reportRequest
+		if !c.decodeRequest(msg, &req) {
 			return
 		}
 
-		targetID, _ := data["targetID"].(string)
-		room.handleVote(c.PlayerID, targetID)
+		room.post(func() { room.handleReport(c.PlayerID, req.TargetID, req.Reason, req.Details) })
+
+	case "ADD_WEBHOOK", "REMOVE_WEBHOOK":
+		isHost := false
+		room.do(func() {
+			player := room.players[c.PlayerID]
+			isHost = player != nil && player.IsHost
+		})
+
+		if !isHost {
+			c.sendError("Only host can manage webhooks")
+			return
+		}
+
+		if msg.Type == "REMOVE_WEBHOOK" {
+			var req removeWebhookRequest
+			if !c.decodeRequest(msg, &req) {
+				return
+			}
+			room.removeWebhook(c, req.ID)
+			return
+		}
+
+		var req addWebhookRequest
+		if !c.decodeRequest(msg, &req) {
+			return
+		}
+		room.addWebhook(c, req.URL, req.Events)
+
+	case "PING":
+		// Nothing to do: any message keeps the player from counting as AFK.
+
+	case "PARTY_CHAT":
+		var req textRequest
+		if !c.decodeRequest(msg, &req) {
+			return
+		}
+
+		if !c.Authenticated {
+			return
+		}
+		if text, ok := c.chatText(req.Text); ok {
+			c.hub.handlePartyChat(c, text)
+		}
+
+	case "QUEUE", "LEAVE_QUEUE":
+		var req queueRequest
+		if !c.decodeRequest(msg, &req) {
+			return
+		}
+		c.hub.handleQueueMessage(c, msg.Type, req.Language)
 
 	default:
-		log.Printf("Unknown message type: %s", msg.Type)
+		c.rejectUnknownMessage(msg.Type)
 	}
 }
 
@@ -334,10 +824,5 @@ func (c *Client) sendError(message string) {
 		},
 	}
 	errData, _ := json.Marshal(errorMsg)
-
-	select {
-	case c.send <- errData:
-	default:
-		log.Printf("Could not send error to %s", c.Username)
-	}
+	c.enqueue(errData)
 }
@@ -0,0 +1,701 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"code-mafia-backend/config"
+	"code-mafia-backend/database"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+	"github.com/redis/go-redis/v9"
+)
+
+// With CLUSTER_MODE on, several backend instances can serve the same
+// rooms. Each room is run by exactly one instance, the one holding its
+// lease in Redis: the room goroutine, its timers and all game logic live
+// there. A player who connects to any other instance is relayed. Their
+// messages are published to the room's inbox, where the owner feeds them
+// to a stand-in Client, and whatever the owner sends that stand-in, or
+// broadcasts to the room, comes back over the room's fan-out channel.
+//
+// Spectator feeds and the admin room endpoints only see rooms owned by
+// the instance they reach.
+
+const (
+	roomLeaseTTL    = 15 * time.Second
+	leaseRenewEvery = 5 * time.Second
+
+	// claimAttempts is how many times taking a room's lease is tried while
+	// Redis is failing, before the connection is turned away.
+	claimAttempts = 3
+)
+
+const (
+	// Inbox, relay to owner.
+	relayJoin     = "join"
+	relayMessage  = "message"
+	relayLeave    = "leave"
+	relayYjsOpen  = "yjs_open"
+	relayYjsClose = "yjs_close"
+
+	// Fan-out, owner to relays.
+	relaySend      = "send"
+	relayBroadcast = "broadcast"
+	relayClose     = "close"
+
+	// Both ways.
+	relayYjs = "yjs"
+)
+
+type relayEnvelope struct {
+	Kind     string          `json:"kind"`
+	ConnID   string          `json:"connId,omitempty"`
+	Instance string          `json:"instance,omitempty"`
+	Client   *relayedClient  `json:"client,omitempty"`
+	Data     json.RawMessage `json:"data,omitempty"`
+
+	// Yjs updates are binary, and for the editor document Doc.
+	Binary      []byte `json:"binary,omitempty"`
+	MessageType int    `json:"messageType,omitempty"`
+	Doc         string `json:"doc,omitempty"`
+}
+
+// relayedClient is what the owner needs to know about a connection it
+// doesn't hold.
+type relayedClient struct {
+	PlayerID      string `json:"playerId"`
+	Authenticated bool   `json:"authenticated"`
+	Resumed       bool   `json:"resumed"`
+	Proven        bool   `json:"proven,omitempty"`
+	Observer      bool   `json:"observer,omitempty"`
+	IP            string `json:"ip"`
+	UserAgent     string `json:"userAgent"`
+}
+
+type relayedYjs struct {
+	conn   *websocket.Conn
+	mu     sync.Mutex
+	roomID string
+	doc    string
+}
+
+type cluster struct {
+	hub      *Hub
+	instance string
+	pubsub   *redis.PubSub
+
+	mu sync.Mutex
+	// Owner side: rooms this instance holds the lease for, with when it
+	// was last taken or renewed, and stand-ins for players connected
+	// elsewhere, by connection ID.
+	owned   map[string]time.Time
+	proxies map[string]*Client
+	// Relay side: local connections to rooms owned elsewhere, and the
+	// owner each room was joined through.
+	relayed    map[string]*Client
+	relayedYjs map[string]*relayedYjs
+	relayOwner map[string]string
+	fanouts    map[string]int
+}
+
+func newCluster(hub *Hub, instance string) *cluster {
+	if instance == "" {
+		hostname, _ := os.Hostname()
+		instance = hostname + "-" + uuid.New().String()[:8]
+	}
+	return &cluster{
+		hub:        hub,
+		instance:   instance,
+		pubsub:     database.Subscribe(),
+		owned:      make(map[string]time.Time),
+		proxies:    make(map[string]*Client),
+		relayed:    make(map[string]*Client),
+		relayedYjs: make(map[string]*relayedYjs),
+		relayOwner: make(map[string]string),
+		fanouts:    make(map[string]int),
+	}
+}
+
+// run delivers inbox and fan-out messages and keeps this instance's leases
+// alive.
+func (cl *cluster) run() {
+	log.Printf("🛰️ Cluster mode on as instance %s", cl.instance)
+
+	go cl.maintain()
+
+	for msg := range cl.pubsub.Channel() {
+		var env relayEnvelope
+		if err := json.Unmarshal([]byte(msg.Payload), &env); err != nil {
+			log.Printf("⚠️ Bad relay message on %s: %v", msg.Channel, err)
+			continue
+		}
+
+		roomID := strings.TrimPrefix(msg.Channel, "room:")
+		switch {
+		case strings.HasSuffix(roomID, ":inbox"):
+			cl.handleInbox(strings.TrimSuffix(roomID, ":inbox"), env)
+		case strings.HasSuffix(roomID, ":fanout"):
+			cl.handleFanout(strings.TrimSuffix(roomID, ":fanout"), env)
+		}
+	}
+}
+
+// claim takes the room's lease, and reports whether this instance should
+// run the room. Redis errors are retried a few times, then returned: the
+// room isn't run without the lease, or another instance could claim it too
+// once Redis is back.
+func (cl *cluster) claim(roomID string) (bool, error) {
+	var claimed bool
+	var err error
+	takenAt := time.Now()
+	for attempt := 1; attempt <= claimAttempts; attempt++ {
+		takenAt = time.Now()
+		claimed, err = database.ClaimRoom(roomID, cl.instance, roomLeaseTTL)
+		if err == nil {
+			break
+		}
+		log.Printf("⚠️ Failed to claim room %s (attempt %d of %d): %v", roomID, attempt, claimAttempts, err)
+		if attempt < claimAttempts {
+			time.Sleep(time.Duration(attempt) * 100 * time.Millisecond)
+		}
+	}
+	if err != nil || !claimed {
+		return false, err
+	}
+
+	cl.mu.Lock()
+	cl.owned[roomID] = takenAt
+	cl.mu.Unlock()
+
+	cl.pubsub.Subscribe(context.Background(), database.RoomInboxChannel(roomID))
+	log.Printf("🛰️ Instance %s owns room %s", cl.instance, roomID)
+	return true, nil
+}
+
+// release gives up the lease on a room that has shut down.
+func (cl *cluster) release(roomID string) {
+	cl.mu.Lock()
+	_, owned := cl.owned[roomID]
+	delete(cl.owned, roomID)
+	cl.mu.Unlock()
+
+	if !owned {
+		return
+	}
+	cl.pubsub.Unsubscribe(context.Background(), database.RoomInboxChannel(roomID))
+	if err := database.ReleaseRoom(roomID, cl.instance); err != nil {
+		log.Printf("⚠️ Failed to release room %s: %v", roomID, err)
+	}
+}
+
+func (cl *cluster) publish(channel string, env relayEnvelope) {
+	env.Instance = cl.instance
+	data, _ := json.Marshal(env)
+	if err := database.Publish(channel, data); err != nil {
+		log.Printf("⚠️ Failed to publish %s to %s: %v", env.Kind, channel, err)
+	}
+}
+
+// publishFanout sends to the room's relayed players. Runs on any
+// goroutine.
+func publishFanout(roomID string, env relayEnvelope) {
+	data, _ := json.Marshal(env)
+	if err := database.Publish(database.RoomFanoutChannel(roomID), data); err != nil {
+		log.Printf("⚠️ Failed to publish %s for room %s: %v", env.Kind, roomID, err)
+	}
+}
+
+// watchRoom subscribes to a room's fan-out while this instance has relayed
+// connections to it.
+func (cl *cluster) watchRoom(roomID, owner string) {
+	cl.mu.Lock()
+	cl.fanouts[roomID]++
+	first := cl.fanouts[roomID] == 1
+	if first {
+		cl.relayOwner[roomID] = owner
+	}
+	cl.mu.Unlock()
+
+	if first {
+		cl.pubsub.Subscribe(context.Background(), database.RoomFanoutChannel(roomID))
+	}
+}
+
+func (cl *cluster) unwatchRoom(roomID string) {
+	cl.mu.Lock()
+	cl.fanouts[roomID]--
+	last := cl.fanouts[roomID] <= 0
+	if last {
+		delete(cl.fanouts, roomID)
+		delete(cl.relayOwner, roomID)
+	}
+	cl.mu.Unlock()
+
+	if last {
+		cl.pubsub.Unsubscribe(context.Background(), database.RoomFanoutChannel(roomID))
+	}
+}
+
+// relay hands a local connection to the room's owner on another instance.
+func (cl *cluster) relay(client *Client) {
+	owner, _ := database.RoomOwner(client.RoomID)
+	client.relayed = true
+
+	cl.mu.Lock()
+	cl.relayed[client.ConnID] = client
+	cl.mu.Unlock()
+	cl.watchRoom(client.RoomID, owner)
+
+	cl.publish(database.RoomInboxChannel(client.RoomID), relayEnvelope{
+		Kind:   relayJoin,
+		ConnID: client.ConnID,
+		Client: &relayedClient{
+			PlayerID:      client.PlayerID,
+			Authenticated: client.Authenticated,
+			Resumed:       client.Resumed,
+			Proven:        client.Proven,
+			Observer:      client.Observer,
+			IP:            client.IP,
+			UserAgent:     client.UserAgent,
+		},
+	})
+	log.Printf("🛰️ Relaying conn %s to room %s on %s", client.ConnID, client.RoomID, owner)
+}
+
+// forward passes a relayed player's message to the owner.
+func (cl *cluster) forward(client *Client, message []byte) {
+	if !json.Valid(message) {
+		log.Printf("Error unmarshaling message (conn=%s): not JSON", client.ConnID)
+		return
+	}
+	cl.publish(database.RoomInboxChannel(client.RoomID), relayEnvelope{
+		Kind:   relayMessage,
+		ConnID: client.ConnID,
+		Data:   message,
+	})
+}
+
+// unrelay tells the owner a relayed connection has closed.
+func (cl *cluster) unrelay(client *Client) {
+	cl.mu.Lock()
+	_, ok := cl.relayed[client.ConnID]
+	delete(cl.relayed, client.ConnID)
+	cl.mu.Unlock()
+
+	client.close()
+	if !ok {
+		return
+	}
+	cl.publish(database.RoomInboxChannel(client.RoomID), relayEnvelope{Kind: relayLeave, ConnID: client.ConnID})
+	cl.unwatchRoom(client.RoomID)
+}
+
+func (cl *cluster) handleInbox(roomID string, env relayEnvelope) {
+	switch env.Kind {
+	case relayJoin:
+		if env.Client == nil {
+			return
+		}
+		proxy := &Client{
+			hub:      cl.hub,
+			send:     make(chan []byte, config.AppConfig.SendBufferSize),
+			done:     make(chan struct{}),
+			RoomID:   roomID,
+			PlayerID: env.Client.PlayerID,
+			ConnID:   env.ConnID,
+
+			Authenticated: env.Client.Authenticated,
+			Resumed:       env.Client.Resumed,
+			Proven:        env.Client.Proven,
+			Observer:      env.Client.Observer,
+
+			IP:        env.Client.IP,
+			UserAgent: env.Client.UserAgent,
+
+			remote:        env.Instance,
+			overflowReady: make(chan struct{}, 1),
+			inbox:         make(chan []byte, 256),
+			registered:    make(chan struct{}),
+		}
+		proxy.touch()
+
+		cl.mu.Lock()
+		cl.proxies[env.ConnID] = proxy
+		cl.mu.Unlock()
+
+		go proxy.remoteWritePump()
+		go proxy.remoteReadPump()
+		cl.hub.register <- proxy
+
+	case relayMessage:
+		cl.mu.Lock()
+		if proxy := cl.proxies[env.ConnID]; proxy != nil {
+			select {
+			case proxy.inbox <- env.Data:
+			default:
+				log.Printf("⚠️ Dropped relayed message for conn %s: inbox full", env.ConnID)
+			}
+		}
+		cl.mu.Unlock()
+
+	case relayLeave:
+		cl.dropProxy(env.ConnID)
+
+	case relayYjsOpen, relayYjs, relayYjsClose:
+		room := cl.hub.getRoom(roomID)
+		if room == nil {
+			return
+		}
+		room.post(func() { room.handleRemoteYjs(env) })
+	}
+}
+
+// dropProxy ends a stand-in whose relayed connection has gone; its read
+// pump then takes it through the normal disconnect path.
+func (cl *cluster) dropProxy(connID string) {
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+
+	if proxy := cl.proxies[connID]; proxy != nil {
+		delete(cl.proxies, connID)
+		close(proxy.inbox)
+	}
+}
+
+func (cl *cluster) dropProxies(roomID string) {
+	cl.mu.Lock()
+	connIDs := make([]string, 0)
+	for connID, proxy := range cl.proxies {
+		if proxy.RoomID == roomID {
+			connIDs = append(connIDs, connID)
+		}
+	}
+	cl.mu.Unlock()
+
+	for _, connID := range connIDs {
+		cl.dropProxy(connID)
+	}
+}
+
+func (cl *cluster) handleFanout(roomID string, env relayEnvelope) {
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+
+	switch env.Kind {
+	case relaySend:
+		if client := cl.relayed[env.ConnID]; client != nil {
+			client.deliver(env.Data)
+		}
+
+	case relayBroadcast:
+		for _, client := range cl.relayed {
+			if client.RoomID == roomID {
+				client.deliver(env.Data)
+			}
+		}
+
+	case relayClose:
+		if client := cl.relayed[env.ConnID]; client != nil {
+			client.close()
+		}
+
+	case relayYjs:
+		for connID, y := range cl.relayedYjs {
+			if y.roomID == roomID && y.doc == env.Doc && connID != env.ConnID {
+				go y.write(env.MessageType, env.Binary)
+			}
+		}
+	}
+}
+
+// deliver queues a relayed message for a local connection.
+func (c *Client) deliver(message []byte) {
+	c.enqueue(message)
+}
+
+func (y *relayedYjs) write(messageType int, message []byte) {
+	y.mu.Lock()
+	defer y.mu.Unlock()
+
+	prepareWrite(y.conn, yjsSocket, len(message))
+	y.conn.SetWriteDeadline(time.Now().Add(writeWait))
+	if err := y.conn.WriteMessage(messageType, message); err != nil {
+		log.Printf("Error relaying Yjs message: %v", err)
+	}
+}
+
+// remoteReadPump feeds a relayed player's messages to the room, in order,
+// until the relay reports the connection closed.
+func (c *Client) remoteReadPump() {
+	defer func() { c.hub.unregister <- c }()
+
+	<-c.registered
+	for message := range c.inbox {
+		c.handleMessage(message)
+	}
+}
+
+// remoteWritePump publishes what the room sends a relayed player, and
+// tells the relay to hang up when the room closes them.
+func (c *Client) remoteWritePump() {
+	for {
+		select {
+		case <-c.done:
+			publishFanout(c.RoomID, relayEnvelope{Kind: relayClose, ConnID: c.ConnID})
+			return
+		case <-c.overflowReady:
+			for _, message := range c.takeQueued() {
+				publishFanout(c.RoomID, relayEnvelope{Kind: relaySend, ConnID: c.ConnID, Data: message})
+			}
+		case message := <-c.send:
+			publishFanout(c.RoomID, relayEnvelope{Kind: relaySend, ConnID: c.ConnID, Data: message})
+		}
+	}
+}
+
+// serveRelayedYjs carries an editor connection to a room owned by another
+// instance, which decides what is relayed on.
+func (cl *cluster) serveRelayedYjs(conn *websocket.Conn, roomID string, peer *yjsPeer) {
+	owner, _ := database.RoomOwner(roomID)
+	if owner == "" {
+		log.Printf("Room %s not found for Yjs connection", roomID)
+		conn.Close()
+		return
+	}
+
+	connID := uuid.New().String()
+	cl.mu.Lock()
+	cl.relayedYjs[connID] = &relayedYjs{conn: conn, roomID: roomID, doc: peer.doc}
+	cl.mu.Unlock()
+	cl.watchRoom(roomID, owner)
+
+	inbox := database.RoomInboxChannel(roomID)
+	cl.publish(inbox, relayEnvelope{
+		Kind:   relayYjsOpen,
+		ConnID: connID,
+		Doc:    peer.doc,
+		Client: &relayedClient{PlayerID: peer.playerID},
+	})
+	log.Printf("Yjs client relayed to room %s on %s", roomID, owner)
+
+	defer func() {
+		cl.mu.Lock()
+		delete(cl.relayedYjs, connID)
+		cl.mu.Unlock()
+
+		cl.publish(inbox, relayEnvelope{Kind: relayYjsClose, ConnID: connID})
+		cl.unwatchRoom(roomID)
+		conn.Close()
+		log.Printf("Yjs client disconnected from room %s", roomID)
+	}()
+
+	for {
+		messageType, message, err := conn.ReadMessage()
+		if err != nil {
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
+				log.Printf("Yjs websocket error: %v", err)
+			}
+			break
+		}
+		addMetric(yjsSocket.rawIn, int64(len(message)))
+		cl.publish(inbox, relayEnvelope{Kind: relayYjs, ConnID: connID, Binary: message, MessageType: messageType})
+	}
+}
+
+// handleRemoteYjs tracks editor connections relayed from other instances
+// and passes their updates on. Runs on the room goroutine.
+func (r *Room) handleRemoteYjs(env relayEnvelope) {
+	switch env.Kind {
+	case relayYjsOpen:
+		peer := &yjsPeer{instance: env.Instance, doc: env.Doc}
+		if env.Client != nil {
+			peer.playerID = env.Client.PlayerID
+		}
+		r.yjsRemote[env.ConnID] = peer
+	case relayYjsClose:
+		delete(r.yjsRemote, env.ConnID)
+	case relayYjs:
+		sender := r.yjsRemote[env.ConnID]
+		if sender == nil || (!r.mayWrite(sender) && yjsWrites(env.Binary)) {
+			return
+		}
+		r.recordEdit(sender.playerID, env.Binary)
+		r.broadcastYjs(nil, env.ConnID, sender.doc, env.MessageType, env.Binary)
+	}
+}
+
+// maintain renews this instance's leases and its heartbeat, and cleans up
+// after instances that have gone away.
+func (cl *cluster) maintain() {
+	ticker := time.NewTicker(leaseRenewEvery)
+	defer ticker.Stop()
+
+	for {
+		if err := database.MarkInstanceAlive(cl.instance, roomLeaseTTL); err != nil {
+			log.Printf("⚠️ Failed to mark instance %s alive: %v", cl.instance, err)
+		}
+		cl.renewLeases()
+		cl.dropDeadRelays()
+		cl.checkOwners()
+		<-ticker.C
+	}
+}
+
+// renewLeases extends every lease this instance holds. A room whose lease
+// was lost is now run elsewhere, so its local copy is shut down and its
+// players reconnect to the new owner. So is one whose lease would run out
+// before the next try, while Redis is failing: by then another instance
+// may claim it.
+func (cl *cluster) renewLeases() {
+	cl.mu.Lock()
+	rooms := make(map[string]time.Time, len(cl.owned))
+	for roomID, renewedAt := range cl.owned {
+		rooms[roomID] = renewedAt
+	}
+	cl.mu.Unlock()
+
+	for roomID, renewedAt := range rooms {
+		started := time.Now()
+		renewed, err := database.RenewRoomLease(roomID, cl.instance, roomLeaseTTL)
+		switch {
+		case err != nil && time.Since(renewedAt)+leaseRenewEvery < roomLeaseTTL:
+			log.Printf("⚠️ Failed to renew lease on room %s: %v", roomID, err)
+			continue
+		case err != nil:
+			log.Printf("🛰️ Couldn't renew the lease on room %s before it runs out, shutting down the local copy: %v", roomID, err)
+		case renewed:
+			cl.mu.Lock()
+			if _, ok := cl.owned[roomID]; ok {
+				cl.owned[roomID] = started
+			}
+			cl.mu.Unlock()
+			continue
+		default:
+			log.Printf("🛰️ Lost the lease on room %s, shutting down the local copy", roomID)
+		}
+
+		cl.release(roomID)
+		cl.hub.abandonRoom(roomID)
+		cl.dropProxies(roomID)
+	}
+}
+
+// dropDeadRelays disconnects stand-ins for players, and forgets editor
+// connections, relayed through instances that have stopped sending
+// heartbeats.
+func (cl *cluster) dropDeadRelays() {
+	cl.mu.Lock()
+	proxies := make(map[string]string, len(cl.proxies))
+	for connID, proxy := range cl.proxies {
+		proxies[connID] = proxy.remote
+	}
+	cl.mu.Unlock()
+
+	peers := make(map[*Room]map[string]string)
+	for _, room := range cl.hub.allRooms() {
+		room := room
+		instances := make(map[string]string)
+		room.do(func() {
+			for connID, peer := range room.yjsRemote {
+				instances[connID] = peer.instance
+			}
+		})
+		peers[room] = instances
+	}
+
+	alive := make(map[string]bool)
+	isAlive := func(instance string) bool {
+		if ok, seen := alive[instance]; seen {
+			return ok
+		}
+		ok, err := database.InstanceAlive(instance)
+		alive[instance] = ok || err != nil
+		return alive[instance]
+	}
+
+	for connID, instance := range proxies {
+		if !isAlive(instance) {
+			log.Printf("🛰️ Instance %s is gone, dropping relayed conn %s", instance, connID)
+			cl.dropProxy(connID)
+		}
+	}
+	for room, instances := range peers {
+		for connID, instance := range instances {
+			if !isAlive(instance) {
+				room, connID := room, connID
+				room.post(func() { delete(room.yjsRemote, connID) })
+			}
+		}
+	}
+}
+
+// checkOwners hangs up relayed connections to rooms whose owner has gone
+// or changed. The game carries on from its state in Redis once a player
+// reconnects and an instance claims the room.
+func (cl *cluster) checkOwners() {
+	cl.mu.Lock()
+	owners := make(map[string]string, len(cl.relayOwner))
+	for roomID, owner := range cl.relayOwner {
+		owners[roomID] = owner
+	}
+	cl.mu.Unlock()
+
+	for roomID, owner := range owners {
+		current, err := database.RoomOwner(roomID)
+		if err != nil || current == owner {
+			continue
+		}
+		log.Printf("🛰️ Room %s is no longer run by %s, disconnecting its relayed players", roomID, owner)
+
+		cl.mu.Lock()
+		for _, client := range cl.relayed {
+			if client.RoomID == roomID {
+				client.close()
+			}
+		}
+		for _, y := range cl.relayedYjs {
+			if y.roomID == roomID {
+				y.conn.Close()
+			}
+		}
+		cl.mu.Unlock()
+	}
+}
+
+// shutdown hands this instance's rooms back so other instances can claim
+// them as soon as their players reconnect.
+func (cl *cluster) shutdown() {
+	cl.mu.Lock()
+	rooms := make([]string, 0, len(cl.owned))
+	for roomID := range cl.owned {
+		rooms = append(rooms, roomID)
+	}
+	cl.mu.Unlock()
+
+	for _, roomID := range rooms {
+		cl.release(roomID)
+	}
+	database.ForgetInstance(cl.instance)
+	log.Printf("🛰️ Instance %s released %d rooms", cl.instance, len(rooms))
+}
+
+// abandonRoom shuts down the local copy of a room another instance now
+// runs.
+func (h *Hub) abandonRoom(roomID string) {
+	h.mu.Lock()
+	room := h.rooms[roomID]
+	delete(h.rooms, roomID)
+	h.mu.Unlock()
+
+	if room == nil {
+		return
+	}
+	room.post(func() { room.close(nil) })
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestClaimDoesNotRunRoomWithoutLease(t *testing.T) {
+	h := newHub()
+	cl := newCluster(h, "test-claim")
+
+	testRedis.SetError("ERR unavailable")
+	claimed, err := cl.claim("CLAIM1")
+	testRedis.SetError("")
+
+	if claimed || err == nil {
+		t.Fatalf("claim with Redis failing = %v, %v; want false and an error", claimed, err)
+	}
+	if _, owned := cl.owned["CLAIM1"]; owned {
+		t.Fatalf("room recorded as owned without a lease")
+	}
+
+	claimed, err = cl.claim("CLAIM1")
+	if !claimed || err != nil {
+		t.Fatalf("claim with Redis back = %v, %v", claimed, err)
+	}
+}
+
+func TestRenewLeasesStopsRoomBeforeLeaseRunsOut(t *testing.T) {
+	h := newHub()
+	cl := newCluster(h, "test-renew")
+	h.cluster = cl
+
+	fresh := openTestRoom(h, "RENEW1")
+	stale := openTestRoom(h, "RENEW2")
+	cl.owned[fresh.ID] = time.Now()
+	cl.owned[stale.ID] = time.Now().Add(-roomLeaseTTL + leaseRenewEvery/2)
+
+	testRedis.SetError("ERR unavailable")
+	cl.renewLeases()
+	testRedis.SetError("")
+
+	waitStopped(t, stale)
+	if h.getRoom(stale.ID) != nil {
+		t.Fatalf("room whose lease was running out is still in the hub")
+	}
+	if h.getRoom(fresh.ID) == nil {
+		t.Fatalf("room with time left on its lease was shut down")
+	}
+	h.closeRoom(fresh, nil)
+}
@@ -0,0 +1,106 @@
+// Command loadtest drives scripted bot players against a running server
+// and fails when latency or errors go past the given limits, so capacity
+// regressions show up before a deploy rather than after:
+//
+//	go run ./cmd/loadtest -url wss://staging.example.com -rooms 50 -players 6 \
+//	    -duration 2m -max-p95 250ms -max-error-rate 0.01
+//
+// Each bot joins, chats, runs the tests, edits over Yjs and votes; the host
+// bot starts the game and calls a meeting halfway through. The report
+// gives latency percentiles per kind of request and what the server's
+// /metrics say the run cost it.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"sort"
+	"time"
+
+	"code-mafia-backend/sim"
+)
+
+func main() {
+	baseURL := flag.String("url", "ws://localhost:8080", "server WebSocket base URL")
+	rooms := flag.Int("rooms", 10, "number of rooms to create")
+	players := flag.Int("players", 5, "bots per room (minimum 3)")
+	duration := flag.Duration("duration", time.Minute, "how long the bots play")
+	chatEvery := flag.Duration("chat", 3*time.Second, "average interval between chat messages per bot")
+	editEvery := flag.Duration("edit", 500*time.Millisecond, "average interval between Yjs edits per bot")
+	testEvery := flag.Duration("tests", 10*time.Second, "average interval between test runs per bot")
+	sampleEvery := flag.Duration("sample", 2*time.Second, "how often to read the server's /metrics")
+	maxP95 := flag.Duration("max-p95", 0, "fail if any kind of request but a whole test run has a p95 latency over this (0 to not check)")
+	maxErrorRate := flag.Float64("max-error-rate", 0, "fail if errors are more than this fraction of messages sent (0 to not check)")
+	minConnected := flag.Float64("min-connected", 1, "fail if fewer than this fraction of the bots connected")
+	asJSON := flag.Bool("json", false, "print the report as JSON")
+	flag.Parse()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	report := sim.Run(ctx, sim.Options{
+		BaseURL:        *baseURL,
+		Rooms:          *rooms,
+		PlayersPerRoom: *players,
+		Duration:       *duration,
+		ChatInterval:   *chatEvery,
+		EditInterval:   *editEvery,
+		TestInterval:   *testEvery,
+		SampleInterval: *sampleEvery,
+	})
+
+	if *asJSON {
+		json.NewEncoder(os.Stdout).Encode(report)
+	} else {
+		fmt.Print(report)
+	}
+
+	failures := check(report, *maxP95, *maxErrorRate, *minConnected)
+	for _, f := range failures {
+		fmt.Fprintln(os.Stderr, "loadtest: FAIL", f)
+	}
+	if len(failures) > 0 {
+		os.Exit(1)
+	}
+}
+
+// check holds a report to the limits, returning what it went over.
+func check(r sim.Report, maxP95 time.Duration, maxErrorRate, minConnected float64) []string {
+	var failures []string
+
+	if want := float64(r.Rooms*r.PlayersPerRoom) * minConnected; float64(r.Connected) < want {
+		failures = append(failures, fmt.Sprintf("%d of %d bots connected", r.Connected, r.Rooms*r.PlayersPerRoom))
+	}
+
+	if maxErrorRate > 0 && r.MessagesSent > 0 {
+		if rate := float64(r.Errors) / float64(r.MessagesSent); rate > maxErrorRate {
+			failures = append(failures, fmt.Sprintf("error rate %.2f%% is over %.2f%%", rate*100, maxErrorRate*100))
+		}
+	}
+
+	if maxP95 > 0 {
+		kinds := make([]string, 0, len(r.Latency))
+		for kind := range r.Latency {
+			kinds = append(kinds, kind)
+		}
+		sort.Strings(kinds)
+
+		limit := float64(maxP95.Microseconds()) / 1000
+		for _, kind := range kinds {
+			// A test run takes as long as the code runner does, which
+			// says nothing about the server's capacity.
+			if kind == "testrun" {
+				continue
+			}
+			if p95 := r.Latency[kind].P95Ms; p95 > limit {
+				failures = append(failures, fmt.Sprintf("%s p95 %.2fms is over %s", kind, p95, maxP95))
+			}
+		}
+	}
+
+	return failures
+}
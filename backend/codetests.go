@@ -0,0 +1,152 @@
+package main
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"os/exec"
+	"time"
+
+	"code-mafia-backend/config"
+	"code-mafia-backend/sandbox"
+)
+
+// heuristicCheckDelay keeps the old diagnostics pause when submissions are
+// only pattern matched, so the terminal doesn't flash straight to a result.
+const heuristicCheckDelay = 5 * time.Second
+
+// codeRunner judges RUN_TESTS for tasks that have tests. While it is nil
+// every task falls back to heuristicRunner.
+var codeRunner sandbox.CodeRunner
+
+// taskTests are each task's unit tests, compiled with the player's code.
+// A test that hangs ends the run, so keep the ones most likely to loop
+// last.
+var taskTests = map[string]string{
+	"task1-sportbrakes": `public class TaskTests {
+    public static void main(String[] args) {
+        Harness h = new Harness();
+        h.test("brakes are installed", 2000, () -> {
+            String out = Harness.capture(() -> new RacingCar("GT-R").applyBrakes());
+            Harness.expect(!out.contains("No brakes installed"), "applyBrakes() found no brakes - does the constructor set them?");
+        });
+        h.test("sport brakes are applied", 2000, () -> {
+            String out = Harness.capture(() -> new RacingCar("GT-R").applyBrakes());
+            Harness.expect(out.contains("Sport brakes applied"), "expected SportBrakes to be applied, got: " + out.trim());
+        });
+        h.finish();
+    }
+}
+`,
+
+	"task2-satellite": `public class TaskTests {
+    public static void main(String[] args) {
+        Harness h = new Harness();
+        h.test("efficiency is not truncated", 2000, () -> {
+            SatelliteSystem.altitude = 2000;
+            String out = Harness.capture(() -> SatelliteSystem.main(new String[0]));
+            Harness.expect(out.contains("Efficiency: 0.5"), "expected \"Efficiency: 0.5\" - watch out for integer division");
+        });
+        h.test("satellite climbs to its target", 2000, () -> {
+            SatelliteSystem.altitude = 2000;
+            Harness.capture(() -> SatelliteSystem.main(new String[0]));
+            int altitude = SatelliteSystem.altitude;
+            Harness.expect(altitude >= 2050 && altitude < 3000, "altitude ended at " + altitude + ", expected it to stop just past 2050");
+        });
+        h.finish();
+    }
+}
+`,
+
+	"task3-oxygen": `public class TaskTests {
+    public static void main(String[] args) {
+        Harness h = new Harness();
+        h.test("every crew member gets oxygen once", 2000, () -> {
+            String out = Harness.capture(() -> new OxygenSystem().distributeOxygen());
+            int handedOut = Harness.count(out, "receiving oxygen");
+            Harness.expect(handedOut == 5, "oxygen was handed out " + handedOut + " times for a crew of 5");
+        });
+        h.test("filtration finishes", 2000, () -> {
+            String out = Harness.capture(() -> new OxygenSystem().filterAir(3));
+            int cycles = Harness.count(out, "Filtering...");
+            Harness.expect(cycles == 3, "expected 3 filtration cycles, got " + cycles);
+        });
+        h.finish();
+    }
+}
+`,
+}
+
+// initCodeRunner picks how RUN_TESTS is judged. Without a Docker binary the
+// server falls back to the heuristic rather than failing every run.
+func initCodeRunner() {
+	cfg := config.AppConfig
+	timeout := time.Duration(cfg.CodeRunnerTimeoutSec) * time.Second
+
+	switch cfg.CodeRunner {
+	case "docker":
+		if _, err := exec.LookPath("docker"); err != nil {
+			log.Printf("⚠️ CODE_RUNNER=docker but no docker binary found, falling back to heuristic checks")
+			return
+		}
+		codeRunner = &sandbox.Docker{
+			Images: map[string]string{
+				"java":       cfg.CodeRunnerImage,
+				"python":     cfg.CodeRunnerPythonImage,
+				"javascript": cfg.CodeRunnerNodeImage,
+				"go":         cfg.CodeRunnerGoImage,
+			},
+			CPUs:     cfg.CodeRunnerCPUs,
+			MemoryMB: cfg.CodeRunnerMemoryMB,
+			Timeout:  timeout,
+		}
+		log.Printf("🧪 Code runner: docker (%s, %s CPU, %dMB, %ds)",
+			cfg.CodeRunnerImage, cfg.CodeRunnerCPUs, cfg.CodeRunnerMemoryMB, cfg.CodeRunnerTimeoutSec)
+
+	case "judge0":
+		codeRunner = &sandbox.Judge0{
+			URL:        cfg.Judge0URL,
+			AuthHeader: cfg.Judge0AuthHeader,
+			AuthToken:  cfg.Judge0AuthToken,
+			MemoryMB:   cfg.CodeRunnerMemoryMB,
+			Timeout:    timeout,
+			Client:     &http.Client{Timeout: 10 * time.Second},
+		}
+		log.Printf("🧪 Code runner: judge0 at %s (%dMB, %ds)", cfg.Judge0URL, cfg.CodeRunnerMemoryMB, cfg.CodeRunnerTimeoutSec)
+
+	default:
+		log.Printf("🧪 Code runner: heuristic checks")
+	}
+}
+
+// heuristicRunner is the original judge: it looks for the stage's fix in
+// the source without running anything. It is used for every task when
+// Docker isn't available, and for season tasks that ship without tests.
+type heuristicRunner struct {
+	stage int
+	// checks replace the stage's built-in checks for library tasks.
+	checks *taskChecks
+}
+
+func (h heuristicRunner) Run(ctx context.Context, sub sandbox.Submission) (sandbox.Result, error) {
+	select {
+	case <-time.After(heuristicCheckDelay):
+	case <-ctx.Done():
+		return sandbox.Result{}, ctx.Err()
+	}
+
+	passed := h.passes(sub.Source)
+	return sandbox.Result{
+		Passed:   passed,
+		Compiled: true,
+		Tests:    []sandbox.TestResult{{Name: "source check", Passed: passed}},
+	}, nil
+}
+
+// passes reports whether source has the fix, without the pause.
+func (h heuristicRunner) passes(source string) bool {
+	if h.checks != nil {
+		return h.checks.passes(source)
+	}
+	return validateStageCode(h.stage, source)
+}
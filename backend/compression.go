@@ -0,0 +1,94 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"net"
+	"net/http"
+
+	"code-mafia-backend/config"
+
+	"github.com/gorilla/websocket"
+)
+
+// socketMetrics names the byte counters of one kind of socket. raw is
+// message payloads as the server reads and writes them; wire is what
+// actually crosses the network, after permessage-deflate and including
+// frame headers, pings and the handshake. Together they show what
+// compression is saving.
+type socketMetrics struct {
+	rawIn, rawOut   string
+	wireIn, wireOut string
+}
+
+var (
+	gameSocket = &socketMetrics{
+		rawIn: "ws_raw_bytes_in_total", rawOut: "ws_raw_bytes_out_total",
+		wireIn: "ws_wire_bytes_in_total", wireOut: "ws_wire_bytes_out_total",
+	}
+	yjsSocket = &socketMetrics{
+		rawIn: "yjs_raw_bytes_in_total", rawOut: "yjs_raw_bytes_out_total",
+		wireIn: "yjs_wire_bytes_in_total", wireOut: "yjs_wire_bytes_out_total",
+	}
+)
+
+// upgrade accepts a WebSocket, offering permessage-deflate when
+// WS_COMPRESSION is on, and counts its traffic under m.
+func upgrade(w http.ResponseWriter, r *http.Request, m *socketMetrics) (*websocket.Conn, error) {
+	cfg := config.AppConfig
+
+	u := upgrader
+	u.EnableCompression = cfg.WSCompression
+	conn, err := u.Upgrade(&countingResponse{ResponseWriter: w, metrics: m}, r, nil)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.WSCompression {
+		conn.SetCompressionLevel(cfg.WSCompressionLevel)
+	}
+	return conn, nil
+}
+
+// prepareWrite decides whether the next message on conn, of size bytes,
+// is worth compressing and counts it. Compression only happens if the
+// client negotiated it.
+func prepareWrite(conn *websocket.Conn, m *socketMetrics, size int) {
+	conn.EnableWriteCompression(size >= config.AppConfig.WSCompressionMinBytes)
+	addMetric(m.rawOut, int64(size))
+}
+
+// countingResponse hands the upgrader a connection that counts the bytes
+// it carries.
+type countingResponse struct {
+	http.ResponseWriter
+	metrics *socketMetrics
+}
+
+func (w *countingResponse) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("response does not implement http.Hijacker")
+	}
+	conn, rw, err := h.Hijack()
+	if err != nil {
+		return nil, nil, err
+	}
+	return &countingConn{Conn: conn, metrics: w.metrics}, rw, nil
+}
+
+type countingConn struct {
+	net.Conn
+	metrics *socketMetrics
+}
+
+func (c *countingConn) Read(p []byte) (int, error) {
+	n, err := c.Conn.Read(p)
+	addMetric(c.metrics.wireIn, int64(n))
+	return n, err
+}
+
+func (c *countingConn) Write(p []byte) (int, error) {
+	n, err := c.Conn.Write(p)
+	addMetric(c.metrics.wireOut, int64(n))
+	return n, err
+}
@@ -1,8 +1,14 @@
 package config
 
 import (
+	"fmt"
 	"log"
+	"net"
+	"net/url"
 	"os"
+	"strconv"
+	"strings"
+	"sync"
 
 	"github.com/joho/godotenv"
 )
@@ -12,40 +18,712 @@ type Config struct {
 	RedisURL      string
 	RedisPassword string
 	RedisDB       int
+	// RedisKeyPrefix goes in front of every key the backend writes, so
+	// several environments can share one Redis. A trailing ":" is added.
+	RedisKeyPrefix string
 
 
 	SupabaseURL        string
 	SupabaseKey        string
 	SupabaseServiceKey string
+	SupabaseJWTSecret  string
+	SupabaseJWKSURL    string
+
+	// RequireAuth turns away players without a valid Supabase token.
+	RequireAuth bool
 
 
 	Port        string
 	Environment string
+	AdminToken  string
+
+	TrustProxyHeaders bool
+	// TrustedProxyHops is how many proxies in front of the server add to
+	// X-Forwarded-For; the client's address is the one the outermost of
+	// them added.
+	TrustedProxyHops int
+
+	// SpectatorDelaySec is the default lag of the streamer feed; hosts can
+	// change it per room.
+	SpectatorDelaySec int
+
+	// ClusterMode lets several instances share rooms through Redis.
+	// InstanceID names this one; it defaults to the hostname.
+	ClusterMode bool
+	InstanceID  string
+
+	// TasksDir holds task definition files that are added to the built-in
+	// tasks; a missing directory just means there are none.
+	TasksDir string
+
+	// ShutdownDrainSec is how long connected players are given, after
+	// being told the server is going down, before their connections close.
+	ShutdownDrainSec int
+
+	// RoomIdleMinutes closes rooms nobody has sent anything in for that
+	// long, and AFKKickMinutes removes players who have gone quiet, both
+	// outside a game. 0 turns either off.
+	RoomIdleMinutes int
+	AFKKickMinutes  int
+
+	// SendBufferSize is how many messages a connection can fall behind
+	// by before SlowClientPolicy applies; SendOverflowMax is how many more
+	// the grow policy holds before giving up on it.
+	SendBufferSize  int
+	SendOverflowMax int
+
+	// WSCompression offers permessage-deflate on /ws, /ws/spectate and
+	// /yjs at WSCompressionLevel (1 fastest to 9 smallest). Messages under
+	// WSCompressionMinBytes go out uncompressed.
+	WSCompression         bool
+	WSCompressionLevel    int
+	WSCompressionMinBytes int
+
+	FCMCredentialsFile string
+	VAPIDPublicKey     string
+	VAPIDPrivateKey    string
+	VAPIDSubject       string
+
+	// PublicURL is where players open the frontend; invite links point here.
+	PublicURL           string
+	SMTPHost            string
+	SMTPPort            int
+	SMTPUsername        string
+	SMTPPassword        string
+	MailFrom            string
+	EmailInvitesPerHour int
+
+	// CodeRunner is "docker" or "judge0" to compile and test submissions,
+	// or "heuristic" to only look for the fix in the source.
+	CodeRunner           string
+	CodeRunnerImage      string
+	CodeRunnerCPUs       string
+	CodeRunnerMemoryMB   int
+	CodeRunnerTimeoutSec int
+	Judge0URL            string
+	Judge0AuthHeader     string
+	Judge0AuthToken      string
+
+	// The Docker images for tasks in languages other than Java, which
+	// runs in CodeRunnerImage.
+	CodeRunnerPythonImage string
+	CodeRunnerNodeImage   string
+	CodeRunnerGoImage     string
+
+	// PracticeBotsMax is how many bots a host can add to a lobby; 0 turns
+	// practice bots off. With BotLLMProvider set ("openai" or "anthropic")
+	// they talk in meetings through that API, otherwise from a script.
+	// BotLLMURL overrides the provider's API base, e.g. for a proxy or an
+	// OpenAI-compatible server.
+	PracticeBotsMax int
+	BotLLMProvider  string
+	BotLLMAPIKey    string
+	BotLLMModel     string
+	BotLLMURL       string
+
+	// TranslationProvider is who translates chat and tasks: "sidecar", the
+	// default, hands them to the translation sidecar over Redis, while
+	// "deepl", "google" or "libretranslate" translates them in-process
+	// through that API, into each of TranslationLanguages.
+	// TranslationURL overrides the API's base, e.g. for a self-hosted
+	// LibreTranslate.
+	TranslationProvider  string
+	TranslationAPIKey    string
+	TranslationURL       string
+	TranslationLanguages []string
+
+	// WordFilterFile is a list of blocked words, one per line, replacing
+	// the built-in list.
+	WordFilterFile string
+
+	// HintChannel is the Redis channel HINT requests are published on for
+	// a sidecar to answer on HintResultChannel, the way chat goes out on
+	// chat:processing. Both get REDIS_KEY_PREFIX in front, like every
+	// channel. Blank turns hints off. A request not answered
+	// within HintTimeoutSec is given up on and costs nothing.
+	HintChannel       string
+	HintResultChannel string
+	HintTimeoutSec    int
+
+	AlertWebhookURL        string
+	AlertSlackWebhookURL   string
+	AlertCooldownMinutes   int
+	AlertStuckPhaseMinutes int
+	AlertRedisDialsPerMin  int
+
+	Tunables
+}
+
+// Tunables can change while the server runs (SIGHUP or the admin reload
+// endpoint). Read them through Current(), never through AppConfig.
+type Tunables struct {
+	LogLevel            string
+	SabotageCooldownSec int
+	AllowedOrigins      []string
+	FeatureFlags        map[string]bool
+
+	RateLimitAPIPerMin    int
+	RateLimitAPIBurst     int
+	RateLimitCreatePerMin int
+	RateLimitCreateBurst  int
+
+	// Per-connection budgets for game WebSocket messages.
+	RateLimitWSPerMin    int
+	RateLimitWSBurst     int
+	RateLimitChatPerMin  int
+	RateLimitChatBurst   int
+	RateLimitTestsPerMin int
+	RateLimitTestsBurst  int
+
+	// ChatMaxLength is the most characters a chat line may have.
+	ChatMaxLength int
+
+	// What the word filter does with a chat line or a username containing
+	// a blocked word: "reject" it, "mask" the words in chat, "flag" it in
+	// the audit log but let it through, or "off".
+	ChatFilterAction     string
+	UsernameFilterAction string
+
+	// ChatHistorySize is how many of a room's latest chat lines a player
+	// joining or reconnecting is sent; 0 sends none.
+	ChatHistorySize int
+
+	// Translations the sidecar fails: how many times each is retried, the
+	// delay before the first retry, which doubles with each one, and how
+	// many given-up ones the dead-letter list keeps.
+	TranslationRetries       int
+	TranslationRetryBaseMs   int
+	TranslationDeadLetterMax int
+
+	// Emergency meetings: how long after one ends or a stage starts before
+	// the next can be called, and how many each player gets per game.
+	EmergencyCooldownSec   int
+	EmergencyStageGraceSec int
+	EmergencyMaxPerPlayer  int
+
+	// TIMER_DRAIN sabotage: seconds it takes off the clock, and uses each
+	// impostor gets per game.
+	TimerDrainSec            int
+	TimerDrainMaxPerImpostor int
+
+	// SlowClientPolicy is what happens to a connection whose send buffer
+	// is full: "disconnect" it, "drop-oldest" of its queued messages, or
+	// "grow" its buffer up to SendOverflowMax.
+	SlowClientPolicy string
+}
+
+func (t Tunables) FeatureEnabled(name string) bool {
+	return t.FeatureFlags[name]
 }
 
 var AppConfig *Config
 
-func Load() {
+var (
+	tunablesMu sync.RWMutex
+	tunables   Tunables
+
+	// processEnv remembers which keys came from the real environment so a
+	// reload lets .env fill gaps without overriding them, same as startup.
+	processEnv map[string]bool
+)
+
+var validLogLevels = map[string]bool{
+	"debug": true,
+	"info":  true,
+	"warn":  true,
+	"error": true,
+}
+
+var validSlowClientPolicies = map[string]bool{
+	"disconnect":  true,
+	"drop-oldest": true,
+	"grow":        true,
+}
+
+// What the word filter does with chat lines and usernames; see
+// wordfilter. Usernames can't be masked.
+var validChatFilterActions = map[string]bool{
+	"off":    true,
+	"reject": true,
+	"mask":   true,
+	"flag":   true,
+}
+
+var validUsernameFilterActions = map[string]bool{
+	"off":    true,
+	"reject": true,
+	"flag":   true,
+}
+
+var validEnvironments = map[string]bool{
+	"development": true,
+	"staging":     true,
+	"production":  true,
+	"test":        true,
+}
+
+// Load reads the environment, applies defaults and validates the result.
+// Every problem is collected so a bad deploy reports all of them at once.
+func Load() error {
+
+	processEnv = make(map[string]bool)
+	for _, kv := range os.Environ() {
+		processEnv[strings.SplitN(kv, "=", 2)[0]] = true
+	}
 
 	godotenv.Load()
 
-	AppConfig = &Config{
+	cfg, err := build()
+	if err != nil {
+		return err
+	}
+
+	AppConfig = cfg
+	setTunables(cfg.Tunables)
+
+	if AppConfig.SupabaseURL == "" {
+		log.Println("WARNING: SUPABASE_URL not set - match history disabled")
+	}
+	if AppConfig.Environment == "production" && AppConfig.allowsAnyOrigin() {
+		log.Println("WARNING: ALLOWED_ORIGINS=* lets any website use players' sessions - list the frontend's origin")
+	}
+
+	log.Printf("Config loaded - Environment: %s, Port: %s", AppConfig.Environment, AppConfig.Port)
+	return nil
+}
+
+func (t Tunables) allowsAnyOrigin() bool {
+	for _, origin := range t.AllowedOrigins {
+		if origin == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+// Reload re-reads the environment and .env file and swaps in the new
+// tunables. Settings outside Tunables need a restart and are left alone.
+// It returns the names of the tunables that changed.
+func Reload() ([]string, error) {
+	if values, err := godotenv.Read(); err == nil {
+		for key, value := range values {
+			if !processEnv[key] {
+				os.Setenv(key, value)
+			}
+		}
+	}
+
+	cfg, err := build()
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.RedisURL != AppConfig.RedisURL || cfg.RedisKeyPrefix != AppConfig.RedisKeyPrefix || cfg.Port != AppConfig.Port || cfg.SupabaseURL != AppConfig.SupabaseURL {
+		log.Println("WARNING: Redis, Supabase and port changes require a restart and were ignored")
+	}
+
+	old := Current()
+	var changed []string
+	if old.LogLevel != cfg.LogLevel {
+		changed = append(changed, "LOG_LEVEL")
+	}
+	if old.SabotageCooldownSec != cfg.SabotageCooldownSec {
+		changed = append(changed, "SABOTAGE_COOLDOWN_SECONDS")
+	}
+	if strings.Join(old.AllowedOrigins, ",") != strings.Join(cfg.AllowedOrigins, ",") {
+		changed = append(changed, "ALLOWED_ORIGINS")
+	}
+	if fmt.Sprint(old.FeatureFlags) != fmt.Sprint(cfg.FeatureFlags) {
+		changed = append(changed, "FEATURE_FLAGS")
+	}
+	if old.RateLimitAPIPerMin != cfg.RateLimitAPIPerMin || old.RateLimitAPIBurst != cfg.RateLimitAPIBurst ||
+		old.RateLimitCreatePerMin != cfg.RateLimitCreatePerMin || old.RateLimitCreateBurst != cfg.RateLimitCreateBurst ||
+		old.RateLimitWSPerMin != cfg.RateLimitWSPerMin || old.RateLimitWSBurst != cfg.RateLimitWSBurst ||
+		old.RateLimitChatPerMin != cfg.RateLimitChatPerMin || old.RateLimitChatBurst != cfg.RateLimitChatBurst ||
+		old.RateLimitTestsPerMin != cfg.RateLimitTestsPerMin || old.RateLimitTestsBurst != cfg.RateLimitTestsBurst {
+		changed = append(changed, "RATE_LIMIT_*")
+	}
+	if old.ChatMaxLength != cfg.ChatMaxLength {
+		changed = append(changed, "CHAT_MAX_LENGTH")
+	}
+	if old.ChatFilterAction != cfg.ChatFilterAction || old.UsernameFilterAction != cfg.UsernameFilterAction {
+		changed = append(changed, "*_FILTER_ACTION")
+	}
+	if old.ChatHistorySize != cfg.ChatHistorySize {
+		changed = append(changed, "CHAT_HISTORY_SIZE")
+	}
+	if old.TranslationRetries != cfg.TranslationRetries || old.TranslationRetryBaseMs != cfg.TranslationRetryBaseMs ||
+		old.TranslationDeadLetterMax != cfg.TranslationDeadLetterMax {
+		changed = append(changed, "TRANSLATION_*")
+	}
+	if old.EmergencyCooldownSec != cfg.EmergencyCooldownSec || old.EmergencyStageGraceSec != cfg.EmergencyStageGraceSec ||
+		old.EmergencyMaxPerPlayer != cfg.EmergencyMaxPerPlayer {
+		changed = append(changed, "EMERGENCY_*")
+	}
+	if old.TimerDrainSec != cfg.TimerDrainSec || old.TimerDrainMaxPerImpostor != cfg.TimerDrainMaxPerImpostor {
+		changed = append(changed, "TIMER_DRAIN_*")
+	}
+	if old.SlowClientPolicy != cfg.SlowClientPolicy {
+		changed = append(changed, "SLOW_CLIENT_POLICY")
+	}
+
+	setTunables(cfg.Tunables)
+	log.Printf("Config reloaded - changed: %v", changed)
+	return changed, nil
+}
+
+func Current() Tunables {
+	tunablesMu.RLock()
+	defer tunablesMu.RUnlock()
+	return tunables
+}
+
+func setTunables(t Tunables) {
+	tunablesMu.Lock()
+	tunables = t
+	tunablesMu.Unlock()
+}
+
+func build() (*Config, error) {
+	p := &envParser{}
+
+	cfg := &Config{
 		RedisURL:           getEnv("REDIS_URL", "localhost:6379"),
 		RedisPassword:      getEnv("REDIS_PASSWORD", ""),
-		RedisDB:            0,
+		RedisDB:            p.int("REDIS_DB", 0),
+		RedisKeyPrefix:     getEnv("REDIS_KEY_PREFIX", ""),
 		SupabaseURL:        getEnv("SUPABASE_URL", ""),
 		SupabaseKey:        getEnv("SUPABASE_KEY", ""),
 		SupabaseServiceKey: getEnv("SUPABASE_SERVICE_KEY", ""),
+		SupabaseJWTSecret:  getEnv("SUPABASE_JWT_SECRET", ""),
+		SupabaseJWKSURL:    getEnv("SUPABASE_JWKS_URL", ""),
+		RequireAuth:        p.bool("REQUIRE_AUTH", false),
 		Port:               getEnv("PORT", "8080"),
 		Environment:        getEnv("ENVIRONMENT", "development"),
+		AdminToken:         getEnv("ADMIN_TOKEN", ""),
+		TrustProxyHeaders:  p.bool("TRUST_PROXY_HEADERS", false),
+		TrustedProxyHops:   p.int("TRUSTED_PROXY_HOPS", 1),
+		SpectatorDelaySec:  p.int("SPECTATOR_DELAY_SECONDS", 30),
+		ClusterMode:        p.bool("CLUSTER_MODE", false),
+		InstanceID:         getEnv("INSTANCE_ID", ""),
+		ShutdownDrainSec:   p.int("SHUTDOWN_DRAIN_SECONDS", 5),
+		RoomIdleMinutes:    p.int("ROOM_IDLE_MINUTES", 30),
+		AFKKickMinutes:     p.int("AFK_KICK_MINUTES", 10),
+		SendBufferSize:     p.int("SEND_BUFFER_SIZE", 256),
+		SendOverflowMax:    p.int("SEND_OVERFLOW_MAX", 4096),
+		TasksDir:           getEnv("TASKS_DIR", "tasks"),
+
+		WSCompression:         p.bool("WS_COMPRESSION", true),
+		WSCompressionLevel:    p.int("WS_COMPRESSION_LEVEL", 1),
+		WSCompressionMinBytes: p.int("WS_COMPRESSION_MIN_BYTES", 256),
+
+		FCMCredentialsFile: getEnv("FCM_CREDENTIALS_FILE", ""),
+		VAPIDPublicKey:     getEnv("VAPID_PUBLIC_KEY", ""),
+		VAPIDPrivateKey:    getEnv("VAPID_PRIVATE_KEY", ""),
+		VAPIDSubject:       getEnv("VAPID_SUBJECT", ""),
+
+		PublicURL:           strings.TrimRight(getEnv("PUBLIC_URL", "http://localhost:5173"), "/"),
+		SMTPHost:            getEnv("SMTP_HOST", ""),
+		SMTPPort:            p.int("SMTP_PORT", 587),
+		SMTPUsername:        getEnv("SMTP_USERNAME", ""),
+		SMTPPassword:        getEnv("SMTP_PASSWORD", ""),
+		MailFrom:            getEnv("MAIL_FROM", ""),
+		EmailInvitesPerHour: p.int("EMAIL_INVITES_PER_HOUR", 30),
+
+		CodeRunner:           strings.ToLower(getEnv("CODE_RUNNER", "docker")),
+		CodeRunnerImage:      getEnv("CODE_RUNNER_IMAGE", "eclipse-temurin:17-jdk"),
+		CodeRunnerCPUs:       getEnv("CODE_RUNNER_CPUS", "1"),
+		CodeRunnerMemoryMB:   p.int("CODE_RUNNER_MEMORY_MB", 256),
+		CodeRunnerTimeoutSec: p.int("CODE_RUNNER_TIMEOUT_SECONDS", 15),
+		Judge0URL:            strings.TrimRight(getEnv("JUDGE0_URL", ""), "/"),
+		Judge0AuthHeader:     getEnv("JUDGE0_AUTH_HEADER", "X-Auth-Token"),
+		Judge0AuthToken:      getEnv("JUDGE0_AUTH_TOKEN", ""),
+
+		CodeRunnerPythonImage: getEnv("CODE_RUNNER_PYTHON_IMAGE", "python:3.12-slim"),
+		CodeRunnerNodeImage:   getEnv("CODE_RUNNER_NODE_IMAGE", "node:20-slim"),
+		CodeRunnerGoImage:     getEnv("CODE_RUNNER_GO_IMAGE", "golang:1.22"),
+
+		PracticeBotsMax: p.int("PRACTICE_BOTS_MAX", 4),
+		BotLLMProvider:  strings.ToLower(getEnv("BOT_LLM_PROVIDER", "")),
+		BotLLMAPIKey:    getEnv("BOT_LLM_API_KEY", ""),
+		BotLLMModel:     getEnv("BOT_LLM_MODEL", ""),
+		BotLLMURL:       strings.TrimRight(getEnv("BOT_LLM_URL", ""), "/"),
+
+		TranslationProvider:  strings.ToLower(getEnv("TRANSLATION_PROVIDER", "sidecar")),
+		TranslationAPIKey:    getEnv("TRANSLATION_API_KEY", ""),
+		TranslationURL:       strings.TrimRight(getEnv("TRANSLATION_URL", ""), "/"),
+		TranslationLanguages: p.list("TRANSLATION_LANGUAGES", "en,hi,de"),
+
+		WordFilterFile: getEnv("WORD_FILTER_FILE", ""),
+
+		HintChannel:       getEnv("HINT_CHANNEL", "hint:request"),
+		HintResultChannel: getEnv("HINT_RESULT_CHANNEL", "hint:results"),
+		HintTimeoutSec:    p.int("HINT_TIMEOUT_SECONDS", 20),
+
+		AlertWebhookURL:        getEnv("ALERT_WEBHOOK_URL", ""),
+		AlertSlackWebhookURL:   getEnv("ALERT_SLACK_WEBHOOK_URL", ""),
+		AlertCooldownMinutes:   p.int("ALERT_COOLDOWN_MINUTES", 5),
+		AlertStuckPhaseMinutes: p.int("ALERT_STUCK_PHASE_MINUTES", 10),
+		AlertRedisDialsPerMin:  p.int("ALERT_REDIS_DIALS_PER_MINUTE", 20),
+
+		Tunables: Tunables{
+			LogLevel:            strings.ToLower(getEnv("LOG_LEVEL", "info")),
+			SabotageCooldownSec: p.int("SABOTAGE_COOLDOWN_SECONDS", 10),
+			AllowedOrigins:      p.list("ALLOWED_ORIGINS", "*"),
+			FeatureFlags:        p.flags("FEATURE_FLAGS"),
+
+			RateLimitAPIPerMin:    p.int("RATE_LIMIT_API_PER_MINUTE", 120),
+			RateLimitAPIBurst:     p.int("RATE_LIMIT_API_BURST", 30),
+			RateLimitCreatePerMin: p.int("RATE_LIMIT_CREATE_PER_MINUTE", 10),
+			RateLimitCreateBurst:  p.int("RATE_LIMIT_CREATE_BURST", 3),
+
+			RateLimitWSPerMin:    p.int("RATE_LIMIT_WS_PER_MINUTE", 600),
+			RateLimitWSBurst:     p.int("RATE_LIMIT_WS_BURST", 30),
+			RateLimitChatPerMin:  p.int("RATE_LIMIT_CHAT_PER_MINUTE", 60),
+			RateLimitChatBurst:   p.int("RATE_LIMIT_CHAT_BURST", 5),
+			RateLimitTestsPerMin: p.int("RATE_LIMIT_TESTS_PER_MINUTE", 6),
+			RateLimitTestsBurst:  p.int("RATE_LIMIT_TESTS_BURST", 1),
+
+			ChatMaxLength:        p.int("CHAT_MAX_LENGTH", 300),
+			ChatFilterAction:     strings.ToLower(getEnv("CHAT_FILTER_ACTION", "mask")),
+			UsernameFilterAction: strings.ToLower(getEnv("USERNAME_FILTER_ACTION", "reject")),
+			ChatHistorySize:      p.int("CHAT_HISTORY_SIZE", 50),
+
+			TranslationRetries:       p.int("TRANSLATION_RETRIES", 3),
+			TranslationRetryBaseMs:   p.int("TRANSLATION_RETRY_BASE_MS", 500),
+			TranslationDeadLetterMax: p.int("TRANSLATION_DEAD_LETTER_MAX", 500),
+
+			EmergencyCooldownSec:   p.int("EMERGENCY_COOLDOWN_SECONDS", 30),
+			EmergencyStageGraceSec: p.int("EMERGENCY_STAGE_GRACE_SECONDS", 15),
+			EmergencyMaxPerPlayer:  p.int("EMERGENCY_MAX_PER_PLAYER", 2),
+
+			TimerDrainSec:            p.int("TIMER_DRAIN_SECONDS", 15),
+			TimerDrainMaxPerImpostor: p.int("TIMER_DRAIN_MAX_PER_IMPOSTOR", 1),
+
+			SlowClientPolicy: strings.ToLower(getEnv("SLOW_CLIENT_POLICY", "disconnect")),
+		},
 	}
 
+	problems := append(p.problems, cfg.Validate()...)
+	if len(problems) > 0 {
+		return nil, fmt.Errorf("invalid configuration:\n  - %s", strings.Join(problems, "\n  - "))
+	}
 
-	if AppConfig.SupabaseURL == "" {
-		log.Println("WARNING: SUPABASE_URL not set - match history disabled")
+	return cfg, nil
+}
+
+func (c *Config) Validate() []string {
+	var problems []string
+
+	if c.RedisURL == "" {
+		problems = append(problems, "REDIS_URL is required")
+	} else if _, port, err := net.SplitHostPort(c.RedisURL); err != nil {
+		problems = append(problems, fmt.Sprintf("REDIS_URL %q must be host:port", c.RedisURL))
+	} else if !validPort(port) {
+		problems = append(problems, fmt.Sprintf("REDIS_URL %q has an invalid port", c.RedisURL))
 	}
 
-	log.Printf("Config loaded - Environment: %s, Port: %s", AppConfig.Environment, AppConfig.Port)
+	if c.RedisDB < 0 || c.RedisDB > 15 {
+		problems = append(problems, fmt.Sprintf("REDIS_DB must be between 0 and 15, got %d", c.RedisDB))
+	}
+	if strings.ContainsAny(c.RedisKeyPrefix, " *?[]") {
+		problems = append(problems, fmt.Sprintf("REDIS_KEY_PREFIX %q must not contain spaces or glob characters", c.RedisKeyPrefix))
+	}
+
+	if (c.SupabaseURL == "") != (c.SupabaseKey == "") {
+		problems = append(problems, "SUPABASE_URL and SUPABASE_KEY must be set together")
+	}
+	if c.SupabaseURL != "" && !validHTTPURL(c.SupabaseURL) {
+		problems = append(problems, fmt.Sprintf("SUPABASE_URL %q must be an http(s) URL", c.SupabaseURL))
+	}
+	if c.Environment == "production" && c.SupabaseURL == "" {
+		problems = append(problems, "SUPABASE_URL and SUPABASE_KEY are required in production")
+	}
+
+	if c.RequireAuth && c.SupabaseJWTSecret == "" && c.SupabaseJWKSURL == "" {
+		problems = append(problems, "REQUIRE_AUTH needs SUPABASE_JWT_SECRET or SUPABASE_JWKS_URL to validate tokens")
+	}
+	if c.SupabaseJWKSURL != "" && !validHTTPURL(c.SupabaseJWKSURL) {
+		problems = append(problems, fmt.Sprintf("SUPABASE_JWKS_URL must be an http(s) URL, got %q", c.SupabaseJWKSURL))
+	}
+
+	if !validPort(c.Port) {
+		problems = append(problems, fmt.Sprintf("PORT %q must be a number between 1 and 65535", c.Port))
+	}
+
+	if !validEnvironments[c.Environment] {
+		problems = append(problems, fmt.Sprintf("ENVIRONMENT %q must be one of development, staging, production, test", c.Environment))
+	}
+
+	if !validLogLevels[c.LogLevel] {
+		problems = append(problems, fmt.Sprintf("LOG_LEVEL %q must be one of debug, info, warn, error", c.LogLevel))
+	}
+
+	problems = append(problems, nonNegative("SABOTAGE_COOLDOWN_SECONDS", c.SabotageCooldownSec)...)
+	problems = append(problems, positive("RATE_LIMIT_API_PER_MINUTE", c.RateLimitAPIPerMin)...)
+	problems = append(problems, positive("RATE_LIMIT_API_BURST", c.RateLimitAPIBurst)...)
+	problems = append(problems, positive("RATE_LIMIT_CREATE_PER_MINUTE", c.RateLimitCreatePerMin)...)
+	problems = append(problems, positive("RATE_LIMIT_CREATE_BURST", c.RateLimitCreateBurst)...)
+	problems = append(problems, positive("RATE_LIMIT_WS_PER_MINUTE", c.RateLimitWSPerMin)...)
+	problems = append(problems, positive("RATE_LIMIT_WS_BURST", c.RateLimitWSBurst)...)
+	problems = append(problems, positive("RATE_LIMIT_CHAT_PER_MINUTE", c.RateLimitChatPerMin)...)
+	problems = append(problems, positive("RATE_LIMIT_CHAT_BURST", c.RateLimitChatBurst)...)
+	problems = append(problems, positive("CHAT_MAX_LENGTH", c.ChatMaxLength)...)
+	if !validChatFilterActions[c.ChatFilterAction] {
+		problems = append(problems, fmt.Sprintf("CHAT_FILTER_ACTION %q must be one of off, reject, mask, flag", c.ChatFilterAction))
+	}
+	if !validUsernameFilterActions[c.UsernameFilterAction] {
+		problems = append(problems, fmt.Sprintf("USERNAME_FILTER_ACTION %q must be one of off, reject, flag", c.UsernameFilterAction))
+	}
+	problems = append(problems, nonNegative("CHAT_HISTORY_SIZE", c.ChatHistorySize)...)
+	problems = append(problems, nonNegative("TRANSLATION_RETRIES", c.TranslationRetries)...)
+	problems = append(problems, positive("TRANSLATION_RETRY_BASE_MS", c.TranslationRetryBaseMs)...)
+	problems = append(problems, positive("TRANSLATION_DEAD_LETTER_MAX", c.TranslationDeadLetterMax)...)
+	problems = append(problems, positive("RATE_LIMIT_TESTS_PER_MINUTE", c.RateLimitTestsPerMin)...)
+	problems = append(problems, positive("RATE_LIMIT_TESTS_BURST", c.RateLimitTestsBurst)...)
+	problems = append(problems, nonNegative("EMERGENCY_COOLDOWN_SECONDS", c.EmergencyCooldownSec)...)
+	problems = append(problems, nonNegative("EMERGENCY_STAGE_GRACE_SECONDS", c.EmergencyStageGraceSec)...)
+	problems = append(problems, positive("EMERGENCY_MAX_PER_PLAYER", c.EmergencyMaxPerPlayer)...)
+	problems = append(problems, positive("TIMER_DRAIN_SECONDS", c.TimerDrainSec)...)
+	problems = append(problems, nonNegative("TIMER_DRAIN_MAX_PER_IMPOSTOR", c.TimerDrainMaxPerImpostor)...)
+	if !validSlowClientPolicies[c.SlowClientPolicy] {
+		problems = append(problems, fmt.Sprintf("SLOW_CLIENT_POLICY %q must be one of disconnect, drop-oldest, grow", c.SlowClientPolicy))
+	}
+
+	if len(c.AllowedOrigins) == 0 {
+		problems = append(problems, "ALLOWED_ORIGINS must list at least one origin (use * to allow all)")
+	}
+	for _, origin := range c.AllowedOrigins {
+		if origin == "*" || strings.HasPrefix(origin, "*.") {
+			continue
+		}
+		if !validHTTPURL(origin) {
+			problems = append(problems, fmt.Sprintf("ALLOWED_ORIGINS entry %q must be *, *.domain or an http(s) origin", origin))
+		}
+	}
+
+	if (c.VAPIDPublicKey == "") != (c.VAPIDPrivateKey == "") {
+		problems = append(problems, "VAPID_PUBLIC_KEY and VAPID_PRIVATE_KEY must be set together")
+	}
+	if c.VAPIDPublicKey != "" && !strings.HasPrefix(c.VAPIDSubject, "mailto:") && !strings.HasPrefix(c.VAPIDSubject, "https://") {
+		problems = append(problems, "VAPID_SUBJECT must be a mailto: or https:// contact when Web Push is enabled")
+	}
+
+	if c.SpectatorDelaySec < 0 || c.SpectatorDelaySec > 300 {
+		problems = append(problems, fmt.Sprintf("SPECTATOR_DELAY_SECONDS must be between 0 and 300, got %d", c.SpectatorDelaySec))
+	}
+	problems = append(problems, positive("TRUSTED_PROXY_HOPS", c.TrustedProxyHops)...)
+	problems = append(problems, nonNegative("SHUTDOWN_DRAIN_SECONDS", c.ShutdownDrainSec)...)
+	problems = append(problems, nonNegative("ROOM_IDLE_MINUTES", c.RoomIdleMinutes)...)
+	problems = append(problems, nonNegative("AFK_KICK_MINUTES", c.AFKKickMinutes)...)
+	problems = append(problems, positive("SEND_BUFFER_SIZE", c.SendBufferSize)...)
+	problems = append(problems, nonNegative("SEND_OVERFLOW_MAX", c.SendOverflowMax)...)
+	if c.WSCompressionLevel < 1 || c.WSCompressionLevel > 9 {
+		problems = append(problems, fmt.Sprintf("WS_COMPRESSION_LEVEL must be between 1 and 9, got %d", c.WSCompressionLevel))
+	}
+	problems = append(problems, nonNegative("WS_COMPRESSION_MIN_BYTES", c.WSCompressionMinBytes)...)
+
+	if !validHTTPURL(c.PublicURL) {
+		problems = append(problems, fmt.Sprintf("PUBLIC_URL %q must be an http(s) URL", c.PublicURL))
+	}
+	if c.SMTPHost != "" {
+		if c.MailFrom == "" {
+			problems = append(problems, "MAIL_FROM is required when SMTP_HOST is set")
+		}
+		if !validPort(strconv.Itoa(c.SMTPPort)) {
+			problems = append(problems, fmt.Sprintf("SMTP_PORT must be between 1 and 65535, got %d", c.SMTPPort))
+		}
+	}
+	problems = append(problems, positive("EMAIL_INVITES_PER_HOUR", c.EmailInvitesPerHour)...)
+
+	switch c.CodeRunner {
+	case "heuristic", "docker":
+	case "judge0":
+		if !validHTTPURL(c.Judge0URL) {
+			problems = append(problems, fmt.Sprintf("CODE_RUNNER=judge0 needs JUDGE0_URL to be an http(s) URL, got %q", c.Judge0URL))
+		}
+	default:
+		problems = append(problems, fmt.Sprintf("CODE_RUNNER %q must be one of heuristic, docker, judge0", c.CodeRunner))
+	}
+	if cpus, err := strconv.ParseFloat(c.CodeRunnerCPUs, 64); err != nil || cpus <= 0 {
+		problems = append(problems, fmt.Sprintf("CODE_RUNNER_CPUS %q must be a positive number", c.CodeRunnerCPUs))
+	}
+	if c.CodeRunnerMemoryMB < 64 {
+		problems = append(problems, fmt.Sprintf("CODE_RUNNER_MEMORY_MB must be at least 64, got %d", c.CodeRunnerMemoryMB))
+	}
+	problems = append(problems, positive("CODE_RUNNER_TIMEOUT_SECONDS", c.CodeRunnerTimeoutSec)...)
+
+	problems = append(problems, nonNegative("PRACTICE_BOTS_MAX", c.PracticeBotsMax)...)
+	switch c.BotLLMProvider {
+	case "":
+	case "openai", "anthropic":
+		if c.BotLLMAPIKey == "" {
+			problems = append(problems, fmt.Sprintf("BOT_LLM_PROVIDER=%s needs BOT_LLM_API_KEY", c.BotLLMProvider))
+		}
+	default:
+		problems = append(problems, fmt.Sprintf("BOT_LLM_PROVIDER %q must be blank, openai or anthropic", c.BotLLMProvider))
+	}
+	problems = append(problems, optionalURL("BOT_LLM_URL", c.BotLLMURL)...)
+
+	switch c.TranslationProvider {
+	case "sidecar", "libretranslate":
+	case "deepl", "google":
+		if c.TranslationAPIKey == "" {
+			problems = append(problems, fmt.Sprintf("TRANSLATION_PROVIDER=%s needs TRANSLATION_API_KEY", c.TranslationProvider))
+		}
+	default:
+		problems = append(problems, fmt.Sprintf("TRANSLATION_PROVIDER %q must be sidecar, deepl, google or libretranslate", c.TranslationProvider))
+	}
+	problems = append(problems, optionalURL("TRANSLATION_URL", c.TranslationURL)...)
+	if c.TranslationProvider != "sidecar" && len(c.TranslationLanguages) == 0 {
+		problems = append(problems, "TRANSLATION_LANGUAGES is required when translating in-process")
+	}
+
+	if c.HintChannel != "" {
+		if c.HintResultChannel == "" {
+			problems = append(problems, "HINT_RESULT_CHANNEL is required when HINT_CHANNEL is set")
+		}
+		problems = append(problems, positive("HINT_TIMEOUT_SECONDS", c.HintTimeoutSec)...)
+	}
+
+	problems = append(problems, optionalURL("ALERT_WEBHOOK_URL", c.AlertWebhookURL)...)
+	problems = append(problems, optionalURL("ALERT_SLACK_WEBHOOK_URL", c.AlertSlackWebhookURL)...)
+	problems = append(problems, nonNegative("ALERT_COOLDOWN_MINUTES", c.AlertCooldownMinutes)...)
+	problems = append(problems, nonNegative("ALERT_STUCK_PHASE_MINUTES", c.AlertStuckPhaseMinutes)...)
+	problems = append(problems, nonNegative("ALERT_REDIS_DIALS_PER_MINUTE", c.AlertRedisDialsPerMin)...)
+
+	return problems
+}
+
+func optionalURL(key, value string) []string {
+	if value != "" && !validHTTPURL(value) {
+		return []string{fmt.Sprintf("%s %q must be an http(s) URL", key, value)}
+	}
+	return nil
+}
+
+func nonNegative(key string, value int) []string {
+	if value < 0 {
+		return []string{fmt.Sprintf("%s must not be negative, got %d", key, value)}
+	}
+	return nil
+}
+
+func positive(key string, value int) []string {
+	if value <= 0 {
+		return []string{fmt.Sprintf("%s must be greater than zero, got %d", key, value)}
+	}
+	return nil
+}
+
+func validPort(port string) bool {
+	n, err := strconv.Atoi(port)
+	return err == nil && n >= 1 && n <= 65535
+}
+
+func validHTTPURL(raw string) bool {
+	u, err := url.Parse(raw)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
 }
 
 func getEnv(key, fallback string) string {
@@ -53,4 +731,59 @@ func getEnv(key, fallback string) string {
 		return value
 	}
 	return fallback
-}
\ No newline at end of file
+}
+
+// envParser reads typed values and records malformed ones instead of
+// silently falling back, so Load can report them together.
+type envParser struct {
+	problems []string
+}
+
+func (p *envParser) int(key string, fallback int) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		p.problems = append(p.problems, fmt.Sprintf("%s=%q is not a number", key, value))
+		return fallback
+	}
+	return parsed
+}
+
+func (p *envParser) bool(key string, fallback bool) bool {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		p.problems = append(p.problems, fmt.Sprintf("%s=%q is not a boolean", key, value))
+		return fallback
+	}
+	return parsed
+}
+
+func (p *envParser) list(key, fallback string) []string {
+	var items []string
+	for _, item := range strings.Split(getEnv(key, fallback), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// flags parses FEATURE_FLAGS=a,b,-c into {a: true, b: true, c: false}.
+func (p *envParser) flags(key string) map[string]bool {
+	flags := make(map[string]bool)
+	for _, item := range p.list(key, "") {
+		if strings.HasPrefix(item, "-") {
+			flags[strings.TrimPrefix(item, "-")] = false
+		} else {
+			flags[item] = true
+		}
+	}
+	return flags
+}
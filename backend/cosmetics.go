@@ -0,0 +1,308 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+
+	"code-mafia-backend/auth"
+	"code-mafia-backend/database"
+)
+
+const (
+	slotNameColor   = "name_color"
+	slotAvatar      = "avatar"
+	slotEditorTheme = "editor_theme"
+)
+
+// Unlock is what a player must reach to earn a cosmetic. Zero fields are
+// not required; a Cosmetic with no requirements is owned by everyone.
+type Unlock struct {
+	XP           int `json:"xp,omitempty"`
+	GamesPlayed  int `json:"gamesPlayed,omitempty"`
+	GamesWon     int `json:"gamesWon,omitempty"`
+	ImpostorWins int `json:"impostorWins,omitempty"`
+	CorrectVotes int `json:"correctVotes,omitempty"`
+	RankedGames  int `json:"rankedGames,omitempty"`
+}
+
+func (u Unlock) free() bool {
+	return u == Unlock{}
+}
+
+func (u Unlock) metBy(stats *database.PlayerStats) bool {
+	return playerXP(stats) >= u.XP &&
+		stats.GamesPlayed >= u.GamesPlayed &&
+		stats.GamesWon >= u.GamesWon &&
+		stats.ByRole["IMPOSTER"].Won >= u.ImpostorWins &&
+		stats.CorrectVotes >= u.CorrectVotes &&
+		stats.RankedGames >= u.RankedGames
+}
+
+type Cosmetic struct {
+	ID          string `json:"id"`
+	Slot        string `json:"slot"`
+	Name        string `json:"name"`
+	Value       string `json:"value"`
+	Requirement string `json:"requirement,omitempty"`
+	Unlock      Unlock `json:"unlock"`
+}
+
+var cosmeticCatalog = []Cosmetic{
+	{ID: "color_white", Slot: slotNameColor, Name: "Standard Issue", Value: "#e5e7eb"},
+	{ID: "color_cyan", Slot: slotNameColor, Name: "Ion Cyan", Value: "#22d3ee", Requirement: "Reach 100 XP", Unlock: Unlock{XP: 100}},
+	{ID: "color_gold", Slot: slotNameColor, Name: "Captain's Gold", Value: "#facc15", Requirement: "Win 25 games", Unlock: Unlock{GamesWon: 25}},
+	{ID: "color_crimson", Slot: slotNameColor, Name: "Saboteur Crimson", Value: "#ef4444", Requirement: "Win 10 games as the impostor", Unlock: Unlock{ImpostorWins: 10}},
+
+	{ID: "avatar_crewmate", Slot: slotAvatar, Name: "Crewmate", Value: "crewmate"},
+	{ID: "avatar_astronaut", Slot: slotAvatar, Name: "Astronaut", Value: "astronaut", Requirement: "Reach 250 XP", Unlock: Unlock{XP: 250}},
+	{ID: "avatar_detective", Slot: slotAvatar, Name: "Detective", Value: "detective", Requirement: "Vote out the impostor 25 times", Unlock: Unlock{CorrectVotes: 25}},
+	{ID: "avatar_hacker", Slot: slotAvatar, Name: "Hacker", Value: "hacker", Requirement: "Win 5 games as the impostor", Unlock: Unlock{ImpostorWins: 5}},
+
+	{ID: "theme_default", Slot: slotEditorTheme, Name: "Ship Default", Value: "vs-dark"},
+	{ID: "theme_midnight", Slot: slotEditorTheme, Name: "Midnight", Value: "midnight", Requirement: "Reach 500 XP", Unlock: Unlock{XP: 500}},
+	{ID: "theme_terminal", Slot: slotEditorTheme, Name: "Green Terminal", Value: "terminal", Requirement: "Play 50 games", Unlock: Unlock{GamesPlayed: 50}},
+	{ID: "theme_ranked", Slot: slotEditorTheme, Name: "Ranked Gold", Value: "ranked-gold", Requirement: "Play 20 ranked games", Unlock: Unlock{RankedGames: 20}},
+}
+
+func findCosmetic(id string) (Cosmetic, bool) {
+	for _, c := range cosmeticCatalog {
+		if c.ID == id {
+			return c, true
+		}
+	}
+	return Cosmetic{}, false
+}
+
+// playerXP is derived from match history, so it never needs migrating
+// when the formula changes.
+func playerXP(stats *database.PlayerStats) int {
+	return stats.GamesPlayed*10 + stats.GamesWon*15 + stats.CorrectVotes*5
+}
+
+// Cosmetics is what other players see of someone's loadout, as values
+// the client can render directly.
+type Cosmetics struct {
+	NameColor   string `json:"nameColor,omitempty"`
+	Avatar      string `json:"avatar,omitempty"`
+	EditorTheme string `json:"editorTheme,omitempty"`
+}
+
+func cosmeticsFor(loadout *database.Loadout) *Cosmetics {
+	if loadout == nil {
+		return nil
+	}
+
+	value := func(id string) string {
+		if c, ok := findCosmetic(id); ok {
+			return c.Value
+		}
+		return ""
+	}
+	c := &Cosmetics{
+		NameColor:   value(loadout.NameColor),
+		Avatar:      value(loadout.Avatar),
+		EditorTheme: value(loadout.EditorTheme),
+	}
+	if *c == (Cosmetics{}) {
+		return nil
+	}
+	return c
+}
+
+// syncUnlocks grants whatever the player's stats now earn and returns the
+// IDs they own along with the ones that are new.
+func syncUnlocks(userID string) (owned map[string]bool, newlyUnlocked []string, stats *database.PlayerStats, err error) {
+	stats, err = database.GetPlayerStats(userID)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	rows, err := database.ListUnlockedCosmetics(userID)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	owned = make(map[string]bool, len(rows))
+	for _, row := range rows {
+		owned[row.CosmeticID] = true
+	}
+
+	for _, c := range cosmeticCatalog {
+		if c.Unlock.free() {
+			owned[c.ID] = true
+		} else if !owned[c.ID] && c.Unlock.metBy(stats) {
+			newlyUnlocked = append(newlyUnlocked, c.ID)
+			owned[c.ID] = true
+		}
+	}
+
+	if err := database.UnlockCosmetics(userID, newlyUnlocked); err != nil {
+		return nil, nil, nil, err
+	}
+	return owned, newlyUnlocked, stats, nil
+}
+
+// loadCosmetics fetches a signed-in player's equipped cosmetics for the
+// player list. Failures just mean no cosmetics are shown.
+func loadCosmetics(userID string) *Cosmetics {
+	if database.SupabaseClient == nil {
+		return nil
+	}
+	loadout, err := database.GetLoadout(userID)
+	if err != nil {
+		log.Printf("Failed to load cosmetics for %s: %v", userID, err)
+		return nil
+	}
+	return cosmeticsFor(loadout)
+}
+
+func (r *Room) setCosmetics(playerID string, cosmetics *Cosmetics) {
+	if player, ok := r.players[playerID]; ok {
+		player.Cosmetics = cosmetics
+		r.saveToRedis()
+	}
+}
+
+// signedInClients maps the room's signed-in players to their connections.
+func (r *Room) signedInClients() map[string]*Client {
+	accounts := make(map[string]*Client)
+	for client := range r.clients {
+		if client.Authenticated {
+			accounts[client.PlayerID] = client
+		}
+	}
+	return accounts
+}
+
+// grantUnlocks runs after a match is saved and tells signed-in players
+// about anything they just earned.
+func grantUnlocks(accounts map[string]*Client) {
+	for userID, client := range accounts {
+		_, unlocked, _, err := syncUnlocks(userID)
+		if err != nil {
+			log.Printf("Failed to sync unlocks for %s: %v", userID, err)
+			continue
+		}
+		if len(unlocked) == 0 {
+			continue
+		}
+
+		items := make([]Cosmetic, 0, len(unlocked))
+		for _, id := range unlocked {
+			c, _ := findCosmetic(id)
+			items = append(items, c)
+		}
+		client.sendMessage(Message{
+			Type: "COSMETICS_UNLOCKED",
+			Data: map[string]interface{}{"cosmetics": items},
+		})
+		log.Printf("🎨 %s unlocked %v", userID, unlocked)
+	}
+}
+
+func handleGetInventory(w http.ResponseWriter, r *http.Request, claims *auth.Claims) {
+	owned, _, stats, err := syncUnlocks(claims.Subject)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	loadout, err := database.GetLoadout(claims.Subject)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	if loadout == nil {
+		loadout = &database.Loadout{UserID: claims.Subject}
+	}
+
+	type item struct {
+		Cosmetic
+		Owned bool `json:"owned"`
+	}
+	items := make([]item, len(cosmeticCatalog))
+	for i, c := range cosmeticCatalog {
+		items[i] = item{Cosmetic: c, Owned: owned[c.ID]}
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"xp":        playerXP(stats),
+		"cosmetics": items,
+		"equipped": map[string]string{
+			slotNameColor:   loadout.NameColor,
+			slotAvatar:      loadout.Avatar,
+			slotEditorTheme: loadout.EditorTheme,
+		},
+	})
+}
+
+// handleEquipCosmetics sets the loadout. Each slot takes a cosmetic ID of
+// that slot the player owns, or "" for the default; omitted slots are
+// left as they are. A player in a room shows up with it right away.
+func (h *Hub) handleEquipCosmetics(w http.ResponseWriter, r *http.Request, claims *auth.Claims) {
+	var req map[string]string
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"error": "invalid request body"})
+		return
+	}
+
+	owned, _, _, err := syncUnlocks(claims.Subject)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	loadout, err := database.GetLoadout(claims.Subject)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	if loadout == nil {
+		loadout = &database.Loadout{}
+	}
+	loadout.UserID = claims.Subject
+
+	slots := map[string]*string{
+		slotNameColor:   &loadout.NameColor,
+		slotAvatar:      &loadout.Avatar,
+		slotEditorTheme: &loadout.EditorTheme,
+	}
+	for slot, id := range req {
+		equipped, ok := slots[slot]
+		if !ok {
+			writeJSON(w, http.StatusBadRequest, map[string]interface{}{"error": "unknown slot: " + slot})
+			return
+		}
+		if id != "" {
+			c, ok := findCosmetic(id)
+			if !ok || c.Slot != slot {
+				writeJSON(w, http.StatusBadRequest, map[string]interface{}{"error": "unknown " + slot + " cosmetic: " + id})
+				return
+			}
+			if !owned[id] {
+				writeJSON(w, http.StatusForbidden, map[string]interface{}{"error": "you haven't unlocked " + c.Name + " yet"})
+				return
+			}
+		}
+		*equipped = id
+	}
+
+	loadout.UpdatedAt = time.Now()
+	if err := database.SaveLoadout(*loadout); err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	if room := h.getRoom(h.presence.roomOf(claims.Subject)); room != nil {
+		room.setCosmetics(claims.Subject, cosmeticsFor(loadout))
+		room.broadcastPlayerList()
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"equipped": map[string]string{
+			slotNameColor:   loadout.NameColor,
+			slotAvatar:      loadout.Avatar,
+			slotEditorTheme: loadout.EditorTheme,
+		},
+		"cosmetics": cosmeticsFor(loadout),
+	})
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"time"
+)
+
+// A CRITICAL sabotage is a meltdown the crew has criticalDuration to stop,
+// by criticalRepairsNeeded different crewmates each sending REPAIR. If they
+// don't, the impostors win.
+const (
+	criticalDuration      = 30 * time.Second
+	criticalRepairsNeeded = 2
+)
+
+// handleCriticalSabotage starts the meltdown countdown. Runs on the room
+// goroutine.
+func (r *Room) handleCriticalSabotage() {
+	log.Printf("CRITICAL sabotage activated in room %s - %s to repair", r.ID, criticalDuration)
+
+	r.repairedIDs = nil
+	r.sabotageEndTime = time.Now().Add(criticalDuration)
+	r.saveToRedis()
+	r.armCritical()
+
+	startMsg := Message{
+		Type: "SABOTAGE_STARTED",
+		Data: map[string]interface{}{
+			"type":     "CRITICAL",
+			"duration": criticalDuration.Milliseconds(),
+		},
+	}
+	data, _ := json.Marshal(startMsg)
+	r.emit(data)
+
+	r.emitSystemChat("system.meltdown", map[string]interface{}{"repairs": criticalRepairsNeeded, "seconds": int(criticalDuration.Seconds())})
+}
+
+// armCritical schedules the meltdown for sabotageEndTime and starts the
+// countdown broadcasts, for a new sabotage or one resumed after a restart.
+// Runs on the room goroutine.
+func (r *Room) armCritical() {
+	r.sabotageTimer = r.schedule(time.Until(r.sabotageEndTime), r.meltdown)
+	r.criticalCountdown(r.game, r.sabotageEndTime)
+}
+
+// criticalCountdown sends CRITICAL_COUNTDOWN once a second until the
+// sabotage that ends at ends is over, one way or the other.
+func (r *Room) criticalCountdown(game int, ends time.Time) {
+	if r.game != game || r.sabotageType != "CRITICAL" || !r.sabotageEndTime.Equal(ends) ||
+		r.gameState.Phase == PhaseEnd {
+		return
+	}
+	r.sendCriticalCountdown()
+	r.schedule(time.Second, func() { r.criticalCountdown(game, ends) })
+}
+
+func (r *Room) sendCriticalCountdown() {
+	seconds := int(time.Until(r.sabotageEndTime).Round(time.Second).Seconds())
+	if seconds < 0 {
+		seconds = 0
+	}
+
+	msg := Message{
+		Type: "CRITICAL_COUNTDOWN",
+		Data: map[string]interface{}{
+			"remainingSeconds": seconds,
+			"repairs":          len(r.repairedIDs),
+			"repairsNeeded":    criticalRepairsNeeded,
+		},
+	}
+	data, _ := json.Marshal(msg)
+	r.emit(data)
+}
+
+// handleRepair counts playerID's repair towards stopping a CRITICAL
+// sabotage, or returns why it doesn't count. Runs on the room goroutine.
+func (r *Room) handleRepair(playerID string) string {
+	if !r.sabotageActive || r.sabotageType != "CRITICAL" || r.gameState.Phase == PhaseEnd {
+		return "There is nothing to repair"
+	}
+	player := r.players[playerID]
+	if player == nil || player.IsEliminated || player.Role == "IMPOSTER" {
+		return "Only crewmates still in the game can repair"
+	}
+	for _, id := range r.repairedIDs {
+		if id == playerID {
+			return "You have already repaired - another crewmate is needed"
+		}
+	}
+
+	r.repairedIDs = append(r.repairedIDs, playerID)
+	log.Printf("REPAIR by %s in room %s (%d/%d)", player.Username, r.ID, len(r.repairedIDs), criticalRepairsNeeded)
+	r.audit("REPAIR", playerID, player.Username, nil)
+
+	if len(r.repairedIDs) >= criticalRepairsNeeded {
+		r.endCritical()
+		return ""
+	}
+	r.saveToRedis()
+	r.sendCriticalCountdown()
+	return ""
+}
+
+// endCritical stops the meltdown once the crew has repaired it. Runs on
+// the room goroutine.
+func (r *Room) endCritical() {
+	if r.sabotageTimer != nil {
+		r.sabotageTimer.Stop()
+		r.sabotageTimer = nil
+	}
+	r.sabotageActive = false
+	r.sabotageType = ""
+	r.repairedIDs = nil
+	r.survivedSabotage()
+	r.saveToRedis()
+
+	endMsg := Message{
+		Type: "SABOTAGE_ENDED",
+		Data: map[string]interface{}{
+			"type": "CRITICAL",
+		},
+	}
+	endData, _ := json.Marshal(endMsg)
+	r.emit(endData)
+
+	r.emitSystemChat("system.meltdown_averted", nil)
+
+	log.Printf("CRITICAL sabotage repaired in room %s", r.ID)
+}
+
+// meltdown ends the game for the impostors when a CRITICAL sabotage runs
+// out unrepaired. Runs on the room goroutine.
+func (r *Room) meltdown() {
+	r.sabotageTimer = nil
+	if r.sabotageType != "CRITICAL" || r.gameState.Phase == PhaseEnd {
+		return
+	}
+	log.Printf("CRITICAL sabotage not repaired in room %s (%d/%d)", r.ID, len(r.repairedIDs), criticalRepairsNeeded)
+
+	r.sabotageActive = false
+	r.sabotageType = ""
+	r.repairedIDs = nil
+	r.endGame("IMPOSTER_WIN_SABOTAGE")
+}
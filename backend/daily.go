@@ -0,0 +1,256 @@
+package main
+
+import (
+	"hash/fnv"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"code-mafia-backend/database"
+
+	"github.com/gorilla/mux"
+)
+
+// Rooms whose code starts with dailyRoomPrefix play the daily challenge.
+const dailyRoomPrefix = "DAILY-"
+
+const dailyDateFormat = "2006-01-02"
+
+type ChallengeModifier struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+
+	TimerSeconds        int  `json:"-"`
+	SabotageCooldownSec int  `json:"-"`
+	RadioSilence        bool `json:"-"`
+}
+
+var challengeModifiers = []ChallengeModifier{
+	{
+		ID:           "time_crunch",
+		Name:         "Time Crunch",
+		Description:  "The reactor timer starts at 75 seconds.",
+		TimerSeconds: 75,
+	},
+	{
+		ID:                  "sabotage_frenzy",
+		Name:                "Sabotage Frenzy",
+		Description:         "The impostor can sabotage every 20 seconds.",
+		SabotageCooldownSec: 20,
+	},
+	{
+		ID:           "radio_silence",
+		Name:         "Radio Silence",
+		Description:  "Chat is closed while tasks are in progress.",
+		RadioSilence: true,
+	},
+}
+
+type DailyChallenge struct {
+	Date     string            `json:"date"`
+	TaskIDs  []string          `json:"taskIds"`
+	Modifier ChallengeModifier `json:"modifier"`
+}
+
+// dailyChallengeFor picks the challenge for the UTC date of t. The choice
+// is a pure function of the date so every server instance agrees on it.
+func dailyChallengeFor(t time.Time) DailyChallenge {
+	date := t.UTC().Format(dailyDateFormat)
+
+	h := fnv.New64a()
+	h.Write([]byte("daily:" + date))
+	seed := h.Sum64()
+
+	byStage := make(map[int][]*Task)
+	maxStage := 0
+	for _, task := range currentTaskPool() {
+		byStage[task.Stage] = append(byStage[task.Stage], task)
+		if task.Stage > maxStage {
+			maxStage = task.Stage
+		}
+	}
+
+	taskIDs := make([]string, 0, maxStage)
+	for stage := 1; stage <= maxStage; stage++ {
+		candidates := byStage[stage]
+		if len(candidates) == 0 {
+			continue
+		}
+		taskIDs = append(taskIDs, candidates[(seed>>uint(stage*8))%uint64(len(candidates))].ID)
+	}
+
+	return DailyChallenge{
+		Date:     date,
+		TaskIDs:  taskIDs,
+		Modifier: challengeModifiers[seed%uint64(len(challengeModifiers))],
+	}
+}
+
+func isDailyRoom(roomID string) bool {
+	return strings.HasPrefix(strings.ToUpper(roomID), dailyRoomPrefix)
+}
+
+// challengeTasks narrows the full task list down to the challenge's set.
+func (c *DailyChallenge) challengeTasks(all []*Task) []*Task {
+	wanted := make(map[string]bool, len(c.TaskIDs))
+	for _, id := range c.TaskIDs {
+		wanted[id] = true
+	}
+
+	tasks := make([]*Task, 0, len(c.TaskIDs))
+	for _, task := range all {
+		if wanted[task.ID] {
+			tasks = append(tasks, task)
+		}
+	}
+	return tasks
+}
+
+func (r *Room) gameDurationSeconds() int {
+	if r.challenge != nil && r.challenge.Modifier.TimerSeconds > 0 {
+		return r.challenge.Modifier.TimerSeconds
+	}
+	if s := r.gameState.Settings.TimerSeconds; s > 0 {
+		return s
+	}
+	return defaultGameSeconds
+}
+
+// applyChallenge sets up the modifier for a new game. Runs on the room
+// goroutine.
+func (r *Room) applyChallenge() {
+	if r.challenge == nil {
+		return
+	}
+
+	r.tasks = r.challenge.challengeTasks(r.tasks)
+
+	m := r.challenge.Modifier
+	if m.SabotageCooldownSec > 0 {
+		r.sabotageCooldownSec = m.SabotageCooldownSec
+	}
+
+	log.Printf("📅 Room %s playing daily challenge %s (%s)", r.ID, r.challenge.Date, m.ID)
+}
+
+func (r *Room) chatSilenced() bool {
+	if r.challenge == nil || !r.challenge.Modifier.RadioSilence {
+		return false
+	}
+
+	switch r.gameState.Phase {
+	case PhaseTask1, PhaseTask2, PhaseTask3:
+		return true
+	}
+	return false
+}
+
+// dailyResults is each player's completion of the room's challenge. It is
+// built on the room goroutine and saved off it by recordDailyResults.
+func (r *Room) dailyResults(winnerRole string, duration, stagesCompleted int) []database.DailyCompletion {
+	results := make([]database.DailyCompletion, 0, len(r.players))
+	for _, player := range r.players {
+		results = append(results, database.DailyCompletion{
+			PlayerID:        player.ID,
+			Username:        player.Username,
+			Role:            player.Role,
+			Won:             roleTeam(player.Role) == winnerRole,
+			Seconds:         duration,
+			StagesCompleted: stagesCompleted,
+			CompletedAt:     time.Now(),
+		})
+	}
+	return results
+}
+
+func recordDailyResults(date string, results []database.DailyCompletion) {
+	for _, result := range results {
+		if err := database.RecordDailyCompletion(date, result); err != nil {
+			log.Printf("Failed to record daily result for %s: %v", result.PlayerID, err)
+		}
+	}
+
+	log.Printf("📅 Recorded %d daily results for %s", len(results), date)
+}
+
+func dailyDateParam(r *http.Request) (string, bool) {
+	date := r.URL.Query().Get("date")
+	if date == "" {
+		return time.Now().UTC().Format(dailyDateFormat), true
+	}
+	if _, err := time.Parse(dailyDateFormat, date); err != nil {
+		return "", false
+	}
+	return date, true
+}
+
+func handleGetDaily(w http.ResponseWriter, r *http.Request) {
+	challenge := dailyChallengeFor(time.Now())
+
+	completions, err := database.CountDailyCompletions(challenge.Date)
+	if err != nil {
+		log.Printf("Failed to count daily completions: %v", err)
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"challenge":   challenge,
+		"roomPrefix":  dailyRoomPrefix,
+		"completions": completions,
+	})
+}
+
+func handleDailyLeaderboard(w http.ResponseWriter, r *http.Request) {
+	date, ok := dailyDateParam(r)
+	if !ok {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"error": "date must be YYYY-MM-DD"})
+		return
+	}
+
+	limit := 50
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxPageSize {
+			writeJSON(w, http.StatusBadRequest, map[string]interface{}{
+				"error": "limit must be between 1 and " + strconv.Itoa(maxPageSize),
+			})
+			return
+		}
+		limit = n
+	}
+
+	entries, err := database.GetDailyLeaderboard(date, limit)
+	if err != nil {
+		log.Printf("Daily leaderboard query failed: %v", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"error": "failed to load leaderboard"})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"date":    date,
+		"entries": entries,
+	})
+}
+
+func handleDailyCompletion(w http.ResponseWriter, r *http.Request) {
+	date, ok := dailyDateParam(r)
+	if !ok {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"error": "date must be YYYY-MM-DD"})
+		return
+	}
+
+	completion, err := database.GetDailyCompletion(date, mux.Vars(r)["id"])
+	if err != nil {
+		log.Printf("Daily completion query failed: %v", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"error": "failed to load completion"})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"date":       date,
+		"completed":  completion != nil,
+		"completion": completion,
+	})
+}
@@ -0,0 +1,158 @@
+package database
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	BanKindIP   = "ip"
+	BanKindUser = "user"
+
+	bansIndexKey = "bans:index"
+)
+
+type Ban struct {
+	Kind      string     `json:"kind"`
+	Value     string     `json:"value"`
+	Reason    string     `json:"reason"`
+	CreatedBy string     `json:"createdBy,omitempty"`
+	CreatedAt time.Time  `json:"createdAt"`
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
+}
+
+func BanKey(kind, value string) string {
+	return keyf("ban:%s:%s", kind, value)
+}
+
+// SaveBan stores a ban. Expiring bans get a matching Redis TTL so they
+// disappear on their own; the index set is pruned lazily by ListBans.
+func SaveBan(ban Ban) error {
+	if ban.Kind != BanKindIP && ban.Kind != BanKindUser {
+		return fmt.Errorf("unknown ban kind %q", ban.Kind)
+	}
+
+	jsonData, err := json.Marshal(ban)
+	if err != nil {
+		return fmt.Errorf("failed to marshal ban: %w", err)
+	}
+
+	var ttl time.Duration
+	if ban.ExpiresAt != nil {
+		ttl = time.Until(*ban.ExpiresAt)
+		if ttl <= 0 {
+			return fmt.Errorf("ban already expired")
+		}
+	}
+
+	key := BanKey(ban.Kind, ban.Value)
+	if err := RDB.Set(ctx, key, jsonData, ttl).Err(); err != nil {
+		return fmt.Errorf("failed to save ban: %w", err)
+	}
+	if err := RDB.SAdd(ctx, Key(bansIndexKey), key).Err(); err != nil {
+		return fmt.Errorf("failed to index ban: %w", err)
+	}
+
+	return nil
+}
+
+// GetBan returns the active ban for kind/value, or nil if there is none.
+func GetBan(kind, value string) (*Ban, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	jsonData, err := RDB.Get(ctx, BanKey(kind, value)).Result()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load ban: %w", err)
+	}
+
+	var ban Ban
+	if err := json.Unmarshal([]byte(jsonData), &ban); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal ban: %w", err)
+	}
+
+	return &ban, nil
+}
+
+func DeleteBan(kind, value string) error {
+	key := BanKey(kind, value)
+	if err := RDB.Del(ctx, key).Err(); err != nil {
+		return fmt.Errorf("failed to delete ban: %w", err)
+	}
+	return RDB.SRem(ctx, Key(bansIndexKey), key).Err()
+}
+
+func ListBans() ([]Ban, error) {
+	keys, err := RDB.SMembers(ctx, Key(bansIndexKey)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list bans: %w", err)
+	}
+
+	bans := make([]Ban, 0, len(keys))
+	for _, key := range keys {
+		jsonData, err := RDB.Get(ctx, key).Result()
+		if err == redis.Nil {
+			RDB.SRem(ctx, Key(bansIndexKey), key)
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to load ban %s: %w", key, err)
+		}
+
+		var ban Ban
+		if err := json.Unmarshal([]byte(jsonData), &ban); err == nil {
+			bans = append(bans, ban)
+		}
+	}
+
+	return bans, nil
+}
+
+// roomBanTTL keeps a room's bans around for as long as its code is likely
+// to be in use, past the 5 minute cleanup after a game ends.
+const roomBanTTL = 24 * time.Hour
+
+func RoomBansKey(roomID string) string {
+	return keyf("room:%s:bans", roomID)
+}
+
+// BanFromRoom keeps identities, such as "user:<id>" or "ip:<addr>", out of
+// one room.
+func BanFromRoom(roomID string, identities ...string) error {
+	if len(identities) == 0 {
+		return nil
+	}
+
+	members := make([]interface{}, len(identities))
+	for i, identity := range identities {
+		members[i] = identity
+	}
+
+	key := RoomBansKey(roomID)
+	if err := RDB.SAdd(ctx, key, members...).Err(); err != nil {
+		return fmt.Errorf("failed to save room ban: %w", err)
+	}
+	return RDB.Expire(ctx, key, roomBanTTL).Err()
+}
+
+// BannedFromRoom reports whether any of identities is banned from roomID.
+func BannedFromRoom(roomID string, identities ...string) (bool, error) {
+	key := RoomBansKey(roomID)
+	for _, identity := range identities {
+		banned, err := RDB.SIsMember(ctx, key, identity).Result()
+		if err != nil {
+			return false, fmt.Errorf("failed to check room ban: %w", err)
+		}
+		if banned {
+			return true, nil
+		}
+	}
+	return false, nil
+}
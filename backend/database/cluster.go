@@ -0,0 +1,117 @@
+package database
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// A room's lease names the instance that runs its game. Renewing and
+// releasing only touch the lease if it is still ours, so an instance that
+// stalled past its TTL can't take a room back from its new owner.
+var (
+	renewLease = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return 0`)
+
+	releaseLease = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0`)
+)
+
+func RoomOwnerKey(roomID string) string {
+	return keyf("room:%s:owner", roomID)
+}
+
+func InstanceKey(instanceID string) string {
+	return keyf("instance:%s:alive", instanceID)
+}
+
+// RoomInboxChannel carries relayed players' joins, messages and leaves to
+// the room's owner.
+func RoomInboxChannel(roomID string) string {
+	return keyf("room:%s:inbox", roomID)
+}
+
+// RoomFanoutChannel carries what the room's owner sends to players
+// connected to other instances.
+func RoomFanoutChannel(roomID string) string {
+	return keyf("room:%s:fanout", roomID)
+}
+
+// ClaimRoom takes the room's lease for instanceID unless another instance
+// holds it, and reports whether instanceID is now the owner.
+func ClaimRoom(roomID, instanceID string, ttl time.Duration) (bool, error) {
+	claimed, err := RDB.SetNX(ctx, RoomOwnerKey(roomID), instanceID, ttl).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to claim room: %w", err)
+	}
+	if claimed {
+		return true, nil
+	}
+	owner, err := RoomOwner(roomID)
+	if err != nil {
+		return false, err
+	}
+	return owner == instanceID, nil
+}
+
+// RenewRoomLease extends instanceID's lease on the room and reports false
+// if the lease has been lost.
+func RenewRoomLease(roomID, instanceID string, ttl time.Duration) (bool, error) {
+	renewed, err := renewLease.Run(ctx, RDB, []string{RoomOwnerKey(roomID)}, instanceID, ttl.Milliseconds()).Int()
+	if err != nil {
+		return false, fmt.Errorf("failed to renew room lease: %w", err)
+	}
+	return renewed == 1, nil
+}
+
+func ReleaseRoom(roomID, instanceID string) error {
+	return releaseLease.Run(ctx, RDB, []string{RoomOwnerKey(roomID)}, instanceID).Err()
+}
+
+// RoomOwner returns the instance holding the room's lease, or "" if none
+// does.
+func RoomOwner(roomID string) (string, error) {
+	owner, err := RDB.Get(ctx, RoomOwnerKey(roomID)).Result()
+	if err == redis.Nil {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to load room owner: %w", err)
+	}
+	return owner, nil
+}
+
+// MarkInstanceAlive records that instanceID is still running. Owners drop
+// players relayed through instances whose mark has expired.
+func MarkInstanceAlive(instanceID string, ttl time.Duration) error {
+	return RDB.Set(ctx, InstanceKey(instanceID), time.Now().Unix(), ttl).Err()
+}
+
+func InstanceAlive(instanceID string) (bool, error) {
+	n, err := RDB.Exists(ctx, InstanceKey(instanceID)).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to check instance: %w", err)
+	}
+	return n == 1, nil
+}
+
+func ForgetInstance(instanceID string) error {
+	return RDB.Del(ctx, InstanceKey(instanceID)).Err()
+}
+
+func Publish(channel string, payload []byte) error {
+	return RDB.Publish(ctx, channel, payload).Err()
+}
+
+// Subscribe opens a subscription with no channels yet; callers add and
+// drop channels as rooms come and go.
+func Subscribe() *redis.PubSub {
+	return RDB.Subscribe(ctx)
+}
@@ -0,0 +1,102 @@
+package database
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+type UnlockedCosmetic struct {
+	UserID     string    `json:"user_id"`
+	CosmeticID string    `json:"cosmetic_id"`
+	UnlockedAt time.Time `json:"unlocked_at"`
+}
+
+// Loadout is what a player has equipped, one cosmetic per slot. Empty
+// slots fall back to the defaults.
+type Loadout struct {
+	UserID      string    `json:"user_id"`
+	NameColor   string    `json:"name_color"`
+	Avatar      string    `json:"avatar"`
+	EditorTheme string    `json:"editor_theme"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+func ListUnlockedCosmetics(userID string) ([]UnlockedCosmetic, error) {
+	if SupabaseClient == nil {
+		return nil, ErrSupabaseNotConfigured
+	}
+
+	var rows []UnlockedCosmetic
+	data, _, err := SupabaseClient.From("player_cosmetics").
+		Select("*", "", false).
+		Eq("user_id", userID).
+		Execute()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load cosmetics: %w", err)
+	}
+	if err := json.Unmarshal(data, &rows); err != nil {
+		return nil, fmt.Errorf("failed to parse cosmetics: %w", err)
+	}
+	return rows, nil
+}
+
+func UnlockCosmetics(userID string, cosmeticIDs []string) error {
+	if SupabaseClient == nil {
+		return ErrSupabaseNotConfigured
+	}
+	if len(cosmeticIDs) == 0 {
+		return nil
+	}
+
+	now := time.Now()
+	rows := make([]UnlockedCosmetic, len(cosmeticIDs))
+	for i, id := range cosmeticIDs {
+		rows[i] = UnlockedCosmetic{UserID: userID, CosmeticID: id, UnlockedAt: now}
+	}
+
+	_, _, err := SupabaseClient.From("player_cosmetics").
+		Upsert(rows, "user_id,cosmetic_id", "", "").
+		Execute()
+	if err != nil {
+		return fmt.Errorf("failed to unlock cosmetics: %w", err)
+	}
+	return nil
+}
+
+// GetLoadout returns nil, nil if the player has never equipped anything.
+func GetLoadout(userID string) (*Loadout, error) {
+	if SupabaseClient == nil {
+		return nil, ErrSupabaseNotConfigured
+	}
+
+	var rows []Loadout
+	data, _, err := SupabaseClient.From("player_loadouts").
+		Select("*", "", false).
+		Eq("user_id", userID).
+		Execute()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load loadout: %w", err)
+	}
+	if err := json.Unmarshal(data, &rows); err != nil {
+		return nil, fmt.Errorf("failed to parse loadout: %w", err)
+	}
+	if len(rows) == 0 {
+		return nil, nil
+	}
+	return &rows[0], nil
+}
+
+func SaveLoadout(loadout Loadout) error {
+	if SupabaseClient == nil {
+		return ErrSupabaseNotConfigured
+	}
+
+	_, _, err := SupabaseClient.From("player_loadouts").
+		Upsert(loadout, "user_id", "", "").
+		Execute()
+	if err != nil {
+		return fmt.Errorf("failed to save loadout: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,124 @@
+package database
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const dailyTTL = 8 * 24 * time.Hour
+
+type DailyCompletion struct {
+	PlayerID        string    `json:"playerId"`
+	Username        string    `json:"username"`
+	Role            string    `json:"role"`
+	Won             bool      `json:"won"`
+	Seconds         int       `json:"seconds"`
+	StagesCompleted int       `json:"stagesCompleted"`
+	CompletedAt     time.Time `json:"completedAt"`
+}
+
+// better reports whether c should replace prev as the player's best run.
+func (c DailyCompletion) better(prev DailyCompletion) bool {
+	if c.Won != prev.Won {
+		return c.Won
+	}
+	if c.StagesCompleted != prev.StagesCompleted {
+		return c.StagesCompleted > prev.StagesCompleted
+	}
+	return c.Seconds < prev.Seconds
+}
+
+func DailyCompletionsKey(date string) string {
+	return keyf("daily:%s:completions", date)
+}
+
+func DailyLeaderboardKey(date string) string {
+	return keyf("daily:%s:leaderboard", date)
+}
+
+// RecordDailyCompletion keeps each player's best attempt at the day's
+// challenge. Only wins make the leaderboard, ranked by fastest time.
+func RecordDailyCompletion(date string, c DailyCompletion) error {
+	prev, err := GetDailyCompletion(date, c.PlayerID)
+	if err != nil {
+		return err
+	}
+	if prev != nil && !c.better(*prev) {
+		return nil
+	}
+
+	jsonData, err := json.Marshal(c)
+	if err != nil {
+		return fmt.Errorf("failed to marshal daily completion: %w", err)
+	}
+
+	completionsKey := DailyCompletionsKey(date)
+	leaderboardKey := DailyLeaderboardKey(date)
+
+	pipe := RDB.TxPipeline()
+	pipe.HSet(ctx, completionsKey, c.PlayerID, jsonData)
+	pipe.Expire(ctx, completionsKey, dailyTTL)
+	if c.Won {
+		pipe.ZAddLT(ctx, leaderboardKey, redis.Z{Score: float64(c.Seconds), Member: c.PlayerID})
+		pipe.Expire(ctx, leaderboardKey, dailyTTL)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to record daily completion: %w", err)
+	}
+
+	return nil
+}
+
+// GetDailyCompletion returns nil, nil if the player hasn't played that day.
+func GetDailyCompletion(date, playerID string) (*DailyCompletion, error) {
+	data, err := RDB.HGet(ctx, DailyCompletionsKey(date), playerID).Bytes()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load daily completion: %w", err)
+	}
+
+	var c DailyCompletion
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("failed to parse daily completion: %w", err)
+	}
+	return &c, nil
+}
+
+func GetDailyLeaderboard(date string, limit int) ([]DailyCompletion, error) {
+	ids, err := RDB.ZRange(ctx, DailyLeaderboardKey(date), 0, int64(limit-1)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load daily leaderboard: %w", err)
+	}
+
+	entries := []DailyCompletion{}
+	if len(ids) == 0 {
+		return entries, nil
+	}
+
+	values, err := RDB.HMGet(ctx, DailyCompletionsKey(date), ids...).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load daily completions: %w", err)
+	}
+
+	for _, v := range values {
+		s, ok := v.(string)
+		if !ok {
+			continue
+		}
+		var c DailyCompletion
+		if err := json.Unmarshal([]byte(s), &c); err == nil {
+			entries = append(entries, c)
+		}
+	}
+
+	return entries, nil
+}
+
+func CountDailyCompletions(date string) (int64, error) {
+	return RDB.HLen(ctx, DailyCompletionsKey(date)).Result()
+}
@@ -0,0 +1,124 @@
+package database
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Every change to a game (a join, a vote, a stage passed, a sabotage, an
+// elimination) is appended to a Redis Stream per room as it happens. The
+// stream is what a restarted room replays on top of its last snapshot, and
+// what admins read to see how a game went. Like the audit log it outlives
+// the room.
+
+const (
+	gameEventLimit = 5000
+	gameEventTTL   = 24 * time.Hour
+
+	// gameEventBatch is how many entries GameEventsSince reads at a time.
+	gameEventBatch = 200
+)
+
+// GameEvent is one entry in a room's event stream. ID is the stream entry
+// ID, set on read. Revision is the room state revision last saved when the
+// event happened, so an event with a Revision of at least a snapshot's
+// happened after it was taken.
+type GameEvent struct {
+	ID       string                 `json:"id,omitempty"`
+	Time     time.Time              `json:"time"`
+	Type     string                 `json:"type"`
+	PlayerID string                 `json:"playerId,omitempty"`
+	Username string                 `json:"username,omitempty"`
+	Revision int64                  `json:"revision"`
+	Data     map[string]interface{} `json:"data,omitempty"`
+}
+
+func RoomEventsKey(roomID string) string {
+	return keyf("room:%s:events", roomID)
+}
+
+// AppendGameEvent adds event to the end of the room's stream, which keeps
+// roughly the latest gameEventLimit events.
+func AppendGameEvent(roomID string, event GameEvent) error {
+	jsonData, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal game event: %w", err)
+	}
+
+	key := RoomEventsKey(roomID)
+	pipe := RDB.TxPipeline()
+	pipe.XAdd(ctx, &redis.XAddArgs{
+		Stream: key,
+		MaxLen: gameEventLimit,
+		Approx: true,
+		Values: map[string]interface{}{"event": jsonData},
+	})
+	pipe.Expire(ctx, key, gameEventTTL)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to append game event: %w", err)
+	}
+	return nil
+}
+
+// GameEvents returns up to limit of the room's events in the order they
+// happened, starting after the entry with ID after, or from the first if
+// after is blank.
+func GameEvents(roomID, after string, limit int) ([]GameEvent, error) {
+	start := "-"
+	if after != "" {
+		start = "(" + after
+	}
+	messages, err := RDB.XRangeN(ctx, RoomEventsKey(roomID), start, "+", int64(limit)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read game events: %w", err)
+	}
+	return decodeGameEvents(messages), nil
+}
+
+// GameEventsSince returns, oldest first, the room's events with a Revision
+// of at least revision: those a snapshot at that revision is missing.
+func GameEventsSince(roomID string, revision int64) ([]GameEvent, error) {
+	var events []GameEvent
+	end := "+"
+	for {
+		messages, err := RDB.XRevRangeN(ctx, RoomEventsKey(roomID), end, "-", gameEventBatch).Result()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read game events: %w", err)
+		}
+
+		for _, event := range decodeGameEvents(messages) {
+			if event.Revision < revision {
+				return reverseGameEvents(events), nil
+			}
+			events = append(events, event)
+		}
+		if len(messages) < gameEventBatch {
+			return reverseGameEvents(events), nil
+		}
+		end = "(" + messages[len(messages)-1].ID
+	}
+}
+
+func decodeGameEvents(messages []redis.XMessage) []GameEvent {
+	events := make([]GameEvent, 0, len(messages))
+	for _, message := range messages {
+		raw, _ := message.Values["event"].(string)
+		var event GameEvent
+		if err := json.Unmarshal([]byte(raw), &event); err != nil {
+			continue
+		}
+		event.ID = message.ID
+		events = append(events, event)
+	}
+	return events
+}
+
+func reverseGameEvents(events []GameEvent) []GameEvent {
+	for i, j := 0, len(events)-1; i < j; i, j = i+1, j-1 {
+		events[i], events[j] = events[j], events[i]
+	}
+	return events
+}
@@ -0,0 +1,154 @@
+package database
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	FriendshipPending  = "pending"
+	FriendshipAccepted = "accepted"
+)
+
+var (
+	ErrFriendshipExists   = errors.New("friend request already exists")
+	ErrFriendshipNotFound = errors.New("friend request not found")
+)
+
+type Friendship struct {
+	RequesterID string     `json:"requester_id"`
+	AddresseeID string     `json:"addressee_id"`
+	Status      string     `json:"status"`
+	CreatedAt   time.Time  `json:"created_at"`
+	RespondedAt *time.Time `json:"responded_at,omitempty"`
+}
+
+// Other returns the user on the other side of the friendship from userID.
+func (f Friendship) Other(userID string) string {
+	if f.RequesterID == userID {
+		return f.AddresseeID
+	}
+	return f.RequesterID
+}
+
+// GetFriendship looks up the relationship between two users in either
+// direction. It returns nil, nil if there is none.
+func GetFriendship(a, b string) (*Friendship, error) {
+	if SupabaseClient == nil {
+		return nil, ErrSupabaseNotConfigured
+	}
+
+	var rows []Friendship
+	data, _, err := SupabaseClient.From("friendships").
+		Select("*", "", false).
+		Or(fmt.Sprintf("and(requester_id.eq.%s,addressee_id.eq.%s),and(requester_id.eq.%s,addressee_id.eq.%s)", a, b, b, a), "").
+		Execute()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load friendship: %w", err)
+	}
+	if err := json.Unmarshal(data, &rows); err != nil {
+		return nil, fmt.Errorf("failed to parse friendship: %w", err)
+	}
+	if len(rows) == 0 {
+		return nil, nil
+	}
+	return &rows[0], nil
+}
+
+func SendFriendRequest(from, to string) (*Friendship, error) {
+	existing, err := GetFriendship(from, to)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		// A request in the other direction means both want it - accept.
+		if existing.Status == FriendshipPending && existing.RequesterID == to {
+			return RespondFriendRequest(to, from, true)
+		}
+		return nil, ErrFriendshipExists
+	}
+
+	friendship := Friendship{
+		RequesterID: from,
+		AddresseeID: to,
+		Status:      FriendshipPending,
+		CreatedAt:   time.Now(),
+	}
+
+	_, _, err = SupabaseClient.From("friendships").
+		Insert(friendship, false, "", "", "").
+		Execute()
+	if err != nil {
+		return nil, fmt.Errorf("failed to send friend request: %w", err)
+	}
+
+	return &friendship, nil
+}
+
+// RespondFriendRequest accepts or declines the pending request requester
+// sent to addressee. Declined requests are deleted so they can be re-sent.
+func RespondFriendRequest(requester, addressee string, accept bool) (*Friendship, error) {
+	existing, err := GetFriendship(requester, addressee)
+	if err != nil {
+		return nil, err
+	}
+	if existing == nil || existing.Status != FriendshipPending || existing.RequesterID != requester {
+		return nil, ErrFriendshipNotFound
+	}
+
+	if !accept {
+		return nil, DeleteFriendship(requester, addressee)
+	}
+
+	now := time.Now()
+	_, _, err = SupabaseClient.From("friendships").
+		Update(map[string]interface{}{"status": FriendshipAccepted, "responded_at": now}, "", "").
+		Eq("requester_id", requester).
+		Eq("addressee_id", addressee).
+		Execute()
+	if err != nil {
+		return nil, fmt.Errorf("failed to accept friend request: %w", err)
+	}
+
+	existing.Status = FriendshipAccepted
+	existing.RespondedAt = &now
+	return existing, nil
+}
+
+func DeleteFriendship(a, b string) error {
+	if SupabaseClient == nil {
+		return ErrSupabaseNotConfigured
+	}
+
+	_, _, err := SupabaseClient.From("friendships").
+		Delete("", "").
+		Or(fmt.Sprintf("and(requester_id.eq.%s,addressee_id.eq.%s),and(requester_id.eq.%s,addressee_id.eq.%s)", a, b, b, a), "").
+		Execute()
+	if err != nil {
+		return fmt.Errorf("failed to delete friendship: %w", err)
+	}
+	return nil
+}
+
+// ListFriendships returns every accepted and pending relationship the user
+// is part of.
+func ListFriendships(userID string) ([]Friendship, error) {
+	if SupabaseClient == nil {
+		return nil, ErrSupabaseNotConfigured
+	}
+
+	rows := []Friendship{}
+	data, _, err := SupabaseClient.From("friendships").
+		Select("*", "", false).
+		Or(fmt.Sprintf("requester_id.eq.%s,addressee_id.eq.%s", userID, userID), "").
+		Execute()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list friends: %w", err)
+	}
+	if err := json.Unmarshal(data, &rows); err != nil {
+		return nil, fmt.Errorf("failed to parse friends: %w", err)
+	}
+	return rows, nil
+}
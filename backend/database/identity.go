@@ -0,0 +1,144 @@
+package database
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+func GuestClaimKey(guestID string) string {
+	return keyf("guest:%s:claimed_by", guestID)
+}
+
+// ClaimGuest re-attributes a guest's match history, rating, matchmaking
+// rating and daily results to an account. It returns the number of matches moved. A guest
+// can only be claimed by one account; claiming again from the same account
+// is a no-op that picks up anything played since.
+func ClaimGuest(guestID, accountID string) (int, error) {
+	if SupabaseClient == nil {
+		return 0, ErrSupabaseNotConfigured
+	}
+
+	ok, err := RDB.SetNX(ctx, GuestClaimKey(guestID), accountID, 0).Result()
+	if err != nil {
+		return 0, fmt.Errorf("failed to record guest claim: %w", err)
+	}
+	if !ok {
+		owner, err := RDB.Get(ctx, GuestClaimKey(guestID)).Result()
+		if err != nil {
+			return 0, fmt.Errorf("failed to load guest claim: %w", err)
+		}
+		if owner != accountID {
+			return 0, ErrGuestAlreadyClaimed
+		}
+	}
+
+	var moved []MatchPlayer
+	data, _, err := SupabaseClient.From("match_players").
+		Update(map[string]interface{}{"user_id": accountID}, "representation", "").
+		Eq("user_id", guestID).
+		Execute()
+	if err != nil {
+		return 0, fmt.Errorf("failed to move guest matches: %w", err)
+	}
+	if err := json.Unmarshal(data, &moved); err != nil {
+		return 0, fmt.Errorf("failed to parse moved matches: %w", err)
+	}
+
+	if err := claimGuestRating(guestID, accountID); err != nil {
+		log.Printf("Failed to move rating from guest %s: %v", guestID, err)
+	}
+	if err := claimGuestMMR(guestID, accountID); err != nil {
+		log.Printf("Failed to move MMR from guest %s: %v", guestID, err)
+	}
+	claimGuestDailyResults(guestID, accountID)
+
+	InvalidatePlayerStats(guestID)
+	InvalidatePlayerStats(accountID)
+
+	return len(moved), nil
+}
+
+// claimGuestRating adopts the guest's rating only when the account has
+// never played ranked; an established account rating is kept as is.
+func claimGuestRating(guestID, accountID string) error {
+	ratings, err := GetRatings([]string{guestID, accountID})
+	if err != nil {
+		return err
+	}
+
+	guest, hasGuest := ratings[guestID]
+	if !hasGuest {
+		return nil
+	}
+
+	if _, hasAccount := ratings[accountID]; !hasAccount {
+		guest.UserID = accountID
+		guest.UpdatedAt = time.Now()
+		if err := SaveRatings([]PlayerRating{guest}); err != nil {
+			return err
+		}
+	}
+
+	_, _, err = SupabaseClient.From("player_ratings").
+		Delete("", "").
+		Eq("user_id", guestID).
+		Execute()
+	return err
+}
+
+// claimGuestMMR does the same for the matchmaking rating: the guest's is
+// adopted only by an account that has never finished a game.
+func claimGuestMMR(guestID, accountID string) error {
+	mmrs, err := GetMMRs([]string{guestID, accountID})
+	if err != nil {
+		return err
+	}
+
+	guest, hasGuest := mmrs[guestID]
+	if !hasGuest {
+		return nil
+	}
+
+	if _, hasAccount := mmrs[accountID]; !hasAccount {
+		guest.UserID = accountID
+		guest.UpdatedAt = time.Now()
+		if err := SaveMMRs([]PlayerMMR{guest}); err != nil {
+			return err
+		}
+	}
+
+	_, _, err = SupabaseClient.From("player_mmr").
+		Delete("", "").
+		Eq("user_id", guestID).
+		Execute()
+	return err
+}
+
+// claimGuestDailyResults moves results for the days still kept in Redis.
+func claimGuestDailyResults(guestID, accountID string) {
+	for day := 0; day < int(dailyTTL/(24*time.Hour)); day++ {
+		date := time.Now().UTC().AddDate(0, 0, -day).Format("2006-01-02")
+
+		completion, err := GetDailyCompletion(date, guestID)
+		if err != nil || completion == nil {
+			continue
+		}
+
+		completion.PlayerID = accountID
+		if err := RecordDailyCompletion(date, *completion); err != nil {
+			log.Printf("Failed to move daily result %s for guest %s: %v", date, guestID, err)
+			continue
+		}
+
+		pipe := RDB.TxPipeline()
+		pipe.HDel(ctx, DailyCompletionsKey(date), guestID)
+		pipe.ZRem(ctx, DailyLeaderboardKey(date), guestID)
+		if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+			log.Printf("Failed to clear daily result %s for guest %s: %v", date, guestID, err)
+		}
+	}
+}
@@ -0,0 +1,28 @@
+package database
+
+import (
+	"fmt"
+	"time"
+)
+
+func EmailInviteQuotaKey(userID string, hour int64) string {
+	return keyf("email_invites:%s:%d", userID, hour)
+}
+
+// ReserveEmailInvites takes n sends out of the user's hourly budget. When
+// the budget would be exceeded nothing is taken and ok is false.
+func ReserveEmailInvites(userID string, n, limit int) (ok bool, err error) {
+	key := EmailInviteQuotaKey(userID, time.Now().Unix()/3600)
+
+	used, err := RDB.IncrBy(ctx, key, int64(n)).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to reserve email invites: %w", err)
+	}
+	RDB.Expire(ctx, key, time.Hour)
+
+	if used > int64(limit) {
+		RDB.DecrBy(ctx, key, int64(n))
+		return false, nil
+	}
+	return true, nil
+}
@@ -0,0 +1,29 @@
+package database
+
+import "strings"
+
+// orphanRoomKeys are the per-room keys that mean nothing once the room's
+// state is gone. Bans and the audit log outlive the room on purpose.
+var orphanRoomKeys = []string{"players", "chat_history", "webhooks"}
+
+// PruneOrphanRoomKeys deletes per-room keys left behind by rooms whose
+// state has gone, and returns how many it deleted.
+func PruneOrphanRoomKeys() (int, error) {
+	pruned := 0
+	for _, suffix := range orphanRoomKeys {
+		iter := RDB.Scan(ctx, 0, Key("room:*:"+suffix), 500).Iterator()
+		for iter.Next(ctx) {
+			parts := strings.Split(strings.TrimPrefix(iter.Val(), keyPrefix), ":")
+			if len(parts) != 3 || RoomExists(parts[1]) {
+				continue
+			}
+			if err := RDB.Del(ctx, iter.Val()).Err(); err == nil {
+				pruned++
+			}
+		}
+		if err := iter.Err(); err != nil {
+			return pruned, err
+		}
+	}
+	return pruned, nil
+}
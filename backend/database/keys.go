@@ -0,0 +1,92 @@
+package database
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Every key the backend uses is built through Key or keyf, which put the
+// configured prefix in front, so several environments can share one Redis.
+// Pub/sub channels are prefixed the same way, the translation sidecar's
+// included: it reads the same REDIS_KEY_PREFIX, so one environment's chat
+// never reaches another's rooms.
+
+var keyPrefix string
+
+// keyFamilies are patterns matching every key the backend writes, without
+// a prefix. RelocateKeys moves only these, so moving keys from no prefix
+// leaves anything else in the Redis alone.
+var keyFamilies = []string{
+	"room:*", "rooms:*", "player:*", "instance:*", "ban:*", "bans:*",
+	"daily:*", "guest:*", "email_invites:*", "leaderboard:*", "lobbies:*",
+	"matchmaking:*", "moderation:*", "season:*", "summary:*",
+	"translation:*", "webhooks:*",
+}
+
+// SetKeyPrefix sets what goes in front of every key. Call it before
+// InitRedis.
+func SetKeyPrefix(prefix string) {
+	keyPrefix = normalizePrefix(prefix)
+}
+
+func normalizePrefix(prefix string) string {
+	if prefix != "" && !strings.HasSuffix(prefix, ":") {
+		prefix += ":"
+	}
+	return prefix
+}
+
+// Key is name with the configured prefix.
+func Key(name string) string {
+	return keyPrefix + name
+}
+
+func keyf(format string, args ...interface{}) string {
+	return keyPrefix + fmt.Sprintf(format, args...)
+}
+
+// The translation sidecar reads chat lines from ChatProcessingChannel and
+// task fields from TaskTranslateChannel, and answers on the matching
+// translations channel.
+
+func ChatProcessingChannel() string   { return Key("chat:processing") }
+func ChatTranslationsChannel() string { return Key("chat:translations") }
+func TaskTranslateChannel() string    { return Key("task:translate") }
+func TaskTranslationsChannel() string { return Key("task:translations") }
+
+// RelocateKeys renames every backend key under prefix from to prefix to,
+// keeping TTLs, and returns how many it moved, or with dryRun would move.
+// A key whose new name is already taken is left where it is and counted
+// in skipped.
+func RelocateKeys(from, to string, dryRun bool) (moved, skipped int, err error) {
+	from, to = normalizePrefix(from), normalizePrefix(to)
+	if from == to {
+		return 0, 0, fmt.Errorf("from and to are the same prefix")
+	}
+
+	for _, family := range keyFamilies {
+		iter := RDB.Scan(ctx, 0, from+family, 500).Iterator()
+		for iter.Next(ctx) {
+			oldKey := iter.Val()
+			newKey := to + strings.TrimPrefix(oldKey, from)
+
+			if dryRun {
+				moved++
+				continue
+			}
+			renamed, err := RDB.RenameNX(ctx, oldKey, newKey).Result()
+			if err != nil {
+				return moved, skipped, fmt.Errorf("failed to move %s: %w", oldKey, err)
+			}
+			if renamed {
+				moved++
+			} else {
+				skipped++
+			}
+		}
+		if err := iter.Err(); err != nil {
+			return moved, skipped, fmt.Errorf("failed to scan %s: %w", from+family, err)
+		}
+	}
+	return moved, skipped, nil
+}
@@ -0,0 +1,218 @@
+package database
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Leaderboard modes for GET /api/leaderboard.
+const (
+	BoardOverall  = "overall"
+	BoardImpostor = "impostor"
+	BoardFastest  = "fastest"
+)
+
+// Leaderboard periods: every match, or the last seven days.
+const (
+	BoardAllTime = "all"
+	BoardWeekly  = "week"
+)
+
+const (
+	leaderboardTTL  = 5 * time.Minute
+	leaderboardSize = 100
+
+	// minImpostorGames keeps a lucky first game from topping the impostor
+	// board.
+	minImpostorGames = 3
+)
+
+// BoardEntry is one player's line on a leaderboard. BestClearSeconds is
+// only set on the fastest-clears board.
+type BoardEntry struct {
+	PlayerID         string  `json:"playerId"`
+	Username         string  `json:"username,omitempty"`
+	GamesPlayed      int     `json:"gamesPlayed"`
+	GamesWon         int     `json:"gamesWon"`
+	WinRate          float64 `json:"winRate"`
+	BestClearSeconds int     `json:"bestClearSeconds,omitempty"`
+}
+
+func LeaderboardKey(mode, period string) string {
+	return keyf("leaderboard:%s:%s", mode, period)
+}
+
+// GetBoard serves the top of a leaderboard from Redis, recomputing it from
+// match history at most every leaderboardTTL.
+func GetBoard(mode, period string, limit int) ([]BoardEntry, error) {
+	key := LeaderboardKey(mode, period)
+
+	var board []BoardEntry
+	data, err := RDB.Get(ctx, key).Bytes()
+	if err == nil && json.Unmarshal(data, &board) == nil {
+		return topOf(board, limit), nil
+	}
+	if err != nil && err != redis.Nil {
+		log.Printf("Failed to read cached leaderboard %s: %v", key, err)
+	}
+
+	board, err = computeBoard(mode, period)
+	if err != nil {
+		return nil, err
+	}
+	if jsonData, err := json.Marshal(board); err == nil {
+		if err := RDB.Set(ctx, key, jsonData, leaderboardTTL).Err(); err != nil {
+			log.Printf("Failed to cache leaderboard %s: %v", key, err)
+		}
+	}
+	return topOf(board, limit), nil
+}
+
+func topOf(board []BoardEntry, limit int) []BoardEntry {
+	if len(board) > limit {
+		return board[:limit]
+	}
+	return board
+}
+
+func computeBoard(mode, period string) ([]BoardEntry, error) {
+	if SupabaseClient == nil {
+		return nil, ErrSupabaseNotConfigured
+	}
+
+	query := SupabaseClient.From("game_matches").Select("*", "", false)
+	if period == BoardWeekly {
+		query = query.Gte("ended_at", time.Now().AddDate(0, 0, -7).UTC().Format(time.RFC3339))
+	}
+	data, _, err := query.Execute()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load matches for leaderboard: %w", err)
+	}
+	var matches []GameMatch
+	if err := json.Unmarshal(data, &matches); err != nil {
+		return nil, fmt.Errorf("failed to parse matches for leaderboard: %w", err)
+	}
+	board := []BoardEntry{}
+	if len(matches) == 0 {
+		return board, nil
+	}
+
+	byID := make(map[string]GameMatch, len(matches))
+	ids := make([]string, 0, len(matches))
+	for _, m := range matches {
+		byID[m.ID] = m
+		ids = append(ids, m.ID)
+	}
+
+	var rows []MatchPlayer
+	data, _, err = SupabaseClient.From("match_players").
+		Select("*", "", false).
+		In("match_id", ids).
+		Execute()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load players for leaderboard: %w", err)
+	}
+	if err := json.Unmarshal(data, &rows); err != nil {
+		return nil, fmt.Errorf("failed to parse players for leaderboard: %w", err)
+	}
+
+	entries := make(map[string]*BoardEntry)
+	for _, row := range rows {
+		match := byID[row.MatchID]
+		switch {
+		case mode == BoardImpostor && row.Role != "IMPOSTER":
+			continue
+		case mode == BoardFastest && row.Role != "CIVILIAN":
+			continue
+		}
+
+		e := entries[row.UserID]
+		if e == nil {
+			e = &BoardEntry{PlayerID: row.UserID}
+			entries[row.UserID] = e
+		}
+		e.GamesPlayed++
+		if match.WinnerRole != row.Role {
+			continue
+		}
+		e.GamesWon++
+		// A clear is a civilian win on all three stages, not by vote.
+		if mode == BoardFastest && match.StagesCompleted == 3 &&
+			(e.BestClearSeconds == 0 || match.DurationSeconds < e.BestClearSeconds) {
+			e.BestClearSeconds = match.DurationSeconds
+		}
+	}
+
+	for _, e := range entries {
+		e.WinRate = float64(e.GamesWon) / float64(e.GamesPlayed)
+		switch {
+		case mode == BoardImpostor && e.GamesPlayed < minImpostorGames:
+			continue
+		case mode == BoardFastest && e.BestClearSeconds == 0:
+			continue
+		}
+		board = append(board, *e)
+	}
+
+	sort.Slice(board, func(i, j int) bool {
+		a, b := board[i], board[j]
+		switch mode {
+		case BoardImpostor:
+			if a.WinRate != b.WinRate {
+				return a.WinRate > b.WinRate
+			}
+		case BoardFastest:
+			if a.BestClearSeconds != b.BestClearSeconds {
+				return a.BestClearSeconds < b.BestClearSeconds
+			}
+		}
+		if a.GamesWon != b.GamesWon {
+			return a.GamesWon > b.GamesWon
+		}
+		return a.PlayerID < b.PlayerID
+	})
+	board = topOf(board, leaderboardSize)
+
+	if err := fillUsernames(board); err != nil {
+		log.Printf("Failed to load usernames for leaderboard: %v", err)
+	}
+	return board, nil
+}
+
+// fillUsernames looks up the board's players in users. Guests have no row
+// there and keep a blank name.
+func fillUsernames(board []BoardEntry) error {
+	if len(board) == 0 {
+		return nil
+	}
+	ids := make([]string, 0, len(board))
+	for _, e := range board {
+		ids = append(ids, e.PlayerID)
+	}
+
+	var users []User
+	data, _, err := SupabaseClient.From("users").
+		Select("id,username", "", false).
+		In("id", ids).
+		Execute()
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, &users); err != nil {
+		return err
+	}
+
+	names := make(map[string]string, len(users))
+	for _, u := range users {
+		names[u.ID] = u.Username
+	}
+	for i := range board {
+		board[i].Username = names[board[i].PlayerID]
+	}
+	return nil
+}
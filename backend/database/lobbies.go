@@ -0,0 +1,61 @@
+package database
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+const publicLobbiesKey = "lobbies:public"
+
+// lobbyListingTTL drops listings a crashed instance never took down, the
+// same hour the room's own state lasts.
+const lobbyListingTTL = time.Hour
+
+// LobbyListing is a public lobby as the server browser shows it. Settings
+// are the room's effective settings, as sent in SETTINGS_UPDATED.
+type LobbyListing struct {
+	RoomID    string          `json:"roomId"`
+	Host      string          `json:"host"`
+	Players   int             `json:"players"`
+	Ranked    bool            `json:"ranked"`
+	Settings  json.RawMessage `json:"settings"`
+	UpdatedAt time.Time       `json:"updatedAt"`
+}
+
+// SaveLobbyListing adds or refreshes a lobby in the public index.
+func SaveLobbyListing(listing LobbyListing) error {
+	jsonData, err := json.Marshal(listing)
+	if err != nil {
+		return fmt.Errorf("failed to marshal lobby listing: %w", err)
+	}
+	if err := RDB.HSet(ctx, Key(publicLobbiesKey), listing.RoomID, jsonData).Err(); err != nil {
+		return fmt.Errorf("failed to save lobby listing: %w", err)
+	}
+	return nil
+}
+
+func RemoveLobbyListing(roomID string) error {
+	return RDB.HDel(ctx, Key(publicLobbiesKey), roomID).Err()
+}
+
+// PublicLobbies returns every listed lobby, in no particular order,
+// pruning listings whose room has gone without taking them down.
+func PublicLobbies() ([]LobbyListing, error) {
+	entries, err := RDB.HGetAll(ctx, Key(publicLobbiesKey)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load lobbies: %w", err)
+	}
+
+	lobbies := make([]LobbyListing, 0, len(entries))
+	for roomID, jsonData := range entries {
+		var listing LobbyListing
+		if err := json.Unmarshal([]byte(jsonData), &listing); err != nil ||
+			time.Since(listing.UpdatedAt) > lobbyListingTTL || !RoomExists(roomID) {
+			RDB.HDel(ctx, Key(publicLobbiesKey), roomID)
+			continue
+		}
+		lobbies = append(lobbies, listing)
+	}
+	return lobbies, nil
+}
@@ -0,0 +1,77 @@
+package database
+
+import (
+	"encoding/json"
+	"log"
+	"strings"
+)
+
+// The detailed match stats are columns a database set up before they
+// existed won't have. Rather than lose the match, SaveGameMatch writes it
+// again without them when PostgREST says a column is missing, and the
+// details show up as soon as the columns are added:
+//
+//	alter table game_matches add column stage_seconds jsonb, add column stage_code jsonb;
+//	alter table match_players add column vote_accuracy real,
+//		add column sabotages_used int not null default 0,
+//		add column seconds_alive int not null default 0;
+
+var (
+	matchDetailColumns  = []string{"stage_seconds", "stage_code"}
+	playerDetailColumns = []string{"vote_accuracy", "sabotages_used", "seconds_alive"}
+
+	// matchListColumns is what match lists read: everything but the
+	// details, which only a single match's page needs.
+	matchListColumns = "id,room_code,winner_role,impostor_id,duration_seconds,stages_completed,ended_at"
+)
+
+// insertMatchDetails inserts rows, a row or a slice of them, into table,
+// and inserts them again without detailColumns if the table lacks one.
+func insertMatchDetails(table string, rows interface{}, detailColumns []string) ([]byte, error) {
+	data, _, err := SupabaseClient.From(table).
+		Insert(rows, false, "", "", "").
+		Execute()
+	if err == nil || !isMissingColumn(err) {
+		return data, err
+	}
+
+	log.Printf("⚠️ %s is missing match detail columns, saving without them: %v", table, err)
+	stripped, err := withoutColumns(rows, detailColumns)
+	if err != nil {
+		return nil, err
+	}
+	data, _, err = SupabaseClient.From(table).
+		Insert(stripped, false, "", "", "").
+		Execute()
+	return data, err
+}
+
+// isMissingColumn reports whether PostgREST rejected a write for naming a
+// column the table doesn't have.
+func isMissingColumn(err error) bool {
+	msg := err.Error()
+	return strings.Contains(msg, "PGRST204") || strings.Contains(msg, "42703")
+}
+
+// withoutColumns is rows as JSON objects with columns removed.
+func withoutColumns(rows interface{}, columns []string) (interface{}, error) {
+	jsonData, err := json.Marshal(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	var list []map[string]interface{}
+	if err := json.Unmarshal(jsonData, &list); err != nil {
+		var row map[string]interface{}
+		if err := json.Unmarshal(jsonData, &row); err != nil {
+			return nil, err
+		}
+		list = []map[string]interface{}{row}
+	}
+	for _, row := range list {
+		for _, column := range columns {
+			delete(row, column)
+		}
+	}
+	return list, nil
+}
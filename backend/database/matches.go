@@ -0,0 +1,208 @@
+package database
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/supabase-community/postgrest-go"
+)
+
+var (
+	ErrSupabaseNotConfigured = errors.New("supabase not configured")
+	ErrGuestAlreadyClaimed   = errors.New("guest already claimed by another account")
+)
+
+type MatchFilter struct {
+	PlayerID string
+	Outcome  string
+	From     *time.Time
+	To       *time.Time
+	Limit    int
+	Offset   int
+}
+
+type MatchDetail struct {
+	GameMatch
+	Players []MatchPlayer `json:"players"`
+}
+
+// ListMatches returns one page of matches, newest first, along with the total
+// number of matches that satisfy the filter.
+func ListMatches(filter MatchFilter) ([]GameMatch, int64, error) {
+	if SupabaseClient == nil {
+		return nil, 0, ErrSupabaseNotConfigured
+	}
+
+	query := SupabaseClient.From("game_matches").
+		Select(matchListColumns, "exact", false)
+
+	if filter.PlayerID != "" {
+		ids, err := matchIDsForPlayer(filter.PlayerID)
+		if err != nil {
+			return nil, 0, err
+		}
+		if len(ids) == 0 {
+			return []GameMatch{}, 0, nil
+		}
+		query = query.In("id", ids)
+	}
+	if filter.Outcome != "" {
+		query = query.Eq("winner_role", filter.Outcome)
+	}
+	if filter.From != nil {
+		query = query.Gte("ended_at", filter.From.UTC().Format(time.RFC3339))
+	}
+	if filter.To != nil {
+		query = query.Lte("ended_at", filter.To.UTC().Format(time.RFC3339))
+	}
+
+	data, total, err := query.
+		Order("ended_at", &postgrest.OrderOpts{Ascending: false}).
+		Range(filter.Offset, filter.Offset+filter.Limit-1, "").
+		Execute()
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list matches: %w", err)
+	}
+
+	matches := []GameMatch{}
+	if err := json.Unmarshal(data, &matches); err != nil {
+		return nil, 0, fmt.Errorf("failed to parse matches: %w", err)
+	}
+
+	return matches, total, nil
+}
+
+// GetMatch returns nil, nil when the match doesn't exist.
+func GetMatch(id string) (*MatchDetail, error) {
+	if SupabaseClient == nil {
+		return nil, ErrSupabaseNotConfigured
+	}
+
+	var matches []GameMatch
+	data, _, err := SupabaseClient.From("game_matches").
+		Select("*", "", false).
+		Eq("id", id).
+		Execute()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load match %s: %w", id, err)
+	}
+	if err := json.Unmarshal(data, &matches); err != nil {
+		return nil, fmt.Errorf("failed to parse match %s: %w", id, err)
+	}
+	if len(matches) == 0 {
+		return nil, nil
+	}
+
+	players := []MatchPlayer{}
+	data, _, err = SupabaseClient.From("match_players").
+		Select("*", "", false).
+		Eq("match_id", id).
+		Execute()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load players for match %s: %w", id, err)
+	}
+	if err := json.Unmarshal(data, &players); err != nil {
+		return nil, fmt.Errorf("failed to parse players for match %s: %w", id, err)
+	}
+
+	return &MatchDetail{GameMatch: matches[0], Players: players}, nil
+}
+
+// PlayerMatch is one of a player's matches as their profile shows it.
+// Outcome is WON, LOST, or UNDECIDED for a game that was stopped early.
+type PlayerMatch struct {
+	MatchID         string    `json:"id"`
+	RoomCode        string    `json:"room_code"`
+	Role            string    `json:"role"`
+	Outcome         string    `json:"outcome"`
+	WinnerRole      string    `json:"winner_role"`
+	DurationSeconds int       `json:"duration_seconds"`
+	StagesCompleted int       `json:"stages_completed"`
+	EndedAt         time.Time `json:"ended_at"`
+}
+
+// ListPlayerMatches returns one page of userID's matches, newest first,
+// along with how many they have played in all. The page is cut from the
+// player's match_players rows, each with its match embedded, so only the
+// page is read however many games they have played.
+func ListPlayerMatches(userID string, limit, offset int) ([]PlayerMatch, int64, error) {
+	if SupabaseClient == nil {
+		return nil, 0, ErrSupabaseNotConfigured
+	}
+
+	data, total, err := SupabaseClient.From("match_players").
+		Select("role,game_matches!inner("+matchListColumns+")", "exact", false).
+		Eq("user_id", userID).
+		Order("game_matches(ended_at)", &postgrest.OrderOpts{Ascending: false}).
+		Range(offset, offset+limit-1, "").
+		Execute()
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list matches for player %s: %w", userID, err)
+	}
+	var rows []struct {
+		Role  string    `json:"role"`
+		Match GameMatch `json:"game_matches"`
+	}
+	if err := json.Unmarshal(data, &rows); err != nil {
+		return nil, 0, fmt.Errorf("failed to parse matches for player %s: %w", userID, err)
+	}
+
+	page := make([]PlayerMatch, 0, len(rows))
+	for _, row := range rows {
+		m, role := row.Match, row.Role
+		outcome := "LOST"
+		switch m.WinnerRole {
+		case role:
+			outcome = "WON"
+		case "", "UNKNOWN":
+			outcome = "UNDECIDED"
+		}
+		page = append(page, PlayerMatch{
+			MatchID:         m.ID,
+			RoomCode:        m.RoomCode,
+			Role:            role,
+			Outcome:         outcome,
+			WinnerRole:      m.WinnerRole,
+			DurationSeconds: m.DurationSeconds,
+			StagesCompleted: m.StagesCompleted,
+			EndedAt:         m.EndedAt,
+		})
+	}
+	return page, total, nil
+}
+
+// GetPlayerMatches returns every match row the player took part in.
+func GetPlayerMatches(userID string) ([]MatchPlayer, error) {
+	if SupabaseClient == nil {
+		return nil, ErrSupabaseNotConfigured
+	}
+
+	rows := []MatchPlayer{}
+	data, _, err := SupabaseClient.From("match_players").
+		Select("*", "", false).
+		Eq("user_id", userID).
+		Execute()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load matches for player %s: %w", userID, err)
+	}
+	if err := json.Unmarshal(data, &rows); err != nil {
+		return nil, fmt.Errorf("failed to parse matches for player %s: %w", userID, err)
+	}
+
+	return rows, nil
+}
+
+func matchIDsForPlayer(userID string) ([]string, error) {
+	rows, err := GetPlayerMatches(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]string, 0, len(rows))
+	for _, row := range rows {
+		ids = append(ids, row.MatchID)
+	}
+	return ids, nil
+}
@@ -0,0 +1,215 @@
+package database
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const matchQueueKey = "matchmaking:queue"
+
+// ErrTicketsTaken is returned by ClaimTickets when the queue changed under
+// it, usually because another instance matched some of the same players.
+var ErrTicketsTaken = errors.New("matchmaking queue changed")
+
+// QueueTicket is one player, or one party, waiting in the quick-match
+// queue. A blank Language means any. A party's ticket is queued by its
+// leader, carries every member and is matched as a whole.
+type QueueTicket struct {
+	UserID   string    `json:"userId"`
+	Username string    `json:"username"`
+	PartyID  string    `json:"partyId,omitempty"`
+	Members  []string  `json:"members,omitempty"`
+	Language string    `json:"language,omitempty"`
+	MMR      float64   `json:"mmr"`
+	QueuedAt time.Time `json:"queuedAt"`
+	// SeenAt is the last time the ticket's players were known to still be
+	// around; QueuedTickets drops tickets that go quiet.
+	SeenAt time.Time `json:"seenAt"`
+}
+
+// Key is the ticket's field in the queue: the party for a party ticket,
+// otherwise the player.
+func (t QueueTicket) Key() string {
+	if t.PartyID != "" {
+		return PartyTicketKey(t.PartyID)
+	}
+	return t.UserID
+}
+
+// Players is everyone the ticket places into a match.
+func (t QueueTicket) Players() []string {
+	if len(t.Members) > 0 {
+		return t.Members
+	}
+	return []string{t.UserID}
+}
+
+// PartyTicketKey is the queue field a party's ticket is stored under.
+func PartyTicketKey(partyID string) string {
+	return "party:" + partyID
+}
+
+func MatchFoundKey(userID string) string {
+	return keyf("matchmaking:found:%s", userID)
+}
+
+// Enqueue puts a ticket in the queue, replacing any the player or party
+// already had.
+func Enqueue(ticket QueueTicket) error {
+	jsonData, err := json.Marshal(ticket)
+	if err != nil {
+		return fmt.Errorf("failed to marshal ticket: %w", err)
+	}
+
+	pipe := RDB.TxPipeline()
+	pipe.HSet(ctx, Key(matchQueueKey), ticket.Key(), jsonData)
+	for _, id := range ticket.Players() {
+		pipe.Del(ctx, MatchFoundKey(id))
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to queue player: %w", err)
+	}
+	return nil
+}
+
+// Dequeue takes a ticket out of the queue by its Key and reports whether it
+// was there.
+func Dequeue(key string) (bool, error) {
+	n, err := RDB.HDel(ctx, Key(matchQueueKey), key).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to dequeue player: %w", err)
+	}
+	return n > 0, nil
+}
+
+// GetTicket returns the ticket stored under key, or nil if there is none.
+func GetTicket(key string) (*QueueTicket, error) {
+	jsonData, err := RDB.HGet(ctx, Key(matchQueueKey), key).Bytes()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load ticket: %w", err)
+	}
+
+	var ticket QueueTicket
+	if err := json.Unmarshal(jsonData, &ticket); err != nil {
+		return nil, fmt.Errorf("failed to parse ticket: %w", err)
+	}
+	return &ticket, nil
+}
+
+// QueuedTickets returns every live ticket in the queue, in no particular
+// order. Tickets not seen for ttl are removed instead.
+func QueuedTickets(ttl time.Duration) ([]QueueTicket, error) {
+	entries, err := RDB.HGetAll(ctx, Key(matchQueueKey)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load queue: %w", err)
+	}
+
+	tickets := make([]QueueTicket, 0, len(entries))
+	var expired []string
+	for key, jsonData := range entries {
+		var ticket QueueTicket
+		if err := json.Unmarshal([]byte(jsonData), &ticket); err != nil {
+			continue
+		}
+		if time.Since(ticket.SeenAt) > ttl {
+			expired = append(expired, key)
+			continue
+		}
+		tickets = append(tickets, ticket)
+	}
+
+	if len(expired) > 0 {
+		if err := RDB.HDel(ctx, Key(matchQueueKey), expired...).Err(); err != nil {
+			return nil, fmt.Errorf("failed to expire tickets: %w", err)
+		}
+	}
+	return tickets, nil
+}
+
+// TouchTicket marks the ticket stored under key as seen at, if it is still
+// queued. It never puts back a ticket that was claimed or left.
+func TouchTicket(key string, at time.Time) error {
+	err := RDB.Watch(ctx, func(tx *redis.Tx) error {
+		jsonData, err := tx.HGet(ctx, Key(matchQueueKey), key).Bytes()
+		if err == redis.Nil {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		var ticket QueueTicket
+		if err := json.Unmarshal(jsonData, &ticket); err != nil {
+			return err
+		}
+		ticket.SeenAt = at
+		if jsonData, err = json.Marshal(ticket); err != nil {
+			return err
+		}
+
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.HSet(ctx, Key(matchQueueKey), key, jsonData)
+			return nil
+		})
+		return err
+	}, Key(matchQueueKey))
+
+	if err == redis.TxFailedErr {
+		// The queue changed; the next pass touches it again.
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to touch ticket: %w", err)
+	}
+	return nil
+}
+
+// ClaimTickets takes the tickets with the given keys out of the queue
+// together, or none of them if any has left or the queue changed while it
+// looked.
+func ClaimTickets(keys []string) error {
+	err := RDB.Watch(ctx, func(tx *redis.Tx) error {
+		for _, id := range keys {
+			queued, err := tx.HExists(ctx, Key(matchQueueKey), id).Result()
+			if err != nil {
+				return err
+			}
+			if !queued {
+				return ErrTicketsTaken
+			}
+		}
+
+		_, err := tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.HDel(ctx, Key(matchQueueKey), keys...)
+			return nil
+		})
+		return err
+	}, Key(matchQueueKey))
+
+	if err == redis.TxFailedErr {
+		return ErrTicketsTaken
+	}
+	return err
+}
+
+// SaveMatchFound remembers which room a matched player was sent to, for
+// clients that missed the push.
+func SaveMatchFound(userID, roomID string, ttl time.Duration) error {
+	return RDB.Set(ctx, MatchFoundKey(userID), roomID, ttl).Err()
+}
+
+// LoadMatchFound returns the room the player was last matched into, or "".
+func LoadMatchFound(userID string) (string, error) {
+	roomID, err := RDB.Get(ctx, MatchFoundKey(userID)).Result()
+	if err == redis.Nil {
+		return "", nil
+	}
+	return roomID, err
+}
@@ -0,0 +1,68 @@
+package database
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// PlayerMMR is a player's matchmaking rating. Unlike PlayerRating it moves
+// after every decided game, ranked or not, so matchmaking has something to
+// go on for players who never play ranked.
+type PlayerMMR struct {
+	UserID    string    `json:"user_id"`
+	MMR       float64   `json:"mmr"`
+	Games     int       `json:"games"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// GetMMRs returns the stored MMR for the given players. Players who have
+// never finished a game are simply absent from the map.
+func GetMMRs(userIDs []string) (map[string]PlayerMMR, error) {
+	if SupabaseClient == nil {
+		return nil, ErrSupabaseNotConfigured
+	}
+
+	mmrs := make(map[string]PlayerMMR, len(userIDs))
+	if len(userIDs) == 0 {
+		return mmrs, nil
+	}
+
+	var rows []PlayerMMR
+	data, _, err := SupabaseClient.From("player_mmr").
+		Select("*", "", false).
+		In("user_id", userIDs).
+		Execute()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load MMR: %w", err)
+	}
+	if err := json.Unmarshal(data, &rows); err != nil {
+		return nil, fmt.Errorf("failed to parse MMR: %w", err)
+	}
+
+	for _, row := range rows {
+		mmrs[row.UserID] = row
+	}
+	return mmrs, nil
+}
+
+func SaveMMRs(mmrs []PlayerMMR) error {
+	if SupabaseClient == nil {
+		return ErrSupabaseNotConfigured
+	}
+	if len(mmrs) == 0 {
+		return nil
+	}
+
+	_, _, err := SupabaseClient.From("player_mmr").
+		Upsert(mmrs, "user_id", "", "").
+		Execute()
+	if err != nil {
+		return fmt.Errorf("failed to save MMR: %w", err)
+	}
+
+	for _, m := range mmrs {
+		InvalidatePlayerStats(m.UserID)
+	}
+	return nil
+}
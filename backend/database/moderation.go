@@ -0,0 +1,196 @@
+package database
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	ReportStatusOpen      = "open"
+	ReportStatusActioned  = "actioned"
+	ReportStatusDismissed = "dismissed"
+
+	reportsKey     = "moderation:reports"
+	reportQueueKey = "moderation:queue"
+
+	auditLogLimit = 500
+	auditLogTTL   = 24 * time.Hour
+)
+
+type Report struct {
+	ID           string     `json:"id"`
+	RoomID       string     `json:"roomId"`
+	ReporterID   string     `json:"reporterId"`
+	ReporterName string     `json:"reporterName"`
+	TargetID     string     `json:"targetId"`
+	TargetName   string     `json:"targetName"`
+	Reason       string     `json:"reason"`
+	Details      string     `json:"details,omitempty"`
+	Status       string     `json:"status"`
+	Action       string     `json:"action,omitempty"`
+	ActionNote   string     `json:"actionNote,omitempty"`
+	CreatedAt    time.Time  `json:"createdAt"`
+	ResolvedAt   *time.Time `json:"resolvedAt,omitempty"`
+}
+
+type AuditEntry struct {
+	Time     time.Time              `json:"time"`
+	Type     string                 `json:"type"`
+	PlayerID string                 `json:"playerId,omitempty"`
+	Username string                 `json:"username,omitempty"`
+	Details  map[string]interface{} `json:"details,omitempty"`
+}
+
+func ReportQuotaKey(reporterID string, hour int64) string {
+	return keyf("moderation:report_quota:%s:%d", reporterID, hour)
+}
+
+func ReportedKey(reporterID, targetID string) string {
+	return keyf("moderation:reported:%s:%s", reporterID, targetID)
+}
+
+// ReserveReport lets reporterID file a report on targetID if they haven't
+// reported them within pairCooldown and have reports left of perHour.
+// When either is used up nothing is taken and ok is false.
+func ReserveReport(reporterID, targetID string, perHour int, pairCooldown time.Duration) (ok bool, err error) {
+	pairKey := ReportedKey(reporterID, targetID)
+	fresh, err := RDB.SetNX(ctx, pairKey, 1, pairCooldown).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to reserve report: %w", err)
+	}
+	if !fresh {
+		return false, nil
+	}
+
+	quotaKey := ReportQuotaKey(reporterID, time.Now().Unix()/3600)
+	used, err := RDB.Incr(ctx, quotaKey).Result()
+	if err != nil {
+		RDB.Del(ctx, pairKey)
+		return false, fmt.Errorf("failed to reserve report: %w", err)
+	}
+	RDB.Expire(ctx, quotaKey, time.Hour)
+
+	if used > int64(perHour) {
+		RDB.Decr(ctx, quotaKey)
+		RDB.Del(ctx, pairKey)
+		return false, nil
+	}
+	return true, nil
+}
+
+func RoomAuditKey(roomID string) string {
+	return keyf("room:%s:audit", roomID)
+}
+
+// AppendAudit records a room event. The audit log deliberately outlives the
+// room (DeleteRoom leaves it alone) so reports can be reviewed afterwards.
+func AppendAudit(roomID string, entry AuditEntry) error {
+	jsonData, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to marshal audit entry: %w", err)
+	}
+
+	key := RoomAuditKey(roomID)
+	if err := RDB.RPush(ctx, key, jsonData).Err(); err != nil {
+		return fmt.Errorf("failed to append audit entry: %w", err)
+	}
+	RDB.LTrim(ctx, key, -auditLogLimit, -1)
+	RDB.Expire(ctx, key, auditLogTTL)
+
+	return nil
+}
+
+func GetAuditLog(roomID string, limit int) ([]AuditEntry, error) {
+	raw, err := RDB.LRange(ctx, RoomAuditKey(roomID), int64(-limit), -1).Result()
+	if err != nil && err != redis.Nil {
+		return nil, fmt.Errorf("failed to load audit log: %w", err)
+	}
+
+	entries := make([]AuditEntry, 0, len(raw))
+	for _, item := range raw {
+		var entry AuditEntry
+		if err := json.Unmarshal([]byte(item), &entry); err == nil {
+			entries = append(entries, entry)
+		}
+	}
+
+	return entries, nil
+}
+
+func SaveReport(report Report) error {
+	jsonData, err := json.Marshal(report)
+	if err != nil {
+		return fmt.Errorf("failed to marshal report: %w", err)
+	}
+
+	if err := RDB.HSet(ctx, Key(reportsKey), report.ID, jsonData).Err(); err != nil {
+		return fmt.Errorf("failed to save report: %w", err)
+	}
+
+	if report.Status == ReportStatusOpen {
+		err = RDB.ZAdd(ctx, Key(reportQueueKey), redis.Z{
+			Score:  float64(report.CreatedAt.Unix()),
+			Member: report.ID,
+		}).Err()
+	} else {
+		err = RDB.ZRem(ctx, Key(reportQueueKey), report.ID).Err()
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update report queue: %w", err)
+	}
+
+	return nil
+}
+
+func GetReport(id string) (*Report, error) {
+	jsonData, err := RDB.HGet(ctx, Key(reportsKey), id).Result()
+	if err == redis.Nil {
+		return nil, fmt.Errorf("report not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load report: %w", err)
+	}
+
+	var report Report
+	if err := json.Unmarshal([]byte(jsonData), &report); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal report: %w", err)
+	}
+
+	return &report, nil
+}
+
+// ListReports returns open reports oldest-first, or every report when
+// status is empty.
+func ListReports(status string, limit int) ([]Report, error) {
+	var ids []string
+	var err error
+
+	if status == ReportStatusOpen {
+		ids, err = RDB.ZRange(ctx, Key(reportQueueKey), 0, int64(limit-1)).Result()
+	} else {
+		ids, err = RDB.HKeys(ctx, Key(reportsKey)).Result()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to list reports: %w", err)
+	}
+
+	reports := make([]Report, 0, len(ids))
+	for _, id := range ids {
+		report, err := GetReport(id)
+		if err != nil {
+			continue
+		}
+		if status != "" && report.Status != status {
+			continue
+		}
+		reports = append(reports, *report)
+		if len(reports) >= limit {
+			break
+		}
+	}
+
+	return reports, nil
+}
@@ -0,0 +1,74 @@
+package database
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+type PushDevice struct {
+	UserID    string    `json:"user_id"`
+	Platform  string    `json:"platform"`
+	Token     string    `json:"token"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// SavePushDevice registers a device, moving it to userID if another
+// account registered the same token before.
+func SavePushDevice(device PushDevice) error {
+	if SupabaseClient == nil {
+		return ErrSupabaseNotConfigured
+	}
+
+	_, _, err := SupabaseClient.From("push_devices").
+		Upsert(device, "token", "", "").
+		Execute()
+	if err != nil {
+		return fmt.Errorf("failed to save push device: %w", err)
+	}
+	return nil
+}
+
+// DeletePushDevice forgets a token. With a userID it only removes the
+// token if it belongs to that user.
+func DeletePushDevice(userID, token string) error {
+	if SupabaseClient == nil {
+		return ErrSupabaseNotConfigured
+	}
+
+	query := SupabaseClient.From("push_devices").
+		Delete("", "").
+		Eq("token", token)
+	if userID != "" {
+		query = query.Eq("user_id", userID)
+	}
+
+	_, _, err := query.Execute()
+	if err != nil {
+		return fmt.Errorf("failed to delete push device: %w", err)
+	}
+	return nil
+}
+
+func ListPushDevices(userIDs []string) ([]PushDevice, error) {
+	if SupabaseClient == nil {
+		return nil, ErrSupabaseNotConfigured
+	}
+
+	devices := []PushDevice{}
+	if len(userIDs) == 0 {
+		return devices, nil
+	}
+
+	data, _, err := SupabaseClient.From("push_devices").
+		Select("*", "", false).
+		In("user_id", userIDs).
+		Execute()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load push devices: %w", err)
+	}
+	if err := json.Unmarshal(data, &devices); err != nil {
+		return nil, fmt.Errorf("failed to parse push devices: %w", err)
+	}
+	return devices, nil
+}
@@ -0,0 +1,88 @@
+package database
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/supabase-community/postgrest-go"
+)
+
+type PlayerRating struct {
+	UserID      string    `json:"user_id"`
+	Rating      float64   `json:"rating"`
+	RankedGames int       `json:"ranked_games"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// GetRatings returns the stored ratings for the given players. Players
+// who have never played a ranked game are simply absent from the map.
+func GetRatings(userIDs []string) (map[string]PlayerRating, error) {
+	if SupabaseClient == nil {
+		return nil, ErrSupabaseNotConfigured
+	}
+
+	ratings := make(map[string]PlayerRating, len(userIDs))
+	if len(userIDs) == 0 {
+		return ratings, nil
+	}
+
+	var rows []PlayerRating
+	data, _, err := SupabaseClient.From("player_ratings").
+		Select("*", "", false).
+		In("user_id", userIDs).
+		Execute()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load ratings: %w", err)
+	}
+	if err := json.Unmarshal(data, &rows); err != nil {
+		return nil, fmt.Errorf("failed to parse ratings: %w", err)
+	}
+
+	for _, row := range rows {
+		ratings[row.UserID] = row
+	}
+	return ratings, nil
+}
+
+func SaveRatings(ratings []PlayerRating) error {
+	if SupabaseClient == nil {
+		return ErrSupabaseNotConfigured
+	}
+	if len(ratings) == 0 {
+		return nil
+	}
+
+	_, _, err := SupabaseClient.From("player_ratings").
+		Upsert(ratings, "user_id", "", "").
+		Execute()
+	if err != nil {
+		return fmt.Errorf("failed to save ratings: %w", err)
+	}
+
+	for _, r := range ratings {
+		InvalidatePlayerStats(r.UserID)
+	}
+	return nil
+}
+
+// TopRatings returns the highest-rated players, best first.
+func TopRatings(limit, offset int) ([]PlayerRating, error) {
+	if SupabaseClient == nil {
+		return nil, ErrSupabaseNotConfigured
+	}
+
+	var rows []PlayerRating
+	data, _, err := SupabaseClient.From("player_ratings").
+		Select("*", "", false).
+		Order("rating", &postgrest.OrderOpts{Ascending: false}).
+		Range(offset, offset+limit-1, "").
+		Execute()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load rating leaderboard: %w", err)
+	}
+	if err := json.Unmarshal(data, &rows); err != nil {
+		return nil, fmt.Errorf("failed to parse rating leaderboard: %w", err)
+	}
+	return rows, nil
+}
@@ -4,12 +4,18 @@ import (
 	"context"
 	"crypto/tls"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"net"
 	"os"
 	"strings"
+	"sync"
 	"time"
 
+	"code-mafia-backend/alerts"
+
+	"github.com/google/uuid"
 	"github.com/redis/go-redis/v9"
 )
 
@@ -18,7 +24,46 @@ var (
 	RDB *redis.Client
 )
 
-func InitRedis(addr, password string, db int) error {
+// dialMonitor counts new connections opened by the pool. A healthy pool
+// reuses connections, so a burst of dials means Redis keeps dropping us.
+type dialMonitor struct {
+	mu          sync.Mutex
+	windowStart time.Time
+	dials       int
+	threshold   int
+}
+
+func (m *dialMonitor) DialHook(next redis.DialHook) redis.DialHook {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		conn, err := next(ctx, network, addr)
+
+		m.mu.Lock()
+		if time.Since(m.windowStart) > time.Minute {
+			m.windowStart = time.Now()
+			m.dials = 0
+		}
+		m.dials++
+		dials := m.dials
+		m.mu.Unlock()
+
+		if m.threshold > 0 && dials > m.threshold {
+			alerts.Fire(alerts.KindRedisReconnects, addr,
+				"%d Redis dials to %s within a minute (threshold %d), last error: %v", dials, addr, m.threshold, err)
+		}
+
+		return conn, err
+	}
+}
+
+func (m *dialMonitor) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return next
+}
+
+func (m *dialMonitor) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return next
+}
+
+func InitRedis(addr, password string, db int, dialAlertThreshold int) error {
 	options := &redis.Options{
 		Addr:         addr,
 		Password:     password,
@@ -43,6 +88,7 @@ func InitRedis(addr, password string, db int) error {
 	}
 
 	RDB = redis.NewClient(options)
+	RDB.AddHook(&dialMonitor{windowStart: time.Now(), threshold: dialAlertThreshold})
 
 	if err := RDB.Ping(ctx).Err(); err != nil {
 		return fmt.Errorf("redis connection failed: %w", err)
@@ -52,34 +98,44 @@ func InitRedis(addr, password string, db int) error {
 	return nil
 }
 
+// Ping checks that Redis answers, for readiness probes.
+func Ping(c context.Context) error {
+	if RDB == nil {
+		return fmt.Errorf("redis is not initialized")
+	}
+	return RDB.Ping(c).Err()
+}
+
+// roomStateTTL is how long a room's state outlives its last save.
+const roomStateTTL = time.Hour
+
+// activeRoomsKey indexes every room with state, scored by the Unix time it
+// was last saved, so listing rooms never has to walk the keyspace.
+const activeRoomsKey = "rooms:active"
+
 func RoomStateKey(roomID string) string {
-	return fmt.Sprintf("room:%s:state", roomID)
+	return keyf("room:%s:state", roomID)
 }
 
 func RoomPlayersKey(roomID string) string {
-	return fmt.Sprintf("room:%s:players", roomID)
+	return keyf("room:%s:players", roomID)
 }
 
 func PlayerSessionKey(playerID string) string {
-	return fmt.Sprintf("player:%s:session", playerID)
-}
-
-func RoomTimerKey(roomID string) string {
-	return fmt.Sprintf("room:%s:timer_start", roomID)
+	return keyf("player:%s:session", playerID)
 }
 
-func SaveGameState(roomID string, state interface{}) error {
-	jsonData, err := json.Marshal(state)
-	if err != nil {
-		return fmt.Errorf("failed to marshal game state: %w", err)
+// LoadGameStateJSON returns a room's saved state as it was written, for
+// callers that migrate it before decoding.
+func LoadGameStateJSON(roomID string) ([]byte, error) {
+	jsonData, err := RDB.Get(ctx, RoomStateKey(roomID)).Bytes()
+	if err == redis.Nil {
+		return nil, fmt.Errorf("game state not found")
 	}
-
-	err = RDB.Set(ctx, RoomStateKey(roomID), jsonData, time.Hour).Err()
 	if err != nil {
-		return fmt.Errorf("failed to save game state: %w", err)
+		return nil, fmt.Errorf("failed to load game state: %w", err)
 	}
-
-	return nil
+	return jsonData, nil
 }
 
 func LoadGameState(roomID string, target interface{}) error {
@@ -98,24 +154,99 @@ func LoadGameState(roomID string, target interface{}) error {
 	return nil
 }
 
-func SavePlayer(roomID string, player interface{}) error {
-	jsonData, err := json.Marshal(player)
+// ErrStaleRoomState is returned by SaveRoom when the stored state has moved
+// on from the revision the caller last saved or loaded, meaning something
+// else wrote the room in between.
+var ErrStaleRoomState = errors.New("room state was saved by someone else")
+
+// roomRevision is the part of a saved state SaveRoom checks.
+type roomRevision struct {
+	Revision int64 `json:"revision"`
+}
+
+// RoomStateRevision returns the revision of a room's saved state, 0 if it
+// has none or no state at all.
+func RoomStateRevision(roomID string) (int64, error) {
+	return roomStateRevision(RDB, roomID)
+}
+
+func roomStateRevision(c redis.Cmdable, roomID string) (int64, error) {
+	jsonData, err := c.Get(ctx, RoomStateKey(roomID)).Bytes()
+	if err == redis.Nil {
+		return 0, nil
+	}
 	if err != nil {
-		return fmt.Errorf("failed to marshal player: %w", err)
+		return 0, fmt.Errorf("failed to load game state: %w", err)
 	}
+	var stored roomRevision
+	if err := json.Unmarshal(jsonData, &stored); err != nil {
+		return 0, fmt.Errorf("failed to unmarshal game state: %w", err)
+	}
+	return stored.Revision, nil
+}
 
-	playerMap := make(map[string]interface{})
-	json.Unmarshal(jsonData, &playerMap)
-	playerID := playerMap["id"].(string)
-
-	err = RDB.HSet(ctx, RoomPlayersKey(roomID), playerID, jsonData).Err()
+// SaveRoom writes a room's state and the given players, already encoded
+// and keyed by ID, in one transaction. Players left out are kept as they
+// were. The write only goes through if the stored state is still at
+// expected, the revision the caller last saved or loaded; otherwise it
+// returns ErrStaleRoomState and writes nothing.
+func SaveRoom(roomID string, state interface{}, expected int64, players map[string][]byte) error {
+	jsonData, err := json.Marshal(state)
 	if err != nil {
-		return fmt.Errorf("failed to save player: %w", err)
+		return fmt.Errorf("failed to marshal game state: %w", err)
 	}
 
-	RDB.Expire(ctx, RoomPlayersKey(roomID), time.Hour)
+	err = RDB.Watch(ctx, func(tx *redis.Tx) error {
+		stored, err := roomStateRevision(tx, roomID)
+		if err != nil {
+			return err
+		}
+		if stored != expected {
+			return fmt.Errorf("%w: stored revision %d, expected %d", ErrStaleRoomState, stored, expected)
+		}
 
-	return nil
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.Set(ctx, RoomStateKey(roomID), jsonData, roomStateTTL)
+			pipe.ZAdd(ctx, Key(activeRoomsKey), redis.Z{Score: float64(time.Now().Unix()), Member: roomID})
+			if len(players) > 0 {
+				fields := make([]interface{}, 0, 2*len(players))
+				for id, data := range players {
+					fields = append(fields, id, data)
+				}
+				pipe.HSet(ctx, RoomPlayersKey(roomID), fields...)
+			}
+			pipe.Expire(ctx, RoomPlayersKey(roomID), roomStateTTL)
+			return nil
+		})
+		return err
+	}, RoomStateKey(roomID))
+
+	if err == redis.TxFailedErr {
+		return fmt.Errorf("%w: state changed during the save", ErrStaleRoomState)
+	}
+	if err != nil && !errors.Is(err, ErrStaleRoomState) {
+		return fmt.Errorf("failed to save room: %w", err)
+	}
+	return err
+}
+
+// LoadRoomSnapshot reads a room's saved state and players together. The
+// state is nil if the room has none.
+func LoadRoomSnapshot(roomID string) ([]byte, map[string]string, error) {
+	pipe := RDB.TxPipeline()
+	stateCmd := pipe.Get(ctx, RoomStateKey(roomID))
+	playersCmd := pipe.HGetAll(ctx, RoomPlayersKey(roomID))
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		return nil, nil, fmt.Errorf("failed to load room: %w", err)
+	}
+
+	state, err := stateCmd.Bytes()
+	if err == redis.Nil {
+		state = nil
+	} else if err != nil {
+		return nil, nil, fmt.Errorf("failed to load game state: %w", err)
+	}
+	return state, playersCmd.Val(), nil
 }
 
 func LoadPlayer(roomID, playerID string, target interface{}) error {
@@ -142,16 +273,56 @@ func DeletePlayer(roomID, playerID string) error {
 	return RDB.HDel(ctx, RoomPlayersKey(roomID), playerID).Err()
 }
 
-func SaveTimerStart(roomID string, startTime time.Time) error {
-	return RDB.Set(ctx, RoomTimerKey(roomID), startTime.Unix(), time.Hour).Err()
+// PlayerSession is what a client has to present to take its seat back
+// after losing its connection mid-game.
+type PlayerSession struct {
+	Token  string `json:"token"`
+	RoomID string `json:"roomId"`
 }
 
-func LoadTimerStart(roomID string) (time.Time, error) {
-	unixTime, err := RDB.Get(ctx, RoomTimerKey(roomID)).Int64()
+func SavePlayerSession(playerID string, session PlayerSession, ttl time.Duration) error {
+	jsonData, err := json.Marshal(session)
 	if err != nil {
-		return time.Time{}, err
+		return fmt.Errorf("failed to marshal session: %w", err)
+	}
+	if err := RDB.Set(ctx, PlayerSessionKey(playerID), jsonData, ttl).Err(); err != nil {
+		return fmt.Errorf("failed to save session: %w", err)
 	}
-	return time.Unix(unixTime, 0), nil
+	return nil
+}
+
+func LoadPlayerSession(playerID string) (*PlayerSession, error) {
+	jsonData, err := RDB.Get(ctx, PlayerSessionKey(playerID)).Result()
+	if err == redis.Nil {
+		return nil, fmt.Errorf("session not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load session: %w", err)
+	}
+
+	var session PlayerSession
+	if err := json.Unmarshal([]byte(jsonData), &session); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal session: %w", err)
+	}
+	return &session, nil
+}
+
+// CreateRoom writes a new room's initial state unless the code is already
+// taken, and reports whether it was written.
+func CreateRoom(roomID string, state interface{}) (bool, error) {
+	jsonData, err := json.Marshal(state)
+	if err != nil {
+		return false, fmt.Errorf("failed to marshal game state: %w", err)
+	}
+
+	created, err := RDB.SetNX(ctx, RoomStateKey(roomID), jsonData, roomStateTTL).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to create room: %w", err)
+	}
+	if created {
+		RDB.ZAdd(ctx, Key(activeRoomsKey), redis.Z{Score: float64(time.Now().Unix()), Member: roomID})
+	}
+	return created, nil
 }
 
 func RoomExists(roomID string) bool {
@@ -163,44 +334,51 @@ func DeleteRoom(roomID string) error {
 	keys := []string{
 		RoomStateKey(roomID),
 		RoomPlayersKey(roomID),
-		RoomTimerKey(roomID),
-		fmt.Sprintf("room:%s:chat_history", roomID),
+		keyf("room:%s:chat_history", roomID),
+		RoomChatLogKey(roomID),
+		RoomWebhooksKey(roomID),
 	}
 
-	return RDB.Del(ctx, keys...).Err()
+	pipe := RDB.TxPipeline()
+	pipe.Del(ctx, keys...)
+	pipe.ZRem(ctx, Key(activeRoomsKey), roomID)
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// pruneActiveRooms drops index entries whose state has expired unsaved.
+func pruneActiveRooms() error {
+	cutoff := time.Now().Add(-roomStateTTL).Unix()
+	return RDB.ZRemRangeByScore(ctx, Key(activeRoomsKey), "-inf", fmt.Sprintf("(%d", cutoff)).Err()
 }
 
+// GetActiveRooms returns every room with state, most recently saved first.
 func GetActiveRooms() ([]string, error) {
-	keys, err := RDB.Keys(ctx, "room:*:state").Result()
+	if err := pruneActiveRooms(); err != nil {
+		return nil, fmt.Errorf("failed to prune room index: %w", err)
+	}
+	rooms, err := RDB.ZRevRange(ctx, Key(activeRoomsKey), 0, -1).Result()
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("failed to load room index: %w", err)
 	}
+	return rooms, nil
+}
 
-	rooms := make([]string, 0, len(keys))
-	for _, key := range keys {
-
-		parts := splitKey(key)
-		if len(parts) >= 2 {
-			rooms = append(rooms, parts[1])
-		}
+func CountActiveRooms() (int64, error) {
+	if err := pruneActiveRooms(); err != nil {
+		return 0, fmt.Errorf("failed to prune room index: %w", err)
 	}
-
-	return rooms, nil
+	return RDB.ZCard(ctx, Key(activeRoomsKey)).Result()
 }
 
-func splitKey(key string) []string {
-	result := []string{}
-	current := ""
-	for _, char := range key {
-		if char == ':' {
-			result = append(result, current)
-			current = ""
-		} else {
-			current += string(char)
-		}
+// RoomLastSaved is when the room's state was last written, from the index.
+// It reports false for rooms that aren't in it.
+func RoomLastSaved(roomID string) (time.Time, bool) {
+	score, err := RDB.ZScore(ctx, Key(activeRoomsKey), roomID).Result()
+	if err != nil {
+		return time.Time{}, false
 	}
-	result = append(result, current)
-	return result
+	return time.Unix(int64(score), 0), true
 }
 
 func PublishChatMessage(messageID, text, username, roomID, playerID string, context []string) error {
@@ -219,7 +397,7 @@ func PublishChatMessage(messageID, text, username, roomID, playerID string, cont
 		return fmt.Errorf("failed to marshal chat message: %w", err)
 	}
 
-	err = RDB.Publish(ctx, "chat:processing", jsonData).Err()
+	err = RDB.Publish(ctx, ChatProcessingChannel(), jsonData).Err()
 	if err != nil {
 		return fmt.Errorf("failed to publish chat message: %w", err)
 	}
@@ -228,8 +406,50 @@ func PublishChatMessage(messageID, text, username, roomID, playerID string, cont
 	return nil
 }
 
+// PublishTaskTranslation asks the translation service to translate one
+// field ("title" or "description") of a room's task. The answer comes back
+// on TaskTranslationsChannel.
+func PublishTaskTranslation(roomID, taskID, field, text string) error {
+	payload := map[string]interface{}{
+		"type":      "task_translation",
+		"taskId":    taskID,
+		"roomId":    roomID,
+		"field":     field,
+		"text":      text,
+		"requestId": uuid.New().String(),
+	}
+
+	jsonData, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal task translation: %w", err)
+	}
+
+	err = RDB.Publish(ctx, TaskTranslateChannel(), jsonData).Err()
+	if err != nil {
+		return fmt.Errorf("failed to publish task translation: %w", err)
+	}
+	return nil
+}
+
+// PublishHintRequest sends a hint request to the hint service on channel,
+// under the key prefix.
+// It reports how many subscribers got it, so a request nobody is listening
+// for can be turned down at once.
+func PublishHintRequest(channel string, request interface{}) (int64, error) {
+	jsonData, err := json.Marshal(request)
+	if err != nil {
+		return 0, fmt.Errorf("failed to marshal hint request: %w", err)
+	}
+
+	receivers, err := RDB.Publish(ctx, Key(channel), jsonData).Result()
+	if err != nil {
+		return 0, fmt.Errorf("failed to publish hint request: %w", err)
+	}
+	return receivers, nil
+}
+
 func GetRoomChatHistory(roomID string, limit int) ([]string, error) {
-	key := fmt.Sprintf("room:%s:chat_history", roomID)
+	key := keyf("room:%s:chat_history", roomID)
 	
 	messages, err := RDB.LRange(ctx, key, 0, int64(limit-1)).Result()
 	if err != nil && err != redis.Nil {
@@ -240,7 +460,7 @@ func GetRoomChatHistory(roomID string, limit int) ([]string, error) {
 }
 
 func AddToChatHistory(roomID, message string) error {
-	key := fmt.Sprintf("room:%s:chat_history", roomID)
+	key := keyf("room:%s:chat_history", roomID)
 	
 	// Add to list
 	err := RDB.LPush(ctx, key, message).Err()
@@ -258,4 +478,33 @@ func AddToChatHistory(roomID, message string) error {
 	RDB.Expire(ctx, key, time.Hour)
 	
 	return nil
-}
\ No newline at end of file
+}
+// RoomChatLogKey holds the chat messages a room has broadcast, oldest
+// first, as sent. chat_history only keeps recent text, as context for
+// translation.
+func RoomChatLogKey(roomID string) string {
+	return keyf("room:%s:chat_log", roomID)
+}
+
+// AppendChatLog records a broadcast chat message, keeping the latest keep.
+func AppendChatLog(roomID string, message []byte, keep int) error {
+	key := RoomChatLogKey(roomID)
+
+	pipe := RDB.TxPipeline()
+	pipe.RPush(ctx, key, message)
+	pipe.LTrim(ctx, key, int64(-keep), -1)
+	pipe.Expire(ctx, key, roomStateTTL)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to append to chat log: %w", err)
+	}
+	return nil
+}
+
+// ChatLog returns a room's latest limit chat messages, oldest first.
+func ChatLog(roomID string, limit int) ([]string, error) {
+	messages, err := RDB.LRange(ctx, RoomChatLogKey(roomID), int64(-limit), -1).Result()
+	if err != nil && err != redis.Nil {
+		return nil, fmt.Errorf("failed to get chat log: %w", err)
+	}
+	return messages, nil
+}
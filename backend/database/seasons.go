@@ -0,0 +1,183 @@
+package database
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/supabase-community/postgrest-go"
+)
+
+// Season is a row of the seasons table. Operators schedule seasons there;
+// the server switches to whichever one covers the current time.
+type Season struct {
+	ID       string    `json:"id"`
+	Name     string    `json:"name"`
+	Theme    string    `json:"theme"`
+	StartsAt time.Time `json:"starts_at"`
+	EndsAt   time.Time `json:"ends_at"`
+}
+
+func (s Season) ActiveAt(t time.Time) bool {
+	return !t.Before(s.StartsAt) && t.Before(s.EndsAt)
+}
+
+// SeasonTask is one task of a season's themed pack, stored in season_tasks.
+type SeasonTask struct {
+	SeasonID    string `json:"season_id"`
+	ID          string `json:"id"`
+	Stage       int    `json:"stage"`
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	Template    string `json:"template"`
+	// Tests is the Java TaskTests class run against submissions. Tasks
+	// without it are judged by the standard task's source patterns.
+	Tests string `json:"tests,omitempty"`
+}
+
+func ListSeasons() ([]Season, error) {
+	if SupabaseClient == nil {
+		return nil, ErrSupabaseNotConfigured
+	}
+
+	var seasons []Season
+	data, _, err := SupabaseClient.From("seasons").
+		Select("*", "", false).
+		Order("starts_at", &postgrest.OrderOpts{Ascending: true}).
+		Execute()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load seasons: %w", err)
+	}
+	if err := json.Unmarshal(data, &seasons); err != nil {
+		return nil, fmt.Errorf("failed to parse seasons: %w", err)
+	}
+	return seasons, nil
+}
+
+func GetSeasonTasks(seasonID string) ([]SeasonTask, error) {
+	if SupabaseClient == nil {
+		return nil, ErrSupabaseNotConfigured
+	}
+
+	var tasks []SeasonTask
+	data, _, err := SupabaseClient.From("season_tasks").
+		Select("*", "", false).
+		Eq("season_id", seasonID).
+		Order("stage", &postgrest.OrderOpts{Ascending: true}).
+		Execute()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load season tasks: %w", err)
+	}
+	if err := json.Unmarshal(data, &tasks); err != nil {
+		return nil, fmt.Errorf("failed to parse season tasks: %w", err)
+	}
+	return tasks, nil
+}
+
+// SeasonStats is a player's record for one season. It lives in Redis
+// beside the season leaderboard, which ranks players by wins.
+type SeasonStats struct {
+	PlayerID    string    `json:"playerId"`
+	Username    string    `json:"username"`
+	GamesPlayed int       `json:"gamesPlayed"`
+	GamesWon    int       `json:"gamesWon"`
+	UpdatedAt   time.Time `json:"updatedAt"`
+}
+
+func SeasonStatsKey(seasonID string) string {
+	return keyf("season:%s:stats", seasonID)
+}
+
+func SeasonLeaderboardKey(seasonID string) string {
+	return keyf("season:%s:leaderboard", seasonID)
+}
+
+func RecordSeasonResult(seasonID, playerID, username string, won bool) error {
+	stats, err := GetSeasonStats(seasonID, playerID)
+	if err != nil {
+		return err
+	}
+	if stats == nil {
+		stats = &SeasonStats{PlayerID: playerID}
+	}
+
+	stats.Username = username
+	stats.GamesPlayed++
+	if won {
+		stats.GamesWon++
+	}
+	stats.UpdatedAt = time.Now()
+
+	jsonData, err := json.Marshal(stats)
+	if err != nil {
+		return fmt.Errorf("failed to marshal season stats: %w", err)
+	}
+
+	pipe := RDB.TxPipeline()
+	pipe.HSet(ctx, SeasonStatsKey(seasonID), playerID, jsonData)
+	pipe.ZAdd(ctx, SeasonLeaderboardKey(seasonID), redis.Z{Score: float64(stats.GamesWon), Member: playerID})
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to record season result: %w", err)
+	}
+	return nil
+}
+
+// GetSeasonStats returns nil, nil if the player hasn't played that season.
+func GetSeasonStats(seasonID, playerID string) (*SeasonStats, error) {
+	data, err := RDB.HGet(ctx, SeasonStatsKey(seasonID), playerID).Bytes()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load season stats: %w", err)
+	}
+
+	var stats SeasonStats
+	if err := json.Unmarshal(data, &stats); err != nil {
+		return nil, fmt.Errorf("failed to parse season stats: %w", err)
+	}
+	return &stats, nil
+}
+
+// GetSeasonLeaderboard returns players with the most wins first.
+func GetSeasonLeaderboard(seasonID string, limit int) ([]SeasonStats, error) {
+	ids, err := RDB.ZRevRange(ctx, SeasonLeaderboardKey(seasonID), 0, int64(limit-1)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load season leaderboard: %w", err)
+	}
+
+	entries := []SeasonStats{}
+	if len(ids) == 0 {
+		return entries, nil
+	}
+
+	values, err := RDB.HMGet(ctx, SeasonStatsKey(seasonID), ids...).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load season stats: %w", err)
+	}
+
+	for _, v := range values {
+		s, ok := v.(string)
+		if !ok {
+			continue
+		}
+		var stats SeasonStats
+		if err := json.Unmarshal([]byte(s), &stats); err == nil {
+			entries = append(entries, stats)
+		}
+	}
+	return entries, nil
+}
+
+// ExpireSeasonStats keeps a finished season's standings readable for
+// retention and then lets Redis drop them.
+func ExpireSeasonStats(seasonID string, retention time.Duration) error {
+	pipe := RDB.TxPipeline()
+	pipe.Expire(ctx, SeasonStatsKey(seasonID), retention)
+	pipe.Expire(ctx, SeasonLeaderboardKey(seasonID), retention)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to expire season stats: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,166 @@
+package database
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const playerStatsTTL = 10 * time.Minute
+
+type RoleStats struct {
+	Played int `json:"played"`
+	Won    int `json:"won"`
+}
+
+type PlayerStats struct {
+	PlayerID    string               `json:"playerId"`
+	GamesPlayed int                  `json:"gamesPlayed"`
+	GamesWon    int                  `json:"gamesWon"`
+	ByRole      map[string]RoleStats `json:"byRole"`
+
+	// Detection accuracy covers votes cast as a civilian: the share that
+	// named the actual impostor. Skips are not counted.
+	VotesCast                 int     `json:"votesCast"`
+	CorrectVotes              int     `json:"correctVotes"`
+	ImpostorDetectionAccuracy float64 `json:"impostorDetectionAccuracy"`
+
+	Rating      *float64 `json:"rating,omitempty"`
+	RankedGames int      `json:"rankedGames"`
+	// MMR is the matchmaking rating, which every decided game moves.
+	MMR *float64 `json:"mmr,omitempty"`
+
+	AvgStageSeconds float64   `json:"avgStageSeconds"`
+	ComputedAt      time.Time `json:"computedAt"`
+}
+
+func PlayerStatsKey(userID string) string {
+	return keyf("player:%s:stats", userID)
+}
+
+// GetPlayerStats serves aggregate stats from Redis, recomputing them from
+// match history when the cache is cold.
+func GetPlayerStats(userID string) (*PlayerStats, error) {
+	key := PlayerStatsKey(userID)
+
+	data, err := RDB.Get(ctx, key).Bytes()
+	if err == nil {
+		var stats PlayerStats
+		if err := json.Unmarshal(data, &stats); err == nil {
+			return &stats, nil
+		}
+	} else if err != redis.Nil {
+		log.Printf("Failed to read cached stats for %s: %v", userID, err)
+	}
+
+	stats, err := ComputePlayerStats(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if jsonData, err := json.Marshal(stats); err == nil {
+		if err := RDB.Set(ctx, key, jsonData, playerStatsTTL).Err(); err != nil {
+			log.Printf("Failed to cache stats for %s: %v", userID, err)
+		}
+	}
+
+	return stats, nil
+}
+
+func InvalidatePlayerStats(userID string) {
+	if RDB == nil {
+		return
+	}
+	if err := RDB.Del(ctx, PlayerStatsKey(userID)).Err(); err != nil {
+		log.Printf("Failed to invalidate stats for %s: %v", userID, err)
+	}
+}
+
+func ComputePlayerStats(userID string) (*PlayerStats, error) {
+	rows, err := GetPlayerMatches(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := &PlayerStats{
+		PlayerID:   userID,
+		ByRole:     make(map[string]RoleStats),
+		ComputedAt: time.Now(),
+	}
+	if ratings, err := GetRatings([]string{userID}); err != nil {
+		log.Printf("Failed to load rating for %s: %v", userID, err)
+	} else if r, ok := ratings[userID]; ok {
+		stats.Rating = &r.Rating
+		stats.RankedGames = r.RankedGames
+	}
+	if mmrs, err := GetMMRs([]string{userID}); err != nil {
+		log.Printf("Failed to load MMR for %s: %v", userID, err)
+	} else if m, ok := mmrs[userID]; ok {
+		stats.MMR = &m.MMR
+	}
+
+	if len(rows) == 0 {
+		return stats, nil
+	}
+
+	ids := make([]string, 0, len(rows))
+	for _, row := range rows {
+		ids = append(ids, row.MatchID)
+	}
+
+	var matches []GameMatch
+	data, _, err := SupabaseClient.From("game_matches").
+		Select("*", "", false).
+		In("id", ids).
+		Execute()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load matches for player %s: %w", userID, err)
+	}
+	if err := json.Unmarshal(data, &matches); err != nil {
+		return nil, fmt.Errorf("failed to parse matches for player %s: %w", userID, err)
+	}
+
+	byID := make(map[string]GameMatch, len(matches))
+	for _, m := range matches {
+		byID[m.ID] = m
+	}
+
+	totalSeconds, totalStages := 0, 0
+	for _, row := range rows {
+		match, ok := byID[row.MatchID]
+		if !ok {
+			continue
+		}
+
+		role := stats.ByRole[row.Role]
+		role.Played++
+		stats.GamesPlayed++
+		if match.WinnerRole == row.Role {
+			role.Won++
+			stats.GamesWon++
+		}
+		stats.ByRole[row.Role] = role
+
+		if row.Role == "CIVILIAN" {
+			stats.VotesCast += row.VotesCast
+			stats.CorrectVotes += row.CorrectVotes
+		}
+
+		if match.StagesCompleted > 0 {
+			totalSeconds += match.DurationSeconds
+			totalStages += match.StagesCompleted
+		}
+	}
+
+	if stats.VotesCast > 0 {
+		stats.ImpostorDetectionAccuracy = float64(stats.CorrectVotes) / float64(stats.VotesCast)
+	}
+	if totalStages > 0 {
+		stats.AvgStageSeconds = float64(totalSeconds) / float64(totalStages)
+	}
+
+	return stats, nil
+}
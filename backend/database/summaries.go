@@ -0,0 +1,80 @@
+package database
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const summaryTTL = 30 * 24 * time.Hour
+
+// MatchSummary is the public, shareable record of a finished game. It only
+// carries display names, never player or account IDs.
+type MatchSummary struct {
+	RoomCode        string           `json:"roomCode"`
+	Reason          string           `json:"reason"`
+	WinnerRole      string           `json:"winnerRole"`
+	DurationSeconds int              `json:"durationSeconds"`
+	Players         []SummaryPlayer  `json:"players"`
+	Stages          []SummaryStage   `json:"stages"`
+	Meetings        []SummaryMeeting `json:"meetings"`
+	EndedAt         time.Time        `json:"endedAt"`
+}
+
+type SummaryPlayer struct {
+	Username   string `json:"username"`
+	Role       string `json:"role"`
+	Eliminated bool   `json:"eliminated"`
+}
+
+// SummaryStage is one coding task. CompletedAtSeconds is measured from the
+// start of the game and is nil for stages the crew never finished.
+type SummaryStage struct {
+	Stage              int    `json:"stage"`
+	Title              string `json:"title"`
+	CompletedAtSeconds *int   `json:"completedAtSeconds"`
+	Code               string `json:"code,omitempty"`
+}
+
+// SummaryMeeting is one vote. Tally maps each target name (or "SKIP") to
+// the votes it got, which is what the vote chart is drawn from.
+type SummaryMeeting struct {
+	Stage      int               `json:"stage"`
+	Votes      map[string]string `json:"votes"`
+	Tally      map[string]int    `json:"tally"`
+	Eliminated string            `json:"eliminated,omitempty"`
+}
+
+func MatchSummaryKey(token string) string {
+	return keyf("summary:%s", token)
+}
+
+func SaveMatchSummary(token string, summary MatchSummary) error {
+	jsonData, err := json.Marshal(summary)
+	if err != nil {
+		return fmt.Errorf("failed to marshal match summary: %w", err)
+	}
+	if err := RDB.Set(ctx, MatchSummaryKey(token), jsonData, summaryTTL).Err(); err != nil {
+		return fmt.Errorf("failed to save match summary: %w", err)
+	}
+	return nil
+}
+
+// GetMatchSummary returns nil, nil for unknown or expired tokens.
+func GetMatchSummary(token string) (*MatchSummary, error) {
+	data, err := RDB.Get(ctx, MatchSummaryKey(token)).Bytes()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load match summary: %w", err)
+	}
+
+	var summary MatchSummary
+	if err := json.Unmarshal(data, &summary); err != nil {
+		return nil, fmt.Errorf("failed to parse match summary: %w", err)
+	}
+	return &summary, nil
+}
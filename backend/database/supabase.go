@@ -1,9 +1,12 @@
 package database
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"log"
+	"net/http"
+	"strings"
 	"time"
 
 	"github.com/supabase-community/postgrest-go"
@@ -13,6 +16,10 @@ import (
 
 var SupabaseClient *supa.Client
 
+// supabaseURL and supabaseKey are kept for PingSupabase, since the client
+// doesn't expose a health check.
+var supabaseURL, supabaseKey string
+
 
 func InitSupabase(url, key string) error {
 	if url == "" || key == "" {
@@ -27,10 +34,37 @@ func InitSupabase(url, key string) error {
 	}
 
 	SupabaseClient = client
+	supabaseURL, supabaseKey = strings.TrimRight(url, "/"), key
 	log.Println("Supabase connected successfully")
 	return nil
 }
 
+// SupabaseEnabled reports whether match history is stored in Supabase.
+func SupabaseEnabled() bool {
+	return SupabaseClient != nil
+}
+
+// PingSupabase checks that Supabase's REST API answers. Any response short
+// of a server error counts: it only has to be reachable.
+func PingSupabase(c context.Context) error {
+	req, err := http.NewRequestWithContext(c, http.MethodGet, supabaseURL+"/rest/v1/", nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("apikey", supabaseKey)
+	req.Header.Set("Authorization", "Bearer "+supabaseKey)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 500 {
+		return fmt.Errorf("supabase returned %d", resp.StatusCode)
+	}
+	return nil
+}
+
 type User struct {
 	ID          string    `json:"id"`
 	Username    string    `json:"username"`
@@ -49,6 +83,12 @@ type GameMatch struct {
 	DurationSeconds int       `json:"duration_seconds"`
 	StagesCompleted int       `json:"stages_completed"`
 	EndedAt         time.Time `json:"ended_at"`
+
+	// StageSeconds is how many seconds into the game each finished stage
+	// was passed, and StageCode the code that passed it. Match lists leave
+	// them out.
+	StageSeconds map[int]int    `json:"stage_seconds,omitempty"`
+	StageCode    map[int]string `json:"stage_code,omitempty"`
 }
 
 type MatchPlayer struct {
@@ -56,6 +96,21 @@ type MatchPlayer struct {
 	UserID        string `json:"user_id"`
 	Role          string `json:"role"`
 	WasEliminated bool   `json:"was_eliminated"`
+	VotesCast     int    `json:"votes_cast"`
+	CorrectVotes  int    `json:"correct_votes"`
+	// VoteAccuracy is CorrectVotes over VotesCast, nil for a player who
+	// cast no votes.
+	VoteAccuracy *float64 `json:"vote_accuracy"`
+
+	TestsRun          int `json:"tests_run"`
+	StagesPassed      int `json:"stages_passed"`
+	SabotagesSurvived int `json:"sabotages_survived"`
+	Score             int `json:"score"`
+
+	SabotagesUsed int `json:"sabotages_used"`
+	// SecondsAlive is how long the player lasted, the whole game if they
+	// were never eliminated.
+	SecondsAlive int `json:"seconds_alive"`
 }
 
 func GetOrCreateUser(username string) (*User, error) {
@@ -125,9 +180,7 @@ func SaveGameMatch(match GameMatch, players []MatchPlayer) error {
 	}
 
 	var matchResult []GameMatch
-	data, _, err := SupabaseClient.From("game_matches").
-		Insert(match, false, "", "", "").
-		Execute()
+	data, err := insertMatchDetails("game_matches", match, matchDetailColumns)
 
 	if err != nil {
 		return fmt.Errorf("failed to save game match: %w", err)
@@ -147,10 +200,7 @@ func SaveGameMatch(match GameMatch, players []MatchPlayer) error {
 		players[i].MatchID = matchID
 	}
 
-	_, _, err = SupabaseClient.From("match_players").
-		Insert(players, false, "", "", "").
-		Execute()
-
+	_, err = insertMatchDetails("match_players", players, playerDetailColumns)
 	if err != nil {
 		log.Printf("Failed to save match players: %v", err)
 	}
@@ -164,8 +214,7 @@ func SaveGameMatch(match GameMatch, players []MatchPlayer) error {
 			continue
 		}
 
-		won := (match.WinnerRole == "CIVILIAN" && p.Role == "CIVILIAN") ||
-			(match.WinnerRole == "IMPOSTER" && p.Role == "IMPOSTER")
+		won := p.Role == match.WinnerRole
 
 		currentUser.GamesPlayed++
 		if won {
@@ -185,6 +234,8 @@ func SaveGameMatch(match GameMatch, players []MatchPlayer) error {
 		if err != nil {
 			log.Printf("Failed to update stats for user %s", p.UserID)
 		}
+
+		InvalidatePlayerStats(p.UserID)
 	}
 
 	log.Printf("Match saved to Supabase: %s (Winner: %s)", matchID, match.WinnerRole)
@@ -0,0 +1,162 @@
+package database
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Translation requests the sidecar answers with an error wait in a retry
+// queue, a sorted set scored by when they are next due, and those out of
+// retries go to a capped dead-letter list where an admin can look at them
+// and send them back.
+
+const (
+	TranslationRetryKey = "translation:retry"
+	TranslationDeadKey  = "translation:dead"
+
+	TranslationKindChat = "chat"
+	TranslationKindTask = "task"
+)
+
+// TranslationJob is a translation request to send again. A chat job
+// names its line by MessageID, a task job its task and field; Text is what
+// to translate either way.
+type TranslationJob struct {
+	ID        string `json:"id"`
+	Kind      string `json:"kind"`
+	RoomID    string `json:"roomId"`
+	MessageID string `json:"messageId,omitempty"`
+	PlayerID  string `json:"playerId,omitempty"`
+	Username  string `json:"username,omitempty"`
+	TaskID    string `json:"taskId,omitempty"`
+	Field     string `json:"field,omitempty"`
+	Text      string `json:"text"`
+	Attempts  int    `json:"attempts"`
+	LastError string `json:"lastError,omitempty"`
+	FailedAt  int64  `json:"failedAt"`
+}
+
+// ScheduleTranslationRetry queues job to be sent again at due.
+func ScheduleTranslationRetry(job TranslationJob, due time.Time) error {
+	jsonData, err := json.Marshal(job)
+	if err != nil {
+		return fmt.Errorf("failed to marshal translation job: %w", err)
+	}
+	err = RDB.ZAdd(ctx, Key(TranslationRetryKey), redis.Z{
+		Score:  float64(due.UnixMilli()),
+		Member: jsonData,
+	}).Err()
+	if err != nil {
+		return fmt.Errorf("failed to queue translation retry: %w", err)
+	}
+	return nil
+}
+
+// ClaimDueTranslationRetries takes up to limit jobs due by now off the
+// retry queue. Each job is removed before it is returned, so of several
+// servers polling the queue only one gets it.
+func ClaimDueTranslationRetries(now time.Time, limit int) ([]TranslationJob, error) {
+	members, err := RDB.ZRangeByScore(ctx, Key(TranslationRetryKey), &redis.ZRangeBy{
+		Min:   "-inf",
+		Max:   strconv.FormatInt(now.UnixMilli(), 10),
+		Count: int64(limit),
+	}).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read translation retries: %w", err)
+	}
+
+	jobs := make([]TranslationJob, 0, len(members))
+	for _, member := range members {
+		removed, err := RDB.ZRem(ctx, Key(TranslationRetryKey), member).Result()
+		if err != nil {
+			return jobs, fmt.Errorf("failed to claim translation retry: %w", err)
+		}
+		if removed == 0 {
+			continue
+		}
+		var job TranslationJob
+		if err := json.Unmarshal([]byte(member), &job); err != nil {
+			continue
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, nil
+}
+
+// DeadLetterTranslation puts job on the dead-letter list, which keeps the
+// latest keep jobs.
+func DeadLetterTranslation(job TranslationJob, keep int) error {
+	jsonData, err := json.Marshal(job)
+	if err != nil {
+		return fmt.Errorf("failed to marshal translation job: %w", err)
+	}
+	pipe := RDB.TxPipeline()
+	pipe.LPush(ctx, Key(TranslationDeadKey), jsonData)
+	pipe.LTrim(ctx, Key(TranslationDeadKey), 0, int64(keep-1))
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to dead-letter translation: %w", err)
+	}
+	return nil
+}
+
+// DeadTranslations returns a page of the dead-lettered jobs, newest first.
+func DeadTranslations(limit, offset int) ([]TranslationJob, error) {
+	members, err := RDB.LRange(ctx, Key(TranslationDeadKey), int64(offset), int64(offset+limit-1)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read dead translations: %w", err)
+	}
+
+	jobs := make([]TranslationJob, 0, len(members))
+	for _, member := range members {
+		var job TranslationJob
+		if err := json.Unmarshal([]byte(member), &job); err != nil {
+			continue
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, nil
+}
+
+// RequeueDeadTranslation moves the dead-lettered job with id back to the
+// retry queue, due now and with its attempts reset. It reports false if
+// there is no such job.
+func RequeueDeadTranslation(id string) (bool, error) {
+	members, err := RDB.LRange(ctx, Key(TranslationDeadKey), 0, -1).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to read dead translations: %w", err)
+	}
+
+	for _, member := range members {
+		var job TranslationJob
+		if err := json.Unmarshal([]byte(member), &job); err != nil || job.ID != id {
+			continue
+		}
+		removed, err := RDB.LRem(ctx, Key(TranslationDeadKey), 1, member).Result()
+		if err != nil {
+			return false, fmt.Errorf("failed to remove dead translation: %w", err)
+		}
+		if removed == 0 {
+			return false, nil
+		}
+		job.Attempts = 0
+		return true, ScheduleTranslationRetry(job, time.Now())
+	}
+	return false, nil
+}
+
+// PublishTranslationResult publishes a translation on channel the way the
+// sidecar answers, for translations made in-process.
+func PublishTranslationResult(channel string, result interface{}) error {
+	jsonData, err := json.Marshal(result)
+	if err != nil {
+		return fmt.Errorf("failed to marshal translation result: %w", err)
+	}
+	if err := RDB.Publish(ctx, channel, jsonData).Err(); err != nil {
+		return fmt.Errorf("failed to publish translation result: %w", err)
+	}
+	return nil
+}
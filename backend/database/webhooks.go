@@ -0,0 +1,55 @@
+package database
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+
+	"code-mafia-backend/webhooks"
+)
+
+const globalWebhooksKey = "webhooks:global"
+
+func RoomWebhooksKey(roomID string) string {
+	return keyf("room:%s:webhooks", roomID)
+}
+
+// SaveWebhook stores an endpoint. An empty roomID registers an operator
+// webhook that receives events from every room.
+func SaveWebhook(roomID string, endpoint webhooks.Endpoint) error {
+	jsonData, err := json.Marshal(endpoint)
+	if err != nil {
+		return fmt.Errorf("failed to marshal webhook: %w", err)
+	}
+	return RDB.HSet(ctx, webhooksKey(roomID), endpoint.ID, jsonData).Err()
+}
+
+func DeleteWebhook(roomID, id string) (bool, error) {
+	n, err := RDB.HDel(ctx, webhooksKey(roomID), id).Result()
+	return n > 0, err
+}
+
+func ListWebhooks(roomID string) ([]webhooks.Endpoint, error) {
+	values, err := RDB.HGetAll(ctx, webhooksKey(roomID)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list webhooks: %w", err)
+	}
+
+	endpoints := []webhooks.Endpoint{}
+	for id, value := range values {
+		var endpoint webhooks.Endpoint
+		if err := json.Unmarshal([]byte(value), &endpoint); err != nil {
+			log.Printf("Skipping corrupt webhook %s: %v", id, err)
+			continue
+		}
+		endpoints = append(endpoints, endpoint)
+	}
+	return endpoints, nil
+}
+
+func webhooksKey(roomID string) string {
+	if roomID == "" {
+		return Key(globalWebhooksKey)
+	}
+	return RoomWebhooksKey(roomID)
+}
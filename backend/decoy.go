@@ -0,0 +1,69 @@
+package main
+
+import (
+	"log"
+	"math/rand"
+	"time"
+
+	"code-mafia-backend/sandbox"
+)
+
+// decoyRunTime is roughly how long a real run takes, so an impostor's
+// decoy run doesn't come back suspiciously fast.
+const (
+	decoyRunTime   = 2 * time.Second
+	decoyRunJitter = 3 * time.Second
+)
+
+// runDecoyTests answers an impostor's RUN_TESTS as if their code had passed,
+// without running it. Only the impostor hears about it: the room's test
+// lock, the stage and the task bar are left alone, so faking work never
+// helps or hinders the crew. In a personal-tasks game their cover task is
+// marked done for them. The detective still hears about it if the code
+// wouldn't have passed the source checks. Runs on the room goroutine.
+func (r *Room) runDecoyTests(client *Client, stage int, code string) {
+	playerID := client.PlayerID
+	task := r.runTask(playerID, stage)
+	if !(heuristicRunner{stage: task.Stage, checks: task.Checks}).passes(code) {
+		r.recordFailedRun(playerID)
+	}
+	client.sendMessage(Message{
+		Type: "TEST_LOCKED",
+		Data: map[string]interface{}{
+			"runner":   "A crewmate",
+			"runnerID": playerID,
+			"stage":    stage,
+		},
+	})
+
+	game := r.game
+	delay := decoyRunTime + time.Duration(rand.Int63n(int64(decoyRunJitter)))
+	r.schedule(delay, func() {
+		if r.game != game || r.gameState.Phase == PhaseEnd {
+			return
+		}
+		client := r.clientFor(playerID)
+		if client == nil {
+			return
+		}
+		client.sendMessage(Message{
+			Type: "TEST_COMPLETE",
+			Data: map[string]interface{}{
+				"passed":         true,
+				"stage":          stage,
+				"runner":         "A crewmate",
+				"compiled":       true,
+				"compilerOutput": "",
+				"tests":          []sandbox.TestResult{{Name: "source check", Passed: true}},
+				"timedOut":       false,
+			},
+		})
+
+		if r.personalGame() && r.assignedTasks[playerID] != nil && !r.gameState.TasksDone[playerID] {
+			r.gameState.TasksDone[playerID] = true
+			r.saveToRedis()
+			r.sendAssignedTask(client)
+		}
+		log.Printf("🎭 Room %s: decoy test run for impostor %s", r.ID, playerID)
+	})
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"math"
+	"sort"
+)
+
+// Every edit a player makes passes through the room on its way to the
+// other editors, so the room can tell who wrote the code. An edit is
+// measured by the size of its Yjs update, which grows with what was typed
+// or deleted: rough, but the same yardstick for everyone.
+
+// EditShare is one player's part of the editing done in a game, as
+// GAME_ENDED reports it.
+type EditShare struct {
+	PlayerID string `json:"playerID"`
+	Username string `json:"username"`
+	Bytes    int    `json:"bytes"`
+	Percent  int    `json:"percent"`
+}
+
+// yjsEditBytes is how much of a y-websocket message is an edit: all of a
+// sync update, and nothing of anything else. A sync step 2 is the whole
+// document sent to a peer that just opened it, not an edit.
+func yjsEditBytes(message []byte) int {
+	if len(message) < 2 || message[0] != yjsMessageSync || message[1] != yjsSyncUpdate {
+		return 0
+	}
+	return len(message)
+}
+
+// recordEdit credits playerID with the edit in message, if any, while a
+// game is under way. Runs on the room goroutine.
+func (r *Room) recordEdit(playerID string, message []byte) {
+	if r.players[playerID] == nil {
+		return
+	}
+	if phase := r.gameState.Phase; phase == PhaseLobby || phase == PhaseEnd {
+		return
+	}
+	r.editBytes[playerID] += yjsEditBytes(message)
+}
+
+// editShares breaks the game's editing down by player, most first, with
+// anyone who never typed at the bottom on 0%. Runs on the room goroutine.
+func (r *Room) editShares() []EditShare {
+	total := 0
+	for _, n := range r.editBytes {
+		total += n
+	}
+
+	shares := make([]EditShare, 0, len(r.players))
+	for id, player := range r.players {
+		share := EditShare{PlayerID: id, Username: player.Username, Bytes: r.editBytes[id]}
+		if total > 0 {
+			share.Percent = int(math.Round(float64(share.Bytes) * 100 / float64(total)))
+		}
+		shares = append(shares, share)
+	}
+	sort.Slice(shares, func(i, j int) bool {
+		if shares[i].Bytes != shares[j].Bytes {
+			return shares[i].Bytes > shares[j].Bytes
+		}
+		return shares[i].Username < shares[j].Username
+	})
+	return shares
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	htmltemplate "html/template"
+	"log"
+	"net/http"
+	"net/mail"
+	"net/url"
+	"strings"
+	texttemplate "text/template"
+
+	"code-mafia-backend/auth"
+	"code-mafia-backend/config"
+	"code-mafia-backend/database"
+	"code-mafia-backend/mailer"
+
+	"github.com/gorilla/mux"
+)
+
+const (
+	maxInviteRecipients   = 20
+	maxInviteMessageRunes = 500
+)
+
+var inviteMailer *mailer.SMTPSender
+
+type emailInvite struct {
+	FromName string
+	RoomID   string
+	Link     string
+	Message  string
+}
+
+var inviteTextTemplate = texttemplate.Must(texttemplate.New("invite").Parse(`{{.FromName}} invited you to a game of Code Mafia.
+{{if .Message}}
+"{{.Message}}"
+{{end}}
+Join room {{.RoomID}}: {{.Link}}
+
+Open the link in a browser, pick a name and you're in. No account needed.
+`))
+
+var inviteHTMLTemplate = htmltemplate.Must(htmltemplate.New("invite").Parse(`<!DOCTYPE html>
+<html>
+<body style="font-family: sans-serif; color: #1a1a1a;">
+  <p><strong>{{.FromName}}</strong> invited you to a game of Code Mafia.</p>
+  {{if .Message}}<blockquote style="border-left: 3px solid #ccc; margin: 0; padding-left: 12px;">{{.Message}}</blockquote>{{end}}
+  <p>
+    <a href="{{.Link}}" style="display: inline-block; padding: 10px 18px; background: #6d28d9; color: #fff; text-decoration: none; border-radius: 6px;">Join room {{.RoomID}}</a>
+  </p>
+  <p style="color: #666; font-size: 13px;">Or open {{.Link}} in a browser. No account needed.</p>
+</body>
+</html>
+`))
+
+func initMailer() {
+	cfg := config.AppConfig
+	if cfg.SMTPHost == "" {
+		return
+	}
+	inviteMailer = &mailer.SMTPSender{
+		Host:     cfg.SMTPHost,
+		Port:     cfg.SMTPPort,
+		Username: cfg.SMTPUsername,
+		Password: cfg.SMTPPassword,
+		From:     cfg.MailFrom,
+	}
+	log.Printf("Email invites enabled via %s:%d", cfg.SMTPHost, cfg.SMTPPort)
+}
+
+func roomLink(roomID string) string {
+	return config.AppConfig.PublicURL + "/room/" + url.PathEscape(roomID)
+}
+
+func renderInvite(invite emailInvite) (mailer.Message, error) {
+	var text, html bytes.Buffer
+	if err := inviteTextTemplate.Execute(&text, invite); err != nil {
+		return mailer.Message{}, err
+	}
+	if err := inviteHTMLTemplate.Execute(&html, invite); err != nil {
+		return mailer.Message{}, err
+	}
+	return mailer.Message{
+		Subject: invite.FromName + " invited you to Code Mafia (room " + invite.RoomID + ")",
+		Text:    text.String(),
+		HTML:    html.String(),
+	}, nil
+}
+
+// handleEmailInvite mails a join link for a lobby to a list of addresses,
+// for organizers whose players aren't on Discord or in anyone's friends
+// list. Each account has an hourly recipient budget on top of the per-IP
+// create limit, and every batch lands in the room's audit log.
+func (h *Hub) handleEmailInvite(w http.ResponseWriter, r *http.Request, claims *auth.Claims) {
+	if inviteMailer == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]interface{}{"error": "email invites are not configured"})
+		return
+	}
+
+	roomID := mux.Vars(r)["roomId"]
+
+	var req struct {
+		Emails  []string `json:"emails"`
+		Message string   `json:"message"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"error": "invalid JSON body"})
+		return
+	}
+	if len(req.Emails) == 0 || len(req.Emails) > maxInviteRecipients {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"error": "emails must list between 1 and 20 addresses"})
+		return
+	}
+	message := strings.TrimSpace(req.Message)
+	if len([]rune(message)) > maxInviteMessageRunes {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"error": "message must be at most 500 characters"})
+		return
+	}
+
+	seen := make(map[string]bool)
+	var recipients []string
+	for _, raw := range req.Emails {
+		addr, err := mail.ParseAddress(strings.TrimSpace(raw))
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]interface{}{"error": "invalid email address: " + raw})
+			return
+		}
+		address := strings.ToLower(addr.Address)
+		if !seen[address] {
+			seen[address] = true
+			recipients = append(recipients, address)
+		}
+	}
+
+	room := h.getRoom(roomID)
+	if room == nil {
+		writeJSON(w, http.StatusNotFound, map[string]interface{}{"error": "room not found"})
+		return
+	}
+	phase := room.phase()
+	if phase != PhaseLobby {
+		writeJSON(w, http.StatusConflict, map[string]interface{}{"error": "game already started"})
+		return
+	}
+
+	ok, err := database.ReserveEmailInvites(claims.Subject, len(recipients), config.AppConfig.EmailInvitesPerHour)
+	if err != nil {
+		log.Printf("Failed to check email invite quota: %v", err)
+		writeJSON(w, http.StatusServiceUnavailable, map[string]interface{}{"error": "try again later"})
+		return
+	}
+	if !ok {
+		writeJSON(w, http.StatusTooManyRequests, map[string]interface{}{"error": "hourly email invite limit reached"})
+		return
+	}
+
+	fromName := h.presence.nameOf(claims)
+	if fromName == "" {
+		fromName = "Someone"
+	}
+	msg, err := renderInvite(emailInvite{
+		FromName: fromName,
+		RoomID:   roomID,
+		Link:     roomLink(roomID),
+		Message:  message,
+	})
+	if err != nil {
+		log.Printf("Failed to render email invite: %v", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"error": "failed to render invite"})
+		return
+	}
+
+	room.audit("EMAIL_INVITE", claims.Subject, fromName, map[string]interface{}{
+		"recipients": len(recipients),
+	})
+	log.Printf("📧 %s is emailing %d invite(s) to room %s", claims.Subject, len(recipients), roomID)
+
+	go func() {
+		sent := 0
+		for _, to := range recipients {
+			msg.To = to
+			if err := inviteMailer.Send(msg); err != nil {
+				log.Printf("Email invite to room %s failed: %v", roomID, err)
+				continue
+			}
+			sent++
+			incMetric("email_invites_sent_total")
+		}
+		log.Printf("📧 Sent %d/%d email invite(s) for room %s", sent, len(recipients), roomID)
+	}()
+
+	writeJSON(w, http.StatusAccepted, map[string]interface{}{
+		"status":     "queued",
+		"recipients": len(recipients),
+		"link":       roomLink(roomID),
+	})
+}
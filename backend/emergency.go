@@ -0,0 +1,82 @@
+package main
+
+import (
+	"time"
+
+	"code-mafia-backend/config"
+)
+
+// Reasons an EMERGENCY is turned away with EMERGENCY_UNAVAILABLE.
+const (
+	emergencyNotAllowed    = "NOT_ALLOWED"
+	emergencyWrongPhase    = "WRONG_PHASE"
+	emergencySabotage      = "SABOTAGE_ACTIVE"
+	emergencyStageStarting = "STAGE_STARTING"
+	emergencyCooldown      = "COOLDOWN"
+	emergencyLimitReached  = "LIMIT_REACHED"
+)
+
+var emergencyMessages = map[string]string{
+	emergencyNotAllowed:    "Eliminated players can't call meetings",
+	emergencyWrongPhase:    "Meetings can only be called while the crew is working on a task",
+	emergencySabotage:      "Communications are down - wait for the sabotage to end",
+	emergencyStageStarting: "The stage has only just started",
+	emergencyCooldown:      "A meeting was held moments ago",
+	emergencyLimitReached:  "You have used all your emergency meetings this game",
+}
+
+// takeEmergency uses up one of playerID's meeting calls, or returns why
+// they can't call one now and, when waiting will help, for how long. Every
+// meeting stops the clock, so the limits keep a player from holding the
+// game up. Runs on the room goroutine.
+func (r *Room) takeEmergency(playerID string) (string, time.Duration) {
+	player := r.players[playerID]
+	if player == nil || player.IsEliminated {
+		return emergencyNotAllowed, 0
+	}
+
+	phase := r.gameState.Phase
+	if phase != PhaseTask1 && phase != PhaseTask2 && phase != PhaseTask3 ||
+		r.gameState.TasksComplete[r.gameState.CurrentStage] {
+		return emergencyWrongPhase, 0
+	}
+	if r.sabotageActive {
+		return emergencySabotage, time.Until(r.sabotageEndTime)
+	}
+
+	tunables := config.Current()
+	if wait := time.Until(r.stageStartedAt.Add(time.Duration(tunables.EmergencyStageGraceSec) * time.Second)); wait > 0 {
+		return emergencyStageStarting, wait
+	}
+	if wait := time.Until(r.lastMeetingEnded.Add(time.Duration(tunables.EmergencyCooldownSec) * time.Second)); wait > 0 {
+		return emergencyCooldown, wait
+	}
+	if r.emergencyCalls[playerID] >= tunables.EmergencyMaxPerPlayer {
+		return emergencyLimitReached, 0
+	}
+
+	r.emergencyCalls[playerID]++
+	return "", 0
+}
+
+// emergencyCallsLeft is how many more meetings playerID may call this game.
+// Runs on the room goroutine.
+func (r *Room) emergencyCallsLeft(playerID string) int {
+	left := config.Current().EmergencyMaxPerPlayer - r.emergencyCalls[playerID]
+	if left < 0 {
+		return 0
+	}
+	return left
+}
+
+func (c *Client) sendEmergencyUnavailable(reason string, retryAfter time.Duration, callsLeft int) {
+	data := map[string]interface{}{
+		"reason":    reason,
+		"message":   emergencyMessages[reason],
+		"callsLeft": callsLeft,
+	}
+	if retryAfter > 0 {
+		data["retryAfterMs"] = retryAfter.Milliseconds()
+	}
+	c.sendMessage(Message{Type: "EMERGENCY_UNAVAILABLE", Data: data})
+}
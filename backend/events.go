@@ -0,0 +1,143 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+
+	"code-mafia-backend/database"
+	"code-mafia-backend/webhooks"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+const maxRoomWebhooks = 3
+
+// emitEvent sends a game event to the operator's webhooks and the room's
+// own. Delivery happens in the background so the room goroutine never
+// waits on it; data must not share maps with room state.
+func (r *Room) emitEvent(eventType string, data map[string]interface{}) {
+	event := webhooks.Event{
+		ID:     uuid.New().String(),
+		Type:   eventType,
+		RoomID: r.ID,
+		Time:   time.Now(),
+		Data:   data,
+	}
+
+	go func() {
+		global, err := database.ListWebhooks("")
+		if err != nil {
+			log.Printf("Failed to load operator webhooks: %v", err)
+		}
+		scoped, err := database.ListWebhooks(r.ID)
+		if err != nil {
+			log.Printf("Failed to load webhooks for room %s: %v", r.ID, err)
+		}
+
+		if len(global)+len(scoped) > 0 {
+			webhooks.Deliver(append(global, scoped...), event)
+		}
+	}()
+}
+
+// playerNames lists who is in the game without giving away roles. Caller
+// holds r.mu.
+func (r *Room) playerNames() []map[string]interface{} {
+	names := make([]map[string]interface{}, 0, len(r.players))
+	for _, p := range r.players {
+		names = append(names, map[string]interface{}{
+			"id":       p.ID,
+			"username": p.Username,
+		})
+	}
+	return names
+}
+
+// addWebhook registers a player-supplied endpoint for this room. The reply
+// carries the signing secret, which is only ever shown to the host.
+func (r *Room) addWebhook(c *Client, rawURL string, events []string) {
+	existing, err := database.ListWebhooks(r.ID)
+	if err != nil {
+		c.sendError("Could not load webhooks")
+		return
+	}
+	if len(existing) >= maxRoomWebhooks {
+		c.sendError("This room already has the maximum number of webhooks")
+		return
+	}
+
+	endpoint, err := webhooks.NewEndpoint(rawURL, events, true)
+	if err != nil {
+		c.sendError(err.Error())
+		return
+	}
+	if err := database.SaveWebhook(r.ID, endpoint); err != nil {
+		log.Printf("Failed to save webhook for room %s: %v", r.ID, err)
+		c.sendError("Could not save webhook")
+		return
+	}
+
+	log.Printf("🪝 Room %s webhook %s -> %s", r.ID, endpoint.ID, endpoint.URL)
+	c.sendMessage(Message{Type: "WEBHOOK_ADDED", Data: endpoint})
+}
+
+func (r *Room) removeWebhook(c *Client, id string) {
+	removed, err := database.DeleteWebhook(r.ID, id)
+	if err != nil || !removed {
+		c.sendError("Webhook not found")
+		return
+	}
+	c.sendMessage(Message{Type: "WEBHOOK_REMOVED", Data: map[string]interface{}{"id": id}})
+}
+
+func handleAdminListWebhooks(w http.ResponseWriter, r *http.Request) {
+	endpoints, err := database.ListWebhooks(r.URL.Query().Get("room"))
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"webhooks": endpoints})
+}
+
+// handleAdminCreateWebhook registers an operator webhook, for every room
+// or, with roomId, for one room.
+func handleAdminCreateWebhook(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		URL    string   `json:"url"`
+		Events []string `json:"events"`
+		RoomID string   `json:"roomId"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"error": "invalid JSON body"})
+		return
+	}
+
+	endpoint, err := webhooks.NewEndpoint(req.URL, req.Events, false)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"error": err.Error()})
+		return
+	}
+	if err := database.SaveWebhook(req.RoomID, endpoint); err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"error": err.Error()})
+		return
+	}
+
+	log.Printf("🪝 Operator webhook %s -> %s (room: %q)", endpoint.ID, endpoint.URL, req.RoomID)
+	writeJSON(w, http.StatusCreated, endpoint)
+}
+
+func handleAdminDeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	removed, err := database.DeleteWebhook(r.URL.Query().Get("room"), mux.Vars(r)["id"])
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"error": err.Error()})
+		return
+	}
+	if !removed {
+		writeJSON(w, http.StatusNotFound, map[string]interface{}{"error": "webhook not found"})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"status": "deleted"})
+}
@@ -0,0 +1,219 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+
+	"code-mafia-backend/auth"
+	"code-mafia-backend/database"
+	"code-mafia-backend/notify"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// withAccount only lets signed-in players through; friends and parties
+// are tied to accounts, not guest IDs.
+func withAccount(next func(http.ResponseWriter, *http.Request, *auth.Claims)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims, err := authenticateRequest(r)
+		if err != nil || claims == nil {
+			writeJSON(w, http.StatusUnauthorized, map[string]interface{}{
+				"error": "a valid access token is required",
+			})
+			return
+		}
+		next(w, r, claims)
+	}
+}
+
+// friendIDParam reads a user ID from the path. Account IDs are UUIDs, and
+// checking that keeps them safe to splice into PostgREST filters.
+func friendIDParam(w http.ResponseWriter, r *http.Request, self string) (string, bool) {
+	id := mux.Vars(r)["id"]
+	if _, err := uuid.Parse(id); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"error": "invalid user id"})
+		return "", false
+	}
+	if id == self {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"error": "that's you"})
+		return "", false
+	}
+	return id, true
+}
+
+func writeFriendError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, database.ErrFriendshipExists):
+		writeJSON(w, http.StatusConflict, map[string]interface{}{"error": err.Error()})
+	case errors.Is(err, database.ErrFriendshipNotFound):
+		writeJSON(w, http.StatusNotFound, map[string]interface{}{"error": err.Error()})
+	default:
+		writeStoreError(w, err)
+	}
+}
+
+func (h *Hub) handleListFriends(w http.ResponseWriter, r *http.Request, claims *auth.Claims) {
+	friendships, err := database.ListFriendships(claims.Subject)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	friends := []map[string]interface{}{}
+	incoming := []string{}
+	outgoing := []string{}
+	for _, f := range friendships {
+		other := f.Other(claims.Subject)
+
+		if f.Status == database.FriendshipPending {
+			if f.RequesterID == claims.Subject {
+				outgoing = append(outgoing, other)
+			} else {
+				incoming = append(incoming, other)
+			}
+			continue
+		}
+
+		roomID := h.presence.roomOf(other)
+		friends = append(friends, map[string]interface{}{
+			"userId": other,
+			"online": roomID != "",
+			"roomId": roomID,
+		})
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"friends":  friends,
+		"incoming": incoming,
+		"outgoing": outgoing,
+	})
+}
+
+func (h *Hub) handleSendFriendRequest(w http.ResponseWriter, r *http.Request, claims *auth.Claims) {
+	target, ok := friendIDParam(w, r, claims.Subject)
+	if !ok {
+		return
+	}
+
+	friendship, err := database.SendFriendRequest(claims.Subject, target)
+	if err != nil {
+		writeFriendError(w, err)
+		return
+	}
+
+	msgType := "FRIEND_REQUEST"
+	if friendship.Status == database.FriendshipAccepted {
+		msgType = "FRIEND_ACCEPTED"
+	}
+	h.presence.send(target, Message{
+		Type: msgType,
+		Data: map[string]interface{}{"userId": claims.Subject},
+	})
+
+	writeJSON(w, http.StatusOK, friendship)
+}
+
+func (h *Hub) handleRespondFriendRequest(w http.ResponseWriter, r *http.Request, claims *auth.Claims) {
+	requester, ok := friendIDParam(w, r, claims.Subject)
+	if !ok {
+		return
+	}
+
+	accept := mux.Vars(r)["action"] == "accept"
+	friendship, err := database.RespondFriendRequest(requester, claims.Subject, accept)
+	if err != nil {
+		writeFriendError(w, err)
+		return
+	}
+
+	if !accept {
+		writeJSON(w, http.StatusOK, map[string]interface{}{"status": "declined"})
+		return
+	}
+
+	h.presence.send(requester, Message{
+		Type: "FRIEND_ACCEPTED",
+		Data: map[string]interface{}{"userId": claims.Subject},
+	})
+	writeJSON(w, http.StatusOK, friendship)
+}
+
+func (h *Hub) handleRemoveFriend(w http.ResponseWriter, r *http.Request, claims *auth.Claims) {
+	friend, ok := friendIDParam(w, r, claims.Subject)
+	if !ok {
+		return
+	}
+
+	if err := database.DeleteFriendship(claims.Subject, friend); err != nil {
+		writeFriendError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"status": "removed"})
+}
+
+// handleInviteFriend pushes a ROOM_INVITE over the friend's open
+// connection. Only accepted friends can be invited, and only into a room
+// that exists and is still in the lobby.
+func (h *Hub) handleInviteFriend(w http.ResponseWriter, r *http.Request, claims *auth.Claims) {
+	friend, ok := friendIDParam(w, r, claims.Subject)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		RoomID string `json:"roomId"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.RoomID == "" {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"error": "roomId is required"})
+		return
+	}
+
+	friendship, err := database.GetFriendship(claims.Subject, friend)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	if friendship == nil || friendship.Status != database.FriendshipAccepted {
+		writeJSON(w, http.StatusForbidden, map[string]interface{}{"error": "you can only invite friends"})
+		return
+	}
+
+	room := h.getRoom(req.RoomID)
+	if room == nil {
+		writeJSON(w, http.StatusNotFound, map[string]interface{}{"error": "room not found"})
+		return
+	}
+	phase := room.phase()
+	if phase != PhaseLobby {
+		writeJSON(w, http.StatusConflict, map[string]interface{}{"error": "game already started"})
+		return
+	}
+
+	fromName := h.presence.nameOf(claims)
+	delivered := h.presence.send(friend, Message{
+		Type: "ROOM_INVITE",
+		Data: map[string]interface{}{
+			"fromId":   claims.Subject,
+			"fromName": fromName,
+			"roomId":   req.RoomID,
+		},
+	})
+
+	// Friends who aren't connected get the invite on their devices instead.
+	via := "connection"
+	if !delivered {
+		via = "push"
+		notifyUsers([]string{friend}, notify.Notification{
+			Title: "You're invited to a game",
+			Body:  fmt.Sprintf("%s invited you to room %s", fromName, req.RoomID),
+			Data:  map[string]string{"roomId": req.RoomID, "fromId": claims.Subject},
+		})
+	}
+
+	log.Printf("✉️ %s invited %s to room %s via %s", claims.Subject, friend, req.RoomID, via)
+	writeJSON(w, http.StatusOK, map[string]interface{}{"status": "sent", "via": via})
+}
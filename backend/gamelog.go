@@ -0,0 +1,123 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"time"
+
+	"code-mafia-backend/database"
+
+	"github.com/gorilla/mux"
+)
+
+// Game events are written to the room's stream before the save that
+// follows them, so when a room is loaded from a snapshot that missed some,
+// because the server died or the save failed, replayEvents puts them back.
+// Votes, passed stages and eliminations are replayed; the rest of the
+// stream is there for the record.
+
+const (
+	gameEventJoin       = "JOIN"
+	gameEventLeave      = "LEAVE"
+	gameEventStart      = "GAME_START"
+	gameEventVote       = "VOTE"
+	gameEventStagePass  = "STAGE_PASSED"
+	gameEventSabotage   = "SABOTAGE"
+	gameEventEliminated = "ELIMINATED"
+	gameEventEnd        = "GAME_END"
+	gameEventRematch    = "REMATCH"
+)
+
+// logEvent appends a state change to the room's event stream, tagged with
+// the revision it comes after. Runs on the room goroutine.
+func (r *Room) logEvent(eventType, playerID, username string, data map[string]interface{}) {
+	err := database.AppendGameEvent(r.ID, database.GameEvent{
+		Time:     time.Now(),
+		Type:     eventType,
+		PlayerID: playerID,
+		Username: username,
+		Revision: r.revision,
+		Data:     data,
+	})
+	if err != nil {
+		log.Printf("Failed to log %s event for room %s: %v", eventType, r.ID, err)
+	}
+}
+
+// replayEvents applies the events logged since the loaded snapshot was
+// saved. Replaying stops at the start of a new game, which the snapshot
+// knows nothing about. Runs while the room is loading, before anything
+// resumes from the state.
+func (r *Room) replayEvents() {
+	events, err := database.GameEventsSince(r.ID, r.revision)
+	if err != nil {
+		log.Printf("Failed to read events for room %s: %v", r.ID, err)
+		return
+	}
+
+	g := &r.gameState
+	replayed := 0
+	for _, event := range events {
+		switch event.Type {
+		case gameEventStart, gameEventRematch:
+			log.Printf("⚠️ Room %s started a game after its last save - not replaying past it", r.ID)
+			return
+
+		case gameEventVote:
+			target, _ := event.Data["targetId"].(string)
+			if g.Phase != PhaseDiscussion || g.VotingDeadline.IsZero() {
+				continue
+			}
+			if g.Votes == nil {
+				g.Votes = make(map[string]string)
+			}
+			g.Votes[event.PlayerID] = target
+
+		case gameEventStagePass:
+			stage, _ := event.Data["stage"].(float64)
+			if int(stage) != g.CurrentStage || !inTaskPhase(g.Phase) {
+				continue
+			}
+			g.TasksComplete[g.CurrentStage] = true
+
+		case gameEventEliminated:
+			player := r.players[event.PlayerID]
+			if player == nil {
+				continue
+			}
+			player.IsEliminated = true
+			player.IsAlive = false
+
+		default:
+			continue
+		}
+		replayed++
+	}
+
+	if replayed > 0 {
+		log.Printf("Replayed %d events on room %s's revision %d snapshot", replayed, r.ID, r.revision)
+	}
+}
+
+func handleAdminRoomEvents(w http.ResponseWriter, r *http.Request) {
+	limit, _, err := parsePage(r)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	roomID := mux.Vars(r)["roomId"]
+	events, err := database.GameEvents(roomID, r.URL.Query().Get("after"), limit)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"events": events,
+	})
+}
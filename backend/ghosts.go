@@ -0,0 +1,105 @@
+package main
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Eliminated players stay in the room as ghosts: they keep getting
+// GAME_STATE and watch the editor, but can't change the game. They talk
+// among themselves on GHOST_CHAT, which the living never see.
+
+// y-websocket message and sync step types, see y-protocols.
+const (
+	yjsMessageSync      = 0
+	yjsMessageAwareness = 1
+	yjsSyncStep1        = 0
+	yjsSyncUpdate       = 2
+)
+
+// yjsPeer is one editor connection. The mutex serialises writes, which
+// happen off the room goroutine. Peers relayed from another instance have
+// no mutex, only the instance they are connected to.
+type yjsPeer struct {
+	mu       *sync.Mutex
+	playerID string
+	instance string
+	// doc is the document the peer has open; updates only reach peers on
+	// the same one.
+	doc string
+}
+
+func (r *Room) isGhost(playerID string) bool {
+	player := r.players[playerID]
+	return player != nil && player.IsEliminated
+}
+
+// canEdit reports whether playerID may change the shared code: only living
+// players of this room can, and not while a FREEZE holds them.
+func (r *Room) canEdit(playerID string) bool {
+	player := r.players[playerID]
+	return player != nil && !player.IsEliminated && !r.isFrozen(playerID)
+}
+
+// mayWrite reports whether peer's edits and cursor are passed on: it must
+// be able to edit, and a player's own document takes nobody else's.
+func (r *Room) mayWrite(peer *yjsPeer) bool {
+	_, owner := yjsDocRoom(peer.doc)
+	return r.canEdit(peer.playerID) && (owner == "" || owner == peer.playerID)
+}
+
+// yjsWrites reports whether a y-websocket message changes the document or
+// the sender's cursor, rather than asking peers for the current state.
+func yjsWrites(message []byte) bool {
+	if len(message) == 0 {
+		return false
+	}
+	switch message[0] {
+	case yjsMessageSync:
+		return len(message) < 2 || message[1] != yjsSyncStep1
+	case yjsMessageAwareness:
+		return true
+	}
+	return false
+}
+
+// handleGhostChat relays a ghost's message to the other ghosts. Runs on the
+// room goroutine.
+func (r *Room) handleGhostChat(client *Client, text string) {
+	player := r.players[client.PlayerID]
+	if player == nil || !player.IsEliminated {
+		client.sendError("Only eliminated players can use ghost chat")
+		return
+	}
+	if r.isMuted(client.PlayerID) {
+		client.sendError("You are muted")
+		return
+	}
+
+	messageID := uuid.New().String()
+	r.audit("GHOST_CHAT", player.ID, player.Username, map[string]interface{}{
+		"messageId": messageID,
+		"text":      text,
+	})
+
+	msg := Message{
+		Type: "GHOST_CHAT",
+		Data: map[string]interface{}{
+			"messageId": messageID,
+			"playerId":  player.ID,
+			"username":  player.Username,
+			"text":      text,
+			"timestamp": time.Now().UnixMilli(),
+		},
+	}
+	for c := range r.clients {
+		if r.isGhost(c.PlayerID) {
+			c.sendMessage(msg)
+		}
+	}
+
+	log.Printf("👻 Ghost chat [%s]: %s: %s", r.ID, player.Username, text)
+}
@@ -0,0 +1,430 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"code-mafia-backend/database"
+	"code-mafia-backend/graphql"
+)
+
+// graphQLLimits keeps one query from fanning out into hundreds of
+// Supabase reads: aliases and lists can ask for the same resolver many
+// times over within the depth limit.
+var graphQLLimits = graphql.Limits{
+	Depth:    8,
+	Fields:   200,
+	Resolves: 100,
+}
+
+const maxGraphQLQueryBytes = 16 * 1024
+
+// The GraphQL endpoint is a read-only view over the same data as the REST
+// history, stats and leaderboard routes:
+//
+//	matches(player, outcome, from, to, limit, offset): MatchPage
+//	match(id): Match
+//	player(id): Player { stats, rating, matches(limit, offset), daily(date) }
+//	leaderboard(limit, offset): [LeaderboardEntry]
+//	dailyChallenge(date): DailyChallenge
+//	dailyLeaderboard(date, limit): [DailyCompletion]
+func graphRoot() graphql.Object {
+	return graphql.Object{
+		"__typename": "Query",
+
+		"matches": graphql.Resolver(func(args map[string]interface{}) (interface{}, error) {
+			filter, err := graphMatchFilter(args)
+			if err != nil {
+				return nil, err
+			}
+			return graphMatchPage(filter)
+		}),
+
+		"match": graphql.Resolver(func(args map[string]interface{}) (interface{}, error) {
+			id, err := requiredStringArg(args, "id")
+			if err != nil {
+				return nil, err
+			}
+			match, err := database.GetMatch(id)
+			if err != nil {
+				return nil, graphStoreError(err)
+			}
+			if match == nil {
+				return nil, nil
+			}
+			obj := graphMatch(match.GameMatch)
+			obj["players"] = graphMatchPlayers(match.Players)
+			return obj, nil
+		}),
+
+		"player": graphql.Resolver(func(args map[string]interface{}) (interface{}, error) {
+			id, err := requiredStringArg(args, "id")
+			if err != nil {
+				return nil, err
+			}
+			return graphPlayer(id), nil
+		}),
+
+		"leaderboard": graphql.Resolver(func(args map[string]interface{}) (interface{}, error) {
+			limit, offset, err := pageArgs(args)
+			if err != nil {
+				return nil, err
+			}
+			rows, err := database.TopRatings(limit, offset)
+			if err != nil {
+				return nil, graphStoreError(err)
+			}
+			entries := make([]graphql.Object, 0, len(rows))
+			for i, row := range rows {
+				entries = append(entries, graphql.Object{
+					"__typename":  "LeaderboardEntry",
+					"rank":        offset + i + 1,
+					"rating":      row.Rating,
+					"rankedGames": row.RankedGames,
+					"player":      graphPlayer(row.UserID),
+				})
+			}
+			return entries, nil
+		}),
+
+		"dailyChallenge": graphql.Resolver(func(args map[string]interface{}) (interface{}, error) {
+			date, err := dateArg(args)
+			if err != nil {
+				return nil, err
+			}
+			day, _ := time.Parse(dailyDateFormat, date)
+			challenge := dailyChallengeFor(day)
+			return graphql.Object{
+				"__typename": "DailyChallenge",
+				"date":       challenge.Date,
+				"taskIds":    toInterfaces(challenge.TaskIDs),
+				"roomPrefix": dailyRoomPrefix,
+				"modifier": graphql.Object{
+					"__typename":  "ChallengeModifier",
+					"id":          challenge.Modifier.ID,
+					"name":        challenge.Modifier.Name,
+					"description": challenge.Modifier.Description,
+				},
+				"completions": graphql.Resolver(func(map[string]interface{}) (interface{}, error) {
+					n, err := database.CountDailyCompletions(challenge.Date)
+					if err != nil {
+						log.Printf("Failed to count daily completions: %v", err)
+						return nil, errors.New("failed to count completions")
+					}
+					return n, nil
+				}),
+			}, nil
+		}),
+
+		"dailyLeaderboard": graphql.Resolver(func(args map[string]interface{}) (interface{}, error) {
+			date, err := dateArg(args)
+			if err != nil {
+				return nil, err
+			}
+			limit, _, err := pageArgs(args)
+			if err != nil {
+				return nil, err
+			}
+			entries, err := database.GetDailyLeaderboard(date, limit)
+			if err != nil {
+				log.Printf("Daily leaderboard query failed: %v", err)
+				return nil, errors.New("failed to load leaderboard")
+			}
+			out := make([]graphql.Object, 0, len(entries))
+			for _, entry := range entries {
+				out = append(out, graphDailyCompletion(entry))
+			}
+			return out, nil
+		}),
+	}
+}
+
+func graphMatchPage(filter database.MatchFilter) (interface{}, error) {
+	matches, total, err := database.ListMatches(filter)
+	if err != nil {
+		return nil, graphStoreError(err)
+	}
+
+	items := make([]graphql.Object, 0, len(matches))
+	for _, m := range matches {
+		items = append(items, graphMatch(m))
+	}
+	return graphql.Object{
+		"__typename": "MatchPage",
+		"total":      total,
+		"limit":      filter.Limit,
+		"offset":     filter.Offset,
+		"items":      items,
+	}, nil
+}
+
+func graphMatch(m database.GameMatch) graphql.Object {
+	return graphql.Object{
+		"__typename":      "Match",
+		"id":              m.ID,
+		"roomCode":        m.RoomCode,
+		"winnerRole":      m.WinnerRole,
+		"impostorId":      m.ImpostorID,
+		"durationSeconds": m.DurationSeconds,
+		"stagesCompleted": m.StagesCompleted,
+		"endedAt":         m.EndedAt.Format(time.RFC3339),
+		"players": graphql.Resolver(func(map[string]interface{}) (interface{}, error) {
+			detail, err := database.GetMatch(m.ID)
+			if err != nil {
+				return nil, graphStoreError(err)
+			}
+			if detail == nil {
+				return []graphql.Object{}, nil
+			}
+			return graphMatchPlayers(detail.Players), nil
+		}),
+	}
+}
+
+func graphMatchPlayers(players []database.MatchPlayer) []graphql.Object {
+	out := make([]graphql.Object, 0, len(players))
+	for _, p := range players {
+		out = append(out, graphql.Object{
+			"__typename":    "MatchPlayer",
+			"userId":        p.UserID,
+			"role":          p.Role,
+			"wasEliminated": p.WasEliminated,
+			"votesCast":     p.VotesCast,
+			"correctVotes":  p.CorrectVotes,
+			"testsRun":      p.TestsRun,
+			"stagesPassed":  p.StagesPassed,
+			"score":         p.Score,
+			"player":        graphPlayer(p.UserID),
+
+			"sabotagesSurvived": p.SabotagesSurvived,
+			"sabotagesUsed":     p.SabotagesUsed,
+			"secondsAlive":      p.SecondsAlive,
+			"voteAccuracy":      p.VoteAccuracy,
+		})
+	}
+	return out
+}
+
+// graphPlayer is lazy: nothing is loaded until a field beyond id is asked for.
+func graphPlayer(id string) graphql.Object {
+	return graphql.Object{
+		"__typename": "Player",
+		"id":         id,
+
+		"stats": graphql.Resolver(func(map[string]interface{}) (interface{}, error) {
+			stats, err := database.GetPlayerStats(id)
+			if err != nil {
+				return nil, graphStoreError(err)
+			}
+			byRole := make([]graphql.Object, 0, len(stats.ByRole))
+			for _, role := range []string{"CIVILIAN", "IMPOSTER", "JESTER"} {
+				if rs, ok := stats.ByRole[role]; ok {
+					byRole = append(byRole, graphql.Object{
+						"__typename": "RoleStats",
+						"role":       role,
+						"played":     rs.Played,
+						"won":        rs.Won,
+					})
+				}
+			}
+			return graphql.Object{
+				"__typename":                "PlayerStats",
+				"gamesPlayed":               stats.GamesPlayed,
+				"gamesWon":                  stats.GamesWon,
+				"byRole":                    byRole,
+				"votesCast":                 stats.VotesCast,
+				"correctVotes":              stats.CorrectVotes,
+				"impostorDetectionAccuracy": stats.ImpostorDetectionAccuracy,
+				"rating":                    stats.Rating,
+				"rankedGames":               stats.RankedGames,
+				"mmr":                       stats.MMR,
+				"avgStageSeconds":           stats.AvgStageSeconds,
+			}, nil
+		}),
+
+		"rating": graphql.Resolver(func(map[string]interface{}) (interface{}, error) {
+			ratings, err := database.GetRatings([]string{id})
+			if err != nil {
+				return nil, graphStoreError(err)
+			}
+			r, ok := ratings[id]
+			if !ok {
+				return nil, nil
+			}
+			return r.Rating, nil
+		}),
+
+		"matches": graphql.Resolver(func(args map[string]interface{}) (interface{}, error) {
+			filter, err := graphMatchFilter(args)
+			if err != nil {
+				return nil, err
+			}
+			filter.PlayerID = id
+			return graphMatchPage(filter)
+		}),
+
+		"daily": graphql.Resolver(func(args map[string]interface{}) (interface{}, error) {
+			date, err := dateArg(args)
+			if err != nil {
+				return nil, err
+			}
+			completion, err := database.GetDailyCompletion(date, id)
+			if err != nil {
+				log.Printf("Daily completion query failed: %v", err)
+				return nil, errors.New("failed to load completion")
+			}
+			if completion == nil {
+				return nil, nil
+			}
+			return graphDailyCompletion(*completion), nil
+		}),
+	}
+}
+
+func graphDailyCompletion(c database.DailyCompletion) graphql.Object {
+	return graphql.Object{
+		"__typename":      "DailyCompletion",
+		"player":          graphPlayer(c.PlayerID),
+		"username":        c.Username,
+		"role":            c.Role,
+		"won":             c.Won,
+		"seconds":         c.Seconds,
+		"stagesCompleted": c.StagesCompleted,
+		"completedAt":     c.CompletedAt.Format(time.RFC3339),
+	}
+}
+
+func graphStoreError(err error) error {
+	if errors.Is(err, database.ErrSupabaseNotConfigured) {
+		return errors.New("match history is disabled on this server")
+	}
+	log.Printf("GraphQL store query failed: %v", err)
+	return errors.New("failed to load match history")
+}
+
+func graphMatchFilter(args map[string]interface{}) (database.MatchFilter, error) {
+	limit, offset, err := pageArgs(args)
+	if err != nil {
+		return database.MatchFilter{}, err
+	}
+	filter := database.MatchFilter{Limit: limit, Offset: offset}
+
+	if v, ok := args["player"].(string); ok {
+		filter.PlayerID = v
+	}
+	if v, ok := args["outcome"].(string); ok {
+		if v != "CIVILIAN" && v != "IMPOSTER" && v != "JESTER" {
+			return filter, errors.New("outcome must be CIVILIAN, IMPOSTER or JESTER")
+		}
+		filter.Outcome = v
+	}
+	for _, name := range []string{"from", "to"} {
+		v, ok := args[name].(string)
+		if !ok {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			if t, err = time.Parse("2006-01-02", v); err != nil {
+				return filter, errors.New(name + " must be RFC3339 or YYYY-MM-DD")
+			}
+		}
+		if name == "from" {
+			filter.From = &t
+		} else {
+			filter.To = &t
+		}
+	}
+	return filter, nil
+}
+
+// pageArgs mirrors parsePage for GraphQL arguments.
+func pageArgs(args map[string]interface{}) (int, int, error) {
+	limit, offset := defaultPageSize, 0
+	if v, ok := args["limit"]; ok && v != nil {
+		n, ok := v.(int)
+		if !ok || n < 1 {
+			return 0, 0, errors.New("limit must be a positive integer")
+		}
+		if n > maxPageSize {
+			n = maxPageSize
+		}
+		limit = n
+	}
+	if v, ok := args["offset"]; ok && v != nil {
+		n, ok := v.(int)
+		if !ok || n < 0 {
+			return 0, 0, errors.New("offset must be a non-negative integer")
+		}
+		offset = n
+	}
+	return limit, offset, nil
+}
+
+func requiredStringArg(args map[string]interface{}, name string) (string, error) {
+	v, ok := args[name].(string)
+	if !ok || v == "" {
+		return "", fmt.Errorf("argument %q is required", name)
+	}
+	return v, nil
+}
+
+// dateArg reads an optional YYYY-MM-DD date, defaulting to today (UTC).
+func dateArg(args map[string]interface{}) (string, error) {
+	v, ok := args["date"].(string)
+	if !ok || v == "" {
+		return time.Now().UTC().Format(dailyDateFormat), nil
+	}
+	if _, err := time.Parse(dailyDateFormat, v); err != nil {
+		return "", errors.New("date must be YYYY-MM-DD")
+	}
+	return v, nil
+}
+
+func toInterfaces(values []string) []interface{} {
+	out := make([]interface{}, len(values))
+	for i, v := range values {
+		out[i] = v
+	}
+	return out
+}
+
+// handleGraphQL accepts queries as GET ?query=&variables=&operationName=
+// or as a POSTed JSON body.
+func handleGraphQL(w http.ResponseWriter, r *http.Request) {
+	var req graphql.Request
+
+	if r.Method == http.MethodGet {
+		q := r.URL.Query()
+		req.Query = q.Get("query")
+		req.OperationName = q.Get("operationName")
+		if v := q.Get("variables"); v != "" {
+			if err := json.Unmarshal([]byte(v), &req.Variables); err != nil {
+				writeJSON(w, http.StatusBadRequest, graphql.Response{Errors: []graphql.Error{{Message: "variables must be a JSON object"}}})
+				return
+			}
+		}
+	} else {
+		body := http.MaxBytesReader(w, r.Body, maxGraphQLQueryBytes)
+		if err := json.NewDecoder(body).Decode(&req); err != nil {
+			writeJSON(w, http.StatusBadRequest, graphql.Response{Errors: []graphql.Error{{Message: "invalid JSON body"}}})
+			return
+		}
+	}
+
+	if req.Query == "" || len(req.Query) > maxGraphQLQueryBytes {
+		writeJSON(w, http.StatusBadRequest, graphql.Response{Errors: []graphql.Error{{Message: "query is required and must be under 16KB"}}})
+		return
+	}
+
+	resp := graphql.Execute(graphRoot(), req, graphQLLimits)
+	if resp.Data == nil {
+		writeJSON(w, http.StatusBadRequest, resp)
+		return
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
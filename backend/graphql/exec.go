@@ -0,0 +1,391 @@
+package graphql
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// Object is a node in the result graph. Values are scalars, Objects, lists
+// of either, or Resolvers, which are only called when the field is
+// selected. A "__typename" entry names the type for __typename queries
+// and fragment type conditions.
+type Object map[string]interface{}
+
+// Resolver computes a field from its arguments. Arguments arrive with
+// variables substituted, integers as int and enums as strings.
+type Resolver func(args map[string]interface{}) (interface{}, error)
+
+type Request struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+type Response struct {
+	Data   interface{} `json:"data,omitempty"`
+	Errors []Error     `json:"errors,omitempty"`
+}
+
+type Error struct {
+	Message string        `json:"message"`
+	Path    []interface{} `json:"path,omitempty"`
+}
+
+// Limits bound what one query may cost. Depth is how deeply selections may
+// nest. Fields caps the fields and fragment spreads the operation selects,
+// counting a fragment each time it is spread, and is checked before
+// anything runs. Resolves caps Resolver calls, which lists multiply and
+// the query alone can't show; fields past it come back null with an error.
+type Limits struct {
+	Depth    int
+	Fields   int
+	Resolves int
+}
+
+type executor struct {
+	doc      *Document
+	vars     map[string]interface{}
+	limits   Limits
+	resolves int
+	errors   []Error
+}
+
+// Execute runs a read-only query against root. Only query operations are
+// accepted, and queries over limits are refused.
+func Execute(root Object, req Request, limits Limits) Response {
+	doc, err := Parse(req.Query)
+	if err != nil {
+		return Response{Errors: []Error{{Message: "Syntax error: " + err.Error()}}}
+	}
+
+	op, err := pickOperation(doc, req.OperationName)
+	if err != nil {
+		return Response{Errors: []Error{{Message: err.Error()}}}
+	}
+	if op.Kind != "query" {
+		return Response{Errors: []Error{{Message: "Only queries are supported; this API is read-only"}}}
+	}
+
+	vars := make(map[string]interface{})
+	for _, def := range op.Variables {
+		if v, ok := req.Variables[def.Name]; ok {
+			vars[def.Name] = normalizeJSON(v)
+		} else if def.Default != nil {
+			vars[def.Name] = literal(def.Default, nil)
+		}
+	}
+
+	if n := countSelections(doc, op.Selections, limits.Fields); n > limits.Fields {
+		return Response{Errors: []Error{{Message: fmt.Sprintf("Query selects too many fields (max %d)", limits.Fields)}}}
+	}
+
+	e := &executor{doc: doc, vars: vars, limits: limits}
+	data := e.selectionSet(root, op.Selections, nil, 1)
+	return Response{Data: data, Errors: e.errors}
+}
+
+func pickOperation(doc *Document, name string) (*Operation, error) {
+	if name == "" {
+		if len(doc.Operations) > 1 {
+			return nil, fmt.Errorf("operationName is required when the document has several operations")
+		}
+		return doc.Operations[0], nil
+	}
+	for _, op := range doc.Operations {
+		if op.Name == name {
+			return op, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown operation %q", name)
+}
+
+// countSelections counts the fields and fragment spreads in sels, expanding
+// fragments, and stops once the count passes max.
+func countSelections(doc *Document, sels []Selection, max int) int {
+	n := 0
+	var walk func(sels []Selection, spreading map[string]bool)
+	walk = func(sels []Selection, spreading map[string]bool) {
+		for _, sel := range sels {
+			if n > max {
+				return
+			}
+			switch s := sel.(type) {
+			case *Field:
+				n++
+				walk(s.Selections, spreading)
+			case *FragmentSpread:
+				n++
+				frag, ok := doc.Fragments[s.Name]
+				if !ok || spreading[s.Name] {
+					continue
+				}
+				spreading[s.Name] = true
+				walk(frag.Selections, spreading)
+				delete(spreading, s.Name)
+			case *InlineFragment:
+				walk(s.Selections, spreading)
+			}
+		}
+	}
+	walk(sels, map[string]bool{})
+	return n
+}
+
+func (e *executor) fail(path []interface{}, format string, args ...interface{}) {
+	e.errors = append(e.errors, Error{
+		Message: fmt.Sprintf(format, args...),
+		Path:    append([]interface{}(nil), path...),
+	})
+}
+
+func (e *executor) selectionSet(obj Object, sels []Selection, path []interface{}, depth int) *orderedMap {
+	if depth > e.limits.Depth {
+		e.fail(path, "Query is nested too deeply (max %d levels)", e.limits.Depth)
+		return nil
+	}
+
+	keys, fields := e.collectFields(obj, sels, map[string]bool{})
+	out := &orderedMap{values: make(map[string]interface{}, len(keys))}
+
+	for _, key := range keys {
+		group := fields[key]
+		field := group[0]
+		fieldPath := append(path, key)
+
+		if field.Name == "__typename" {
+			out.set(key, obj["__typename"])
+			continue
+		}
+
+		raw, ok := obj[field.Name]
+		if !ok {
+			e.fail(fieldPath, "Cannot query field %q", field.Name)
+			out.set(key, nil)
+			continue
+		}
+
+		if resolve, ok := raw.(Resolver); ok {
+			e.resolves++
+			if e.resolves > e.limits.Resolves {
+				e.fail(fieldPath, "Query resolves too many fields (max %d)", e.limits.Resolves)
+				out.set(key, nil)
+				continue
+			}
+			value, err := resolve(e.arguments(field.Args))
+			if err != nil {
+				e.fail(fieldPath, "%s", err.Error())
+				out.set(key, nil)
+				continue
+			}
+			raw = value
+		}
+
+		var sub []Selection
+		for _, f := range group {
+			sub = append(sub, f.Selections...)
+		}
+		out.set(key, e.complete(raw, field, sub, fieldPath, depth))
+	}
+	return out
+}
+
+func (e *executor) complete(value interface{}, field *Field, sub []Selection, path []interface{}, depth int) interface{} {
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case Object:
+		if len(sub) == 0 {
+			e.fail(path, "Field %q of type %v must have a selection of subfields", field.Name, v["__typename"])
+			return nil
+		}
+		return e.selectionSet(v, sub, path, depth+1)
+	case []Object:
+		items := make([]interface{}, len(v))
+		for i, item := range v {
+			items[i] = e.complete(item, field, sub, append(path, i), depth)
+		}
+		return items
+	case []interface{}:
+		items := make([]interface{}, len(v))
+		for i, item := range v {
+			items[i] = e.complete(item, field, sub, append(path, i), depth)
+		}
+		return items
+	}
+
+	if len(sub) > 0 {
+		e.fail(path, "Field %q is a scalar and cannot have a selection", field.Name)
+		return nil
+	}
+	return value
+}
+
+// collectFields flattens fragments and applies @skip/@include, grouping
+// fields by response key in query order.
+func (e *executor) collectFields(obj Object, sels []Selection, visited map[string]bool) ([]string, map[string][]*Field) {
+	var keys []string
+	fields := make(map[string][]*Field)
+
+	add := func(k []string, f map[string][]*Field) {
+		for _, key := range k {
+			if _, seen := fields[key]; !seen {
+				keys = append(keys, key)
+			}
+			fields[key] = append(fields[key], f[key]...)
+		}
+	}
+
+	for _, sel := range sels {
+		switch s := sel.(type) {
+		case *Field:
+			if !e.included(s.Directives) {
+				continue
+			}
+			add([]string{s.ResponseKey()}, map[string][]*Field{s.ResponseKey(): {s}})
+
+		case *FragmentSpread:
+			if !e.included(s.Directives) || visited[s.Name] {
+				continue
+			}
+			frag, ok := e.doc.Fragments[s.Name]
+			if !ok {
+				e.fail(nil, "Unknown fragment %q", s.Name)
+				continue
+			}
+			if !typeMatches(obj, frag.TypeCondition) {
+				continue
+			}
+			visited[s.Name] = true
+			add(e.collectFields(obj, frag.Selections, visited))
+
+		case *InlineFragment:
+			if !e.included(s.Directives) || !typeMatches(obj, s.TypeCondition) {
+				continue
+			}
+			add(e.collectFields(obj, s.Selections, visited))
+		}
+	}
+	return keys, fields
+}
+
+func typeMatches(obj Object, typeCondition string) bool {
+	if typeCondition == "" {
+		return true
+	}
+	name, _ := obj["__typename"].(string)
+	return name == "" || name == typeCondition
+}
+
+func (e *executor) included(dirs []Directive) bool {
+	for _, dir := range dirs {
+		flag, _ := literal(dir.Args["if"], e.vars).(bool)
+		switch dir.Name {
+		case "skip":
+			if flag {
+				return false
+			}
+		case "include":
+			if !flag {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func (e *executor) arguments(args map[string]Value) map[string]interface{} {
+	out := make(map[string]interface{}, len(args))
+	for name, value := range args {
+		if v, ok := value.(Variable); ok {
+			if _, set := e.vars[string(v)]; !set {
+				continue
+			}
+		}
+		out[name] = literal(value, e.vars)
+	}
+	return out
+}
+
+// literal converts a parsed value into plain Go values, substituting
+// variables from vars.
+func literal(value Value, vars map[string]interface{}) interface{} {
+	switch v := value.(type) {
+	case Variable:
+		return vars[string(v)]
+	case Enum:
+		return string(v)
+	case int64:
+		return int(v)
+	case []Value:
+		out := make([]interface{}, len(v))
+		for i, item := range v {
+			out[i] = literal(item, vars)
+		}
+		return out
+	case map[string]Value:
+		out := make(map[string]interface{}, len(v))
+		for k, item := range v {
+			out[k] = literal(item, vars)
+		}
+		return out
+	}
+	return value
+}
+
+// normalizeJSON makes whole-number variables ints, matching literals.
+func normalizeJSON(v interface{}) interface{} {
+	switch value := v.(type) {
+	case float64:
+		if value == float64(int(value)) {
+			return int(value)
+		}
+	case []interface{}:
+		for i, item := range value {
+			value[i] = normalizeJSON(item)
+		}
+	case map[string]interface{}:
+		for k, item := range value {
+			value[k] = normalizeJSON(item)
+		}
+	}
+	return v
+}
+
+// orderedMap keeps fields in the order the query asked for them, as the
+// spec requires of response objects.
+type orderedMap struct {
+	keys   []string
+	values map[string]interface{}
+}
+
+func (m *orderedMap) set(key string, value interface{}) {
+	if _, exists := m.values[key]; !exists {
+		m.keys = append(m.keys, key)
+	}
+	m.values[key] = value
+}
+
+func (m *orderedMap) MarshalJSON() ([]byte, error) {
+	if m == nil {
+		return []byte("null"), nil
+	}
+
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, key := range m.keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		k, _ := json.Marshal(key)
+		buf.Write(k)
+		buf.WriteByte(':')
+		v, err := json.Marshal(m.values[key])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(v)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
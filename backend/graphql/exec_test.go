@@ -0,0 +1,134 @@
+package graphql
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+var testLimits = Limits{Depth: 4, Fields: 50, Resolves: 20}
+
+// testRoot is a small graph: player(id) with a name and a list of matches,
+// each of which resolves its winner lazily.
+func testRoot(resolves *int) Object {
+	match := func(id string) Object {
+		return Object{
+			"__typename": "Match",
+			"id":         id,
+			"winner": Resolver(func(map[string]interface{}) (interface{}, error) {
+				*resolves++
+				return "CIVILIAN", nil
+			}),
+		}
+	}
+	return Object{
+		"__typename": "Query",
+		"player": Resolver(func(args map[string]interface{}) (interface{}, error) {
+			*resolves++
+			id, _ := args["id"].(string)
+			if id == "" {
+				return nil, errors.New("id is required")
+			}
+			return Object{
+				"__typename": "Player",
+				"id":         id,
+				"name":       "Ada",
+				"matches":    []Object{match("m1"), match("m2"), match("m3")},
+			}, nil
+		}),
+	}
+}
+
+func run(t *testing.T, req Request, limits Limits) (string, []Error, int) {
+	t.Helper()
+	resolves := 0
+	resp := Execute(testRoot(&resolves), req, limits)
+	data, err := json.Marshal(resp.Data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data), resp.Errors, resolves
+}
+
+func TestExecuteSelectsInQueryOrder(t *testing.T) {
+	data, errs, _ := run(t, Request{
+		Query:     `query P($id: String) { p: player(id: $id) { name ...Ids matches { ... on Match { winner } } } } fragment Ids on Player { id }`,
+		Variables: map[string]interface{}{"id": "u1"},
+	}, testLimits)
+
+	if len(errs) != 0 {
+		t.Fatalf("errors = %+v", errs)
+	}
+	want := `{"p":{"name":"Ada","id":"u1","matches":[{"winner":"CIVILIAN"},{"winner":"CIVILIAN"},{"winner":"CIVILIAN"}]}}`
+	if data != want {
+		t.Fatalf("data = %s, want %s", data, want)
+	}
+}
+
+func TestExecuteDirectivesAndErrors(t *testing.T) {
+	data, errs, _ := run(t, Request{
+		Query:     `query($skip: Boolean) { player(id: "u1") { name @skip(if: $skip) id } missing: player { id } }`,
+		Variables: map[string]interface{}{"skip": true},
+	}, testLimits)
+
+	if data != `{"player":{"id":"u1"},"missing":null}` {
+		t.Fatalf("data = %s", data)
+	}
+	if len(errs) != 1 || errs[0].Message != "id is required" || errs[0].Path[0] != "missing" {
+		t.Fatalf("errors = %+v", errs)
+	}
+}
+
+func TestExecuteRefusesMutations(t *testing.T) {
+	resp := Execute(Object{}, Request{Query: `mutation { ban(id: "x") }`}, testLimits)
+	if resp.Data != nil || len(resp.Errors) != 1 || !strings.Contains(resp.Errors[0].Message, "read-only") {
+		t.Fatalf("response = %+v", resp)
+	}
+}
+
+func TestExecuteLimitsDepth(t *testing.T) {
+	_, errs, _ := run(t, Request{Query: `{ player(id: "u1") { matches { winner } } }`}, Limits{Depth: 1, Fields: 50, Resolves: 20})
+	if len(errs) != 1 || !strings.Contains(errs[0].Message, "nested too deeply") {
+		t.Fatalf("errors = %+v", errs)
+	}
+}
+
+func TestExecuteLimitsFieldsBeforeRunning(t *testing.T) {
+	var aliases strings.Builder
+	for i := 0; i < 30; i++ {
+		fmt.Fprintf(&aliases, ` p%d: player(id: "u1") { id }`, i)
+	}
+	resolves := 0
+	resp := Execute(testRoot(&resolves), Request{Query: `{` + aliases.String() + ` }`}, testLimits)
+
+	if resp.Data != nil || len(resp.Errors) != 1 || !strings.Contains(resp.Errors[0].Message, "too many fields") {
+		t.Fatalf("response = %+v", resp)
+	}
+	if resolves != 0 {
+		t.Fatalf("%d resolvers ran for a refused query", resolves)
+	}
+}
+
+func TestExecuteCountsFragmentsEachTimeSpread(t *testing.T) {
+	query := `{ player(id: "u1") { ...A ...A ...A } }
+		fragment A on Player { ...B ...B ...B }
+		fragment B on Player { ...C ...C ...C }
+		fragment C on Player { id name id name id name }`
+	resp := Execute(Object{}, Request{Query: query}, testLimits)
+	if resp.Data != nil || len(resp.Errors) != 1 || !strings.Contains(resp.Errors[0].Message, "too many fields") {
+		t.Fatalf("response = %+v", resp)
+	}
+}
+
+func TestExecuteLimitsResolves(t *testing.T) {
+	_, errs, resolves := run(t, Request{Query: `{ player(id: "u1") { matches { winner } } }`}, Limits{Depth: 4, Fields: 50, Resolves: 3})
+
+	if resolves != 3 {
+		t.Fatalf("resolvers ran %d times, want 3", resolves)
+	}
+	if len(errs) != 1 || !strings.Contains(errs[0].Message, "resolves too many fields") {
+		t.Fatalf("errors = %+v", errs)
+	}
+}
@@ -0,0 +1,691 @@
+package graphql
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// The parser covers the executable subset of the GraphQL grammar: queries
+// with variables, fields, aliases, arguments, directives and fragments.
+// Type-system definitions are rejected.
+
+type Document struct {
+	Operations []*Operation
+	Fragments  map[string]*Fragment
+}
+
+type Operation struct {
+	Kind       string
+	Name       string
+	Variables  []VariableDef
+	Selections []Selection
+}
+
+type VariableDef struct {
+	Name    string
+	Default Value
+}
+
+type Fragment struct {
+	Name          string
+	TypeCondition string
+	Selections    []Selection
+}
+
+type Selection interface{}
+
+type Field struct {
+	Alias      string
+	Name       string
+	Args       map[string]Value
+	Directives []Directive
+	Selections []Selection
+}
+
+func (f *Field) ResponseKey() string {
+	if f.Alias != "" {
+		return f.Alias
+	}
+	return f.Name
+}
+
+type FragmentSpread struct {
+	Name       string
+	Directives []Directive
+}
+
+type InlineFragment struct {
+	TypeCondition string
+	Directives    []Directive
+	Selections    []Selection
+}
+
+type Directive struct {
+	Name string
+	Args map[string]Value
+}
+
+// Value is a literal from the query: nil, bool, int64, float64, string,
+// Enum, Variable, []Value or map[string]Value.
+type Value interface{}
+
+type Variable string
+
+type Enum string
+
+const (
+	tokEOF = iota
+	tokPunct
+	tokName
+	tokInt
+	tokFloat
+	tokString
+)
+
+type token struct {
+	kind  int
+	value string
+	pos   int
+}
+
+type lexer struct {
+	src string
+	pos int
+}
+
+func (l *lexer) next() (token, error) {
+	for l.pos < len(l.src) {
+		c := l.src[l.pos]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == ',':
+			l.pos++
+		case c == '#':
+			for l.pos < len(l.src) && l.src[l.pos] != '\n' && l.src[l.pos] != '\r' {
+				l.pos++
+			}
+		case strings.HasPrefix(l.src[l.pos:], "\uFEFF"):
+			l.pos += len("\uFEFF")
+		default:
+			return l.token()
+		}
+	}
+	return token{kind: tokEOF, pos: l.pos}, nil
+}
+
+func (l *lexer) token() (token, error) {
+	start := l.pos
+	c := l.src[l.pos]
+
+	switch {
+	case strings.HasPrefix(l.src[l.pos:], "..."):
+		l.pos += 3
+		return token{tokPunct, "...", start}, nil
+	case strings.ContainsRune("!$()&:=@[]{}|", rune(c)):
+		l.pos++
+		return token{tokPunct, string(c), start}, nil
+	case c == '_' || isLetter(c):
+		for l.pos < len(l.src) && (l.src[l.pos] == '_' || isLetter(l.src[l.pos]) || isDigit(l.src[l.pos])) {
+			l.pos++
+		}
+		return token{tokName, l.src[start:l.pos], start}, nil
+	case c == '-' || isDigit(c):
+		return l.number()
+	case strings.HasPrefix(l.src[l.pos:], `"""`):
+		return l.blockString()
+	case c == '"':
+		return l.string()
+	}
+	return token{}, fmt.Errorf("unexpected character %q at %d", c, start)
+}
+
+func (l *lexer) number() (token, error) {
+	start := l.pos
+	kind := tokInt
+	if l.src[l.pos] == '-' {
+		l.pos++
+	}
+	digits := func() {
+		for l.pos < len(l.src) && isDigit(l.src[l.pos]) {
+			l.pos++
+		}
+	}
+	digits()
+	if l.pos < len(l.src) && l.src[l.pos] == '.' {
+		kind = tokFloat
+		l.pos++
+		digits()
+	}
+	if l.pos < len(l.src) && (l.src[l.pos] == 'e' || l.src[l.pos] == 'E') {
+		kind = tokFloat
+		l.pos++
+		if l.pos < len(l.src) && (l.src[l.pos] == '+' || l.src[l.pos] == '-') {
+			l.pos++
+		}
+		digits()
+	}
+	return token{kind, l.src[start:l.pos], start}, nil
+}
+
+func (l *lexer) string() (token, error) {
+	start := l.pos
+	l.pos++
+	var b strings.Builder
+	for l.pos < len(l.src) {
+		c := l.src[l.pos]
+		switch c {
+		case '"':
+			l.pos++
+			return token{tokString, b.String(), start}, nil
+		case '\n', '\r':
+			return token{}, fmt.Errorf("unterminated string at %d", start)
+		case '\\':
+			if l.pos+1 >= len(l.src) {
+				return token{}, fmt.Errorf("unterminated string at %d", start)
+			}
+			esc := l.src[l.pos+1]
+			l.pos += 2
+			switch esc {
+			case '"', '\\', '/':
+				b.WriteByte(esc)
+			case 'b':
+				b.WriteByte('\b')
+			case 'f':
+				b.WriteByte('\f')
+			case 'n':
+				b.WriteByte('\n')
+			case 'r':
+				b.WriteByte('\r')
+			case 't':
+				b.WriteByte('\t')
+			case 'u':
+				if l.pos+4 > len(l.src) {
+					return token{}, fmt.Errorf("bad unicode escape at %d", l.pos)
+				}
+				n, err := strconv.ParseUint(l.src[l.pos:l.pos+4], 16, 32)
+				if err != nil {
+					return token{}, fmt.Errorf("bad unicode escape at %d", l.pos)
+				}
+				b.WriteRune(rune(n))
+				l.pos += 4
+			default:
+				return token{}, fmt.Errorf("bad escape \\%c at %d", esc, l.pos-2)
+			}
+		default:
+			b.WriteByte(c)
+			l.pos++
+		}
+	}
+	return token{}, fmt.Errorf("unterminated string at %d", start)
+}
+
+// blockString keeps the raw contents; the common-indent stripping from the
+// spec doesn't matter for argument values this API accepts.
+func (l *lexer) blockString() (token, error) {
+	start := l.pos
+	end := strings.Index(l.src[l.pos+3:], `"""`)
+	if end < 0 {
+		return token{}, fmt.Errorf("unterminated block string at %d", start)
+	}
+	value := l.src[l.pos+3 : l.pos+3+end]
+	l.pos += 3 + end + 3
+	return token{tokString, strings.TrimSpace(value), start}, nil
+}
+
+func isLetter(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+type parser struct {
+	lex *lexer
+	tok token
+}
+
+// Parse turns a query string into a Document.
+func Parse(query string) (*Document, error) {
+	p := &parser{lex: &lexer{src: query}}
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+
+	doc := &Document{Fragments: make(map[string]*Fragment)}
+	for p.tok.kind != tokEOF {
+		switch {
+		case p.peek(tokPunct, "{"):
+			sels, err := p.selectionSet()
+			if err != nil {
+				return nil, err
+			}
+			doc.Operations = append(doc.Operations, &Operation{Kind: "query", Selections: sels})
+		case p.peek(tokName, "query"), p.peek(tokName, "mutation"), p.peek(tokName, "subscription"):
+			op, err := p.operation()
+			if err != nil {
+				return nil, err
+			}
+			doc.Operations = append(doc.Operations, op)
+		case p.peek(tokName, "fragment"):
+			frag, err := p.fragment()
+			if err != nil {
+				return nil, err
+			}
+			if _, dup := doc.Fragments[frag.Name]; dup {
+				return nil, fmt.Errorf("fragment %q is defined more than once", frag.Name)
+			}
+			doc.Fragments[frag.Name] = frag
+		default:
+			return nil, p.unexpected()
+		}
+	}
+
+	if len(doc.Operations) == 0 {
+		return nil, fmt.Errorf("document has no operations")
+	}
+	return doc, nil
+}
+
+func (p *parser) advance() error {
+	tok, err := p.lex.next()
+	if err != nil {
+		return err
+	}
+	p.tok = tok
+	return nil
+}
+
+func (p *parser) peek(kind int, value string) bool {
+	return p.tok.kind == kind && p.tok.value == value
+}
+
+func (p *parser) unexpected() error {
+	if p.tok.kind == tokEOF {
+		return fmt.Errorf("unexpected end of query")
+	}
+	return fmt.Errorf("unexpected %q at %d", p.tok.value, p.tok.pos)
+}
+
+func (p *parser) expect(value string) error {
+	if p.tok.kind != tokPunct || p.tok.value != value {
+		return fmt.Errorf("expected %q, got %s", value, p.describe())
+	}
+	return p.advance()
+}
+
+func (p *parser) describe() string {
+	if p.tok.kind == tokEOF {
+		return "end of query"
+	}
+	return fmt.Sprintf("%q at %d", p.tok.value, p.tok.pos)
+}
+
+func (p *parser) name() (string, error) {
+	if p.tok.kind != tokName {
+		return "", fmt.Errorf("expected a name, got %s", p.describe())
+	}
+	name := p.tok.value
+	return name, p.advance()
+}
+
+func (p *parser) operation() (*Operation, error) {
+	op := &Operation{Kind: p.tok.value}
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+
+	if p.tok.kind == tokName {
+		op.Name = p.tok.value
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+	}
+
+	if p.peek(tokPunct, "(") {
+		defs, err := p.variableDefinitions()
+		if err != nil {
+			return nil, err
+		}
+		op.Variables = defs
+	}
+
+	if _, err := p.directives(); err != nil {
+		return nil, err
+	}
+
+	sels, err := p.selectionSet()
+	if err != nil {
+		return nil, err
+	}
+	op.Selections = sels
+	return op, nil
+}
+
+func (p *parser) variableDefinitions() ([]VariableDef, error) {
+	if err := p.expect("("); err != nil {
+		return nil, err
+	}
+
+	var defs []VariableDef
+	for !p.peek(tokPunct, ")") {
+		if err := p.expect("$"); err != nil {
+			return nil, err
+		}
+		name, err := p.name()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(":"); err != nil {
+			return nil, err
+		}
+		if err := p.skipType(); err != nil {
+			return nil, err
+		}
+
+		def := VariableDef{Name: name}
+		if p.peek(tokPunct, "=") {
+			if err := p.advance(); err != nil {
+				return nil, err
+			}
+			value, err := p.value(true)
+			if err != nil {
+				return nil, err
+			}
+			def.Default = value
+		}
+		if _, err := p.directives(); err != nil {
+			return nil, err
+		}
+		defs = append(defs, def)
+	}
+	return defs, p.advance()
+}
+
+// skipType consumes a type reference such as [String!]!. Variable types
+// aren't checked; resolvers validate the values they receive.
+func (p *parser) skipType() error {
+	if p.peek(tokPunct, "[") {
+		if err := p.advance(); err != nil {
+			return err
+		}
+		if err := p.skipType(); err != nil {
+			return err
+		}
+		if err := p.expect("]"); err != nil {
+			return err
+		}
+	} else if _, err := p.name(); err != nil {
+		return err
+	}
+
+	if p.peek(tokPunct, "!") {
+		return p.advance()
+	}
+	return nil
+}
+
+func (p *parser) fragment() (*Fragment, error) {
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+	name, err := p.name()
+	if err != nil {
+		return nil, err
+	}
+	if name == "on" {
+		return nil, fmt.Errorf("a fragment cannot be named \"on\"")
+	}
+	if !p.peek(tokName, "on") {
+		return nil, fmt.Errorf("expected \"on\", got %s", p.describe())
+	}
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+	typeCondition, err := p.name()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := p.directives(); err != nil {
+		return nil, err
+	}
+	sels, err := p.selectionSet()
+	if err != nil {
+		return nil, err
+	}
+	return &Fragment{Name: name, TypeCondition: typeCondition, Selections: sels}, nil
+}
+
+func (p *parser) selectionSet() ([]Selection, error) {
+	if err := p.expect("{"); err != nil {
+		return nil, err
+	}
+
+	var sels []Selection
+	for !p.peek(tokPunct, "}") {
+		if p.tok.kind == tokEOF {
+			return nil, p.unexpected()
+		}
+		sel, err := p.selection()
+		if err != nil {
+			return nil, err
+		}
+		sels = append(sels, sel)
+	}
+	if len(sels) == 0 {
+		return nil, fmt.Errorf("empty selection set at %d", p.tok.pos)
+	}
+	return sels, p.advance()
+}
+
+func (p *parser) selection() (Selection, error) {
+	if p.peek(tokPunct, "...") {
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+
+		if p.tok.kind == tokName && p.tok.value != "on" {
+			spread := &FragmentSpread{Name: p.tok.value}
+			if err := p.advance(); err != nil {
+				return nil, err
+			}
+			dirs, err := p.directives()
+			if err != nil {
+				return nil, err
+			}
+			spread.Directives = dirs
+			return spread, nil
+		}
+
+		inline := &InlineFragment{}
+		if p.peek(tokName, "on") {
+			if err := p.advance(); err != nil {
+				return nil, err
+			}
+			typeCondition, err := p.name()
+			if err != nil {
+				return nil, err
+			}
+			inline.TypeCondition = typeCondition
+		}
+		dirs, err := p.directives()
+		if err != nil {
+			return nil, err
+		}
+		inline.Directives = dirs
+		sels, err := p.selectionSet()
+		if err != nil {
+			return nil, err
+		}
+		inline.Selections = sels
+		return inline, nil
+	}
+
+	field := &Field{}
+	name, err := p.name()
+	if err != nil {
+		return nil, err
+	}
+	if p.peek(tokPunct, ":") {
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		field.Alias = name
+		if name, err = p.name(); err != nil {
+			return nil, err
+		}
+	}
+	field.Name = name
+
+	if p.peek(tokPunct, "(") {
+		args, err := p.arguments()
+		if err != nil {
+			return nil, err
+		}
+		field.Args = args
+	}
+	if field.Directives, err = p.directives(); err != nil {
+		return nil, err
+	}
+	if p.peek(tokPunct, "{") {
+		if field.Selections, err = p.selectionSet(); err != nil {
+			return nil, err
+		}
+	}
+	return field, nil
+}
+
+func (p *parser) arguments() (map[string]Value, error) {
+	if err := p.expect("("); err != nil {
+		return nil, err
+	}
+
+	args := make(map[string]Value)
+	for !p.peek(tokPunct, ")") {
+		name, err := p.name()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(":"); err != nil {
+			return nil, err
+		}
+		value, err := p.value(false)
+		if err != nil {
+			return nil, err
+		}
+		if _, dup := args[name]; dup {
+			return nil, fmt.Errorf("argument %q given more than once", name)
+		}
+		args[name] = value
+	}
+	return args, p.advance()
+}
+
+func (p *parser) directives() ([]Directive, error) {
+	var dirs []Directive
+	for p.peek(tokPunct, "@") {
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		name, err := p.name()
+		if err != nil {
+			return nil, err
+		}
+		dir := Directive{Name: name}
+		if p.peek(tokPunct, "(") {
+			if dir.Args, err = p.arguments(); err != nil {
+				return nil, err
+			}
+		}
+		dirs = append(dirs, dir)
+	}
+	return dirs, nil
+}
+
+func (p *parser) value(constant bool) (Value, error) {
+	tok := p.tok
+	switch {
+	case tok.kind == tokPunct && tok.value == "$":
+		if constant {
+			return nil, fmt.Errorf("variables are not allowed in default values")
+		}
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		name, err := p.name()
+		if err != nil {
+			return nil, err
+		}
+		return Variable(name), nil
+
+	case tok.kind == tokPunct && tok.value == "[":
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		list := []Value{}
+		for !p.peek(tokPunct, "]") {
+			if p.tok.kind == tokEOF {
+				return nil, p.unexpected()
+			}
+			item, err := p.value(constant)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, item)
+		}
+		return list, p.advance()
+
+	case tok.kind == tokPunct && tok.value == "{":
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		obj := make(map[string]Value)
+		for !p.peek(tokPunct, "}") {
+			name, err := p.name()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect(":"); err != nil {
+				return nil, err
+			}
+			item, err := p.value(constant)
+			if err != nil {
+				return nil, err
+			}
+			obj[name] = item
+		}
+		return obj, p.advance()
+
+	case tok.kind == tokInt:
+		n, err := strconv.ParseInt(tok.value, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid integer %q", tok.value)
+		}
+		return n, p.advance()
+
+	case tok.kind == tokFloat:
+		f, err := strconv.ParseFloat(tok.value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid float %q", tok.value)
+		}
+		return f, p.advance()
+
+	case tok.kind == tokString:
+		return tok.value, p.advance()
+
+	case tok.kind == tokName:
+		var v Value
+		switch tok.value {
+		case "true":
+			v = true
+		case "false":
+			v = false
+		case "null":
+			v = nil
+		default:
+			v = Enum(tok.value)
+		}
+		return v, p.advance()
+	}
+	return nil, p.unexpected()
+}
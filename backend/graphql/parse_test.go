@@ -0,0 +1,88 @@
+package graphql
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseQuery(t *testing.T) {
+	doc, err := Parse(`
+		query Recent($limit: Int = 5, $player: String) {
+			latest: matches(limit: $limit, outcome: WON, tags: ["a", "b"]) {
+				total
+				items @include(if: true) { ...MatchFields }
+			}
+			player(id: $player) { ... on Player { id } }
+		}
+		fragment MatchFields on Match { id roomCode }
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(doc.Operations) != 1 {
+		t.Fatalf("operations = %d, want 1", len(doc.Operations))
+	}
+	op := doc.Operations[0]
+	if op.Kind != "query" || op.Name != "Recent" {
+		t.Fatalf("operation = %s %s, want query Recent", op.Kind, op.Name)
+	}
+	if len(op.Variables) != 2 || op.Variables[0].Name != "limit" || op.Variables[0].Default != int64(5) {
+		t.Fatalf("variables = %+v", op.Variables)
+	}
+
+	matches := op.Selections[0].(*Field)
+	if matches.Name != "matches" || matches.ResponseKey() != "latest" {
+		t.Fatalf("field = %s as %s, want matches as latest", matches.Name, matches.ResponseKey())
+	}
+	wantArgs := map[string]Value{
+		"limit":   Variable("limit"),
+		"outcome": Enum("WON"),
+		"tags":    []Value{"a", "b"},
+	}
+	if !reflect.DeepEqual(matches.Args, wantArgs) {
+		t.Fatalf("args = %#v, want %#v", matches.Args, wantArgs)
+	}
+
+	items := matches.Selections[1].(*Field)
+	if len(items.Directives) != 1 || items.Directives[0].Name != "include" {
+		t.Fatalf("directives = %+v", items.Directives)
+	}
+	if spread, ok := items.Selections[0].(*FragmentSpread); !ok || spread.Name != "MatchFields" {
+		t.Fatalf("selection = %#v, want a MatchFields spread", items.Selections[0])
+	}
+
+	player := op.Selections[1].(*Field)
+	if inline, ok := player.Selections[0].(*InlineFragment); !ok || inline.TypeCondition != "Player" {
+		t.Fatalf("selection = %#v, want an inline fragment on Player", player.Selections[0])
+	}
+
+	frag := doc.Fragments["MatchFields"]
+	if frag == nil || frag.TypeCondition != "Match" || len(frag.Selections) != 2 {
+		t.Fatalf("fragment = %+v", frag)
+	}
+}
+
+func TestParseShorthandQuery(t *testing.T) {
+	doc, err := Parse(`{ leaderboard { rank } }`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if op := doc.Operations[0]; op.Kind != "query" || len(op.Selections) != 1 {
+		t.Fatalf("operation = %+v", op)
+	}
+}
+
+func TestParseRejectsBadDocuments(t *testing.T) {
+	for _, query := range []string{
+		``,
+		`{ matches { id }`,
+		`query { matches(limit: ) { id } }`,
+		`type Query { id: ID }`,
+		`fragment F on Match { id } fragment F on Match { id } { ...F }`,
+	} {
+		if _, err := Parse(query); err == nil {
+			t.Errorf("Parse(%q) succeeded, want an error", query)
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"code-mafia-backend/database"
+)
+
+// /healthz says the process is up and serving; /readyz also says whether
+// it can do its job, so an orchestrator stops routing players to an
+// instance that has lost Redis, Supabase or the translation sidecar's
+// subscription, or is draining for shutdown. Dependency checks are cached
+// for readyCacheTTL so frequent probes don't hammer them.
+
+const (
+	readyCacheTTL     = 5 * time.Second
+	readyCheckTimeout = 2 * time.Second
+)
+
+type dependencyCheck struct {
+	OK        bool   `json:"ok"`
+	Skipped   bool   `json:"skipped,omitempty"`
+	LatencyMs int64  `json:"latencyMs"`
+	Error     string `json:"error,omitempty"`
+}
+
+type readinessReport struct {
+	Ready     bool                       `json:"ready"`
+	Draining  bool                       `json:"draining,omitempty"`
+	Checks    map[string]dependencyCheck `json:"checks"`
+	CheckedAt time.Time                  `json:"checkedAt"`
+}
+
+// readiness caches the last dependency checks.
+type readiness struct {
+	mu     sync.Mutex
+	report readinessReport
+}
+
+var ready readiness
+
+func handleHealthz(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("OK"))
+}
+
+func (h *Hub) handleReadyz(w http.ResponseWriter, r *http.Request) {
+	report := ready.check(h)
+	if h.isClosing() {
+		report.Ready = false
+		report.Draining = true
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	if !report.Ready {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	json.NewEncoder(w).Encode(report)
+}
+
+// check returns the cached report, running the checks again once it is
+// older than readyCacheTTL. Probes arriving meanwhile wait for the run
+// under way rather than starting their own.
+func (rd *readiness) check(h *Hub) readinessReport {
+	rd.mu.Lock()
+	defer rd.mu.Unlock()
+
+	if time.Since(rd.report.CheckedAt) < readyCacheTTL {
+		return rd.report
+	}
+
+	checks := map[string]dependencyCheck{
+		"redis":        runCheck(database.Ping),
+		"supabase":     dependencyCheck{OK: true, Skipped: true},
+		"translations": dependencyCheck{OK: true},
+	}
+	if database.SupabaseEnabled() {
+		checks["supabase"] = runCheck(database.PingSupabase)
+	}
+	if !h.translationsListening() {
+		checks["translations"] = dependencyCheck{Error: "not subscribed to the translation channels"}
+	}
+
+	report := readinessReport{Ready: true, Checks: checks, CheckedAt: time.Now()}
+	for _, c := range checks {
+		report.Ready = report.Ready && c.OK
+	}
+	rd.report = report
+	return report
+}
+
+func runCheck(ping func(context.Context) error) dependencyCheck {
+	ctx, cancel := context.WithTimeout(context.Background(), readyCheckTimeout)
+	defer cancel()
+
+	start := time.Now()
+	err := ping(ctx)
+	check := dependencyCheck{OK: err == nil, LatencyMs: time.Since(start).Milliseconds()}
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			check.Error = "timed out"
+		} else {
+			check.Error = err.Error()
+		}
+	}
+	return check
+}
+
+// translationsListening reports whether listenForTranslations is
+// subscribed and reading.
+func (h *Hub) translationsListening() bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.translationsUp
+}
+
+func (h *Hub) setTranslationsListening(up bool) {
+	h.mu.Lock()
+	h.translationsUp = up
+	h.mu.Unlock()
+}
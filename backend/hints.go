@@ -0,0 +1,264 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"code-mafia-backend/config"
+	"code-mafia-backend/database"
+
+	"github.com/google/uuid"
+)
+
+// Hints help a stuck crew along at a price. A HINT sends the task and the
+// asker's code to a sidecar on HINT_CHANNEL, the way chat goes out for
+// translation, and the answer on HINT_RESULT_CHANNEL goes to whoever works
+// on that code: the whole room, or in a personal-tasks game just the
+// asker. Each hint takes the host's penalty off the clock, and each one
+// in a stage says more than the last, up to hintTiers.
+
+// hintTiers is how specific hints get: 1 points at where the bug is, 2
+// says what is wrong, 3 all but gives the fix.
+const hintTiers = 3
+
+// hintPenaltyFloor is as low as a hint's penalty takes the clock.
+const hintPenaltyFloor = 10 * time.Second
+
+// maxHintCodeBytes is as much of the code as goes to the hint service.
+const maxHintCodeBytes = 16 << 10
+
+// hintRequest is what the hint service is sent on HINT_CHANNEL.
+type hintRequest struct {
+	RequestID   string `json:"requestId"`
+	RoomID      string `json:"roomId"`
+	PlayerID    string `json:"playerId"`
+	Stage       int    `json:"stage"`
+	TaskID      string `json:"taskId"`
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	Language    string `json:"language"`
+	Code        string `json:"code"`
+	Tier        int    `json:"tier"`
+	// Previous are the hints already given for this task, so the next one
+	// doesn't repeat them.
+	Previous []string `json:"previous"`
+
+	key   string
+	timer *time.Timer
+}
+
+// hintResult is the hint service's answer on HINT_RESULT_CHANNEL.
+type hintResult struct {
+	RequestID string `json:"requestId"`
+	RoomID    string `json:"roomId"`
+	Hint      string `json:"hint"`
+	Error     string `json:"error,omitempty"`
+}
+
+// hintKey is what the per-stage limit counts against: the stage, or in a
+// personal-tasks game the player's own task.
+func (r *Room) hintKey(playerID string) string {
+	if r.personalGame() {
+		return "player:" + playerID
+	}
+	return fmt.Sprintf("stage:%d", r.gameState.CurrentStage)
+}
+
+// checkHint returns why playerID can't ask for a hint now, or "" if they
+// can. Runs on the room goroutine.
+func (r *Room) checkHint(playerID string) string {
+	player := r.players[playerID]
+	phase := r.gameState.Phase
+	switch {
+	case config.AppConfig.HintChannel == "":
+		return "Hints are turned off on this server"
+	case !r.gameState.Settings.Hints:
+		return "The host hasn't turned hints on"
+	case player == nil || player.IsEliminated:
+		return "Only players still in the game can ask for hints"
+	case phase != PhaseTask1 && phase != PhaseTask2 && phase != PhaseTask3:
+		return "Hints are only given while the crew is working on a task"
+	case r.gameState.TasksComplete[r.gameState.CurrentStage]:
+		return "This stage is already done"
+	case r.pendingHint != nil:
+		return "A hint is already on its way"
+	case len(r.hints[r.hintKey(playerID)]) >= r.gameState.Settings.hintsPerStage():
+		return "No hints left for this task"
+	}
+	return ""
+}
+
+// requestHint asks the hint service about playerID's task and code. Runs
+// on the room goroutine; the answer comes back through deliverHint.
+func (r *Room) requestHint(playerID, code string) string {
+	if reason := r.checkHint(playerID); reason != "" {
+		return reason
+	}
+
+	if len(code) > maxHintCodeBytes {
+		code = code[:maxHintCodeBytes]
+	}
+	key := r.hintKey(playerID)
+	previous := r.hints[key]
+	tier := len(previous) + 1
+	if tier > hintTiers {
+		tier = hintTiers
+	}
+
+	task := r.runTask(playerID, r.gameState.CurrentStage)
+	language := task.Language
+	if language == "" {
+		language = defaultTaskLanguage
+	}
+	req := &hintRequest{
+		RequestID:   uuid.New().String(),
+		RoomID:      r.ID,
+		PlayerID:    playerID,
+		Stage:       r.gameState.CurrentStage,
+		TaskID:      task.ID,
+		Title:       task.Title,
+		Description: task.Description,
+		Language:    language,
+		Code:        code,
+		Tier:        tier,
+		Previous:    append([]string(nil), previous...),
+		key:         key,
+	}
+	r.pendingHint = req
+
+	requestID := req.RequestID
+	req.timer = r.schedule(time.Duration(config.AppConfig.HintTimeoutSec)*time.Second, func() {
+		r.failHint(requestID, "The hint service didn't answer in time - try again")
+	})
+
+	go func() {
+		defer recoverPanic("publishHintRequest")
+
+		receivers, err := database.PublishHintRequest(config.AppConfig.HintChannel, req)
+		switch {
+		case err != nil:
+			log.Printf("❌ Room %s: %v", r.ID, err)
+			r.post(func() { r.failHint(requestID, "Couldn't reach the hint service") })
+		case receivers == 0:
+			r.post(func() { r.failHint(requestID, "No hint service is running") })
+		default:
+			log.Printf("💡 Room %s: tier %d hint requested for %s", r.ID, tier, key)
+		}
+	}()
+	return ""
+}
+
+// failHint gives up on a request, telling the asker why. Nothing is
+// charged for it. Runs on the room goroutine.
+func (r *Room) failHint(requestID, reason string) {
+	req := r.pendingHint
+	if req == nil || req.RequestID != requestID {
+		return
+	}
+	r.dropPendingHint()
+
+	if client := r.clientFor(req.PlayerID); client != nil {
+		client.sendError(reason)
+	}
+}
+
+func (r *Room) dropPendingHint() {
+	if r.pendingHint != nil && r.pendingHint.timer != nil {
+		r.pendingHint.timer.Stop()
+	}
+	r.pendingHint = nil
+}
+
+// deliverHint hands out the hint service's answer and takes the penalty
+// off the clock. An answer for a stage that has since ended, or that
+// comes during a meeting, is dropped without charge. Runs on the room
+// goroutine.
+func (r *Room) deliverHint(res hintResult) {
+	req := r.pendingHint
+	if req == nil || req.RequestID != res.RequestID {
+		return
+	}
+
+	hint := strings.TrimSpace(res.Hint)
+	if res.Error != "" || hint == "" {
+		log.Printf("⚠️ Room %s: hint service couldn't help: %s", r.ID, res.Error)
+		r.failHint(req.RequestID, "The hint service couldn't come up with a hint - try again")
+		return
+	}
+	if r.gameState.CurrentStage != req.Stage || r.gameState.Phase == PhaseDiscussion || r.gameState.Phase == PhaseEnd ||
+		r.gameState.TasksComplete[req.Stage] {
+		r.failHint(req.RequestID, "The hint arrived too late for this stage")
+		return
+	}
+	r.dropPendingHint()
+
+	r.hints[req.key] = append(r.hints[req.key], hint)
+	left := r.gameState.Settings.hintsPerStage() - len(r.hints[req.key])
+
+	penalty := time.Duration(r.gameState.Settings.hintPenaltySec()) * time.Second
+	if most := r.gameState.timerRemaining(time.Now()) - hintPenaltyFloor; penalty > most {
+		penalty = most
+	}
+	if penalty < 0 {
+		penalty = 0
+	}
+	r.gameState.TimerDeadline = r.gameState.TimerDeadline.Add(-penalty)
+	r.armClock()
+	r.saveToRedis()
+
+	seconds := int(penalty.Round(time.Second).Seconds())
+	asker := "Someone"
+	if player := r.players[req.PlayerID]; player != nil {
+		asker = player.Username
+	}
+	log.Printf("💡 Room %s: tier %d hint for %s cost %ds", r.ID, req.Tier, req.key, seconds)
+
+	msg := Message{
+		Type: "HINT",
+		Data: map[string]interface{}{
+			"stage":          req.Stage,
+			"tier":           req.Tier,
+			"text":           hint,
+			"requestedBy":    asker,
+			"hintsLeft":      left,
+			"penaltySeconds": seconds,
+		},
+	}
+	if r.personalGame() {
+		if client := r.clientFor(req.PlayerID); client != nil {
+			client.sendMessage(msg)
+		}
+	} else {
+		data, _ := json.Marshal(msg)
+		r.emit(data)
+	}
+
+	payload := r.timerPayload()
+	payload["hintPenaltySeconds"] = seconds
+	data, _ := json.Marshal(Message{Type: "SYNC_TIMER", Data: payload})
+	r.emit(data)
+}
+
+// handleHintResult routes an answer from the hint service to its room.
+func (h *Hub) handleHintResult(payload string) {
+	defer recoverPanic("handleHintResult")
+
+	var res hintResult
+	if err := json.Unmarshal([]byte(payload), &res); err != nil {
+		log.Printf("Failed to parse hint result: %v", err)
+		return
+	}
+
+	h.mu.RLock()
+	room := h.rooms[res.RoomID]
+	h.mu.RUnlock()
+
+	if room == nil {
+		debugf("Room %s not found for hint %s", res.RoomID, res.RequestID)
+		return
+	}
+	room.post(func() { room.deliverHint(res) })
+}
@@ -2,12 +2,13 @@ package main
 
 import (
 	"code-mafia-backend/database"
+	"code-mafia-backend/webhooks"
 	"encoding/json"
 	"log"
 	"sync"
 	"time"
 
-	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
 )
 
 type Hub struct {
@@ -15,6 +16,19 @@ type Hub struct {
 	register   chan *Client
 	unregister chan *Client
 	mu         sync.RWMutex
+
+	presence *presence
+	parties  *parties
+
+	// cluster is nil unless CLUSTER_MODE is on.
+	cluster *cluster
+
+	// translations is the subscription listenForTranslations reads, and
+	// translationsUp is set while it is subscribed and reading.
+	// closing is closed when the server is shutting down.
+	translations   *redis.PubSub
+	translationsUp bool
+	closing        chan struct{}
 }
 
 func newHub() *Hub {
@@ -22,6 +36,9 @@ func newHub() *Hub {
 		rooms:      make(map[string]*Room),
 		register:   make(chan *Client),
 		unregister: make(chan *Client),
+		presence:   newPresence(),
+		parties:    newParties(),
+		closing:    make(chan struct{}),
 	}
 }
 
@@ -37,23 +54,118 @@ func (h *Hub) run() {
 }
 
 func (h *Hub) handleRegister(client *Client) {
+	placed := false
+	defer func() {
+		client.refused = !placed
+		close(client.registered)
+	}()
+
+	if bannedFromRoom(client) {
+		log.Printf("🔨 Rejected %s, banned from room %s by the host (conn=%s)", client.PlayerID, client.RoomID, client.ConnID)
+		client.refuse(Message{
+			Type: "ERROR_ACCESS_DENIED",
+			Data: map[string]interface{}{
+				"reason":  "BANNED",
+				"message": "You were banned from this room by the host",
+			},
+		})
+		return
+	}
+
 	h.mu.Lock()
 	room, exists := h.rooms[client.RoomID]
 
+	if !exists && h.cluster != nil {
+		// A stand-in for a room this instance no longer runs is sent back
+		// to reconnect.
+		if client.remote != "" {
+			h.mu.Unlock()
+			client.close()
+			return
+		}
+		claimed, err := h.cluster.claim(client.RoomID)
+		if err != nil {
+			h.mu.Unlock()
+			client.refuse(Message{
+				Type: "ERROR_ACCESS_DENIED",
+				Data: map[string]interface{}{
+					"reason":  "ROOM_UNAVAILABLE",
+					"message": "The room can't be reached right now - try again in a moment",
+				},
+			})
+			return
+		}
+		if !claimed {
+			h.mu.Unlock()
+			h.cluster.relay(client)
+			placed = true
+			return
+		}
+	}
+
 	if !exists {
 		room = newRoom(client.RoomID)
+		room.hub = h
 		h.rooms[client.RoomID] = room
 		go room.run()
 		log.Printf("✅ Created new room %s", client.RoomID)
 	}
 	h.mu.Unlock()
 
-	room.mu.RLock()
-	currentPhase := room.gameState.Phase
-	room.mu.RUnlock()
+	room.do(func() { placed = room.admit(client) })
+
+	if placed {
+		h.presence.add(client)
+	}
+}
+
+// refuse sends a connection its last message and hangs up once it has had
+// a moment to go out.
+func (c *Client) refuse(msg Message) {
+	data, _ := json.Marshal(msg)
+
+	go func() {
+		select {
+		case c.send <- data:
+			log.Printf("📤 Sent rejection message to client")
+		case <-time.After(1 * time.Second):
+			log.Printf("⚠️ Timeout sending rejection message")
+		}
+
+		time.Sleep(500 * time.Millisecond)
+
+		c.close()
+		log.Printf("🔌 Closed rejected client connection")
+	}()
+}
+
+// admit adds a connection to the room, or turns it away if a game is
+// already under way and it isn't resuming a seat in it or observing, or if
+// it claims a seated player's ID without showing it is theirs. Runs on the
+// room goroutine.
+func (r *Room) admit(client *Client) bool {
+	currentPhase := r.gameState.Phase
 
-	if currentPhase != "LOBBY" {
-		log.Printf("🚫 REJECTED join attempt - room %s in phase %s", client.RoomID, currentPhase)
+	if client.Observer {
+		return r.admitObserver(client)
+	}
+	// Player IDs are visible to everyone in the room, so anyone could
+	// otherwise connect as the host.
+	if r.players[client.PlayerID] != nil && !client.Proven && !client.Resumed {
+		log.Printf("🚫 REJECTED unproven connection as seated player %s in room %s (conn=%s)", client.PlayerID, client.RoomID, client.ConnID)
+		client.refuse(Message{
+			Type: "ERROR_ACCESS_DENIED",
+			Data: map[string]interface{}{
+				"reason":  "PLAYER_ID_IN_USE",
+				"message": "That player is already in the room - rejoin from the device you joined on",
+			},
+		})
+		return false
+	}
+	if client.Resumed && r.players[client.PlayerID] != nil {
+		r.resume(client)
+	} else if currentPhase != PhaseLobby {
+		log.Printf("🚫 REJECTED join attempt - room %s in phase %s (conn=%s)", client.RoomID, currentPhase, client.ConnID)
 
 		errorMsg := Message{
 			Type: "ERROR_ACCESS_DENIED",
@@ -63,132 +175,146 @@ func (h *Hub) handleRegister(client *Client) {
 				"phase":   string(currentPhase),
 			},
 		}
-		errData, _ := json.Marshal(errorMsg)
-
-		go func() {
-			select {
-			case client.send <- errData:
-				log.Printf("📤 Sent rejection message to client")
-			case <-time.After(1 * time.Second):
-				log.Printf("⚠️ Timeout sending rejection message")
-			}
-
-			time.Sleep(500 * time.Millisecond)
-
-			client.conn.Close()
-			log.Printf("🔌 Closed rejected client connection")
-		}()
-
-		return
+		client.refuse(errorMsg)
+		return false
 	}
 
-	room.mu.Lock()
-	room.clients[client] = true
-	clientCount := len(room.clients)
-	room.mu.Unlock()
+	r.clients[client] = true
 
-	log.Printf("📥 Client joined room %s (total: %d clients)", client.RoomID, clientCount)
+	log.Printf("📥 Client joined room %s (conn=%s, total: %d clients)", client.RoomID, client.ConnID, len(r.clients))
+	return true
 }
 
 func (h *Hub) handleDisconnect(client *Client) {
+	h.presence.remove(client)
+	if client.Authenticated && !h.presence.anyOnline([]string{client.PlayerID}) {
+		// Nobody waits in the queue for a player who isn't there.
+		go h.dropTickets(h.ticketKey(client.PlayerID))
+	}
+
+	if client.relayed {
+		h.cluster.unrelay(client)
+		return
+	}
+
 	h.mu.Lock()
 	room, roomExists := h.rooms[client.RoomID]
 	h.mu.Unlock()
 
 	if !roomExists {
-		log.Printf("⚠️ Client disconnected from non-existent room %s", client.RoomID)
-		select {
-		case <-client.send:
-		default:
-			close(client.send)
-		}
+		log.Printf("⚠️ Client disconnected from non-existent room %s (conn=%s)", client.RoomID, client.ConnID)
+		client.close()
 		return
 	}
 
-	room.mu.Lock()
+	empty := false
+	if !room.do(func() { empty = room.leave(client) }) {
+		client.close()
+		return
+	}
 
-	player, playerExists := room.players[client.PlayerID]
-	if !playerExists {
-		delete(room.clients, client)
-		room.mu.Unlock()
+	if empty {
+		h.closeRoom(room, nil)
+		log.Printf("🧹 Room %s cleaned up (empty)", client.RoomID)
+	}
+}
 
-		select {
-		case <-client.send:
-		default:
-			close(client.send)
-		}
+// closeRoom takes the room out of the hub and shuts it down; see
+// Room.close. It doesn't wait for the room to finish.
+func (h *Hub) closeRoom(room *Room, notice *Message) {
+	h.mu.Lock()
+	owned := h.rooms[room.ID] == room
+	if owned {
+		delete(h.rooms, room.ID)
+	}
+	h.mu.Unlock()
 
-		log.Printf("⚠️ Disconnected client had no player record")
+	room.post(func() { room.close(notice) })
+	if !owned {
 		return
 	}
 
+	database.RemoveLobbyListing(room.ID)
+	if h.cluster != nil {
+		h.cluster.release(room.ID)
+	}
+}
+
+// leave removes a connection. Mid-game the player's seat is held for them
+// to reconnect to; otherwise they are removed straight away. It reports
+// whether the room is now empty. Runs on the room goroutine.
+func (r *Room) leave(client *Client) bool {
+	client.close()
+
+	if r.dropObserver(client) {
+		return r.emptied()
+	}
+	// Connections that were turned away, or replaced by a reconnect, take
+	// nothing with them.
+	if !r.clients[client] {
+		log.Printf("🔁 Closed connection that was not in the room (conn=%s)", client.ConnID)
+		return r.emptied()
+	}
+	delete(r.clients, client)
+	r.stopTyping(client.PlayerID)
+
+	player, playerExists := r.players[client.PlayerID]
+	if !playerExists {
+		log.Printf("⚠️ Disconnected client had no player record (conn=%s)", client.ConnID)
+		return r.emptied()
+	}
+
 	playerName := player.Username
 	playerID := client.PlayerID
-	wasHost := player.IsHost
-	currentPhase := room.gameState.Phase
-	wasTestRunner := room.testRunning && room.testRunner == playerID
+	currentPhase := r.gameState.Phase
+	wasTestRunner := r.testRunning && r.testRunner == playerID
 
-	log.Printf("💀 Player disconnecting: %s (ID: %s, Phase: %s, Host: %v, TestRunner: %v)",
-		playerName, playerID, currentPhase, wasHost, wasTestRunner)
+	log.Printf("💀 Player disconnecting: %s (ID: %s, conn=%s, Phase: %s, Host: %v, TestRunner: %v)",
+		playerName, playerID, client.ConnID, currentPhase, player.IsHost, wasTestRunner)
 
-	delete(room.clients, client)
-	delete(room.players, playerID)
+	if wasTestRunner {
+		r.cancelTests(playerName + " disconnected during test execution")
+		log.Printf("⚠️ Test runner %s disconnected, unlocking room", playerName)
+	}
 
-	select {
-	case <-client.send:
+	switch currentPhase {
+	case PhaseRoleReveal, PhaseTask1, PhaseTask2, PhaseTask3, PhaseDiscussion:
+		r.holdSeat(player)
 	default:
-		close(client.send)
+		r.removePlayer(player)
 	}
+	return r.emptied()
+}
 
-	if wasTestRunner {
-		room.testRunning = false
-		room.testRunner = ""
-		room.testRunnerName = ""
-		room.codeSnapshot = ""
+// removePlayer takes a player out of the room, handing off whatever they
+// held: the host seat, or the game if they were the last of a side. Runs
+// on the room goroutine.
+func (r *Room) removePlayer(player *Player) {
+	playerName := player.Username
+	playerID := player.ID
+	wasHost := player.IsHost
+	currentPhase := r.gameState.Phase
 
-		cancelMsg := Message{
-			Type: "TEST_CANCELLED",
-			Data: map[string]interface{}{
-				"reason": playerName + " disconnected during test execution",
-			},
-		}
-		msgData, _ := json.Marshal(cancelMsg)
-		room.broadcast <- msgData
+	delete(r.players, playerID)
 
-		log.Printf("⚠️ Test runner %s disconnected, unlocking room", playerName)
-	}
+	r.audit("LEAVE", playerID, playerName, map[string]interface{}{"phase": string(currentPhase)})
+	r.logEvent(gameEventLeave, playerID, playerName, map[string]interface{}{"phase": string(currentPhase)})
 
 	switch currentPhase {
 	case "LOBBY":
 		log.Printf("📋 [LOBBY] Player %s left lobby", playerName)
 
-		disconnectMsg := Message{
-			Type: "CHAT",
-			Data: map[string]interface{}{
-				"username": "System",
-				"text":     playerName + " left the lobby",
-				"system":   true,
-			},
-		}
-		msgData, _ := json.Marshal(disconnectMsg)
-		room.broadcast <- msgData
+		r.emitSystemChat("system.left_lobby", map[string]interface{}{"name": playerName})
 
 	case "ROLE_REVEAL", "TASK_1", "TASK_2", "TASK_3", "DISCUSSION":
 		log.Printf("☠️ [IN-GAME] Player %s SELF-KILLED (disconnected)", playerName)
 
 		player.IsEliminated = true
 		player.IsAlive = false
+		r.eliminatedAt[playerID] = int(time.Since(r.gameState.GameStartTime).Seconds())
+		r.logEvent(gameEventEliminated, playerID, playerName, map[string]interface{}{"reason": "DISCONNECTED"})
 
-		gameLogMsg := Message{
-			Type: "CHAT",
-			Data: map[string]interface{}{
-				"username": "System",
-				"text":     "⚠️ COMMUNICATION LOST: " + playerName + " has disconnected",
-				"system":   true,
-			},
-		}
-		msgData, _ := json.Marshal(gameLogMsg)
-		room.broadcast <- msgData
+		r.emitSystemChat("system.connection_lost", map[string]interface{}{"name": playerName})
 
 		elimMsg := Message{
 			Type: "PLAYER_ELIMINATED",
@@ -199,37 +325,30 @@ func (h *Hub) handleDisconnect(client *Client) {
 			},
 		}
 		elimData, _ := json.Marshal(elimMsg)
-		room.broadcast <- elimData
-
-		if playerID == room.gameState.ImposterID {
-			log.Printf("🎉 Impostor disconnected - Civilians win by default!")
-			room.mu.Unlock()
-			room.endGame("CIVILIAN_WIN_DISCONNECT")
+		r.emit(elimData)
+		r.emitEvent(webhooks.EventPlayerEliminated, map[string]interface{}{
+			"playerId": playerID,
+			"username": playerName,
+			"reason":   "DISCONNECTED",
+		})
+
+		if reason := r.winByNumbers("CIVILIAN_WIN_DISCONNECT"); reason != "" {
+			log.Printf("🏁 Disconnect decided the game - %s", reason)
+			r.endGame(reason)
 			return
 		}
-
-		civilianCount := 0
-		for _, p := range room.players {
-			if p.Role == "CIVILIAN" && !p.IsEliminated {
-				civilianCount++
-			}
-		}
-
-		if civilianCount == 0 {
-			log.Printf("💀 All civilians eliminated - Impostor wins!")
-			room.mu.Unlock()
-			room.endGame("IMPOSTER_WIN")
+		if r.checkTaskBar() {
 			return
 		}
 	}
 
-	if wasHost && len(room.players) > 0 {
+	if wasHost && len(r.players) > 0 {
 		log.Printf("Host migration required - old host %s disconnected", playerName)
 
 		var newHost *Player
 		var newHostID string
 
-		for id, p := range room.players {
+		for id, p := range r.players {
 			newHost = p
 			newHostID = id
 			break
@@ -239,44 +358,35 @@ func (h *Hub) handleDisconnect(client *Client) {
 			newHost.IsHost = true
 			log.Printf("New host assigned: %s (ID: %s)", newHost.Username, newHostID)
 
-			room.mu.Unlock()
-			room.broadcastPlayerList()
-			room.mu.Lock()
+			r.broadcastPlayerList()
 
 			hostMsg := Message{
 				Type: "NEW_HOST_ASSIGNED",
 				Data: map[string]interface{}{
 					"newHostID":   newHostID,
 					"newHostName": newHost.Username,
-					"canStart":    len(room.players) >= 3,
+					"canStart":    len(r.players) >= r.minPlayers(),
 				},
 			}
 			hostData, _ := json.Marshal(hostMsg)
-			room.broadcast <- hostData
+			r.emit(hostData)
 
-			chatMsg := Message{
-				Type: "CHAT",
-				Data: map[string]interface{}{
-					"username": "System",
-					"text":     "👑 " + newHost.Username + " is now the host",
-					"system":   true,
-				},
-			}
-			chatData, _ := json.Marshal(chatMsg)
-			room.broadcast <- chatData
+			r.emitSystemChat("system.new_host", map[string]interface{}{"name": newHost.Username})
 		}
 	}
 
-	room.mu.Unlock()
-
-	room.broadcastPlayerList()
+	r.broadcastPlayerList()
+	r.syncListing()
+}
 
-	h.mu.Lock()
-	if len(room.clients) == 0 {
-		delete(h.rooms, client.RoomID)
-		log.Printf("🧹 Room %s cleaned up (empty)", client.RoomID)
+// emptied closes the spectator feeds once the last player connection is
+// gone and reports whether that happened.
+func (r *Room) emptied() bool {
+	if len(r.clients) > 0 {
+		return false
 	}
-	h.mu.Unlock()
+	r.closeSpectators()
+	return true
 }
 
 func (h *Hub) getRoom(roomID string) *Room {
@@ -285,15 +395,32 @@ func (h *Hub) getRoom(roomID string) *Room {
 	return h.rooms[roomID]
 }
 
-func (h *Hub) handleChatMessage(roomID, playerID, username, text string) {
+func (h *Hub) allRooms() []*Room {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	rooms := make([]*Room, 0, len(h.rooms))
+	for _, room := range h.rooms {
+		rooms = append(rooms, room)
+	}
+	return rooms
+}
+
+// handleChatMessage records a chat line and sends it for translation. The
+// room holds the line meanwhile; see newChatLine.
+func (h *Hub) handleChatMessage(roomID string, line chatLine) {
 	room := h.getRoom(roomID)
 	if room == nil {
 		return
 	}
 
-	messageID := uuid.New().String()
+	room.audit("CHAT", line.PlayerID, line.Username, map[string]interface{}{
+		"messageId": line.ID,
+		"text":      line.Text,
+	})
+	room.post(func() { room.checkRoleLeak(line.PlayerID, line.Username, line.Text) })
 
-	database.AddToChatHistory(roomID, text)
+	database.AddToChatHistory(roomID, line.Text)
 
 	context, err := database.GetRoomChatHistory(roomID, 3)
 	if err != nil {
@@ -302,11 +429,11 @@ func (h *Hub) handleChatMessage(roomID, playerID, username, text string) {
 	}
 
 	go func() {
-		err := database.PublishChatMessage(messageID, text, username, roomID, playerID, context)
+		err := requestChatTranslation(line.ID, line.Text, line.Username, roomID, line.PlayerID, context)
 		if err != nil {
 			log.Printf("Failed to publish chat message for translation: %v", err)
 		}
 	}()
 
-	log.Printf("📤 Chat [%s]: %s: %s (sent for translation)", roomID, username, text)
+	log.Printf("📤 Chat [%s]: %s: %s (sent for translation)", roomID, line.Username, line.Text)
 }
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// In games with more than one impostor, the impostors can plot on
+// IMPOSTOR_CHAT. It goes to the living impostors, and to ghosts when the
+// host allows it, never to spectators, and isn't kept in the room's chat
+// history.
+
+// handleImpostorChat relays an impostor's line to their teammates,
+// returning why it can't when it can't. Runs on the room goroutine.
+func (r *Room) handleImpostorChat(playerID, text string) string {
+	player := r.players[playerID]
+	if player == nil || player.IsEliminated || player.Role != "IMPOSTER" {
+		return "Only impostors still in the game can use impostor chat"
+	}
+	switch r.gameState.Phase {
+	case PhaseLobby, PhaseEnd:
+		return "Impostor chat is only open during a game"
+	}
+	if len(r.gameState.ImposterIDs) < 2 {
+		return "Impostor chat needs more than one impostor"
+	}
+	if r.isMuted(playerID) {
+		return "You are muted"
+	}
+
+	messageID := uuid.New().String()
+	r.audit("IMPOSTOR_CHAT", player.ID, player.Username, map[string]interface{}{
+		"messageId": messageID,
+		"text":      text,
+	})
+
+	msg := Message{
+		Type: "IMPOSTOR_CHAT",
+		Data: map[string]interface{}{
+			"messageId": messageID,
+			"playerId":  player.ID,
+			"username":  player.Username,
+			"text":      text,
+			"timestamp": time.Now().UnixMilli(),
+		},
+	}
+	for c := range r.clients {
+		if r.hearsImpostors(c.PlayerID) {
+			c.sendMessage(msg)
+		}
+	}
+
+	log.Printf("🔪 Impostor chat [%s]: %s: %s", r.ID, player.Username, text)
+	return ""
+}
+
+// hearsImpostors reports whether playerID receives IMPOSTOR_CHAT.
+func (r *Room) hearsImpostors(playerID string) bool {
+	player := r.players[playerID]
+	if player == nil {
+		return false
+	}
+	if player.IsEliminated {
+		return r.gameState.Settings.GhostsHearImpostors
+	}
+	return r.gameState.isImpostor(playerID)
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"code-mafia-backend/database"
+)
+
+const janitorInterval = time.Minute
+
+func (c *Client) touch() {
+	c.lastSeen.Store(time.Now().UnixNano())
+}
+
+func (c *Client) idleFor(now time.Time) time.Duration {
+	return now.Sub(time.Unix(0, c.lastSeen.Load()))
+}
+
+// runJanitor sweeps up what disconnects alone don't: rooms and players
+// that have gone quiet, and Redis keys rooms left behind. It only acts
+// outside a game, where the clock ends things anyway and players may be
+// busy in the editor without saying a word.
+func (h *Hub) runJanitor(roomIdle, afk time.Duration) {
+	ticker := time.NewTicker(janitorInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-h.closing:
+			return
+		case <-ticker.C:
+			h.sweep(roomIdle, afk)
+		}
+	}
+}
+
+func (h *Hub) sweep(roomIdle, afk time.Duration) {
+	defer recoverPanic("sweep")
+
+	now := time.Now()
+	for _, room := range h.allRooms() {
+		room.post(func() { room.sweep(now, roomIdle, afk) })
+	}
+
+	if roomIdle > 0 {
+		h.expireStoredRooms(roomIdle)
+	}
+
+	pruned, err := database.PruneOrphanRoomKeys()
+	if err != nil {
+		log.Printf("Failed to prune orphaned room keys: %v", err)
+	}
+	if pruned > 0 {
+		log.Printf("🧹 Pruned %d orphaned room keys", pruned)
+	}
+}
+
+// sweep closes the room if nobody in it has sent anything for roomIdle,
+// and otherwise removes players silent for afk. Runs on the room goroutine.
+func (r *Room) sweep(now time.Time, roomIdle, afk time.Duration) {
+	if r.gameState.Phase != PhaseLobby && r.gameState.Phase != PhaseEnd {
+		return
+	}
+	if len(r.clients) == 0 {
+		return
+	}
+
+	quietest := make(map[string]time.Duration)
+	roomQuiet := true
+	for client := range r.clients {
+		idle := client.idleFor(now)
+		if prev, ok := quietest[client.PlayerID]; !ok || idle < prev {
+			quietest[client.PlayerID] = idle
+		}
+		if roomIdle <= 0 || idle < roomIdle {
+			roomQuiet = false
+		}
+	}
+
+	if roomQuiet {
+		log.Printf("🧹 Closing room %s, idle for %s", r.ID, roomIdle)
+		r.audit("IDLE_CLOSE", "", "", nil)
+		message := fmt.Sprintf("The room was closed after %d minutes without activity", int(roomIdle.Minutes()))
+		for _, player := range r.players {
+			r.kick(player, "IDLE", message)
+		}
+		database.DeleteRoom(r.ID)
+		return
+	}
+
+	if afk <= 0 {
+		return
+	}
+	for playerID, idle := range quietest {
+		player := r.players[playerID]
+		if player == nil || idle < afk {
+			continue
+		}
+		log.Printf("💤 Removing AFK player %s from room %s", player.Username, r.ID)
+		r.audit("AFK_KICK", playerID, player.Username, map[string]interface{}{"idleSeconds": int(idle.Seconds())})
+		r.kick(player, "AFK", fmt.Sprintf("You were removed after %d minutes without activity", int(afk.Minutes())))
+	}
+}
+
+// expireStoredRooms deletes rooms that exist only in Redis, such as a
+// lobby whose creator closed the tab before joining, once nothing has
+// saved them for idle.
+func (h *Hub) expireStoredRooms(idle time.Duration) {
+	rooms, err := database.GetActiveRooms()
+	if err != nil {
+		log.Printf("Failed to list stored rooms: %v", err)
+		return
+	}
+
+	for _, roomID := range rooms {
+		if h.getRoom(roomID) != nil {
+			continue
+		}
+		if h.cluster != nil {
+			if owner, err := database.RoomOwner(roomID); err != nil || owner != "" {
+				continue
+			}
+		}
+		if saved, ok := database.RoomLastSaved(roomID); !ok || time.Since(saved) < idle {
+			continue
+		}
+
+		if err := database.DeleteRoom(roomID); err != nil {
+			log.Printf("Failed to expire room %s: %v", roomID, err)
+			continue
+		}
+		database.RemoveLobbyListing(roomID)
+		log.Printf("🧹 Expired room %s, unused for %s", roomID, idle)
+	}
+}
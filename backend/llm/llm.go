@@ -0,0 +1,151 @@
+// Package llm asks a hosted language model for a short completion. It
+// speaks just enough of the OpenAI and Anthropic APIs for practice bots to
+// say something in a meeting.
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	ProviderOpenAI    = "openai"
+	ProviderAnthropic = "anthropic"
+)
+
+// Completer turns a system prompt and a user prompt into a reply.
+type Completer interface {
+	Complete(ctx context.Context, system, prompt string) (string, error)
+}
+
+// New returns a Completer for provider. A blank model or baseURL picks the
+// provider's default.
+func New(provider, apiKey, model, baseURL string) (Completer, error) {
+	client := &http.Client{Timeout: 20 * time.Second}
+
+	switch provider {
+	case ProviderOpenAI:
+		if model == "" {
+			model = "gpt-4o-mini"
+		}
+		if baseURL == "" {
+			baseURL = "https://api.openai.com"
+		}
+		return &openAI{apiKey: apiKey, model: model, baseURL: baseURL, client: client}, nil
+	case ProviderAnthropic:
+		if model == "" {
+			model = "claude-3-5-haiku-latest"
+		}
+		if baseURL == "" {
+			baseURL = "https://api.anthropic.com"
+		}
+		return &anthropic{apiKey: apiKey, model: model, baseURL: baseURL, client: client}, nil
+	}
+	return nil, fmt.Errorf("unknown LLM provider %q", provider)
+}
+
+// maxTokens keeps replies to a chat line or two.
+const maxTokens = 120
+
+type openAI struct {
+	apiKey  string
+	model   string
+	baseURL string
+	client  *http.Client
+}
+
+func (o *openAI) Complete(ctx context.Context, system, prompt string) (string, error) {
+	body := map[string]interface{}{
+		"model":      o.model,
+		"max_tokens": maxTokens,
+		"messages": []map[string]string{
+			{"role": "system", "content": system},
+			{"role": "user", "content": prompt},
+		},
+	}
+	var resp struct {
+		Choices []struct {
+			Message struct {
+				Content string `json:"content"`
+			} `json:"message"`
+		} `json:"choices"`
+	}
+	headers := map[string]string{"Authorization": "Bearer " + o.apiKey}
+	if err := post(ctx, o.client, o.baseURL+"/v1/chat/completions", headers, body, &resp); err != nil {
+		return "", err
+	}
+	if len(resp.Choices) == 0 {
+		return "", fmt.Errorf("openai: no choices in response")
+	}
+	return strings.TrimSpace(resp.Choices[0].Message.Content), nil
+}
+
+type anthropic struct {
+	apiKey  string
+	model   string
+	baseURL string
+	client  *http.Client
+}
+
+func (a *anthropic) Complete(ctx context.Context, system, prompt string) (string, error) {
+	body := map[string]interface{}{
+		"model":      a.model,
+		"max_tokens": maxTokens,
+		"system":     system,
+		"messages": []map[string]string{
+			{"role": "user", "content": prompt},
+		},
+	}
+	var resp struct {
+		Content []struct {
+			Type string `json:"type"`
+			Text string `json:"text"`
+		} `json:"content"`
+	}
+	headers := map[string]string{
+		"x-api-key":         a.apiKey,
+		"anthropic-version": "2023-06-01",
+	}
+	if err := post(ctx, a.client, a.baseURL+"/v1/messages", headers, body, &resp); err != nil {
+		return "", err
+	}
+	for _, block := range resp.Content {
+		if block.Type == "text" {
+			return strings.TrimSpace(block.Text), nil
+		}
+	}
+	return "", fmt.Errorf("anthropic: no text in response")
+}
+
+func post(ctx context.Context, client *http.Client, url string, headers map[string]string, body, out interface{}) error {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("%s returned %d: %s", url, resp.StatusCode, strings.TrimSpace(string(detail)))
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"sort"
+	"time"
+
+	"code-mafia-backend/database"
+)
+
+// syncListing keeps the room's entry in the public lobby browser current:
+// listed while it is a public lobby with someone in it, and gone the moment
+// a game starts. Runs on the room goroutine.
+func (r *Room) syncListing() {
+	if !r.gameState.Settings.Public || r.gameState.Phase != PhaseLobby || len(r.players) == 0 {
+		if r.listed {
+			if err := database.RemoveLobbyListing(r.ID); err != nil {
+				log.Printf("Failed to unlist room %s: %v", r.ID, err)
+				return
+			}
+			r.listed = false
+		}
+		return
+	}
+
+	settings, _ := json.Marshal(r.effectiveSettings())
+	listing := database.LobbyListing{
+		RoomID:    r.ID,
+		Players:   len(r.players),
+		Ranked:    r.ranked,
+		Settings:  settings,
+		UpdatedAt: time.Now(),
+	}
+	for _, p := range r.players {
+		if p.IsHost {
+			listing.Host = p.Username
+		}
+	}
+
+	if err := database.SaveLobbyListing(listing); err != nil {
+		log.Printf("Failed to list room %s: %v", r.ID, err)
+		return
+	}
+	r.listed = true
+}
+
+// handleListLobbies is the server browser: public lobbies waiting for
+// players, fullest first, optionally only those playing ?language=.
+func handleListLobbies(w http.ResponseWriter, r *http.Request) {
+	language := r.URL.Query().Get("language")
+	if err := oneOf("language", &language, taskLanguages); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"error": err.Error()})
+		return
+	}
+
+	lobbies, err := database.PublicLobbies()
+	if err != nil {
+		log.Printf("❌ Failed to list lobbies: %v", err)
+		writeJSON(w, http.StatusServiceUnavailable, map[string]interface{}{"error": "could not load lobbies, try again"})
+		return
+	}
+
+	if language != "" {
+		matching := lobbies[:0]
+		for _, l := range lobbies {
+			var settings Settings
+			if json.Unmarshal(l.Settings, &settings) == nil && settings.taskLanguage() == language {
+				matching = append(matching, l)
+			}
+		}
+		lobbies = matching
+	}
+
+	sort.Slice(lobbies, func(i, j int) bool {
+		if lobbies[i].Players != lobbies[j].Players {
+			return lobbies[i].Players > lobbies[j].Players
+		}
+		return lobbies[i].UpdatedAt.After(lobbies[j].UpdatedAt)
+	})
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{"lobbies": lobbies})
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"code-mafia-backend/config"
+)
+
+var logLevelRank = map[string]int{
+	"debug": 0,
+	"info":  1,
+	"warn":  2,
+	"error": 3,
+}
+
+// Runtime overrides set through /admin/logging. They win over LOG_LEVEL
+// until cleared, and survive config reloads.
+var (
+	logMu            sync.RWMutex
+	logLevelOverride string
+	debugRooms             = make(map[string]bool)
+	sampleEvery      int64 = 1
+	sampleCounters   sync.Map
+)
+
+func effectiveLogLevel() string {
+	logMu.RLock()
+	override := logLevelOverride
+	logMu.RUnlock()
+
+	if override != "" {
+		return override
+	}
+	return config.Current().LogLevel
+}
+
+func logEnabled(level string) bool {
+	return logLevelRank[level] >= logLevelRank[effectiveLogLevel()]
+}
+
+func roomDebugEnabled(roomID string) bool {
+	logMu.RLock()
+	defer logMu.RUnlock()
+	return debugRooms[roomID]
+}
+
+// debugf logs only when LOG_LEVEL=debug. Use it for per-broadcast and
+// per-tick noise that would drown everything else in production.
+func debugf(format string, args ...interface{}) {
+	if logEnabled("debug") {
+		log.Printf(format, args...)
+	}
+}
+
+// roomDebugf is debugf that can also be switched on for a single room.
+func roomDebugf(roomID, format string, args ...interface{}) {
+	if logEnabled("debug") || roomDebugEnabled(roomID) {
+		log.Printf(format, args...)
+	}
+}
+
+// sampledf is for the hottest paths (broadcasts): on top of
+// the room/level gate only one in every sampleEvery lines per category is
+// written.
+func sampledf(category, roomID, format string, args ...interface{}) {
+	if !logEnabled("debug") && !roomDebugEnabled(roomID) {
+		return
+	}
+
+	every := atomic.LoadInt64(&sampleEvery)
+	if every > 1 {
+		value, _ := sampleCounters.LoadOrStore(category+":"+roomID, new(int64))
+		if atomic.AddInt64(value.(*int64), 1)%every != 1 {
+			return
+		}
+	}
+
+	log.Printf(format, args...)
+}
+
+// forgetSampling drops the room's sampledf counters once it has closed.
+func forgetSampling(roomID string) {
+	suffix := ":" + roomID
+	sampleCounters.Range(func(key, _ interface{}) bool {
+		if strings.HasSuffix(key.(string), suffix) {
+			sampleCounters.Delete(key)
+		}
+		return true
+	})
+}
+
+type loggingState struct {
+	Level       string   `json:"level"`
+	Override    string   `json:"override,omitempty"`
+	SampleEvery int64    `json:"sampleEvery"`
+	DebugRooms  []string `json:"debugRooms"`
+}
+
+func currentLoggingState() loggingState {
+	logMu.RLock()
+	defer logMu.RUnlock()
+
+	rooms := make([]string, 0, len(debugRooms))
+	for id := range debugRooms {
+		rooms = append(rooms, id)
+	}
+
+	level := logLevelOverride
+	if level == "" {
+		level = config.Current().LogLevel
+	}
+
+	return loggingState{
+		Level:       level,
+		Override:    logLevelOverride,
+		SampleEvery: atomic.LoadInt64(&sampleEvery),
+		DebugRooms:  rooms,
+	}
+}
+
+func handleAdminGetLogging(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, currentLoggingState())
+}
+
+// handleAdminSetLogging accepts any subset of:
+//
+//	{"level": "debug"}                   override LOG_LEVEL ("" clears it)
+//	{"sampleEvery": 10}                  keep 1 in 10 high-volume lines
+//	{"room": "ABC123", "debug": true}    verbose logging for one room
+func handleAdminSetLogging(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Level       *string `json:"level"`
+		SampleEvery *int64  `json:"sampleEvery"`
+		Room        string  `json:"room"`
+		Debug       *bool   `json:"debug"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{
+			"error": "invalid JSON body",
+		})
+		return
+	}
+
+	if req.Level != nil {
+		if _, ok := logLevelRank[*req.Level]; !ok && *req.Level != "" {
+			writeJSON(w, http.StatusBadRequest, map[string]interface{}{
+				"error": "level must be one of debug, info, warn, error or empty",
+			})
+			return
+		}
+	}
+	if req.SampleEvery != nil && *req.SampleEvery < 1 {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{
+			"error": "sampleEvery must be at least 1",
+		})
+		return
+	}
+
+	logMu.Lock()
+	if req.Level != nil {
+		logLevelOverride = *req.Level
+	}
+	if req.Room != "" && req.Debug != nil {
+		if *req.Debug {
+			debugRooms[req.Room] = true
+		} else {
+			delete(debugRooms, req.Room)
+		}
+	}
+	logMu.Unlock()
+
+	if req.SampleEvery != nil {
+		atomic.StoreInt64(&sampleEvery, *req.SampleEvery)
+	}
+
+	state := currentLoggingState()
+	log.Printf("🔧 Logging updated - level: %s, sampleEvery: %d, debugRooms: %v",
+		state.Level, state.SampleEvery, state.DebugRooms)
+	writeJSON(w, http.StatusOK, state)
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"sync/atomic"
+	"testing"
+)
+
+func TestClosedRoomDropsSampleCounters(t *testing.T) {
+	previous := atomic.SwapInt64(&sampleEvery, 10)
+	defer atomic.StoreInt64(&sampleEvery, previous)
+
+	h := newHub()
+	room := openTestRoom(h, "LOG01")
+	logMu.Lock()
+	debugRooms[room.ID] = true
+	logMu.Unlock()
+	defer func() {
+		logMu.Lock()
+		delete(debugRooms, room.ID)
+		logMu.Unlock()
+	}()
+
+	room.do(func() { room.emit([]byte(`{"type":"PING"}`)) })
+	if _, ok := sampleCounters.Load("broadcast:" + room.ID); !ok {
+		t.Fatalf("broadcast wasn't counted")
+	}
+
+	h.closeRoom(room, nil)
+	waitStopped(t, room)
+	if _, ok := sampleCounters.Load("broadcast:" + room.ID); ok {
+		t.Fatalf("closed room's sample counter is still kept")
+	}
+}
@@ -8,7 +8,9 @@ import (
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
+	"code-mafia-backend/alerts"
 	"code-mafia-backend/config"
 	"code-mafia-backend/database"
 
@@ -18,13 +20,35 @@ import (
 
 func main() {
 
-	config.Load()
+	if len(os.Args) > 1 && os.Args[1] == "simulate" {
+		runSimulateCommand(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "wsschema" {
+		runSchemaCommand(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "rekey" {
+		runRekeyCommand(os.Args[2:])
+		return
+	}
+
+	if err := config.Load(); err != nil {
+		log.Fatalf("❌ %v", err)
+	}
 
+	alerts.Init(
+		config.AppConfig.AlertWebhookURL,
+		config.AppConfig.AlertSlackWebhookURL,
+		time.Duration(config.AppConfig.AlertCooldownMinutes)*time.Minute,
+	)
 
+	database.SetKeyPrefix(config.AppConfig.RedisKeyPrefix)
 	err := database.InitRedis(
 		config.AppConfig.RedisURL,
 		config.AppConfig.RedisPassword,
 		config.AppConfig.RedisDB,
+		config.AppConfig.AlertRedisDialsPerMin,
 	)
 	if err != nil {
 		log.Fatalf("Failed to connect to Redis: %v", err)
@@ -36,35 +60,43 @@ func main() {
 		config.AppConfig.SupabaseKey,
 	)
 
+	initAuth()
+	initNotifications()
+	initMailer()
+	initCodeRunner()
+	initPracticeBots()
+	initWordFilter()
+	initTranslator()
+	initSeasons()
+	initTaskLibrary()
+
 
 	hub := newHub()
+	if config.AppConfig.ClusterMode {
+		hub.cluster = newCluster(hub, config.AppConfig.InstanceID)
+		go hub.cluster.run()
+	}
 	go hub.run()
 
 	go hub.listenForTranslations()
+	go hub.runTranslationRetries()
+
+	go hub.runMatchmaker()
+	go hub.runTimerSync()
+
+	go hub.runJanitor(
+		time.Duration(config.AppConfig.RoomIdleMinutes)*time.Minute,
+		time.Duration(config.AppConfig.AFKKickMinutes)*time.Minute,
+	)
+
+	go hub.watchStuckRooms(time.Duration(config.AppConfig.AlertStuckPhaseMinutes) * time.Minute)
 
 	r := mux.NewRouter()
 
+	r.Use(requestLogger)
+	r.Use(recoverer)
 
-	r.Use(func(next http.Handler) http.Handler {
-		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			origin := r.Header.Get("Origin")
-			if origin == "" {
-				origin = "*"
-			}
-			
-			w.Header().Set("Access-Control-Allow-Origin", origin)
-			w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-			w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, Upgrade, Connection, Sec-WebSocket-Key, Sec-WebSocket-Version, Sec-WebSocket-Extensions")
-			w.Header().Set("Access-Control-Allow-Credentials", "true")
-			
-			if r.Method == "OPTIONS" {
-				w.WriteHeader(http.StatusOK)
-				return
-			}
-			
-			next.ServeHTTP(w, r)
-		})
-	})
+	r.Use(cors)
 
 
 	r.HandleFunc("/ws", func(w http.ResponseWriter, r *http.Request) {
@@ -72,6 +104,11 @@ func main() {
 		serveWs(hub, w, r)
 	})
 
+	r.HandleFunc("/ws/spectate", func(w http.ResponseWriter, r *http.Request) {
+		log.Printf("Spectator WebSocket connection attempt from %s", r.RemoteAddr)
+		serveSpectator(hub, w, r)
+	})
+
 	r.PathPrefix("/yjs").HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
         log.Printf("Yjs WebSocket connection attempt from %s for room: %s", 
             r.RemoteAddr, r.URL.Query().Get("room"))
@@ -79,17 +116,83 @@ func main() {
     }).Methods("GET")
 
 
-	r.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
-		w.WriteHeader(http.StatusOK)
-		w.Write([]byte("OK"))
-	})
-
-
-	r.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {
-		rooms, _ := database.GetActiveRooms()
-		w.Header().Set("Content-Type", "application/json")
-		w.Write([]byte(`{"active_rooms": ` + string(rune(len(rooms))) + `}`))
-	})
+	// REST endpoints live under /api with a per-IP budget; handlers that
+	// create rooms, queue tickets or invites add createRateLimiter on top.
+	api := r.PathPrefix("/api").Subrouter()
+	api.Use(apiRateLimiter.middleware)
+	api.Use(rejectBanned)
+	api.HandleFunc("/matches", handleListMatches).Methods("GET")
+	api.HandleFunc("/matches/{id}", handleGetMatch).Methods("GET")
+	api.HandleFunc("/players/{id}/stats", handlePlayerStats).Methods("GET")
+	api.HandleFunc("/players/{id}/matches", handlePlayerMatches).Methods("GET")
+	api.HandleFunc("/leaderboard", handleLeaderboard).Methods("GET")
+	api.HandleFunc("/account/claim-guest", handleClaimGuest).Methods("POST")
+	api.HandleFunc("/friends", withAccount(hub.handleListFriends)).Methods("GET")
+	api.HandleFunc("/friends/{id}", withAccount(hub.handleSendFriendRequest)).Methods("POST")
+	api.HandleFunc("/friends/{id}", withAccount(hub.handleRemoveFriend)).Methods("DELETE")
+	api.HandleFunc("/friends/{id}/{action:accept|decline}", withAccount(hub.handleRespondFriendRequest)).Methods("POST")
+	api.HandleFunc("/friends/{id}/invite", withAccount(hub.handleInviteFriend)).Methods("POST")
+	api.HandleFunc("/party", withAccount(hub.handleGetParty)).Methods("GET")
+	api.HandleFunc("/party", createRateLimiter.wrap(withAccount(hub.handleCreateParty))).Methods("POST")
+	api.HandleFunc("/party/leave", withAccount(hub.handleLeaveParty)).Methods("POST")
+	api.HandleFunc("/party/room", withAccount(hub.handlePartyRoom)).Methods("POST")
+	api.HandleFunc("/party/members/{userId}", withAccount(hub.handleKickPartyMember)).Methods("DELETE")
+	api.HandleFunc("/parties/{id}/join", withAccount(hub.handleJoinParty)).Methods("POST")
+	api.HandleFunc("/inventory", withAccount(handleGetInventory)).Methods("GET")
+	api.HandleFunc("/inventory/equipped", withAccount(hub.handleEquipCosmetics)).Methods("PUT")
+	api.HandleFunc("/notifications/vapid-key", handleGetVAPIDKey).Methods("GET")
+	api.HandleFunc("/notifications/devices", withAccount(handleRegisterDevice)).Methods("POST")
+	api.HandleFunc("/notifications/devices", withAccount(handleUnregisterDevice)).Methods("DELETE")
+	api.HandleFunc("/rooms", createRateLimiter.wrap(handleCreateRoom)).Methods("POST")
+	api.HandleFunc("/lobbies", handleListLobbies).Methods("GET")
+	api.HandleFunc("/matchmaking/queue", withAccount(hub.handleQueueStatus)).Methods("GET")
+	api.HandleFunc("/matchmaking/queue", createRateLimiter.wrap(withAccount(hub.handleJoinQueue))).Methods("POST")
+	api.HandleFunc("/matchmaking/queue", withAccount(hub.handleLeaveQueue)).Methods("DELETE")
+	api.HandleFunc("/rooms/{roomId}/invites/email", createRateLimiter.wrap(withAccount(hub.handleEmailInvite))).Methods("POST")
+	api.HandleFunc("/graphql", handleGraphQL).Methods("GET", "POST")
+	api.HandleFunc("/daily", handleGetDaily).Methods("GET")
+	api.HandleFunc("/quick-chat", handleGetQuickChat).Methods("GET")
+	api.HandleFunc("/daily/leaderboard", handleDailyLeaderboard).Methods("GET")
+	api.HandleFunc("/daily/players/{id}", handleDailyCompletion).Methods("GET")
+	api.HandleFunc("/seasons/current", handleGetCurrentSeason).Methods("GET")
+	api.HandleFunc("/seasons/{id}/leaderboard", handleSeasonLeaderboard).Methods("GET")
+	api.HandleFunc("/seasons/{id}/players/{playerId}", handleSeasonPlayerStats).Methods("GET")
+
+
+	r.HandleFunc("/admin/config/reload", requireAdmin(hub.handleAdminReload)).Methods("POST")
+	r.HandleFunc("/admin/seasons/refresh", requireAdmin(handleAdminRefreshSeasons)).Methods("POST")
+	r.HandleFunc("/admin/logging", requireAdmin(handleAdminGetLogging)).Methods("GET")
+	r.HandleFunc("/admin/logging", requireAdmin(handleAdminSetLogging)).Methods("PUT")
+	r.HandleFunc("/admin/simulate", requireAdmin(handleAdminSimulate)).Methods("POST")
+	r.HandleFunc("/admin/bans", requireAdmin(handleAdminListBans)).Methods("GET")
+	r.HandleFunc("/admin/bans", requireAdmin(handleAdminCreateBan)).Methods("POST")
+	r.HandleFunc("/admin/bans/{kind}/{value}", requireAdmin(handleAdminDeleteBan)).Methods("DELETE")
+	r.HandleFunc("/admin/webhooks", requireAdmin(handleAdminListWebhooks)).Methods("GET")
+	r.HandleFunc("/admin/webhooks", requireAdmin(handleAdminCreateWebhook)).Methods("POST")
+	r.HandleFunc("/admin/webhooks/{id}", requireAdmin(handleAdminDeleteWebhook)).Methods("DELETE")
+	r.HandleFunc("/admin/reports", requireAdmin(handleAdminListReports)).Methods("GET")
+	r.HandleFunc("/admin/reports/{id}", requireAdmin(handleAdminGetReport)).Methods("GET")
+	r.HandleFunc("/admin/reports/{id}/actions", requireAdmin(hub.handleAdminReportAction)).Methods("POST")
+	r.HandleFunc("/admin/rooms", requireAdmin(hub.handleAdminListRooms)).Methods("GET")
+	r.HandleFunc("/admin/rooms/{roomId}", requireAdmin(hub.handleAdminGetRoom)).Methods("GET")
+	r.HandleFunc("/admin/rooms/{roomId}/end", requireAdmin(hub.handleAdminEndRoom)).Methods("POST")
+	r.HandleFunc("/admin/rooms/{roomId}/events", requireAdmin(handleAdminRoomEvents)).Methods("GET")
+	r.HandleFunc("/admin/rooms/{roomId}/advance", requireAdmin(hub.handleAdminAdvanceRoom)).Methods("POST")
+	r.HandleFunc("/admin/rooms/{roomId}/players/{playerId}/kick", requireAdmin(hub.handleAdminKickPlayer)).Methods("POST")
+	r.HandleFunc("/admin/translations/dead", requireAdmin(handleAdminListDeadTranslations)).Methods("GET")
+	r.HandleFunc("/admin/translations/dead/{id}/requeue", requireAdmin(handleAdminRequeueTranslation)).Methods("POST")
+	r.HandleFunc("/admin/announcements", requireAdmin(hub.handleAdminAnnounce)).Methods("POST")
+
+
+	r.HandleFunc("/summary/{token}", apiRateLimiter.wrap(handleGetSummary)).Methods("GET")
+
+	// /health is the old name of /healthz.
+	r.HandleFunc("/health", handleHealthz)
+	r.HandleFunc("/healthz", handleHealthz)
+	r.HandleFunc("/readyz", hub.handleReadyz)
+
+
+	r.HandleFunc("/metrics", hub.handleMetrics)
 
 	port := config.AppConfig.Port
 
@@ -98,63 +201,100 @@ func main() {
 	log.Println("╚═══════════════════════════════════════════════╝")
 	log.Printf("  Game WebSocket: ws://localhost:%s/ws", port)
 	log.Printf("  Yjs WebSocket:  ws://localhost:%s/yjs", port)
-	log.Printf("  Health Check:   http://localhost:%s/health", port)
-	log.Printf("  Translation:  Enabled (sidecar mode)")
+	log.Printf("  Health Check:   http://localhost:%s/healthz", port)
+	log.Printf("  Readiness:      http://localhost:%s/readyz", port)
+	log.Printf("  Translation:    %s", translationMode())
 	log.Println("═══════════════════════════════════════════════")
 
 
+	go func() {
+		sighup := make(chan os.Signal, 1)
+		signal.Notify(sighup, syscall.SIGHUP)
+		for range sighup {
+			log.Println("SIGHUP received - reloading config")
+			hub.reloadConfig()
+		}
+	}()
+
+	server := &http.Server{Addr: ":" + port, Handler: r}
+
 	go func() {
 		sigint := make(chan os.Signal, 1)
 		signal.Notify(sigint, os.Interrupt, syscall.SIGTERM)
 		<-sigint
 
-		log.Println("Shutting down gracefully...")
-
+		gracefulShutdown(hub, server, time.Duration(config.AppConfig.ShutdownDrainSec)*time.Second)
 		os.Exit(0)
 	}()
 
-	log.Fatal(http.ListenAndServe(":"+port, r))
+	if err := server.ListenAndServe(); err != http.ErrServerClosed {
+		log.Fatal(err)
+	}
+	select {}
 }
 
 func (h *Hub) listenForTranslations() {
 	ctx := context.Background()
 	
 	// 🔥 Subscribe to BOTH channels
-	pubsub := database.RDB.Subscribe(ctx, "chat:translations", "task:translations")
+	chatResults, taskResults := database.ChatTranslationsChannel(), database.TaskTranslationsChannel()
+	channels := []string{chatResults, taskResults}
+	hintResults := database.Key(config.AppConfig.HintResultChannel)
+	if config.AppConfig.HintChannel != "" {
+		channels = append(channels, hintResults)
+	}
+	pubsub := database.RDB.Subscribe(ctx, channels...)
 	defer pubsub.Close()
 
+	h.mu.Lock()
+	h.translations = pubsub
+	h.mu.Unlock()
+
 	log.Println("🎧 Translation listeners started...")
-	log.Println("   - chat:translations")
-	log.Println("   - task:translations")
+	log.Println("   - " + chatResults)
+	log.Println("   - " + taskResults)
+	if config.AppConfig.HintChannel != "" {
+		log.Println("   - " + hintResults)
+	}
 
 	_, err := pubsub.Receive(ctx)
 	if err != nil {
 		log.Printf("Failed to subscribe to translations: %v", err)
+		alerts.Fire(alerts.KindTranslationDown, "subscribe", "translation subscription failed: %v", err)
 		return
 	}
 
 	ch := pubsub.Channel()
-	
+	h.setTranslationsListening(true)
+	defer h.setTranslationsListening(false)
+
 	for msg := range ch {
 		// 🔥 Route based on channel
-		if msg.Channel == "chat:translations" {
+		if msg.Channel == chatResults {
 			h.handleChatTranslation(msg.Payload)
-		} else if msg.Channel == "task:translations" {
+		} else if msg.Channel == taskResults {
 			h.handleTaskTranslation(msg.Payload)
+		} else if msg.Channel == hintResults {
+			h.handleHintResult(msg.Payload)
 		}
 	}
+
+	if h.isClosing() {
+		log.Println("🎧 Translation listeners stopped")
+		return
+	}
+	alerts.Fire(alerts.KindTranslationDown, "closed", "translation subscription channel closed - chat and task translations are no longer delivered")
 }
 
-// 🔥 Handle chat translations
+// handleChatTranslation hands a line's translations to its room, which
+// sends the line out. Only the translations are taken from the sidecar.
 func (h *Hub) handleChatTranslation(payload string) {
+	defer recoverPanic("handleChatTranslation")
+
 	var translation struct {
 		MessageID    string            `json:"messageId"`
-		Username     string            `json:"username"`
-		Text         string            `json:"text"`
-		Translations map[string]string `json:"translations"`
 		RoomID       string            `json:"roomId"`
-		PlayerID     string            `json:"playerId"`
-		Timestamp    int64             `json:"timestamp"`
+		Translations map[string]string `json:"translations"`
 		Error        string            `json:"error,omitempty"`
 	}
 
@@ -179,26 +319,18 @@ func (h *Hub) handleChatTranslation(payload string) {
 		return
 	}
 
-	chatMsg := Message{
-		Type: "CHAT",
-		Data: map[string]interface{}{
-			"messageId":    translation.MessageID,
-			"username":     translation.Username,
-			"text":         translation.Text,
-			"playerId":     translation.PlayerID,
-			"translations": translation.Translations,
-			"timestamp":    translation.Timestamp,
-			"system":       false,
-		},
+	if translation.Error != "" {
+		room.post(func() { room.chatTranslationFailed(translation.MessageID, translation.Error) })
+		return
 	}
-
-	msgData, _ := json.Marshal(chatMsg)
-	room.broadcast <- msgData
+	room.post(func() { room.deliverChat(translation.MessageID, translation.Translations) })
 	log.Printf("📤 Broadcasted chat message %s to room %s", translation.MessageID, translation.RoomID)
 }
 
 // 🔥 NEW: Handle task translations
 func (h *Hub) handleTaskTranslation(payload string) {
+	defer recoverPanic("handleTaskTranslation")
+
 	var translation struct {
 		TaskID       string            `json:"taskId"`
 		RoomID       string            `json:"roomId"`
@@ -216,11 +348,10 @@ func (h *Hub) handleTaskTranslation(payload string) {
 
 	if translation.Error != "" {
 		log.Printf("⚠️ Translation error for task %s.%s: %s", translation.TaskID, translation.Field, translation.Error)
-		return
+	} else {
+		log.Printf("✅ Received task translations for %s.%s", translation.TaskID, translation.Field)
 	}
 
-	log.Printf("✅ Received task translations for %s.%s", translation.TaskID, translation.Field)
-
 	h.mu.RLock()
 	room := h.rooms[translation.RoomID]
 	h.mu.RUnlock()
@@ -230,6 +361,15 @@ func (h *Hub) handleTaskTranslation(payload string) {
 		return
 	}
 
+	if translation.Error != "" {
+		room.post(func() {
+			room.taskTranslationFailed(translation.TaskID, translation.Field, translation.Error)
+		})
+		return
+	}
+
 	// Update task with translations
-	room.updateTaskTranslations(translation.TaskID, translation.Field, translation.Translations)
+	room.post(func() {
+		room.updateTaskTranslations(translation.TaskID, translation.Field, translation.Translations)
+	})
 }
\ No newline at end of file
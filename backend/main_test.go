@@ -0,0 +1,39 @@
+package main
+
+import (
+	"io"
+	"log"
+	"os"
+	"testing"
+
+	"code-mafia-backend/config"
+	"code-mafia-backend/database"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+// testRedis is the in-memory Redis the tests run against.
+var testRedis *miniredis.Miniredis
+
+// TestMain runs the package's tests against an in-memory Redis, with
+// development config and without Supabase.
+func TestMain(m *testing.M) {
+	os.Setenv("ENVIRONMENT", "development")
+	if err := config.Load(); err != nil {
+		log.Fatalf("config: %v", err)
+	}
+
+	var err error
+	testRedis, err = miniredis.Run()
+	if err != nil {
+		log.Fatalf("miniredis: %v", err)
+	}
+	if err := database.InitRedis(testRedis.Addr(), "", 0, 0); err != nil {
+		log.Fatalf("redis: %v", err)
+	}
+
+	log.SetOutput(io.Discard)
+	code := m.Run()
+	testRedis.Close()
+	os.Exit(code)
+}
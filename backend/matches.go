@@ -0,0 +1,218 @@
+package main
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"code-mafia-backend/database"
+
+	"github.com/gorilla/mux"
+)
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+// parsePage reads limit/offset query params, clamping limit to maxPageSize.
+func parsePage(r *http.Request) (int, int, error) {
+	limit, offset := defaultPageSize, 0
+
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			return 0, 0, errors.New("limit must be a positive integer")
+		}
+		if n > maxPageSize {
+			n = maxPageSize
+		}
+		limit = n
+	}
+	if v := r.URL.Query().Get("offset"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return 0, 0, errors.New("offset must be a non-negative integer")
+		}
+		offset = n
+	}
+
+	return limit, offset, nil
+}
+
+// parseTimeParam accepts either a full RFC3339 timestamp or a plain date.
+func parseTimeParam(r *http.Request, name string) (*time.Time, error) {
+	v := r.URL.Query().Get(name)
+	if v == "" {
+		return nil, nil
+	}
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return &t, nil
+	}
+	if t, err := time.Parse("2006-01-02", v); err == nil {
+		return &t, nil
+	}
+	return nil, errors.New(name + " must be RFC3339 or YYYY-MM-DD")
+}
+
+func writeStoreError(w http.ResponseWriter, err error) {
+	if errors.Is(err, database.ErrSupabaseNotConfigured) {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]interface{}{
+			"error": "match history is disabled on this server",
+		})
+		return
+	}
+	log.Printf("Match history query failed: %v", err)
+	writeJSON(w, http.StatusInternalServerError, map[string]interface{}{
+		"error": "failed to load match history",
+	})
+}
+
+func handleListMatches(w http.ResponseWriter, r *http.Request) {
+	limit, offset, err := parsePage(r)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"error": err.Error()})
+		return
+	}
+
+	from, err := parseTimeParam(r, "from")
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"error": err.Error()})
+		return
+	}
+	to, err := parseTimeParam(r, "to")
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"error": err.Error()})
+		return
+	}
+
+	outcome := r.URL.Query().Get("outcome")
+	if outcome != "" && outcome != "CIVILIAN" && outcome != "IMPOSTER" && outcome != "JESTER" {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{
+			"error": "outcome must be CIVILIAN, IMPOSTER or JESTER",
+		})
+		return
+	}
+
+	matches, total, err := database.ListMatches(database.MatchFilter{
+		PlayerID: r.URL.Query().Get("player"),
+		Outcome:  outcome,
+		From:     from,
+		To:       to,
+		Limit:    limit,
+		Offset:   offset,
+	})
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"matches": matches,
+		"total":   total,
+		"limit":   limit,
+		"offset":  offset,
+	})
+}
+
+func handleGetMatch(w http.ResponseWriter, r *http.Request) {
+	match, err := database.GetMatch(mux.Vars(r)["id"])
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	if match == nil {
+		writeJSON(w, http.StatusNotFound, map[string]interface{}{"error": "match not found"})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, match)
+}
+
+// handlePlayerMatches is a player's match history for their profile page,
+// with the role they played and whether they won.
+func handlePlayerMatches(w http.ResponseWriter, r *http.Request) {
+	limit, offset, err := parsePage(r)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"error": err.Error()})
+		return
+	}
+
+	matches, total, err := database.ListPlayerMatches(mux.Vars(r)["id"], limit, offset)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"matches": matches,
+		"total":   total,
+		"limit":   limit,
+		"offset":  offset,
+	})
+}
+
+// handleLeaderboard serves one of the boards computed from match history:
+// ?mode=overall (most wins), impostor (best impostor win rate) or fastest
+// (quickest three-stage civilian win), over ?period=all or week.
+func handleLeaderboard(w http.ResponseWriter, r *http.Request) {
+	mode := r.URL.Query().Get("mode")
+	switch mode {
+	case "":
+		mode = database.BoardOverall
+	case database.BoardOverall, database.BoardImpostor, database.BoardFastest:
+	default:
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{
+			"error": "mode must be overall, impostor or fastest",
+		})
+		return
+	}
+
+	period := r.URL.Query().Get("period")
+	switch period {
+	case "":
+		period = database.BoardAllTime
+	case database.BoardAllTime, database.BoardWeekly:
+	default:
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{
+			"error": "period must be all or week",
+		})
+		return
+	}
+
+	limit := 50
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxPageSize {
+			writeJSON(w, http.StatusBadRequest, map[string]interface{}{
+				"error": "limit must be between 1 and " + strconv.Itoa(maxPageSize),
+			})
+			return
+		}
+		limit = n
+	}
+
+	entries, err := database.GetBoard(mode, period, limit)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"mode":    mode,
+		"period":  period,
+		"entries": entries,
+	})
+}
+
+func handlePlayerStats(w http.ResponseWriter, r *http.Request) {
+	stats, err := database.GetPlayerStats(mux.Vars(r)["id"])
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, stats)
+}
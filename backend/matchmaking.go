@@ -0,0 +1,451 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"code-mafia-backend/auth"
+	"code-mafia-backend/database"
+	"code-mafia-backend/rating"
+)
+
+const (
+	matchMinPlayers = 5
+	matchMaxPlayers = 8
+
+	// Players are matched with others within mmrBand of their rating. The
+	// band widens by mmrBandGrowth for every mmrBandStep they have waited,
+	// up to mmrBandMax, so nobody waits forever for a perfect game.
+	mmrBand       = 150.0
+	mmrBandGrowth = 50.0
+	mmrBandStep   = 10 * time.Second
+	mmrBandMax    = 600.0
+
+	matchInterval = 2 * time.Second
+
+	// A ticket whose players go quiet for queueTicketTTL is dropped. Each
+	// instance touches the tickets of players connected to it every
+	// queueTouchEvery; players who queued over REST keep theirs by polling
+	// the queue status.
+	queueTicketTTL  = 30 * time.Second
+	queueTouchEvery = 10 * time.Second
+
+	// matchFoundTTL is how long a matched player can still look up their
+	// room after the push.
+	matchFoundTTL = 2 * time.Minute
+)
+
+var (
+	errQueueLanguage = fmt.Errorf("language must be one of %s", strings.Join(taskLanguages, ", "))
+	errNotQueued     = errors.New("you are not in the queue")
+)
+
+// queue puts a signed-in player in the quick-match queue with their current
+// MMR. A blank language means they'll play in any. A party is queued as one
+// ticket, by its leader, at its members' average MMR.
+func (h *Hub) queue(userID, username, language string) (database.QueueTicket, error) {
+	language = strings.ToLower(strings.TrimSpace(language))
+	if oneOf("Language", &language, taskLanguages) != nil {
+		return database.QueueTicket{}, errQueueLanguage
+	}
+
+	now := time.Now()
+	ticket := database.QueueTicket{
+		UserID:   userID,
+		Username: username,
+		Language: language,
+		QueuedAt: now,
+		SeenAt:   now,
+	}
+	if party, ok := h.parties.of(userID); ok {
+		if party.LeaderID != userID {
+			return database.QueueTicket{}, errNotLeader
+		}
+		ticket.PartyID = party.ID
+		ticket.Members = party.Members
+		// Members' own tickets would match them apart from the party.
+		h.dropTickets(party.Members...)
+	}
+	ticket.MMR = queueMMR(ticket.Players())
+
+	if err := database.Enqueue(ticket); err != nil {
+		return database.QueueTicket{}, err
+	}
+
+	log.Printf("🎯 %s queued for a match (players=%d, language=%q, mmr=%.0f)", ticket.Key(), len(ticket.Players()), language, ticket.MMR)
+	return ticket, nil
+}
+
+// queueMMR is the average MMR of the players, counting unrated ones at
+// rating.Initial.
+func queueMMR(userIDs []string) float64 {
+	mmrs, err := database.GetMMRs(userIDs)
+	if err != nil {
+		debugf("No MMR for %v, queueing at %.0f: %v", userIDs, rating.Initial, err)
+	}
+
+	total := 0.0
+	for _, id := range userIDs {
+		if m, ok := mmrs[id]; ok {
+			total += m.MMR
+		} else {
+			total += rating.Initial
+		}
+	}
+	return total / float64(len(userIDs))
+}
+
+// ticketKey is where the user's ticket is queued: their party's, if they
+// are in one.
+func (h *Hub) ticketKey(userID string) string {
+	if party, ok := h.parties.of(userID); ok {
+		return database.PartyTicketKey(party.ID)
+	}
+	return userID
+}
+
+// leaveQueue takes the user's ticket out of the queue. Any party member can
+// take the party out.
+func (h *Hub) leaveQueue(userID string) error {
+	left, err := database.Dequeue(h.ticketKey(userID))
+	if err != nil {
+		return err
+	}
+	if !left {
+		return errNotQueued
+	}
+
+	log.Printf("🎯 %s left the match queue", userID)
+	return nil
+}
+
+// dropTickets takes the tickets with the given keys out of the queue, for
+// players who went away or parties whose members changed.
+func (h *Hub) dropTickets(keys ...string) {
+	for _, key := range keys {
+		if left, err := database.Dequeue(key); err != nil {
+			log.Printf("Failed to dequeue %s: %v", key, err)
+		} else if left {
+			log.Printf("🎯 Dropped %s from the match queue", key)
+		}
+	}
+}
+
+type ticketWait struct {
+	database.QueueTicket
+}
+
+// size is how many players the ticket brings to a match.
+func (t ticketWait) size() int {
+	return len(t.Players())
+}
+
+// band is how far from the ticket's MMR it will accept a match right now.
+func (t ticketWait) band(now time.Time) float64 {
+	steps := math.Floor(float64(now.Sub(t.QueuedAt)) / float64(mmrBandStep))
+	return math.Min(mmrBand+steps*mmrBandGrowth, mmrBandMax)
+}
+
+func languagesCompatible(a, b string) bool {
+	return a == "" || b == "" || a == b
+}
+
+// formMatch gathers a game around the longest-waiting ticket: players who
+// can play in the same language and whose ratings are in each other's band,
+// closest rating first. Parties come whole or not at all. It returns nil
+// if there aren't enough players.
+func formMatch(anchor ticketWait, pool []ticketWait, now time.Time) []ticketWait {
+	language := anchor.Language
+
+	var candidates []ticketWait
+	for _, t := range pool {
+		if t.Key() == anchor.Key() || !languagesCompatible(language, t.Language) {
+			continue
+		}
+		gap := math.Abs(t.MMR - anchor.MMR)
+		if gap > anchor.band(now) || gap > t.band(now) {
+			continue
+		}
+		candidates = append(candidates, t)
+	}
+
+	sort.Slice(candidates, func(i, j int) bool {
+		return math.Abs(candidates[i].MMR-anchor.MMR) < math.Abs(candidates[j].MMR-anchor.MMR)
+	})
+
+	group := []ticketWait{anchor}
+	players := anchor.size()
+	for _, t := range candidates {
+		if players+t.size() > matchMaxPlayers || !languagesCompatible(language, t.Language) {
+			continue
+		}
+		if language == "" {
+			language = t.Language
+		}
+		group = append(group, t)
+		players += t.size()
+	}
+
+	if players < matchMinPlayers {
+		return nil
+	}
+	return group
+}
+
+func groupLanguage(group []ticketWait) string {
+	for _, t := range group {
+		if t.Language != "" {
+			return t.Language
+		}
+	}
+	return ""
+}
+
+// runMatchmaker forms games out of the queue until the server shuts down.
+// Every instance runs one; ClaimTickets makes sure each player is only
+// placed once.
+func (h *Hub) runMatchmaker() {
+	ticker := time.NewTicker(matchInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-h.closing:
+			return
+		case <-ticker.C:
+			h.matchQueued()
+		}
+	}
+}
+
+func (h *Hub) matchQueued() {
+	defer recoverPanic("matchQueued")
+
+	tickets, err := database.QueuedTickets(queueTicketTTL)
+	if err != nil {
+		log.Printf("Failed to load match queue: %v", err)
+		return
+	}
+
+	now := time.Now()
+	h.touchTickets(tickets, now)
+
+	players := 0
+	pool := make([]ticketWait, 0, len(tickets))
+	for _, t := range tickets {
+		pool = append(pool, ticketWait{t})
+		players += len(t.Players())
+	}
+	if players < matchMinPlayers {
+		return
+	}
+	sort.Slice(pool, func(i, j int) bool { return pool[i].QueuedAt.Before(pool[j].QueuedAt) })
+
+	matched := make(map[string]bool)
+	for _, anchor := range pool {
+		if matched[anchor.Key()] {
+			continue
+		}
+
+		remaining := make([]ticketWait, 0, len(pool))
+		for _, t := range pool {
+			if !matched[t.Key()] {
+				remaining = append(remaining, t)
+			}
+		}
+
+		group := formMatch(anchor, remaining, now)
+		if group == nil {
+			continue
+		}
+		if err := h.startMatch(group); err != nil {
+			if err != database.ErrTicketsTaken {
+				log.Printf("Failed to start match: %v", err)
+			}
+			return
+		}
+		for _, t := range group {
+			matched[t.Key()] = true
+		}
+	}
+}
+
+// touchTickets keeps alive the tickets of players connected to this
+// instance.
+func (h *Hub) touchTickets(tickets []database.QueueTicket, now time.Time) {
+	for _, t := range tickets {
+		if now.Sub(t.SeenAt) < queueTouchEvery || !h.presence.anyOnline(t.Players()) {
+			continue
+		}
+		if err := database.TouchTicket(t.Key(), now); err != nil {
+			log.Printf("Failed to keep %s queued: %v", t.Key(), err)
+		}
+	}
+}
+
+// startMatch takes the group out of the queue, creates their room and
+// tells each of them where to go.
+func (h *Hub) startMatch(group []ticketWait) error {
+	keys := make([]string, 0, len(group))
+	var players []string
+	for _, t := range group {
+		keys = append(keys, t.Key())
+		players = append(players, t.Players()...)
+	}
+	if err := database.ClaimTickets(keys); err != nil {
+		return err
+	}
+
+	language := groupLanguage(group)
+	roomID, err := createRoomWithSettings("", Settings{TaskLanguage: language})
+	if err != nil {
+		// Put them back so the next pass can try again.
+		for _, t := range group {
+			if err := database.Enqueue(t.QueueTicket); err != nil {
+				log.Printf("Failed to requeue %s: %v", t.Key(), err)
+			}
+		}
+		return err
+	}
+
+	msg := Message{
+		Type: "MATCH_FOUND",
+		Data: map[string]interface{}{
+			"roomId":   roomID,
+			"joinUrl":  roomLink(roomID),
+			"language": language,
+			"players":  len(players),
+		},
+	}
+	for _, id := range players {
+		if err := database.SaveMatchFound(id, roomID, matchFoundTTL); err != nil {
+			log.Printf("Failed to save match for %s: %v", id, err)
+		}
+		h.presence.send(id, msg)
+	}
+
+	log.Printf("🎯 Matched %d players into room %s (language=%q)", len(players), roomID, language)
+	return nil
+}
+
+func writeQueueError(w http.ResponseWriter, err error) {
+	switch err {
+	case errQueueLanguage:
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"error": err.Error()})
+	case errNotLeader:
+		writeJSON(w, http.StatusForbidden, map[string]interface{}{"error": err.Error()})
+	case errNotQueued:
+		writeJSON(w, http.StatusNotFound, map[string]interface{}{"error": err.Error()})
+	default:
+		log.Printf("❌ Matchmaking queue error: %v", err)
+		writeJSON(w, http.StatusServiceUnavailable, map[string]interface{}{"error": "matchmaking is unavailable, try again"})
+	}
+}
+
+func (h *Hub) handleJoinQueue(w http.ResponseWriter, r *http.Request, claims *auth.Claims) {
+	var req struct {
+		Language string `json:"language"`
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]interface{}{"error": "invalid JSON body"})
+			return
+		}
+	}
+
+	ticket, err := h.queue(claims.Subject, h.presence.nameOf(claims), req.Language)
+	if err != nil {
+		writeQueueError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, map[string]interface{}{"status": "queued", "ticket": ticket})
+}
+
+func (h *Hub) handleLeaveQueue(w http.ResponseWriter, r *http.Request, claims *auth.Claims) {
+	if err := h.leaveQueue(claims.Subject); err != nil {
+		writeQueueError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"status": "left"})
+}
+
+// handleQueueStatus lets a client that missed MATCH_FOUND, or is connected
+// to another instance, poll for its match. Polling keeps the ticket queued.
+func (h *Hub) handleQueueStatus(w http.ResponseWriter, r *http.Request, claims *auth.Claims) {
+	key := h.ticketKey(claims.Subject)
+	ticket, err := database.GetTicket(key)
+	if err != nil {
+		writeQueueError(w, err)
+		return
+	}
+	if ticket != nil {
+		if err := database.TouchTicket(key, time.Now()); err != nil {
+			log.Printf("Failed to keep %s queued: %v", key, err)
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{"status": "queued", "ticket": ticket})
+		return
+	}
+
+	roomID, err := database.LoadMatchFound(claims.Subject)
+	if err != nil {
+		writeQueueError(w, err)
+		return
+	}
+	if roomID != "" {
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"status":  "matched",
+			"roomId":  roomID,
+			"joinUrl": roomLink(roomID),
+		})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"status": "idle"})
+}
+
+// handleQueueMessage is QUEUE and LEAVE_QUEUE over the game socket.
+func (h *Hub) handleQueueMessage(c *Client, msgType, language string) {
+	if !c.Authenticated {
+		c.sendError("Sign in to use quick match")
+		return
+	}
+
+	if msgType == "LEAVE_QUEUE" {
+		if err := h.leaveQueue(c.PlayerID); err != nil {
+			c.sendError(err.Error())
+			return
+		}
+		c.sendMessage(Message{Type: "QUEUE_STATUS", Data: map[string]interface{}{"status": "left"}})
+		return
+	}
+
+	ticket, err := h.queue(c.PlayerID, c.Username, language)
+	if err != nil {
+		if err != errQueueLanguage && err != errNotLeader {
+			log.Printf("❌ Matchmaking queue error: %v", err)
+			err = errors.New("Matchmaking is unavailable, try again")
+		}
+		c.sendError(err.Error())
+		return
+	}
+	status := Message{
+		Type: "QUEUE_STATUS",
+		Data: map[string]interface{}{
+			"status":   "queued",
+			"language": ticket.Language,
+			"mmr":      ticket.MMR,
+		},
+	}
+	c.sendMessage(status)
+	for _, id := range ticket.Members {
+		if id != c.PlayerID {
+			h.presence.send(id, status)
+		}
+	}
+}
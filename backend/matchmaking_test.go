@@ -0,0 +1,101 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"code-mafia-backend/database"
+)
+
+func soloTicket(id string, mmr float64, queuedAt time.Time) ticketWait {
+	return ticketWait{database.QueueTicket{UserID: id, MMR: mmr, QueuedAt: queuedAt, SeenAt: queuedAt}}
+}
+
+func TestFormMatchKeepsPartiesWhole(t *testing.T) {
+	now := time.Now()
+	party := ticketWait{database.QueueTicket{
+		UserID:   "leader",
+		PartyID:  "P1",
+		Members:  []string{"leader", "m1", "m2", "m3"},
+		MMR:      1500,
+		QueuedAt: now,
+	}}
+	var pool []ticketWait
+	for i := 0; i < 5; i++ {
+		pool = append(pool, soloTicket(fmt.Sprintf("solo%d", i), 1500, now))
+	}
+
+	group := formMatch(party, append(pool, party), now)
+	players := 0
+	for _, t := range group {
+		players += t.size()
+	}
+	if group[0].Key() != party.Key() || players != matchMaxPlayers {
+		t.Fatalf("party group = %d tickets, %d players; want the party and %d players", len(group), players, matchMaxPlayers)
+	}
+
+	// Only one of two parties of four fits around three solo players; the
+	// other stays out whole rather than being split.
+	other := party
+	other.PartyID, other.UserID = "P2", "leader2"
+	other.Members = []string{"leader2", "n1", "n2", "n3"}
+	group = formMatch(pool[0], []ticketWait{pool[0], pool[1], pool[2], party, other}, now)
+	players = 0
+	for _, t := range group {
+		players += t.size()
+	}
+	if players != matchMaxPlayers-1 {
+		t.Fatalf("group around a solo player = %d players, want %d", players, matchMaxPlayers-1)
+	}
+}
+
+func TestQueuedTicketsDropsQuietTickets(t *testing.T) {
+	now := time.Now()
+	fresh := soloTicket("fresh", 1500, now).QueueTicket
+	stale := soloTicket("stale", 1500, now.Add(-2*queueTicketTTL)).QueueTicket
+	for _, ticket := range []database.QueueTicket{fresh, stale} {
+		if err := database.Enqueue(ticket); err != nil {
+			t.Fatal(err)
+		}
+	}
+	defer database.Dequeue("fresh")
+
+	tickets, err := database.QueuedTickets(queueTicketTTL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tickets) != 1 || tickets[0].UserID != "fresh" {
+		t.Fatalf("queued tickets = %+v, want only fresh", tickets)
+	}
+	if ticket, _ := database.GetTicket("stale"); ticket != nil {
+		t.Fatalf("stale ticket was left in the queue")
+	}
+}
+
+func TestDisconnectLeavesQueue(t *testing.T) {
+	h := newHub()
+	c := newTestClient("QUEUE1", "queued-player")
+	c.Authenticated = true
+	h.presence.add(c)
+	if _, err := h.queue(c.PlayerID, "Queued", ""); err != nil {
+		t.Fatal(err)
+	}
+
+	h.handleDisconnect(c)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		ticket, err := database.GetTicket(c.PlayerID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ticket == nil {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("ticket still queued after the player disconnected")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
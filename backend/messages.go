@@ -0,0 +1,129 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+
+	"code-mafia-backend/wsschema"
+)
+
+// protocolVersion is the version of the game protocol this server speaks.
+// Clients say which they speak with ?protocol= when they connect; raise
+// it when a change would break clients built against the old one, and
+// raise minProtocolVersion once those clients must not play at all.
+// Clients that don't say are taken to speak version 1.
+const (
+	protocolVersion    = 2
+	minProtocolVersion = 1
+)
+
+// clientProtocol is the protocol version a connecting client speaks.
+func clientProtocol(r *http.Request) int {
+	version, err := strconv.Atoi(r.URL.Query().Get("protocol"))
+	if err != nil || version < 1 {
+		return 1
+	}
+	return version
+}
+
+// inboundMessage is a message as a client sends it. Data is decoded into
+// the typed request for its type once the type is known.
+type inboundMessage struct {
+	Type string          `json:"type"`
+	Data json.RawMessage `json:"data"`
+}
+
+// The payloads clients send, one per message type or family of types.
+// wsschema describes the same fields; decodeRequest holds a payload to
+// that description before filling these in.
+type (
+	joinRequest struct {
+		Username string `json:"username"`
+		Language string `json:"language"`
+	}
+
+	textRequest struct {
+		Text string `json:"text"`
+	}
+
+	quickChatRequest struct {
+		PresetID string `json:"presetId"`
+	}
+
+	targetRequest struct {
+		TargetID string `json:"targetID"`
+	}
+
+	muteRequest struct {
+		TargetID string `json:"targetID"`
+		Muted    bool   `json:"muted"`
+	}
+
+	slowModeRequest struct {
+		Seconds int `json:"seconds"`
+	}
+
+	sabotageRequest struct {
+		Type string `json:"type"`
+	}
+
+	setRankedRequest struct {
+		Ranked bool `json:"ranked"`
+	}
+
+	spectatorDelayRequest struct {
+		Seconds int `json:"seconds"`
+	}
+
+	codeRequest struct {
+		Code string `json:"code"`
+	}
+
+	reportRequest struct {
+		TargetID string `json:"targetID"`
+		Reason   string `json:"reason"`
+		Details  string `json:"details"`
+	}
+
+	addWebhookRequest struct {
+		URL    string   `json:"url"`
+		Events []string `json:"events"`
+	}
+
+	removeWebhookRequest struct {
+		ID string `json:"id"`
+	}
+
+	queueRequest struct {
+		Language string `json:"language"`
+	}
+)
+
+// decodeRequest fills req in from the message's data, or tells the client
+// what is wrong with it and returns false.
+func (c *Client) decodeRequest(msg inboundMessage, req interface{}) bool {
+	err := wsschema.CheckPayload(msg.Type, msg.Data)
+	if err == nil && len(msg.Data) > 0 && string(msg.Data) != "null" {
+		if json.Unmarshal(msg.Data, req) != nil {
+			err = fmt.Errorf("data does not match the schema")
+		}
+	}
+	if err != nil {
+		incMetric("invalid_messages_total")
+		debugf("Invalid %s from %s: %v (conn=%s)", msg.Type, c.PlayerID, err, c.ConnID)
+		c.sendError(fmt.Sprintf("Invalid %s: %v", msg.Type, err))
+		return false
+	}
+	return true
+}
+
+// rejectUnknownMessage answers a message type the server doesn't handle,
+// which is usually a client newer or older than it.
+func (c *Client) rejectUnknownMessage(msgType string) {
+	incMetric("invalid_messages_total")
+	log.Printf("Unknown message type: %s (conn=%s)", msgType, c.ConnID)
+	c.sendError(fmt.Sprintf("Unknown message type %q - try refreshing the page", msgType))
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"runtime"
+	"sync"
+	"sync/atomic"
+
+	"code-mafia-backend/database"
+)
+
+// A deliberately tiny counter registry: /metrics serves a JSON snapshot,
+// which is all the dashboards and load tests need.
+var (
+	metricsMu sync.RWMutex
+	counters  = make(map[string]*int64)
+)
+
+func counter(name string) *int64 {
+	metricsMu.RLock()
+	c, ok := counters[name]
+	metricsMu.RUnlock()
+	if ok {
+		return c
+	}
+
+	metricsMu.Lock()
+	defer metricsMu.Unlock()
+	if c, ok := counters[name]; ok {
+		return c
+	}
+	c = new(int64)
+	counters[name] = c
+	return c
+}
+
+func incMetric(name string) {
+	atomic.AddInt64(counter(name), 1)
+}
+
+func addMetric(name string, delta int64) {
+	atomic.AddInt64(counter(name), delta)
+}
+
+func setMetric(name string, value int64) {
+	atomic.StoreInt64(counter(name), value)
+}
+
+func metricsSnapshot() map[string]int64 {
+	metricsMu.RLock()
+	defer metricsMu.RUnlock()
+
+	snapshot := make(map[string]int64, len(counters))
+	for name, c := range counters {
+		snapshot[name] = atomic.LoadInt64(c)
+	}
+	return snapshot
+}
+
+func (h *Hub) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	activeRooms, err := database.CountActiveRooms()
+	if err != nil {
+		log.Printf("Failed to count active rooms: %v", err)
+	}
+
+	h.mu.RLock()
+	localRooms := len(h.rooms)
+	h.mu.RUnlock()
+
+	// What the process is using, so load tests can see what a run cost.
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+
+	payload := map[string]interface{}{
+		"active_rooms": activeRooms,
+		"local_rooms":  localRooms,
+
+		"goroutines":       runtime.NumGoroutine(),
+		"heap_alloc_bytes": mem.HeapAlloc,
+		"sys_bytes":        mem.Sys,
+		"gc_cycles_total":  mem.NumGC,
+	}
+	for name, value := range metricsSnapshot() {
+		payload[name] = value
+	}
+
+	writeJSON(w, http.StatusOK, payload)
+}
@@ -0,0 +1,218 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"code-mafia-backend/alerts"
+	"code-mafia-backend/config"
+
+	"github.com/google/uuid"
+)
+
+type contextKey string
+
+const requestIDKey contextKey = "requestID"
+
+func requestIDFromContext(ctx context.Context) string {
+	if id, ok := ctx.Value(requestIDKey).(string); ok {
+		return id
+	}
+	return ""
+}
+
+// statusRecorder captures the response status and whether the connection
+// was hijacked for a WebSocket upgrade.
+type statusRecorder struct {
+	http.ResponseWriter
+	status   int
+	hijacked bool
+}
+
+func (s *statusRecorder) WriteHeader(code int) {
+	if s.status == 0 {
+		s.status = code
+	}
+	s.ResponseWriter.WriteHeader(code)
+}
+
+func (s *statusRecorder) Write(b []byte) (int, error) {
+	if s.status == 0 {
+		s.status = http.StatusOK
+	}
+	return s.ResponseWriter.Write(b)
+}
+
+func (s *statusRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := s.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("response writer does not support hijacking")
+	}
+	conn, rw, err := hijacker.Hijack()
+	if err == nil {
+		s.hijacked = true
+		s.status = http.StatusSwitchingProtocols
+	}
+	return conn, rw, err
+}
+
+// requestLogger tags every request with an ID (reusing X-Request-ID when a
+// proxy already set one) and logs one key=value line when it finishes. For
+// WebSockets the line is written once the upgrade succeeds or fails, and the
+// same ID is carried by the Client into room logs.
+func requestLogger(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+
+		requestID := r.Header.Get("X-Request-ID")
+		if requestID == "" {
+			requestID = uuid.New().String()
+		}
+		w.Header().Set("X-Request-ID", requestID)
+
+		rec := &statusRecorder{ResponseWriter: w}
+		ctx := context.WithValue(r.Context(), requestIDKey, requestID)
+
+		next.ServeHTTP(rec, r.WithContext(ctx))
+
+		status := rec.status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		if status >= 500 {
+			incMetric("http_errors_total")
+		}
+
+		upgrade := "-"
+		if r.Header.Get("Upgrade") != "" {
+			if rec.hijacked {
+				upgrade = "ok"
+			} else {
+				upgrade = "failed"
+			}
+		}
+
+		log.Printf("http request_id=%s method=%s path=%s origin=%q remote=%s status=%d upgrade=%s duration=%s",
+			requestID, r.Method, r.URL.Path, r.Header.Get("Origin"), r.RemoteAddr,
+			status, upgrade, time.Since(start).Round(time.Microsecond))
+	})
+}
+
+// recoverer turns a panicking handler into a 500 for that request only. It
+// must run inside requestLogger so the response carries the request ID.
+func recoverer(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			rec := recover()
+			if rec == nil {
+				return
+			}
+			if rec == http.ErrAbortHandler {
+				panic(rec)
+			}
+
+			requestID := requestIDFromContext(r.Context())
+			log.Printf("🔥 PANIC request_id=%s method=%s path=%s: %v\n%s",
+				requestID, r.Method, r.URL.Path, rec, debug.Stack())
+			incMetric("http_panics_total")
+			alerts.Fire(alerts.KindPanicRecovered, r.URL.Path, "panic in %s %s (request_id=%s): %v",
+				r.Method, r.URL.Path, requestID, rec)
+
+			// Nothing useful can be written once the connection was
+			// hijacked for a WebSocket or the response already started.
+			if sr, ok := w.(*statusRecorder); ok && (sr.hijacked || sr.status != 0) {
+				return
+			}
+
+			writeJSON(w, http.StatusInternalServerError, map[string]interface{}{
+				"error":     "internal_error",
+				"message":   "Something went wrong on our side",
+				"requestId": requestID,
+			})
+		}()
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// originAllowed reports whether a browser on origin may use the API and
+// open WebSockets, going by ALLOWED_ORIGINS: an exact origin, *.domain for
+// any subdomain of domain over http or https, or * for anything.
+func originAllowed(origin string) bool {
+	origin = strings.ToLower(strings.TrimRight(origin, "/"))
+	u, err := url.Parse(origin)
+	if err != nil || u.Host == "" {
+		return false
+	}
+
+	for _, allowed := range config.Current().AllowedOrigins {
+		allowed = strings.ToLower(strings.TrimRight(allowed, "/"))
+		switch {
+		case allowed == "*":
+			return true
+		case strings.HasPrefix(allowed, "*."):
+			if (u.Scheme == "http" || u.Scheme == "https") && strings.HasSuffix(u.Hostname(), allowed[1:]) {
+				return true
+			}
+		case allowed == origin:
+			return true
+		}
+	}
+	return false
+}
+
+// cors answers browsers from ALLOWED_ORIGINS. An allowed Origin is echoed
+// back rather than answered with *, since requests carry credentials.
+// Requests without an Origin aren't from a browser page and pass as they
+// are; other origins get no CORS headers, and their preflights a 403.
+func cors(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Origin")
+
+		origin := r.Header.Get("Origin")
+		if origin != "" && !originAllowed(origin) {
+			incMetric("cors_rejected_total")
+			if r.Method == http.MethodOptions {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if origin != "" {
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+			w.Header().Set("Access-Control-Allow-Credentials", "true")
+		}
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, Upgrade, Connection, Sec-WebSocket-Key, Sec-WebSocket-Version, Sec-WebSocket-Extensions")
+
+		if r.Method == http.MethodOptions {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// checkOrigin keeps other websites from opening a player's WebSockets.
+// Clients that send no Origin, like bots and the load tester, aren't
+// browsers and get through.
+func checkOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" || originAllowed(origin) {
+		return true
+	}
+	incMetric("ws_origin_rejected_total")
+	log.Printf("🚫 WebSocket from origin %q refused: not in ALLOWED_ORIGINS", origin)
+	return false
+}
@@ -0,0 +1,523 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"code-mafia-backend/database"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+const (
+	// A player can report each other player once per reportPairCooldown,
+	// and file at most reportsPerHour reports in all.
+	reportPairCooldown = 24 * time.Hour
+	reportsPerHour     = 10
+	maxReportDetails   = 500
+)
+
+var reportReasons = map[string]bool{
+	"HARASSMENT": true,
+	"CHEATING":   true,
+	"SPAM":       true,
+	"OFFENSIVE":  true,
+	"OTHER":      true,
+}
+
+// audit appends an event to the room's audit trail. It only touches Redis,
+// so it is safe to call from any goroutine.
+func (r *Room) audit(eventType, playerID, username string, details map[string]interface{}) {
+	err := database.AppendAudit(r.ID, database.AuditEntry{
+		Time:     time.Now(),
+		Type:     eventType,
+		PlayerID: playerID,
+		Username: username,
+		Details:  details,
+	})
+	if err != nil {
+		log.Printf("Failed to write audit entry for room %s: %v", r.ID, err)
+	}
+}
+
+// clientFor finds the live connection of a player. Runs on the room
+// goroutine.
+func (r *Room) clientFor(playerID string) *Client {
+	for client := range r.clients {
+		if client.PlayerID == playerID {
+			return client
+		}
+	}
+	return nil
+}
+
+// isMuted reports whether the host or a moderator has muted playerID.
+func (r *Room) isMuted(playerID string) bool {
+	if r.gameState.MutedIDs[playerID] {
+		return true
+	}
+	until, ok := r.mutedUntil[playerID]
+	return ok && time.Now().Before(until)
+}
+
+func (c *Client) sendMessage(msg Message) {
+	data, _ := json.Marshal(msg)
+	c.enqueue(data)
+}
+
+// kick hangs up on player, telling them why with an ERROR_ACCESS_DENIED
+// of the given reason, and removes them from the room without holding
+// their seat. Runs on the room goroutine.
+func (r *Room) kick(player *Player, reason, message string) {
+	for client := range r.clients {
+		if client.PlayerID != player.ID {
+			continue
+		}
+		delete(r.clients, client)
+		client.refuse(Message{
+			Type: "ERROR_ACCESS_DENIED",
+			Data: map[string]interface{}{
+				"reason":  reason,
+				"message": message,
+			},
+		})
+	}
+
+	if timer := r.reconnects[player.ID]; timer != nil {
+		timer.Stop()
+		delete(r.reconnects, player.ID)
+	}
+	if r.testRunning && r.testRunner == player.ID {
+		r.cancelTests(player.Username + " was removed during test execution")
+	}
+
+	r.removePlayer(player)
+}
+
+// removeByHost handles KICK_PLAYER and BAN_PLAYER. A ban also keeps the
+// player out of this room for good. Runs on the room goroutine.
+func (r *Room) removeByHost(host *Client, targetID string, ban bool) {
+	hostPlayer := r.players[host.PlayerID]
+	if hostPlayer == nil || !hostPlayer.IsHost {
+		host.sendError("Only host can remove players")
+		return
+	}
+	if targetID == host.PlayerID {
+		host.sendError("You can't remove yourself")
+		return
+	}
+	target := r.players[targetID]
+	if target == nil {
+		host.sendError("Player not found")
+		return
+	}
+	// Mid-game a removal can decide the game, which a host shouldn't be
+	// able to do to a ranked one.
+	if r.ranked && r.gameState.Phase != PhaseLobby && r.gameState.Phase != PhaseEnd {
+		host.sendError("Players can't be removed from a ranked game in progress")
+		return
+	}
+
+	action, reason, message := "HOST_KICK", "KICKED", "You were removed from the room by the host"
+	if ban {
+		action, reason, message = "HOST_BAN", "BANNED", "You were banned from this room by the host"
+
+		identities := []string{"user:" + targetID}
+		if client := r.clientFor(targetID); client != nil {
+			identities = roomBanIdentities(client)
+		}
+		if err := database.BanFromRoom(r.ID, identities...); err != nil {
+			log.Printf("❌ Failed to ban %s from room %s: %v", target.Username, r.ID, err)
+			host.sendError("Could not ban that player, try again")
+			return
+		}
+	}
+
+	log.Printf("👢 Host %s removed %s from room %s (ban: %v)", hostPlayer.Username, target.Username, r.ID, ban)
+	r.audit(action, targetID, target.Username, map[string]interface{}{"by": host.PlayerID})
+	r.kick(target, reason, message)
+}
+
+// muteByHost mutes or unmutes a player for as long as they are in the
+// room, reconnects included: their chat is turned away with an ERROR.
+// Runs on the room goroutine.
+func (r *Room) muteByHost(host *Client, targetID string, muted bool) {
+	hostPlayer := r.players[host.PlayerID]
+	if hostPlayer == nil || !hostPlayer.IsHost {
+		host.sendError("Only host can mute players")
+		return
+	}
+	if targetID == host.PlayerID {
+		host.sendError("You can't mute yourself")
+		return
+	}
+	target := r.players[targetID]
+	if target == nil {
+		host.sendError("Player not found")
+		return
+	}
+	if r.gameState.MutedIDs[targetID] == muted {
+		return
+	}
+
+	if r.gameState.MutedIDs == nil {
+		r.gameState.MutedIDs = make(map[string]bool)
+	}
+	if muted {
+		r.gameState.MutedIDs[targetID] = true
+	} else {
+		delete(r.gameState.MutedIDs, targetID)
+	}
+	target.Muted = muted
+	action, notice := "HOST_UNMUTE", "The host unmuted you"
+	if muted {
+		action, notice = "HOST_MUTE", "The host muted you - your chat won't be sent"
+		r.stopTyping(targetID)
+	}
+
+	log.Printf("🔇 Host %s set %s muted: %v in room %s", hostPlayer.Username, target.Username, muted, r.ID)
+	r.audit(action, targetID, target.Username, map[string]interface{}{"by": host.PlayerID})
+	r.saveToRedis()
+	r.broadcastPlayerList()
+
+	if client := r.clientFor(targetID); client != nil {
+		client.sendMessage(Message{
+			Type: "HOST_MUTED",
+			Data: map[string]interface{}{"muted": muted, "message": notice},
+		})
+	}
+}
+
+// maxSlowModeSec is the longest gap slow mode can put between a player's
+// lines.
+const maxSlowModeSec = 60
+
+// setSlowMode sets the gap each player must leave between chat lines in a
+// meeting. Runs on the room goroutine.
+func (r *Room) setSlowMode(host *Client, seconds int) {
+	hostPlayer := r.players[host.PlayerID]
+	if hostPlayer == nil || !hostPlayer.IsHost {
+		host.sendError("Only host can set slow mode")
+		return
+	}
+	if seconds < 0 || seconds > maxSlowModeSec {
+		host.sendError(fmt.Sprintf("Slow mode must be between 0 and %d seconds", maxSlowModeSec))
+		return
+	}
+
+	r.gameState.Settings.SlowModeSec = seconds
+	r.audit("SLOW_MODE", host.PlayerID, hostPlayer.Username, map[string]interface{}{"seconds": seconds})
+	r.saveToRedis()
+	r.broadcastSettings()
+}
+
+// slowModeWait is how much longer playerID must wait before their next
+// chat line, which is only ever non-zero in a meeting. Runs on the room
+// goroutine.
+func (r *Room) slowModeWait(playerID string) time.Duration {
+	gap := time.Duration(r.gameState.Settings.SlowModeSec) * time.Second
+	if gap == 0 || r.gameState.Phase != PhaseDiscussion {
+		return 0
+	}
+	last, ok := r.lastChat[playerID]
+	if !ok {
+		return 0
+	}
+	return time.Until(last.Add(gap))
+}
+
+// noteChat starts playerID's slow mode wait. Runs on the room goroutine.
+func (r *Room) noteChat(playerID string) {
+	if r.gameState.Phase == PhaseDiscussion {
+		r.lastChat[playerID] = time.Now()
+	}
+}
+
+// roomBanIdentities are what a room ban records: the player's ID and, for
+// guests, who can come back under a new ID at will, their IP too.
+func roomBanIdentities(client *Client) []string {
+	identities := []string{"user:" + client.PlayerID}
+	if !client.Authenticated && client.IP != "" {
+		identities = append(identities, "ip:"+client.IP)
+	}
+	return identities
+}
+
+// bannedFromRoom reports whether the host banned client from its room.
+// Redis errors fail open, as with server bans.
+func bannedFromRoom(client *Client) bool {
+	banned, err := database.BannedFromRoom(client.RoomID, roomBanIdentities(client)...)
+	if err != nil {
+		log.Printf("Room ban lookup failed for %s in room %s: %v", client.PlayerID, client.RoomID, err)
+		return false
+	}
+	return banned
+}
+
+func (r *Room) handleReport(reporterID, targetID, reason, details string) {
+	reporter := r.players[reporterID]
+	target := r.players[targetID]
+
+	if reporter == nil || target == nil || reporterID == targetID {
+		return
+	}
+	if !reportReasons[reason] {
+		reason = "OTHER"
+	}
+	if runes := []rune(details); len(runes) > maxReportDetails {
+		details = string(runes[:maxReportDetails])
+	}
+
+	ok, err := database.ReserveReport(reporterID, targetID, reportsPerHour, reportPairCooldown)
+	if err != nil {
+		log.Printf("Failed to check report quota: %v", err)
+		return
+	}
+	if !ok {
+		if client := r.clientFor(reporterID); client != nil {
+			client.sendError("You have already reported this player, or too many players recently")
+		}
+		return
+	}
+
+	report := database.Report{
+		ID:           uuid.New().String(),
+		RoomID:       r.ID,
+		ReporterID:   reporterID,
+		ReporterName: reporter.Username,
+		TargetID:     targetID,
+		TargetName:   target.Username,
+		Reason:       reason,
+		Details:      details,
+		Status:       database.ReportStatusOpen,
+		CreatedAt:    time.Now(),
+	}
+
+	if err := database.SaveReport(report); err != nil {
+		log.Printf("Failed to save report: %v", err)
+		return
+	}
+
+	r.audit("REPORT", reporterID, reporter.Username, map[string]interface{}{
+		"reportId": report.ID,
+		"targetId": targetID,
+		"reason":   reason,
+	})
+
+	log.Printf("🚩 %s reported %s in room %s (%s)", reporter.Username, target.Username, r.ID, reason)
+}
+
+func handleAdminListReports(w http.ResponseWriter, r *http.Request) {
+	status := r.URL.Query().Get("status")
+	if status == "" {
+		status = database.ReportStatusOpen
+	} else if status == "all" {
+		status = ""
+	}
+
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	if limit <= 0 || limit > 200 {
+		limit = 50
+	}
+
+	reports, err := database.ListReports(status, limit)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"reports": reports,
+	})
+}
+
+// handleAdminGetReport returns the report with the room's audit trail and
+// the chat transcript leading up to it.
+func handleAdminGetReport(w http.ResponseWriter, r *http.Request) {
+	report, err := database.GetReport(mux.Vars(r)["id"])
+	if err != nil {
+		writeJSON(w, http.StatusNotFound, map[string]interface{}{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	auditLog, err := database.GetAuditLog(report.RoomID, 200)
+	if err != nil {
+		log.Printf("Failed to load audit log for report %s: %v", report.ID, err)
+	}
+
+	transcript := make([]database.AuditEntry, 0)
+	for _, entry := range auditLog {
+		if entry.Type == "CHAT" {
+			transcript = append(transcript, entry)
+		}
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"report":     report,
+		"auditLog":   auditLog,
+		"transcript": transcript,
+	})
+}
+
+func (h *Hub) handleAdminReportAction(w http.ResponseWriter, r *http.Request) {
+	report, err := database.GetReport(mux.Vars(r)["id"])
+	if err != nil {
+		writeJSON(w, http.StatusNotFound, map[string]interface{}{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	var req struct {
+		Action          string `json:"action"`
+		Note            string `json:"note"`
+		DurationMinutes int    `json:"durationMinutes"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{
+			"error": "invalid JSON body",
+		})
+		return
+	}
+
+	duration := time.Duration(req.DurationMinutes) * time.Minute
+
+	switch req.Action {
+	case "warn":
+		h.warnPlayer(report.RoomID, report.TargetID, req.Note)
+	case "mute":
+		if duration <= 0 {
+			duration = 15 * time.Minute
+		}
+		h.mutePlayer(report.RoomID, report.TargetID, duration, req.Note)
+	case "ban":
+		ban := database.Ban{
+			Kind:      database.BanKindUser,
+			Value:     report.TargetID,
+			Reason:    req.Note,
+			CreatedBy: "moderation:" + report.ID,
+			CreatedAt: time.Now(),
+		}
+		if duration > 0 {
+			expiresAt := ban.CreatedAt.Add(duration)
+			ban.ExpiresAt = &expiresAt
+		}
+		if err := database.SaveBan(ban); err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]interface{}{
+				"error": err.Error(),
+			})
+			return
+		}
+		h.disconnectBanned(report.RoomID, report.TargetID, &ban)
+	case "dismiss":
+	default:
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{
+			"error": "action must be one of warn, mute, ban, dismiss",
+		})
+		return
+	}
+
+	now := time.Now()
+	report.Action = req.Action
+	report.ActionNote = req.Note
+	report.ResolvedAt = &now
+	report.Status = database.ReportStatusActioned
+	if req.Action == "dismiss" {
+		report.Status = database.ReportStatusDismissed
+	}
+
+	if err := database.SaveReport(*report); err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	database.AppendAudit(report.RoomID, database.AuditEntry{
+		Time:     now,
+		Type:     "MODERATION_" + req.Action,
+		PlayerID: report.TargetID,
+		Username: report.TargetName,
+		Details:  map[string]interface{}{"reportId": report.ID, "note": req.Note},
+	})
+
+	log.Printf("🛡️ Report %s resolved with %s", report.ID, req.Action)
+	writeJSON(w, http.StatusOK, report)
+}
+
+func (h *Hub) warnPlayer(roomID, playerID, note string) {
+	room := h.getRoom(roomID)
+	if room == nil {
+		return
+	}
+
+	var client *Client
+	room.do(func() { client = room.clientFor(playerID) })
+
+	if client != nil {
+		client.sendMessage(Message{
+			Type: "MODERATION_WARNING",
+			Data: map[string]interface{}{
+				"message": note,
+			},
+		})
+	}
+}
+
+func (h *Hub) mutePlayer(roomID, playerID string, duration time.Duration, note string) {
+	room := h.getRoom(roomID)
+	if room == nil {
+		return
+	}
+
+	until := time.Now().Add(duration)
+
+	var client *Client
+	room.do(func() {
+		room.mutedUntil[playerID] = until
+		room.stopTyping(playerID)
+		client = room.clientFor(playerID)
+	})
+
+	if client != nil {
+		client.sendMessage(Message{
+			Type: "MODERATION_MUTED",
+			Data: map[string]interface{}{
+				"message": note,
+				"until":   until,
+			},
+		})
+	}
+}
+
+// disconnectBanned tells a live player about their ban and drops the
+// connection; the normal disconnect path then removes them from the room.
+func (h *Hub) disconnectBanned(roomID, playerID string, ban *database.Ban) {
+	room := h.getRoom(roomID)
+	if room == nil {
+		return
+	}
+
+	var client *Client
+	room.do(func() { client = room.clientFor(playerID) })
+
+	if client == nil {
+		return
+	}
+
+	client.sendMessage(Message{Type: "ERROR_ACCESS_DENIED", Data: banPayload(ban)})
+
+	go func() {
+		time.Sleep(500 * time.Millisecond)
+		client.close()
+	}()
+}
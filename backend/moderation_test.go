@@ -0,0 +1,73 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"code-mafia-backend/database"
+)
+
+// reportsBy returns the stored reports reporterID filed.
+func reportsBy(t *testing.T, reporterID string) []database.Report {
+	t.Helper()
+	all, err := database.ListReports("", 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var mine []database.Report
+	for _, report := range all {
+		if report.ReporterID == reporterID {
+			mine = append(mine, report)
+		}
+	}
+	return mine
+}
+
+func TestReportsAreCapped(t *testing.T) {
+	h := newHub()
+	room := openTestRoom(h, "REPORT")
+	defer h.closeRoom(room, nil)
+	reporter := "player-" + room.ID
+
+	room.do(func() {
+		for i := 0; i <= reportsPerHour+1; i++ {
+			room.addPlayer(string(rune('a'+i))+"-target", "Target")
+		}
+		room.handleReport(reporter, "a-target", "SPAM", "")
+		room.handleReport(reporter, "a-target", "SPAM", "again")
+	})
+	if got := len(reportsBy(t, reporter)); got != 1 {
+		t.Fatalf("reports after reporting one player twice = %d, want 1", got)
+	}
+
+	room.do(func() {
+		for i := 1; i <= reportsPerHour+1; i++ {
+			room.handleReport(reporter, string(rune('a'+i))+"-target", "SPAM", "")
+		}
+	})
+	if got := len(reportsBy(t, reporter)); got != reportsPerHour {
+		t.Fatalf("reports after an hour's worth and more = %d, want %d", got, reportsPerHour)
+	}
+}
+
+func TestReportDetailsAreCutByRune(t *testing.T) {
+	h := newHub()
+	room := openTestRoom(h, "REPDET")
+	defer h.closeRoom(room, nil)
+	reporter := "player-" + room.ID
+
+	room.do(func() {
+		room.addPlayer("target", "Target")
+		room.handleReport(reporter, "target", "OTHER", strings.Repeat("é", maxReportDetails+10))
+	})
+
+	reports := reportsBy(t, reporter)
+	if len(reports) != 1 {
+		t.Fatalf("reports = %d, want 1", len(reports))
+	}
+	details := reports[0].Details
+	if !utf8.ValidString(details) || utf8.RuneCountInString(details) != maxReportDetails {
+		t.Fatalf("details = %d runes (valid UTF-8: %v), want %d", utf8.RuneCountInString(details), utf8.ValidString(details), maxReportDetails)
+	}
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"log"
+	"runtime/debug"
+	"time"
+
+	"code-mafia-backend/alerts"
+)
+
+// recoverPanic must be deferred directly. It keeps a single bad message from
+// taking the whole server down and notifies operators about it.
+func recoverPanic(scope string) {
+	if rec := recover(); rec != nil {
+		stack := debug.Stack()
+		log.Printf("🔥 PANIC recovered in %s: %v\n%s", scope, rec, stack)
+		incMetric("panics_recovered_total")
+		alerts.Fire(alerts.KindPanicRecovered, scope, "panic recovered in %s: %v", scope, rec)
+	}
+}
+
+type phaseObservation struct {
+	phase GamePhase
+	since time.Time
+}
+
+// watchStuckRooms periodically samples every room's phase and alerts when an
+// in-game phase hasn't changed for longer than threshold.
+func (h *Hub) watchStuckRooms(threshold time.Duration) {
+	if threshold <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(30 * time.Second)
+	defer ticker.Stop()
+
+	observed := make(map[string]phaseObservation)
+
+	for range ticker.C {
+		h.mu.RLock()
+		rooms := make(map[string]*Room, len(h.rooms))
+		for id, room := range h.rooms {
+			rooms[id] = room
+		}
+		h.mu.RUnlock()
+
+		for id := range observed {
+			if _, ok := rooms[id]; !ok {
+				delete(observed, id)
+			}
+		}
+
+		for id, room := range rooms {
+			phase := room.phase()
+
+			prev, ok := observed[id]
+			if !ok || prev.phase != phase {
+				observed[id] = phaseObservation{phase: phase, since: time.Now()}
+				continue
+			}
+
+			if phase == PhaseLobby || phase == PhaseEnd {
+				continue
+			}
+
+			if stuckFor := time.Since(prev.since); stuckFor > threshold {
+				alerts.Fire(alerts.KindRoomStuck, id+":"+string(phase),
+					"room %s stuck in phase %s for %s", id, phase, stuckFor.Round(time.Second))
+			}
+		}
+	}
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"os"
+	"time"
+
+	"code-mafia-backend/auth"
+	"code-mafia-backend/config"
+	"code-mafia-backend/database"
+	"code-mafia-backend/notify"
+)
+
+var webPushSender *notify.WebPushSender
+
+// initNotifications registers whichever push services are configured.
+// Failures only disable that platform; they never stop the server.
+func initNotifications() {
+	cfg := config.AppConfig
+
+	if cfg.FCMCredentialsFile != "" {
+		credentials, err := os.ReadFile(cfg.FCMCredentialsFile)
+		if err != nil {
+			log.Printf("FCM disabled - cannot read credentials: %v", err)
+		} else if sender, err := notify.NewFCMSender(credentials); err != nil {
+			log.Printf("FCM disabled: %v", err)
+		} else {
+			notify.Register(notify.PlatformFCM, sender)
+			log.Println("Push notifications enabled for FCM")
+		}
+	}
+
+	if cfg.VAPIDPublicKey != "" {
+		sender, err := notify.NewWebPushSender(cfg.VAPIDPublicKey, cfg.VAPIDPrivateKey, cfg.VAPIDSubject)
+		if err != nil {
+			log.Printf("Web Push disabled: %v", err)
+		} else {
+			webPushSender = sender
+			notify.Register(notify.PlatformWebPush, sender)
+			log.Println("Push notifications enabled for Web Push")
+		}
+	}
+}
+
+// notifyUsers pushes n to every registered device of the given accounts in
+// the background. Guests and users without devices are skipped.
+func notifyUsers(userIDs []string, n notify.Notification) {
+	if database.SupabaseClient == nil || (!notify.Enabled(notify.PlatformFCM) && !notify.Enabled(notify.PlatformWebPush)) {
+		return
+	}
+
+	go func() {
+		devices, err := database.ListPushDevices(userIDs)
+		if err != nil {
+			log.Printf("Failed to load push devices: %v", err)
+			return
+		}
+
+		for _, device := range devices {
+			ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+			err := notify.Send(ctx, device.Platform, device.Token, n)
+			cancel()
+
+			if err == notify.ErrDeviceGone {
+				database.DeletePushDevice("", device.Token)
+				continue
+			}
+			if err != nil {
+				log.Printf("Push to %s (%s) failed: %v", device.UserID, device.Platform, err)
+				continue
+			}
+			incMetric("push_notifications_sent_total")
+		}
+	}()
+}
+
+func handleGetVAPIDKey(w http.ResponseWriter, r *http.Request) {
+	if webPushSender == nil {
+		writeJSON(w, http.StatusNotFound, map[string]interface{}{"error": "web push is not enabled"})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"publicKey": webPushSender.PublicKey()})
+}
+
+// handleRegisterDevice stores an FCM registration token or, for Web Push,
+// the browser's subscription object.
+func handleRegisterDevice(w http.ResponseWriter, r *http.Request, claims *auth.Claims) {
+	var req struct {
+		Platform     string          `json:"platform"`
+		Token        string          `json:"token"`
+		Subscription json.RawMessage `json:"subscription"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"error": "invalid JSON body"})
+		return
+	}
+
+	token := req.Token
+	if req.Platform == notify.PlatformWebPush {
+		var sub notify.Subscription
+		if err := json.Unmarshal(req.Subscription, &sub); err != nil || sub.Endpoint == "" || sub.Keys.P256dh == "" || sub.Keys.Auth == "" {
+			writeJSON(w, http.StatusBadRequest, map[string]interface{}{"error": "subscription must include endpoint and keys"})
+			return
+		}
+		token = string(req.Subscription)
+	}
+
+	if !notify.Enabled(req.Platform) {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"error": "unsupported platform"})
+		return
+	}
+	if token == "" {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"error": "token is required"})
+		return
+	}
+
+	err := database.SavePushDevice(database.PushDevice{
+		UserID:    claims.Subject,
+		Platform:  req.Platform,
+		Token:     token,
+		CreatedAt: time.Now(),
+	})
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, map[string]interface{}{"status": "registered"})
+}
+
+func handleUnregisterDevice(w http.ResponseWriter, r *http.Request, claims *auth.Claims) {
+	var req struct {
+		Token string `json:"token"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Token == "" {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"error": "token is required"})
+		return
+	}
+
+	if err := database.DeletePushDevice(claims.Subject, req.Token); err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"status": "unregistered"})
+}
@@ -0,0 +1,202 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const fcmScope = "https://www.googleapis.com/auth/firebase.messaging"
+
+// FCMSender talks to the FCM HTTP v1 API using a service account.
+type FCMSender struct {
+	projectID   string
+	clientEmail string
+	tokenURI    string
+	key         *rsa.PrivateKey
+	client      *http.Client
+
+	mu          sync.Mutex
+	accessToken string
+	expiresAt   time.Time
+}
+
+// NewFCMSender reads a service account key file downloaded from the
+// Firebase console.
+func NewFCMSender(credentials []byte) (*FCMSender, error) {
+	var sa struct {
+		ProjectID   string `json:"project_id"`
+		ClientEmail string `json:"client_email"`
+		PrivateKey  string `json:"private_key"`
+		TokenURI    string `json:"token_uri"`
+	}
+	if err := json.Unmarshal(credentials, &sa); err != nil {
+		return nil, fmt.Errorf("invalid FCM credentials: %w", err)
+	}
+
+	block, _ := pem.Decode([]byte(sa.PrivateKey))
+	if block == nil {
+		return nil, fmt.Errorf("invalid FCM credentials: no private key")
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid FCM private key: %w", err)
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("FCM private key is not RSA")
+	}
+
+	if sa.TokenURI == "" {
+		sa.TokenURI = "https://oauth2.googleapis.com/token"
+	}
+
+	return &FCMSender{
+		projectID:   sa.ProjectID,
+		clientEmail: sa.ClientEmail,
+		tokenURI:    sa.TokenURI,
+		key:         key,
+		client:      &http.Client{Timeout: 10 * time.Second},
+	}, nil
+}
+
+func (s *FCMSender) Send(ctx context.Context, token string, n Notification) error {
+	accessToken, err := s.token(ctx)
+	if err != nil {
+		return err
+	}
+
+	message := map[string]interface{}{
+		"token": token,
+		"notification": map[string]string{
+			"title": n.Title,
+			"body":  n.Body,
+		},
+	}
+	if len(n.Data) > 0 {
+		message["data"] = n.Data
+	}
+	if n.URL != "" {
+		message["webpush"] = map[string]interface{}{
+			"fcm_options": map[string]string{"link": n.URL},
+		}
+	}
+
+	body, err := json.Marshal(map[string]interface{}{"message": message})
+	if err != nil {
+		return err
+	}
+
+	endpoint := fmt.Sprintf("https://fcm.googleapis.com/v1/projects/%s/messages:send", s.projectID)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("fcm send failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return ErrDeviceGone
+	case resp.StatusCode >= 300:
+		return fmt.Errorf("fcm send failed: status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// token returns a cached OAuth access token, exchanging a fresh signed
+// assertion shortly before the old one expires.
+func (s *FCMSender) token(ctx context.Context) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.accessToken != "" && time.Until(s.expiresAt) > time.Minute {
+		return s.accessToken, nil
+	}
+
+	assertion, err := s.assertion()
+	if err != nil {
+		return "", err
+	}
+
+	form := url.Values{
+		"grant_type": {"urn:ietf:params:oauth:grant-type:jwt-bearer"},
+		"assertion":  {assertion},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.tokenURI, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("fcm token exchange failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return "", fmt.Errorf("fcm token exchange failed: status %d", resp.StatusCode)
+	}
+
+	var result struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", fmt.Errorf("fcm token exchange failed: %w", err)
+	}
+
+	s.accessToken = result.AccessToken
+	s.expiresAt = time.Now().Add(time.Duration(result.ExpiresIn) * time.Second)
+	return s.accessToken, nil
+}
+
+func (s *FCMSender) assertion() (string, error) {
+	now := time.Now().Unix()
+	header := base64URL(mustJSON(map[string]string{"alg": "RS256", "typ": "JWT"}))
+	claims := base64URL(mustJSON(map[string]interface{}{
+		"iss":   s.clientEmail,
+		"scope": fcmScope,
+		"aud":   s.tokenURI,
+		"iat":   now,
+		"exp":   now + 3600,
+	}))
+
+	signingInput := header + "." + claims
+	digest := sha256.Sum256([]byte(signingInput))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, s.key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", fmt.Errorf("failed to sign fcm assertion: %w", err)
+	}
+
+	return signingInput + "." + base64URL(signature), nil
+}
+
+func base64URL(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func mustJSON(v interface{}) []byte {
+	b, _ := json.Marshal(v)
+	return b
+}
@@ -0,0 +1,49 @@
+// Package notify sends push notifications to players' registered devices
+// through Firebase Cloud Messaging or the Web Push protocol.
+package notify
+
+import (
+	"context"
+	"errors"
+)
+
+const (
+	PlatformFCM     = "fcm"
+	PlatformWebPush = "webpush"
+)
+
+// ErrDeviceGone means the push service no longer knows the device; its
+// token should be forgotten.
+var ErrDeviceGone = errors.New("device is no longer registered")
+
+type Notification struct {
+	Title string            `json:"title"`
+	Body  string            `json:"body"`
+	URL   string            `json:"url,omitempty"`
+	Data  map[string]string `json:"data,omitempty"`
+}
+
+type Sender interface {
+	Send(ctx context.Context, token string, n Notification) error
+}
+
+var senders = make(map[string]Sender)
+
+// Register makes a sender available for a platform. Platforms without a
+// sender are skipped silently.
+func Register(platform string, s Sender) {
+	senders[platform] = s
+}
+
+func Enabled(platform string) bool {
+	_, ok := senders[platform]
+	return ok
+}
+
+func Send(ctx context.Context, platform, token string, n Notification) error {
+	s, ok := senders[platform]
+	if !ok {
+		return nil
+	}
+	return s.Send(ctx, token, n)
+}
@@ -0,0 +1,223 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// WebPushSender delivers to browser push subscriptions, identifying this
+// server with VAPID and encrypting payloads as RFC 8291 requires.
+type WebPushSender struct {
+	publicKey  string
+	privateKey *ecdsa.PrivateKey
+	subject    string
+	client     *http.Client
+}
+
+// Subscription is the JSON a browser's PushManager.subscribe() returns;
+// it is stored verbatim as the device token.
+type Subscription struct {
+	Endpoint string `json:"endpoint"`
+	Keys     struct {
+		P256dh string `json:"p256dh"`
+		Auth   string `json:"auth"`
+	} `json:"keys"`
+}
+
+// NewWebPushSender takes the base64url VAPID key pair (as generated by
+// the web-push CLI) and a mailto: or https: contact subject.
+func NewWebPushSender(publicKey, privateKey, subject string) (*WebPushSender, error) {
+	d, err := decodeBase64(privateKey)
+	if err != nil || len(d) != 32 {
+		return nil, fmt.Errorf("invalid VAPID private key")
+	}
+	pub, err := decodeBase64(publicKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid VAPID public key")
+	}
+
+	x, y := elliptic.Unmarshal(elliptic.P256(), pub)
+	if x == nil {
+		return nil, fmt.Errorf("invalid VAPID public key")
+	}
+
+	return &WebPushSender{
+		publicKey: publicKey,
+		privateKey: &ecdsa.PrivateKey{
+			PublicKey: ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y},
+			D:         new(big.Int).SetBytes(d),
+		},
+		subject: subject,
+		client:  &http.Client{Timeout: 10 * time.Second},
+	}, nil
+}
+
+func (s *WebPushSender) PublicKey() string {
+	return s.publicKey
+}
+
+func (s *WebPushSender) Send(ctx context.Context, token string, n Notification) error {
+	var sub Subscription
+	if err := json.Unmarshal([]byte(token), &sub); err != nil || sub.Endpoint == "" {
+		return ErrDeviceGone
+	}
+
+	payload, err := json.Marshal(n)
+	if err != nil {
+		return err
+	}
+	body, err := encryptPayload(sub, payload)
+	if err != nil {
+		return ErrDeviceGone
+	}
+
+	authorization, err := s.vapidHeader(sub.Endpoint)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sub.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", authorization)
+	req.Header.Set("Content-Encoding", "aes128gcm")
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set("TTL", "86400")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("web push failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone:
+		return ErrDeviceGone
+	case resp.StatusCode >= 300:
+		return fmt.Errorf("web push failed: status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func (s *WebPushSender) vapidHeader(endpoint string) (string, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return "", err
+	}
+
+	header := base64URL(mustJSON(map[string]string{"typ": "JWT", "alg": "ES256"}))
+	claims := base64URL(mustJSON(map[string]interface{}{
+		"aud": u.Scheme + "://" + u.Host,
+		"exp": time.Now().Add(12 * time.Hour).Unix(),
+		"sub": s.subject,
+	}))
+
+	signingInput := header + "." + claims
+	digest := sha256.Sum256([]byte(signingInput))
+	r, sig, err := ecdsa.Sign(rand.Reader, s.privateKey, digest[:])
+	if err != nil {
+		return "", fmt.Errorf("failed to sign VAPID token: %w", err)
+	}
+
+	signature := make([]byte, 64)
+	r.FillBytes(signature[:32])
+	sig.FillBytes(signature[32:])
+
+	return fmt.Sprintf("vapid t=%s.%s, k=%s", signingInput, base64URL(signature), s.publicKey), nil
+}
+
+// encryptPayload implements the aes128gcm content coding from RFC 8188
+// with the Web Push key derivation from RFC 8291, as a single record.
+func encryptPayload(sub Subscription, payload []byte) ([]byte, error) {
+	uaPublicBytes, err := decodeBase64(sub.Keys.P256dh)
+	if err != nil {
+		return nil, err
+	}
+	authSecret, err := decodeBase64(sub.Keys.Auth)
+	if err != nil {
+		return nil, err
+	}
+
+	curve := ecdh.P256()
+	uaPublic, err := curve.NewPublicKey(uaPublicBytes)
+	if err != nil {
+		return nil, err
+	}
+	asPrivate, err := curve.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	asPublic := asPrivate.PublicKey().Bytes()
+
+	sharedSecret, err := asPrivate.ECDH(uaPublic)
+	if err != nil {
+		return nil, err
+	}
+
+	keyInfo := append([]byte("WebPush: info\x00"), uaPublicBytes...)
+	keyInfo = append(keyInfo, asPublic...)
+	ikm := hkdf(authSecret, sharedSecret, keyInfo, 32)
+
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	cek := hkdf(salt, ikm, []byte("Content-Encoding: aes128gcm\x00"), 16)
+	nonce := hkdf(salt, ikm, []byte("Content-Encoding: nonce\x00"), 12)
+
+	block, err := aes.NewCipher(cek)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	// 0x02 marks the last (and only) record.
+	ciphertext := gcm.Seal(nil, nonce, append(payload, 0x02), nil)
+
+	header := make([]byte, 16+4+1)
+	copy(header, salt)
+	binary.BigEndian.PutUint32(header[16:], 4096)
+	header[20] = byte(len(asPublic))
+
+	body := append(header, asPublic...)
+	return append(body, ciphertext...), nil
+}
+
+// hkdf is HKDF-SHA256 (RFC 5869) for outputs of at most one block.
+func hkdf(salt, ikm, info []byte, length int) []byte {
+	extract := hmac.New(sha256.New, salt)
+	extract.Write(ikm)
+	prk := extract.Sum(nil)
+
+	expand := hmac.New(sha256.New, prk)
+	expand.Write(info)
+	expand.Write([]byte{0x01})
+	return expand.Sum(nil)[:length]
+}
+
+// decodeBase64 accepts base64url with or without padding, which is how
+// browsers and key generators variously emit keys.
+func decodeBase64(s string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+}
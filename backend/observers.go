@@ -0,0 +1,122 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Observers connect to /ws with ?spectate=1, and can do so after the game
+// has started. Unlike the streamers' feed in spectate.go they watch live,
+// with the same redaction: roles are stripped from everything they get
+// until GAME_ENDED. They read the editor without writing to it and read
+// the room's chat, but can't vote, run tests or talk to the players: their
+// CHAT goes to the other observers as OBSERVER_CHAT.
+
+const observerName = "Spectator"
+
+// admitObserver adds an observer connection to the room and brings it up
+// to date. Runs on the room goroutine.
+func (r *Room) admitObserver(client *Client) bool {
+	if len(r.spectators)+len(r.observers) >= maxSpectatorsPerRoom {
+		client.refuse(Message{
+			Type: "ERROR_ACCESS_DENIED",
+			Data: map[string]interface{}{"reason": "SPECTATORS_FULL", "message": "This room has too many spectators"},
+		})
+		return false
+	}
+
+	r.observers[client] = true
+	incMetric("observer_connections_total")
+	log.Printf("👀 Observer joined room %s in phase %s (conn=%s, total: %d observers)", r.ID, r.gameState.Phase, client.ConnID, len(r.observers))
+
+	players, _ := json.Marshal(Message{Type: "PLAYER_LIST", Data: r.players})
+	state, _ := json.Marshal(Message{Type: "GAME_STATE", Data: r.buildGameStatePayload()})
+	client.enqueue(redactForSpectators(players))
+	client.enqueue(redactForSpectators(state))
+	client.sendMessage(r.settingsMessage())
+	r.sendChatHistory(client)
+	return true
+}
+
+// emitToObservers sends a room broadcast to the observers, without roles.
+// Runs on the room goroutine.
+func (r *Room) emitToObservers(message []byte) {
+	if len(r.observers) == 0 {
+		return
+	}
+	redacted := redactForSpectators(message)
+	for client := range r.observers {
+		client.enqueue(redacted)
+	}
+}
+
+// dropObserver lets an observer's connection go, reporting whether it was
+// one. Runs on the room goroutine.
+func (r *Room) dropObserver(client *Client) bool {
+	if !r.observers[client] {
+		return false
+	}
+	delete(r.observers, client)
+	log.Printf("👀 Observer left room %s (conn=%s)", r.ID, client.ConnID)
+	return true
+}
+
+// handleObserverMessage handles what an observer may send: a name, chat
+// for the other observers, and keepalives.
+func (c *Client) handleObserverMessage(room *Room, msg inboundMessage) {
+	switch msg.Type {
+	case "JOIN":
+		var req joinRequest
+		if !c.decodeRequest(msg, &req) {
+			return
+		}
+		username, reason := c.checkUsername(req.Username)
+		if reason != "" {
+			c.sendError(reason)
+			return
+		}
+		c.Username = username
+
+	case "CHAT":
+		var req textRequest
+		if !c.decodeRequest(msg, &req) {
+			return
+		}
+		if text, ok := c.chatText(req.Text); ok {
+			room.post(func() { room.handleObserverChat(c, text) })
+		}
+
+	case "PING":
+
+	default:
+		c.sendError("Spectators can only watch and talk to other spectators")
+	}
+}
+
+// handleObserverChat relays an observer's line to the other observers.
+// Runs on the room goroutine.
+func (r *Room) handleObserverChat(client *Client, text string) {
+	if !r.observers[client] {
+		return
+	}
+	name := client.Username
+	if name == "" {
+		name = observerName
+	}
+
+	msg := Message{
+		Type: "OBSERVER_CHAT",
+		Data: map[string]interface{}{
+			"messageId": uuid.New().String(),
+			"username":  name,
+			"text":      text,
+			"timestamp": time.Now().UnixMilli(),
+		},
+	}
+	for c := range r.observers {
+		c.sendMessage(msg)
+	}
+}
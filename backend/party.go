@@ -0,0 +1,315 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"code-mafia-backend/auth"
+	"code-mafia-backend/database"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+const maxPartySize = 4
+
+var (
+	errInParty       = errors.New("you are already in a party")
+	errNotInParty    = errors.New("you are not in a party")
+	errPartyNotFound = errors.New("party not found")
+	errPartyFull     = errors.New("party is full")
+	errNotLeader     = errors.New("only the party leader can do that")
+)
+
+type Party struct {
+	ID        string    `json:"id"`
+	LeaderID  string    `json:"leaderId"`
+	Members   []string  `json:"members"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+func (p *Party) has(userID string) bool {
+	for _, id := range p.Members {
+		if id == userID {
+			return true
+		}
+	}
+	return false
+}
+
+// parties holds the groups that queue and move between rooms together.
+// Like presence they live on this instance only; members are accounts.
+type parties struct {
+	mu       sync.Mutex
+	byID     map[string]*Party
+	byMember map[string]*Party
+}
+
+func newParties() *parties {
+	return &parties{
+		byID:     make(map[string]*Party),
+		byMember: make(map[string]*Party),
+	}
+}
+
+func (ps *parties) snapshot(p *Party) Party {
+	copied := *p
+	copied.Members = append([]string(nil), p.Members...)
+	return copied
+}
+
+func (ps *parties) of(userID string) (Party, bool) {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	p, ok := ps.byMember[userID]
+	if !ok {
+		return Party{}, false
+	}
+	return ps.snapshot(p), true
+}
+
+func (ps *parties) create(leaderID string) (Party, error) {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	if _, ok := ps.byMember[leaderID]; ok {
+		return Party{}, errInParty
+	}
+
+	p := &Party{
+		ID:        strings.ToUpper(uuid.New().String()[:8]),
+		LeaderID:  leaderID,
+		Members:   []string{leaderID},
+		CreatedAt: time.Now(),
+	}
+	ps.byID[p.ID] = p
+	ps.byMember[leaderID] = p
+	return ps.snapshot(p), nil
+}
+
+func (ps *parties) join(partyID, userID string) (Party, error) {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	if _, ok := ps.byMember[userID]; ok {
+		return Party{}, errInParty
+	}
+	p, ok := ps.byID[partyID]
+	if !ok {
+		return Party{}, errPartyNotFound
+	}
+	if len(p.Members) >= maxPartySize {
+		return Party{}, errPartyFull
+	}
+
+	p.Members = append(p.Members, userID)
+	ps.byMember[userID] = p
+	return ps.snapshot(p), nil
+}
+
+// leave removes userID from their party, handing leadership to the longest
+// standing member. It returns the party as it is afterwards; an empty
+// Members list means the party was disbanded.
+func (ps *parties) leave(userID string) (Party, error) {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	p, ok := ps.byMember[userID]
+	if !ok {
+		return Party{}, errNotInParty
+	}
+
+	delete(ps.byMember, userID)
+	for i, id := range p.Members {
+		if id == userID {
+			p.Members = append(p.Members[:i], p.Members[i+1:]...)
+			break
+		}
+	}
+
+	if len(p.Members) == 0 {
+		delete(ps.byID, p.ID)
+	} else if p.LeaderID == userID {
+		p.LeaderID = p.Members[0]
+	}
+	return ps.snapshot(p), nil
+}
+
+// broadcastParty pushes msg to every member's open connections.
+func (h *Hub) broadcastParty(party Party, msg Message) {
+	for _, member := range party.Members {
+		h.presence.send(member, msg)
+	}
+}
+
+func (h *Hub) partyUpdated(party Party) {
+	h.broadcastParty(party, Message{Type: "PARTY_UPDATE", Data: party})
+}
+
+// handlePartyChat relays a chat line to the sender's party, wherever the
+// members currently are.
+func (h *Hub) handlePartyChat(c *Client, text string) {
+	party, ok := h.parties.of(c.PlayerID)
+	if !ok {
+		c.sendError(errNotInParty.Error())
+		return
+	}
+
+	h.broadcastParty(party, Message{
+		Type: "PARTY_CHAT",
+		Data: map[string]interface{}{
+			"partyId":   party.ID,
+			"messageId": uuid.New().String(),
+			"playerID":  c.PlayerID,
+			"username":  c.Username,
+			"text":      text,
+			"timestamp": time.Now().UnixMilli(),
+		},
+	})
+}
+
+func writePartyError(w http.ResponseWriter, err error) {
+	status := http.StatusBadRequest
+	switch err {
+	case errPartyNotFound:
+		status = http.StatusNotFound
+	case errInParty, errPartyFull:
+		status = http.StatusConflict
+	case errNotLeader:
+		status = http.StatusForbidden
+	}
+	writeJSON(w, status, map[string]interface{}{"error": err.Error()})
+}
+
+func (h *Hub) handleGetParty(w http.ResponseWriter, r *http.Request, claims *auth.Claims) {
+	party, ok := h.parties.of(claims.Subject)
+	if !ok {
+		writeJSON(w, http.StatusOK, map[string]interface{}{"party": nil})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"party": party})
+}
+
+func (h *Hub) handleCreateParty(w http.ResponseWriter, r *http.Request, claims *auth.Claims) {
+	party, err := h.parties.create(claims.Subject)
+	if err != nil {
+		writePartyError(w, err)
+		return
+	}
+
+	log.Printf("🎉 Party %s created by %s", party.ID, claims.Subject)
+	writeJSON(w, http.StatusCreated, map[string]interface{}{"party": party})
+}
+
+func (h *Hub) handleJoinParty(w http.ResponseWriter, r *http.Request, claims *auth.Claims) {
+	party, err := h.parties.join(strings.ToUpper(mux.Vars(r)["id"]), claims.Subject)
+	if err != nil {
+		writePartyError(w, err)
+		return
+	}
+	// The queued party ticket doesn't carry the new member, and their own
+	// ticket would split them from the party.
+	h.dropTickets(database.PartyTicketKey(party.ID), claims.Subject)
+
+	h.partyUpdated(party)
+	writeJSON(w, http.StatusOK, map[string]interface{}{"party": party})
+}
+
+func (h *Hub) handleLeaveParty(w http.ResponseWriter, r *http.Request, claims *auth.Claims) {
+	party, err := h.parties.leave(claims.Subject)
+	if err != nil {
+		writePartyError(w, err)
+		return
+	}
+	h.dropTickets(database.PartyTicketKey(party.ID))
+
+	if len(party.Members) > 0 {
+		h.partyUpdated(party)
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"status": "left"})
+}
+
+func (h *Hub) handleKickPartyMember(w http.ResponseWriter, r *http.Request, claims *auth.Claims) {
+	party, ok := h.parties.of(claims.Subject)
+	if !ok {
+		writePartyError(w, errNotInParty)
+		return
+	}
+	if party.LeaderID != claims.Subject {
+		writePartyError(w, errNotLeader)
+		return
+	}
+
+	target := mux.Vars(r)["userId"]
+	if target == claims.Subject || !party.has(target) {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"error": "not a member of your party"})
+		return
+	}
+
+	updated, err := h.parties.leave(target)
+	if err != nil {
+		writePartyError(w, err)
+		return
+	}
+	h.dropTickets(database.PartyTicketKey(party.ID))
+
+	h.presence.send(target, Message{
+		Type: "PARTY_KICKED",
+		Data: map[string]interface{}{"partyId": party.ID},
+	})
+	h.partyUpdated(updated)
+	writeJSON(w, http.StatusOK, map[string]interface{}{"party": updated})
+}
+
+// handlePartyRoom is the leader sending the whole party into a lobby.
+// Members' clients follow the PARTY_JOIN_ROOM push, so the party lands in
+// the same room together.
+func (h *Hub) handlePartyRoom(w http.ResponseWriter, r *http.Request, claims *auth.Claims) {
+	party, ok := h.parties.of(claims.Subject)
+	if !ok {
+		writePartyError(w, errNotInParty)
+		return
+	}
+	if party.LeaderID != claims.Subject {
+		writePartyError(w, errNotLeader)
+		return
+	}
+
+	var req struct {
+		RoomID string `json:"roomId"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.RoomID == "" {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"error": "roomId is required"})
+		return
+	}
+
+	if !h.roomKnown(req.RoomID) {
+		writeJSON(w, http.StatusNotFound, map[string]interface{}{"error": "room not found"})
+		return
+	}
+
+	if room := h.getRoom(req.RoomID); room != nil {
+		phase := room.phase()
+		if phase != PhaseLobby {
+			writeJSON(w, http.StatusConflict, map[string]interface{}{"error": "game already started"})
+			return
+		}
+	}
+
+	h.broadcastParty(party, Message{
+		Type: "PARTY_JOIN_ROOM",
+		Data: map[string]interface{}{
+			"partyId": party.ID,
+			"roomId":  req.RoomID,
+		},
+	})
+
+	log.Printf("🎉 Party %s heading to room %s", party.ID, req.RoomID)
+	writeJSON(w, http.StatusOK, map[string]interface{}{"status": "sent", "roomId": req.RoomID})
+}
@@ -0,0 +1,221 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"math/rand"
+	"sort"
+	"strings"
+)
+
+// In a personal-tasks game (Settings.PersonalTasks) each player works on a
+// task of their own in their own editor instead of the crew sharing one per
+// stage. The game stays on stage 1, and the crew wins once the task bar is
+// full: every crewmate still aboard has passed their task. A crewmate voted
+// out or gone before finishing takes their task off the bar.
+
+// personalDocSep joins a room ID and a player ID into the name of that
+// player's own editor document.
+const personalDocSep = "-task-"
+
+// yjsDocRoom splits an editor document name into the room it belongs to
+// and, for a player's own document, whose it is. Shared documents are one
+// per stage, named <room>-stage<n>.
+func yjsDocRoom(doc string) (roomID, owner string) {
+	if roomID, owner, ok := strings.Cut(doc, personalDocSep); ok {
+		return roomID, owner
+	}
+	return strings.Split(doc, "-stage")[0], ""
+}
+
+// personalGame reports whether the game under way deals personal tasks.
+func (r *Room) personalGame() bool {
+	return len(r.gameState.Assignments) > 0
+}
+
+// assignPersonalTasks deals every player a task from pool, preferring what
+// the host asked for the same way the stage draw does, and spreading them
+// so two players only share a task once there are more players than
+// tasks. Impostors and the jester get one as cover, but theirs never counts.
+func (r *Room) assignPersonalTasks(pool []*Task) {
+	candidates := pool
+	prefer := r.gameState.Settings.taskPreferences()
+	for n := len(prefer); n > 0; n-- {
+		if preferred := tasksMatching(pool, prefer[:n]); len(preferred) > 0 {
+			candidates = preferred
+			break
+		}
+	}
+	if len(candidates) == 0 {
+		return
+	}
+
+	deck := make([]*Task, len(candidates))
+	copy(deck, candidates)
+	rand.Shuffle(len(deck), func(i, j int) {
+		deck[i], deck[j] = deck[j], deck[i]
+	})
+
+	ids := make([]string, 0, len(r.players))
+	for id := range r.players {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	rand.Shuffle(len(ids), func(i, j int) {
+		ids[i], ids[j] = ids[j], ids[i]
+	})
+
+	r.gameState.Assignments = make(map[string]string, len(ids))
+	r.gameState.TasksDone = make(map[string]bool)
+	r.assignedTasks = make(map[string]*Task, len(ids))
+	for i, id := range ids {
+		task := deck[i%len(deck)]
+		r.gameState.Assignments[id] = task.ID
+		r.assignedTasks[id] = task
+	}
+	log.Printf("📋 Room %s dealt personal tasks: %v", r.ID, r.gameState.Assignments)
+}
+
+// restoreAssignments finds a restored game's personal tasks again by ID. A
+// task that has since left the pool is swapped for another.
+func restoreAssignments(assignments map[string]string) map[string]*Task {
+	if len(assignments) == 0 {
+		return nil
+	}
+	pool := currentTaskPool()
+	byID := make(map[string]*Task, len(pool))
+	for _, t := range pool {
+		byID[t.ID] = t
+	}
+
+	tasks := make(map[string]*Task, len(assignments))
+	for playerID, taskID := range assignments {
+		t, ok := byID[taskID]
+		if !ok && len(pool) > 0 {
+			t = pool[rand.Intn(len(pool))]
+		}
+		if t != nil {
+			tasks[playerID] = t
+		}
+	}
+	return tasks
+}
+
+// gameTasks is every task the game uses: the stage tasks and, in a
+// personal-tasks game, the ones dealt to players.
+func (r *Room) gameTasks() []*Task {
+	tasks := append([]*Task(nil), r.tasks...)
+	seen := make(map[*Task]bool, len(tasks))
+	for _, t := range tasks {
+		seen[t] = true
+	}
+	for _, t := range r.assignedTasks {
+		if !seen[t] {
+			seen[t] = true
+			tasks = append(tasks, t)
+		}
+	}
+	return tasks
+}
+
+// runTask is the task playerID's code is tested against: their own in a
+// personal-tasks game, otherwise the stage's.
+func (r *Room) runTask(playerID string, stage int) Task {
+	t := r.assignedTasks[playerID]
+	if t == nil {
+		return r.stageTask(stage)
+	}
+	task := *t
+	if task.Tests == "" {
+		task.Tests = taskTests[task.ID]
+	}
+	return task
+}
+
+// taskBar is how many crew tasks are done out of how many count.
+func (r *Room) taskBar() (done, total int) {
+	for id, player := range r.players {
+		if !isCrewmate(player.Role) || r.gameState.Assignments[id] == "" {
+			continue
+		}
+		switch {
+		case r.gameState.TasksDone[id]:
+			done++
+			total++
+		case !player.IsEliminated:
+			total++
+		}
+	}
+	return done, total
+}
+
+func (r *Room) taskProgressPayload() map[string]interface{} {
+	done, total := r.taskBar()
+	return map[string]interface{}{
+		"complete": done,
+		"total":    total,
+	}
+}
+
+// completePersonalTask records that playerID passed their own task and,
+// for a crewmate, moves the task bar. Runs on the room goroutine.
+func (r *Room) completePersonalTask(playerID string) {
+	player := r.players[playerID]
+	if player == nil || r.gameState.TasksDone[playerID] {
+		return
+	}
+	r.gameState.TasksDone[playerID] = true
+	r.stagesPassed[playerID]++
+	r.saveToRedis()
+	if client := r.clientFor(playerID); client != nil {
+		r.sendAssignedTask(client)
+	}
+	if !isCrewmate(player.Role) {
+		return
+	}
+
+	done, total := r.taskBar()
+	log.Printf("📋 Room %s task bar %d/%d", r.ID, done, total)
+	r.broadcastTaskProgress()
+	r.checkTaskBar()
+}
+
+func (r *Room) broadcastTaskProgress() {
+	data, _ := json.Marshal(Message{Type: "TASK_PROGRESS", Data: r.taskProgressPayload()})
+	r.emit(data)
+}
+
+// checkTaskBar ends the game for the crew if the task bar is full, and
+// reports whether it did. Runs on the room goroutine.
+func (r *Room) checkTaskBar() bool {
+	if !r.personalGame() || r.gameState.Phase == PhaseEnd {
+		return false
+	}
+	done, total := r.taskBar()
+	if total == 0 || done < total {
+		return false
+	}
+	r.endGame("CIVILIAN_WIN_TASKS")
+	return true
+}
+
+// sendAssignedTask tells c which task is theirs in a personal-tasks game.
+func (r *Room) sendAssignedTask(c *Client) {
+	task := r.assignedTasks[c.PlayerID]
+	if task == nil {
+		return
+	}
+	c.sendMessage(Message{
+		Type: "ASSIGNED_TASK",
+		Data: map[string]interface{}{
+			"task": task,
+			"done": r.gameState.TasksDone[c.PlayerID],
+		},
+	})
+}
+
+func (r *Room) sendAssignedTasks() {
+	for client := range r.clients {
+		r.sendAssignedTask(client)
+	}
+}
@@ -0,0 +1,277 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand"
+	"strings"
+	"time"
+
+	"code-mafia-backend/config"
+	"code-mafia-backend/database"
+	"code-mafia-backend/llm"
+	"code-mafia-backend/sim"
+)
+
+// Practice bots fill a lobby out so one or two people can play a real
+// game. Each is a sim.Bot connected back to this server as a guest, so
+// the room treats it like any other player; a practiceBrain decides what
+// it says and who it votes for in meetings.
+
+// botLLM is what practice bots talk through. While it is nil they speak
+// from botLines.
+var botLLM llm.Completer
+
+var botNames = []string{"Ada", "Linus", "Grace", "Dennis", "Barbara", "Ken", "Margaret", "Alan"}
+
+// botPrefix marks a bot's username so nobody mistakes it for a person.
+const botPrefix = "🤖 "
+
+// botJoinWait is how long a bot that was sent in still counts towards the
+// limit without having joined.
+const botJoinWait = 15 * time.Second
+
+func initPracticeBots() {
+	cfg := config.AppConfig
+	if cfg.PracticeBotsMax == 0 {
+		log.Printf("🤖 Practice bots: off")
+		return
+	}
+	if cfg.BotLLMProvider == "" {
+		log.Printf("🤖 Practice bots: up to %d per room, scripted", cfg.PracticeBotsMax)
+		return
+	}
+
+	completer, err := llm.New(cfg.BotLLMProvider, cfg.BotLLMAPIKey, cfg.BotLLMModel, cfg.BotLLMURL)
+	if err != nil {
+		log.Printf("⚠️ Practice bots will be scripted: %v", err)
+		return
+	}
+	botLLM = completer
+	log.Printf("🤖 Practice bots: up to %d per room, talking through %s", cfg.PracticeBotsMax, cfg.BotLLMProvider)
+}
+
+// addPracticeBot sends a bot into the lobby for the host. Runs on the room
+// goroutine.
+func (r *Room) addPracticeBot(c *Client) error {
+	cfg := config.AppConfig
+
+	player := r.players[c.PlayerID]
+	if player == nil || !player.IsHost {
+		return errors.New("Only host can add bots")
+	}
+	if cfg.PracticeBotsMax == 0 {
+		return errors.New("Practice bots are off on this server")
+	}
+	if cfg.RequireAuth {
+		return errors.New("Practice bots can't join on a server that requires sign-in")
+	}
+	if r.gameState.Phase != PhaseLobby {
+		return errors.New("Bots can only be added in the lobby")
+	}
+	if r.ranked {
+		return errors.New("Bots can't play ranked games")
+	}
+
+	name := r.freeBotName()
+	if name == "" || r.botCount() >= cfg.PracticeBotsMax {
+		return fmt.Errorf("A room can have at most %d bots", cfg.PracticeBotsMax)
+	}
+	r.bots[name] = time.Now()
+
+	go runPracticeBot(r.ID, name)
+	log.Printf("🤖 Room %s: %s added %s", r.ID, c.PlayerID, name)
+	return nil
+}
+
+// botCount is how many bots are in the room or on their way, forgetting
+// those that have left or never arrived.
+func (r *Room) botCount() int {
+	present := make(map[string]bool)
+	for _, p := range r.players {
+		present[p.Username] = true
+	}
+	for name, sentAt := range r.bots {
+		if !present[name] && time.Since(sentAt) > botJoinWait {
+			delete(r.bots, name)
+		}
+	}
+	return len(r.bots)
+}
+
+func (r *Room) freeBotName() string {
+	taken := make(map[string]bool)
+	for _, p := range r.players {
+		taken[p.Username] = true
+	}
+	for _, name := range botNames {
+		if name = botPrefix + name; !taken[name] && r.bots[name].IsZero() {
+			return name
+		}
+	}
+	return ""
+}
+
+// runPracticeBot plays until the server hangs up on the bot: the room
+// closed, the host kicked it or it went AFK.
+func runPracticeBot(roomID, name string) {
+	defer recoverPanic("runPracticeBot")
+
+	opts := sim.Options{
+		BaseURL: "ws://127.0.0.1:" + config.AppConfig.Port,
+		Brain:   practiceBrain{},
+	}
+	bot := sim.NewPracticeBot(opts, roomID, name)
+
+	ctx := context.Background()
+	if err := bot.Connect(ctx); err != nil {
+		log.Printf("🤖 %s couldn't join room %s: %v", name, roomID, err)
+		return
+	}
+	bot.Play(ctx)
+	debugf("🤖 %s left room %s", name, roomID)
+}
+
+// practiceBrain speaks through botLLM when there is one, reading the
+// room's recent chat from Redis, and from botLines otherwise.
+type practiceBrain struct{}
+
+const botLLMTimeout = 15 * time.Second
+
+func (practiceBrain) Speak(ctx context.Context, v sim.View) string {
+	if botLLM != nil {
+		ctx, cancel := context.WithTimeout(ctx, botLLMTimeout)
+		defer cancel()
+
+		reply, err := botLLM.Complete(ctx, botSystemPrompt(v), botSituation(v)+
+			"\nWrite your one chat message for this meeting: an accusation, a defense or a question, under 25 words.")
+		if err == nil {
+			if line := cleanBotLine(reply); line != "" {
+				return line
+			}
+			err = errors.New("empty reply")
+		}
+		log.Printf("🤖 %s fell back to a scripted line: %v", v.Self.Username, err)
+	}
+	return scriptedLine(v)
+}
+
+func (practiceBrain) Vote(ctx context.Context, v sim.View) string {
+	candidates := livingOthers(v)
+
+	if botLLM != nil && len(candidates) > 0 {
+		ctx, cancel := context.WithTimeout(ctx, botLLMTimeout)
+		defer cancel()
+
+		reply, err := botLLM.Complete(ctx, botSystemPrompt(v), botSituation(v)+
+			"\nWho do you vote out? Answer with exactly one username from the list, or SKIP.")
+		if err == nil {
+			reply = strings.TrimSpace(reply)
+			if strings.EqualFold(reply, "SKIP") {
+				return "SKIP"
+			}
+			for _, p := range candidates {
+				if strings.EqualFold(reply, p.Username) || strings.EqualFold(reply, strings.TrimPrefix(p.Username, botPrefix)) {
+					return p.ID
+				}
+			}
+		}
+	}
+
+	// Without a model: mostly suspect someone, sometimes play it safe.
+	if len(candidates) == 0 || rand.Intn(10) < 4 {
+		return "SKIP"
+	}
+	return candidates[rand.Intn(len(candidates))].ID
+}
+
+func botSystemPrompt(v sim.View) string {
+	role := "a crewmate fixing buggy code with the others"
+	if v.Self.Role == "IMPOSTER" {
+		role = "the impostor, secretly sabotaging the code; never admit it"
+	}
+	return fmt.Sprintf("You are %s, a player in Code Mafia, a social deduction game like Among Us where players fix code together. "+
+		"You are %s. Chat like a casual player in a hurry: short, lowercase is fine, no emojis, never say you are an AI or a bot.",
+		strings.TrimPrefix(v.Self.Username, botPrefix), role)
+}
+
+// botSituation describes the meeting: who is still in and what was said
+// lately, which the translation pipeline keeps in the room's Redis chat
+// history.
+func botSituation(v sim.View) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "A meeting was called during stage %d.\nPlayers still in:", v.Stage)
+	for _, p := range livingOthers(v) {
+		fmt.Fprintf(&b, " %s;", p.Username)
+	}
+
+	history, err := database.GetRoomChatHistory(v.RoomID, 10)
+	if err != nil {
+		log.Printf("Failed to get chat history for bot in room %s: %v", v.RoomID, err)
+	}
+	if len(history) > 0 {
+		b.WriteString("\nRecent chat, newest first:")
+		for _, line := range history {
+			fmt.Fprintf(&b, "\n- %s", line)
+		}
+	} else {
+		b.WriteString("\nNobody has said anything yet.")
+	}
+	return b.String()
+}
+
+// cleanBotLine keeps the first line of a reply and trims what models tend
+// to wrap it in.
+func cleanBotLine(reply string) string {
+	line := strings.TrimSpace(strings.SplitN(reply, "\n", 2)[0])
+	line = strings.Trim(line, `"'`)
+	if len(line) > 200 {
+		line = line[:200]
+	}
+	return line
+}
+
+var botLines = map[string][]string{
+	"crew": {
+		"i was on the tests the whole stage, they were passing until the last run",
+		"%s has been really quiet, what were you working on?",
+		"did anyone see who touched the code right before it broke?",
+		"skip unless someone has actual proof",
+		"%s, explain the last edit please",
+	},
+	"IMPOSTER": {
+		"not me, i was fixing the loop bounds",
+		"honestly %s has been acting weird this round",
+		"we're wasting time, let's skip and get back to the code",
+		"i ran the tests twice, ask anyone",
+	},
+}
+
+func scriptedLine(v sim.View) string {
+	lines := botLines["crew"]
+	if v.Self.Role == "IMPOSTER" {
+		lines = botLines["IMPOSTER"]
+	}
+	line := lines[rand.Intn(len(lines))]
+	if !strings.Contains(line, "%s") {
+		return line
+	}
+
+	others := livingOthers(v)
+	if len(others) == 0 {
+		return "let's just skip"
+	}
+	return fmt.Sprintf(line, others[rand.Intn(len(others))].Username)
+}
+
+func livingOthers(v sim.View) []sim.PlayerView {
+	var others []sim.PlayerView
+	for _, p := range v.Players {
+		if p.ID != v.Self.ID && !p.Eliminated {
+			others = append(others, p)
+		}
+	}
+	return others
+}
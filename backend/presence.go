@@ -0,0 +1,96 @@
+package main
+
+import (
+	"sync"
+
+	"code-mafia-backend/auth"
+)
+
+// presence tracks which signed-in players have a live connection on this
+// instance, so friends can see each other online and receive invites.
+type presence struct {
+	mu    sync.RWMutex
+	conns map[string]map[*Client]bool
+}
+
+func newPresence() *presence {
+	return &presence{conns: make(map[string]map[*Client]bool)}
+}
+
+func (p *presence) add(client *Client) {
+	if !client.Authenticated {
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.conns[client.PlayerID] == nil {
+		p.conns[client.PlayerID] = make(map[*Client]bool)
+	}
+	p.conns[client.PlayerID][client] = true
+}
+
+func (p *presence) remove(client *Client) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	conns := p.conns[client.PlayerID]
+	delete(conns, client)
+	if len(conns) == 0 {
+		delete(p.conns, client.PlayerID)
+	}
+}
+
+// anyOnline reports whether any of the users has a connection here.
+func (p *presence) anyOnline(userIDs []string) bool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	for _, id := range userIDs {
+		if len(p.conns[id]) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// roomOf returns the room the user is connected to, or "" if offline.
+func (p *presence) roomOf(userID string) string {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	for client := range p.conns[userID] {
+		return client.RoomID
+	}
+	return ""
+}
+
+// nameOf prefers the name the user joined their current room with.
+func (p *presence) nameOf(claims *auth.Claims) string {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	for client := range p.conns[claims.Subject] {
+		if client.Username != "" {
+			return client.Username
+		}
+	}
+	return claims.DisplayName()
+}
+
+// send delivers msg to every connection the user has open and reports
+// whether there was one.
+func (p *presence) send(userID string, msg Message) bool {
+	p.mu.RLock()
+	clients := make([]*Client, 0, len(p.conns[userID]))
+	for client := range p.conns[userID] {
+		clients = append(clients, client)
+	}
+	p.mu.RUnlock()
+
+	for _, client := range clients {
+		client.sendMessage(msg)
+	}
+	return len(clients) > 0
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Quick chat lines are picked from a fixed catalog, so every client can
+// show them in its own language straight away: the CHAT carries the
+// preset's translation key, with the English text for clients that don't
+// know it, and skips the translation sidecar.
+
+type QuickChat struct {
+	ID   string `json:"id"`
+	Key  string `json:"key"`
+	Text string `json:"text"`
+}
+
+var quickChatCatalog = []QuickChat{
+	{ID: "where", Key: "quickchat.where", Text: "Where is everyone?"},
+	{ID: "sus", Key: "quickchat.sus", Text: "That's sus."},
+	{ID: "not-me", Key: "quickchat.notMe", Text: "It wasn't me!"},
+	{ID: "agree", Key: "quickchat.agree", Text: "Agreed."},
+	{ID: "skip", Key: "quickchat.skip", Text: "Let's skip this one."},
+	{ID: "help", Key: "quickchat.help", Text: "I need help with this task."},
+	{ID: "tests-pass", Key: "quickchat.testsPass", Text: "The tests pass for me."},
+	{ID: "tests-fail", Key: "quickchat.testsFail", Text: "The tests fail for me."},
+	{ID: "i-fixed-task-1", Key: "quickchat.fixedTask1", Text: "I fixed task 1."},
+	{ID: "i-fixed-task-2", Key: "quickchat.fixedTask2", Text: "I fixed task 2."},
+	{ID: "i-fixed-task-3", Key: "quickchat.fixedTask3", Text: "I fixed task 3."},
+}
+
+func findQuickChat(id string) (QuickChat, bool) {
+	for _, q := range quickChatCatalog {
+		if q.ID == id {
+			return q, true
+		}
+	}
+	return QuickChat{}, false
+}
+
+func handleGetQuickChat(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{"presets": quickChatCatalog})
+}
+
+// sendQuickChat sends a preset line from player to the room. Runs on the
+// room goroutine.
+func (r *Room) sendQuickChat(player *Player, preset QuickChat) {
+	messageID := uuid.New().String()
+	r.audit("QUICK_CHAT", player.ID, player.Username, map[string]interface{}{
+		"messageId": messageID,
+		"presetId":  preset.ID,
+	})
+
+	data, _ := json.Marshal(Message{
+		Type: "CHAT",
+		Data: map[string]interface{}{
+			"messageId": messageID,
+			"username":  player.Username,
+			"text":      preset.Text,
+			"key":       preset.Key,
+			"presetId":  preset.ID,
+			"playerId":  player.ID,
+			"timestamp": time.Now().UnixMilli(),
+			"system":    false,
+		},
+	})
+	r.emit(data)
+
+	log.Printf("💬 Quick chat [%s]: %s: %s", r.ID, player.Username, preset.ID)
+}
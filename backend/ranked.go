@@ -0,0 +1,113 @@
+package main
+
+import (
+	"errors"
+	"log"
+	"time"
+
+	"code-mafia-backend/database"
+	"code-mafia-backend/rating"
+)
+
+// setRanked flags the lobby as ranked. Ranked games update player ratings
+// when they end, so the flag is locked once play starts.
+func (r *Room) setRanked(ranked bool) error {
+	if ranked && database.SupabaseClient == nil {
+		return errors.New("Ranked games are unavailable on this server")
+	}
+
+	if r.gameState.Phase != PhaseLobby {
+		return errors.New("Ranked mode can only be changed in the lobby")
+	}
+	r.ranked = ranked
+	r.syncListing()
+
+	log.Printf("🏆 Room %s ranked=%v", r.ID, ranked)
+	r.broadcastGameState()
+	return nil
+}
+
+func (r *Room) updateRatings(players []database.MatchPlayer, winnerRole string) {
+	ids := make([]string, 0, len(players))
+	for _, p := range players {
+		ids = append(ids, p.UserID)
+	}
+
+	current, err := database.GetRatings(ids)
+	if err != nil {
+		log.Printf("Failed to load ratings for room %s: %v", r.ID, err)
+		return
+	}
+
+	participants := make([]rating.Participant, 0, len(players))
+	for _, p := range players {
+		participants = append(participants, rating.Participant{
+			ID:     p.UserID,
+			Role:   p.Role,
+			Rating: current[p.UserID].Rating,
+		})
+	}
+
+	updated := rating.Update(participants, winnerRole)
+
+	rows := make([]database.PlayerRating, 0, len(updated))
+	for _, p := range participants {
+		if _, rated := updated[p.ID]; !rated {
+			continue
+		}
+		rows = append(rows, database.PlayerRating{
+			UserID:      p.ID,
+			Rating:      updated[p.ID],
+			RankedGames: current[p.ID].RankedGames + 1,
+			UpdatedAt:   time.Now(),
+		})
+		log.Printf("🏆 Rating %s (%s): %.0f -> %.0f", p.ID, p.Role, p.Rating, updated[p.ID])
+	}
+
+	if err := database.SaveRatings(rows); err != nil {
+		log.Printf("Failed to save ratings for room %s: %v", r.ID, err)
+	}
+}
+
+// updateMMR moves every player's matchmaking rating after a decided game,
+// ranked or not, with the same Elo as ranked play.
+func (r *Room) updateMMR(players []database.MatchPlayer, winnerRole string) {
+	ids := make([]string, 0, len(players))
+	for _, p := range players {
+		ids = append(ids, p.UserID)
+	}
+
+	current, err := database.GetMMRs(ids)
+	if err != nil {
+		log.Printf("Failed to load MMR for room %s: %v", r.ID, err)
+		return
+	}
+
+	participants := make([]rating.Participant, 0, len(players))
+	for _, p := range players {
+		participants = append(participants, rating.Participant{
+			ID:     p.UserID,
+			Role:   p.Role,
+			Rating: current[p.UserID].MMR,
+		})
+	}
+
+	updated := rating.Update(participants, winnerRole)
+
+	rows := make([]database.PlayerMMR, 0, len(updated))
+	for _, p := range participants {
+		if _, rated := updated[p.ID]; !rated {
+			continue
+		}
+		rows = append(rows, database.PlayerMMR{
+			UserID:    p.ID,
+			MMR:       updated[p.ID],
+			Games:     current[p.ID].Games + 1,
+			UpdatedAt: time.Now(),
+		})
+	}
+
+	if err := database.SaveMMRs(rows); err != nil {
+		log.Printf("Failed to save MMR for room %s: %v", r.ID, err)
+	}
+}
@@ -0,0 +1,214 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"code-mafia-backend/config"
+)
+
+type tokenBucket struct {
+	tokens   float64
+	lastSeen time.Time
+}
+
+// ipRateLimiter is a per-key token bucket. The budget is looked up on every
+// call so reloaded limits apply immediately to existing buckets.
+type ipRateLimiter struct {
+	name   string
+	budget func() (perMinute, burst int)
+
+	mu      sync.Mutex
+	buckets map[string]*tokenBucket
+}
+
+func newIPRateLimiter(name string, budget func() (int, int)) *ipRateLimiter {
+	l := &ipRateLimiter{
+		name:    name,
+		budget:  budget,
+		buckets: make(map[string]*tokenBucket),
+	}
+	go l.cleanup()
+	return l
+}
+
+// allow takes a token for key and reports how long to wait when none is left.
+func (l *ipRateLimiter) allow(key string) (bool, time.Duration) {
+	perMinute, burst := l.budget()
+	rate := float64(perMinute) / 60
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &tokenBucket{tokens: float64(burst), lastSeen: now}
+		l.buckets[key] = b
+	}
+
+	return b.take(now, rate, burst)
+}
+
+// take refills the bucket for the time since it was last used and takes a
+// token, reporting how long to wait when none is left.
+func (b *tokenBucket) take(now time.Time, rate float64, burst int) (bool, time.Duration) {
+	b.tokens += now.Sub(b.lastSeen).Seconds() * rate
+	if b.tokens > float64(burst) {
+		b.tokens = float64(burst)
+	}
+	b.lastSeen = now
+
+	if b.tokens < 1 {
+		return false, time.Duration((1 - b.tokens) / rate * float64(time.Second))
+	}
+	b.tokens--
+	return true, 0
+}
+
+func (l *ipRateLimiter) cleanup() {
+	ticker := time.NewTicker(5 * time.Minute)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		l.mu.Lock()
+		for key, b := range l.buckets {
+			if time.Since(b.lastSeen) > 10*time.Minute {
+				delete(l.buckets, key)
+			}
+		}
+		l.mu.Unlock()
+	}
+}
+
+func (l *ipRateLimiter) middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodOptions {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		ip := clientIP(r)
+		if ok, retryAfter := l.allow(ip); !ok {
+			seconds := int(retryAfter.Seconds()) + 1
+			w.Header().Set("Retry-After", fmt.Sprint(seconds))
+			writeJSON(w, http.StatusTooManyRequests, map[string]interface{}{
+				"error":             "rate_limited",
+				"message":           "Too many requests - slow down",
+				"retryAfterSeconds": seconds,
+			})
+			debugf("⏳ Rate limited %s on %s budget (%s %s)", ip, l.name, r.Method, r.URL.Path)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (l *ipRateLimiter) wrap(next http.HandlerFunc) http.HandlerFunc {
+	return l.middleware(next).ServeHTTP
+}
+
+var (
+	apiRateLimiter = newIPRateLimiter("api", func() (int, int) {
+		t := config.Current()
+		return t.RateLimitAPIPerMin, t.RateLimitAPIBurst
+	})
+
+	// createRateLimiter guards endpoints that allocate server-side state
+	// (rooms, queue tickets, invites) with a much smaller budget.
+	createRateLimiter = newIPRateLimiter("create", func() (int, int) {
+		t := config.Current()
+		return t.RateLimitCreatePerMin, t.RateLimitCreateBurst
+	})
+)
+
+// wsBudget names the budget a game WebSocket message draws from and returns
+// its size. Chat lines, test runs and typing indicators have their own;
+// everything else shares one.
+func wsBudget(msgType string) (name string, perMinute, burst int) {
+	t := config.Current()
+	switch msgType {
+	case "CHAT", "QUICK_CHAT", "GHOST_CHAT", "IMPOSTOR_CHAT", "PARTY_CHAT":
+		return "chat", t.RateLimitChatPerMin, t.RateLimitChatBurst
+	case "RUN_TESTS":
+		return "tests", t.RateLimitTestsPerMin, t.RateLimitTestsBurst
+	case "TYPING_START", "TYPING_STOP":
+		return "typing", typingPerMinute, typingBurst
+	}
+	return "messages", t.RateLimitWSPerMin, t.RateLimitWSBurst
+}
+
+// allowMessage takes a token from the connection's budget for msgType.
+// Only the goroutine handling the client's messages calls it.
+func (c *Client) allowMessage(msgType string) (bool, time.Duration) {
+	name, perMinute, burst := wsBudget(msgType)
+
+	now := time.Now()
+	if c.buckets == nil {
+		c.buckets = make(map[string]*tokenBucket)
+	}
+	b, ok := c.buckets[name]
+	if !ok {
+		b = &tokenBucket{tokens: float64(burst), lastSeen: now}
+		c.buckets[name] = b
+	}
+	return b.take(now, float64(perMinute)/60, burst)
+}
+
+// sendRateLimited tells the client a message was dropped for going over
+// budget. retryAfter is zero when waiting won't help, such as a second
+// meeting call in the same phase.
+func (c *Client) sendRateLimited(msgType string, retryAfter time.Duration) {
+	data := map[string]interface{}{
+		"messageType": msgType,
+		"message":     "Too many " + msgType + " messages - slow down",
+	}
+	if retryAfter > 0 {
+		data["retryAfterMs"] = retryAfter.Milliseconds()
+	}
+	c.sendMessage(Message{Type: "ERROR_RATE_LIMITED", Data: data})
+}
+
+// clientIP returns the caller's address, trusting X-Forwarded-For only when
+// the server is configured to sit behind a proxy that sets it.
+func clientIP(r *http.Request) string {
+	if config.AppConfig != nil && config.AppConfig.TrustProxyHeaders {
+		if ip := forwardedFor(r.Header.Values("X-Forwarded-For"), config.AppConfig.TrustedProxyHops); ip != "" {
+			return ip
+		}
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// forwardedFor picks the client's address out of X-Forwarded-For headers:
+// the entry added by the outermost of the hops trusted proxies, counting
+// from the right. Entries to the left of it were sent by the client, which
+// can put anything there.
+func forwardedFor(headers []string, hops int) string {
+	var entries []string
+	for _, header := range headers {
+		for _, entry := range strings.Split(header, ",") {
+			if entry = strings.TrimSpace(entry); entry != "" {
+				entries = append(entries, entry)
+			}
+		}
+	}
+	if len(entries) == 0 {
+		return ""
+	}
+	i := len(entries) - hops
+	if i < 0 {
+		i = 0
+	}
+	return entries[i]
+}
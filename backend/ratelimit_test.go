@@ -0,0 +1,26 @@
+package main
+
+import "testing"
+
+func TestForwardedForIgnoresClientEntries(t *testing.T) {
+	tests := []struct {
+		headers []string
+		hops    int
+		want    string
+	}{
+		{nil, 1, ""},
+		{[]string{"203.0.113.7"}, 1, "203.0.113.7"},
+		// The client made up the first entry; the proxy added the last.
+		{[]string{"1.2.3.4, 203.0.113.7"}, 1, "203.0.113.7"},
+		{[]string{"1.2.3.4", "203.0.113.7"}, 1, "203.0.113.7"},
+		// A CDN and a load balancer.
+		{[]string{"1.2.3.4, 203.0.113.7, 10.0.0.2"}, 2, "203.0.113.7"},
+		{[]string{"203.0.113.7"}, 2, "203.0.113.7"},
+		{[]string{" , 203.0.113.7 "}, 1, "203.0.113.7"},
+	}
+	for _, tt := range tests {
+		if got := forwardedFor(tt.headers, tt.hops); got != tt.want {
+			t.Errorf("forwardedFor(%q, %d) = %q, want %q", tt.headers, tt.hops, got, tt.want)
+		}
+	}
+}
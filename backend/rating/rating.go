@@ -0,0 +1,78 @@
+// Package rating implements Elo updates for ranked matches. A match is
+// scored as the impostor team against the crew: each side plays the other
+// side's average rating, and every member of a side moves by the side's
+// result. Roles on neither side, like the jester, aren't rated.
+package rating
+
+import "math"
+
+const (
+	Initial = 1500.0
+
+	// Impostors are few and carry the result between them, so their
+	// ratings move faster than any single crewmate's.
+	KImpostor = 32.0
+	KCivilian = 20.0
+
+	RoleImpostor  = "IMPOSTER"
+	RoleCivilian  = "CIVILIAN"
+	RoleDetective = "DETECTIVE"
+)
+
+type Participant struct {
+	ID     string
+	Role   string
+	Rating float64
+}
+
+// Expected returns the probability that a player rated r beats opp.
+func Expected(r, opp float64) float64 {
+	return 1 / (1 + math.Pow(10, (opp-r)/400))
+}
+
+// Update returns the new rating of every participant on the impostor team
+// or the crew. Participants on neither side are left out, as is everyone
+// if either side is empty, since nothing was decided between them.
+func Update(participants []Participant, winnerRole string) map[string]float64 {
+	var impostors, crew []Participant
+	for _, p := range participants {
+		if p.Rating == 0 {
+			p.Rating = Initial
+		}
+		switch p.Role {
+		case RoleImpostor:
+			impostors = append(impostors, p)
+		case RoleCivilian, RoleDetective:
+			crew = append(crew, p)
+		}
+	}
+
+	result := make(map[string]float64, len(impostors)+len(crew))
+	if len(impostors) == 0 || len(crew) == 0 {
+		return result
+	}
+
+	impostorAvg, crewAvg := average(impostors), average(crew)
+	impostorScore := 0.0
+	if winnerRole == RoleImpostor {
+		impostorScore = 1
+	}
+
+	impostorDelta := KImpostor * (impostorScore - Expected(impostorAvg, crewAvg))
+	for _, p := range impostors {
+		result[p.ID] = p.Rating + impostorDelta
+	}
+	crewDelta := KCivilian * ((1 - impostorScore) - Expected(crewAvg, impostorAvg))
+	for _, p := range crew {
+		result[p.ID] = p.Rating + crewDelta
+	}
+	return result
+}
+
+func average(team []Participant) float64 {
+	total := 0.0
+	for _, p := range team {
+		total += p.Rating
+	}
+	return total / float64(len(team))
+}
@@ -0,0 +1,76 @@
+package rating
+
+import (
+	"math"
+	"testing"
+)
+
+func near(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
+
+func TestUpdateRatesWholeTeams(t *testing.T) {
+	participants := []Participant{
+		{ID: "imp1", Role: RoleImpostor, Rating: 1500},
+		{ID: "imp2", Role: RoleImpostor, Rating: 1500},
+		{ID: "civ1", Role: RoleCivilian, Rating: 1500},
+		{ID: "civ2", Role: RoleCivilian, Rating: 1500},
+		{ID: "det", Role: RoleDetective, Rating: 1500},
+		{ID: "jester", Role: "JESTER", Rating: 1500},
+	}
+
+	got := Update(participants, RoleImpostor)
+
+	for _, id := range []string{"imp1", "imp2"} {
+		if !near(got[id], 1500+KImpostor/2) {
+			t.Errorf("%s = %v, want %v", id, got[id], 1500+KImpostor/2)
+		}
+	}
+	for _, id := range []string{"civ1", "civ2", "det"} {
+		if !near(got[id], 1500-KCivilian/2) {
+			t.Errorf("%s = %v, want %v", id, got[id], 1500-KCivilian/2)
+		}
+	}
+	if _, rated := got["jester"]; rated {
+		t.Errorf("jester was rated")
+	}
+}
+
+func TestUpdateUsesTeamAverages(t *testing.T) {
+	participants := []Participant{
+		{ID: "imp1", Role: RoleImpostor, Rating: 1400},
+		{ID: "imp2", Role: RoleImpostor, Rating: 1600},
+		{ID: "civ1", Role: RoleCivilian, Rating: 1300},
+		{ID: "civ2", Role: RoleCivilian, Rating: 1700},
+	}
+
+	got := Update(participants, RoleCivilian)
+
+	// Both sides average 1500, so the crew's win is worth half of K.
+	if !near(got["imp1"], 1400-KImpostor/2) || !near(got["imp2"], 1600-KImpostor/2) {
+		t.Errorf("impostors = %v, %v", got["imp1"], got["imp2"])
+	}
+	if !near(got["civ1"], 1300+KCivilian/2) || !near(got["civ2"], 1700+KCivilian/2) {
+		t.Errorf("crew = %v, %v", got["civ1"], got["civ2"])
+	}
+}
+
+func TestUpdateNeedsBothSides(t *testing.T) {
+	got := Update([]Participant{
+		{ID: "civ", Role: RoleCivilian},
+		{ID: "jester", Role: "JESTER"},
+	}, RoleCivilian)
+	if len(got) != 0 {
+		t.Errorf("one-sided game rated: %v", got)
+	}
+}
+
+func TestUpdateStartsUnratedPlayersAtInitial(t *testing.T) {
+	got := Update([]Participant{
+		{ID: "imp", Role: RoleImpostor},
+		{ID: "civ", Role: RoleCivilian},
+	}, RoleImpostor)
+	if !near(got["imp"], Initial+KImpostor/2) || !near(got["civ"], Initial-KCivilian/2) {
+		t.Errorf("got %v", got)
+	}
+}
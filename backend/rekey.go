@@ -0,0 +1,48 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"code-mafia-backend/config"
+	"code-mafia-backend/database"
+)
+
+// runRekeyCommand implements `main rekey -from old -to new [-dry-run]`,
+// moving the backend's keys in the configured Redis from one
+// REDIS_KEY_PREFIX to another. Run it with the servers stopped: a server
+// still using the old prefix would carry on writing there.
+func runRekeyCommand(args []string) {
+	fs := flag.NewFlagSet("rekey", flag.ExitOnError)
+	from := fs.String("from", "", "prefix the keys are under now, blank for none")
+	to := fs.String("to", "", "prefix to move them under, blank for none")
+	dryRun := fs.Bool("dry-run", false, "count the keys that would move without moving them")
+	fs.Parse(args)
+
+	if err := config.Load(); err != nil {
+		fmt.Fprintln(os.Stderr, "rekey:", err)
+		os.Exit(1)
+	}
+	err := database.InitRedis(
+		config.AppConfig.RedisURL,
+		config.AppConfig.RedisPassword,
+		config.AppConfig.RedisDB,
+		0,
+	)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "rekey:", err)
+		os.Exit(1)
+	}
+
+	moved, skipped, err := database.RelocateKeys(*from, *to, *dryRun)
+	if *dryRun {
+		fmt.Printf("would move %d keys from %q to %q\n", moved, *from, *to)
+	} else {
+		fmt.Printf("moved %d keys from %q to %q, skipped %d already there\n", moved, *from, *to, skipped)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "rekey:", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"time"
+)
+
+// roomCleanupDelay is how long a finished game's room stays in Redis
+// waiting for a rematch. Tests shorten it.
+var roomCleanupDelay = 5 * time.Minute
+
+// rematch takes a finished room back to the lobby with the same players and
+// settings, ready for the host to start another game. Roles are dealt again
+// when it starts. Runs on the room goroutine.
+func (r *Room) rematch(host *Client) {
+	hostPlayer := r.players[host.PlayerID]
+	if hostPlayer == nil || !hostPlayer.IsHost {
+		host.sendError("Only host can start a rematch")
+		return
+	}
+	if r.gameState.Phase != PhaseEnd {
+		host.sendError("The game isn't over yet")
+		return
+	}
+
+	if r.cleanup != nil {
+		r.cleanup.Stop()
+		r.cleanup = nil
+	}
+
+	// Anything still scheduled belongs to the finished game.
+	r.game++
+	for id, timer := range r.reconnects {
+		timer.Stop()
+		delete(r.reconnects, id)
+	}
+	if r.sabotageTimer != nil {
+		r.sabotageTimer.Stop()
+		r.sabotageTimer = nil
+	}
+
+	r.testRunning = false
+	r.testRunner = ""
+	r.testRunnerName = ""
+	r.codeSnapshot = ""
+	r.votes = make(map[string]string)
+	r.votingActive = false
+	r.votingDeadline = time.Time{}
+	r.sabotageActive = false
+	r.sabotageType = ""
+	r.sabotageEndTime = time.Time{}
+	r.swappedIDs = nil
+	r.repairedIDs = nil
+	r.corruptMarkers = nil
+	r.lastSabotageTime = time.Time{}
+	r.tasks = nil
+	r.translationsAsked = make(map[string]bool)
+	r.translationFailures = make(map[string]int)
+
+	r.gameState = GameState{
+		Phase:         PhaseLobby,
+		TasksComplete: make(map[int]bool),
+		Settings:      r.gameState.Settings,
+		MutedIDs:      r.gameState.MutedIDs,
+	}
+
+	for _, player := range r.players {
+		player.Role = ""
+		player.IsEliminated = false
+		player.IsAlive = true
+	}
+	// Seats held for players who never came back are given up.
+	for _, player := range r.players {
+		if player.Disconnected && r.clientFor(player.ID) == nil {
+			r.removePlayer(player)
+		}
+	}
+
+	r.logEvent(gameEventRematch, host.PlayerID, hostPlayer.Username, nil)
+	r.saveToRedis()
+	r.audit("REMATCH", host.PlayerID, hostPlayer.Username, nil)
+	log.Printf("🔁 %s started a rematch in room %s", hostPlayer.Username, r.ID)
+
+	rematchMsg := Message{
+		Type: "REMATCH_STARTED",
+		Data: map[string]interface{}{
+			"hostName": hostPlayer.Username,
+		},
+	}
+	data, _ := json.Marshal(rematchMsg)
+	r.emit(data)
+
+	r.broadcastGameState()
+	r.broadcastPlayerList()
+}
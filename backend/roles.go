@@ -0,0 +1,88 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// roleUnknown is what players see in place of a role they aren't meant to
+// know yet.
+const roleUnknown = "UNKNOWN"
+
+// hidesRoles reports whether roles are still secret: from the moment they
+// are dealt until the reveal in GAME_ENDED. Runs on the room goroutine.
+func (r *Room) hidesRoles() bool {
+	phase := r.gameState.Phase
+	return phase != PhaseLobby && phase != PhaseEnd
+}
+
+// carriesRoles is a cheap check for messages that may need redacting, so
+// chat and the like go out without being decoded.
+func carriesRoles(message []byte) bool {
+	return bytes.Contains(message, []byte(`"role"`)) || bytes.Contains(message, []byte(`"imposterID`))
+}
+
+// redactRolesFor returns message as viewerID may see it while roles are
+// secret. Impostors know who everyone is, except that a detective or jester
+// reads CIVILIAN to them; anyone else only keeps their own role, with the
+// rest reading UNKNOWN and impostor IDs dropped. Runs on the room goroutine.
+func (r *Room) redactRolesFor(viewerID string, message []byte) []byte {
+	if !r.hidesRoles() || !carriesRoles(message) {
+		return message
+	}
+	impostor := r.gameState.isImpostor(viewerID)
+	if impostor && !r.hasSpecialRoles() {
+		return message
+	}
+
+	var msg map[string]interface{}
+	if err := json.Unmarshal(message, &msg); err != nil {
+		return message
+	}
+	if msg["type"] == "GAME_ENDED" {
+		return message
+	}
+
+	msg["data"] = maskRoles(msg["data"], viewerID, impostor)
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return message
+	}
+	return data
+}
+
+func maskRoles(v interface{}, viewerID string, impostor bool) interface{} {
+	switch value := v.(type) {
+	case map[string]interface{}:
+		if !impostor {
+			delete(value, "imposterID")
+			delete(value, "imposterIDs")
+		}
+		if role, ok := value["role"]; ok && role != "" && value["id"] != viewerID {
+			switch {
+			case !impostor:
+				value["role"] = roleUnknown
+			case role != "IMPOSTER":
+				value["role"] = "CIVILIAN"
+			}
+		}
+		for key, child := range value {
+			value[key] = maskRoles(child, viewerID, impostor)
+		}
+	case []interface{}:
+		for i, child := range value {
+			value[i] = maskRoles(child, viewerID, impostor)
+		}
+	}
+	return v
+}
+
+// hasSpecialRoles reports whether anyone was dealt a detective or jester.
+func (r *Room) hasSpecialRoles() bool {
+	for _, player := range r.players {
+		if player.Role == "DETECTIVE" || player.Role == "JESTER" {
+			return true
+		}
+	}
+	return false
+}
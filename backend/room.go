@@ -1,24 +1,38 @@
 package main
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
 	"math/rand"
 	"net/http"
 	"regexp"
+	"slices"
+	"sort"
 	"strings"
 	"sync"
 	"time"
 
+	"code-mafia-backend/config"
 	"code-mafia-backend/database"
+	"code-mafia-backend/notify"
+	"code-mafia-backend/sandbox"
+	"code-mafia-backend/webhooks"
 
-	"github.com/google/uuid"
 	"github.com/gorilla/websocket"
 )
 
 type GamePhase string
 
+const minPlayersToStart = 3
+
+// playersPerImpostor sizes the impostor team: one impostor for every this
+// many players, always leaving the crew outnumbering them.
+const playersPerImpostor = 5
+
 const (
 	PhaseLobby      GamePhase = "LOBBY"
 	PhaseRoleReveal GamePhase = "ROLE_REVEAL"
@@ -36,6 +50,20 @@ type Player struct {
 	IsHost       bool   `json:"isHost"`
 	IsEliminated bool   `json:"isEliminated"`
 	IsAlive      bool   `json:"isAlive"`
+
+	// Disconnected is set while a player who dropped mid-game has their
+	// seat held for them.
+	Disconnected bool `json:"disconnected,omitempty"`
+
+	// Language is the locale the player reads chat in, from JOIN; blank
+	// means English. See deliverChat.
+	Language string `json:"language,omitempty"`
+
+	// Muted shows the host has muted the player; GameState.MutedIDs is
+	// what counts. See muteByHost.
+	Muted bool `json:"muted,omitempty"`
+
+	Cosmetics *Cosmetics `json:"cosmetics,omitempty"`
 }
 
 type Task struct {
@@ -44,27 +72,119 @@ type Task struct {
 	Description    string `json:"description"`
 	Template       string `json:"template"`
 	Title          string `json:"title"`
+	// Tests are the unit tests a submission must pass; see taskTests.
+	Tests          string `json:"-"`
+	// Language is what the template and tests are written in.
+	Language       string `json:"language,omitempty"`
+	// Category and Difficulty are what hosts pick tasks by; see
+	// taskCategories and taskDifficulties.
+	Category       string `json:"category,omitempty"`
+	Difficulty     string `json:"difficulty,omitempty"`
+	// Checks are what the heuristic judge looks for in place of the
+	// built-in per-stage checks; see tasklibrary.go.
+	Checks         *taskChecks `json:"-"`
 	TitleTranslations       map[string]string `json:"titleTranslations,omitempty"`
 	DescriptionTranslations map[string]string `json:"descriptionTranslations,omitempty"`
 }
 
 type GameState struct {
+	// SchemaVersion is the roomStateVersion the state was saved under.
+	SchemaVersion int `json:"schemaVersion"`
+	// Revision goes up by one with every save, so a save can tell whether
+	// anything else wrote the room since this one last did.
+	Revision int64 `json:"revision"`
+
 	Phase         GamePhase    `json:"phase"`
 	CurrentStage  int          `json:"currentStage"`
-	TimerSeconds  int          `json:"timerSeconds"`
-	ImposterID    string       `json:"imposterID"`
+	ImposterIDs   []string     `json:"imposterIDs"`
 	TasksComplete map[int]bool `json:"tasksComplete"`
-	TimerPaused   bool         `json:"timerPaused"`
 	GameStartTime time.Time    `json:"gameStartTime"`
+
+	// The game clock runs out at TimerDeadline. While TimerPaused the
+	// clock is stopped at TimerPausedAt, and resuming pushes the deadline
+	// back by however long it was paused.
+	TimerDeadline time.Time `json:"timerDeadline"`
+	TimerPaused   bool      `json:"timerPaused"`
+	TimerPausedAt time.Time `json:"timerPausedAt"`
+
+	// TaskIDs and SeasonID pin the game to what it started with, so a
+	// restart or a season rollover doesn't change its tasks.
+	TaskIDs  []string `json:"taskIds,omitempty"`
+	SeasonID string   `json:"seasonId,omitempty"`
+
+	Settings Settings `json:"settings"`
+
+	// A meeting or sabotage under way, copied from the room on every save
+	// so a restart picks it up where it was.
+	Votes          map[string]string `json:"votes,omitempty"`
+	VotingDeadline time.Time         `json:"votingDeadline,omitempty"`
+	SabotageType   string            `json:"sabotageType,omitempty"`
+	SabotageEndsAt time.Time         `json:"sabotageEndsAt,omitempty"`
+	LastSabotageAt time.Time         `json:"lastSabotageAt,omitempty"`
+	SwappedIDs     []string          `json:"swappedIds,omitempty"`
+	RepairedIDs    []string          `json:"repairedIds,omitempty"`
+	CorruptMarkers []string          `json:"corruptMarkers,omitempty"`
+
+	// What each player has used of their per-game allowances, and when
+	// the last meeting ended, so a restart doesn't hand them out again.
+	TimerDrains        map[string]int `json:"timerDrains,omitempty"`
+	EmergencyCalls     map[string]int `json:"emergencyCalls,omitempty"`
+	LastMeetingEndedAt time.Time      `json:"lastMeetingEndedAt,omitempty"`
+
+	// RevoteCandidates are the players a tied meeting is voting between
+	// again; nil outside a revote.
+	RevoteCandidates []string `json:"revoteCandidates,omitempty"`
+
+	// Ejected is the player a meeting voted out while the ejection plays
+	// out, and Meetings the meetings held so far this game, so a restart
+	// neither loses a verdict nor the match summary's record of it.
+	Ejected  string                    `json:"ejected,omitempty"`
+	Meetings []database.SummaryMeeting `json:"meetings,omitempty"`
+
+	// TestRun is the RUN_TESTS the room is waiting on, so a restart runs
+	// it again instead of leaving the tests locked.
+	TestRun *SavedTestRun `json:"testRun,omitempty"`
+
+	// Assignments are each player's task ID in a personal-tasks game, and
+	// TasksDone the players who have passed theirs; only the crew's count.
+	// See personaltasks.go.
+	Assignments map[string]string `json:"assignments,omitempty"`
+	TasksDone   map[string]bool   `json:"tasksDone,omitempty"`
+
+	// FailedRuns counts each player's failed test runs and DetectiveQueried
+	// whether the detective has asked about them yet. See specialroles.go.
+	FailedRuns       map[string]int `json:"failedRuns,omitempty"`
+	DetectiveQueried bool           `json:"detectiveQueried,omitempty"`
+
+	// MutedIDs are the players the host has muted, kept here rather than
+	// on the player so it holds when they leave the lobby and come back.
+	MutedIDs map[string]bool `json:"mutedIds,omitempty"`
 }
 
+// Room is an actor: run is the only goroutine that touches its state.
+// Everything else hands it work with post or do, and delays are scheduled
+// as commands rather than slept through, so nothing here takes a lock.
 type Room struct {
 	ID         string
+	// hub is who to hand the room back to once it is finished with.
+	hub        *Hub
 	clients    map[*Client]bool
 	players    map[string]*Player
-	broadcast  chan []byte
-	mu         sync.RWMutex
-	yjsClients map[*websocket.Conn]*sync.Mutex
+	yjsClients map[*websocket.Conn]*yjsPeer
+	// yjsRemote are editor connections relayed from other instances, by
+	// connection ID.
+	yjsRemote map[string]*yjsPeer
+
+	commands chan func()
+	stopped  chan struct{}
+	stopOnce sync.Once
+
+	// game counts games played in the room, so commands scheduled for one
+	// game never run in the next.
+	game int
+	// cleanup deletes the room from Redis a while after a game ends,
+	// unless a rematch starts first.
+	cleanup *time.Timer
 
 	gameState GameState
 	tasks     []*Task
@@ -73,23 +193,125 @@ type Room struct {
 	testRunner     string
 	testRunnerName string
 	codeSnapshot   string
-
-	votes        map[string]string
-	votingActive bool
-	votingTimer  *time.Timer
-
-	timerCancel     chan struct{}
-	timerDone       chan struct{}
-	timerCancelOnce sync.Once
+	// testRun numbers RUN_TESTS requests so a cancelled run's late result
+	// is dropped.
+	testRun int
+
+	votes          map[string]string
+	votingActive   bool
+	votingDeadline time.Time
+
+	// emergencyCalls counts the meetings each player has called this
+	// game. Meetings can't be called for a while after a stage starts or
+	// the last meeting ends; see takeEmergency.
+	emergencyCalls   map[string]int
+	stageStartedAt   time.Time
+	lastMeetingEnded time.Time
+
+	// Per-game vote record for player stats: non-skip votes each player
+	// cast and how many of them named the impostor.
+	votesCast    map[string]int
+	correctVotes map[string]int
+
+	// The rest of what goes into each player's score: test runs started,
+	// stages or personal tasks their run passed, and sabotages they were
+	// still aboard at the end of. See scores.go.
+	testsRun          map[string]int
+	stagesPassed      map[string]int
+	sabotagesSurvived map[string]int
+
+	// For match history: sabotages each impostor set off, and seconds into
+	// the game each eliminated player went out.
+	sabotagesUsed map[string]int
+	eliminatedAt  map[string]int
+
+	// editBytes is how much editing each player did. See editshare.go.
+	editBytes map[string]int
+
+	// What the shareable post-game summary is built from: seconds into the
+	// game each stage was finished, the code that passed it, and each vote.
+	stageTimes map[int]int
+	passedCode map[int]string
+	meetings   []database.SummaryMeeting
+
+	// assignedTasks are the tasks named by gameState.Assignments.
+	assignedTasks map[string]*Task
+
+	// clock fires when the game timer's deadline passes.
+	clock *time.Timer
 
 	sabotageActive      bool
 	sabotageType        string
 	sabotageEndTime     time.Time
-	corruptedCode       string
-	freezeTimer         *time.Timer
+	// corruptMarkers are the lines CORRUPT sabotages have put in the code
+	// that nobody has taken out yet. Tests won't run while one is left.
+	corruptMarkers      []string
+	sabotageTimer       *time.Timer
+	swappedIDs          []string
+	// repairedIDs are the crewmates who have sent REPAIR during a CRITICAL
+	// sabotage.
+	repairedIDs         []string
+	// timerDrains counts the TIMER_DRAIN sabotages each impostor has used
+	// this game.
+	timerDrains         map[string]int
 	lastSabotageTime    time.Time
 	sabotageCooldownSec int
-	tasksTranslated bool
+	// translationsAsked are the tasks sent for translation this game, by
+	// ID; see translateStageTasks.
+	translationsAsked map[string]bool
+	// translationFailures counts the sidecar's errors for each chat line
+	// ("chat:<messageId>") or task field ("task:<taskId>.<field>") being
+	// retried; see retryTranslation.
+	translationFailures map[string]int
+
+	mutedUntil map[string]time.Time
+	// lastChat is when each player last spoke in a meeting, for slow mode.
+	lastChat map[string]time.Time
+
+	// chatPending are chat lines waiting for their translations, by
+	// message ID. See chat.go.
+	chatPending map[string]*chatLine
+
+	// reconnects are the pending drops of players holding a seat after
+	// losing their connection mid-game.
+	reconnects map[string]*time.Timer
+
+	cheats cheatWatch
+
+	ranked bool
+
+	// savedPlayers is each player as last written to Redis, so saves can
+	// skip the ones that haven't changed.
+	savedPlayers map[string][]byte
+	// revision is the Revision of the state as last saved or loaded, and
+	// savedState that state without it; see resolveSaveConflict.
+	revision   int64
+	savedState []byte
+
+	// listed is set while the room may be in the public lobby index. It
+	// starts set so a room restored from Redis clears any stale entry.
+	listed bool
+
+	// bots are the practice bots added to the room, by name, with when
+	// they were sent in. See practice.go.
+	bots map[string]time.Time
+
+	// hints are the hints given this game, by hintKey, and pendingHint the
+	// one the hint service is working on. See hints.go.
+	hints       map[string][]string
+	pendingHint *hintRequest
+
+	// typing are the players shown as typing in a meeting, with when
+	// that runs out. See typing.go.
+	typing map[string]*time.Timer
+
+	challenge *DailyChallenge
+
+	spectators     map[*spectator]bool
+	// observers are connections watching with ?spectate=1. See
+	// observers.go.
+	observers map[*Client]bool
+	spectatorDelay time.Duration
 }
 
 func newRoom(id string) *Room {
@@ -97,23 +319,52 @@ func newRoom(id string) *Room {
 		ID:         id,
 		clients:    make(map[*Client]bool),
 		players:    make(map[string]*Player),
-		broadcast:  make(chan []byte, 256),
-		yjsClients: make(map[*websocket.Conn]*sync.Mutex),
+		yjsClients: make(map[*websocket.Conn]*yjsPeer),
+		yjsRemote:  make(map[string]*yjsPeer),
+		commands:   make(chan func(), 256),
+		stopped:    make(chan struct{}),
 		gameState: GameState{
 			Phase:         PhaseLobby,
 			CurrentStage:  0,
-			TimerSeconds:  120,
 			TasksComplete: make(map[int]bool),
 			TimerPaused:   false,
 		},
 		testRunning:         false,
 		votes:               make(map[string]string),
+		emergencyCalls:      make(map[string]int),
+		timerDrains:         make(map[string]int),
+		votesCast:           make(map[string]int),
+		correctVotes:        make(map[string]int),
+		testsRun:            make(map[string]int),
+		stagesPassed:        make(map[string]int),
+		sabotagesSurvived:   make(map[string]int),
+		sabotagesUsed:       make(map[string]int),
+		eliminatedAt:        make(map[string]int),
+		editBytes:           make(map[string]int),
+		stageTimes:          make(map[int]int),
+		passedCode:          make(map[int]string),
+		spectators:          make(map[*spectator]bool),
+		observers:           make(map[*Client]bool),
+		spectatorDelay:      time.Duration(config.AppConfig.SpectatorDelaySec) * time.Second,
 		votingActive:        false,
-		timerCancel:         make(chan struct{}),
-		timerDone:           make(chan struct{}),
 		sabotageActive:      false,
-		sabotageCooldownSec: 10,
-		tasksTranslated:     false,
+		sabotageCooldownSec: config.Current().SabotageCooldownSec,
+		translationsAsked:   make(map[string]bool),
+		translationFailures: make(map[string]int),
+		mutedUntil:          make(map[string]time.Time),
+		lastChat:            make(map[string]time.Time),
+		chatPending:         make(map[string]*chatLine),
+		reconnects:          make(map[string]*time.Timer),
+		savedPlayers:        make(map[string][]byte),
+		listed:              true,
+		bots:                make(map[string]time.Time),
+		hints:               make(map[string][]string),
+		typing:              make(map[string]*time.Timer),
+	}
+
+	if isDailyRoom(id) {
+		challenge := dailyChallengeFor(time.Now())
+		room.challenge = &challenge
 	}
 
 	room.loadFromRedis()
@@ -122,9 +373,16 @@ func newRoom(id string) *Room {
 }
 
 func (r *Room) loadFromRedis() {
-	err := database.LoadGameState(r.ID, &r.gameState)
+	data, err := database.LoadGameStateJSON(r.ID)
 	if err == nil {
+		err = loadGameState(data, &r.gameState)
+	}
+	if err == nil {
+		r.revision = r.gameState.Revision
+		r.savedState = gameContent(r.gameState)
 		log.Printf("Loaded game state from Redis for room %s (Phase: %s)", r.ID, r.gameState.Phase)
+	} else if data != nil {
+		log.Printf("Failed to load game state for room %s: %v", r.ID, err)
 	}
 
 	playersData, err := database.LoadAllPlayers(r.ID)
@@ -133,91 +391,327 @@ func (r *Room) loadFromRedis() {
 			var player Player
 			if err := json.Unmarshal([]byte(playerJSON), &player); err == nil {
 				r.players[playerID] = &player
+				r.savedPlayers[playerID] = []byte(playerJSON)
 				log.Printf("Loaded player %s from Redis", player.Username)
 			}
 		}
 	}
 
 	if r.gameState.Phase != PhaseLobby {
-		r.tasks = r.loadAllTasks()
-		if !r.tasksTranslated {
-			go r.requestTaskTranslations()
-		}
+		r.replayEvents()
+	}
+
+	if r.gameState.Phase != PhaseLobby {
+		r.tasks = restoreTasks(r.gameState.TaskIDs)
+		r.assignedTasks = restoreAssignments(r.gameState.Assignments)
+		r.translateStageTasks(r.gameState.CurrentStage)
 	}
 
 	if r.gameState.Phase >= PhaseTask1 && r.gameState.Phase <= PhaseTask3 {
 		r.resumeTimerFromRedis()
 	}
+
+	if r.gameState.Phase != PhaseLobby && r.gameState.Phase != PhaseEnd {
+		r.resumeMeetingAndSabotage()
+		r.resumeStageChange()
+		r.resumeTestRun()
+	}
 }
 
-func (r *Room) saveToRedis() {
-	err := database.SaveGameState(r.ID, r.gameState)
-	if err != nil {
-		log.Printf("Failed to save game state to Redis: %v", err)
+// resumeMeetingAndSabotage restores what saveToRedis copied into the game
+// state. A vote whose deadline passed while the server was down is tallied
+// straight away; one already tallied has its ejection played out, or the
+// game goes back to work. Sabotage deadlines are absolute, like the
+// clock's: one that ran out while the server was down ends as soon as it
+// is back, and an unrepaired meltdown melts down.
+func (r *Room) resumeMeetingAndSabotage() {
+	g := &r.gameState
+	r.sabotageCooldownSec = r.sabotageCooldown()
+	r.lastSabotageTime = g.LastSabotageAt
+	r.corruptMarkers = g.CorruptMarkers
+	r.meetings = g.Meetings
+	r.lastMeetingEnded = g.LastMeetingEndedAt
+	if g.TimerDrains != nil {
+		r.timerDrains = g.TimerDrains
+	}
+	if g.EmergencyCalls != nil {
+		r.emergencyCalls = g.EmergencyCalls
+	}
+
+	if g.Phase == PhaseDiscussion && g.VotingDeadline.IsZero() {
+		if ejected := g.Ejected; ejected != "" {
+			log.Printf("Resuming the ejection of %s in room %s", ejected, r.ID)
+			r.schedule(time.Second, func() { r.playOutEjection(ejected) })
+		} else {
+			log.Printf("Meeting in room %s was over - resuming the game", r.ID)
+			r.schedule(time.Second, r.resumeGameAfterVoting)
+		}
 	}
+	if g.Phase == PhaseDiscussion && !g.VotingDeadline.IsZero() {
+		r.votingActive = true
+		r.votingDeadline = g.VotingDeadline
+		if g.Votes != nil {
+			r.votes = g.Votes
+		}
+		seconds := int(time.Until(g.VotingDeadline).Seconds())
+		if seconds < 0 {
+			seconds = 0
+		}
+		log.Printf("Resuming vote in room %s with %ds left (%d votes in)", r.ID, seconds, len(r.votes))
+		r.votingCountdown(len(r.meetings), seconds)
+	}
+
+	if g.SabotageType == "CRITICAL" {
+		r.sabotageActive = true
+		r.sabotageType = g.SabotageType
+		r.sabotageEndTime = g.SabotageEndsAt
+		r.repairedIDs = g.RepairedIDs
+		r.armCritical()
+	}
+	if g.SabotageType == "FREEZE" || g.SabotageType == "SWAP" {
+		r.sabotageActive = true
+		r.sabotageType = g.SabotageType
+		r.sabotageEndTime = g.SabotageEndsAt
+		end := r.endFreeze
+		if g.SabotageType == "SWAP" {
+			r.swappedIDs = g.SwappedIDs
+			end = r.endSwap
+		}
+		r.sabotageTimer = r.schedule(time.Until(g.SabotageEndsAt), end)
+	}
+}
 
-	for _, player := range r.players {
-		err := database.SavePlayer(r.ID, player)
+func (r *Room) saveToRedis() {
+	r.gameState.Votes = nil
+	r.gameState.VotingDeadline = time.Time{}
+	if r.votingActive {
+		r.gameState.Votes = r.votes
+		r.gameState.VotingDeadline = r.votingDeadline
+	}
+	r.gameState.SabotageType = ""
+	r.gameState.SabotageEndsAt = time.Time{}
+	r.gameState.SwappedIDs = nil
+	r.gameState.RepairedIDs = nil
+	if r.sabotageActive {
+		r.gameState.SabotageType = r.sabotageType
+		r.gameState.SabotageEndsAt = r.sabotageEndTime
+		r.gameState.SwappedIDs = r.swappedIDs
+		r.gameState.RepairedIDs = r.repairedIDs
+	}
+	r.gameState.LastSabotageAt = r.lastSabotageTime
+	r.gameState.CorruptMarkers = r.corruptMarkers
+	r.gameState.Meetings = r.meetings
+	r.gameState.TimerDrains = r.timerDrains
+	r.gameState.EmergencyCalls = r.emergencyCalls
+	r.gameState.LastMeetingEndedAt = r.lastMeetingEnded
+	r.gameState.TestRun = r.savedTestRun()
+	r.gameState.SchemaVersion = roomStateVersion
+
+	// Only players who changed since the last save are written.
+	changed := make(map[string][]byte)
+	for id, player := range r.players {
+		data, err := json.Marshal(player)
 		if err != nil {
-			log.Printf("Failed to save player %s: %v", player.Username, err)
+			log.Printf("Failed to marshal player %s: %v", player.Username, err)
+			continue
+		}
+		if !bytes.Equal(data, r.savedPlayers[id]) {
+			changed[id] = data
 		}
 	}
-}
 
-func (r *Room) resumeTimerFromRedis() {
-	startTime, err := database.LoadTimerStart(r.ID)
+	started := time.Now()
+	err := r.saveRevision(changed)
+	if errors.Is(err, database.ErrStaleRoomState) {
+		err = r.resolveSaveConflict(err, changed)
+	}
+	elapsed := time.Since(started)
+	incMetric("room_saves_total")
+	addMetric("room_save_micros_total", elapsed.Microseconds())
+	setMetric("room_save_micros_last", elapsed.Microseconds())
+
 	if err != nil {
-		log.Printf("No timer start time found, starting fresh")
-		r.startGlobalTimer()
-		return
+		log.Printf("Failed to save room %s to Redis: %v", r.ID, err)
+	} else {
+		for id, data := range changed {
+			r.savedPlayers[id] = data
+		}
 	}
 
-	elapsed := time.Since(startTime).Seconds()
-	remaining := 120 - int(elapsed)
+	r.syncListing()
+}
 
-	if remaining > 0 {
-		r.gameState.TimerSeconds = remaining
-		log.Printf("Resuming timer with %d seconds remaining", remaining)
-		r.startGlobalTimer()
+// resumeTimerFromRedis picks the clock back up after a restart. The
+// deadline is absolute, so time spent down counts against the crew exactly.
+func (r *Room) resumeTimerFromRedis() {
+	if r.gameState.TimerDeadline.IsZero() {
+		log.Printf("No timer deadline found, starting fresh")
 	} else {
-		log.Printf("Timer expired during downtime - ending game")
-		r.endGame("IMPOSTER_WIN_TIMEOUT")
+		log.Printf("Resuming timer with %v remaining", r.gameState.timerRemaining(time.Now()).Round(time.Second))
 	}
+	r.startGlobalTimer()
 }
 
 func (r *Room) run() {
 	for {
 		select {
-		case message := <-r.broadcast:
-			r.mu.RLock()
-			for client := range r.clients {
-				select {
-				case client.send <- message:
-				default:
-					close(client.send)
-					delete(r.clients, client)
-				}
+		case cmd := <-r.commands:
+			r.exec(cmd)
+		case <-r.stopped:
+			return
+		}
+	}
+}
+
+func (r *Room) exec(cmd func()) {
+	defer recoverPanic("room " + r.ID)
+	cmd()
+}
+
+// post queues fn to run on the room goroutine and returns without waiting.
+// It reports false if the room has already shut down.
+func (r *Room) post(fn func()) bool {
+	select {
+	case r.commands <- fn:
+		return true
+	case <-r.stopped:
+		return false
+	}
+}
+
+// do runs fn on the room goroutine and waits for it, for callers that need
+// an answer. Calling it from the room goroutine deadlocks.
+func (r *Room) do(fn func()) bool {
+	done := make(chan struct{})
+	if !r.post(func() {
+		defer close(done)
+		fn()
+	}) {
+		return false
+	}
+	select {
+	case <-done:
+		return true
+	case <-r.stopped:
+		return false
+	}
+}
+
+// schedule runs fn on the room goroutine after d, unless the game it was
+// scheduled in has ended by then.
+func (r *Room) schedule(d time.Duration, fn func()) *time.Timer {
+	game := r.game
+	return time.AfterFunc(d, func() {
+		r.post(func() {
+			if r.game == game && r.gameState.Phase != PhaseEnd {
+				fn()
 			}
-			r.mu.RUnlock()
+		})
+	})
+}
+
+func (r *Room) stop() {
+	r.stopOnce.Do(func() { close(r.stopped) })
+}
+
+// close shuts the room down for good: anything still scheduled is
+// cancelled, every game, editor and spectator connection is hung up, after
+// notice if there is one, and run returns. Runs on the room goroutine.
+func (r *Room) close(notice *Message) {
+	r.game++
+	if r.clock != nil {
+		r.clock.Stop()
+		r.clock = nil
+	}
+	if r.sabotageTimer != nil {
+		r.sabotageTimer.Stop()
+		r.sabotageTimer = nil
+	}
+	for id, timer := range r.reconnects {
+		timer.Stop()
+		delete(r.reconnects, id)
+	}
 
-			time.Sleep(5 * time.Millisecond)
+	for client := range r.clients {
+		delete(r.clients, client)
+		if notice != nil {
+			client.refuse(*notice)
+		} else {
+			client.close()
 		}
 	}
+	for client := range r.observers {
+		delete(r.observers, client)
+		if notice != nil {
+			client.refuse(*notice)
+		} else {
+			client.close()
+		}
+	}
+	for conn := range r.yjsClients {
+		delete(r.yjsClients, conn)
+		conn.Close()
+	}
+	for connID := range r.yjsRemote {
+		delete(r.yjsRemote, connID)
+	}
+	r.closeSpectators()
+	forgetSampling(r.ID)
+
+	r.stop()
 }
 
-func (r *Room) addPlayer(playerID, username string) {
-	r.mu.Lock()
-	defer r.mu.Unlock()
+// phase is for other goroutines that need to know how far along the room
+// is; it returns "" once the room has shut down.
+func (r *Room) phase() GamePhase {
+	var phase GamePhase
+	r.do(func() { phase = r.gameState.Phase })
+	return phase
+}
+
+// emit sends an encoded message to everyone in the room, redacted to its
+// observers and, redacted and delayed, to its spectators. Clients too far
+// behind to take it are dealt with by SLOW_CLIENT_POLICY.
+func (r *Room) emit(message []byte) {
+	sampledf("broadcast", r.ID, "📡 Room %s broadcast %d bytes to %d clients", r.ID, len(message), len(r.clients))
+	secret := r.hidesRoles() && carriesRoles(message)
+	relayed := false
+	for client := range r.clients {
+		out := message
+		if secret {
+			// Each player gets their own copy, so relayed players can't
+			// share the fan-out either.
+			out = r.redactRolesFor(client.PlayerID, message)
+		} else if client.remote != "" {
+			// Players on other instances get one copy through the fan-out.
+			relayed = true
+			continue
+		}
+		client.enqueue(out)
+	}
+	if relayed {
+		publishFanout(r.ID, relayEnvelope{Kind: relayBroadcast, Data: message})
+	}
+
+	if len(r.spectators) > 0 {
+		redacted := redactForSpectators(message)
+		for s := range r.spectators {
+			r.enqueueSpectator(s, redacted)
+		}
+	}
+	r.emitToObservers(message)
 
-	if existingPlayer, exists := r.players[playerID]; exists {
+	r.recordChat(message)
+}
+
+func (r *Room) addPlayer(playerID, username string) {
+	if _, exists := r.players[playerID]; exists {
 		log.Printf("Player %s reconnected to room %s", username, r.ID)
-		existingPlayer.IsAlive = true
-		existingPlayer.IsEliminated = false
-		r.saveToRedis()
 		return
 	}
 
 	isHost := len(r.players) == 0
+	username = r.uniqueUsername(playerID, username)
 
 	r.players[playerID] = &Player{
 		ID:           playerID,
@@ -225,20 +719,34 @@ func (r *Room) addPlayer(playerID, username string) {
 		IsHost:       isHost,
 		IsEliminated: false,
 		IsAlive:      true,
+		Muted:        r.gameState.MutedIDs[playerID],
 	}
 
 	log.Printf("Player %s (%s) added to room %s (host: %v)", username, playerID, r.ID, isHost)
 
+	r.audit("JOIN", playerID, username, map[string]interface{}{"host": isHost})
+	r.logEvent(gameEventJoin, playerID, username, map[string]interface{}{"host": isHost})
+
+	// Tell a host who has wandered off that enough players have arrived.
+	if len(r.players) == r.minPlayers() && r.gameState.Phase == PhaseLobby {
+		for id, p := range r.players {
+			if p.IsHost {
+				notifyUsers([]string{id}, notify.Notification{
+					Title: "Your lobby is ready",
+					Body:  fmt.Sprintf("%d players are waiting in room %s", len(r.players), r.ID),
+					Data:  map[string]string{"roomId": r.ID},
+				})
+			}
+		}
+	}
+
 	r.saveToRedis()
 }
 
 func (r *Room) startGame() {
 	log.Printf("[1/10] startGame() CALLED for room %s", r.ID)
 
-	r.mu.Lock()
-
 	if r.gameState.Phase != PhaseLobby {
-		r.mu.Unlock()
 		log.Printf("[ABORT] Game already started in phase %s", r.gameState.Phase)
 		return
 	}
@@ -246,25 +754,47 @@ func (r *Room) startGame() {
 	playerCount := len(r.players)
 	log.Printf("[2/10] Player count: %d", playerCount)
 
-	if playerCount < 3 {
-		r.mu.Unlock()
-		log.Printf("[ABORT] Not enough players to start (need 3, have %d)", playerCount)
+	if playerCount < r.minPlayers() {
+		log.Printf("[ABORT] Not enough players to start (need %d, have %d)", r.minPlayers(), playerCount)
 		return
 	}
 
 	r.sabotageActive = false
 	r.sabotageType = ""
+	r.sabotageCooldownSec = r.sabotageCooldown()
 	r.lastSabotageTime = time.Time{}
-	if r.freezeTimer != nil {
-		r.freezeTimer.Stop()
-		r.freezeTimer = nil
+	if r.sabotageTimer != nil {
+		r.sabotageTimer.Stop()
+		r.sabotageTimer = nil
 	}
+	r.swappedIDs = nil
+	r.repairedIDs = nil
+	r.corruptMarkers = nil
 
-	r.timerCancel = make(chan struct{})
-	r.timerDone = make(chan struct{})
-	r.timerCancelOnce = sync.Once{}
-
-	log.Printf("[3/10] Selecting random imposter...")
+	r.game++
+	r.gameState.TimerDeadline = time.Time{}
+	r.gameState.TimerPaused = false
+	r.gameState.TimerPausedAt = time.Time{}
+	r.votesCast = make(map[string]int)
+	r.correctVotes = make(map[string]int)
+	r.testsRun = make(map[string]int)
+	r.stagesPassed = make(map[string]int)
+	r.sabotagesSurvived = make(map[string]int)
+	r.sabotagesUsed = make(map[string]int)
+	r.eliminatedAt = make(map[string]int)
+	r.editBytes = make(map[string]int)
+	r.stageTimes = make(map[int]int)
+	r.passedCode = make(map[int]string)
+	r.meetings = nil
+	r.emergencyCalls = make(map[string]int)
+	r.lastMeetingEnded = time.Time{}
+	r.timerDrains = make(map[string]int)
+	r.hints = make(map[string][]string)
+	r.dropPendingHint()
+	r.cheats.reset()
+
+	impostorCount := r.impostorCount(playerCount)
+	log.Printf("[3/10] Selecting %d random imposters...", impostorCount)
 
 	playerIDs := make([]string, 0, len(r.players))
 	for id := range r.players {
@@ -272,13 +802,15 @@ func (r *Room) startGame() {
 	}
 
 	rand.Seed(time.Now().UnixNano())
-	imposterIdx := rand.Intn(len(playerIDs))
-	r.gameState.ImposterID = playerIDs[imposterIdx]
+	rand.Shuffle(len(playerIDs), func(i, j int) {
+		playerIDs[i], playerIDs[j] = playerIDs[j], playerIDs[i]
+	})
+	r.gameState.ImposterIDs = playerIDs[:impostorCount]
 
-	log.Printf("[4/10] Imposter selected: %s", r.gameState.ImposterID)
+	log.Printf("[4/10] Imposters selected: %v", r.gameState.ImposterIDs)
 
 	for id, player := range r.players {
-		if id == r.gameState.ImposterID {
+		if r.gameState.isImpostor(id) {
 			player.Role = "IMPOSTER"
 			log.Printf("%s is IMPOSTER", player.Username)
 		} else {
@@ -286,152 +818,181 @@ func (r *Room) startGame() {
 			log.Printf("%s is CIVILIAN", player.Username)
 		}
 	}
+	r.dealSpecialRoles(playerIDs[impostorCount:])
 
 	log.Printf("[5/10] Loading tasks...")
 
-	r.tasks = r.loadAllTasks()
-
-	log.Printf("[6/10] Tasks loaded: %d tasks", len(r.tasks))
+	r.tasks = currentTaskPool()
 
 	r.gameState.Phase = PhaseRoleReveal
 	r.gameState.CurrentStage = 0
-	r.gameState.TimerSeconds = 120
 	r.gameState.TasksComplete = make(map[int]bool)
 	r.gameState.GameStartTime = time.Now()
+	r.gameState.Assignments = nil
+	r.gameState.TasksDone = nil
+	r.gameState.FailedRuns = nil
+	r.gameState.DetectiveQueried = false
+	r.assignedTasks = nil
+	r.applyChallenge()
+	if r.gameState.Settings.PersonalTasks {
+		r.assignPersonalTasks(r.tasks)
+	}
+	r.tasks = pickStageTasks(r.tasks, r.gameState.Settings.taskPreferences()...)
+	r.gameState.TaskIDs = taskIDs(r.tasks)
+	r.gameState.SeasonID = seasonIDOf(currentSeason())
+
+	log.Printf("[6/10] Tasks loaded: %v (season %q)", r.gameState.TaskIDs, r.gameState.SeasonID)
 
 	log.Printf("[7/10] Game state initialized - Phase: %s", r.gameState.Phase)
 
+	r.audit("GAME_START", "", "", map[string]interface{}{
+		"players":     playerCount,
+		"imposterIDs": r.gameState.ImposterIDs,
+	})
+	r.emitEvent(webhooks.EventGameStarted, map[string]interface{}{
+		"players": r.playerNames(),
+	})
+	r.logEvent(gameEventStart, "", "", map[string]interface{}{
+		"players":     r.playerNames(),
+		"imposterIDs": r.gameState.ImposterIDs,
+		"taskIDs":     r.gameState.TaskIDs,
+	})
+
 	r.saveToRedis()
 
-	r.mu.Unlock()
-	go r.requestTaskTranslations()
+	r.translateStageTasks(1)
 	log.Printf("[8/10] Broadcasting ROLE_REVEAL state to all clients...")
 
 	r.broadcastGameState()
+	r.revealImpostorTeam()
 
 	log.Printf("startGame() COMPLETED - Starting 5-second role reveal timer")
 
-	go func() {
-		log.Printf("[Goroutine] Waiting 5 seconds for role reveal...")
-		time.Sleep(5 * time.Second)
-
-		log.Printf("[Goroutine] Role reveal complete - Transitioning to TASK_1")
+	r.schedule(5*time.Second, func() {
+		if r.gameState.Phase == PhaseRoleReveal {
+			r.beginTasks()
+		}
+	})
+}
 
-		r.mu.Lock()
-		r.gameState.Phase = PhaseTask1
-		r.gameState.CurrentStage = 1
-		r.saveToRedis()
-		r.mu.Unlock()
+// beginTasks ends the role reveal and starts the clock on stage 1.
+func (r *Room) beginTasks() {
+	log.Printf("Role reveal complete - Transitioning to TASK_1")
 
-		log.Printf("[Goroutine] Broadcasting TASK_1 state...")
-		r.broadcastGameState()
+	r.gameState.Phase = PhaseTask1
+	r.gameState.CurrentStage = 1
+	r.stageStartedAt = time.Now()
+	r.saveToRedis()
 
-		log.Printf("[Goroutine] Starting global timer...")
-		r.startGlobalTimer()
+	log.Printf("Starting global timer...")
+	r.startGlobalTimer()
 
-		log.Printf("[Goroutine] Timer started successfully")
-	}()
+	log.Printf("Broadcasting TASK_1 state...")
+	r.broadcastGameState()
 }
 
-func (r *Room) requestTaskTranslations() {
-	log.Printf("🌐 Requesting translations for %d tasks", len(r.tasks))
-	ctx := context.Background()
-	
-	for _, task := range r.tasks {
-		// Create translation request for title
-		titleReq := map[string]interface{}{
-			"type":      "task_translation",
-			"taskId":    task.ID,
-			"roomId":    r.ID,
-			"field":     "title",
-			"text":      task.Title,
-			"requestId": uuid.New().String(),
-		}
-		titleData, _ := json.Marshal(titleReq)
-		database.RDB.Publish(ctx, "task:translate", titleData)
-		
-		// Create translation request for description
-		descReq := map[string]interface{}{
-			"type":      "task_translation",
-			"taskId":    task.ID,
-			"roomId":    r.ID,
-			"field":     "description",
-			"text":      task.Description,
-			"requestId": uuid.New().String(),
-		}
-		descData, _ := json.Marshal(descReq)
-		database.RDB.Publish(ctx, "task:translate", descData)
-	}
-	
-	log.Printf("✅ Sent translation requests for all tasks")
+// translateStageTasks sends the tasks stage uses, and in a personal-tasks
+// game the dealt ones, for translation, skipping any already sent this
+// game. Tasks are translated a stage at a time as the game reaches them;
+// stage 0, the role reveal, counts as stage 1. Runs on the room goroutine.
+func (r *Room) translateStageTasks(stage int) {
+	if stage < 1 {
+		stage = 1
+	}
+	var tasks []*Task
+	if stage <= len(r.tasks) {
+		tasks = append(tasks, r.tasks[stage-1])
+	}
+	for _, t := range r.assignedTasks {
+		tasks = append(tasks, t)
+	}
+
+	var pending []*Task
+	for _, t := range tasks {
+		if !r.translationsAsked[t.ID] {
+			r.translationsAsked[t.ID] = true
+			pending = append(pending, t)
+		}
+	}
+	if len(pending) > 0 {
+		go r.requestTaskTranslations(pending)
+	}
 }
 
+// requestTaskTranslations runs off the room goroutine, so it is handed the
+// tasks rather than reading r.tasks; it only reads fields that never change.
+func (r *Room) requestTaskTranslations(tasks []*Task) {
+	log.Printf("🌐 Requesting translations for %d tasks", len(tasks))
+
+	for _, task := range tasks {
+		if err := requestTaskTranslation(r.ID, task.ID, "title", task.Title); err != nil {
+			log.Printf("Failed to request title translation for task %s: %v", task.ID, err)
+		}
+		if err := requestTaskTranslation(r.ID, task.ID, "description", task.Description); err != nil {
+			log.Printf("Failed to request description translation for task %s: %v", task.ID, err)
+		}
+	}
+}
 
+// updateTaskTranslations stores a field's translations on every copy of
+// the task the game holds, and sends them out if the task is in play.
+// Runs on the room goroutine.
 func (r *Room) updateTaskTranslations(taskID, field string, translations map[string]string) {
-	r.mu.Lock()
-	defer r.mu.Unlock()
-	
 	taskFound := false
-	for _, task := range r.tasks {
-		if task.ID == taskID {
-			taskFound = true
-			if field == "title" {
-				if task.TitleTranslations == nil {
-					task.TitleTranslations = make(map[string]string)
-				}
-				for lang, text := range translations {
-					task.TitleTranslations[lang] = text
-				}
-				log.Printf("✅ Updated title translations for task %s", taskID)
-				log.Printf("   Available languages: %v", getKeys(task.TitleTranslations))
-			} else if field == "description" {
-				if task.DescriptionTranslations == nil {
-					task.DescriptionTranslations = make(map[string]string)
-				}
-				for lang, text := range translations {
-					task.DescriptionTranslations[lang] = text
-				}
-				log.Printf("✅ Updated description translations for task %s", taskID)
-				log.Printf("   Available languages: %v", getKeys(task.DescriptionTranslations))
+	for _, task := range r.gameTasks() {
+		if task.ID != taskID {
+			continue
+		}
+		taskFound = true
+		delete(r.translationFailures, "task:"+taskID+"."+field)
+		var stored map[string]string
+		switch field {
+		case "title":
+			if task.TitleTranslations == nil {
+				task.TitleTranslations = make(map[string]string)
+			}
+			stored = task.TitleTranslations
+		case "description":
+			if task.DescriptionTranslations == nil {
+				task.DescriptionTranslations = make(map[string]string)
+			}
+			stored = task.DescriptionTranslations
+		default:
+			log.Printf("⚠️ Unknown task translation field %q for task %s", field, taskID)
+			return
+		}
+		for lang, text := range translations {
+			// Translations shipped with the task win over machine ones.
+			if _, ok := stored[lang]; !ok {
+				stored[lang] = text
 			}
-			
-			break
 		}
+		log.Printf("✅ Updated %s translations for task %s: %v", field, taskID, getKeys(stored))
 	}
-	
+
 	if !taskFound {
 		log.Printf("⚠️ Task %s not found for translation update", taskID)
 		return
 	}
-	
-	// Check if all tasks are fully translated
-	allTranslated := true
-	for _, t := range r.tasks {
-		if t.TitleTranslations == nil || len(t.TitleTranslations) == 0 {
-			allTranslated = false
-			log.Printf("   Task %s missing title translations", t.ID)
-			break
-		}
-		if t.DescriptionTranslations == nil || len(t.DescriptionTranslations) == 0 {
-			allTranslated = false
-			log.Printf("   Task %s missing description translations", t.ID)
-			break
-		}
-	}
-	
-	if allTranslated && !r.tasksTranslated {
-		r.tasksTranslated = true
-		log.Printf("🎉 All tasks fully translated!")
+
+	if r.taskInPlay(taskID) {
+		r.broadcastGameState()
 	}
-	
+}
 
-	log.Printf("📡 Broadcasting game state after translation update for %s.%s", taskID, field)
-	
-	// Unlock before broadcasting to avoid deadlock
-	r.mu.Unlock()
-	r.broadcastGameState()
-	r.mu.Lock()
+// taskInPlay reports whether players can see taskID now: it is the current
+// stage's task or one dealt to a player. Translations for later stages wait
+// for the GAME_STATE that starts the stage.
+func (r *Room) taskInPlay(taskID string) bool {
+	for _, t := range r.assignedTasks {
+		if t.ID == taskID {
+			return true
+		}
+	}
+	stage := r.gameState.CurrentStage
+	return stage >= 1 && stage <= len(r.tasks) && r.tasks[stage-1].ID == taskID
 }
+
 func getKeys(m map[string]string) []string {
 	keys := make([]string, 0, len(m))
 	for k := range m {
@@ -444,68 +1005,131 @@ func getKeys(m map[string]string) []string {
 func (r *Room) startGlobalTimer() {
 	log.Printf("Starting global timer for room %s", r.ID)
 
-	database.SaveTimerStart(r.ID, time.Now())
+	if r.gameState.TimerDeadline.IsZero() {
+		r.gameState.TimerDeadline = time.Now().Add(time.Duration(r.gameDurationSeconds()) * time.Second)
+		r.saveToRedis()
+	}
+	r.armClock()
+}
 
-	go func() {
-		ticker := time.NewTicker(1 * time.Second)
-		defer ticker.Stop()
-		defer close(r.timerDone)
-
-		for {
-			select {
-			case <-ticker.C:
-				r.mu.Lock()
-
-				if r.gameState.TimerPaused {
-					r.mu.Unlock()
-					continue
-				}
+// armClock schedules the end of the game for the current deadline,
+// replacing any earlier schedule. A paused clock has nothing scheduled.
+func (r *Room) armClock() {
+	if r.clock != nil {
+		r.clock.Stop()
+		r.clock = nil
+	}
+	if r.gameState.TimerPaused || r.gameState.TimerDeadline.IsZero() {
+		return
+	}
+	r.clock = r.schedule(r.gameState.timerRemaining(time.Now()), r.clockExpired)
+}
 
-				r.gameState.TimerSeconds--
-				currentTime := r.gameState.TimerSeconds
+func (r *Room) clockExpired() {
+	if r.gameState.TimerPaused {
+		return
+	}
+	if r.gameState.timerRemaining(time.Now()) > 0 {
+		r.armClock()
+		return
+	}
+	log.Printf("Timer expired for room %s - Imposter wins!", r.ID)
+	r.endGame("IMPOSTER_WIN_TIMEOUT")
+}
 
-				if currentTime%5 == 0 {
-					r.saveToRedis()
-				}
+// timerRemaining is how long the clock has left at now.
+func (g *GameState) timerRemaining(now time.Time) time.Duration {
+	if g.TimerDeadline.IsZero() {
+		return 0
+	}
+	if g.TimerPaused {
+		now = g.TimerPausedAt
+	}
+	if remaining := g.TimerDeadline.Sub(now); remaining > 0 {
+		return remaining
+	}
+	return 0
+}
 
-				r.mu.Unlock()
+// timerSeconds is the whole seconds left to show, or the full game length
+// before the clock has started. Runs on the room goroutine.
+func (r *Room) timerSeconds() int {
+	if r.gameState.TimerDeadline.IsZero() {
+		return r.gameDurationSeconds()
+	}
+	remaining := r.gameState.timerRemaining(time.Now())
+	return int((remaining + time.Second - 1) / time.Second)
+}
 
-				msg := Message{
-					Type: "SYNC_TIMER",
-					Data: map[string]interface{}{
-						"timerSeconds": currentTime,
-					},
-				}
-				data, _ := json.Marshal(msg)
-				r.broadcast <- data
+// timerPayload is what clients need to run the countdown themselves.
+// Times are Unix milliseconds; serverTime lets them correct for clock
+// skew. Runs on the room goroutine.
+func (r *Room) timerPayload() map[string]interface{} {
+	now := time.Now()
+	payload := map[string]interface{}{
+		"paused":       r.gameState.TimerPaused,
+		"remainingMs":  r.gameState.timerRemaining(now).Milliseconds(),
+		"serverTime":   now.UnixMilli(),
+		"timerSeconds": r.timerSeconds(),
+	}
+	if !r.gameState.TimerDeadline.IsZero() {
+		payload["deadline"] = r.gameState.TimerDeadline.UnixMilli()
+	}
+	if r.gameState.TimerPaused {
+		payload["pausedAt"] = r.gameState.TimerPausedAt.UnixMilli()
+	}
+	return payload
+}
 
-				if currentTime <= 0 {
-					log.Printf("Timer expired for room %s - Imposter wins!", r.ID)
-					r.endGame("IMPOSTER_WIN_TIMEOUT")
-					return
-				}
+// broadcastTimer is sent on every clock change and, while the clock runs,
+// every timerSyncInterval; never per tick. Starting the clock goes out with
+// the TASK_1 game state instead.
+func (r *Room) broadcastTimer() {
+	msg := Message{
+		Type: "SYNC_TIMER",
+		Data: r.timerPayload(),
+	}
 
-			case <-r.timerCancel:
-				log.Printf("Timer cancelled for room %s", r.ID)
-				return
-			}
-		}
-	}()
+	data, _ := json.Marshal(msg)
+	r.emit(data)
 }
 
-func (r *Room) pauseTimer() {
-	r.mu.Lock()
-	defer r.mu.Unlock()
+// stopClock and startClock change the clock without broadcasting, for
+// callers that send a GAME_STATE anyway. Runs on the room goroutine.
+func (r *Room) stopClock() {
+	if r.gameState.TimerPaused {
+		return
+	}
 	r.gameState.TimerPaused = true
+	r.gameState.TimerPausedAt = time.Now()
+	r.armClock()
+}
+
+func (r *Room) startClock() {
+	if !r.gameState.TimerPaused {
+		return
+	}
+	if !r.gameState.TimerDeadline.IsZero() {
+		r.gameState.TimerDeadline = r.gameState.TimerDeadline.Add(time.Since(r.gameState.TimerPausedAt))
+	}
+	r.gameState.TimerPaused = false
+	r.gameState.TimerPausedAt = time.Time{}
+	r.armClock()
+}
+
+func (r *Room) pauseTimer() {
+	r.stopClock()
 	r.saveToRedis()
+
+	r.broadcastTimer()
 	log.Printf("Timer paused for room %s", r.ID)
 }
 
 func (r *Room) resumeTimer() {
-	r.mu.Lock()
-	defer r.mu.Unlock()
-	r.gameState.TimerPaused = false
+	r.startClock()
 	r.saveToRedis()
+
+	r.broadcastTimer()
 	log.Printf("Timer resumed for room %s", r.ID)
 }
 
@@ -514,6 +1138,8 @@ func (r *Room) loadAllTasks() []*Task {
 		{
 			ID:             "task1-sportbrakes",
 			Stage:          1,
+			Category:       "OOPS",
+			Difficulty:     "EASY",
 			Title:          "ENGINE ROOM - Brake System Failure",
 			Description:    "The racing car's brake system is malfunctioning! Fix the constructor to properly initialize SportBrakes.",
 			Template: `public class RacingCar {
@@ -552,6 +1178,8 @@ TitleTranslations:       make(map[string]string),
 		{
 			ID:             "task2-satellite",
 			Stage:          2,
+			Category:       "DEBUGGING",
+			Difficulty:     "MEDIUM",
 			Title:          "🛰️ NAVIGATION - Satellite Orbit Calculation",
 			Description:    "The satellite's orbit calculation is broken! Fix the integer division and variable shadowing bugs.",
 			Template: `public class SatelliteSystem {
@@ -583,6 +1211,8 @@ TitleTranslations:       make(map[string]string),
 		{
 			ID:             "task3-oxygen",
 			Stage:          3,
+			Category:       "DEBUGGING",
+			Difficulty:     "HARD",
 			Title:          "💨 OXYGEN SYSTEM - Life Support Critical",
 			Description:    "CRITICAL! Fix both the oxygen flow calculation AND the filtration loop logic before the system fails!",
 			Template: `public class OxygenSystem {
@@ -616,48 +1246,58 @@ TitleTranslations:       make(map[string]string),
 }
 
 func (r *Room) handleRunTests(playerID, code string) {
-	r.mu.Lock()
-
 	if r.testRunning {
-		r.mu.Unlock()
-
-		errorMsg := Message{
-			Type: "ERROR_BUSY",
-			Data: map[string]interface{}{
-				"message": "System is currently processing. Please wait.",
-				"runner":  r.testRunnerName,
-			},
-		}
-		data, _ := json.Marshal(errorMsg)
-		for client := range r.clients {
-			if client.PlayerID == playerID {
-				client.send <- data
-				break
-			}
+		if client := r.clientFor(playerID); client != nil {
+			client.sendMessage(Message{
+				Type: "ERROR_BUSY",
+				Data: map[string]interface{}{
+					"message": "System is currently processing. Please wait.",
+					"runner":  r.testRunnerName,
+				},
+			})
 		}
 		return
 	}
 
 	player := r.players[playerID]
 	if player == nil || player.IsEliminated {
-		r.mu.Unlock()
 		return
 	}
 
 	currentStage := r.gameState.CurrentStage
 	if currentStage < 1 || currentStage > 3 {
-		r.mu.Unlock()
 		log.Printf("Invalid stage: %d", currentStage)
 		return
 	}
 
+	r.testsRun[playerID]++
+	if player.Role == "IMPOSTER" {
+		if client := r.clientFor(playerID); client != nil {
+			r.runDecoyTests(client, currentStage, code)
+		}
+		return
+	}
+
+	if !r.checkCorruptCleanup(playerID, code) {
+		return
+	}
+
 	r.testRunning = true
 	r.testRunner = playerID
 	r.testRunnerName = player.Username
 	r.codeSnapshot = code
+	r.saveToRedis()
 
-	r.mu.Unlock()
+	r.checkMassDeletion(playerID, player.Username, currentStage, code)
+
+	log.Printf("Stage %d test locked by %s", currentStage, player.Username)
+	r.runTests(playerID, currentStage, code)
+}
 
+// runTests tells the room the tests are locked and runs playerID's code,
+// which holds the lock, against the stage's tests. Runs on the room
+// goroutine.
+func (r *Room) runTests(playerID string, currentStage int, code string) {
 	testLockedMsg := Message{
 		Type: "TEST_LOCKED",
 		Data: map[string]interface{}{
@@ -667,41 +1307,134 @@ func (r *Room) handleRunTests(playerID, code string) {
 		},
 	}
 	data, _ := json.Marshal(testLockedMsg)
-	r.broadcast <- data
+	r.emit(data)
 
-	log.Printf("Stage %d test locked by %s", currentStage, player.Username)
+	r.testRun++
+	run := r.testRun
+	task := r.runTask(playerID, currentStage)
+	sub := sandbox.Submission{
+		Task:     task.ID,
+		Language: task.Language,
+		Source:   code,
+		Tests:    task.Tests,
+	}
+	if sub.Language == "" {
+		sub.Language = "java"
+	}
+	sub.Progress = func(status string) {
+		r.post(func() { r.reportTestStatus(run, currentStage, status) })
+	}
+	runner := codeRunner
+	if runner == nil || sub.Tests == "" {
+		runner = heuristicRunner{stage: task.Stage, checks: task.Checks}
+	}
 
+	// Compiling and running takes seconds, so it happens off the room
+	// goroutine and the result is posted back.
 	go func() {
-		time.Sleep(5 * time.Second)
+		result, err := runner.Run(context.Background(), sub)
+		if err != nil {
+			log.Printf("❌ Room %s: code runner failed for stage %d: %v", r.ID, currentStage, err)
+		} else {
+			log.Printf("🧪 Room %s stage %d: passed=%v compiled=%v tests=%d in %s",
+				r.ID, currentStage, result.Passed, result.Compiled, len(result.Tests), result.Duration)
+		}
+		r.post(func() {
+			r.finishTests(run, playerID, currentStage, code, result, err)
+		})
+	}()
+}
+
+// reportTestStatus tells the room how a run is getting on, e.g. that it's
+// waiting for a Judge0 worker. Runs on the room goroutine.
+func (r *Room) reportTestStatus(run, stage int, status string) {
+	if !r.testRunning || r.testRun != run {
+		return
+	}
+	msg := Message{
+		Type: "TEST_STATUS",
+		Data: map[string]interface{}{
+			"stage":  stage,
+			"status": status,
+		},
+	}
+	data, _ := json.Marshal(msg)
+	r.emit(data)
+}
 
-		passed := r.validateStageCode(currentStage, r.codeSnapshot)
+// finishTests reports a run's result unless the run was cancelled in the
+// meantime. Runs on the room goroutine.
+func (r *Room) finishTests(run int, playerID string, stage int, code string, result sandbox.Result, runErr error) {
+	if !r.testRunning || r.testRun != run || r.testRunner != playerID {
+		return
+	}
 
-		r.mu.Lock()
-		r.testRunning = false
-		r.testRunner = ""
-		r.testRunnerName = ""
-		r.codeSnapshot = ""
-		r.mu.Unlock()
+	r.testRunning = false
+	r.testRunner = ""
+	r.testRunnerName = ""
+	r.codeSnapshot = ""
+	r.saveToRedis()
 
-		testCompleteMsg := Message{
-			Type: "TEST_COMPLETE",
+	if runErr != nil {
+		cancelMsg := Message{
+			Type: "TEST_CANCELLED",
 			Data: map[string]interface{}{
-				"passed": passed,
-				"stage":  currentStage,
-				"runner": "A crewmate",
+				"reason": "The test runner is unavailable, try again in a moment",
 			},
 		}
-		data, _ := json.Marshal(testCompleteMsg)
-		r.broadcast <- data
+		data, _ := json.Marshal(cancelMsg)
+		r.emit(data)
+		return
+	}
+
+	if result.Passed && !r.personalGame() {
+		r.passedCode[stage] = code
+	}
+	if !result.Passed {
+		r.recordFailedRun(playerID)
+	}
+
+	testCompleteMsg := Message{
+		Type: "TEST_COMPLETE",
+		Data: map[string]interface{}{
+			"passed":         result.Passed,
+			"stage":          stage,
+			"runner":         "A crewmate",
+			"compiled":       result.Compiled,
+			"compilerOutput": result.CompilerOutput,
+			"tests":          result.Tests,
+			"timedOut":       result.TimedOut,
+		},
+	}
+	data, _ := json.Marshal(testCompleteMsg)
+	r.emit(data)
 
-		if passed {
-			r.advanceStage(currentStage)
+	if result.Passed {
+		if r.personalGame() {
+			r.completePersonalTask(playerID)
+			return
 		}
-	}()
+		r.stagesPassed[playerID]++
+		r.advanceStage(stage)
+	}
 }
 
-func (r *Room) validateStageCode(stage int, code string) bool {
-
+// stageTask is the task played at stage, with its tests filled in.
+func (r *Room) stageTask(stage int) Task {
+	for _, t := range r.tasks {
+		if t.Stage == stage {
+			task := *t
+			if task.Tests == "" {
+				task.Tests = taskTests[task.ID]
+			}
+			return task
+		}
+	}
+	return Task{Stage: stage}
+}
+
+func validateStageCode(stage int, code string) bool {
+
 	normalized := normalizeCode(code)
 
 	switch stage {
@@ -784,23 +1517,22 @@ func containsAny(text string, patterns []string) bool {
 }
 
 func (r *Room) advanceStage(completedStage int) {
-	r.mu.Lock()
-
 	r.gameState.TasksComplete[completedStage] = true
+	r.stageTimes[completedStage] = int(time.Since(r.gameState.GameStartTime).Seconds())
 
 	log.Printf("Stage %d completed!", completedStage)
 
+	r.logEvent(gameEventStagePass, "", "", map[string]interface{}{
+		"stage":   completedStage,
+		"seconds": r.stageTimes[completedStage],
+	})
 	r.saveToRedis()
 
 	if completedStage == 3 {
-		r.gameState.Phase = PhaseEnd
-		r.mu.Unlock()
 		r.endGame("CIVILIAN_WIN_TASKS")
 		return
 	}
 
-	r.mu.Unlock()
-
 	nextStage := completedStage + 1
 	msg := Message{
 		Type: "CHANGE_SCENE",
@@ -811,92 +1543,162 @@ func (r *Room) advanceStage(completedStage int) {
 		},
 	}
 	data, _ := json.Marshal(msg)
-	r.broadcast <- data
+	r.emit(data)
 
 	log.Printf("Transitioning from Stage %d to Stage %d", completedStage, nextStage)
+	r.translateStageTasks(nextStage)
 
-	go func() {
-		time.Sleep(3 * time.Second)
+	r.schedule(3*time.Second, func() { r.enterStage(nextStage) })
+}
 
-		r.mu.Lock()
-		r.gameState.CurrentStage = nextStage
+// enterStage moves the crew on to stage once the one before is done. Runs
+// on the room goroutine.
+func (r *Room) enterStage(stage int) {
+	r.gameState.CurrentStage = stage
+	r.stageStartedAt = time.Now()
 
-		switch nextStage {
-		case 2:
-			r.gameState.Phase = PhaseTask2
-		case 3:
-			r.gameState.Phase = PhaseTask3
-		}
+	switch stage {
+	case 2:
+		r.gameState.Phase = PhaseTask2
+	case 3:
+		r.gameState.Phase = PhaseTask3
+	}
 
-		r.saveToRedis()
-		r.mu.Unlock()
+	r.saveToRedis()
 
-		r.broadcastGameState()
-		log.Printf("Now on Stage %d", nextStage)
-	}()
+	r.broadcastGameState()
+	log.Printf("Now on Stage %d", stage)
 }
 
 func (r *Room) startDiscussion() {
-	r.mu.Lock()
-	r.gameState.TimerPaused = true
+	r.stopClock()
 	r.gameState.Phase = PhaseDiscussion
+	r.gameState.RevoteCandidates = nil
 	r.votes = make(map[string]string)
 	r.votingActive = true
+	r.votingDeadline = time.Now().Add(time.Duration(r.votingSeconds()) * time.Second)
+	r.resetTyping()
 	r.saveToRedis()
-	r.mu.Unlock()
+	r.emitEvent(webhooks.EventMeetingCalled, map[string]interface{}{
+		"stage": r.gameState.CurrentStage,
+	})
 
 	r.broadcastGameState()
 
 	log.Printf("Discussion started in room %s - Timer paused", r.ID)
 
-	votingDuration := 30
-
-	go func() {
-		for i := votingDuration; i > 0; i-- {
-			time.Sleep(1 * time.Second)
-
-			r.mu.RLock()
-			stillVoting := r.gameState.Phase == PhaseDiscussion
-			r.mu.RUnlock()
-
-			if !stillVoting {
-				return
-			}
+	r.votingCountdown(len(r.meetings), r.votingSeconds())
+}
 
-			msg := Message{
-				Type: "VOTING_TIMER",
-				Data: map[string]interface{}{
-					"seconds": i,
-				},
-			}
-			data, _ := json.Marshal(msg)
-			r.broadcast <- data
+// votingCountdown ticks the voting clock down once a second and tallies
+// when it runs out. meeting tells this meeting's ticks apart from a later
+// one's.
+func (r *Room) votingCountdown(meeting, seconds int) {
+	r.schedule(time.Second, func() {
+		if !r.votingActive || len(r.meetings) != meeting {
+			return
 		}
-
-		r.mu.Lock()
-		if r.gameState.Phase == PhaseDiscussion {
+		if seconds == 0 {
 			log.Printf("Voting timeout - tallying votes")
-			r.mu.Unlock()
 			r.tallyVotes()
-		} else {
-			r.mu.Unlock()
+			return
 		}
-	}()
+
+		msg := Message{
+			Type: "VOTING_TIMER",
+			Data: map[string]interface{}{
+				"seconds": seconds,
+			},
+		}
+		data, _ := json.Marshal(msg)
+		r.emit(data)
+
+		r.votingCountdown(meeting, seconds-1)
+	})
 }
 
-func (r *Room) handleVote(voterID, targetID string) {
-	r.mu.Lock()
+// voteSkip is the ballot for ejecting no one.
+const voteSkip = "SKIP"
+
+// Reasons a ballot is turned away with VOTE_REJECTED.
+const (
+	voteClosed           = "VOTING_CLOSED"
+	voteNotAllowed       = "NOT_ALLOWED"
+	voteUnknownTarget    = "UNKNOWN_TARGET"
+	voteTargetEliminated = "TARGET_ELIMINATED"
+	voteSelf             = "SELF_VOTE"
+	voteLocked           = "VOTE_LOCKED"
+	voteNotCandidate     = "NOT_A_CANDIDATE"
+)
+
+var voteRejectionMessages = map[string]string{
+	voteClosed:           "Voting is not open",
+	voteNotAllowed:       "Eliminated players can't vote",
+	voteUnknownTarget:    "That player isn't in the game",
+	voteTargetEliminated: "That player has already been eliminated",
+	voteSelf:             "You can't vote for yourself",
+	voteLocked:           "Votes are locked in this room",
+	voteNotCandidate:     "The revote is only between the tied players",
+}
+
+// checkVote returns why voterID's ballot for targetID can't be counted, or
+// "" if it can. Runs on the room goroutine.
+func (r *Room) checkVote(voterID, targetID string) string {
+	if !r.votingActive {
+		return voteClosed
+	}
+	if voter := r.players[voterID]; voter == nil || voter.IsEliminated {
+		return voteNotAllowed
+	}
+	if _, voted := r.votes[voterID]; voted && r.gameState.Settings.LockVotes {
+		return voteLocked
+	}
+	if targetID == voteSkip {
+		return ""
+	}
+	if candidates := r.gameState.RevoteCandidates; candidates != nil && !slices.Contains(candidates, targetID) {
+		return voteNotCandidate
+	}
+	if targetID == voterID {
+		return voteSelf
+	}
+	target := r.players[targetID]
+	if target == nil {
+		return voteUnknownTarget
+	}
+	if target.IsEliminated {
+		return voteTargetEliminated
+	}
+	return ""
+}
+
+// handleVote counts a ballot, or returns why it was rejected. Runs on the
+// room goroutine.
+func (r *Room) handleVote(voterID, targetID string) string {
+	if reason := r.checkVote(voterID, targetID); reason != "" {
+		return reason
+	}
 
 	r.votes[voterID] = targetID
+	voterName := ""
+	if voter := r.players[voterID]; voter != nil {
+		voterName = voter.Username
+	}
+	r.logEvent(gameEventVote, voterID, voterName, map[string]interface{}{"targetId": targetID})
+	r.saveToRedis()
 
 	log.Printf("Player %s voted for %s", voterID, targetID)
 
+	if voterName != "" {
+		r.audit("VOTE", voterID, voterName, map[string]interface{}{"targetId": targetID})
+	}
+
 	voteStatus := make(map[string]bool)
 	for vid := range r.votes {
 		voteStatus[vid] = true
 	}
 
-	r.mu.Unlock()
+	r.checkLockstepVoting(voterID, voterName, targetID)
 
 	msg := Message{
 		Type: "VOTE_UPDATE",
@@ -905,9 +1707,8 @@ func (r *Room) handleVote(voterID, targetID string) {
 		},
 	}
 	data, _ := json.Marshal(msg)
-	r.broadcast <- data
+	r.emit(data)
 
-	r.mu.RLock()
 	aliveCount := 0
 	for _, p := range r.players {
 		if !p.IsEliminated {
@@ -915,7 +1716,6 @@ func (r *Room) handleVote(voterID, targetID string) {
 		}
 	}
 	voteCount := len(r.votes)
-	r.mu.RUnlock()
 
 	if voteCount >= aliveCount {
 		log.Printf("All players voted (%d/%d) - tallying in 1 second", voteCount, aliveCount)
@@ -927,26 +1727,37 @@ func (r *Room) handleVote(voterID, targetID string) {
 			},
 		}
 		allInData, _ := json.Marshal(allInMsg)
-		r.broadcast <- allInData
-
-		time.Sleep(1 * time.Second)
-		r.tallyVotes()
+		r.emit(allInData)
+
+		// A vote changed after this, or the clock running out, mustn't
+		// tally a revote that has started in the meantime.
+		meeting := len(r.meetings)
+		r.schedule(time.Second, func() {
+			if len(r.meetings) == meeting {
+				r.tallyVotes()
+			}
+		})
 	}
+	return ""
 }
 
 func (r *Room) tallyVotes() {
-	r.mu.Lock()
-
 	if !r.votingActive {
-		r.mu.Unlock()
 		return
 	}
 
 	r.votingActive = false
 
 	voteCounts := make(map[string]int)
-	for _, targetID := range r.votes {
+	for voterID, targetID := range r.votes {
 		voteCounts[targetID]++
+
+		if targetID != "SKIP" {
+			r.votesCast[voterID]++
+			if r.gameState.isImpostor(targetID) {
+				r.correctVotes[voterID]++
+			}
+		}
 	}
 
 	maxVotes := 0
@@ -961,86 +1772,157 @@ func (r *Room) tallyVotes() {
 		}
 	}
 
-	isImpostor := eliminated == r.gameState.ImposterID
-
-	var eliminatedName string
-	if eliminated != "" && eliminated != "SKIP" {
-		if player, exists := r.players[eliminated]; exists {
-			eliminatedName = player.Username
+	revoting := r.gameState.RevoteCandidates != nil
+	r.gameState.RevoteCandidates = nil
+	if eliminated == "" && maxVotes > 0 && !revoting && r.gameState.Settings.TieRevote {
+		// A player tied only with SKIP has no one to be voted against.
+		if tied := tiedCandidates(voteCounts, maxVotes); len(tied) >= 2 {
+			r.recordMeeting("")
+			r.startRevote(tied)
+			return
 		}
 	}
 
-	r.mu.Unlock()
+	r.recordMeeting(eliminated)
 
 	if eliminated == "" || eliminated == "SKIP" {
 		log.Printf("⏭ No one eliminated - resuming game")
 
-		chatMsg := Message{
-			Type: "CHAT",
-			Data: map[string]interface{}{
-				"username": "System",
-				"text":     "No one was eliminated. The crew continues...",
-				"system":   true,
-			},
-		}
-		chatData, _ := json.Marshal(chatMsg)
-		r.broadcast <- chatData
+		r.emitSystemChat("system.no_elimination", nil)
 
 		r.resumeGameAfterVoting()
 
-		r.mu.Lock()
 		r.votes = make(map[string]string)
-		r.mu.Unlock()
 		return
 	}
 
-	chatMsg := Message{
-		Type: "CHAT",
-		Data: map[string]interface{}{
-			"username": "System",
-			"text":     "🗳️ " + eliminatedName + " was voted out!",
-			"system":   true,
-		},
+	var eliminatedName string
+	if player, exists := r.players[eliminated]; exists {
+		eliminatedName = player.Username
 	}
-	chatData, _ := json.Marshal(chatMsg)
-	r.broadcast <- chatData
+	r.emitSystemChat("system.voted_out", map[string]interface{}{"name": eliminatedName})
 
-	time.Sleep(1 * time.Second)
+	r.gameState.Ejected = eliminated
+	r.saveToRedis()
+	r.schedule(time.Second, func() { r.playOutEjection(eliminated) })
+}
 
-	r.eliminatePlayer(eliminated)
+// playOutEjection eliminates the player a meeting voted out and, a second
+// later, ends the game or sends everyone back to work. Runs on the room
+// goroutine.
+func (r *Room) playOutEjection(eliminated string) {
+	if r.gameState.Phase != PhaseDiscussion {
+		return
+	}
+	if player := r.players[eliminated]; player != nil && !player.IsEliminated {
+		r.eliminatePlayer(eliminated)
+	}
 
-	time.Sleep(1 * time.Second)
+	var eliminatedName string
+	if player, exists := r.players[eliminated]; exists {
+		eliminatedName = player.Username
+	}
+	isImpostor := r.gameState.isImpostor(eliminated)
 
-	if isImpostor {
-		log.Printf("Impostor eliminated - Crewmates win!")
-		r.endGame("CIVILIAN_WIN_VOTE")
-	} else {
-		log.Printf("Wrong vote - game continues")
+	r.schedule(time.Second, func() {
+		if player := r.players[eliminated]; player != nil && player.Role == "JESTER" {
+			log.Printf("Jester %s voted out", player.Username)
+			r.endGame("JESTER_WIN_VOTED_OUT")
+			return
+		}
+		if reason := r.winByNumbers("CIVILIAN_WIN_VOTE"); reason != "" {
+			log.Printf("Vote decided the game - %s", reason)
+			r.endGame(reason)
+			return
+		}
+		// Voting out the last crewmate with work left fills the bar.
+		if r.checkTaskBar() {
+			return
+		}
 
-		wrongVoteMsg := Message{
-			Type: "CHAT",
-			Data: map[string]interface{}{
-				"username": "System",
-				"text":     eliminatedName + " was not the impostor...",
-				"system":   true,
-			},
+		if isImpostor {
+			impostors, _ := r.aliveByRole()
+			log.Printf("Impostor eliminated - %d still aboard", impostors)
+			r.emitSystemChat("system.impostor_caught", map[string]interface{}{"name": eliminatedName, "remaining": impostors})
+		} else {
+			log.Printf("Wrong vote - game continues")
+			r.emitSystemChat("system.not_impostor", map[string]interface{}{"name": eliminatedName})
 		}
-		wrongVoteData, _ := json.Marshal(wrongVoteMsg)
-		r.broadcast <- wrongVoteData
 
-		time.Sleep(1 * time.Second)
-		r.resumeGameAfterVoting()
+		r.schedule(time.Second, func() {
+			r.resumeGameAfterVoting()
+			r.votes = make(map[string]string)
+		})
+	})
+}
+
+// tiedCandidates are the players who shared the most votes. SKIP may have
+// tied with them, but it is always on the ballot anyway.
+func tiedCandidates(voteCounts map[string]int, maxVotes int) []string {
+	var tied []string
+	for targetID, count := range voteCounts {
+		if count == maxVotes && targetID != voteSkip {
+			tied = append(tied, targetID)
+		}
+	}
+	sort.Strings(tied)
+	return tied
+}
+
+// startRevote reopens voting on a tie, for half the usual time, between the
+// tied players only. If it ties again no one is eliminated. Runs on the
+// room goroutine.
+func (r *Room) startRevote(candidates []string) {
+	seconds := r.votingSeconds() / 2
+	if seconds < minVotingSeconds {
+		seconds = minVotingSeconds
 	}
 
-	r.mu.Lock()
+	r.gameState.RevoteCandidates = candidates
 	r.votes = make(map[string]string)
-	r.mu.Unlock()
+	r.votingActive = true
+	r.votingDeadline = time.Now().Add(time.Duration(seconds) * time.Second)
+	r.saveToRedis()
+
+	tied := make([]map[string]interface{}, 0, len(candidates))
+	names := make([]string, 0, len(candidates))
+	for _, id := range candidates {
+		name := "?"
+		if player := r.players[id]; player != nil {
+			name = player.Username
+		}
+		tied = append(tied, map[string]interface{}{"id": id, "username": name})
+		names = append(names, name)
+	}
+	log.Printf("🗳️ Vote tied in room %s between %v - revote for %ds", r.ID, names, seconds)
+
+	tieMsg := Message{
+		Type: "VOTE_TIE",
+		Data: map[string]interface{}{
+			"candidates": tied,
+			"seconds":    seconds,
+		},
+	}
+	data, _ := json.Marshal(tieMsg)
+	r.emit(data)
+
+	updateMsg := Message{
+		Type: "VOTE_UPDATE",
+		Data: map[string]interface{}{
+			"hasVoted": map[string]bool{},
+		},
+	}
+	data, _ = json.Marshal(updateMsg)
+	r.emit(data)
+
+	r.votingCountdown(len(r.meetings), seconds)
 }
 
 func (r *Room) resumeGameAfterVoting() {
-	r.resumeTimer()
-
-	r.mu.Lock()
+	r.lastMeetingEnded = time.Now()
+	r.resetTyping()
+	r.startClock()
+	log.Printf("Timer resumed for room %s", r.ID)
 	currentStage := r.gameState.CurrentStage
 	switch currentStage {
 	case 1:
@@ -1052,22 +1934,28 @@ func (r *Room) resumeGameAfterVoting() {
 	default:
 		r.gameState.Phase = PhaseTask1
 	}
+	r.gameState.Ejected = ""
 	r.saveToRedis()
-	r.mu.Unlock()
 
 	r.broadcastGameState()
 }
 
 func (r *Room) eliminatePlayer(playerID string) {
-	r.mu.Lock()
-	defer r.mu.Unlock()
-
 	if player, exists := r.players[playerID]; exists {
 		player.IsEliminated = true
 		player.IsAlive = false
+		r.eliminatedAt[playerID] = int(time.Since(r.gameState.GameStartTime).Seconds())
 
+		r.logEvent(gameEventEliminated, playerID, player.Username, map[string]interface{}{"reason": "VOTED_OUT"})
 		r.saveToRedis()
 
+		r.audit("ELIMINATED", playerID, player.Username, map[string]interface{}{"reason": "VOTED_OUT"})
+		r.emitEvent(webhooks.EventPlayerEliminated, map[string]interface{}{
+			"playerId": playerID,
+			"username": player.Username,
+			"reason":   "VOTED_OUT",
+		})
+
 		elimMsg := Message{
 			Type: "PLAYER_ELIMINATED",
 			Data: map[string]interface{}{
@@ -1076,7 +1964,7 @@ func (r *Room) eliminatePlayer(playerID string) {
 			},
 		}
 		data, _ := json.Marshal(elimMsg)
-		r.broadcast <- data
+		r.emit(data)
 
 		log.Printf("Player %s eliminated", player.Username)
 	}
@@ -1085,94 +1973,266 @@ func (r *Room) eliminatePlayer(playerID string) {
 func (r *Room) endGame(reason string) {
 	log.Printf("🏁 [endGame] Starting game end sequence - Reason: %s", reason)
 
-	r.timerCancelOnce.Do(func() {
-		close(r.timerCancel)
-		log.Printf("[endGame] Timer cancel channel closed")
-	})
-
-	select {
-	case <-r.timerDone:
-		log.Printf("Timer goroutine stopped cleanly")
-	case <-time.After(2 * time.Second):
-		log.Printf("Timer stop timeout")
+	if r.gameState.Phase == PhaseEnd {
+		return
+	}
+	if r.clock != nil {
+		r.clock.Stop()
+		r.clock = nil
 	}
 
-	r.mu.Lock()
-	r.gameState.Phase = "GAME_OVER"
-	imposterID := r.gameState.ImposterID
+	r.gameState.Phase = PhaseEnd
+	r.resetTyping()
+	imposterIDs := r.gameState.ImposterIDs
+	seasonID := r.gameState.SeasonID
 
 	finalState := r.buildGameStatePayload()
 
 	duration := int(time.Since(r.gameState.GameStartTime).Seconds())
+	stagesCompleted := r.stagesCompleted()
+	summary := r.buildSummary(reason, duration)
 
+	r.logEvent(gameEventEnd, "", "", map[string]interface{}{
+		"reason":          reason,
+		"durationSeconds": duration,
+		"stagesCompleted": stagesCompleted,
+	})
 	r.saveToRedis()
 
-	r.mu.Unlock()
+	summaryToken := saveSummary(summary)
 
-	go r.saveMatchHistory(reason, duration)
+	r.audit("GAME_END", "", "", map[string]interface{}{"reason": reason})
+	r.emitEvent(webhooks.EventGameEnded, map[string]interface{}{
+		"reason":          reason,
+		"winnerRole":      winnerRoleFor(reason),
+		"imposterID":      firstOf(imposterIDs),
+		"imposterIDs":     imposterIDs,
+		"durationSeconds": duration,
+		"stagesCompleted": stagesCompleted,
+	})
 
-	msg := Message{
-		Type: "GAME_ENDED",
-		Data: map[string]interface{}{
-			"reason":     reason,
-			"imposterID": imposterID,
-			"finalState": finalState,
-		},
+	match, matchPlayers := r.matchRecord(reason, duration)
+	go r.saveMatchHistory(match, matchPlayers, r.ranked, r.signedInClients())
+
+	decided := winnerRoleFor(reason) != "UNKNOWN"
+	if r.challenge != nil && decided {
+		go recordDailyResults(r.challenge.Date, r.dailyResults(winnerRoleFor(reason), duration, stagesCompleted))
+	}
+	if seasonID != "" && decided {
+		go recordSeasonResults(seasonID, r.seasonResults(winnerRoleFor(reason)))
+	}
+
+	scores := r.scores()
+	endData := map[string]interface{}{
+		"reason":     reason,
+		"imposterIDs":  imposterIDs,
+		"finalState":   finalState,
+		"summaryToken": summaryToken,
+		"scores":       scores,
+		"editShare":    r.editShares(),
 	}
+	if mvp := mvpOf(scores); mvp != nil {
+		endData["mvp"] = mvp
+	}
+	msg := Message{Type: "GAME_ENDED", Data: endData}
 
 	data, _ := json.Marshal(msg)
 	log.Printf("[endGame] Broadcasting GAME_ENDED message")
-	r.broadcast <- data
+	r.emit(data)
 
 	log.Printf("[endGame] Game ended: %s", reason)
 
-	go func() {
-		time.Sleep(5 * time.Minute)
+	r.cleanup = time.AfterFunc(roomCleanupDelay, func() {
 		database.DeleteRoom(r.ID)
 		log.Printf("🧹 Room %s cleaned up from Redis", r.ID)
-	}()
+
+		// Whoever is still looking at the results is sent on their way.
+		expired := false
+		r.do(func() { expired = r.gameState.Phase == PhaseEnd })
+		if expired && r.hub != nil {
+			r.hub.closeRoom(r, &Message{
+				Type: "ERROR_ACCESS_DENIED",
+				Data: map[string]interface{}{
+					"reason":  "ROOM_CLOSED",
+					"message": "This room has closed - create a new one to play again",
+				},
+			})
+		}
+	})
 }
 
-func (r *Room) saveMatchHistory(reason string, duration int) {
-	var winnerRole string
-	if strings.Contains(reason, "CIVILIAN") {
-		winnerRole = "CIVILIAN"
-	} else if strings.Contains(reason, "IMPOSTER") || strings.Contains(reason, "IMPOSTER") {
-		winnerRole = "IMPOSTER"
-	} else {
-		winnerRole = "UNKNOWN"
+func impostorCountFor(players int) int {
+	count := players / playersPerImpostor
+	if count < 1 {
+		count = 1
+	}
+	for count > 1 && count*2 >= players {
+		count--
+	}
+	return count
+}
+
+func (g *GameState) isImpostor(playerID string) bool {
+	for _, id := range g.ImposterIDs {
+		if id == playerID {
+			return true
+		}
+	}
+	return false
+}
+
+// aliveByRole counts the players still in the game on each side.
+func (r *Room) aliveByRole() (impostors, civilians int) {
+	for _, p := range r.players {
+		if p.IsEliminated {
+			continue
+		}
+		if p.Role == "IMPOSTER" {
+			impostors++
+		} else {
+			civilians++
+		}
+	}
+	return impostors, civilians
+}
+
+// winByNumbers is the end reason if the players left decide the game:
+// civilianReason once every impostor is gone, or an impostor win once they
+// are as many as the crew. It is "" while the game goes on.
+func (r *Room) winByNumbers(civilianReason string) string {
+	impostors, civilians := r.aliveByRole()
+	switch {
+	case impostors == 0:
+		return civilianReason
+	case impostors >= civilians:
+		return "IMPOSTER_WIN"
+	}
+	return ""
+}
+
+// revealImpostorTeam tells each impostor who the others are. Civilians
+// never get this message.
+func (r *Room) revealImpostorTeam() {
+	team := r.impostorTeam()
+	for _, id := range r.gameState.ImposterIDs {
+		if client := r.clientFor(id); client != nil {
+			client.sendMessage(Message{
+				Type: "IMPOSTOR_TEAM",
+				Data: map[string]interface{}{"impostors": team},
+			})
+		}
+	}
+}
+
+func (r *Room) impostorTeam() []map[string]interface{} {
+	team := make([]map[string]interface{}, 0, len(r.gameState.ImposterIDs))
+	for _, id := range r.gameState.ImposterIDs {
+		if p := r.players[id]; p != nil {
+			team = append(team, map[string]interface{}{"id": p.ID, "username": p.Username})
+		}
+	}
+	return team
+}
+
+func firstOf(ids []string) string {
+	if len(ids) == 0 {
+		return ""
 	}
+	return ids[0]
+}
+
+func winnerRoleFor(reason string) string {
+	if strings.Contains(reason, "JESTER") {
+		return "JESTER"
+	} else if strings.Contains(reason, "CIVILIAN") {
+		return "CIVILIAN"
+	} else if strings.Contains(reason, "IMPOSTER") {
+		return "IMPOSTER"
+	}
+	return "UNKNOWN"
+}
 
+func (r *Room) stagesCompleted() int {
 	stagesCompleted := 0
 	for _, completed := range r.gameState.TasksComplete {
 		if completed {
 			stagesCompleted++
 		}
 	}
+	return stagesCompleted
+}
 
+// matchRecord is the finished game as match history, built on the room
+// goroutine so saveMatchHistory can store it from another.
+func (r *Room) matchRecord(reason string, duration int) (database.GameMatch, []database.MatchPlayer) {
 	match := database.GameMatch{
 		RoomCode:        r.ID,
-		WinnerRole:      winnerRole,
-		ImpostorID:      r.gameState.ImposterID,
+		WinnerRole:      winnerRoleFor(reason),
+		ImpostorID:      firstOf(r.gameState.ImposterIDs),
 		DurationSeconds: duration,
-		StagesCompleted: stagesCompleted,
+		StagesCompleted: r.stagesCompleted(),
 		EndedAt:         time.Now(),
+		StageSeconds:    make(map[int]int, len(r.stageTimes)),
+		StageCode:       make(map[int]string, len(r.passedCode)),
+	}
+	for stage, seconds := range r.stageTimes {
+		match.StageSeconds[stage] = seconds
+	}
+	for stage, code := range r.passedCode {
+		match.StageCode[stage] = code
 	}
 
 	var matchPlayers []database.MatchPlayer
 	for _, player := range r.players {
+		score := r.playerScore(player)
+		secondsAlive := duration
+		if player.IsEliminated {
+			secondsAlive = r.eliminatedAt[player.ID]
+		}
+		var accuracy *float64
+		if cast := r.votesCast[player.ID]; cast > 0 {
+			a := float64(r.correctVotes[player.ID]) / float64(cast)
+			accuracy = &a
+		}
+
 		matchPlayers = append(matchPlayers, database.MatchPlayer{
 			UserID:        player.ID,
-			Role:          player.Role,
+			Role:          roleTeam(player.Role),
 			WasEliminated: player.IsEliminated,
+			VotesCast:     r.votesCast[player.ID],
+			CorrectVotes:  r.correctVotes[player.ID],
+			VoteAccuracy:  accuracy,
+
+			TestsRun:          score.TestsRun,
+			StagesPassed:      score.StagesPassed,
+			SabotagesSurvived: score.SabotagesSurvived,
+			Score:             score.Score,
+
+			SabotagesUsed: r.sabotagesUsed[player.ID],
+			SecondsAlive:  secondsAlive,
 		})
 	}
+	return match, matchPlayers
+}
 
+func (r *Room) saveMatchHistory(match database.GameMatch, matchPlayers []database.MatchPlayer, ranked bool, accounts map[string]*Client) {
 	err := database.SaveGameMatch(match, matchPlayers)
 	if err != nil {
 		log.Printf("Failed to save match history: %v", err)
 	} else {
 		log.Printf("Match history saved to Supabase")
+		grantUnlocks(accounts)
+	}
+
+	// A jester's win says nothing about how the crew and impostors played.
+	if match.WinnerRole != "CIVILIAN" && match.WinnerRole != "IMPOSTER" {
+		return
+	}
+	if err == nil {
+		r.updateMMR(matchPlayers, match.WinnerRole)
+	}
+	if ranked {
+		r.updateRatings(matchPlayers, match.WinnerRole)
 	}
 }
 
@@ -1182,54 +2242,72 @@ func (r *Room) buildGameStatePayload() map[string]interface{} {
 		currentTask = r.tasks[r.gameState.CurrentStage-1]
 	}
 
-	return map[string]interface{}{
+	payload := map[string]interface{}{
 		"phase":         r.gameState.Phase,
 		"currentStage":  r.gameState.CurrentStage,
-		"timerSeconds":  r.gameState.TimerSeconds,
+		"timerSeconds":  r.timerSeconds(),
+		"timer":         r.timerPayload(),
 		"tasksComplete": r.gameState.TasksComplete,
 		"players":       r.players,
 		"testRunning":   r.testRunning,
 		"testRunner":    r.testRunnerName,
 		"task":          currentTask,
+		"ranked":        r.ranked,
+
+		"spectatorDelaySeconds": int(r.spectatorDelay.Seconds()),
 	}
+	if r.personalGame() {
+		delete(payload, "task")
+		payload["taskProgress"] = r.taskProgressPayload()
+	}
+	return payload
 }
 
 func (r *Room) handleSabotage(playerID, sabotageType string) {
-	r.mu.Lock()
-
 	player := r.players[playerID]
 	if player == nil || player.Role != "IMPOSTER" {
-		r.mu.Unlock()
 		log.Printf("Invalid sabotage attempt: %s", playerID)
 		return
 	}
 
 	if r.sabotageActive {
-		r.mu.Unlock()
 		log.Printf("Sabotage already active")
 		return
 	}
 
+	// SWAP needs two crewmates to swap and TIMER_DRAIN a use left, and an
+	// impostor shouldn't lose the cooldown to finding out they can't.
+	var swapped []string
+	if sabotageType == "SWAP" {
+		if swapped = r.swapPair(); swapped == nil {
+			if client := r.clientFor(playerID); client != nil {
+				client.sendError("Not enough crewmates online to swap")
+			}
+			return
+		}
+	}
+	if sabotageType == "TIMER_DRAIN" {
+		if reason := r.checkTimerDrain(playerID); reason != "" {
+			if client := r.clientFor(playerID); client != nil {
+				client.sendError(reason)
+			}
+			return
+		}
+	}
+
 	timeSinceLastSabotage := time.Since(r.lastSabotageTime).Seconds()
 	if timeSinceLastSabotage < float64(r.sabotageCooldownSec) && !r.lastSabotageTime.IsZero() {
-		r.mu.Unlock()
-
 		remainingCooldown := r.sabotageCooldownSec - int(timeSinceLastSabotage)
 		log.Printf("Sabotage on cooldown: %d seconds remaining", remainingCooldown)
 
 		// Send cooldown message to impostor
-		cooldownMsg := Message{
-			Type: "SABOTAGE_COOLDOWN",
-			Data: map[string]interface{}{
-				"remainingSeconds": remainingCooldown,
-			},
-		}
-		data, _ := json.Marshal(cooldownMsg)
-		for client := range r.clients {
-			if client.PlayerID == playerID {
-				client.send <- data
-				break
-			}
+		if client := r.clientFor(playerID); client != nil {
+			client.sendMessage(Message{
+				Type: "SABOTAGE_COOLDOWN",
+				Data: map[string]interface{}{
+					"remainingSeconds": remainingCooldown,
+				},
+			})
 		}
 		return
 	}
@@ -1237,10 +2315,12 @@ func (r *Room) handleSabotage(playerID, sabotageType string) {
 	r.sabotageActive = true
 	r.sabotageType = sabotageType
 	r.lastSabotageTime = time.Now()
+	r.sabotagesUsed[playerID]++
 
 	log.Printf("SABOTAGE: %s activated %s", player.Username, sabotageType)
 
-	r.mu.Unlock()
+	r.audit("SABOTAGE", playerID, player.Username, map[string]interface{}{"type": sabotageType})
+	r.logEvent(gameEventSabotage, playerID, player.Username, map[string]interface{}{"type": sabotageType})
 
 	switch sabotageType {
 	case "FREEZE":
@@ -1249,14 +2329,31 @@ func (r *Room) handleSabotage(playerID, sabotageType string) {
 	case "CORRUPT":
 		r.handleCorruptSabotage()
 
+	case "SWAP":
+		r.handleSwapSabotage(swapped)
+
+	case "TIMER_DRAIN":
+		r.handleTimerDrain(playerID)
+
+	case "CRITICAL":
+		r.handleCriticalSabotage()
+
 	default:
 		log.Printf("Unknown sabotage type: %s", sabotageType)
-		r.mu.Lock()
 		r.sabotageActive = false
-		r.mu.Unlock()
 	}
 }
 
+// isFrozen reports whether a FREEZE sabotage has playerID locked out of the
+// code, the chat and the test runner. It only ever holds back the crew.
+func (r *Room) isFrozen(playerID string) bool {
+	if !r.sabotageActive || r.sabotageType != "FREEZE" {
+		return false
+	}
+	player := r.players[playerID]
+	return player != nil && player.Role != "IMPOSTER"
+}
+
 func (r *Room) handleFreezeSabotage() {
 	log.Printf("FREEZE sabotage activated - 5 second lockout")
 
@@ -1268,56 +2365,43 @@ func (r *Room) handleFreezeSabotage() {
 		},
 	}
 	data, _ := json.Marshal(freezeMsg)
-	r.broadcast <- data
+	r.emit(data)
 
-	chatMsg := Message{
-		Type: "CHAT",
-		Data: map[string]interface{}{
-			"username": "System",
-			"text":     "⚠️ SYSTEM JAMMED - Communications frozen!",
-			"system":   true,
-		},
-	}
-	chatData, _ := json.Marshal(chatMsg)
-	r.broadcast <- chatData
+	r.emitSystemChat("system.freeze", nil)
 
-	go func() {
-		time.Sleep(5 * time.Second)
+	r.sabotageEndTime = time.Now().Add(5 * time.Second)
+	r.sabotageTimer = r.schedule(5*time.Second, r.endFreeze)
+	r.saveToRedis()
+}
 
-		r.mu.Lock()
-		r.sabotageActive = false
-		r.sabotageType = ""
-		// r.lastSabotageTime = time.Time{}
-		r.mu.Unlock()
+func (r *Room) endFreeze() {
+	r.sabotageTimer = nil
+	r.sabotageActive = false
+	r.sabotageType = ""
+	r.survivedSabotage()
+	r.saveToRedis()
+	// r.lastSabotageTime = time.Time{}
 
-		endMsg := Message{
-			Type: "SABOTAGE_ENDED",
-			Data: map[string]interface{}{
-				"type": "FREEZE",
-			},
-		}
-		endData, _ := json.Marshal(endMsg)
-		r.broadcast <- endData
+	endMsg := Message{
+		Type: "SABOTAGE_ENDED",
+		Data: map[string]interface{}{
+			"type": "FREEZE",
+		},
+	}
+	endData, _ := json.Marshal(endMsg)
+	r.emit(endData)
 
-		chatMsg := Message{
-			Type: "CHAT",
-			Data: map[string]interface{}{
-				"username": "System",
-				"text":     "✅ Systems restored - Communications online",
-				"system":   true,
-			},
-		}
-		chatData, _ := json.Marshal(chatMsg)
-		r.broadcast <- chatData
+	r.emitSystemChat("system.freeze_ended", nil)
 
-		log.Printf("FREEZE sabotage ended")
-	}()
+	log.Printf("FREEZE sabotage ended")
 }
 
 func (r *Room) handleCorruptSabotage() {
 	log.Printf("CORRUPT sabotage activated - injecting malware")
 
-	malwareText := "\n// ⚠️ MALWARE DETECTED - REMOVE THIS LINE TO COMPILE\n// SYSTEM_FAILURE_CODE_0x00FF\n"
+	marker := fmt.Sprintf("SYSTEM_FAILURE_CODE_0x%04X", rand.Intn(0x10000))
+	malwareText := "\n// ⚠️ MALWARE DETECTED - REMOVE THIS LINE TO COMPILE\n// " + marker + "\n"
+	r.corruptMarkers = append(r.corruptMarkers, marker)
 
 	corruptMsg := Message{
 		Type: "SABOTAGE_CORRUPT",
@@ -1327,51 +2411,78 @@ func (r *Room) handleCorruptSabotage() {
 		},
 	}
 	data, _ := json.Marshal(corruptMsg)
-	r.broadcast <- data
+	r.emit(data)
 
-	chatMsg := Message{
-		Type: "CHAT",
-		Data: map[string]interface{}{
-			"username": "System",
-			"text":     "🦠 MALWARE DETECTED - Code corrupted!",
-			"system":   true,
-		},
-	}
-	chatData, _ := json.Marshal(chatMsg)
-	r.broadcast <- chatData
+	r.emitSystemChat("system.corrupt", nil)
 
-	r.mu.Lock()
 	r.sabotageActive = false
 	r.sabotageType = ""
-	r.mu.Unlock()
+	r.saveToRedis()
 
 	log.Printf("CORRUPT sabotage injected - players must remove malware manually")
 }
 
-func (r *Room) broadcastGameState() {
-	r.mu.RLock()
+// checkCorruptCleanup reports whether code is free of every marker a CORRUPT
+// sabotage left. If it isn't, playerID is told to clean it up first; if it
+// is, the sabotage is resolved for everyone. Runs on the room goroutine.
+func (r *Room) checkCorruptCleanup(playerID, code string) bool {
+	if len(r.corruptMarkers) == 0 {
+		return true
+	}
+	for _, marker := range r.corruptMarkers {
+		if strings.Contains(code, marker) {
+			if client := r.clientFor(playerID); client != nil {
+				client.sendError("Malware still in the code - remove it before running tests")
+			}
+			return false
+		}
+	}
+
+	r.corruptMarkers = nil
+	r.survivedSabotage()
+	r.saveToRedis()
 
-	log.Printf("[broadcastGameState] Starting broadcast for room %s", r.ID)
-	log.Printf("[broadcastGameState] Current phase: %s", r.gameState.Phase)
-	log.Printf("[broadcastGameState] Current stage: %d", r.gameState.CurrentStage)
+	log.Printf("CORRUPT sabotage resolved by %s in room %s", playerID, r.ID)
+
+	// Like TEST_LOCKED, this doesn't say whose run it was.
+	resolvedMsg := Message{
+		Type: "SABOTAGE_RESOLVED",
+		Data: map[string]interface{}{
+			"type": "CORRUPT",
+		},
+	}
+	data, _ := json.Marshal(resolvedMsg)
+	r.emit(data)
+
+	r.emitSystemChat("system.corrupt_cleaned", nil)
+	return true
+}
+
+func (r *Room) broadcastGameState() {
+	roomDebugf(r.ID, "[broadcastGameState] Starting broadcast for room %s", r.ID)
+	roomDebugf(r.ID, "[broadcastGameState] Current phase: %s", r.gameState.Phase)
+	roomDebugf(r.ID, "[broadcastGameState] Current stage: %d", r.gameState.CurrentStage)
 
 	var currentTask *Task
 	if r.gameState.CurrentStage >= 1 && r.gameState.CurrentStage <= 3 {
 		currentTask = r.tasks[r.gameState.CurrentStage-1]
-		log.Printf("[broadcastGameState] Current task: %s", currentTask.Title)
+		roomDebugf(r.ID, "[broadcastGameState] Current task: %s", currentTask.Title)
 	}
 
 	state := map[string]interface{}{
 		"phase":         r.gameState.Phase,
 		"currentStage":  r.gameState.CurrentStage,
-		"timerSeconds":  r.gameState.TimerSeconds,
+		"timerSeconds":  r.timerSeconds(),
+		"timer":         r.timerPayload(),
 		"tasksComplete": r.gameState.TasksComplete,
 		"players":       r.players,
 		"testRunning":   r.testRunning,
 		"testRunner":    r.testRunnerName,
 	}
 
-	if currentTask != nil {
+	if r.personalGame() {
+		state["taskProgress"] = r.taskProgressPayload()
+	} else if currentTask != nil {
 		state["task"] = currentTask
 	}
 
@@ -1383,14 +2494,14 @@ func (r *Room) broadcastGameState() {
 	data, err := json.Marshal(msg)
 	if err != nil {
 		log.Printf("[broadcastGameState] Error marshaling: %v", err)
-		r.mu.RUnlock()
 		return
 	}
 
-	r.mu.RUnlock()
-
-	r.broadcast <- data
-	log.Printf("[broadcastGameState] Broadcast complete!")
+	r.emit(data)
+	if r.personalGame() {
+		r.sendAssignedTasks()
+	}
+	roomDebugf(r.ID, "[broadcastGameState] Broadcast complete!")
 }
 
 func (r *Room) broadcastPlayerList() {
@@ -1400,7 +2511,7 @@ func (r *Room) broadcastPlayerList() {
 	}
 
 	data, _ := json.Marshal(msg)
-	r.broadcast <- data
+	r.emit(data)
 }
 
 func (h *Hub) handleYjsConnection(w http.ResponseWriter, r *http.Request, conn *websocket.Conn) {
@@ -1413,27 +2524,40 @@ func (h *Hub) handleYjsConnection(w http.ResponseWriter, r *http.Request, conn *
 
 	log.Printf("Yjs connection attempt for room: %s", roomID)
 
-	baseRoomID := strings.Split(roomID, "-stage")[0]
+	baseRoomID, _ := yjsDocRoom(roomID)
+
+	// Ghosts, observers and anyone else who isn't a living player get a
+	// read-only view; see relayYjs. So does a connection that can't show
+	// which player it is.
+	peer := &yjsPeer{mu: &sync.Mutex{}, doc: roomID}
+	if claims, err := authenticateRequest(r); err == nil && r.URL.Query().Get("spectate") != "1" {
+		peer.playerID = verifiedPlayerID(r, claims, baseRoomID)
+	}
 
 	room := h.getRoom(baseRoomID)
+	if room == nil && h.cluster != nil {
+		h.cluster.serveRelayedYjs(conn, baseRoomID, peer)
+		return
+	}
 	if room == nil {
 		log.Printf("Room %s not found for Yjs connection", roomID)
 		conn.Close()
 		return
 	}
-	clientMutex := &sync.Mutex{}
 
-	room.mu.Lock()
-	room.yjsClients[conn] = clientMutex
-	clientCount := len(room.yjsClients)
-	room.mu.Unlock()
+	var clientCount int
+	if !room.do(func() {
+		room.yjsClients[conn] = peer
+		clientCount = len(room.yjsClients)
+	}) {
+		conn.Close()
+		return
+	}
 
 	log.Printf("Yjs client connected to room %s (total: %d)", roomID, clientCount)
 
 	defer func() {
-		room.mu.Lock()
-		delete(room.yjsClients, conn)
-		room.mu.Unlock()
+		room.post(func() { delete(room.yjsClients, conn) })
 		conn.Close()
 		log.Printf("Yjs client disconnected from room %s", roomID)
 	}()
@@ -1446,24 +2570,49 @@ func (h *Hub) handleYjsConnection(w http.ResponseWriter, r *http.Request, conn *
 			}
 			break
 		}
+		addMetric(yjsSocket.rawIn, int64(len(message)))
 
-		room.mu.RLock()
-		for client, clientMu := range room.yjsClients {
-			if client != conn {
-				targetClient := client
-				targetMu := clientMu
+		room.post(func() {
+			room.relayYjs(conn, messageType, message)
+		})
+	}
+}
 
-				go func() {
-					targetMu.Lock()
-					defer targetMu.Unlock()
+// relayYjs forwards an editor update to every other Yjs connection. Each
+// connection has its own write lock since writes happen off the room
+// goroutine. Read-only peers may ask for the document but their edits and
+// cursors are dropped.
+func (r *Room) relayYjs(from *websocket.Conn, messageType int, message []byte) {
+	sender := r.yjsClients[from]
+	if sender == nil || (!r.mayWrite(sender) && yjsWrites(message)) {
+		return
+	}
+	r.recordEdit(sender.playerID, message)
+	r.broadcastYjs(from, "", sender.doc, messageType, message)
+}
 
-					targetClient.SetWriteDeadline(time.Now().Add(writeWait))
-					if err := targetClient.WriteMessage(messageType, message); err != nil {
-						log.Printf("Error broadcasting Yjs message: %v", err)
-					}
-				}()
-			}
+// broadcastYjs sends an editor update to every connection on doc but the
+// one it came from, which is either local (from) or relayed (fromConnID).
+func (r *Room) broadcastYjs(from *websocket.Conn, fromConnID, doc string, messageType int, message []byte) {
+	if len(r.yjsRemote) > 0 {
+		publishFanout(r.ID, relayEnvelope{Kind: relayYjs, ConnID: fromConnID, Doc: doc, Binary: message, MessageType: messageType})
+	}
+
+	for client, peer := range r.yjsClients {
+		if client != from && peer.doc == doc {
+			targetClient := client
+			targetMu := peer.mu
+
+			go func() {
+				targetMu.Lock()
+				defer targetMu.Unlock()
+
+				prepareWrite(targetClient, yjsSocket, len(message))
+				targetClient.SetWriteDeadline(time.Now().Add(writeWait))
+				if err := targetClient.WriteMessage(messageType, message); err != nil {
+					log.Printf("Error broadcasting Yjs message: %v", err)
+				}
+			}()
 		}
-		room.mu.RUnlock()
 	}
 }
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+// openTestRoom creates a room in hub the way handleRegister does, with one
+// player in it.
+func openTestRoom(h *Hub, id string) *Room {
+	room := newRoom(id)
+	room.hub = h
+	h.mu.Lock()
+	h.rooms[id] = room
+	h.mu.Unlock()
+	go room.run()
+
+	room.do(func() { room.addPlayer("player-"+id, "Tester") })
+	return room
+}
+
+func waitStopped(t *testing.T, room *Room) {
+	t.Helper()
+	select {
+	case <-room.stopped:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("room %s never stopped", room.ID)
+	}
+}
+
+// settledGoroutines waits for goroutines that are on their way out, such
+// as match history saves, to finish, and returns how many are left.
+func settledGoroutines(limit int) int {
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		n := countGoroutines()
+		if n <= limit || time.Now().After(deadline) {
+			return n
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+// countGoroutines counts running goroutines other than the in-memory
+// Redis's, which serves each pooled connection on goroutines of its own
+// and so grows with concurrent use.
+func countGoroutines() int {
+	buf := make([]byte, 1<<20)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) {
+			buf = buf[:n]
+			break
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+
+	count := 0
+	for _, stack := range strings.Split(string(buf), "\n\n") {
+		if !strings.Contains(stack, "miniredis") {
+			count++
+		}
+	}
+	return count
+}
+
+func TestRoomLifecycleLeavesNoGoroutines(t *testing.T) {
+	defer func(delay time.Duration) { roomCleanupDelay = delay }(roomCleanupDelay)
+	roomCleanupDelay = time.Millisecond
+
+	h := newHub()
+
+	// One lifecycle of each kind first, so the Redis pool and anything
+	// else started once is already counted.
+	closeTestRoom(t, h, "WARM01", false)
+	closeTestRoom(t, h, "WARM02", true)
+	before := settledGoroutines(0)
+
+	const cycles = 1000
+	for i := 0; i < cycles; i++ {
+		closeTestRoom(t, h, fmt.Sprintf("LIFE%04d", i), i%2 == 1)
+	}
+
+	if after := settledGoroutines(before); after > before {
+		t.Fatalf("goroutines grew from %d to %d over %d room lifecycles", before, after, cycles)
+	}
+	h.mu.RLock()
+	left := len(h.rooms)
+	h.mu.RUnlock()
+	if left != 0 {
+		t.Fatalf("%d rooms left in the hub", left)
+	}
+}
+
+// closeTestRoom runs a room from creation to shutdown: emptied by its last
+// player leaving, or, if expire is set, closed by the cleanup timer endGame
+// schedules.
+func closeTestRoom(t *testing.T, h *Hub, id string, expire bool) {
+	t.Helper()
+	room := openTestRoom(h, id)
+
+	if expire {
+		room.do(func() { room.endGame("Test over") })
+	} else {
+		room.do(func() { room.removePlayer(room.players["player-"+id]) })
+		h.closeRoom(room, nil)
+	}
+	waitStopped(t, room)
+}
+
+func TestTieRevoteNeedsTwoPlayers(t *testing.T) {
+	h := newHub()
+	room := openTestRoom(h, "VOTE01")
+	defer h.closeRoom(room, nil)
+
+	tally := func(votes map[string]string) (phase GamePhase, candidates []string) {
+		room.do(func() {
+			room.gameState.Settings.TieRevote = true
+			room.gameState.Phase = PhaseDiscussion
+			room.gameState.CurrentStage = 1
+			room.gameState.RevoteCandidates = nil
+			room.votingActive = true
+			room.votes = votes
+			room.tallyVotes()
+			phase, candidates = room.gameState.Phase, room.gameState.RevoteCandidates
+		})
+		return phase, candidates
+	}
+	room.do(func() {
+		room.addPlayer("a", "A")
+		room.addPlayer("b", "B")
+		room.tasks = []*Task{{Title: "Stage 1"}}
+	})
+
+	// One vote against a, one to skip: nobody is eliminated.
+	phase, candidates := tally(map[string]string{"a": "b", "b": voteSkip})
+	if candidates != nil || phase != PhaseTask1 {
+		t.Fatalf("player/SKIP tie: phase = %s, revote between %v; want back to work with no revote", phase, candidates)
+	}
+
+	phase, candidates = tally(map[string]string{"a": "b", "b": "a"})
+	if phase != PhaseDiscussion || len(candidates) != 2 {
+		t.Fatalf("two-player tie: phase = %s, revote between %v; want a revote between a and b", phase, candidates)
+	}
+}
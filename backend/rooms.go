@@ -0,0 +1,100 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"log"
+	"math/big"
+	"net/http"
+	"strings"
+
+	"code-mafia-backend/database"
+)
+
+const (
+	roomCodeLength = 6
+
+	// Letters and digits that can't be misread for one another when a code
+	// is read out loud or copied off a stream.
+	roomCodeAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+	roomCodeAttempts = 10
+)
+
+var errNoRoomCode = errors.New("could not find a free room code")
+
+func newRoomCode() string {
+	var b strings.Builder
+	max := big.NewInt(int64(len(roomCodeAlphabet)))
+	for i := 0; i < roomCodeLength; i++ {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			panic(err)
+		}
+		b.WriteByte(roomCodeAlphabet[n.Int64()])
+	}
+	return b.String()
+}
+
+// createRoom reserves a fresh code and stores the room's lobby state under
+// it, retrying on the rare collision with a live room.
+func createRoom(daily bool) (string, error) {
+	prefix := ""
+	if daily {
+		prefix = dailyRoomPrefix
+	}
+	return createRoomWithSettings(prefix, Settings{})
+}
+
+// createRoomWithSettings is createRoom for a lobby that starts with
+// settings other than the defaults, such as a matched game's language.
+func createRoomWithSettings(prefix string, settings Settings) (string, error) {
+	for i := 0; i < roomCodeAttempts; i++ {
+		code := prefix + newRoomCode()
+
+		created, err := database.CreateRoom(code, GameState{
+			Phase:         PhaseLobby,
+			TasksComplete: make(map[int]bool),
+			Settings:      settings,
+		})
+		if err != nil {
+			return "", err
+		}
+		if created {
+			return code, nil
+		}
+	}
+	return "", errNoRoomCode
+}
+
+// roomKnown reports whether roomID was created through the API and hasn't
+// expired. Connections to any other code are refused.
+func (h *Hub) roomKnown(roomID string) bool {
+	return roomID != "" && (h.getRoom(roomID) != nil || database.RoomExists(roomID))
+}
+
+func handleCreateRoom(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Daily bool `json:"daily"`
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]interface{}{"error": "invalid JSON body"})
+			return
+		}
+	}
+
+	code, err := createRoom(req.Daily)
+	if err != nil {
+		log.Printf("❌ Failed to create room: %v", err)
+		writeJSON(w, http.StatusServiceUnavailable, map[string]interface{}{"error": "could not create a room, try again"})
+		return
+	}
+
+	log.Printf("🏠 Room %s created", code)
+	writeJSON(w, http.StatusCreated, map[string]interface{}{
+		"code":    code,
+		"joinUrl": roomLink(code),
+	})
+}
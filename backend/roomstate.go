@@ -0,0 +1,303 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"code-mafia-backend/database"
+)
+
+// The game state saveToRedis writes is versioned, so a server can load a
+// room saved by an older build, mid-deploy or after a rollback, and bring
+// it up to date: loadGameState runs it through roomStateMigrations before
+// decoding it. Whatever was in flight when the old server stopped (a test
+// run, the role reveal, a stage change) is picked up again here too.
+
+// roomStateVersion is the GameState shape this build saves. Bump it and add
+// a migration whenever a change to GameState would make an older save mean
+// something else.
+const roomStateVersion = 1
+
+// roomStateMigrations[v] brings a version v state, as decoded JSON, to
+// version v+1. Version 0 is everything saved before states were versioned.
+var roomStateMigrations = []func(state map[string]interface{}){
+	migrateTimerSeconds,
+}
+
+// migrateTimerSeconds turns the seconds left on the clock, which is how it
+// used to be saved, into the deadline it is saved as now. The clock picks
+// up from what was left at the save.
+func migrateTimerSeconds(state map[string]interface{}) {
+	seconds, _ := state["timerSeconds"].(float64)
+	delete(state, "timerSeconds")
+	if seconds <= 0 {
+		return
+	}
+	if deadline, _ := state["timerDeadline"].(string); deadline != "" && deadline != (time.Time{}).Format(time.RFC3339) {
+		return
+	}
+
+	now := time.Now()
+	state["timerDeadline"] = now.Add(time.Duration(seconds) * time.Second)
+	if paused, _ := state["timerPaused"].(bool); paused {
+		state["timerPausedAt"] = now
+	}
+}
+
+// loadGameState decodes a saved game state into g, migrating it first if
+// it is older than roomStateVersion. A state from a newer build is loaded
+// as it is, which drops whatever this build doesn't know about.
+func loadGameState(data []byte, g *GameState) error {
+	var state map[string]interface{}
+	if err := json.Unmarshal(data, &state); err != nil {
+		return fmt.Errorf("failed to decode game state: %w", err)
+	}
+
+	version := 0
+	if v, ok := state["schemaVersion"].(float64); ok {
+		version = int(v)
+	}
+	switch {
+	case version > roomStateVersion:
+		log.Printf("⚠️ Game state is version %d, newer than this server's %d - loading what it knows", version, roomStateVersion)
+	case version < roomStateVersion:
+		for v := version; v < roomStateVersion; v++ {
+			roomStateMigrations[v](state)
+		}
+		state["schemaVersion"] = roomStateVersion
+		log.Printf("Migrated game state from version %d to %d", version, roomStateVersion)
+	}
+
+	migrated, err := json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("failed to re-encode game state: %w", err)
+	}
+	if err := json.Unmarshal(migrated, g); err != nil {
+		return fmt.Errorf("failed to decode game state: %w", err)
+	}
+	return nil
+}
+
+// saveRevision writes the room as the revision after the one it last saved
+// or loaded. Runs on the room goroutine.
+func (r *Room) saveRevision(players map[string][]byte) error {
+	r.gameState.Revision = r.revision + 1
+	if err := database.SaveRoom(r.ID, r.gameState, r.revision, players); err != nil {
+		return err
+	}
+	r.revision = r.gameState.Revision
+	r.savedState = gameContent(r.gameState)
+	return nil
+}
+
+// gameContent is a game state encoded without its revision, to tell
+// whether two saves hold the same game.
+func gameContent(g GameState) []byte {
+	g.Revision = 0
+	data, _ := json.Marshal(g)
+	return data
+}
+
+// resolveSaveConflict deals with a save that found the stored state at a
+// revision it didn't expect: something else wrote the room since this copy
+// last saved or loaded it. The stored room is loaded and compared with
+// what this copy last saved, and the save is retried on top of it only
+// when that can't undo the other writer's changes:
+//   - if there is no stored state, because it expired or was deleted,
+//     there is nothing to undo;
+//   - if the other writer changed only players this copy hasn't, their
+//     versions are taken before saving again.
+//
+// Otherwise, or if another instance owns the room now, the stored state
+// wins: this copy shuts down and its players reconnect to the room loaded
+// from Redis. Runs on the room goroutine.
+func (r *Room) resolveSaveConflict(conflict error, changed map[string][]byte) error {
+	incMetric("room_save_conflicts_total")
+	log.Printf("⚠️ Save conflict on room %s at revision %d: %v", r.ID, r.revision, conflict)
+
+	if r.hub != nil && r.hub.cluster != nil {
+		if owner, err := database.RoomOwner(r.ID); err == nil && owner != "" && owner != r.hub.cluster.instance {
+			log.Printf("🛰️ Room %s is run by %s now, shutting down the local copy", r.ID, owner)
+			r.yield()
+			return conflict
+		}
+	}
+
+	data, storedPlayers, err := database.LoadRoomSnapshot(r.ID)
+	if err != nil {
+		return err
+	}
+
+	players := changed
+	var stored GameState
+	if data == nil {
+		// Everything goes back in, since nothing is left.
+		players = make(map[string][]byte, len(r.players))
+		for id := range r.players {
+			players[id] = r.savedPlayers[id]
+			if data, ok := changed[id]; ok {
+				players[id] = data
+			}
+		}
+	} else {
+		if err := loadGameState(data, &stored); err != nil {
+			return err
+		}
+		if !bytes.Equal(gameContent(stored), r.savedState) {
+			log.Printf("Room %s's game was changed at revision %d by another writer, reloading it", r.ID, stored.Revision)
+			r.yield()
+			return conflict
+		}
+		if !r.takePlayers(storedPlayers, changed) {
+			log.Printf("Room %s's players were changed at revision %d by another writer, reloading it", r.ID, stored.Revision)
+			r.yield()
+			return conflict
+		}
+	}
+
+	r.revision = stored.Revision
+	err = r.saveRevision(players)
+	if errors.Is(err, database.ErrStaleRoomState) {
+		incMetric("room_save_conflicts_total")
+	} else if err == nil {
+		log.Printf("Saved room %s over revision %d after a conflict", r.ID, stored.Revision)
+	}
+	return err
+}
+
+// takePlayers takes the versions of players another writer saved, when
+// this copy hasn't changed them since its last save and still seats them,
+// and reports whether every such change could be taken. Runs on the room
+// goroutine.
+func (r *Room) takePlayers(stored map[string]string, changed map[string][]byte) bool {
+	taken := make(map[string]*Player)
+	for id, data := range stored {
+		if bytes.Equal([]byte(data), r.savedPlayers[id]) {
+			continue
+		}
+		if _, mine := changed[id]; mine || r.players[id] == nil {
+			return false
+		}
+		var player Player
+		if err := json.Unmarshal([]byte(data), &player); err != nil {
+			return false
+		}
+		taken[id] = &player
+	}
+
+	for id, player := range taken {
+		r.players[id] = player
+		r.savedPlayers[id] = []byte(stored[id])
+	}
+	if len(taken) > 0 {
+		r.broadcastPlayerList()
+	}
+	return true
+}
+
+// yield shuts this copy of the room down in favour of the stored state.
+// Its players are disconnected and reconnect to the room loaded from
+// Redis, and anything it hadn't saved is lost. Runs on the room goroutine.
+func (r *Room) yield() {
+	if r.hub == nil {
+		return
+	}
+	go func() {
+		cl := r.hub.cluster
+		if cl != nil {
+			cl.release(r.ID)
+		}
+		r.hub.abandonRoom(r.ID)
+		if cl != nil {
+			cl.dropProxies(r.ID)
+		}
+	}()
+}
+
+// SavedTestRun is a RUN_TESTS the room was waiting on when it was saved.
+type SavedTestRun struct {
+	RunnerID   string `json:"runnerId"`
+	RunnerName string `json:"runnerName"`
+	Stage      int    `json:"stage"`
+	Code       string `json:"code"`
+}
+
+// savedTestRun is the test run under way, for saveToRedis. Runs on the
+// room goroutine.
+func (r *Room) savedTestRun() *SavedTestRun {
+	if !r.testRunning {
+		return nil
+	}
+	return &SavedTestRun{
+		RunnerID:   r.testRunner,
+		RunnerName: r.testRunnerName,
+		Stage:      r.gameState.CurrentStage,
+		Code:       r.codeSnapshot,
+	}
+}
+
+// resumeTestRun runs the tests a restart cut short again, holding the lock
+// for the same crewmate meanwhile, unless the game has moved past them.
+// Their old run's result died with the old server.
+func (r *Room) resumeTestRun() {
+	run := r.gameState.TestRun
+	r.gameState.TestRun = nil
+	if run == nil {
+		return
+	}
+
+	player := r.players[run.RunnerID]
+	if !inTaskPhase(r.gameState.Phase) || run.Stage != r.gameState.CurrentStage ||
+		r.gameState.TasksComplete[run.Stage] || player == nil || player.IsEliminated {
+		log.Printf("Dropping %s's test run in room %s - the game has moved on", run.RunnerName, r.ID)
+		return
+	}
+
+	r.testRunning = true
+	r.testRunner = run.RunnerID
+	r.testRunnerName = run.RunnerName
+	r.codeSnapshot = run.Code
+	log.Printf("Re-running %s's stage %d tests in room %s after a restart", run.RunnerName, run.Stage, r.ID)
+
+	r.schedule(time.Second, func() {
+		if r.testRunning && r.testRunner == run.RunnerID && r.gameState.CurrentStage == run.Stage {
+			r.runTests(run.RunnerID, run.Stage, run.Code)
+		}
+	})
+}
+
+// resumeStageChange finishes a move between phases the restart cut off:
+// the role reveal ending, or the crew moving on from a finished stage.
+func (r *Room) resumeStageChange() {
+	g := &r.gameState
+	switch {
+	case g.Phase == PhaseRoleReveal:
+		log.Printf("Resuming role reveal in room %s", r.ID)
+		r.schedule(time.Second, func() {
+			if r.gameState.Phase == PhaseRoleReveal {
+				r.beginTasks()
+			}
+		})
+	case inTaskPhase(g.Phase) && g.TasksComplete[g.CurrentStage]:
+		stage := g.CurrentStage
+		log.Printf("Resuming the move on from stage %d in room %s", stage, r.ID)
+		r.schedule(time.Second, func() {
+			if r.gameState.CurrentStage != stage || !inTaskPhase(r.gameState.Phase) {
+				return
+			}
+			if stage >= 3 {
+				r.endGame("CIVILIAN_WIN_TASKS")
+				return
+			}
+			r.enterStage(stage + 1)
+		})
+	}
+}
+
+func inTaskPhase(phase GamePhase) bool {
+	return phase >= PhaseTask1 && phase <= PhaseTask3
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+
+	"code-mafia-backend/database"
+)
+
+// writeAsOtherWriter saves the room's stored state again, changed by
+// change, with players, the way another copy of the room would.
+func writeAsOtherWriter(t *testing.T, roomID string, change func(*GameState), players map[string]*Player) {
+	t.Helper()
+	data, err := database.LoadGameStateJSON(roomID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var g GameState
+	if err := loadGameState(data, &g); err != nil {
+		t.Fatal(err)
+	}
+	if change != nil {
+		change(&g)
+	}
+
+	encoded := make(map[string][]byte)
+	for id, player := range players {
+		encoded[id], _ = json.Marshal(player)
+	}
+	expected := g.Revision
+	g.Revision++
+	if err := database.SaveRoom(roomID, g, expected, encoded); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSaveConflictTakesOtherWritersPlayers(t *testing.T) {
+	h := newHub()
+	room := openTestRoom(h, "SAVE01")
+	defer h.closeRoom(room, nil)
+	var before int64
+	room.do(func() {
+		room.addPlayer("other", "Other")
+		room.saveToRedis()
+		before = room.revision
+	})
+
+	writeAsOtherWriter(t, room.ID, nil, map[string]*Player{
+		"other": {ID: "other", Username: "Renamed", IsAlive: true},
+	})
+
+	var revision int64
+	var other, mine string
+	room.do(func() {
+		room.players["player-"+room.ID].Username = "Changed"
+		room.saveToRedis()
+		revision = room.revision
+		other = room.players["other"].Username
+		mine = room.players["player-"+room.ID].Username
+	})
+
+	if revision != before+2 {
+		t.Fatalf("revision after retry = %d, want %d", revision, before+2)
+	}
+	if other != "Renamed" || mine != "Changed" {
+		t.Fatalf("players after merge = %q and %q, want Renamed and Changed", other, mine)
+	}
+	var saved Player
+	if err := database.LoadPlayer(room.ID, "other", &saved); err != nil || saved.Username != "Renamed" {
+		t.Fatalf("other writer's player was overwritten: %+v, %v", saved, err)
+	}
+}
+
+func TestSaveConflictYieldsToChangedGame(t *testing.T) {
+	h := newHub()
+	room := openTestRoom(h, "SAVE02")
+	var before int64
+	room.do(func() {
+		room.saveToRedis()
+		before = room.revision
+	})
+
+	writeAsOtherWriter(t, room.ID, func(g *GameState) { g.Phase = PhaseTask1 }, nil)
+	room.do(func() {
+		room.players["player-"+room.ID].Username = "Changed"
+		room.saveToRedis()
+	})
+
+	waitStopped(t, room)
+	if h.getRoom(room.ID) != nil {
+		t.Fatalf("room that lost a conflict is still in the hub")
+	}
+	data, _ := database.LoadGameStateJSON(room.ID)
+	var g GameState
+	if err := loadGameState(data, &g); err != nil || g.Phase != PhaseTask1 || g.Revision != before+1 {
+		t.Fatalf("other writer's state was overwritten: phase %s, revision %d, %v", g.Phase, g.Revision, err)
+	}
+}
+
+func TestSaveConflictRewritesDeletedState(t *testing.T) {
+	h := newHub()
+	room := openTestRoom(h, "SAVE03")
+	defer h.closeRoom(room, nil)
+	room.do(room.saveToRedis)
+
+	database.DeleteRoom(room.ID)
+	var revision int64
+	room.do(func() {
+		room.saveToRedis()
+		revision = room.revision
+	})
+
+	if revision != 1 {
+		t.Fatalf("revision after rewrite = %d, want 1", revision)
+	}
+	var saved Player
+	if err := database.LoadPlayer(room.ID, "player-"+room.ID, &saved); err != nil {
+		t.Fatalf("players weren't written back: %v", err)
+	}
+}
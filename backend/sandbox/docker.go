@@ -0,0 +1,203 @@
+package sandbox
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	compileFailedExit = 97
+	maxOutputBytes    = 64 * 1024
+
+	// killGrace is how long a timed-out run gets to exit after its
+	// container is killed.
+	killGrace = 5 * time.Second
+)
+
+// dockerScript unpacks the archive on stdin, builds it and runs the tests.
+// Build errors go to stderr and exit with compileFailedExit so they can't
+// be confused with a failing test.
+func dockerScript(lang language) string {
+	return fmt.Sprintf("mkdir -p /tmp/src && cd /tmp/src && tar xf - && { %s || exit %d; } && exec %s",
+		lang.build, compileFailedExit, lang.run)
+}
+
+// Docker builds and tests submissions in a throwaway container with no
+// network, a read-only root and hard CPU, memory, process and time limits.
+// Sources are streamed in over stdin, so it works the same when the server
+// itself runs in a container with the Docker socket mounted. Images holds
+// the image for each language, which must have its compiler or runtime on
+// the PATH.
+type Docker struct {
+	Binary   string
+	Images   map[string]string
+	CPUs     string
+	MemoryMB int
+	Timeout  time.Duration
+}
+
+func (d *Docker) Run(ctx context.Context, sub Submission) (Result, error) {
+	nonce := newNonce()
+	lang, files, err := languageFor(sub, nonce)
+	if err != nil {
+		return Result{}, err
+	}
+	image := d.Images[languageName(sub.Language)]
+	if image == "" {
+		return Result{}, fmt.Errorf("no image configured for %s", languageName(sub.Language))
+	}
+	archive, err := tarFiles(files)
+	if err != nil {
+		return Result{}, err
+	}
+
+	sub.report(StatusRunning)
+
+	name := "codemafia-run-" + randomSuffix()
+	memory := strconv.Itoa(d.MemoryMB) + "m"
+	cmd := exec.Command(d.binary(), "run", "--rm", "-i",
+		"--name", name,
+		"--network", "none",
+		"--cpus", d.CPUs,
+		"--memory", memory,
+		"--memory-swap", memory,
+		"--pids-limit", "128",
+		"--read-only",
+		"--tmpfs", fmt.Sprintf("/tmp:rw,exec,size=%dm", lang.scratchMB),
+		"--cap-drop", "ALL",
+		"--security-opt", "no-new-privileges",
+		"--user", "65534:65534",
+		image, "sh", "-c", dockerScript(lang),
+	)
+
+	var stdout, stderr limitedBuffer
+	cmd.Stdin = archive
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	cmd.WaitDelay = killGrace
+
+	start := time.Now()
+	if err := cmd.Start(); err != nil {
+		return Result{}, fmt.Errorf("failed to start docker: %w", err)
+	}
+
+	limit := d.Timeout + lang.buildTime
+	ctx, cancel := context.WithTimeout(ctx, limit)
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() { done <- cmd.Wait() }()
+
+	var runErr error
+	timedOut := false
+	select {
+	case runErr = <-done:
+	case <-ctx.Done():
+		// Killing the CLI would leave the container running, so stop the
+		// container itself and let the CLI exit on its own.
+		timedOut = true
+		exec.Command(d.binary(), "kill", name).Run()
+		select {
+		case runErr = <-done:
+		case <-time.After(killGrace):
+			// A wedged daemon mustn't hold the room's test lock forever.
+			cmd.Process.Kill()
+			runErr = <-done
+		}
+	}
+
+	result := Result{Duration: time.Since(start), TimedOut: timedOut}
+
+	exitCode := 0
+	var exitErr *exec.ExitError
+	if errors.As(runErr, &exitErr) {
+		exitCode = exitErr.ExitCode()
+	} else if runErr != nil && !errors.Is(runErr, exec.ErrWaitDelay) {
+		return Result{}, fmt.Errorf("docker run failed: %w", runErr)
+	}
+
+	switch {
+	case timedOut:
+		result.Compiled = true
+		result.Tests = parseTestOutput(stdout.String(), nonce)
+		result.Tests = append(result.Tests, TestResult{
+			Name:    "time limit",
+			Message: fmt.Sprintf("stopped after %s", limit),
+		})
+		result.Tests = withMissingTests(result.Tests, expectedTests(sub.Tests))
+		return result, nil
+
+	case exitCode == compileFailedExit:
+		result.CompilerOutput = strings.TrimSpace(stderr.String())
+		return result, nil
+
+	// 125 is Docker itself failing, 126/127 the image lacking the
+	// language's tools.
+	case exitCode >= 125 && exitCode <= 127:
+		return Result{}, fmt.Errorf("docker exited %d: %s", exitCode, strings.TrimSpace(stderr.String()))
+	}
+
+	result.Compiled = true
+	result.Tests = parseTestOutput(stdout.String(), nonce)
+
+	if exitCode != 0 && !anyFailed(result.Tests) {
+		message := strings.TrimSpace(stderr.String())
+		if exitCode == 137 {
+			message = fmt.Sprintf("killed - over the %dMB memory limit?", d.MemoryMB)
+		}
+		result.Tests = append(result.Tests, TestResult{Name: "run", Message: message})
+	}
+	result.Tests = withMissingTests(result.Tests, expectedTests(sub.Tests))
+
+	result.Passed = exitCode == 0 && len(result.Tests) > 0 && !anyFailed(result.Tests)
+	return result, nil
+}
+
+func (d *Docker) binary() string {
+	if d.Binary == "" {
+		return "docker"
+	}
+	return d.Binary
+}
+
+// tarFiles tars up the submission's files for the container.
+func tarFiles(files []sourceFile) (*bytes.Buffer, error) {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, f := range files {
+		if err := tw.WriteHeader(&tar.Header{Name: f.name, Mode: 0644, Size: int64(len(f.body))}); err != nil {
+			return nil, err
+		}
+		if _, err := tw.Write([]byte(f.body)); err != nil {
+			return nil, err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	return &buf, nil
+}
+
+// limitedBuffer keeps the first maxOutputBytes of a stream so a program
+// printing in a loop can't exhaust the server's memory.
+type limitedBuffer struct {
+	bytes.Buffer
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if room := maxOutputBytes - b.Len(); room > 0 {
+		if len(p) > room {
+			b.Buffer.Write(p[:room])
+		} else {
+			b.Buffer.Write(p)
+		}
+	}
+	return len(p), nil
+}
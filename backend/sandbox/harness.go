@@ -0,0 +1,342 @@
+package sandbox
+
+// harnessSource is compiled next to every Java submission. Task tests call
+// test() for each check, and the runner reads the PASS/FAIL lines back.
+// Every harness reads the run's nonce from nonceFile and deletes it as it
+// loads, before the submission can run, and tags each result line with it.
+const harnessSource = `import java.io.*;
+import java.nio.file.*;
+import java.util.concurrent.*;
+
+class Harness {
+    interface Check { void run() throws Exception; }
+
+    private static final String NONCE = readNonce();
+
+    private final PrintStream out = System.out;
+    private int failed;
+
+    // The JVM starts here so the nonce is taken before the submission's
+    // classes load.
+    public static void main(String[] args) throws Exception {
+        TaskTests.main(args);
+    }
+
+    private static String readNonce() {
+        Path path = Paths.get(".nonce");
+        try {
+            String nonce = new String(Files.readAllBytes(path), "UTF-8").trim();
+            Files.delete(path);
+            return nonce;
+        } catch (IOException e) {
+            return "";
+        }
+    }
+
+    static String capture(Check body) throws Exception {
+        PrintStream original = System.out;
+        ByteArrayOutputStream buf = new ByteArrayOutputStream();
+        System.setOut(new PrintStream(buf, true));
+        try {
+            body.run();
+        } finally {
+            System.setOut(original);
+        }
+        return buf.toString();
+    }
+
+    static void expect(boolean ok, String message) {
+        if (!ok) throw new AssertionError(message);
+    }
+
+    static int count(String text, String needle) {
+        int n = 0;
+        for (int i = text.indexOf(needle); i >= 0; i = text.indexOf(needle, i + needle.length())) n++;
+        return n;
+    }
+
+    void test(String name, long timeoutMs, Check check) {
+        ExecutorService pool = Executors.newSingleThreadExecutor(r -> {
+            Thread t = new Thread(r);
+            t.setDaemon(true);
+            return t;
+        });
+        Future<?> result = pool.submit(() -> { check.run(); return null; });
+        try {
+            result.get(timeoutMs, TimeUnit.MILLISECONDS);
+            out.println("PASS " + NONCE + " " + name);
+        } catch (TimeoutException e) {
+            // The stuck thread still owns System.out, so nothing after this
+            // can be trusted.
+            out.println("FAIL " + NONCE + " " + name + ": did not finish within " + timeoutMs + "ms - is there an infinite loop?");
+            out.flush();
+            System.exit(1);
+        } catch (ExecutionException e) {
+            failed++;
+            Throwable cause = e.getCause();
+            String message = cause instanceof AssertionError ? cause.getMessage() : cause.toString();
+            out.println("FAIL " + NONCE + " " + name + ": " + message.replace('\n', ' '));
+        } catch (InterruptedException e) {
+            failed++;
+            out.println("FAIL " + NONCE + " " + name + ": interrupted");
+        }
+        pool.shutdownNow();
+    }
+
+    void finish() {
+        out.flush();
+        System.exit(failed == 0 ? 0 : 1);
+    }
+}
+`
+
+// pythonHarnessSource is the Python harness. A check runs on a daemon
+// thread so one that never returns can be abandoned.
+const pythonHarnessSource = `import contextlib
+import io
+import os
+import sys
+import threading
+
+
+def _read_nonce():
+    try:
+        with open(".nonce") as f:
+            nonce = f.read().strip()
+        os.remove(".nonce")
+        return nonce
+    except OSError:
+        return ""
+
+
+_NONCE = _read_nonce()
+
+
+class Harness:
+    def __init__(self):
+        self.out = sys.stdout
+        self.failed = 0
+
+    @staticmethod
+    def capture(body):
+        buf = io.StringIO()
+        with contextlib.redirect_stdout(buf):
+            body()
+        return buf.getvalue()
+
+    @staticmethod
+    def expect(ok, message):
+        if not ok:
+            raise AssertionError(message)
+
+    @staticmethod
+    def count(text, needle):
+        return text.count(needle)
+
+    def test(self, name, timeout_ms, check):
+        errors = []
+
+        def run():
+            try:
+                check()
+            except AssertionError as e:
+                errors.append(str(e))
+            except BaseException as e:
+                errors.append("%s: %s" % (type(e).__name__, e))
+
+        thread = threading.Thread(target=run, daemon=True)
+        thread.start()
+        thread.join(timeout_ms / 1000)
+        if thread.is_alive():
+            # The stuck thread still owns sys.stdout, so nothing after this
+            # can be trusted.
+            print("FAIL %s %s: did not finish within %dms - is there an infinite loop?" % (_NONCE, name, timeout_ms), file=self.out, flush=True)
+            os._exit(1)
+        if errors:
+            self.failed += 1
+            print("FAIL %s %s: %s" % (_NONCE, name, errors[0].replace("\n", " ")), file=self.out)
+        else:
+            print("PASS %s %s" % (_NONCE, name), file=self.out)
+
+    def finish(self):
+        self.out.flush()
+        os._exit(0 if self.failed == 0 else 1)
+`
+
+// javascriptHarnessSource is the JavaScript harness. Node can't stop a
+// function that never returns from the outside, so each check runs in a vm
+// context whose timeout interrupts it.
+const javascriptHarnessSource = `const fs = require('fs');
+const vm = require('vm');
+
+const nonce = (() => {
+  try {
+    const value = fs.readFileSync('.nonce', 'utf8').trim();
+    fs.unlinkSync('.nonce');
+    return value;
+  } catch (e) {
+    return '';
+  }
+})();
+
+class HarnessFailure extends Error {}
+
+class Harness {
+  constructor() {
+    this.write = process.stdout.write.bind(process.stdout);
+    this.failed = 0;
+  }
+
+  static capture(body) {
+    const original = process.stdout.write;
+    let out = '';
+    process.stdout.write = (chunk) => {
+      out += chunk;
+      return true;
+    };
+    try {
+      body();
+    } finally {
+      process.stdout.write = original;
+    }
+    return out;
+  }
+
+  static expect(ok, message) {
+    if (!ok) throw new HarnessFailure(message);
+  }
+
+  static count(text, needle) {
+    return text.split(needle).length - 1;
+  }
+
+  test(name, timeoutMs, check) {
+    try {
+      vm.runInNewContext('check()', { check }, { timeout: timeoutMs });
+      this.write('PASS ' + nonce + ' ' + name + '\n');
+    } catch (e) {
+      if (e && e.code === 'ERR_SCRIPT_EXECUTION_TIMEOUT') {
+        this.write('FAIL ' + nonce + ' ' + name + ': did not finish within ' + timeoutMs + 'ms - is there an infinite loop?\n');
+        process.exit(1);
+      }
+      this.failed++;
+      const message = e instanceof HarnessFailure ? e.message : String(e);
+      this.write('FAIL ' + nonce + ' ' + name + ': ' + message.replace(/\n/g, ' ') + '\n');
+    }
+  }
+
+  finish() {
+    process.exit(this.failed === 0 ? 0 : 1);
+  }
+}
+
+module.exports = { Harness };
+`
+
+// goHarnessSource is the Go harness, built into the same package as the
+// submission and its tests. A check runs on its own goroutine so one that
+// never returns can be abandoned. harness.go sorts before solution.go, so
+// its nonce is read before any of the submission's variables or init
+// functions.
+const goHarnessSource = `package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+type Harness struct {
+	out    *os.File
+	failed int
+}
+
+type harnessFailure string
+
+var harnessNonce = readHarnessNonce()
+
+func readHarnessNonce() string {
+	b, err := os.ReadFile(".nonce")
+	if err != nil {
+		return ""
+	}
+	os.Remove(".nonce")
+	return strings.TrimSpace(string(b))
+}
+
+func NewHarness() *Harness {
+	return &Harness{out: os.Stdout}
+}
+
+// Capture returns what body prints to os.Stdout.
+func Capture(body func()) string {
+	r, w, err := os.Pipe()
+	if err != nil {
+		panic(err)
+	}
+	original := os.Stdout
+	os.Stdout = w
+	read := make(chan string)
+	go func() {
+		b, _ := io.ReadAll(r)
+		read <- string(b)
+	}()
+	defer func() {
+		os.Stdout = original
+	}()
+	func() {
+		defer w.Close()
+		body()
+	}()
+	return <-read
+}
+
+func Expect(ok bool, message string) {
+	if !ok {
+		panic(harnessFailure(message))
+	}
+}
+
+func Count(text, needle string) int {
+	return strings.Count(text, needle)
+}
+
+func (h *Harness) Test(name string, timeoutMs int, check func()) {
+	done := make(chan string, 1)
+	go func() {
+		defer func() {
+			switch e := recover().(type) {
+			case nil:
+				done <- ""
+			case harnessFailure:
+				done <- string(e)
+			default:
+				done <- fmt.Sprintf("panic: %v", e)
+			}
+		}()
+		check()
+	}()
+
+	select {
+	case message := <-done:
+		if message == "" {
+			fmt.Fprintf(h.out, "PASS %s %s\n", harnessNonce, name)
+			return
+		}
+		h.failed++
+		fmt.Fprintf(h.out, "FAIL %s %s: %s\n", harnessNonce, name, strings.ReplaceAll(message, "\n", " "))
+	case <-time.After(time.Duration(timeoutMs) * time.Millisecond):
+		fmt.Fprintf(h.out, "FAIL %s %s: did not finish within %dms - is there an infinite loop?\n", harnessNonce, name, timeoutMs)
+		os.Exit(1)
+	}
+}
+
+func (h *Harness) Finish() {
+	if h.failed > 0 {
+		os.Exit(1)
+	}
+	os.Exit(0)
+}
+`
@@ -0,0 +1,36 @@
+package sandbox
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// TestsClass is the class a task's Java tests must declare; its main runs
+// the checks through Harness. The JVM starts in Harness, which calls it.
+const TestsClass = "TaskTests"
+
+var publicClassPattern = regexp.MustCompile(`(?m)^\s*public\s+(?:(?:final|abstract)\s+)*class\s+(\w+)`)
+
+type sourceFile struct {
+	name string
+	body string
+}
+
+// javaFiles names the submission after its public class, as javac insists
+// on, and adds the tests and the harness next to it.
+func javaFiles(sub Submission) ([]sourceFile, error) {
+	file := "Main.java"
+	if m := publicClassPattern.FindStringSubmatch(sub.Source); m != nil {
+		file = m[1] + ".java"
+	}
+	if file == TestsClass+".java" || file == "Harness.java" {
+		return nil, fmt.Errorf("class name %s is reserved", strings.TrimSuffix(file, ".java"))
+	}
+
+	return []sourceFile{
+		{file, sub.Source},
+		{TestsClass + ".java", sub.Tests},
+		{"Harness.java", harnessSource},
+	}, nil
+}
@@ -0,0 +1,253 @@
+package sandbox
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	// Judge0's "Multi-file program" language builds whatever the
+	// additional_files zip says to with its compile and run scripts.
+	judge0MultiFileLanguage = 89
+
+	judge0PollInterval = 500 * time.Millisecond
+
+	// judge0QueueWait is how long a submission may wait for a worker on
+	// top of its own time limit before the run is given up on.
+	judge0QueueWait = 60 * time.Second
+)
+
+// Judge0 submission statuses, from GET /statuses.
+const (
+	judge0InQueue           = 1
+	judge0Processing        = 2
+	judge0Accepted          = 3
+	judge0TimeLimitExceeded = 5
+	judge0CompilationError  = 6
+	judge0RuntimeErrorFirst = 7
+	judge0RuntimeErrorLast  = 12
+)
+
+// Judge0 runs submissions on a Judge0 server, self-hosted or through
+// RapidAPI, for deployments that can't reach a Docker daemon. Judge0
+// sandboxes the run itself; the limits here are passed along with each
+// submission.
+type Judge0 struct {
+	URL        string
+	AuthHeader string
+	AuthToken  string
+	MemoryMB   int
+	Timeout    time.Duration
+	Client     *http.Client
+}
+
+type judge0Submission struct {
+	Stdout        *string `json:"stdout"`
+	Stderr        *string `json:"stderr"`
+	CompileOutput *string `json:"compile_output"`
+	Message       *string `json:"message"`
+	Status        struct {
+		ID          int    `json:"id"`
+		Description string `json:"description"`
+	} `json:"status"`
+}
+
+func (j *Judge0) Run(ctx context.Context, sub Submission) (Result, error) {
+	nonce := newNonce()
+	lang, files, err := languageFor(sub, nonce)
+	if err != nil {
+		return Result{}, err
+	}
+	bundle, err := judge0Bundle(lang, files)
+	if err != nil {
+		return Result{}, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, judge0QueueWait+j.Timeout)
+	defer cancel()
+
+	start := time.Now()
+	token, err := j.submit(ctx, map[string]interface{}{
+		"language_id":                  judge0MultiFileLanguage,
+		"additional_files":             bundle,
+		"cpu_time_limit":               j.Timeout.Seconds(),
+		"wall_time_limit":              j.Timeout.Seconds(),
+		"memory_limit":                 j.MemoryMB * 1024,
+		"max_processes_and_or_threads": 128,
+		"enable_network":               false,
+	})
+	if err != nil {
+		return Result{}, err
+	}
+	sub.report(StatusQueued)
+
+	status := StatusQueued
+	for {
+		select {
+		case <-time.After(judge0PollInterval):
+		case <-ctx.Done():
+			return Result{}, fmt.Errorf("judge0 submission %s: %w", token, ctx.Err())
+		}
+
+		s, err := j.fetch(ctx, token)
+		if err != nil {
+			return Result{}, err
+		}
+
+		switch s.Status.ID {
+		case judge0InQueue:
+			continue
+		case judge0Processing:
+			if status != StatusRunning {
+				status = StatusRunning
+				sub.report(status)
+			}
+			continue
+		}
+
+		result, err := j.result(s, nonce, expectedTests(sub.Tests))
+		result.Duration = time.Since(start)
+		return result, err
+	}
+}
+
+func (j *Judge0) result(s *judge0Submission, nonce string, expected []string) (Result, error) {
+	stdout := decodeJudge0(s.Stdout)
+	stderr := decodeJudge0(s.Stderr)
+
+	switch id := s.Status.ID; {
+	case id == judge0CompilationError:
+		return Result{CompilerOutput: strings.TrimSpace(decodeJudge0(s.CompileOutput))}, nil
+
+	case id == judge0TimeLimitExceeded:
+		tests := append(parseTestOutput(stdout, nonce), TestResult{
+			Name:    "time limit",
+			Message: fmt.Sprintf("stopped after %s", j.Timeout),
+		})
+		return Result{Compiled: true, TimedOut: true, Tests: withMissingTests(tests, expected)}, nil
+
+	case id == judge0Accepted, id >= judge0RuntimeErrorFirst && id <= judge0RuntimeErrorLast:
+		result := Result{Compiled: true, Tests: parseTestOutput(stdout, nonce)}
+		if id != judge0Accepted && !anyFailed(result.Tests) {
+			message := strings.TrimSpace(stderr)
+			if message == "" {
+				message = s.Status.Description
+			}
+			result.Tests = append(result.Tests, TestResult{Name: "run", Message: message})
+		}
+		result.Tests = withMissingTests(result.Tests, expected)
+		result.Passed = id == judge0Accepted && len(result.Tests) > 0 && !anyFailed(result.Tests)
+		return result, nil
+	}
+
+	// Internal and exec format errors are Judge0's problem, not the
+	// player's.
+	return Result{}, fmt.Errorf("judge0 status %d (%s): %s", s.Status.ID, s.Status.Description, decodeJudge0(s.Message))
+}
+
+func (j *Judge0) submit(ctx context.Context, body map[string]interface{}) (string, error) {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return "", err
+	}
+
+	var created struct {
+		Token string `json:"token"`
+	}
+	if err := j.do(ctx, http.MethodPost, "/submissions?base64_encoded=true&wait=false", payload, &created); err != nil {
+		return "", err
+	}
+	if created.Token == "" {
+		return "", fmt.Errorf("judge0 returned no submission token")
+	}
+	return created.Token, nil
+}
+
+func (j *Judge0) fetch(ctx context.Context, token string) (*judge0Submission, error) {
+	var s judge0Submission
+	path := "/submissions/" + url.PathEscape(token) +
+		"?base64_encoded=true&fields=stdout,stderr,compile_output,message,status"
+	if err := j.do(ctx, http.MethodGet, path, nil, &s); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+func (j *Judge0) do(ctx context.Context, method, path string, body []byte, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, method, strings.TrimRight(j.URL, "/")+path, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if j.AuthToken != "" {
+		req.Header.Set(j.AuthHeader, j.AuthToken)
+		// RapidAPI also wants to be told which API the key is for.
+		if strings.EqualFold(j.AuthHeader, "X-RapidAPI-Key") {
+			req.Header.Set("X-RapidAPI-Host", req.URL.Host)
+		}
+	}
+
+	client := j.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("judge0 request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		snippet, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("judge0 %s %s returned %d: %s", method, req.URL.Path, resp.StatusCode, strings.TrimSpace(string(snippet)))
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// judge0Bundle zips the sources with the scripts the multi-file language
+// runs, base64 encoded as additional_files expects.
+func judge0Bundle(lang language, files []sourceFile) (string, error) {
+	files = append(files,
+		sourceFile{"compile", "#!/bin/bash\n" + lang.build + "\n"},
+		sourceFile{"run", "#!/bin/bash\n" + lang.run + "\n"},
+	)
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, f := range files {
+		w, err := zw.Create(f.name)
+		if err != nil {
+			return "", err
+		}
+		if _, err := io.WriteString(w, f.body); err != nil {
+			return "", err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(buf.Bytes()), nil
+}
+
+func decodeJudge0(field *string) string {
+	if field == nil {
+		return ""
+	}
+	decoded, err := base64.StdEncoding.DecodeString(strings.ReplaceAll(*field, "\n", ""))
+	if err != nil {
+		return *field
+	}
+	if len(decoded) > maxOutputBytes {
+		decoded = decoded[:maxOutputBytes]
+	}
+	return string(decoded)
+}
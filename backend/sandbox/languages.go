@@ -0,0 +1,111 @@
+package sandbox
+
+import (
+	"fmt"
+	"time"
+)
+
+// Languages are the languages tasks can be written in. A submission with
+// no language is Java.
+var Languages = []string{"java", "python", "javascript", "go"}
+
+// language is how one language's submissions are laid out and run. build
+// and run are shell commands run in the directory holding the files; build
+// failing means the source didn't compile, and its stderr is shown to the
+// player. run starts the harness before the tests, so it has taken the
+// nonce before any of the submission's code runs.
+type language struct {
+	files      func(sub Submission) ([]sourceFile, error)
+	build, run string
+
+	// scratchMB is the space the Docker runner gives the build, and
+	// buildTime how long it gets on top of the run's time limit.
+	scratchMB int
+	buildTime time.Duration
+}
+
+var languages = map[string]language{
+	"java": {
+		files:     javaFiles,
+		build:     "mkdir -p classes && javac -encoding UTF-8 -nowarn -d classes *.java",
+		run:       "java -XX:-UsePerfData -Xss4m -cp classes Harness",
+		scratchMB: 64,
+	},
+	"python": {
+		files:     pythonFiles,
+		build:     "python3 -m py_compile solution.py",
+		run:       `python3 -c 'import harness, runpy; runpy.run_path("task_tests.py", run_name="__main__")'`,
+		scratchMB: 64,
+	},
+	"javascript": {
+		files:     javascriptFiles,
+		build:     "node --check solution.js",
+		run:       `node -e 'require("./harness"); require("./task_tests")'`,
+		scratchMB: 64,
+	},
+	// Go rebuilds the parts of the standard library it uses on every run
+	// in a fresh container, which takes a while.
+	"go": {
+		files:     goFiles,
+		build:     "HOME=/tmp GOCACHE=/tmp/go-cache GOTOOLCHAIN=local GOWORK=off go build -o task .",
+		run:       "./task",
+		scratchMB: 512,
+		buildTime: 30 * time.Second,
+	},
+}
+
+// Supported reports whether tasks in lang can be judged.
+func Supported(lang string) bool {
+	_, ok := languages[languageName(lang)]
+	return ok
+}
+
+func languageName(lang string) string {
+	if lang == "" {
+		return "java"
+	}
+	return lang
+}
+
+// languageFor lays out the submission's files, with the run's nonce.
+func languageFor(sub Submission, nonce string) (language, []sourceFile, error) {
+	lang, ok := languages[languageName(sub.Language)]
+	if !ok {
+		return language{}, nil, fmt.Errorf("unsupported language %q", sub.Language)
+	}
+	files, err := lang.files(sub)
+	if err != nil {
+		return language{}, nil, err
+	}
+	return lang, append(files, sourceFile{nonceFile, nonce}), nil
+}
+
+// pythonFiles puts the submission in solution.py for the tests to import.
+func pythonFiles(sub Submission) ([]sourceFile, error) {
+	return []sourceFile{
+		{"solution.py", sub.Source},
+		{"task_tests.py", sub.Tests},
+		{"harness.py", pythonHarnessSource},
+	}, nil
+}
+
+// javascriptFiles puts the submission in solution.js for the tests to
+// require, so templates end with a module.exports of what they test.
+func javascriptFiles(sub Submission) ([]sourceFile, error) {
+	return []sourceFile{
+		{"solution.js", sub.Source},
+		{"task_tests.js", sub.Tests},
+		{"harness.js", javascriptHarnessSource},
+	}, nil
+}
+
+// goFiles builds the submission, the tests and the harness as one main
+// package; the tests provide main.
+func goFiles(sub Submission) ([]sourceFile, error) {
+	return []sourceFile{
+		{"go.mod", "module task\n\ngo 1.21\n"},
+		{"solution.go", sub.Source},
+		{"task_tests.go", sub.Tests},
+		{"harness.go", goHarnessSource},
+	}, nil
+}
@@ -0,0 +1,91 @@
+package sandbox
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/hex"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// nonceFile holds the run's nonce. The harness reads and deletes it before
+// any of the submission's code runs, then puts the nonce on every result
+// line, so a submission printing PASS lines of its own isn't believed.
+const nonceFile = ".nonce"
+
+// testNamePattern finds the name each check is registered under: the first
+// argument of a harness test() or Test() call.
+var testNamePattern = regexp.MustCompile(`\.(?:test|Test)\(\s*("(?:[^"\\\n]|\\.)*"|'(?:[^'\\\n]|\\.)*')`)
+
+// expectedTests are the checks the tests register, in order. Names built
+// at run time can't be known up front and aren't listed.
+func expectedTests(tests string) []string {
+	var names []string
+	for _, m := range testNamePattern.FindAllStringSubmatch(tests, -1) {
+		quoted := m[1]
+		if quoted[0] == '\'' {
+			quoted = `"` + strings.ReplaceAll(quoted[1:len(quoted)-1], `"`, `\"`) + `"`
+		}
+		if name, err := strconv.Unquote(quoted); err == nil {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// parseTestOutput reads the harness's PASS/FAIL lines, ignoring any that
+// don't carry the run's nonce.
+func parseTestOutput(out, nonce string) []TestResult {
+	pass, fail := "PASS "+nonce+" ", "FAIL "+nonce+" "
+	var tests []TestResult
+	scanner := bufio.NewScanner(strings.NewReader(out))
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, pass):
+			tests = append(tests, TestResult{Name: strings.TrimPrefix(line, pass), Passed: true})
+		case strings.HasPrefix(line, fail):
+			name, message, _ := strings.Cut(strings.TrimPrefix(line, fail), ": ")
+			tests = append(tests, TestResult{Name: name, Message: message})
+		}
+	}
+	return tests
+}
+
+// withMissingTests adds a failure for every expected check the run never
+// reported, so a run that exits early can't pass on the checks it skipped.
+func withMissingTests(tests []TestResult, expected []string) []TestResult {
+	reported := make(map[string]bool, len(tests))
+	for _, t := range tests {
+		reported[t.Name] = true
+	}
+	for _, name := range expected {
+		if !reported[name] {
+			reported[name] = true
+			tests = append(tests, TestResult{Name: name, Message: "no result - the run ended before this check finished"})
+		}
+	}
+	return tests
+}
+
+func anyFailed(tests []TestResult) bool {
+	for _, t := range tests {
+		if !t.Passed {
+			return true
+		}
+	}
+	return false
+}
+
+func randomSuffix() string {
+	b := make([]byte, 6)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func newNonce() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
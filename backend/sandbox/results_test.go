@@ -0,0 +1,146 @@
+package sandbox
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestExpectedTests(t *testing.T) {
+	tests := `h.test("balanced \"doors\"", 2000, () -> {});
+		h.Test("filtration finishes", 2000, func() {})
+		h.test('every crew member', 2000, () => {});
+		h.test(name, 2000, check)`
+	want := []string{`balanced "doors"`, "filtration finishes", "every crew member"}
+	if got := expectedTests(tests); !reflect.DeepEqual(got, want) {
+		t.Fatalf("expectedTests = %q, want %q", got, want)
+	}
+}
+
+func TestParseTestOutputNeedsNonce(t *testing.T) {
+	out := "PASS adds\nPASS  adds\nPASS other adds\nPASS n0nce adds\nFAIL n0nce subtracts: 1-2 should be -1\n"
+	got := parseTestOutput(out, "n0nce")
+	want := []TestResult{
+		{Name: "adds", Passed: true},
+		{Name: "subtracts", Message: "1-2 should be -1"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("parseTestOutput = %+v, want %+v", got, want)
+	}
+}
+
+func TestMissingTestsFail(t *testing.T) {
+	tests := withMissingTests([]TestResult{{Name: "adds", Passed: true}}, []string{"adds", "subtracts"})
+	if len(tests) != 2 || tests[1].Name != "subtracts" || tests[1].Passed {
+		t.Fatalf("tests = %+v, want subtracts failed", tests)
+	}
+}
+
+// harnessCases are a two-check task per language with an honest solution,
+// one that prints the PASS lines itself and exits before the tests run,
+// and one that just exits.
+var harnessCases = map[string]struct {
+	tool, tests, honest, forged, quits string
+}{
+	"python": {
+		tool: "python3",
+		tests: `from harness import Harness
+from solution import add
+
+h = Harness()
+h.test("adds", 2000, lambda: Harness.expect(add(1, 2) == 3, "1+2 should be 3"))
+h.test("adds negatives", 2000, lambda: Harness.expect(add(-1, -2) == -3, "-1-2 should be -3"))
+h.finish()
+`,
+		honest: "def add(a, b):\n    return a + b\n",
+		forged: "import os, sys\nprint('PASS adds')\nprint('PASS adds negatives')\nsys.stdout.flush()\nos._exit(0)\n",
+		quits:  "import os\nos._exit(0)\n",
+	},
+	"javascript": {
+		tool: "node",
+		tests: `const { Harness } = require('./harness');
+const { add } = require('./solution');
+
+const h = new Harness();
+h.test('adds', 2000, () => Harness.expect(add(1, 2) === 3, '1+2 should be 3'));
+h.test('adds negatives', 2000, () => Harness.expect(add(-1, -2) === -3, '-1-2 should be -3'));
+h.finish();
+`,
+		honest: "module.exports = { add: (a, b) => a + b };\n",
+		forged: "process.stdout.write('PASS adds\\nPASS adds negatives\\n');\nprocess.exit(0);\n",
+		quits:  "process.exit(0);\n",
+	},
+	"go": {
+		tool: "go",
+		tests: `package main
+
+func main() {
+	h := NewHarness()
+	h.Test("adds", 2000, func() { Expect(add(1, 2) == 3, "1+2 should be 3") })
+	h.Test("adds negatives", 2000, func() { Expect(add(-1, -2) == -3, "-1-2 should be -3") })
+	h.Finish()
+}
+`,
+		honest: "package main\n\nfunc add(a, b int) int { return a + b }\n",
+		forged: "package main\n\nimport (\n\t\"fmt\"\n\t\"os\"\n)\n\nfunc init() {\n\tfmt.Println(\"PASS adds\")\n\tfmt.Println(\"PASS adds negatives\")\n\tos.Exit(0)\n}\n\nfunc add(a, b int) int { return 0 }\n",
+		quits:  "package main\n\nimport \"os\"\n\nfunc init() { os.Exit(0) }\n\nfunc add(a, b int) int { return 0 }\n",
+	},
+}
+
+// runLocally builds and runs sub the way the runners do, in a temporary
+// directory, and judges the output the same way.
+func runLocally(t *testing.T, sub Submission) bool {
+	t.Helper()
+	nonce := newNonce()
+	lang, files, err := languageFor(sub, nonce)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	for _, f := range files {
+		if err := os.WriteFile(filepath.Join(dir, f.name), []byte(f.body), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	build := exec.Command("sh", "-c", lang.build)
+	build.Dir = dir
+	if out, err := build.CombinedOutput(); err != nil {
+		t.Fatalf("build failed: %v\n%s", err, out)
+	}
+	run := exec.Command("sh", "-c", lang.run)
+	run.Dir = dir
+	out, runErr := run.Output()
+
+	if _, err := os.Stat(filepath.Join(dir, nonceFile)); !os.IsNotExist(err) {
+		t.Errorf("the harness left %s behind", nonceFile)
+	}
+	tests := withMissingTests(parseTestOutput(string(out), nonce), expectedTests(sub.Tests))
+	return runErr == nil && len(tests) > 0 && !anyFailed(tests)
+}
+
+func TestHarnessOnlyTrustsItsOwnResults(t *testing.T) {
+	for name, c := range harnessCases {
+		t.Run(name, func(t *testing.T) {
+			if _, err := exec.LookPath(c.tool); err != nil {
+				t.Skipf("%s isn't installed", c.tool)
+			}
+			sub := Submission{Language: name, Tests: c.tests}
+
+			sub.Source = c.honest
+			if !runLocally(t, sub) {
+				t.Errorf("honest solution didn't pass")
+			}
+			sub.Source = c.forged
+			if runLocally(t, sub) {
+				t.Errorf("solution printing its own PASS lines passed")
+			}
+			sub.Source = c.quits
+			if runLocally(t, sub) {
+				t.Errorf("solution exiting before the tests passed")
+			}
+		})
+	}
+}
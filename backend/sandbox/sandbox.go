@@ -0,0 +1,55 @@
+// Package sandbox runs player submissions against a task's unit tests in
+// isolation.
+package sandbox
+
+import (
+	"context"
+	"time"
+)
+
+// Statuses a runner reports through Submission.Progress while it works.
+const (
+	StatusQueued  = "QUEUED"
+	StatusRunning = "RUNNING"
+)
+
+// Submission is one run request: the player's source and the task's tests.
+type Submission struct {
+	Task     string
+	Language string
+	Source   string
+	Tests    string
+
+	// Progress, if set, is called as the run moves through the statuses
+	// above. It may be called from any goroutine.
+	Progress func(status string)
+}
+
+func (s Submission) report(status string) {
+	if s.Progress != nil {
+		s.Progress(status)
+	}
+}
+
+type TestResult struct {
+	Name    string `json:"name"`
+	Passed  bool   `json:"passed"`
+	Message string `json:"message,omitempty"`
+}
+
+// Result is what a run produced. Compiled is false when the source never
+// got as far as the tests; CompilerOutput then says why.
+type Result struct {
+	Passed         bool          `json:"passed"`
+	Compiled       bool          `json:"compiled"`
+	CompilerOutput string        `json:"compilerOutput,omitempty"`
+	Tests          []TestResult  `json:"tests,omitempty"`
+	TimedOut       bool          `json:"timedOut,omitempty"`
+	Duration       time.Duration `json:"-"`
+}
+
+// CodeRunner executes a submission. An error means the runner itself broke
+// (Docker is down, say), not that the player's code is wrong.
+type CodeRunner interface {
+	Run(ctx context.Context, sub Submission) (Result, error)
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"sort"
+)
+
+// What each contribution is worth towards a player's score at the end of
+// the game.
+const (
+	pointsPerTestRun          = 1
+	pointsPerStagePassed      = 10
+	pointsPerCorrectVote      = 5
+	pointsPerSabotageSurvived = 2
+)
+
+// PlayerScore is what one player did for the game that just ended, as
+// GAME_ENDED reports it.
+type PlayerScore struct {
+	PlayerID          string `json:"playerID"`
+	Username          string `json:"username"`
+	TestsRun          int    `json:"testsRun"`
+	StagesPassed      int    `json:"stagesPassed"`
+	CorrectVotes      int    `json:"correctVotes"`
+	SabotagesSurvived int    `json:"sabotagesSurvived"`
+	Score             int    `json:"score"`
+}
+
+// playerScore adds up player's contribution. Runs on the room goroutine.
+func (r *Room) playerScore(player *Player) PlayerScore {
+	s := PlayerScore{
+		PlayerID:          player.ID,
+		Username:          player.Username,
+		TestsRun:          r.testsRun[player.ID],
+		StagesPassed:      r.stagesPassed[player.ID],
+		CorrectVotes:      r.correctVotes[player.ID],
+		SabotagesSurvived: r.sabotagesSurvived[player.ID],
+	}
+	s.Score = s.TestsRun*pointsPerTestRun +
+		s.StagesPassed*pointsPerStagePassed +
+		s.CorrectVotes*pointsPerCorrectVote +
+		s.SabotagesSurvived*pointsPerSabotageSurvived
+	return s
+}
+
+// scores ranks every player by score, best first, with ties in name order.
+// Runs on the room goroutine.
+func (r *Room) scores() []PlayerScore {
+	scores := make([]PlayerScore, 0, len(r.players))
+	for _, player := range r.players {
+		scores = append(scores, r.playerScore(player))
+	}
+	sort.Slice(scores, func(i, j int) bool {
+		if scores[i].Score != scores[j].Score {
+			return scores[i].Score > scores[j].Score
+		}
+		return scores[i].Username < scores[j].Username
+	})
+	return scores
+}
+
+// mvpOf is the top of ranked scores, or nil if nobody scored at all.
+func mvpOf(scores []PlayerScore) *PlayerScore {
+	if len(scores) == 0 || scores[0].Score == 0 {
+		return nil
+	}
+	return &scores[0]
+}
+
+// survivedSabotage credits everyone the sabotage that has just ended was
+// aimed at: the non-impostors still in the game. Runs on the room goroutine.
+func (r *Room) survivedSabotage() {
+	for id, player := range r.players {
+		if player.Role != "IMPOSTER" && !player.IsEliminated {
+			r.sabotagesSurvived[id]++
+		}
+	}
+}
@@ -0,0 +1,340 @@
+package main
+
+import (
+	"log"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"code-mafia-backend/database"
+
+	"github.com/gorilla/mux"
+)
+
+const (
+	seasonRefreshInterval = 5 * time.Minute
+	seasonStatsRetention  = 30 * 24 * time.Hour
+)
+
+// seasonState is the season this server is running and its task pack.
+// Rooms read it when a game starts, so a rollover never swaps tasks out
+// from under a game in progress.
+type seasonState struct {
+	refreshMu sync.Mutex
+
+	mu     sync.RWMutex
+	season *database.Season
+	tasks  []database.SeasonTask
+}
+
+var seasons seasonState
+
+func seasonIDOf(s *database.Season) string {
+	if s == nil {
+		return ""
+	}
+	return s.ID
+}
+
+func currentSeason() *database.Season {
+	seasons.mu.RLock()
+	defer seasons.mu.RUnlock()
+	return seasons.season
+}
+
+// initSeasons loads the season schedule and keeps following it. Without
+// Supabase there are no seasons and games use the standard tasks.
+func initSeasons() {
+	if database.SupabaseClient == nil {
+		log.Println("🗓️ Seasons disabled (Supabase not configured)")
+		return
+	}
+
+	next := refreshSeasons()
+	go func() {
+		for {
+			wait := seasonRefreshInterval
+			if !next.IsZero() {
+				if untilBoundary := time.Until(next); untilBoundary < wait {
+					wait = untilBoundary + time.Second
+				}
+			}
+			time.Sleep(wait)
+			next = refreshSeasons()
+		}
+	}()
+}
+
+// refreshSeasons switches to the season that covers now, rolling over if
+// it changed, and returns when the schedule next changes (zero if never).
+func refreshSeasons() time.Time {
+	seasons.refreshMu.Lock()
+	defer seasons.refreshMu.Unlock()
+
+	schedule, err := database.ListSeasons()
+	if err != nil {
+		log.Printf("Failed to load seasons: %v", err)
+		return time.Time{}
+	}
+
+	now := time.Now()
+	var active *database.Season
+	var next time.Time
+	for i := range schedule {
+		s := schedule[i]
+		if s.ActiveAt(now) && active == nil {
+			active = &s
+		}
+		for _, boundary := range []time.Time{s.StartsAt, s.EndsAt} {
+			if boundary.After(now) && (next.IsZero() || boundary.Before(next)) {
+				next = boundary
+			}
+		}
+	}
+
+	// Packs are reloaded every time so edits made mid-season are picked up.
+	var tasks []database.SeasonTask
+	if active != nil {
+		tasks, err = database.GetSeasonTasks(active.ID)
+		if err != nil {
+			log.Printf("Failed to load tasks for season %s: %v", active.ID, err)
+			return time.Time{}
+		}
+	}
+
+	seasons.mu.Lock()
+	previous := seasons.season
+	seasons.season = active
+	seasons.tasks = tasks
+	seasons.mu.Unlock()
+
+	if seasonIDOf(previous) == seasonIDOf(active) {
+		return next
+	}
+
+	if previous != nil {
+		if err := database.ExpireSeasonStats(previous.ID, seasonStatsRetention); err != nil {
+			log.Printf("Failed to expire stats for season %s: %v", previous.ID, err)
+		}
+		log.Printf("🗓️ Season %s ended", previous.ID)
+	}
+	if active != nil {
+		log.Printf("🗓️ Season %s (%s) started with %d themed tasks", active.ID, active.Name, len(tasks))
+	}
+	incMetric("season_rollovers_total")
+	return next
+}
+
+// currentTaskPool is every task a new game may draw from: the season's
+// themed tasks for the stages its pack covers and the standard tasks (see
+// standardTasks) for the rest. Tasks are fresh copies since rooms store translations on them.
+func currentTaskPool() []*Task {
+	seasons.mu.RLock()
+	pack := seasons.tasks
+	seasons.mu.RUnlock()
+
+	covered := make(map[int]bool)
+	pool := make([]*Task, 0, len(pack)+3)
+	for _, t := range pack {
+		covered[t.Stage] = true
+		pool = append(pool, &Task{
+			ID:                      t.ID,
+			Stage:                   t.Stage,
+			Title:                   t.Title,
+			Description:             t.Description,
+			Template:                t.Template,
+			Tests:                   t.Tests,
+			TitleTranslations:       make(map[string]string),
+			DescriptionTranslations: make(map[string]string),
+		})
+	}
+
+	for _, t := range standardTasks() {
+		if !covered[t.Stage] {
+			pool = append(pool, t)
+		}
+	}
+	return pool
+}
+
+// pickStageTasks draws one task per stage, in stage order. Tasks every
+// prefer accepts are drawn first; a stage with none of them falls back to
+// those the earlier ones accept, and then to the rest, so a narrow filter
+// never leaves a stage out.
+func pickStageTasks(pool []*Task, prefer ...func(*Task) bool) []*Task {
+	byStage := make(map[int][]*Task)
+	maxStage := 0
+	for _, t := range pool {
+		byStage[t.Stage] = append(byStage[t.Stage], t)
+		if t.Stage > maxStage {
+			maxStage = t.Stage
+		}
+	}
+
+	tasks := make([]*Task, 0, maxStage)
+	for stage := 1; stage <= maxStage; stage++ {
+		candidates := byStage[stage]
+		for n := len(prefer); n > 0; n-- {
+			if preferred := tasksMatching(candidates, prefer[:n]); len(preferred) > 0 {
+				candidates = preferred
+				break
+			}
+		}
+		if len(candidates) > 0 {
+			tasks = append(tasks, candidates[rand.Intn(len(candidates))])
+		}
+	}
+	return tasks
+}
+
+func tasksMatching(tasks []*Task, filters []func(*Task) bool) []*Task {
+	var matching []*Task
+	for _, t := range tasks {
+		if acceptsAll(filters, t) {
+			matching = append(matching, t)
+		}
+	}
+	return matching
+}
+
+func acceptsAll(filters []func(*Task) bool, t *Task) bool {
+	for _, accepts := range filters {
+		if !accepts(t) {
+			return false
+		}
+	}
+	return true
+}
+
+// restoreTasks finds a restored game's tasks again by ID, drawing afresh
+// if any of them has since left the pool.
+func restoreTasks(ids []string) []*Task {
+	pool := currentTaskPool()
+	byID := make(map[string]*Task, len(pool))
+	for _, t := range pool {
+		byID[t.ID] = t
+	}
+
+	tasks := make([]*Task, 0, len(ids))
+	for _, id := range ids {
+		t, ok := byID[id]
+		if !ok {
+			return pickStageTasks(pool)
+		}
+		tasks = append(tasks, t)
+	}
+	if len(tasks) == 0 {
+		return pickStageTasks(pool)
+	}
+	return tasks
+}
+
+func taskIDs(tasks []*Task) []string {
+	ids := make([]string, len(tasks))
+	for i, t := range tasks {
+		ids[i] = t.ID
+	}
+	return ids
+}
+
+type seasonResult struct {
+	playerID, username string
+	won                bool
+}
+
+// seasonResults is built on the room goroutine and saved off it by
+// recordSeasonResults.
+func (r *Room) seasonResults(winnerRole string) []seasonResult {
+	results := make([]seasonResult, 0, len(r.players))
+	for _, player := range r.players {
+		results = append(results, seasonResult{player.ID, player.Username, roleTeam(player.Role) == winnerRole})
+	}
+	return results
+}
+
+// recordSeasonResults counts the game towards the season it started in.
+func recordSeasonResults(season string, results []seasonResult) {
+	for _, res := range results {
+		if err := database.RecordSeasonResult(season, res.playerID, res.username, res.won); err != nil {
+			log.Printf("Failed to record season result for %s: %v", res.playerID, err)
+		}
+	}
+	log.Printf("🗓️ Recorded %d results for season %s", len(results), season)
+}
+
+func handleGetCurrentSeason(w http.ResponseWriter, r *http.Request) {
+	season := currentSeason()
+	if season == nil {
+		writeJSON(w, http.StatusOK, map[string]interface{}{"season": nil})
+		return
+	}
+
+	seasons.mu.RLock()
+	themedTasks := len(seasons.tasks)
+	seasons.mu.RUnlock()
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"season":      season,
+		"themedTasks": themedTasks,
+		"endsIn":      int(time.Until(season.EndsAt).Seconds()),
+	})
+}
+
+func handleSeasonLeaderboard(w http.ResponseWriter, r *http.Request) {
+	limit := 50
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxPageSize {
+			writeJSON(w, http.StatusBadRequest, map[string]interface{}{
+				"error": "limit must be between 1 and " + strconv.Itoa(maxPageSize),
+			})
+			return
+		}
+		limit = n
+	}
+
+	seasonID := mux.Vars(r)["id"]
+	entries, err := database.GetSeasonLeaderboard(seasonID, limit)
+	if err != nil {
+		log.Printf("Season leaderboard query failed: %v", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"error": "failed to load leaderboard"})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"season":  seasonID,
+		"entries": entries,
+	})
+}
+
+func handleSeasonPlayerStats(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	stats, err := database.GetSeasonStats(vars["id"], vars["playerId"])
+	if err != nil {
+		log.Printf("Season stats query failed: %v", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"error": "failed to load season stats"})
+		return
+	}
+	if stats == nil {
+		stats = &database.SeasonStats{PlayerID: vars["playerId"]}
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"season": vars["id"],
+		"stats":  stats,
+	})
+}
+
+// handleAdminRefreshSeasons applies schedule edits made in Supabase
+// without waiting for the next periodic refresh.
+func handleAdminRefreshSeasons(w http.ResponseWriter, r *http.Request) {
+	if database.SupabaseClient == nil {
+		writeStoreError(w, database.ErrSupabaseNotConfigured)
+		return
+	}
+
+	refreshSeasons()
+	writeJSON(w, http.StatusOK, map[string]interface{}{"season": currentSeason()})
+}
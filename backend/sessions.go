@@ -0,0 +1,171 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"time"
+
+	"code-mafia-backend/database"
+)
+
+const (
+	// reconnectGrace is how long a player who drops mid-game keeps their
+	// seat before they are written off as eliminated.
+	reconnectGrace = 60 * time.Second
+
+	sessionTTL = 2 * time.Hour
+)
+
+// issueSession gives a client the token it presents to reconnect to the
+// same player if its connection drops mid-game. Only a connection that has
+// proven the player ID is its own gets one, and a player already seated
+// keeps the token they have: everyone in the room can see their ID, so a
+// second connection using it mustn't be able to take their seat. Runs on
+// the room goroutine, before the JOIN seats the player.
+func (r *Room) issueSession(c *Client) {
+	if c.Resumed || !c.Proven {
+		return
+	}
+	if r.players[c.PlayerID] != nil {
+		session, err := database.LoadPlayerSession(c.PlayerID)
+		if err == nil && session.RoomID == r.ID {
+			return
+		}
+	}
+
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		log.Printf("⚠️ Failed to generate session token: %v", err)
+		return
+	}
+	token := hex.EncodeToString(b)
+
+	session := database.PlayerSession{Token: token, RoomID: c.RoomID}
+	if err := database.SavePlayerSession(c.PlayerID, session, sessionTTL); err != nil {
+		log.Printf("⚠️ Failed to save session for %s: %v", c.PlayerID, err)
+		return
+	}
+
+	c.sendMessage(Message{
+		Type: "SESSION",
+		Data: map[string]interface{}{
+			"token":    token,
+			"playerID": c.PlayerID,
+			"roomID":   c.RoomID,
+		},
+	})
+}
+
+// validSession reports whether token is the one last issued to playerID
+// for roomID.
+func validSession(playerID, roomID, token string) bool {
+	if playerID == "" || token == "" {
+		return false
+	}
+	session, err := database.LoadPlayerSession(playerID)
+	if err != nil || session.RoomID != roomID {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(session.Token), []byte(token)) == 1
+}
+
+// holdSeat keeps a player who lost their connection mid-game in the game
+// for reconnectGrace, then drops them. Runs on the room goroutine.
+func (r *Room) holdSeat(player *Player) {
+	player.Disconnected = true
+
+	log.Printf("📡 Holding %s's seat for %s (room %s)", player.Username, reconnectGrace, r.ID)
+
+	r.emitSystemChat("system.disconnected", map[string]interface{}{"name": player.Username})
+	r.broadcastPlayerList()
+
+	if timer := r.reconnects[player.ID]; timer != nil {
+		timer.Stop()
+	}
+	playerID := player.ID
+	r.reconnects[playerID] = r.schedule(reconnectGrace, func() {
+		delete(r.reconnects, playerID)
+		if p := r.players[playerID]; p != nil && p.Disconnected && r.clientFor(playerID) == nil {
+			r.removePlayer(p)
+		}
+	})
+}
+
+// resume hands a held seat back to a reconnecting client, hanging up any
+// older connection for the same player that hasn't noticed it is dead yet.
+// Runs on the room goroutine.
+func (r *Room) resume(client *Client) {
+	for other := range r.clients {
+		if other.PlayerID == client.PlayerID {
+			delete(r.clients, other)
+			other.close()
+		}
+	}
+
+	if timer := r.reconnects[client.PlayerID]; timer != nil {
+		timer.Stop()
+		delete(r.reconnects, client.PlayerID)
+	}
+
+	player := r.players[client.PlayerID]
+	if !player.Disconnected {
+		return
+	}
+	player.Disconnected = false
+
+	log.Printf("♻️ %s resumed their seat in room %s (conn=%s)", player.Username, r.ID, client.ConnID)
+
+	r.emitSystemChat("system.reconnected", map[string]interface{}{"name": player.Username})
+	r.broadcastPlayerList()
+}
+
+// resync brings a resumed client up to date with a game that carried on
+// without it. Runs on the room goroutine.
+func (r *Room) resync(c *Client) {
+	stateData, _ := json.Marshal(Message{Type: "GAME_STATE", Data: r.buildGameStatePayload()})
+	c.deliver(r.redactRolesFor(c.PlayerID, stateData))
+	r.sendAssignedTask(c)
+
+	if r.gameState.isImpostor(c.PlayerID) {
+		c.sendMessage(Message{
+			Type: "IMPOSTOR_TEAM",
+			Data: map[string]interface{}{"impostors": r.impostorTeam()},
+		})
+	}
+
+	if r.votingActive {
+		hasVoted := make(map[string]bool)
+		for id := range r.votes {
+			hasVoted[id] = true
+		}
+		c.sendMessage(Message{
+			Type: "VOTE_UPDATE",
+			Data: map[string]interface{}{"hasVoted": hasVoted},
+		})
+	}
+
+	r.sendSabotageState(c)
+}
+
+// sendSabotageState tells a resumed client about the sabotage under way,
+// for what is left of it. Runs on the room goroutine.
+func (r *Room) sendSabotageState(c *Client) {
+	left := time.Until(r.sabotageEndTime)
+	if !r.sabotageActive || left <= 0 {
+		return
+	}
+
+	c.sendMessage(Message{
+		Type: "SABOTAGE_STARTED",
+		Data: map[string]interface{}{
+			"type":     r.sabotageType,
+			"duration": left.Milliseconds(),
+		},
+	})
+	if r.sabotageType == "SWAP" {
+		r.sendSwapPartner(c, left)
+	}
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"code-mafia-backend/database"
+)
+
+func newTestClient(roomID, playerID string) *Client {
+	return &Client{
+		RoomID:        roomID,
+		PlayerID:      playerID,
+		send:          make(chan []byte, 16),
+		done:          make(chan struct{}),
+		overflowReady: make(chan struct{}, 1),
+	}
+}
+
+func sessionToken(t *testing.T, playerID string) string {
+	t.Helper()
+	session, err := database.LoadPlayerSession(playerID)
+	if err != nil {
+		return ""
+	}
+	return session.Token
+}
+
+func TestIssueSessionOnlyToProvenConnections(t *testing.T) {
+	room := newRoom("SESS01")
+
+	stranger := newTestClient(room.ID, "anon-stranger")
+	room.issueSession(stranger)
+	if token := sessionToken(t, "anon-stranger"); token != "" {
+		t.Fatalf("unproven connection was issued a session")
+	}
+
+	newcomer := newTestClient(room.ID, "anon-newcomer")
+	newcomer.Proven = true
+	room.issueSession(newcomer)
+	if token := sessionToken(t, "anon-newcomer"); token == "" {
+		t.Fatalf("proven connection wasn't issued a session")
+	}
+}
+
+func TestIssueSessionKeepsSeatedPlayersToken(t *testing.T) {
+	room := newRoom("SESS02")
+	room.addPlayer("anon-victim", "Victim")
+	original := database.PlayerSession{Token: "original", RoomID: room.ID}
+	if err := database.SavePlayerSession("anon-victim", original, sessionTTL); err != nil {
+		t.Fatal(err)
+	}
+
+	// Someone who read the victim's ID off PLAYER_LIST.
+	impostor := newTestClient(room.ID, "anon-victim")
+	room.issueSession(impostor)
+
+	// Even the victim, proven on a second connection, doesn't replace it.
+	second := newTestClient(room.ID, "anon-victim")
+	second.Proven = true
+	room.issueSession(second)
+
+	if token := sessionToken(t, "anon-victim"); token != "original" {
+		t.Fatalf("seated player's session was replaced with %q", token)
+	}
+	if validSession("anon-victim", room.ID, "") {
+		t.Fatalf("empty token accepted")
+	}
+}
+
+func TestSeatedPlayerIDNeedsProof(t *testing.T) {
+	h := newHub()
+	room := openTestRoom(h, "SESS03")
+	defer h.closeRoom(room, nil)
+	host := "player-" + room.ID
+	room.do(func() {
+		for i := len(room.players); i < room.minPlayers(); i++ {
+			room.addPlayer(fmt.Sprintf("anon-%d", i), "Crew")
+		}
+	})
+
+	// Someone who read the host's ID off PLAYER_LIST.
+	impostor := newTestClient(room.ID, host)
+	impostor.hub = h
+	impostor.registered = make(chan struct{})
+	h.handleRegister(impostor)
+
+	var refusal Message
+	select {
+	case data := <-impostor.send:
+		if err := json.Unmarshal(data, &refusal); err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatalf("impostor was never refused")
+	}
+	if data, _ := refusal.Data.(map[string]interface{}); refusal.Type != "ERROR_ACCESS_DENIED" || data["reason"] != "PLAYER_ID_IN_USE" {
+		t.Fatalf("impostor got %+v, want PLAYER_ID_IN_USE", refusal)
+	}
+
+	impostor.handleMessage([]byte(`{"type":"START_GAME","data":{}}`))
+	var phase GamePhase
+	var admitted bool
+	room.do(func() { phase, admitted = room.gameState.Phase, room.clients[impostor] })
+	if admitted || phase != PhaseLobby {
+		t.Fatalf("impostor admitted = %v, phase = %s; want refused in the lobby", admitted, phase)
+	}
+}
@@ -0,0 +1,339 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"code-mafia-backend/config"
+	"code-mafia-backend/sandbox"
+)
+
+const (
+	defaultGameSeconds   = 120
+	defaultVotingSeconds = 30
+
+	minGameSeconds, maxGameSeconds                 = 60, 900
+	minVotingSeconds, maxVotingSeconds             = 10, 120
+	minSabotageCooldownSec, maxSabotageCooldownSec = 5, 300
+	maxImpostors                                   = 3
+	maxMinPlayers                                  = 15
+
+	defaultHintsPerStage, defaultHintPenaltySec = 2, 20
+	minHintsPerStage, maxHintsPerStage          = 1, 5
+	minHintPenaltySec, maxHintPenaltySec        = 5, 120
+)
+
+// Hosts can narrow the task draw to one category and difficulty, and pick
+// the language the game is played in.
+var (
+	taskCategories   = []string{"DSA", "OOPS", "DEBUGGING"}
+	taskDifficulties = []string{"EASY", "MEDIUM", "HARD"}
+	taskLanguages    = sandbox.Languages
+)
+
+const defaultTaskLanguage = "java"
+
+// Settings are the rules a host picked in the lobby. A zero field means
+// the server's default, so a config reload still reaches rooms that never
+// changed it.
+type Settings struct {
+	TimerSeconds        int `json:"timerSeconds,omitempty"`
+	VotingSeconds       int `json:"votingSeconds,omitempty"`
+	SabotageCooldownSec int `json:"sabotageCooldownSec,omitempty"`
+	ImpostorCount       int `json:"impostorCount,omitempty"`
+	MinPlayers          int `json:"minPlayers,omitempty"`
+
+	// Blank means any.
+	TaskCategory   string `json:"taskCategory,omitempty"`
+	TaskDifficulty string `json:"taskDifficulty,omitempty"`
+	// TaskLanguage is what every stage's task is written in; blank means
+	// Java. Stages with no task in it fall back to another language.
+	TaskLanguage string `json:"taskLanguage,omitempty"`
+
+	// LockVotes makes a meeting's first ballot final.
+	LockVotes bool `json:"lockVotes,omitempty"`
+	// TieRevote gives a tied meeting one more, shorter round between the
+	// tied players.
+	TieRevote bool `json:"tieRevote,omitempty"`
+	// PersonalTasks deals each player a task of their own and fills a task
+	// bar as the crew passes them, in place of three shared stages.
+	PersonalTasks bool `json:"personalTasks,omitempty"`
+	// Detective and Jester deal those roles to a crewmate each when there
+	// are enough players. See specialroles.go.
+	Detective bool `json:"detective,omitempty"`
+	Jester    bool `json:"jester,omitempty"`
+	// GhostsHearImpostors lets eliminated players read IMPOSTOR_CHAT. See
+	// impostorchat.go.
+	GhostsHearImpostors bool `json:"ghostsHearImpostors,omitempty"`
+
+	// Hints lets players ask the hint service for help with the task, up
+	// to HintsPerStage times a stage, each costing HintPenaltySec off the
+	// clock. See hints.go.
+	Hints          bool `json:"hints,omitempty"`
+	HintsPerStage  int  `json:"hintsPerStage,omitempty"`
+	HintPenaltySec int  `json:"hintPenaltySec,omitempty"`
+
+	// Public lists the lobby in the server browser. See lobbies.go.
+	Public bool `json:"public,omitempty"`
+
+	// SlowModeSec is how long each player waits between chat lines in a
+	// meeting; 0 is off. The host sets it any time with SLOW_MODE rather
+	// than ROOM_SETTINGS. See moderation.go.
+	SlowModeSec int `json:"slowModeSec,omitempty"`
+}
+
+// settingsUpdate is a ROOM_SETTINGS message. Fields left out are kept.
+type settingsUpdate struct {
+	TimerSeconds        *int `json:"timerSeconds"`
+	VotingSeconds       *int `json:"votingSeconds"`
+	SabotageCooldownSec *int `json:"sabotageCooldownSec"`
+	ImpostorCount       *int `json:"impostorCount"`
+	MinPlayers          *int `json:"minPlayers"`
+
+	TaskCategory   *string `json:"taskCategory"`
+	TaskDifficulty *string `json:"taskDifficulty"`
+	TaskLanguage   *string `json:"taskLanguage"`
+
+	LockVotes     *bool `json:"lockVotes"`
+	TieRevote     *bool `json:"tieRevote"`
+	PersonalTasks *bool `json:"personalTasks"`
+	Detective     *bool `json:"detective"`
+	Jester        *bool `json:"jester"`
+	Public        *bool `json:"public"`
+
+	GhostsHearImpostors *bool `json:"ghostsHearImpostors"`
+
+	Hints          *bool `json:"hints"`
+	HintsPerStage  *int  `json:"hintsPerStage"`
+	HintPenaltySec *int  `json:"hintPenaltySec"`
+}
+
+func inRange(name string, v *int, min, max int) error {
+	if v != nil && (*v < min || *v > max) {
+		return fmt.Errorf("%s must be between %d and %d", name, min, max)
+	}
+	return nil
+}
+
+func oneOf(name string, v *string, allowed []string) error {
+	if v == nil || *v == "" {
+		return nil
+	}
+	for _, a := range allowed {
+		if *v == a {
+			return nil
+		}
+	}
+	return fmt.Errorf("%s must be one of %s", name, strings.Join(allowed, ", "))
+}
+
+func (u settingsUpdate) validate() error {
+	for _, err := range []error{
+		inRange("Timer length", u.TimerSeconds, minGameSeconds, maxGameSeconds),
+		inRange("Voting duration", u.VotingSeconds, minVotingSeconds, maxVotingSeconds),
+		inRange("Sabotage cooldown", u.SabotageCooldownSec, minSabotageCooldownSec, maxSabotageCooldownSec),
+		inRange("Impostor count", u.ImpostorCount, 0, maxImpostors),
+		inRange("Minimum players", u.MinPlayers, minPlayersToStart, maxMinPlayers),
+		inRange("Hints per stage", u.HintsPerStage, minHintsPerStage, maxHintsPerStage),
+		inRange("Hint penalty", u.HintPenaltySec, minHintPenaltySec, maxHintPenaltySec),
+		oneOf("Task category", u.TaskCategory, taskCategories),
+		oneOf("Task difficulty", u.TaskDifficulty, taskDifficulties),
+		oneOf("Task language", u.TaskLanguage, taskLanguages),
+	} {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Settings) apply(u settingsUpdate) {
+	set := func(dst *int, v *int) {
+		if v != nil {
+			*dst = *v
+		}
+	}
+	set(&s.TimerSeconds, u.TimerSeconds)
+	set(&s.VotingSeconds, u.VotingSeconds)
+	set(&s.SabotageCooldownSec, u.SabotageCooldownSec)
+	set(&s.ImpostorCount, u.ImpostorCount)
+	set(&s.MinPlayers, u.MinPlayers)
+	set(&s.HintsPerStage, u.HintsPerStage)
+	set(&s.HintPenaltySec, u.HintPenaltySec)
+	if u.TaskCategory != nil {
+		s.TaskCategory = *u.TaskCategory
+	}
+	if u.TaskDifficulty != nil {
+		s.TaskDifficulty = *u.TaskDifficulty
+	}
+	if u.TaskLanguage != nil {
+		s.TaskLanguage = *u.TaskLanguage
+	}
+	if u.LockVotes != nil {
+		s.LockVotes = *u.LockVotes
+	}
+	if u.TieRevote != nil {
+		s.TieRevote = *u.TieRevote
+	}
+	if u.PersonalTasks != nil {
+		s.PersonalTasks = *u.PersonalTasks
+	}
+	if u.Detective != nil {
+		s.Detective = *u.Detective
+	}
+	if u.Jester != nil {
+		s.Jester = *u.Jester
+	}
+	if u.GhostsHearImpostors != nil {
+		s.GhostsHearImpostors = *u.GhostsHearImpostors
+	}
+	if u.Public != nil {
+		s.Public = *u.Public
+	}
+	if u.Hints != nil {
+		s.Hints = *u.Hints
+	}
+}
+
+// taskPreferences are what the task draw prefers, most important first:
+// tasks in the game's language, then ones matching the host's category and
+// difficulty.
+func (s Settings) taskPreferences() []func(*Task) bool {
+	language := s.taskLanguage()
+	prefer := []func(*Task) bool{
+		func(t *Task) bool {
+			return t.Language == language || t.Language == "" && language == defaultTaskLanguage
+		},
+	}
+	if s.TaskCategory != "" || s.TaskDifficulty != "" {
+		prefer = append(prefer, func(t *Task) bool {
+			return (s.TaskCategory == "" || t.Category == s.TaskCategory) &&
+				(s.TaskDifficulty == "" || t.Difficulty == s.TaskDifficulty)
+		})
+	}
+	return prefer
+}
+
+func (s Settings) hintsPerStage() int {
+	if s.HintsPerStage == 0 {
+		return defaultHintsPerStage
+	}
+	return s.HintsPerStage
+}
+
+func (s Settings) hintPenaltySec() int {
+	if s.HintPenaltySec == 0 {
+		return defaultHintPenaltySec
+	}
+	return s.HintPenaltySec
+}
+
+func (s Settings) taskLanguage() string {
+	if s.TaskLanguage == "" {
+		return defaultTaskLanguage
+	}
+	return s.TaskLanguage
+}
+
+// updateSettings changes the lobby's rules and tells everyone. Daily
+// challenge rooms play the challenge's rules, so they can't be changed.
+func (r *Room) updateSettings(u settingsUpdate) error {
+	if r.gameState.Phase != PhaseLobby {
+		return errors.New("Settings can only be changed in the lobby")
+	}
+	if r.challenge != nil {
+		return errors.New("Daily challenge rooms use the challenge's rules")
+	}
+	if err := u.validate(); err != nil {
+		return err
+	}
+
+	settings := r.gameState.Settings
+	settings.apply(u)
+	minPlayers := settings.MinPlayers
+	if minPlayers == 0 {
+		minPlayers = minPlayersToStart
+	}
+	if settings.ImpostorCount*2 >= minPlayers {
+		return errors.New("Impostors must be outnumbered by the crew at the minimum player count")
+	}
+
+	r.gameState.Settings = settings
+	r.saveToRedis()
+
+	log.Printf("⚙️ Room %s settings: %+v", r.ID, r.gameState.Settings)
+	r.broadcastSettings()
+	return nil
+}
+
+// effectiveSettings fills in the defaults, which is what clients show. An
+// impostor count of 0 still means one per playersPerImpostor players.
+func (r *Room) effectiveSettings() Settings {
+	return Settings{
+		TimerSeconds:        r.gameDurationSeconds(),
+		VotingSeconds:       r.votingSeconds(),
+		SabotageCooldownSec: r.sabotageCooldown(),
+		ImpostorCount:       r.gameState.Settings.ImpostorCount,
+		MinPlayers:          r.minPlayers(),
+		TaskCategory:        r.gameState.Settings.TaskCategory,
+		TaskDifficulty:      r.gameState.Settings.TaskDifficulty,
+		TaskLanguage:        r.gameState.Settings.taskLanguage(),
+		LockVotes:           r.gameState.Settings.LockVotes,
+		TieRevote:           r.gameState.Settings.TieRevote,
+		PersonalTasks:       r.gameState.Settings.PersonalTasks,
+		Detective:           r.gameState.Settings.Detective,
+		Jester:              r.gameState.Settings.Jester,
+		GhostsHearImpostors: r.gameState.Settings.GhostsHearImpostors,
+		Public:              r.gameState.Settings.Public,
+		Hints:               r.gameState.Settings.Hints,
+		HintsPerStage:       r.gameState.Settings.hintsPerStage(),
+		HintPenaltySec:      r.gameState.Settings.hintPenaltySec(),
+		SlowModeSec:         r.gameState.Settings.SlowModeSec,
+	}
+}
+
+func (r *Room) settingsMessage() Message {
+	return Message{Type: "SETTINGS_UPDATED", Data: r.effectiveSettings()}
+}
+
+func (r *Room) broadcastSettings() {
+	data, _ := json.Marshal(r.settingsMessage())
+	r.emit(data)
+}
+
+func (r *Room) votingSeconds() int {
+	if s := r.gameState.Settings.VotingSeconds; s > 0 {
+		return s
+	}
+	return defaultVotingSeconds
+}
+
+func (r *Room) minPlayers() int {
+	if n := r.gameState.Settings.MinPlayers; n > 0 {
+		return n
+	}
+	return minPlayersToStart
+}
+
+// sabotageCooldown is the cooldown the next game is played with.
+func (r *Room) sabotageCooldown() int {
+	if r.challenge != nil && r.challenge.Modifier.SabotageCooldownSec > 0 {
+		return r.challenge.Modifier.SabotageCooldownSec
+	}
+	if s := r.gameState.Settings.SabotageCooldownSec; s > 0 {
+		return s
+	}
+	return config.Current().SabotageCooldownSec
+}
+
+// impostorCount is the host's choice if it still leaves the crew in the
+// majority, and otherwise one per playersPerImpostor players.
+func (r *Room) impostorCount(players int) int {
+	if n := r.gameState.Settings.ImpostorCount; n > 0 && n*2 < players {
+		return n
+	}
+	return impostorCountFor(players)
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+)
+
+// gracefulShutdown takes the server down without losing games: players are
+// warned, new connections and requests stop, and every room is saved before
+// its connections close. Sessions and the saved rooms let players pick up
+// where they left off once an instance is back.
+func gracefulShutdown(hub *Hub, server *http.Server, drain time.Duration) {
+	log.Printf("Shutting down gracefully (drain %s)...", drain)
+
+	deadline := time.Now().Add(drain)
+	hub.announceShutdown(drain)
+
+	ctx, cancel := context.WithDeadline(context.Background(), deadline)
+	defer cancel()
+	if err := server.Shutdown(ctx); err != nil {
+		log.Printf("⚠️ HTTP server did not drain in time: %v", err)
+	}
+
+	// Game connections were hijacked from the HTTP server, so Shutdown
+	// doesn't wait for them; they get whatever is left of the window.
+	if hub.connectedClients() > 0 {
+		<-ctx.Done()
+	}
+
+	saved := hub.closeRooms()
+	log.Printf("💾 Saved %d rooms", saved)
+
+	hub.stopTranslations()
+	if hub.cluster != nil {
+		hub.cluster.shutdown()
+	}
+
+	// Let the writers send their close frames.
+	time.Sleep(250 * time.Millisecond)
+}
+
+// announceShutdown warns everyone in every room that their connection is
+// about to close.
+func (h *Hub) announceShutdown(drain time.Duration) {
+	notice := Message{
+		Type: "SHUTDOWN_NOTICE",
+		Data: map[string]interface{}{
+			"message":      "🔧 Server restarting - your game is saved, reconnect in a few seconds to carry on",
+			"drainSeconds": int(drain.Seconds()),
+		},
+	}
+	data, _ := json.Marshal(notice)
+
+	for _, room := range h.allRooms() {
+		room.post(func() { room.emit(data) })
+	}
+}
+
+func (h *Hub) connectedClients() int {
+	total := 0
+	for _, room := range h.allRooms() {
+		room.do(func() { total += len(room.clients) })
+	}
+	return total
+}
+
+// closeRooms saves every room, hangs up its connections and stops it, so
+// nothing the disconnects set off can change the saved state. It returns
+// how many rooms were saved.
+func (h *Hub) closeRooms() int {
+	saved := 0
+	for _, room := range h.allRooms() {
+		ok := room.do(func() {
+			room.saveToRedis()
+			for client := range room.clients {
+				delete(room.clients, client)
+				client.close()
+			}
+			for client := range room.observers {
+				delete(room.observers, client)
+				client.close()
+			}
+		})
+		room.stop()
+		if ok {
+			saved++
+		}
+	}
+	return saved
+}
+
+// stopTranslations closes the translation subscription. Its listener sees
+// the hub closing and exits without raising an alert.
+func (h *Hub) stopTranslations() {
+	close(h.closing)
+
+	h.mu.RLock()
+	translations := h.translations
+	h.mu.RUnlock()
+	if translations != nil {
+		translations.Close()
+	}
+}
+
+func (h *Hub) isClosing() bool {
+	select {
+	case <-h.closing:
+		return true
+	default:
+		return false
+	}
+}
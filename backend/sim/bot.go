@@ -0,0 +1,468 @@
+package sim
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+)
+
+type pendingRequest struct {
+	kind   string
+	sentAt time.Time
+}
+
+type message struct {
+	Type string          `json:"type"`
+	Data json.RawMessage `json:"data"`
+}
+
+// Bot is a scripted player that speaks the game protocol: it joins, starts
+// the game when it is host, chats, pushes Yjs edits, runs the tests and
+// votes in meetings.
+type Bot struct {
+	Name     string
+	RoomID   string
+	PlayerID string
+	IsHost   bool
+
+	opts  Options
+	stats *collector
+
+	conn    *websocket.Conn
+	yjs     *websocket.Conn
+	writeMu sync.Mutex
+	yjsMu   sync.Mutex
+
+	mu      sync.Mutex
+	pending map[string]pendingRequest
+	phase   string
+	stage   int
+	started bool
+	voted   bool
+	role    string
+	players map[string]PlayerView
+
+	// closed is closed when the server hangs up.
+	closed chan struct{}
+}
+
+func NewBot(opts Options, roomID, name string, isHost bool, stats *collector) *Bot {
+	return &Bot{
+		Name:     name,
+		RoomID:   roomID,
+		PlayerID: uuid.New().String(),
+		IsHost:   isHost,
+		opts:     opts,
+		stats:    stats,
+		pending:  make(map[string]pendingRequest),
+		players:  make(map[string]PlayerView),
+		closed:   make(chan struct{}),
+	}
+}
+
+// NewPracticeBot returns a bot to play in roomID alongside real players.
+// See Options.Practice.
+func NewPracticeBot(opts Options, roomID, name string) *Bot {
+	opts.Practice = true
+	opts.applyDefaults()
+	return NewBot(opts, roomID, name, false, newCollector())
+}
+
+func (b *Bot) Connect(ctx context.Context) error {
+	query := url.Values{"room": {b.RoomID}, "userId": {b.PlayerID}}
+	conn, _, err := websocket.DefaultDialer.DialContext(ctx, b.opts.BaseURL+"/ws?"+query.Encode(), nil)
+	if err != nil {
+		return fmt.Errorf("dial failed: %w", err)
+	}
+	b.conn = conn
+
+	// JOIN is only handled once the hub has registered the connection,
+	// which the server signals by sending INIT.
+	conn.SetReadDeadline(time.Now().Add(10 * time.Second))
+	_, frame, err := conn.ReadMessage()
+	if err != nil {
+		return fmt.Errorf("no INIT received: %w", err)
+	}
+	conn.SetReadDeadline(time.Time{})
+
+	var init message
+	if err := json.Unmarshal(bytes.Split(frame, []byte{'\n'})[0], &init); err != nil || init.Type != "INIT" {
+		return fmt.Errorf("expected INIT, got %q", frame)
+	}
+	b.stats.addReceived()
+
+	// Guests are given an ID of the server's choosing.
+	var assigned struct {
+		PlayerID string `json:"playerID"`
+	}
+	if json.Unmarshal(init.Data, &assigned) == nil && assigned.PlayerID != "" {
+		b.PlayerID = assigned.PlayerID
+	}
+
+	return b.send("JOIN", "PLAYER_LIST", "join", map[string]interface{}{
+		"username": b.Name,
+	})
+}
+
+func (b *Bot) Play(ctx context.Context) {
+	defer b.close()
+
+	go b.readLoop(ctx)
+
+	chatTicker := time.NewTicker(jitter(b.opts.ChatInterval))
+	defer chatTicker.Stop()
+	editTicker := time.NewTicker(jitter(b.opts.EditInterval))
+	defer editTicker.Stop()
+	testTicker := time.NewTicker(jitter(b.opts.TestInterval))
+	defer testTicker.Stop()
+
+	var meeting <-chan time.Time
+	if b.IsHost {
+		meeting = time.After(b.opts.MeetingAfter)
+	}
+
+	if b.opts.Practice {
+		// Meetings are all a practice bot takes part in, and those are
+		// driven by what the server sends.
+		chatTicker.Stop()
+		editTicker.Stop()
+		testTicker.Stop()
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+
+		case <-b.closed:
+			return
+
+		case <-chatTicker.C:
+			if b.inTaskPhase() {
+				b.send("CHAT", "CHAT", "chat", map[string]interface{}{
+					"text": fmt.Sprintf("%s checking in at %s", b.Name, time.Now().Format("15:04:05")),
+				})
+			}
+
+		case <-editTicker.C:
+			if b.inTaskPhase() {
+				b.sendEdit(ctx)
+			}
+
+		case <-testTicker.C:
+			if b.inTaskPhase() {
+				b.send("RUN_TESTS", "TEST_LOCKED", "tests", map[string]interface{}{
+					"code": fmt.Sprintf("// %s's attempt\nclass Solution {}\n", b.Name),
+				})
+			}
+
+		case <-meeting:
+			if b.inTaskPhase() {
+				b.send("EMERGENCY", "GAME_STATE", "emergency", nil)
+			}
+		}
+	}
+}
+
+func (b *Bot) inTaskPhase() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return strings.HasPrefix(b.phase, "TASK_")
+}
+
+func (b *Bot) send(msgType, expect, kind string, data interface{}) error {
+	payload, err := json.Marshal(map[string]interface{}{"type": msgType, "data": data})
+	if err != nil {
+		return err
+	}
+
+	if expect != "" {
+		b.mu.Lock()
+		if _, waiting := b.pending[expect]; !waiting {
+			b.pending[expect] = pendingRequest{kind: kind, sentAt: time.Now()}
+		}
+		b.mu.Unlock()
+	}
+
+	b.writeMu.Lock()
+	b.conn.SetWriteDeadline(time.Now().Add(5 * time.Second))
+	err = b.conn.WriteMessage(websocket.TextMessage, payload)
+	b.writeMu.Unlock()
+
+	if err != nil {
+		b.stats.addError()
+		return err
+	}
+	b.stats.addSent()
+	return nil
+}
+
+func (b *Bot) readLoop(ctx context.Context) {
+	defer close(b.closed)
+
+	for {
+		_, frame, err := b.conn.ReadMessage()
+		if err != nil {
+			if ctx.Err() == nil {
+				b.stats.addError()
+			}
+			return
+		}
+
+		// The server batches queued messages into one frame separated by newlines.
+		for _, raw := range bytes.Split(frame, []byte{'\n'}) {
+			var msg message
+			if err := json.Unmarshal(raw, &msg); err != nil {
+				continue
+			}
+			b.stats.addReceived()
+			b.handle(ctx, msg)
+		}
+	}
+}
+
+func (b *Bot) handle(ctx context.Context, msg message) {
+	switch msg.Type {
+	case "CHAT":
+		var chat struct {
+			PlayerID string `json:"playerId"`
+		}
+		json.Unmarshal(msg.Data, &chat)
+		if chat.PlayerID != b.PlayerID {
+			return
+		}
+
+	case "TEST_LOCKED":
+		var lock struct {
+			RunnerID string `json:"runnerID"`
+		}
+		json.Unmarshal(msg.Data, &lock)
+		if lock.RunnerID != b.PlayerID {
+			return
+		}
+
+		// The room runs one test at a time, so the next TEST_COMPLETE is
+		// this run's; time it from RUN_TESTS too.
+		b.mu.Lock()
+		if req, ok := b.pending["TEST_LOCKED"]; ok {
+			b.pending["TEST_COMPLETE"] = pendingRequest{kind: "testrun", sentAt: req.sentAt}
+		}
+		b.mu.Unlock()
+
+	case "ERROR_BUSY":
+		// Someone else's run got there first; this one never started.
+		b.mu.Lock()
+		delete(b.pending, "TEST_LOCKED")
+		b.mu.Unlock()
+		return
+
+	case "SELF":
+		var self PlayerView
+		json.Unmarshal(msg.Data, &self)
+
+		b.mu.Lock()
+		b.role = self.Role
+		b.mu.Unlock()
+
+	case "PLAYER_LIST":
+		var players map[string]PlayerView
+		json.Unmarshal(msg.Data, &players)
+
+		b.mu.Lock()
+		b.players = players
+		if self, ok := players[b.PlayerID]; ok && self.Role != "" && self.Role != "UNKNOWN" {
+			b.role = self.Role
+		}
+		shouldStart := b.IsHost && !b.started && len(players) >= b.opts.PlayersPerRoom
+		if shouldStart {
+			b.started = true
+		}
+		b.mu.Unlock()
+
+		if shouldStart {
+			b.send("START_GAME", "GAME_STATE", "start", nil)
+		}
+
+	case "GAME_STATE":
+		var state struct {
+			Phase        string `json:"phase"`
+			CurrentStage int    `json:"currentStage"`
+		}
+		json.Unmarshal(msg.Data, &state)
+
+		b.mu.Lock()
+		stageChanged := state.CurrentStage != b.stage
+		b.phase = state.Phase
+		b.stage = state.CurrentStage
+		shouldVote := state.Phase == "DISCUSSION" && !b.voted
+		if shouldVote {
+			b.voted = true
+		} else if state.Phase != "DISCUSSION" {
+			b.voted = false
+		}
+		b.mu.Unlock()
+
+		if stageChanged && state.CurrentStage > 0 && !b.opts.Practice {
+			go b.connectYjs(ctx, state.CurrentStage)
+		}
+		if shouldVote && b.opts.Brain != nil {
+			go b.meet(ctx)
+		} else if shouldVote {
+			b.send("VOTE", "VOTE_UPDATE", "vote", map[string]interface{}{"targetID": "SKIP"})
+		}
+	}
+
+	b.mu.Lock()
+	req, ok := b.pending[msg.Type]
+	if ok {
+		delete(b.pending, msg.Type)
+	}
+	b.mu.Unlock()
+
+	if ok {
+		b.stats.observe(req.kind, time.Since(req.sentAt))
+	}
+}
+
+// meet has the bot's Brain speak up and then vote, with a pause before
+// each as a person would take.
+func (b *Bot) meet(ctx context.Context) {
+	view, ok := b.view()
+	if !ok || view.Self.Eliminated {
+		return
+	}
+
+	if !pause(ctx, b.closed, 3*time.Second+jitter(4*time.Second)) {
+		return
+	}
+	if line := b.opts.Brain.Speak(ctx, view); line != "" {
+		b.send("CHAT", "", "chat", map[string]interface{}{"text": line})
+	}
+
+	if !pause(ctx, b.closed, 2*time.Second+jitter(4*time.Second)) {
+		return
+	}
+	b.mu.Lock()
+	stillMeeting := b.phase == "DISCUSSION"
+	b.mu.Unlock()
+	if !stillMeeting {
+		return
+	}
+	view, _ = b.view()
+	b.send("VOTE", "VOTE_UPDATE", "vote", map[string]interface{}{"targetID": b.opts.Brain.Vote(ctx, view)})
+}
+
+// view is what the bot knows right now, and false if the server hasn't
+// told it who it is yet.
+func (b *Bot) view() (View, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	self, ok := b.players[b.PlayerID]
+	if !ok {
+		return View{}, false
+	}
+	self.Role = b.role
+
+	v := View{RoomID: b.RoomID, Self: self, Stage: b.stage}
+	for _, p := range b.players {
+		if p.ID == b.PlayerID {
+			p = self
+		}
+		v.Players = append(v.Players, p)
+	}
+	sort.Slice(v.Players, func(i, j int) bool { return v.Players[i].Username < v.Players[j].Username })
+	return v, true
+}
+
+func pause(ctx context.Context, closed <-chan struct{}, d time.Duration) bool {
+	select {
+	case <-ctx.Done():
+		return false
+	case <-closed:
+		return false
+	case <-time.After(d):
+		return true
+	}
+}
+
+func (b *Bot) connectYjs(ctx context.Context, stage int) {
+	query := url.Values{"room": {fmt.Sprintf("%s-stage%d", b.RoomID, stage)}}
+	conn, _, err := websocket.DefaultDialer.DialContext(ctx, b.opts.BaseURL+"/yjs?"+query.Encode(), nil)
+	if err != nil {
+		b.stats.addError()
+		return
+	}
+
+	b.yjsMu.Lock()
+	if b.yjs != nil {
+		b.yjs.Close()
+	}
+	b.yjs = conn
+	b.yjsMu.Unlock()
+
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		b.stats.addReceived()
+		if len(data) >= 8 {
+			sentAt := time.Unix(0, int64(binary.BigEndian.Uint64(data[:8])))
+			b.stats.observe("edit", time.Since(sentAt))
+		}
+	}
+}
+
+// sendEdit pushes an opaque Yjs-sized update. The server relays Yjs frames
+// without decoding them, so a timestamp prefix lets receivers measure lag.
+func (b *Bot) sendEdit(ctx context.Context) {
+	b.yjsMu.Lock()
+	defer b.yjsMu.Unlock()
+
+	if b.yjs == nil {
+		return
+	}
+
+	update := make([]byte, 8+64)
+	binary.BigEndian.PutUint64(update[:8], uint64(time.Now().UnixNano()))
+	rand.Read(update[8:])
+
+	b.yjs.SetWriteDeadline(time.Now().Add(5 * time.Second))
+	if err := b.yjs.WriteMessage(websocket.BinaryMessage, update); err != nil {
+		b.stats.addError()
+		return
+	}
+	b.stats.addSent()
+}
+
+func (b *Bot) close() {
+	b.writeMu.Lock()
+	if b.conn != nil {
+		b.conn.WriteMessage(websocket.CloseMessage,
+			websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+		b.conn.Close()
+	}
+	b.writeMu.Unlock()
+
+	b.yjsMu.Lock()
+	if b.yjs != nil {
+		b.yjs.Close()
+	}
+	b.yjsMu.Unlock()
+}
+
+func jitter(d time.Duration) time.Duration {
+	return d/2 + time.Duration(rand.Int63n(int64(d)))
+}
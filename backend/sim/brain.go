@@ -0,0 +1,29 @@
+package sim
+
+import "context"
+
+// Brain decides what a bot says and who it votes for in a meeting. Bots
+// without one say nothing and vote to skip.
+type Brain interface {
+	// Speak returns a chat line for the meeting, or "" to stay quiet.
+	Speak(ctx context.Context, v View) string
+	// Vote returns the ID of the player to vote out, or "SKIP".
+	Vote(ctx context.Context, v View) string
+}
+
+// View is what a bot knows when a meeting starts.
+type View struct {
+	RoomID  string
+	Self    PlayerView
+	Players []PlayerView
+	Stage   int
+}
+
+// PlayerView is one player as the bot sees them. Roles are as the server
+// reveals them, so usually UNKNOWN for anyone but the bot itself.
+type PlayerView struct {
+	ID         string `json:"id"`
+	Username   string `json:"username"`
+	Role       string `json:"role"`
+	Eliminated bool   `json:"isEliminated"`
+}
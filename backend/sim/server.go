@@ -0,0 +1,132 @@
+package sim
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ServerUsage is what the server reported spending on a run: peaks of its
+// gauges while the bots played, and how much its counters went up.
+type ServerUsage struct {
+	Samples            int    `json:"samples"`
+	PeakGoroutines     int64  `json:"peakGoroutines"`
+	PeakHeapAllocBytes int64  `json:"peakHeapAllocBytes"`
+	PeakSysBytes       int64  `json:"peakSysBytes"`
+	GCCycles           int64  `json:"gcCycles"`
+	GameRawBytesOut    int64  `json:"gameRawBytesOut"`
+	GameWireBytesOut   int64  `json:"gameWireBytesOut"`
+	YjsRawBytesOut     int64  `json:"yjsRawBytesOut"`
+	YjsWireBytesOut    int64  `json:"yjsWireBytesOut"`
+	RoomSaves          int64  `json:"roomSaves"`
+	SlowClientDrops    int64  `json:"slowClientDrops"`
+	SlowDisconnects    int64  `json:"slowClientDisconnects"`
+	Error              string `json:"error,omitempty"`
+}
+
+// sampler polls the server's /metrics while a run goes on.
+type sampler struct {
+	url    string
+	client *http.Client
+
+	mu          sync.Mutex
+	first, last map[string]int64
+	peaks       ServerUsage
+	err         error
+}
+
+func newSampler(url string) *sampler {
+	return &sampler{url: url, client: &http.Client{Timeout: 5 * time.Second}}
+}
+
+func (s *sampler) run(ctx context.Context, every time.Duration) {
+	ticker := time.NewTicker(every)
+	defer ticker.Stop()
+
+	s.sample()
+	for {
+		select {
+		case <-ctx.Done():
+			s.sample()
+			return
+		case <-ticker.C:
+			s.sample()
+		}
+	}
+}
+
+func (s *sampler) sample() {
+	metrics, err := s.fetch()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err != nil {
+		s.err = err
+		return
+	}
+	if s.first == nil {
+		s.first = metrics
+	}
+	s.last = metrics
+	s.peaks.Samples++
+	s.peaks.PeakGoroutines = max64(s.peaks.PeakGoroutines, metrics["goroutines"])
+	s.peaks.PeakHeapAllocBytes = max64(s.peaks.PeakHeapAllocBytes, metrics["heap_alloc_bytes"])
+	s.peaks.PeakSysBytes = max64(s.peaks.PeakSysBytes, metrics["sys_bytes"])
+}
+
+func (s *sampler) fetch() (map[string]int64, error) {
+	resp, err := s.client.Get(s.url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var raw map[string]float64
+	if err := json.NewDecoder(resp.Body).Decode(&raw); err != nil {
+		return nil, err
+	}
+	metrics := make(map[string]int64, len(raw))
+	for name, value := range raw {
+		metrics[name] = int64(value)
+	}
+	return metrics, nil
+}
+
+// usage sums up the samples taken, or returns nil if there were none.
+func (s *sampler) usage() *ServerUsage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.first == nil {
+		if s.err != nil {
+			return &ServerUsage{Error: s.err.Error()}
+		}
+		return nil
+	}
+
+	u := s.peaks
+	delta := func(name string) int64 { return s.last[name] - s.first[name] }
+	u.GCCycles = delta("gc_cycles_total")
+	u.GameRawBytesOut = delta("ws_raw_bytes_out_total")
+	u.GameWireBytesOut = delta("ws_wire_bytes_out_total")
+	u.YjsRawBytesOut = delta("yjs_raw_bytes_out_total")
+	u.YjsWireBytesOut = delta("yjs_wire_bytes_out_total")
+	u.RoomSaves = delta("room_saves_total")
+	u.SlowClientDrops = delta("slow_client_drops_total")
+	u.SlowDisconnects = delta("slow_client_disconnects_total")
+	return &u
+}
+
+func max64(a, b int64) int64 {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+func mib(bytes int64) float64 {
+	return float64(bytes) / (1 << 20)
+}
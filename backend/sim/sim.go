@@ -0,0 +1,167 @@
+package sim
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Options describes a simulated load run against a live server.
+type Options struct {
+	BaseURL        string        `json:"baseUrl"`
+	Rooms          int           `json:"rooms"`
+	PlayersPerRoom int           `json:"playersPerRoom"`
+	Duration       time.Duration `json:"-"`
+	ChatInterval   time.Duration `json:"-"`
+	EditInterval   time.Duration `json:"-"`
+	TestInterval   time.Duration `json:"-"`
+	MeetingAfter   time.Duration `json:"-"`
+
+	// SampleInterval is how often the server's /metrics are read for the
+	// report's resource usage.
+	SampleInterval time.Duration `json:"-"`
+
+	// Practice bots join a real player's room: they leave the shared
+	// document and the test runner alone, and only speak and vote in
+	// meetings, as Brain decides.
+	Practice bool  `json:"-"`
+	Brain    Brain `json:"-"`
+}
+
+func (o *Options) applyDefaults() {
+	if o.BaseURL == "" {
+		o.BaseURL = "ws://localhost:8080"
+	}
+	if o.Rooms <= 0 {
+		o.Rooms = 1
+	}
+	if o.PlayersPerRoom < 3 {
+		o.PlayersPerRoom = 5
+	}
+	if o.Duration <= 0 {
+		o.Duration = 60 * time.Second
+	}
+	if o.ChatInterval <= 0 {
+		o.ChatInterval = 3 * time.Second
+	}
+	if o.EditInterval <= 0 {
+		o.EditInterval = 500 * time.Millisecond
+	}
+	if o.TestInterval <= 0 {
+		o.TestInterval = 10 * time.Second
+	}
+	if o.SampleInterval <= 0 {
+		o.SampleInterval = 2 * time.Second
+	}
+	if o.MeetingAfter <= 0 {
+		o.MeetingAfter = o.Duration / 2
+	}
+}
+
+// Run spins up the requested rooms, lets the bots play until the duration
+// elapses or ctx is cancelled, and returns the collected measurements.
+func Run(ctx context.Context, opts Options) Report {
+	opts.applyDefaults()
+
+	ctx, cancel := context.WithTimeout(ctx, opts.Duration)
+	defer cancel()
+
+	stats := newCollector()
+	runID := uuid.New().String()[:8]
+	start := time.Now()
+
+	var connected int64
+	var wg sync.WaitGroup
+
+	log.Printf("🤖 Simulation %s: %d rooms x %d players against %s for %s",
+		runID, opts.Rooms, opts.PlayersPerRoom, opts.BaseURL, opts.Duration)
+
+	sampler := newSampler(httpURL(opts.BaseURL) + "/metrics")
+	samplerDone := make(chan struct{})
+	go func() {
+		defer close(samplerDone)
+		sampler.run(ctx, opts.SampleInterval)
+	}()
+
+	for i := 0; i < opts.Rooms; i++ {
+		roomID, err := createRoom(ctx, opts.BaseURL)
+		if err != nil {
+			stats.addError()
+			log.Printf("🤖 Simulation %s failed to create room %d: %v", runID, i, err)
+			continue
+		}
+		bots := make([]*Bot, opts.PlayersPerRoom)
+		for j := range bots {
+			bots[j] = NewBot(opts, roomID, fmt.Sprintf("bot-%d-%d", i, j), j == 0, stats)
+		}
+
+		for _, b := range bots {
+			wg.Add(1)
+			go func(b *Bot) {
+				defer wg.Done()
+				if err := b.Connect(ctx); err != nil {
+					stats.addError()
+					log.Printf("🤖 Bot %s failed to connect: %v", b.Name, err)
+					return
+				}
+				atomic.AddInt64(&connected, 1)
+				b.Play(ctx)
+			}(b)
+
+			// Stagger joins so the host is registered first and the
+			// server isn't hit with every handshake at once.
+			time.Sleep(20 * time.Millisecond)
+		}
+	}
+
+	wg.Wait()
+	cancel()
+	<-samplerDone
+
+	report := stats.report(opts, int(atomic.LoadInt64(&connected)), time.Since(start))
+	report.Server = sampler.usage()
+	log.Printf("🤖 Simulation %s finished: sent=%d received=%d errors=%d",
+		runID, report.MessagesSent, report.MessagesReceived, report.Errors)
+	return report
+}
+
+// createRoom asks the server for a fresh room code, since connections to
+// codes it didn't hand out are refused.
+func createRoom(ctx context.Context, baseURL string) (string, error) {
+	apiURL := httpURL(baseURL) + "/api/rooms"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, apiURL, nil)
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	var body struct {
+		Code  string `json:"code"`
+		Error string `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusCreated {
+		return "", fmt.Errorf("create room: %s (%d)", body.Error, resp.StatusCode)
+	}
+	return body.Code, nil
+}
+
+// httpURL is the HTTP base URL of a server given its WebSocket one.
+func httpURL(baseURL string) string {
+	return strings.Replace(baseURL, "ws", "http", 1)
+}
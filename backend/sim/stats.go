@@ -0,0 +1,136 @@
+package sim
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type collector struct {
+	sent     int64
+	received int64
+	errors   int64
+
+	mu        sync.Mutex
+	latencies map[string][]time.Duration
+}
+
+func newCollector() *collector {
+	return &collector{latencies: make(map[string][]time.Duration)}
+}
+
+func (c *collector) addSent()     { atomic.AddInt64(&c.sent, 1) }
+func (c *collector) addReceived() { atomic.AddInt64(&c.received, 1) }
+func (c *collector) addError()    { atomic.AddInt64(&c.errors, 1) }
+
+func (c *collector) observe(kind string, d time.Duration) {
+	c.mu.Lock()
+	c.latencies[kind] = append(c.latencies[kind], d)
+	c.mu.Unlock()
+}
+
+type LatencySummary struct {
+	Count int     `json:"count"`
+	P50Ms float64 `json:"p50Ms"`
+	P95Ms float64 `json:"p95Ms"`
+	P99Ms float64 `json:"p99Ms"`
+	MaxMs float64 `json:"maxMs"`
+}
+
+type Report struct {
+	Rooms            int                       `json:"rooms"`
+	PlayersPerRoom   int                       `json:"playersPerRoom"`
+	Connected        int                       `json:"connected"`
+	DurationSeconds  float64                   `json:"durationSeconds"`
+	MessagesSent     int64                     `json:"messagesSent"`
+	MessagesReceived int64                     `json:"messagesReceived"`
+	Errors           int64                     `json:"errors"`
+	SentPerSecond    float64                   `json:"sentPerSecond"`
+	RecvPerSecond    float64                   `json:"receivedPerSecond"`
+	Latency          map[string]LatencySummary `json:"latency"`
+
+	// Server is what the server's /metrics said the run cost; its Error
+	// is set if they couldn't be read.
+	Server *ServerUsage `json:"server,omitempty"`
+}
+
+func (c *collector) report(opts Options, connected int, elapsed time.Duration) Report {
+	r := Report{
+		Rooms:            opts.Rooms,
+		PlayersPerRoom:   opts.PlayersPerRoom,
+		Connected:        connected,
+		DurationSeconds:  elapsed.Seconds(),
+		MessagesSent:     atomic.LoadInt64(&c.sent),
+		MessagesReceived: atomic.LoadInt64(&c.received),
+		Errors:           atomic.LoadInt64(&c.errors),
+		Latency:          make(map[string]LatencySummary),
+	}
+
+	if secs := elapsed.Seconds(); secs > 0 {
+		r.SentPerSecond = float64(r.MessagesSent) / secs
+		r.RecvPerSecond = float64(r.MessagesReceived) / secs
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for kind, samples := range c.latencies {
+		if len(samples) == 0 {
+			continue
+		}
+		sorted := append([]time.Duration(nil), samples...)
+		sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+		r.Latency[kind] = LatencySummary{
+			Count: len(sorted),
+			P50Ms: ms(percentile(sorted, 0.50)),
+			P95Ms: ms(percentile(sorted, 0.95)),
+			P99Ms: ms(percentile(sorted, 0.99)),
+			MaxMs: ms(sorted[len(sorted)-1]),
+		}
+	}
+
+	return r
+}
+
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	idx := int(float64(len(sorted)-1) * p)
+	return sorted[idx]
+}
+
+func ms(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
+
+func (r Report) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "rooms=%d players/room=%d connected=%d duration=%.1fs\n",
+		r.Rooms, r.PlayersPerRoom, r.Connected, r.DurationSeconds)
+	fmt.Fprintf(&b, "sent=%d (%.1f/s) received=%d (%.1f/s) errors=%d\n",
+		r.MessagesSent, r.SentPerSecond, r.MessagesReceived, r.RecvPerSecond, r.Errors)
+
+	kinds := make([]string, 0, len(r.Latency))
+	for kind := range r.Latency {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+
+	for _, kind := range kinds {
+		l := r.Latency[kind]
+		fmt.Fprintf(&b, "  %-10s n=%-6d p50=%7.2fms p95=%7.2fms p99=%7.2fms max=%7.2fms\n",
+			kind, l.Count, l.P50Ms, l.P95Ms, l.P99Ms, l.MaxMs)
+	}
+
+	if s := r.Server; s != nil && s.Error != "" {
+		fmt.Fprintf(&b, "server: no metrics (%s)\n", s.Error)
+	} else if s != nil {
+		fmt.Fprintf(&b, "server: goroutines peak=%d heap peak=%.1fMiB sys peak=%.1fMiB gc=%d samples=%d\n",
+			s.PeakGoroutines, mib(s.PeakHeapAllocBytes), mib(s.PeakSysBytes), s.GCCycles, s.Samples)
+		fmt.Fprintf(&b, "  bytes out: game raw=%.1fMiB wire=%.1fMiB  yjs raw=%.1fMiB wire=%.1fMiB\n",
+			mib(s.GameRawBytesOut), mib(s.GameWireBytesOut), mib(s.YjsRawBytesOut), mib(s.YjsWireBytesOut))
+	}
+	return b.String()
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"time"
+
+	"code-mafia-backend/config"
+	"code-mafia-backend/sim"
+)
+
+const (
+	maxSimDuration = 5 * time.Minute
+	maxSimPlayers  = 1000
+)
+
+// runSimulateCommand implements `main simulate [flags]`, driving bots
+// against an already running server and printing the report.
+func runSimulateCommand(args []string) {
+	fs := flag.NewFlagSet("simulate", flag.ExitOnError)
+	baseURL := fs.String("url", "ws://localhost:8080", "server WebSocket base URL")
+	rooms := fs.Int("rooms", 10, "number of rooms to create")
+	players := fs.Int("players", 5, "bots per room (minimum 3)")
+	duration := fs.Duration("duration", time.Minute, "how long the bots play")
+	chatEvery := fs.Duration("chat", 3*time.Second, "average interval between chat messages per bot")
+	editEvery := fs.Duration("edit", 500*time.Millisecond, "average interval between Yjs edits per bot")
+	testEvery := fs.Duration("tests", 10*time.Second, "average interval between test runs per bot")
+	asJSON := fs.Bool("json", false, "print the report as JSON")
+	fs.Parse(args)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	report := sim.Run(ctx, sim.Options{
+		BaseURL:        *baseURL,
+		Rooms:          *rooms,
+		PlayersPerRoom: *players,
+		Duration:       *duration,
+		ChatInterval:   *chatEvery,
+		EditInterval:   *editEvery,
+		TestInterval:   *testEvery,
+	})
+
+	if *asJSON {
+		json.NewEncoder(os.Stdout).Encode(report)
+		return
+	}
+	fmt.Print(report)
+}
+
+func handleAdminSimulate(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Rooms           int `json:"rooms"`
+		PlayersPerRoom  int `json:"playersPerRoom"`
+		DurationSeconds int `json:"durationSeconds"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{
+			"error": "invalid JSON body",
+		})
+		return
+	}
+
+	duration := time.Duration(req.DurationSeconds) * time.Second
+	if duration <= 0 || duration > maxSimDuration {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{
+			"error": fmt.Sprintf("durationSeconds must be between 1 and %d", int(maxSimDuration.Seconds())),
+		})
+		return
+	}
+	if req.Rooms*req.PlayersPerRoom > maxSimPlayers {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{
+			"error": fmt.Sprintf("at most %d simulated players per run", maxSimPlayers),
+		})
+		return
+	}
+
+	report := sim.Run(r.Context(), sim.Options{
+		BaseURL:        "ws://127.0.0.1:" + config.AppConfig.Port,
+		Rooms:          req.Rooms,
+		PlayersPerRoom: req.PlayersPerRoom,
+		Duration:       duration,
+	})
+
+	writeJSON(w, http.StatusOK, report)
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"time"
+
+	"code-mafia-backend/config"
+
+	"github.com/gorilla/websocket"
+)
+
+// slowWarningInterval is how often a player falling behind is told so.
+const slowWarningInterval = 5 * time.Second
+
+// slowClosedReason is the close frame reason for a connection hung up on
+// for not keeping up.
+const slowClosedReason = "SLOW_CONNECTION"
+
+// enqueue queues a message for the connection. When its send buffer is
+// full, SLOW_CLIENT_POLICY decides what gives: the connection is hung up
+// on with a reason, its oldest queued message is dropped, or the message
+// waits in an overflow of up to SEND_OVERFLOW_MAX more. Safe from any
+// goroutine.
+func (c *Client) enqueue(data []byte) {
+	c.sendMu.Lock()
+	defer c.sendMu.Unlock()
+
+	// Once messages are waiting in the overflow, new ones go behind them
+	// so the connection sees everything in order.
+	if len(c.overflow) == 0 {
+		select {
+		case c.send <- data:
+			return
+		default:
+		}
+	}
+
+	switch config.Current().SlowClientPolicy {
+	case "drop-oldest":
+		c.dropOldest(data)
+		if c.slowWarningDue() {
+			c.dropOldest(c.slowWarning("drop-oldest"))
+		}
+	case "grow":
+		if len(c.overflow) >= config.AppConfig.SendOverflowMax {
+			c.disconnectSlow()
+			return
+		}
+		if len(c.overflow) == 0 {
+			c.overflow = append(c.overflow, c.slowWarning("grow"))
+		}
+		c.overflow = append(c.overflow, data)
+		select {
+		case c.overflowReady <- struct{}{}:
+		default:
+		}
+	default:
+		c.disconnectSlow()
+	}
+}
+
+// dropOldest makes room for data by throwing away whatever has waited
+// longest. Called with sendMu held.
+func (c *Client) dropOldest(data []byte) {
+	for {
+		select {
+		case c.send <- data:
+			return
+		default:
+		}
+		select {
+		case <-c.send:
+			c.drops++
+			incMetric("slow_client_drops_total")
+		default:
+		}
+	}
+}
+
+// slowWarningDue reports whether the player should hear about falling
+// behind again. Called with sendMu held.
+func (c *Client) slowWarningDue() bool {
+	if time.Since(c.lastSlowWarning) < slowWarningInterval {
+		return false
+	}
+	c.lastSlowWarning = time.Now()
+	return true
+}
+
+func (c *Client) slowWarning(policy string) []byte {
+	c.lastSlowWarning = time.Now()
+	data, _ := json.Marshal(Message{
+		Type: "SLOW_CONNECTION",
+		Data: map[string]interface{}{
+			"policy":  policy,
+			"dropped": c.drops,
+			"message": "Your connection is falling behind - some updates may be late or missing",
+		},
+	})
+	return data
+}
+
+// disconnectSlow hangs up on the connection, telling it why in the close
+// frame. The room hears about it the same way as any other disconnect.
+// Called with sendMu held.
+func (c *Client) disconnectSlow() {
+	if c.closeReason == "" {
+		c.closeReason = slowClosedReason
+		incMetric("slow_client_disconnects_total")
+		log.Printf("🐢 Hanging up on slow client %s in room %s (conn=%s)", c.PlayerID, c.RoomID, c.ConnID)
+	}
+	c.close()
+}
+
+// takeQueued empties the send buffer and the overflow behind it, oldest
+// first, for a write pump woken by overflowReady.
+func (c *Client) takeQueued() [][]byte {
+	c.sendMu.Lock()
+	defer c.sendMu.Unlock()
+
+	var queued [][]byte
+	for {
+		select {
+		case message := <-c.send:
+			queued = append(queued, message)
+			continue
+		default:
+		}
+		break
+	}
+	queued = append(queued, c.overflow...)
+	c.overflow = nil
+	return queued
+}
+
+// closeMessage is the close frame writePump ends the connection with.
+func (c *Client) closeMessage() []byte {
+	c.sendMu.Lock()
+	defer c.sendMu.Unlock()
+
+	if c.closeReason == "" {
+		return []byte{}
+	}
+	return websocket.FormatCloseMessage(websocket.CloseTryAgainLater, c.closeReason)
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"log"
+)
+
+// Besides impostors and civilians, the host can switch on two special roles,
+// each dealt to a player who would otherwise have been a civilian:
+//
+//   - A DETECTIVE works with the crew and may once per game ask whether
+//     another player has failed their test runs suspiciously often.
+//   - A JESTER is on no one's side and wins alone by being voted out.
+
+// suspiciousFailures is how many failed runs make a player suspicious to
+// the detective.
+const suspiciousFailures = 3
+
+// minCrewWithJester is how many crewmates a game needs besides the jester
+// for one to be dealt.
+const minCrewWithJester = 2
+
+// roleTeam is the side a role wins with: the detective wins with the crew,
+// the jester only on their own.
+func roleTeam(role string) string {
+	if role == "DETECTIVE" {
+		return "CIVILIAN"
+	}
+	return role
+}
+
+// isCrewmate reports whether role is on the crew's side, so its tasks count
+// and it can repair.
+func isCrewmate(role string) bool {
+	return roleTeam(role) == "CIVILIAN"
+}
+
+// dealSpecialRoles hands the special roles the host switched on to players
+// from civilians, which is already in random order. Runs on the room
+// goroutine.
+func (r *Room) dealSpecialRoles(civilians []string) {
+	settings := r.gameState.Settings
+	if settings.Jester && len(civilians) > minCrewWithJester {
+		jester := r.players[civilians[0]]
+		jester.Role = "JESTER"
+		civilians = civilians[1:]
+		log.Printf("%s is JESTER", jester.Username)
+	}
+	if settings.Detective && len(civilians) > 0 {
+		detective := r.players[civilians[0]]
+		detective.Role = "DETECTIVE"
+		log.Printf("%s is DETECTIVE", detective.Username)
+	}
+}
+
+// recordFailedRun counts a failed test run towards what the detective can
+// find out about playerID. Runs on the room goroutine.
+func (r *Room) recordFailedRun(playerID string) {
+	if r.gameState.FailedRuns == nil {
+		r.gameState.FailedRuns = make(map[string]int)
+	}
+	r.gameState.FailedRuns[playerID]++
+}
+
+// handleDetectiveQuery answers the detective's one question about targetID,
+// privately, or returns why it can't be asked. Runs on the room goroutine.
+func (r *Room) handleDetectiveQuery(playerID, targetID string) string {
+	player := r.players[playerID]
+	if player == nil || player.Role != "DETECTIVE" {
+		return "Only the detective can investigate"
+	}
+	if player.IsEliminated {
+		return "Eliminated players can't investigate"
+	}
+	switch r.gameState.Phase {
+	case PhaseLobby, PhaseRoleReveal, PhaseEnd:
+		return "Investigations can only be made during the game"
+	}
+	if r.gameState.DetectiveQueried {
+		return "You have already used your investigation this game"
+	}
+	target := r.players[targetID]
+	if target == nil || targetID == playerID {
+		return "Choose another player to investigate"
+	}
+
+	r.gameState.DetectiveQueried = true
+	r.saveToRedis()
+
+	suspicious := r.gameState.FailedRuns[targetID] >= suspiciousFailures
+	log.Printf("🔍 Room %s: detective %s investigated %s (suspicious=%v)", r.ID, player.Username, target.Username, suspicious)
+	r.audit("DETECTIVE_QUERY", playerID, player.Username, map[string]interface{}{
+		"targetId":   targetID,
+		"suspicious": suspicious,
+	})
+
+	if client := r.clientFor(playerID); client != nil {
+		client.sendMessage(Message{
+			Type: "DETECTIVE_RESULT",
+			Data: map[string]interface{}{
+				"targetID":   targetID,
+				"username":   target.Username,
+				"suspicious": suspicious,
+			},
+		})
+	}
+	return ""
+}
@@ -0,0 +1,249 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	maxSpectatorsPerRoom   = 50
+	maxSpectatorDelaySec   = 300
+	spectatorFeedQueueSize = 1024
+)
+
+// spectator is a read-only connection for streamers. It gets the room's
+// broadcasts after the room's delay with roles stripped out, so a stream
+// never tells viewers who are also playing anything they don't know yet.
+type spectator struct {
+	conn *websocket.Conn
+	feed chan delayedMessage
+	done chan struct{}
+}
+
+type delayedMessage struct {
+	at   time.Time
+	data []byte
+}
+
+func (s *spectator) ping() error {
+	s.conn.SetWriteDeadline(time.Now().Add(writeWait))
+	return s.conn.WriteMessage(websocket.PingMessage, nil)
+}
+
+// writePump releases each message once its delay has passed, pinging while
+// it waits. Messages are queued in broadcast order with the same delay, so
+// order is preserved.
+func (s *spectator) writePump() {
+	ticker := time.NewTicker(pingPeriod)
+	defer func() {
+		ticker.Stop()
+		s.conn.Close()
+	}()
+
+	for {
+		select {
+		case msg := <-s.feed:
+			for wait := time.Until(msg.at); wait > 0; wait = time.Until(msg.at) {
+				select {
+				case <-time.After(wait):
+				case <-ticker.C:
+					if err := s.ping(); err != nil {
+						return
+					}
+				case <-s.done:
+					return
+				}
+			}
+			s.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if msg.data == nil {
+				s.conn.WriteMessage(websocket.CloseMessage,
+					websocket.FormatCloseMessage(websocket.CloseNormalClosure, "room closed"))
+				return
+			}
+			prepareWrite(s.conn, gameSocket, len(msg.data))
+			if err := s.conn.WriteMessage(websocket.TextMessage, msg.data); err != nil {
+				return
+			}
+		case <-ticker.C:
+			if err := s.ping(); err != nil {
+				return
+			}
+		case <-s.done:
+			return
+		}
+	}
+}
+
+// readPump discards anything the spectator sends and unregisters it once
+// the connection drops.
+func (s *spectator) readPump(room *Room) {
+	defer func() {
+		room.post(func() { delete(room.spectators, s) })
+		close(s.done)
+	}()
+
+	s.conn.SetReadLimit(512)
+	s.conn.SetReadDeadline(time.Now().Add(pongWait))
+	s.conn.SetPongHandler(func(string) error {
+		s.conn.SetReadDeadline(time.Now().Add(pongWait))
+		return nil
+	})
+	for {
+		if _, _, err := s.conn.ReadMessage(); err != nil {
+			return
+		}
+	}
+}
+
+// enqueueSpectator schedules data for the spectator. It runs on the room
+// goroutine; a spectator that has fallen too far behind is disconnected.
+func (r *Room) enqueueSpectator(s *spectator, data []byte) {
+	select {
+	case s.feed <- delayedMessage{at: time.Now().Add(r.spectatorDelay), data: data}:
+	default:
+		delete(r.spectators, s)
+		s.conn.Close()
+	}
+}
+
+// closeSpectators ends every spectator feed once what is already queued
+// has been played out. Runs on the room goroutine.
+func (r *Room) closeSpectators() {
+	for s := range r.spectators {
+		r.enqueueSpectator(s, nil)
+		delete(r.spectators, s)
+	}
+}
+
+// setSpectatorDelay changes how far behind the spectator feed runs. It is
+// locked once play starts so a running stream can't suddenly catch up.
+func (r *Room) setSpectatorDelay(seconds int) error {
+	if seconds < 0 || seconds > maxSpectatorDelaySec {
+		return fmt.Errorf("Spectator delay must be between 0 and %d seconds", maxSpectatorDelaySec)
+	}
+
+	if r.gameState.Phase != PhaseLobby {
+		return errors.New("Spectator delay can only be changed in the lobby")
+	}
+	r.spectatorDelay = time.Duration(seconds) * time.Second
+
+	log.Printf("📺 Room %s spectator delay set to %ds", r.ID, seconds)
+	r.broadcastGameState()
+	return nil
+}
+
+// redactForSpectators strips role information from a broadcast. The
+// reveal at GAME_ENDED is left alone since the game is over by then.
+func redactForSpectators(message []byte) []byte {
+	var msg map[string]interface{}
+	if err := json.Unmarshal(message, &msg); err != nil {
+		return message
+	}
+	if msg["type"] == "GAME_ENDED" {
+		return message
+	}
+
+	msg["data"] = stripRoles(msg["data"])
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return message
+	}
+	return data
+}
+
+func stripRoles(v interface{}) interface{} {
+	switch value := v.(type) {
+	case map[string]interface{}:
+		delete(value, "imposterID")
+		delete(value, "imposterIDs")
+		if _, ok := value["role"]; ok {
+			value["role"] = ""
+		}
+		for key, child := range value {
+			value[key] = stripRoles(child)
+		}
+	case []interface{}:
+		for i, child := range value {
+			value[i] = stripRoles(child)
+		}
+	}
+	return v
+}
+
+// serveSpectator attaches a read-only, delayed feed to an existing room.
+func serveSpectator(hub *Hub, w http.ResponseWriter, r *http.Request) {
+	conn, err := upgrade(w, r, gameSocket)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+
+	roomID := r.URL.Query().Get("room")
+
+	if ban := findBan(clientIP(r), ""); ban != nil {
+		rejectConnection(conn, Message{Type: "ERROR_ACCESS_DENIED", Data: banPayload(ban)})
+		return
+	}
+
+	room := hub.getRoom(roomID)
+	if room == nil {
+		rejectConnection(conn, Message{
+			Type: "ERROR_ACCESS_DENIED",
+			Data: map[string]interface{}{"reason": "ROOM_NOT_FOUND", "message": "That room doesn't exist"},
+		})
+		return
+	}
+
+	s := &spectator{
+		conn: conn,
+		feed: make(chan delayedMessage, spectatorFeedQueueSize),
+		done: make(chan struct{}),
+	}
+
+	full := true
+	var delay time.Duration
+	alive := room.do(func() {
+		if len(room.spectators) >= maxSpectatorsPerRoom {
+			return
+		}
+		full = false
+		delay = room.spectatorDelay
+		initData, _ := json.Marshal(Message{
+			Type: "SPECTATOR_INIT",
+			Data: map[string]interface{}{
+				"roomID":       roomID,
+				"delaySeconds": int(delay.Seconds()),
+			},
+		})
+		stateData, _ := json.Marshal(Message{Type: "GAME_STATE", Data: room.buildGameStatePayload()})
+		room.spectators[s] = true
+		s.feed <- delayedMessage{at: time.Now(), data: initData}
+		room.enqueueSpectator(s, redactForSpectators(stateData))
+	})
+	if !alive {
+		rejectConnection(conn, Message{
+			Type: "ERROR_ACCESS_DENIED",
+			Data: map[string]interface{}{"reason": "ROOM_NOT_FOUND", "message": "That room doesn't exist"},
+		})
+		return
+	}
+	if full {
+		rejectConnection(conn, Message{
+			Type: "ERROR_ACCESS_DENIED",
+			Data: map[string]interface{}{"reason": "SPECTATORS_FULL", "message": "This room has too many spectators"},
+		})
+		return
+	}
+
+	incMetric("spectator_connections_total")
+	log.Printf("📺 Spectator joined room %s (delay %s)", roomID, delay)
+
+	go s.writePump()
+	go s.readPump(room)
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"code-mafia-backend/database"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// recordMeeting adds a finished vote to the game's summary, keyed by
+// display name. Runs on the room goroutine.
+func (r *Room) recordMeeting(eliminated string) {
+	nameOf := func(id string) string {
+		if id == "SKIP" {
+			return "SKIP"
+		}
+		if player, ok := r.players[id]; ok {
+			return player.Username
+		}
+		return "?"
+	}
+
+	meeting := database.SummaryMeeting{
+		Stage: r.gameState.CurrentStage,
+		Votes: make(map[string]string, len(r.votes)),
+		Tally: make(map[string]int),
+	}
+	for voterID, targetID := range r.votes {
+		target := nameOf(targetID)
+		meeting.Votes[nameOf(voterID)] = target
+		meeting.Tally[target]++
+	}
+	if eliminated != "" && eliminated != "SKIP" {
+		meeting.Eliminated = nameOf(eliminated)
+	}
+	r.meetings = append(r.meetings, meeting)
+}
+
+// buildSummary assembles the shareable record of the game that just ended.
+// Runs on the room goroutine.
+func (r *Room) buildSummary(reason string, duration int) database.MatchSummary {
+	summary := database.MatchSummary{
+		RoomCode:        r.ID,
+		Reason:          reason,
+		WinnerRole:      winnerRoleFor(reason),
+		DurationSeconds: duration,
+		Meetings:        r.meetings,
+		EndedAt:         time.Now(),
+	}
+	if summary.Meetings == nil {
+		summary.Meetings = []database.SummaryMeeting{}
+	}
+
+	for _, player := range r.players {
+		summary.Players = append(summary.Players, database.SummaryPlayer{
+			Username:   player.Username,
+			Role:       player.Role,
+			Eliminated: player.IsEliminated,
+		})
+	}
+	sort.Slice(summary.Players, func(i, j int) bool {
+		return summary.Players[i].Username < summary.Players[j].Username
+	})
+
+	for _, task := range r.tasks {
+		stage := database.SummaryStage{
+			Stage: task.Stage,
+			Title: task.Title,
+			Code:  r.passedCode[task.Stage],
+		}
+		if seconds, ok := r.stageTimes[task.Stage]; ok {
+			stage.CompletedAtSeconds = &seconds
+		}
+		summary.Stages = append(summary.Stages, stage)
+	}
+
+	return summary
+}
+
+// saveSummary stores the summary under a fresh unguessable token and
+// returns it, or "" if it could not be saved.
+func saveSummary(summary database.MatchSummary) string {
+	token := strings.ReplaceAll(uuid.New().String(), "-", "")
+	if err := database.SaveMatchSummary(token, summary); err != nil {
+		log.Printf("Failed to save match summary for room %s: %v", summary.RoomCode, err)
+		return ""
+	}
+	return token
+}
+
+// handleGetSummary serves a match summary to anyone holding its token.
+// Unlike /api/matches it needs no account and reveals no player IDs.
+func handleGetSummary(w http.ResponseWriter, r *http.Request) {
+	token := mux.Vars(r)["token"]
+
+	summary, err := database.GetMatchSummary(token)
+	if err != nil {
+		log.Printf("Failed to load match summary: %v", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"error": "failed to load summary"})
+		return
+	}
+	if summary == nil {
+		writeJSON(w, http.StatusNotFound, map[string]interface{}{"error": "summary not found"})
+		return
+	}
+
+	w.Header().Set("Cache-Control", "public, max-age=3600")
+	writeJSON(w, http.StatusOK, summary)
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"math/rand"
+	"time"
+)
+
+// swapDuration is how long a SWAP sabotage leaves two crewmates working
+// from each other's place in the editor.
+const swapDuration = 15 * time.Second
+
+// swapPair picks two alive, connected civilians to swap, or returns nil if
+// there aren't two. Runs on the room goroutine.
+func (r *Room) swapPair() []string {
+	var crew []string
+	for id, player := range r.players {
+		if player.Role != "IMPOSTER" && !player.IsEliminated && r.clientFor(id) != nil {
+			crew = append(crew, id)
+		}
+	}
+	if len(crew) < 2 {
+		return nil
+	}
+
+	rand.Shuffle(len(crew), func(i, j int) {
+		crew[i], crew[j] = crew[j], crew[i]
+	})
+	return crew[:2]
+}
+
+// handleSwapSabotage tells the two crewmates in pair whose place they have
+// taken. The rest of the room only learns that a swap is on. Runs on the
+// room goroutine.
+func (r *Room) handleSwapSabotage(pair []string) {
+	log.Printf("SWAP sabotage activated - %v swapped for %s", pair, swapDuration)
+
+	r.swappedIDs = pair
+	r.sabotageEndTime = time.Now().Add(swapDuration)
+	r.sabotageTimer = r.schedule(swapDuration, r.endSwap)
+	r.saveToRedis()
+
+	startMsg := Message{
+		Type: "SABOTAGE_STARTED",
+		Data: map[string]interface{}{
+			"type":     "SWAP",
+			"duration": swapDuration.Milliseconds(),
+		},
+	}
+	data, _ := json.Marshal(startMsg)
+	r.emit(data)
+
+	for _, id := range pair {
+		if client := r.clientFor(id); client != nil {
+			r.sendSwapPartner(client, swapDuration)
+		}
+	}
+
+	r.emitSystemChat("system.swap", nil)
+}
+
+// sendSwapPartner tells c, if its player is one of the swapped pair, whose
+// place they have taken for the left of the swap. Runs on the room
+// goroutine.
+func (r *Room) sendSwapPartner(c *Client, left time.Duration) {
+	if len(r.swappedIDs) != 2 {
+		return
+	}
+	for i, id := range r.swappedIDs {
+		if id != c.PlayerID {
+			continue
+		}
+		partner := r.players[r.swappedIDs[1-i]]
+		if partner == nil {
+			return
+		}
+		c.sendMessage(Message{
+			Type: "SABOTAGE_SWAP",
+			Data: map[string]interface{}{
+				"partnerID":   partner.ID,
+				"partnerName": partner.Username,
+				"duration":    left.Milliseconds(),
+			},
+		})
+		return
+	}
+}
+
+// endSwap puts the swapped crewmates back. Runs on the room goroutine.
+func (r *Room) endSwap() {
+	r.sabotageTimer = nil
+	r.sabotageActive = false
+	r.sabotageType = ""
+	r.swappedIDs = nil
+	r.survivedSabotage()
+	r.saveToRedis()
+
+	endMsg := Message{
+		Type: "SABOTAGE_ENDED",
+		Data: map[string]interface{}{
+			"type": "SWAP",
+		},
+	}
+	endData, _ := json.Marshal(endMsg)
+	r.emit(endData)
+
+	r.emitSystemChat("system.swap_ended", nil)
+
+	log.Printf("SWAP sabotage ended")
+}
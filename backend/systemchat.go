@@ -0,0 +1,75 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// System chat lines go out as an i18n key and its parameters, so clients
+// can show them in the reader's language, with the English text filled in
+// for clients that don't know the key. They take the same path as players'
+// lines once translated: emit, and so CHAT_HISTORY and spectators.
+
+// systemMessages are the English texts of the system chat keys. Each
+// {param} is filled in from the line's params.
+var systemMessages = map[string]string{
+	"system.left_lobby":      "{name} left the lobby",
+	"system.new_host":        "👑 {name} is now the host",
+	"system.disconnected":    "⚠️ COMMUNICATION LOST: {name} has disconnected - waiting for them to reconnect",
+	"system.reconnected":     "📡 {name} reconnected",
+	"system.connection_lost": "⚠️ COMMUNICATION LOST: {name} did not reconnect",
+
+	"system.no_elimination":  "No one was eliminated. The crew continues...",
+	"system.voted_out":       "🗳️ {name} was voted out!",
+	"system.not_impostor":    "{name} was not the impostor...",
+	"system.impostor_caught": "{name} was an impostor! {remaining} more still aboard...",
+
+	"system.freeze":           "⚠️ SYSTEM JAMMED - Communications frozen!",
+	"system.freeze_ended":     "✅ Systems restored - Communications online",
+	"system.corrupt":          "🦠 MALWARE DETECTED - Code corrupted!",
+	"system.corrupt_cleaned":  "✅ Malware purged - Code integrity restored",
+	"system.swap":             "🔀 CROSSED WIRES - Two crewmates' terminals have been swapped!",
+	"system.swap_ended":       "✅ Wiring repaired - Terminals back in the right hands",
+	"system.timer_drain":      "⏳ POWER DRAIN - {seconds} seconds ripped off the clock!",
+	"system.meltdown":         "☢️ REACTOR MELTDOWN - {repairs} crewmates must REPAIR within {seconds} seconds!",
+	"system.meltdown_averted": "✅ Reactor stabilised - Meltdown averted",
+}
+
+// systemText renders key's English text with params.
+func systemText(key string, params map[string]interface{}) string {
+	text, ok := systemMessages[key]
+	if !ok {
+		return key
+	}
+	for name, value := range params {
+		text = strings.ReplaceAll(text, "{"+name+"}", fmt.Sprint(value))
+	}
+	return text
+}
+
+// emitSystemChat sends the room a system chat line. Runs on the room
+// goroutine.
+func (r *Room) emitSystemChat(key string, params map[string]interface{}) {
+	line := map[string]interface{}{
+		"messageId": uuid.New().String(),
+		"username":  "System",
+		"text":      systemText(key, params),
+		"key":       key,
+		"timestamp": time.Now().UnixMilli(),
+		"system":    true,
+	}
+	if len(params) > 0 {
+		values := make(map[string]string, len(params))
+		for name, value := range params {
+			values[name] = fmt.Sprint(value)
+		}
+		line["params"] = values
+	}
+
+	data, _ := json.Marshal(Message{Type: "CHAT", Data: line})
+	r.emit(data)
+}
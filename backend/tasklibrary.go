@@ -0,0 +1,239 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"code-mafia-backend/config"
+	"code-mafia-backend/sandbox"
+)
+
+// taskLibraryPollInterval is how often TASKS_DIR is checked for changes.
+const taskLibraryPollInterval = 30 * time.Second
+
+// libraryTask is one task definition file in TASKS_DIR. A file whose id
+// matches a built-in task replaces it, and "disabled" takes either out of
+// the pool without deleting it.
+//
+//	{
+//	  "id": "task1-lasers",
+//	  "stage": 1,
+//	  "title": "WEAPONS - Laser Calibration",
+//	  "description": "...",
+//	  "language": "java", // or python, javascript, go
+//	  "category": "OOPS",
+//	  "difficulty": "EASY",
+//	  "template": "public class Laser { ... }",
+//	  "tests": "public class TaskTests { ... }",
+//	  "checks": {"require": [["newlaser()"], ["power>0", "power>=1"]]},
+//	  "translations": {"es": {"title": "...", "description": "..."}}
+//	}
+type libraryTask struct {
+	ID           string                     `json:"id"`
+	Stage        int                        `json:"stage"`
+	Title        string                     `json:"title"`
+	Description  string                     `json:"description"`
+	Language     string                     `json:"language"`
+	Category     string                     `json:"category"`
+	Difficulty   string                     `json:"difficulty"`
+	Template     string                     `json:"template"`
+	Tests        string                     `json:"tests"`
+	Checks       *taskChecks                `json:"checks"`
+	Translations map[string]taskTranslation `json:"translations"`
+	Disabled     bool                       `json:"disabled"`
+}
+
+type taskTranslation struct {
+	Title       string `json:"title"`
+	Description string `json:"description"`
+}
+
+// taskChecks judge a submission without running it, for when there is no
+// code runner or the task has no tests. Each entry in Require lists
+// alternative spellings of one fix, matched against the source with
+// comments and whitespace stripped and lowercased. A submission passes when
+// it makes MinFixes of them, or all of them if MinFixes is zero.
+type taskChecks struct {
+	Require  [][]string `json:"require"`
+	MinFixes int        `json:"minFixes"`
+}
+
+func (c *taskChecks) passes(code string) bool {
+	normalized := normalizeCode(code)
+	fixed := 0
+	for _, alternatives := range c.Require {
+		if containsAny(normalized, alternatives) {
+			fixed++
+		}
+	}
+	want := c.MinFixes
+	if want <= 0 || want > len(c.Require) {
+		want = len(c.Require)
+	}
+	return len(c.Require) > 0 && fixed >= want
+}
+
+// taskLibrary is what was last loaded from TASKS_DIR.
+type taskLibrary struct {
+	mu          sync.RWMutex
+	tasks       []libraryTask
+	fingerprint string
+}
+
+var tasksFromFiles taskLibrary
+
+// initTaskLibrary loads TASKS_DIR and keeps watching it, so new puzzles
+// ship without a restart.
+func initTaskLibrary() {
+	dir := config.AppConfig.TasksDir
+	if dir == "" {
+		log.Println("📚 Task library disabled (TASKS_DIR empty)")
+		return
+	}
+
+	reloadTaskLibrary(dir)
+	go func() {
+		for range time.Tick(taskLibraryPollInterval) {
+			reloadTaskLibrary(dir)
+		}
+	}()
+}
+
+// reloadTaskLibrary reads dir again if any file in it changed. Broken files
+// are skipped with a log line rather than taking the rest down with them.
+func reloadTaskLibrary(dir string) {
+	files, _ := filepath.Glob(filepath.Join(dir, "*.json"))
+	sort.Strings(files)
+
+	fingerprint := libraryFingerprint(files)
+	tasksFromFiles.mu.RLock()
+	unchanged := fingerprint == tasksFromFiles.fingerprint
+	tasksFromFiles.mu.RUnlock()
+	if unchanged {
+		return
+	}
+
+	tasks := make([]libraryTask, 0, len(files))
+	seen := make(map[string]string)
+	for _, file := range files {
+		task, err := readLibraryTask(file)
+		if err != nil {
+			log.Printf("⚠️ Skipping task file %s: %v", file, err)
+			continue
+		}
+		if other, dup := seen[task.ID]; dup {
+			log.Printf("⚠️ Skipping task file %s: id %q is already used by %s", file, task.ID, other)
+			continue
+		}
+		seen[task.ID] = file
+		tasks = append(tasks, task)
+	}
+
+	tasksFromFiles.mu.Lock()
+	tasksFromFiles.tasks = tasks
+	tasksFromFiles.fingerprint = fingerprint
+	tasksFromFiles.mu.Unlock()
+
+	log.Printf("📚 Task library loaded %d tasks from %s", len(tasks), dir)
+}
+
+func libraryFingerprint(files []string) string {
+	var b strings.Builder
+	for _, file := range files {
+		info, err := os.Stat(file)
+		if err != nil {
+			continue
+		}
+		fmt.Fprintf(&b, "%s:%d:%d;", file, info.Size(), info.ModTime().UnixNano())
+	}
+	return b.String()
+}
+
+func readLibraryTask(file string) (libraryTask, error) {
+	var task libraryTask
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return task, err
+	}
+	if err := json.Unmarshal(data, &task); err != nil {
+		return task, err
+	}
+
+	switch {
+	case task.ID == "":
+		return task, fmt.Errorf("id is required")
+	case task.Disabled:
+		return task, nil
+	case task.Stage < 1 || task.Stage > 3:
+		return task, fmt.Errorf("stage must be 1, 2 or 3, got %d", task.Stage)
+	case task.Title == "" || task.Template == "":
+		return task, fmt.Errorf("title and template are required")
+	case !sandbox.Supported(task.Language):
+		return task, fmt.Errorf("language %q is not supported", task.Language)
+	case task.Tests == "" && task.Checks == nil && taskTests[task.ID] == "":
+		return task, fmt.Errorf("tests or checks are required to judge submissions")
+	}
+
+	if err := oneOf("category", &task.Category, taskCategories); err != nil {
+		return task, err
+	}
+	return task, oneOf("difficulty", &task.Difficulty, taskDifficulties)
+}
+
+// standardTasks are the built-in tasks with the library's changes applied.
+func standardTasks() []*Task {
+	tasksFromFiles.mu.RLock()
+	library := tasksFromFiles.tasks
+	tasksFromFiles.mu.RUnlock()
+
+	overridden := make(map[string]bool, len(library))
+	for _, t := range library {
+		overridden[t.ID] = true
+	}
+
+	pool := make([]*Task, 0, 3+len(library))
+	for _, t := range new(Room).loadAllTasks() {
+		if !overridden[t.ID] {
+			pool = append(pool, t)
+		}
+	}
+	for _, t := range library {
+		if !t.Disabled {
+			pool = append(pool, t.task())
+		}
+	}
+	return pool
+}
+
+func (t libraryTask) task() *Task {
+	task := &Task{
+		ID:                      t.ID,
+		Stage:                   t.Stage,
+		Title:                   t.Title,
+		Description:             t.Description,
+		Template:                t.Template,
+		Tests:                   t.Tests,
+		Language:                t.Language,
+		Category:                t.Category,
+		Difficulty:              t.Difficulty,
+		Checks:                  t.Checks,
+		TitleTranslations:       make(map[string]string),
+		DescriptionTranslations: make(map[string]string),
+	}
+	for lang, tr := range t.Translations {
+		if tr.Title != "" {
+			task.TitleTranslations[lang] = tr.Title
+		}
+		if tr.Description != "" {
+			task.DescriptionTranslations[lang] = tr.Description
+		}
+	}
+	return task
+}
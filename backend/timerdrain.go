@@ -0,0 +1,59 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"time"
+
+	"code-mafia-backend/config"
+)
+
+// timerDrainFloor is as low as a TIMER_DRAIN takes the clock. Running it
+// out is for the crew to do.
+const timerDrainFloor = 10 * time.Second
+
+// checkTimerDrain returns why playerID can't drain the clock now, or "" if
+// they can. Runs on the room goroutine.
+func (r *Room) checkTimerDrain(playerID string) string {
+	limit := config.Current().TimerDrainMaxPerImpostor
+	switch {
+	case limit == 0:
+		return "Timer drain is turned off on this server"
+	case r.timerDrains[playerID] >= limit:
+		return "You have used all your timer drains this game"
+	case r.gameState.TimerDeadline.IsZero():
+		return "The clock hasn't started yet"
+	case r.gameState.timerRemaining(time.Now()) <= timerDrainFloor:
+		return "The clock is too low to drain"
+	}
+	return ""
+}
+
+// handleTimerDrain takes TIMER_DRAIN_SECONDS off the game clock at once,
+// or as much as leaves timerDrainFloor. Runs on the room goroutine.
+func (r *Room) handleTimerDrain(playerID string) {
+	r.timerDrains[playerID]++
+
+	drain := time.Duration(config.Current().TimerDrainSec) * time.Second
+	if most := r.gameState.timerRemaining(time.Now()) - timerDrainFloor; drain > most {
+		drain = most
+	}
+	r.gameState.TimerDeadline = r.gameState.TimerDeadline.Add(-drain)
+	r.armClock()
+
+	// The drain is over as soon as it lands, like CORRUPT.
+	r.sabotageActive = false
+	r.sabotageType = ""
+	r.survivedSabotage()
+	r.saveToRedis()
+
+	seconds := int(drain.Round(time.Second).Seconds())
+	log.Printf("TIMER_DRAIN sabotage took %ds off the clock in room %s", seconds, r.ID)
+
+	payload := r.timerPayload()
+	payload["drainedSeconds"] = seconds
+	data, _ := json.Marshal(Message{Type: "SYNC_TIMER", Data: payload})
+	r.emit(data)
+
+	r.emitSystemChat("system.timer_drain", map[string]interface{}{"seconds": seconds})
+}
@@ -0,0 +1,39 @@
+package main
+
+import "time"
+
+// timerSyncInterval is how often a running game clock is re-sent so
+// clients counting down on their own don't drift far.
+const timerSyncInterval = 5 * time.Second
+
+// runTimerSync re-sends every running clock on one ticker for the whole
+// hub. Rooms never tick themselves: the end of the clock is scheduled for
+// its deadline, and clients count down between syncs.
+func (h *Hub) runTimerSync() {
+	ticker := time.NewTicker(timerSyncInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-h.closing:
+			return
+		case <-ticker.C:
+			for _, room := range h.allRooms() {
+				room.post(room.resyncTimer)
+			}
+		}
+	}
+}
+
+// resyncTimer sends the clock if it is running. Runs on the room goroutine.
+func (r *Room) resyncTimer() {
+	switch r.gameState.Phase {
+	case PhaseTask1, PhaseTask2, PhaseTask3:
+	default:
+		return
+	}
+	if r.gameState.TimerPaused || r.gameState.TimerDeadline.IsZero() {
+		return
+	}
+	r.broadcastTimer()
+}
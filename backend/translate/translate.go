@@ -0,0 +1,215 @@
+// Package translate machine-translates chat lines and task text through a
+// hosted translation API: DeepL, Google Cloud Translation or a
+// LibreTranslate server. It lets the backend translate in-process, without
+// the translation sidecar.
+package translate
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	ProviderDeepL          = "deepl"
+	ProviderGoogle         = "google"
+	ProviderLibreTranslate = "libretranslate"
+)
+
+// Translator translates text, in whatever language it is written, into
+// target, a locale code like "de" or "pt-BR".
+type Translator interface {
+	Translate(ctx context.Context, text, target string) (string, error)
+}
+
+// New returns a Translator for provider. A blank baseURL picks the
+// provider's default.
+func New(provider, apiKey, baseURL string) (Translator, error) {
+	client := &http.Client{Timeout: 10 * time.Second}
+
+	switch provider {
+	case ProviderDeepL:
+		if baseURL == "" {
+			// DeepL's free plan has its own host; its keys end in ":fx".
+			baseURL = "https://api.deepl.com"
+			if strings.HasSuffix(apiKey, ":fx") {
+				baseURL = "https://api-free.deepl.com"
+			}
+		}
+		return &deepL{apiKey: apiKey, baseURL: baseURL, client: client}, nil
+	case ProviderGoogle:
+		if baseURL == "" {
+			baseURL = "https://translation.googleapis.com"
+		}
+		return &google{apiKey: apiKey, baseURL: baseURL, client: client}, nil
+	case ProviderLibreTranslate:
+		if baseURL == "" {
+			baseURL = "https://libretranslate.com"
+		}
+		return &libreTranslate{apiKey: apiKey, baseURL: baseURL, client: client}, nil
+	}
+	return nil, fmt.Errorf("unknown translation provider %q", provider)
+}
+
+// All translates text into each of targets at once, returning the
+// translations by target. It fails if any of them does.
+func All(ctx context.Context, t Translator, text string, targets []string) (map[string]string, error) {
+	var (
+		mu           sync.Mutex
+		wg           sync.WaitGroup
+		translations = make(map[string]string, len(targets))
+		firstErr     error
+	)
+	for _, target := range targets {
+		wg.Add(1)
+		go func(target string) {
+			defer wg.Done()
+			translated, err := t.Translate(ctx, text, target)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				if firstErr == nil {
+					firstErr = fmt.Errorf("%s: %w", target, err)
+				}
+				return
+			}
+			translations[target] = translated
+		}(target)
+	}
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	return translations, nil
+}
+
+type deepL struct {
+	apiKey  string
+	baseURL string
+	client  *http.Client
+}
+
+func (d *deepL) Translate(ctx context.Context, text, target string) (string, error) {
+	body := map[string]interface{}{
+		"text":        []string{text},
+		"target_lang": deepLLanguage(target),
+	}
+	var resp struct {
+		Translations []struct {
+			Text string `json:"text"`
+		} `json:"translations"`
+	}
+	headers := map[string]string{"Authorization": "DeepL-Auth-Key " + d.apiKey}
+	if err := post(ctx, d.client, d.baseURL+"/v2/translate", headers, body, &resp); err != nil {
+		return "", err
+	}
+	if len(resp.Translations) == 0 {
+		return "", fmt.Errorf("deepl: no translations in response")
+	}
+	return resp.Translations[0].Text, nil
+}
+
+// deepLLanguage is target as DeepL names it: upper case, and English with
+// a variant, which DeepL requires.
+func deepLLanguage(target string) string {
+	target = strings.ToUpper(target)
+	if target == "EN" {
+		return "EN-US"
+	}
+	return target
+}
+
+type google struct {
+	apiKey  string
+	baseURL string
+	client  *http.Client
+}
+
+func (g *google) Translate(ctx context.Context, text, target string) (string, error) {
+	body := map[string]interface{}{
+		"q":      text,
+		"target": target,
+		"format": "text",
+	}
+	var resp struct {
+		Data struct {
+			Translations []struct {
+				TranslatedText string `json:"translatedText"`
+			} `json:"translations"`
+		} `json:"data"`
+	}
+	endpoint := g.baseURL + "/language/translate/v2?key=" + url.QueryEscape(g.apiKey)
+	if err := post(ctx, g.client, endpoint, nil, body, &resp); err != nil {
+		return "", err
+	}
+	if len(resp.Data.Translations) == 0 {
+		return "", fmt.Errorf("google: no translations in response")
+	}
+	return resp.Data.Translations[0].TranslatedText, nil
+}
+
+type libreTranslate struct {
+	apiKey  string
+	baseURL string
+	client  *http.Client
+}
+
+func (l *libreTranslate) Translate(ctx context.Context, text, target string) (string, error) {
+	body := map[string]interface{}{
+		"q":      text,
+		"source": "auto",
+		"target": target,
+		"format": "text",
+	}
+	if l.apiKey != "" {
+		body["api_key"] = l.apiKey
+	}
+	var resp struct {
+		TranslatedText string `json:"translatedText"`
+	}
+	if err := post(ctx, l.client, l.baseURL+"/translate", nil, body, &resp); err != nil {
+		return "", err
+	}
+	return resp.TranslatedText, nil
+}
+
+func post(ctx context.Context, client *http.Client, endpoint string, headers map[string]string, body, out interface{}) error {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		// The URL may carry an API key, so only the host is named.
+		host := endpoint
+		if u, err := url.Parse(endpoint); err == nil {
+			host = u.Host
+		}
+		return fmt.Errorf("%s returned %d: %s", host, resp.StatusCode, strings.TrimSpace(string(detail)))
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"time"
+
+	"code-mafia-backend/config"
+	"code-mafia-backend/database"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// When the translator answers a request with an error, the room
+// that asked queues it in Redis to be sent again, backing off each time,
+// and gives up after TRANSLATION_RETRIES: the request goes to the
+// dead-letter list and a waiting chat line goes out untranslated. The
+// room counts the failures, since the sidecar's error doesn't say which
+// attempt failed. Admins can list the dead-lettered requests and send them
+// back; a chat line will have gone out by then, so that only helps tasks.
+
+const (
+	translationRetryInterval = 250 * time.Millisecond
+	translationRetryBatch    = 50
+)
+
+// chatTranslationFailed retries or gives up on the translation of a held
+// chat line. Runs on the room goroutine.
+func (r *Room) chatTranslationFailed(messageID, reason string) {
+	line, ok := r.chatPending[messageID]
+	if !ok {
+		return
+	}
+
+	key := "chat:" + messageID
+	r.translationFailures[key]++
+	job := database.TranslationJob{
+		ID:        uuid.New().String(),
+		Kind:      database.TranslationKindChat,
+		RoomID:    r.ID,
+		MessageID: line.ID,
+		PlayerID:  line.PlayerID,
+		Username:  line.Username,
+		Text:      line.Text,
+		Attempts:  r.translationFailures[key],
+		LastError: reason,
+		FailedAt:  time.Now().Unix(),
+	}
+	if !r.retryTranslation(key, job) {
+		r.deliverChat(messageID, nil)
+	}
+}
+
+// taskTranslationFailed retries or gives up on the translation of a task's
+// field. Runs on the room goroutine.
+func (r *Room) taskTranslationFailed(taskID, field, reason string) {
+	var text string
+	for _, t := range r.gameTasks() {
+		if t.ID != taskID {
+			continue
+		}
+		switch field {
+		case "title":
+			text = t.Title
+		case "description":
+			text = t.Description
+		}
+	}
+	if text == "" {
+		return
+	}
+
+	key := "task:" + taskID + "." + field
+	r.translationFailures[key]++
+	r.retryTranslation(key, database.TranslationJob{
+		ID:        uuid.New().String(),
+		Kind:      database.TranslationKindTask,
+		RoomID:    r.ID,
+		TaskID:    taskID,
+		Field:     field,
+		Text:      text,
+		Attempts:  r.translationFailures[key],
+		LastError: reason,
+		FailedAt:  time.Now().Unix(),
+	})
+}
+
+// retryTranslation queues job to be sent again after a delay doubling with
+// each attempt, or dead-letters it once its retries are spent, reporting
+// which. Runs on the room goroutine.
+func (r *Room) retryTranslation(key string, job database.TranslationJob) bool {
+	cfg := config.Current()
+	if job.Attempts > cfg.TranslationRetries {
+		delete(r.translationFailures, key)
+		incMetric("translation_dead_letters_total")
+		log.Printf("💀 Giving up on %s translation in room %s after %d attempts: %s", key, r.ID, job.Attempts, job.LastError)
+		go func() {
+			if err := database.DeadLetterTranslation(job, cfg.TranslationDeadLetterMax); err != nil {
+				log.Printf("Failed to dead-letter %s translation: %v", key, err)
+			}
+		}()
+		return false
+	}
+
+	delay := time.Duration(cfg.TranslationRetryBaseMs) * time.Millisecond << (job.Attempts - 1)
+	incMetric("translation_retries_total")
+	log.Printf("🔁 Retrying %s translation in room %s in %v (attempt %d): %s", key, r.ID, delay, job.Attempts, job.LastError)
+	go func() {
+		if err := database.ScheduleTranslationRetry(job, time.Now().Add(delay)); err != nil {
+			log.Printf("Failed to queue %s translation retry: %v", key, err)
+		}
+	}()
+	return true
+}
+
+// runTranslationRetries sends queued translation requests again as they
+// come due.
+func (h *Hub) runTranslationRetries() {
+	ticker := time.NewTicker(translationRetryInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-h.closing:
+			return
+		case <-ticker.C:
+			h.resendDueTranslations()
+		}
+	}
+}
+
+func (h *Hub) resendDueTranslations() {
+	defer recoverPanic("resendDueTranslations")
+
+	jobs, err := database.ClaimDueTranslationRetries(time.Now(), translationRetryBatch)
+	if err != nil {
+		log.Printf("Failed to claim translation retries: %v", err)
+	}
+	for _, job := range jobs {
+		go func(job database.TranslationJob) {
+			if err := resendTranslation(job); err != nil {
+				log.Printf("Failed to resend translation %s: %v", job.ID, err)
+			}
+		}(job)
+	}
+}
+
+func resendTranslation(job database.TranslationJob) error {
+	if job.Kind == database.TranslationKindTask {
+		return requestTaskTranslation(job.RoomID, job.TaskID, job.Field, job.Text)
+	}
+
+	history, err := database.GetRoomChatHistory(job.RoomID, 3)
+	if err != nil {
+		history = []string{}
+	}
+	return requestChatTranslation(job.MessageID, job.Text, job.Username, job.RoomID, job.PlayerID, history)
+}
+
+func handleAdminListDeadTranslations(w http.ResponseWriter, r *http.Request) {
+	limit, offset, err := parsePage(r)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	jobs, err := database.DeadTranslations(limit, offset)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"translations": jobs,
+	})
+}
+
+func handleAdminRequeueTranslation(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	found, err := database.RequeueDeadTranslation(id)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{
+			"error": err.Error(),
+		})
+		return
+	}
+	if !found {
+		writeJSON(w, http.StatusNotFound, map[string]interface{}{
+			"error": "no dead-lettered translation with that id",
+		})
+		return
+	}
+
+	log.Printf("🔁 Requeued dead-lettered translation %s", id)
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"code-mafia-backend/config"
+	"code-mafia-backend/database"
+	"code-mafia-backend/translate"
+
+	"github.com/google/uuid"
+)
+
+// Chat lines and task text are sent for translation through
+// requestChatTranslation and requestTaskTranslation. With the sidecar they
+// are published for it on Redis. In-process, translator translates them
+// and the result goes out on the channel the sidecar answers on, so either
+// way listenForTranslations picks it up, on whichever server holds the
+// room, and failures are retried the same way.
+
+// translator translates in-process. While it is nil the sidecar does.
+var translator translate.Translator
+
+// inProcessTranslationTimeout bounds translating one text into every
+// language.
+const inProcessTranslationTimeout = 10 * time.Second
+
+func initTranslator() {
+	cfg := config.AppConfig
+	if cfg.TranslationProvider == "sidecar" {
+		log.Printf("🌐 Translation: sidecar")
+		return
+	}
+
+	t, err := translate.New(cfg.TranslationProvider, cfg.TranslationAPIKey, cfg.TranslationURL)
+	if err != nil {
+		log.Printf("⚠️ Translation falls back to the sidecar: %v", err)
+		return
+	}
+	translator = t
+	log.Printf("🌐 Translation: in-process through %s, into %v", cfg.TranslationProvider, cfg.TranslationLanguages)
+}
+
+// translationMode describes who translates, for the startup banner.
+func translationMode() string {
+	if translator == nil {
+		return "sidecar"
+	}
+	return "in-process (" + config.AppConfig.TranslationProvider + ")"
+}
+
+// requestChatTranslation sends a chat line for translation. In-process it
+// blocks while translating, so it is called off the room goroutine.
+func requestChatTranslation(messageID, text, username, roomID, playerID string, history []string) error {
+	if translator == nil {
+		return database.PublishChatMessage(messageID, text, username, roomID, playerID, history)
+	}
+
+	result := map[string]interface{}{
+		"messageId": messageID,
+		"roomId":    roomID,
+	}
+	translateInProcess(text, result)
+	return database.PublishTranslationResult(database.ChatTranslationsChannel(), result)
+}
+
+// requestTaskTranslation sends a task's field for translation. In-process
+// it blocks while translating, so it is called off the room goroutine.
+func requestTaskTranslation(roomID, taskID, field, text string) error {
+	if translator == nil {
+		return database.PublishTaskTranslation(roomID, taskID, field, text)
+	}
+
+	result := map[string]interface{}{
+		"taskId":    taskID,
+		"roomId":    roomID,
+		"field":     field,
+		"requestId": uuid.New().String(),
+	}
+	translateInProcess(text, result)
+	return database.PublishTranslationResult(database.TaskTranslationsChannel(), result)
+}
+
+// translateInProcess adds text's translations to result, or the error
+// that stopped them, as the sidecar would.
+func translateInProcess(text string, result map[string]interface{}) {
+	ctx, cancel := context.WithTimeout(context.Background(), inProcessTranslationTimeout)
+	defer cancel()
+
+	translations, err := translate.All(ctx, translator, text, config.AppConfig.TranslationLanguages)
+	if err != nil {
+		result["error"] = err.Error()
+		return
+	}
+	result["translations"] = translations
+}
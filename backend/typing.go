@@ -0,0 +1,80 @@
+package main
+
+import "time"
+
+// During a meeting, living players see who else is typing. A client sends
+// TYPING_START when its player starts composing and again every few
+// seconds while they keep at it, and TYPING_STOP when they stop or send.
+// Only changes are relayed: a repeated TYPING_START just keeps the player
+// marked, and a player the server hasn't heard from for typingTimeout, or
+// who sends their line, leaves or is muted, stops typing on their own.
+
+// typingTimeout is how long a TYPING_START keeps a player marked as typing.
+const typingTimeout = 6 * time.Second
+
+// Typing messages have their own small budget, so a chatty client can't
+// spend the one chat lines and votes draw from.
+const (
+	typingPerMinute = 40
+	typingBurst     = 6
+)
+
+// canType reports whether playerID's typing is shown: only living players,
+// during a meeting, who may chat.
+func (r *Room) canType(playerID string) bool {
+	player := r.players[playerID]
+	return r.gameState.Phase == PhaseDiscussion && player != nil && !player.IsEliminated && !r.isMuted(playerID)
+}
+
+// startTyping marks playerID as typing, telling the others if they weren't
+// already. Runs on the room goroutine.
+func (r *Room) startTyping(playerID string) {
+	if !r.canType(playerID) {
+		r.stopTyping(playerID)
+		return
+	}
+
+	if timer, ok := r.typing[playerID]; ok {
+		timer.Reset(typingTimeout)
+		return
+	}
+	r.typing[playerID] = r.schedule(typingTimeout, func() { r.stopTyping(playerID) })
+	r.relayTyping(playerID, true)
+}
+
+// stopTyping clears playerID's typing mark, telling the others if it was
+// set. Runs on the room goroutine.
+func (r *Room) stopTyping(playerID string) {
+	timer, ok := r.typing[playerID]
+	if !ok {
+		return
+	}
+	timer.Stop()
+	delete(r.typing, playerID)
+	r.relayTyping(playerID, false)
+}
+
+// resetTyping forgets everyone's typing without telling anyone, for when
+// a meeting starts or ends. Runs on the room goroutine.
+func (r *Room) resetTyping() {
+	for _, timer := range r.typing {
+		timer.Stop()
+	}
+	r.typing = make(map[string]*time.Timer)
+}
+
+// relayTyping tells the other living players that playerID started or
+// stopped typing. Runs on the room goroutine.
+func (r *Room) relayTyping(playerID string, typing bool) {
+	msg := Message{Type: "TYPING_STOP", Data: map[string]interface{}{"playerId": playerID}}
+	if typing {
+		msg = Message{Type: "TYPING_START", Data: map[string]interface{}{"playerId": playerID}}
+	}
+
+	for c := range r.clients {
+		player := r.players[c.PlayerID]
+		if c.PlayerID != playerID && player != nil && !player.IsEliminated {
+			c.sendMessage(msg)
+		}
+	}
+}
@@ -0,0 +1,209 @@
+// Package webhooks delivers signed game events to external endpoints such
+// as Discord bots, stream overlays and classroom dashboards.
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+const (
+	EventGameStarted      = "game.started"
+	EventMeetingCalled    = "meeting.called"
+	EventPlayerEliminated = "player.eliminated"
+	EventGameEnded        = "game.ended"
+
+	SignatureHeader = "X-CodeMafia-Signature"
+	EventHeader     = "X-CodeMafia-Event"
+
+	maxAttempts = 3
+)
+
+var ErrPrivateAddress = errors.New("webhook target resolves to a private address")
+
+var KnownEvents = map[string]bool{
+	EventGameStarted:      true,
+	EventMeetingCalled:    true,
+	EventPlayerEliminated: true,
+	EventGameEnded:        true,
+}
+
+type Endpoint struct {
+	ID        string    `json:"id"`
+	URL       string    `json:"url"`
+	Secret    string    `json:"secret"`
+	Events    []string  `json:"events,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+
+	// Untrusted endpoints were registered by players rather than an
+	// operator and may not reach private networks.
+	Untrusted bool `json:"untrusted,omitempty"`
+}
+
+// Wants reports whether the endpoint subscribed to eventType. An empty
+// subscription list means every event.
+func (e Endpoint) Wants(eventType string) bool {
+	if len(e.Events) == 0 {
+		return true
+	}
+	for _, t := range e.Events {
+		if t == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+type Event struct {
+	ID     string      `json:"id"`
+	Type   string      `json:"type"`
+	RoomID string      `json:"roomId"`
+	Time   time.Time   `json:"time"`
+	Data   interface{} `json:"data"`
+}
+
+// NewEndpoint validates rawURL and generates a signing secret.
+func NewEndpoint(rawURL string, events []string, untrusted bool) (Endpoint, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return Endpoint{}, fmt.Errorf("invalid webhook url")
+	}
+	if u.Scheme != "https" && (untrusted || u.Scheme != "http") {
+		return Endpoint{}, fmt.Errorf("webhook url must use https")
+	}
+	for _, e := range events {
+		if !KnownEvents[e] {
+			return Endpoint{}, fmt.Errorf("unknown event %q", e)
+		}
+	}
+
+	return Endpoint{
+		ID:        randomHex(8),
+		URL:       rawURL,
+		Secret:    randomHex(24),
+		Events:    events,
+		CreatedAt: time.Now(),
+		Untrusted: untrusted,
+	}, nil
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// Sign returns the signature header value for body sent at timestamp.
+// Receivers recompute HMAC-SHA256(secret, "<t>.<body>") and compare it to
+// v1, rejecting stale timestamps to stop replays.
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10) + "."))
+	mac.Write(body)
+	return fmt.Sprintf("t=%d,v1=%s", timestamp, hex.EncodeToString(mac.Sum(nil)))
+}
+
+var (
+	trustedClient = &http.Client{Timeout: 5 * time.Second}
+
+	// publicClient refuses to connect to loopback, private and link-local
+	// addresses, checked after DNS resolution so rebinding can't dodge it.
+	publicClient = &http.Client{
+		Timeout: 5 * time.Second,
+		Transport: &http.Transport{
+			DialContext: (&net.Dialer{
+				Timeout: 3 * time.Second,
+				Control: func(network, address string, _ syscall.RawConn) error {
+					host, _, err := net.SplitHostPort(address)
+					if err != nil {
+						return err
+					}
+					ip := net.ParseIP(host)
+					if ip == nil || ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+						ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() {
+						return ErrPrivateAddress
+					}
+					return nil
+				},
+			}).DialContext,
+		},
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+)
+
+// Deliver sends event to every endpoint that wants it, in the background,
+// retrying failed deliveries with backoff.
+func Deliver(endpoints []Endpoint, event Event) {
+	body, err := json.Marshal(event)
+	if err != nil {
+		log.Printf("Failed to marshal webhook event %s: %v", event.Type, err)
+		return
+	}
+
+	for _, endpoint := range endpoints {
+		if !endpoint.Wants(event.Type) {
+			continue
+		}
+		go deliver(endpoint, event.Type, body)
+	}
+}
+
+func deliver(endpoint Endpoint, eventType string, body []byte) {
+	client := trustedClient
+	if endpoint.Untrusted {
+		client = publicClient
+	}
+
+	var err error
+	for attempt := 1; attempt <= maxAttempts; attempt++ {
+		if err = post(client, endpoint, eventType, body); err == nil {
+			return
+		}
+		if errors.Is(err, ErrPrivateAddress) {
+			break
+		}
+		time.Sleep(time.Duration(attempt*attempt) * time.Second)
+	}
+
+	log.Printf("Webhook %s (%s) failed for %s: %v", endpoint.ID, endpoint.URL, eventType, err)
+}
+
+func post(client *http.Client, endpoint Endpoint, eventType string, body []byte) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventHeader, eventType)
+	req.Header.Set(SignatureHeader, Sign(endpoint.Secret, time.Now().Unix(), body))
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("status %d", resp.StatusCode)
+	}
+	return nil
+}
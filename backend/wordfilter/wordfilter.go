@@ -0,0 +1,158 @@
+// Package wordfilter finds blocked words in chat lines and usernames.
+// Words are matched case-insensitively and through the usual character
+// swaps (sh1t, $hit), as whole words in chat so innocent words containing
+// one aren't caught, and anywhere in a username, where words run together.
+package wordfilter
+
+import (
+	"bufio"
+	"os"
+	"strings"
+	"unicode"
+)
+
+// Default is used when no word list is configured. It is deliberately
+// short, and leaves out words that are also names or sit inside common
+// words (Dick, Hitchcock); operators with stricter needs load their own
+// list.
+var Default = []string{
+	"fuck", "fucker", "fucking", "motherfucker", "shit", "bullshit",
+	"bitch", "bastard", "asshole", "cunt", "dickhead", "twat", "wanker",
+	"whore", "slut", "retard",
+}
+
+// leet undoes the character swaps people use to get around filters.
+var leet = map[rune]rune{
+	'0': 'o', '1': 'i', '3': 'e', '4': 'a', '5': 's', '7': 't',
+	'@': 'a', '$': 's',
+}
+
+// Filter matches one list of words.
+type Filter struct {
+	words map[string]bool
+}
+
+// New returns a Filter for words. Blank entries are skipped.
+func New(words []string) *Filter {
+	f := &Filter{words: make(map[string]bool, len(words))}
+	for _, w := range words {
+		if w = normalize(strings.TrimSpace(w)); w != "" {
+			f.words[w] = true
+		}
+	}
+	return f
+}
+
+// Load reads a word list with one word per line; blank lines and lines
+// starting with # are skipped.
+func Load(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var words []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line != "" && !strings.HasPrefix(line, "#") {
+			words = append(words, line)
+		}
+	}
+	return words, scanner.Err()
+}
+
+// Len is how many words the filter blocks.
+func (f *Filter) Len() int {
+	return len(f.words)
+}
+
+// span is where a token sits in the original text, in bytes.
+type span struct {
+	start, end int
+	word       string
+}
+
+// tokens splits text into runs of letters, digits and the characters in
+// leet, normalized.
+func tokens(text string) []span {
+	var spans []span
+	start := -1
+	for i, r := range text {
+		if isWordRune(r) {
+			if start < 0 {
+				start = i
+			}
+			continue
+		}
+		if start >= 0 {
+			spans = append(spans, span{start, i, normalize(text[start:i])})
+			start = -1
+		}
+	}
+	if start >= 0 {
+		spans = append(spans, span{start, len(text), normalize(text[start:])})
+	}
+	return spans
+}
+
+func isWordRune(r rune) bool {
+	_, swapped := leet[r]
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || swapped
+}
+
+func normalize(word string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(word) {
+		if swap, ok := leet[r]; ok {
+			r = swap
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// Words returns the blocked words in text, matched as whole words.
+func (f *Filter) Words(text string) []string {
+	var found []string
+	for _, t := range tokens(text) {
+		if f.words[t.word] {
+			found = append(found, t.word)
+		}
+	}
+	return found
+}
+
+// Mask replaces each blocked word in text with as many asterisks as it has
+// characters.
+func (f *Filter) Mask(text string) string {
+	var b strings.Builder
+	last := 0
+	for _, t := range tokens(text) {
+		if !f.words[t.word] {
+			continue
+		}
+		b.WriteString(text[last:t.start])
+		b.WriteString(strings.Repeat("*", len([]rune(text[t.start:t.end]))))
+		last = t.end
+	}
+	b.WriteString(text[last:])
+	return b.String()
+}
+
+// Embedded returns a blocked word found anywhere in name, ignoring spaces
+// and punctuation between its letters, or "" if there is none.
+func (f *Filter) Embedded(name string) string {
+	var joined strings.Builder
+	for _, t := range tokens(name) {
+		joined.WriteString(t.word)
+	}
+	squashed := joined.String()
+	for w := range f.words {
+		if strings.Contains(squashed, w) {
+			return w
+		}
+	}
+	return ""
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"unicode/utf8"
+
+	"code-mafia-backend/config"
+	"code-mafia-backend/wordfilter"
+)
+
+// blockedWords is what CHAT_FILTER_ACTION and USERNAME_FILTER_ACTION act
+// on: WORD_FILTER_FILE, or the built-in list.
+var blockedWords = wordfilter.New(wordfilter.Default)
+
+const maxUsernameRunes = 24
+
+func initWordFilter() {
+	path := config.AppConfig.WordFilterFile
+	if path == "" {
+		log.Printf("🧼 Word filter: %d built-in words", blockedWords.Len())
+		return
+	}
+
+	words, err := wordfilter.Load(path)
+	if err != nil {
+		log.Printf("⚠️ Word filter keeps the built-in list: %v", err)
+		return
+	}
+	blockedWords = wordfilter.New(words)
+	log.Printf("🧼 Word filter: %d words from %s", blockedWords.Len(), path)
+}
+
+// filterChat applies CHAT_FILTER_ACTION to a line c sent, returning what
+// to send in its place, or false with c told why when it is turned away.
+func (c *Client) filterChat(text string) (string, bool) {
+	action := config.Current().ChatFilterAction
+	if action == "off" {
+		return text, true
+	}
+	words := blockedWords.Words(text)
+	if len(words) == 0 {
+		return text, true
+	}
+
+	incMetric("chat_filtered_total")
+	switch action {
+	case "reject":
+		c.sendError("That message contains words that aren't allowed here")
+		return "", false
+	case "mask":
+		return blockedWords.Mask(text), true
+	}
+	c.flagWords("CHAT_FLAGGED", words)
+	return text, true
+}
+
+// checkUsername cleans a JOIN's username and applies
+// USERNAME_FILTER_ACTION, returning the name to use or why it can't be.
+func (c *Client) checkUsername(username string) (string, string) {
+	username = strings.ReplaceAll(cleanChatText(username), "\n", " ")
+	switch {
+	case username == "":
+		return "", "Pick a username"
+	case utf8.RuneCountInString(username) > maxUsernameRunes:
+		return "", fmt.Sprintf("Usernames can be at most %d characters", maxUsernameRunes)
+	}
+
+	action := config.Current().UsernameFilterAction
+	if action == "off" {
+		return username, ""
+	}
+	word := blockedWords.Embedded(username)
+	if word == "" {
+		return username, ""
+	}
+
+	incMetric("usernames_filtered_total")
+	if action == "reject" {
+		return "", "That username isn't allowed here - pick another"
+	}
+	c.flagWords("USERNAME_FLAGGED", []string{word})
+	return username, ""
+}
+
+// flagWords notes a filter match in the room's audit log for moderators.
+func (c *Client) flagWords(event string, words []string) {
+	log.Printf("🧼 %s from %s in room %s: %v", event, c.PlayerID, c.RoomID, words)
+	if room := c.hub.getRoom(c.RoomID); room != nil {
+		room.audit(event, c.PlayerID, c.Username, map[string]interface{}{"words": words})
+	}
+}
+
+// uniqueUsername returns username, or if someone else in the room already
+// goes by it, the same with the first free number after it. Runs on the
+// room goroutine.
+func (r *Room) uniqueUsername(playerID, username string) string {
+	taken := make(map[string]bool, len(r.players))
+	for id, p := range r.players {
+		if id != playerID {
+			taken[strings.ToLower(p.Username)] = true
+		}
+	}
+
+	name := username
+	for n := 2; taken[strings.ToLower(name)]; n++ {
+		name = fmt.Sprintf("%s %d", username, n)
+	}
+	return name
+}
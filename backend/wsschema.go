@@ -0,0 +1,171 @@
+package main
+
+//go:generate go run . wsschema
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+
+	"code-mafia-backend/wsschema"
+)
+
+// runSchemaCommand implements `main wsschema [flags]`. It writes the
+// AsyncAPI document and TypeScript types from package wsschema, or with
+// -check verifies that they are current and that every message type this
+// package sends or handles is in the schema, for CI.
+func runSchemaCommand(args []string) {
+	fs := flag.NewFlagSet("wsschema", flag.ExitOnError)
+	asyncPath := fs.String("asyncapi", "wsschema/asyncapi.json", "where to write the AsyncAPI document")
+	tsPath := fs.String("ts", "../frontend/src/types/messages.d.ts", "where to write the TypeScript types")
+	src := fs.String("src", ".", "directory of the server sources to check against the schema")
+	check := fs.Bool("check", false, "fail instead of writing when anything is out of date")
+	fs.Parse(args)
+
+	if err := wsschema.Validate(); err != nil {
+		fmt.Fprintln(os.Stderr, "wsschema:", err)
+		os.Exit(1)
+	}
+
+	asyncDoc, err := wsschema.AsyncAPI()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "wsschema:", err)
+		os.Exit(1)
+	}
+	outputs := []struct {
+		path string
+		data []byte
+	}{
+		{*asyncPath, asyncDoc},
+		{*tsPath, wsschema.TypeScript()},
+	}
+
+	if !*check {
+		for _, out := range outputs {
+			if err := os.MkdirAll(filepath.Dir(out.path), 0755); err != nil {
+				fmt.Fprintln(os.Stderr, "wsschema:", err)
+				os.Exit(1)
+			}
+			if err := os.WriteFile(out.path, out.data, 0644); err != nil {
+				fmt.Fprintln(os.Stderr, "wsschema:", err)
+				os.Exit(1)
+			}
+			fmt.Println("wrote", out.path)
+		}
+		return
+	}
+
+	var problems []string
+	for _, out := range outputs {
+		current, err := os.ReadFile(out.path)
+		if err != nil || !bytes.Equal(current, out.data) {
+			problems = append(problems, out.path+" is out of date; run go generate")
+		}
+	}
+
+	sent, handled, err := scanMessageTypes(*src)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "wsschema:", err)
+		os.Exit(1)
+	}
+	problems = append(problems, compareNames("sent", sent, wsschema.Names(wsschema.ServerToClient))...)
+	problems = append(problems, compareNames("handled", handled, wsschema.Names(wsschema.ClientToServer))...)
+
+	if len(problems) > 0 {
+		for _, p := range problems {
+			fmt.Fprintln(os.Stderr, "wsschema:", p)
+		}
+		os.Exit(1)
+	}
+	fmt.Println("wsschema: schema matches the server")
+}
+
+// scanMessageTypes finds the literal types in Message{Type: "..."}
+// composites, which is everything the server sends, and the cases of
+// Client.handleMessage, which is everything it accepts.
+func scanMessageTypes(dir string) (sent, handled map[string]string, err error) {
+	fset := token.NewFileSet()
+	pkgs, err := parser.ParseDir(fset, dir, nil, 0)
+	if err != nil {
+		return nil, nil, err
+	}
+	pkg, ok := pkgs["main"]
+	if !ok {
+		return nil, nil, fmt.Errorf("no package main in %s", dir)
+	}
+
+	sent = make(map[string]string)
+	handled = make(map[string]string)
+	for _, file := range pkg.Files {
+		ast.Inspect(file, func(n ast.Node) bool {
+			switch node := n.(type) {
+			case *ast.CompositeLit:
+				if ident, ok := node.Type.(*ast.Ident); !ok || ident.Name != "Message" {
+					return true
+				}
+				for _, elt := range node.Elts {
+					kv, ok := elt.(*ast.KeyValueExpr)
+					if !ok {
+						continue
+					}
+					if key, ok := kv.Key.(*ast.Ident); ok && key.Name == "Type" {
+						if name, ok := stringLit(kv.Value); ok {
+							sent[name] = fset.Position(kv.Pos()).String()
+						}
+					}
+				}
+
+			case *ast.FuncDecl:
+				if node.Name.Name != "handleMessage" || node.Recv == nil {
+					return true
+				}
+				ast.Inspect(node.Body, func(n ast.Node) bool {
+					clause, ok := n.(*ast.CaseClause)
+					if !ok {
+						return true
+					}
+					for _, expr := range clause.List {
+						if name, ok := stringLit(expr); ok {
+							handled[name] = fset.Position(expr.Pos()).String()
+						}
+					}
+					return true
+				})
+			}
+			return true
+		})
+	}
+	return sent, handled, nil
+}
+
+func stringLit(expr ast.Expr) (string, bool) {
+	lit, ok := expr.(*ast.BasicLit)
+	if !ok || lit.Kind != token.STRING {
+		return "", false
+	}
+	s, err := strconv.Unquote(lit.Value)
+	return s, err == nil
+}
+
+func compareNames(verb string, found map[string]string, schema map[string]bool) []string {
+	var problems []string
+	for name, pos := range found {
+		if !schema[name] {
+			problems = append(problems, fmt.Sprintf("%s: %s is %s but not in the schema", pos, name, verb))
+		}
+	}
+	for name := range schema {
+		if _, ok := found[name]; !ok {
+			problems = append(problems, fmt.Sprintf("%s is in the schema but never %s", name, verb))
+		}
+	}
+	sort.Strings(problems)
+	return problems
+}
@@ -0,0 +1,176 @@
+package wsschema
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// typeExpr is a parsed Field.Type.
+type typeExpr struct {
+	name     string
+	list     bool
+	dict     bool
+	nullable bool
+}
+
+func parseType(t string) typeExpr {
+	var e typeExpr
+	if strings.HasSuffix(t, "?") {
+		e.nullable = true
+		t = strings.TrimSuffix(t, "?")
+	}
+	if strings.HasPrefix(t, "[]") {
+		e.list = true
+		t = strings.TrimPrefix(t, "[]")
+	} else if strings.HasPrefix(t, "map:") {
+		e.dict = true
+		t = strings.TrimPrefix(t, "map:")
+	}
+	e.name = t
+	return e
+}
+
+func isScalar(name string) bool {
+	switch name {
+	case "string", "integer", "number", "boolean", "timestamp":
+		return true
+	}
+	return false
+}
+
+// AsyncAPI renders the protocol as an AsyncAPI 2.6 document. In AsyncAPI
+// 2.x terms the application is the server, so "publish" lists what
+// clients send it and "subscribe" what they receive.
+func AsyncAPI() ([]byte, error) {
+	messages := make(map[string]interface{})
+	var fromClient, fromServer []interface{}
+
+	for _, m := range Messages {
+		key := string(m.Direction) + "." + m.Name
+		messages[key] = asyncMessage(m)
+		ref := map[string]interface{}{"$ref": "#/components/messages/" + key}
+		if m.Direction == ClientToServer {
+			fromClient = append(fromClient, ref)
+		} else {
+			fromServer = append(fromServer, ref)
+		}
+	}
+
+	schemas := make(map[string]interface{})
+	for _, t := range Types {
+		schemas[t.Name] = objectSchema(t.Fields, t.Doc)
+	}
+
+	doc := map[string]interface{}{
+		"asyncapi": "2.6.0",
+		"info": map[string]interface{}{
+			"title":       "Code Mafia game socket",
+			"version":     "1.0.0",
+			"description": "Messages are JSON text frames of the form {\"type\": ..., \"data\": ...}. Generated from backend/wsschema; do not edit.",
+		},
+		"defaultContentType": "application/json",
+		"channels": map[string]interface{}{
+			"/ws": map[string]interface{}{
+				"description": "A player's connection to a room. Query parameters: room, playerID, and token when signed in.",
+				"publish":     map[string]interface{}{"operationId": "sendToRoom", "message": map[string]interface{}{"oneOf": fromClient}},
+				"subscribe":   map[string]interface{}{"operationId": "receiveFromRoom", "message": map[string]interface{}{"oneOf": fromServer}},
+			},
+			"/ws/spectate": map[string]interface{}{
+				"description": "A read-only feed of the room's broadcasts, delayed and with roles blanked until GAME_ENDED. Query parameter: room.",
+				"subscribe": map[string]interface{}{
+					"operationId": "spectateRoom",
+					"message":     map[string]interface{}{"oneOf": fromServer},
+				},
+			},
+		},
+		"components": map[string]interface{}{
+			"messages": messages,
+			"schemas":  schemas,
+		},
+	}
+
+	out, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(out, '\n'), nil
+}
+
+func asyncMessage(m MessageType) map[string]interface{} {
+	var data map[string]interface{}
+	switch {
+	case m.Data != "":
+		data = schemaFor(parseType(m.Data))
+	case len(m.Fields) > 0:
+		data = objectSchema(m.Fields, "")
+	default:
+		data = map[string]interface{}{"type": "object"}
+	}
+
+	msg := map[string]interface{}{
+		"name": m.Name,
+		"payload": map[string]interface{}{
+			"type":     "object",
+			"required": []string{"type"},
+			"properties": map[string]interface{}{
+				"type": map[string]interface{}{"const": m.Name},
+				"data": data,
+			},
+		},
+	}
+	if m.Doc != "" {
+		msg["summary"] = m.Doc
+	}
+	return msg
+}
+
+func objectSchema(fields []Field, doc string) map[string]interface{} {
+	props := make(map[string]interface{}, len(fields))
+	required := []string{}
+	for _, f := range fields {
+		s := schemaFor(parseType(f.Type))
+		if len(f.Enum) > 0 {
+			s["enum"] = f.Enum
+		}
+		if f.Doc != "" {
+			s["description"] = f.Doc
+		}
+		props[f.Name] = s
+		if !f.Optional {
+			required = append(required, f.Name)
+		}
+	}
+
+	s := map[string]interface{}{
+		"type":       "object",
+		"properties": props,
+		"required":   required,
+	}
+	if doc != "" {
+		s["description"] = doc
+	}
+	return s
+}
+
+func schemaFor(e typeExpr) map[string]interface{} {
+	var s map[string]interface{}
+	switch e.name {
+	case "timestamp":
+		s = map[string]interface{}{"type": "string", "format": "date-time"}
+	case "string", "integer", "number", "boolean":
+		s = map[string]interface{}{"type": e.name}
+	default:
+		s = map[string]interface{}{"$ref": "#/components/schemas/" + e.name}
+	}
+
+	if e.list {
+		s = map[string]interface{}{"type": "array", "items": s}
+	} else if e.dict {
+		s = map[string]interface{}{"type": "object", "additionalProperties": s}
+	}
+
+	if e.nullable {
+		s = map[string]interface{}{"oneOf": []interface{}{s, map[string]interface{}{"type": "null"}}}
+	}
+	return s
+}
@@ -0,0 +1,767 @@
+// Package wsschema is the single description of the game WebSocket
+// protocol. The AsyncAPI document and the frontend's TypeScript types are
+// generated from it by `main wsschema` (go generate), and
+// `main wsschema -check` fails when the server emits or handles a message
+// type that isn't listed here. The server also checks what clients send
+// against it; see CheckPayload.
+package wsschema
+
+import "fmt"
+
+// Direction says who sends a message.
+type Direction string
+
+const (
+	ServerToClient Direction = "server"
+	ClientToServer Direction = "client"
+)
+
+// Field is one property of a payload. Type is "string", "integer",
+// "number", "boolean", "timestamp" or the name of a shared Type, optionally
+// prefixed with "[]" for a list or "map:" for an object keyed by string,
+// and suffixed with "?" when the value may be null.
+type Field struct {
+	Name     string
+	Type     string
+	Optional bool
+	Enum     []string
+	Doc      string
+}
+
+// Type is a named object shared by several messages.
+type Type struct {
+	Name   string
+	Doc    string
+	Fields []Field
+}
+
+// MessageType is one value of the envelope's "type". The payload in "data"
+// is either Fields or, when the server sends a shared value as is, Data.
+type MessageType struct {
+	Name      string
+	Direction Direction
+	Doc       string
+	Fields    []Field
+	Data      string
+}
+
+var phases = []string{"LOBBY", "ROLE_REVEAL", "TASK_1", "TASK_2", "TASK_3", "DISCUSSION", "GAME_OVER"}
+
+var endReasons = []string{
+	"CIVILIAN_WIN_TASKS", "CIVILIAN_WIN_VOTE", "CIVILIAN_WIN_DISCONNECT",
+	"IMPOSTER_WIN", "IMPOSTER_WIN_TIMEOUT", "IMPOSTER_WIN_SABOTAGE", "JESTER_WIN_VOTED_OUT", "ADMIN_ENDED",
+}
+
+var Types = []Type{
+	{
+		Name: "Player",
+		Fields: []Field{
+			{Name: "id", Type: "string"},
+			{Name: "username", Type: "string"},
+			{Name: "role", Type: "string", Enum: []string{"", "CIVILIAN", "IMPOSTER", "DETECTIVE", "JESTER", "UNKNOWN"}, Doc: "Blank until roles are dealt, and for spectators. UNKNOWN to non-impostors for everyone but themselves until GAME_ENDED; impostors see a DETECTIVE or JESTER as CIVILIAN."},
+			{Name: "isHost", Type: "boolean"},
+			{Name: "isEliminated", Type: "boolean"},
+			{Name: "isAlive", Type: "boolean"},
+			{Name: "disconnected", Type: "boolean", Optional: true, Doc: "Set while a player who dropped mid-game has their seat held."},
+			{Name: "muted", Type: "boolean", Optional: true, Doc: "Set while the host has the player muted."},
+			{Name: "language", Type: "string", Optional: true, Doc: "The locale the player reads chat in, from JOIN."},
+			{Name: "cosmetics", Type: "Cosmetics", Optional: true, Doc: "Equipped cosmetics of signed-in players."},
+		},
+	},
+	{
+		Name: "Cosmetics",
+		Doc:  "Render-ready values; a missing slot means the default look.",
+		Fields: []Field{
+			{Name: "nameColor", Type: "string", Optional: true, Doc: "A CSS color."},
+			{Name: "avatar", Type: "string", Optional: true},
+			{Name: "editorTheme", Type: "string", Optional: true, Doc: "A Monaco theme name."},
+		},
+	},
+	{
+		Name: "Cosmetic",
+		Fields: []Field{
+			{Name: "id", Type: "string"},
+			{Name: "slot", Type: "string", Enum: []string{"name_color", "avatar", "editor_theme"}},
+			{Name: "name", Type: "string"},
+			{Name: "value", Type: "string"},
+			{Name: "requirement", Type: "string", Optional: true},
+			{Name: "unlock", Type: "Unlock"},
+		},
+	},
+	{
+		Name: "Unlock",
+		Doc:  "Thresholds a player must reach; absent fields aren't required.",
+		Fields: []Field{
+			{Name: "xp", Type: "integer", Optional: true},
+			{Name: "gamesPlayed", Type: "integer", Optional: true},
+			{Name: "gamesWon", Type: "integer", Optional: true},
+			{Name: "impostorWins", Type: "integer", Optional: true},
+			{Name: "correctVotes", Type: "integer", Optional: true},
+			{Name: "rankedGames", Type: "integer", Optional: true},
+		},
+	},
+	{
+		Name: "Task",
+		Fields: []Field{
+			{Name: "id", Type: "string"},
+			{Name: "stage", Type: "integer"},
+			{Name: "title", Type: "string"},
+			{Name: "description", Type: "string"},
+			{Name: "template", Type: "string"},
+			{Name: "language", Type: "string", Optional: true, Enum: []string{"java", "python", "javascript", "go"}, Doc: "What the template is written in. Absent means Java."},
+			{Name: "category", Type: "string", Optional: true, Enum: []string{"DSA", "OOPS", "DEBUGGING"}},
+			{Name: "difficulty", Type: "string", Optional: true, Enum: []string{"EASY", "MEDIUM", "HARD"}},
+			{Name: "titleTranslations", Type: "map:string", Optional: true},
+			{Name: "descriptionTranslations", Type: "map:string", Optional: true},
+		},
+	},
+	{
+		Name: "Timer",
+		Doc:  "The game clock. Clients count down from it; the server sends it when the clock starts, pauses or resumes, and every 5 seconds while it runs. Times are Unix milliseconds.",
+		Fields: []Field{
+			{Name: "deadline", Type: "integer", Optional: true, Doc: "When the clock runs out if it keeps running. Absent before the game clock starts."},
+			{Name: "paused", Type: "boolean"},
+			{Name: "pausedAt", Type: "integer", Optional: true, Doc: "When the clock was stopped, while paused."},
+			{Name: "remainingMs", Type: "integer", Doc: "Time left as of serverTime."},
+			{Name: "serverTime", Type: "integer", Doc: "The server's clock when this was sent, for correcting skew."},
+			{Name: "drainedSeconds", Type: "integer", Optional: true, Doc: "Set in the SYNC_TIMER sent when a TIMER_DRAIN sabotage took this much off the clock."},
+			{Name: "hintPenaltySeconds", Type: "integer", Optional: true, Doc: "Set in the SYNC_TIMER sent when a HINT took this much off the clock."},
+			{Name: "timerSeconds", Type: "integer", Doc: "Whole seconds left as of serverTime."},
+		},
+	},
+	{
+		Name: "GameState",
+		Doc:  "Full room state, sent on every phase change.",
+		Fields: []Field{
+			{Name: "phase", Type: "string", Enum: phases},
+			{Name: "currentStage", Type: "integer"},
+			{Name: "timerSeconds", Type: "integer", Doc: "Snapshot of timer.timerSeconds."},
+			{Name: "timer", Type: "Timer"},
+			{Name: "tasksComplete", Type: "map:boolean", Doc: "Keyed by stage number."},
+			{Name: "players", Type: "map:Player", Doc: "Keyed by player ID."},
+			{Name: "testRunning", Type: "boolean"},
+			{Name: "testRunner", Type: "string"},
+			{Name: "task", Type: "Task?", Optional: true, Doc: "The current stage's task, null outside task phases. Absent in a personal-tasks game, where each player gets theirs in ASSIGNED_TASK."},
+			{Name: "taskProgress", Type: "TaskProgress", Optional: true, Doc: "Only in a personal-tasks game."},
+			{Name: "ranked", Type: "boolean"},
+			{Name: "spectatorDelaySeconds", Type: "integer"},
+		},
+	},
+	{
+		Name: "TaskProgress",
+		Doc:  "The task bar of a personal-tasks game. The crew wins when complete reaches total.",
+		Fields: []Field{
+			{Name: "complete", Type: "integer", Doc: "Crewmates who have passed their task."},
+			{Name: "total", Type: "integer", Doc: "Crewmates whose task counts: those who passed it and those still in the game."},
+		},
+	},
+	{
+		Name: "Settings",
+		Doc:  "Lobby rules. In SETTINGS_UPDATED every field is filled in with the value the next game will use.",
+		Fields: []Field{
+			{Name: "timerSeconds", Type: "integer", Optional: true},
+			{Name: "votingSeconds", Type: "integer", Optional: true},
+			{Name: "sabotageCooldownSec", Type: "integer", Optional: true},
+			{Name: "impostorCount", Type: "integer", Optional: true, Doc: "0 or absent picks one impostor per five players."},
+			{Name: "minPlayers", Type: "integer", Optional: true},
+			{Name: "taskCategory", Type: "string", Optional: true, Enum: []string{"", "DSA", "OOPS", "DEBUGGING"}, Doc: "Blank or absent draws from every category. Stages with no matching task draw from all of them."},
+			{Name: "taskDifficulty", Type: "string", Optional: true, Enum: []string{"", "EASY", "MEDIUM", "HARD"}, Doc: "Blank or absent draws from every difficulty."},
+			{Name: "taskLanguage", Type: "string", Optional: true, Enum: []string{"", "java", "python", "javascript", "go"}, Doc: "The language tasks are written in; blank or absent means java. It counts for more than the category and difficulty, and stages with no task in it fall back to another language."},
+			{Name: "lockVotes", Type: "boolean", Optional: true, Doc: "When true a player's first vote in a meeting can't be changed."},
+			{Name: "tieRevote", Type: "boolean", Optional: true, Doc: "When true a tied meeting votes again between the tied players; a second tie ejects no one."},
+			{Name: "personalTasks", Type: "boolean", Optional: true, Doc: "When true each player gets a task of their own, edited in the Yjs document <room>-task-<playerID>, and the crew wins by filling the task bar instead of finishing three stages."},
+			{Name: "detective", Type: "boolean", Optional: true, Doc: "Deal one crewmate the DETECTIVE role, who can send one DETECTIVE_QUERY per game."},
+			{Name: "jester", Type: "boolean", Optional: true, Doc: "Deal the JESTER role, who wins alone by being voted out, when there are at least three non-impostors."},
+			{Name: "ghostsHearImpostors", Type: "boolean", Optional: true, Doc: "Eliminated players also receive IMPOSTOR_CHAT."},
+			{Name: "public", Type: "boolean", Optional: true, Doc: "When true the lobby is listed by GET /api/lobbies until its game starts."},
+			{Name: "hints", Type: "boolean", Optional: true, Doc: "When true players can send HINT during tasks. Needs the server's hint service."},
+			{Name: "hintsPerStage", Type: "integer", Optional: true, Doc: "Hints per stage, or per player's task in a personal-tasks game; 1 to 5, absent means 2."},
+			{Name: "hintPenaltySec", Type: "integer", Optional: true, Doc: "Seconds each hint takes off the clock; 5 to 120, absent means 20."},
+			{Name: "slowModeSec", Type: "integer", Optional: true, Doc: "Seconds each player must wait between chat lines in a meeting; absent or 0 is off. Set with SLOW_MODE, not ROOM_SETTINGS."},
+		},
+	},
+	{
+		Name: "ChatLine",
+		Doc:  "A CHAT as broadcast.",
+		Fields: []Field{
+			{Name: "username", Type: "string"},
+			{Name: "text", Type: "string"},
+			{Name: "system", Type: "boolean"},
+			{Name: "messageId", Type: "string", Optional: true},
+			{Name: "playerId", Type: "string", Optional: true},
+			{Name: "translations", Type: "map:string", Optional: true, Doc: "The translation into the reader's JOIN language, keyed by that locale code; empty when there is none."},
+			{Name: "timestamp", Type: "integer", Optional: true, Doc: "Unix milliseconds."},
+			{Name: "key", Type: "string", Optional: true, Doc: "Set on QUICK_CHAT lines and system notices: an i18n key to show in the reader's language in place of text, which is the English."},
+			{Name: "params", Type: "map:string", Optional: true, Doc: "Values for the key's {placeholders}, on system notices that have any."},
+			{Name: "presetId", Type: "string", Optional: true, Doc: "Set on QUICK_CHAT lines."},
+		},
+	},
+	{
+		Name: "TestResult",
+		Doc:  "One unit test of a RUN_TESTS run.",
+		Fields: []Field{
+			{Name: "name", Type: "string"},
+			{Name: "passed", Type: "boolean"},
+			{Name: "message", Type: "string", Optional: true, Doc: "Why it failed."},
+		},
+	},
+	{
+		Name: "PlayerScore",
+		Doc:  "What one player did for the game, in GAME_ENDED. score is 1 per test run, 10 per stage (or personal task) their run passed, 5 per vote for an impostor and 2 per sabotage they were still aboard at the end of.",
+		Fields: []Field{
+			{Name: "playerID", Type: "string"},
+			{Name: "username", Type: "string"},
+			{Name: "testsRun", Type: "integer"},
+			{Name: "stagesPassed", Type: "integer"},
+			{Name: "correctVotes", Type: "integer"},
+			{Name: "sabotagesSurvived", Type: "integer"},
+			{Name: "score", Type: "integer"},
+		},
+	},
+	{
+		Name: "EditShare",
+		Doc:  "How much of the game's editing one player did, in GAME_ENDED, measured by the size of their Yjs updates.",
+		Fields: []Field{
+			{Name: "playerID", Type: "string"},
+			{Name: "username", Type: "string"},
+			{Name: "bytes", Type: "integer"},
+			{Name: "percent", Type: "integer", Doc: "Rounded, so the shares may not add up to exactly 100."},
+		},
+	},
+	{
+		Name: "Teammate",
+		Fields: []Field{
+			{Name: "id", Type: "string"},
+			{Name: "username", Type: "string"},
+		},
+	},
+	{
+		Name: "Candidate",
+		Doc:  "A player on the ballot of a revote.",
+		Fields: []Field{
+			{Name: "id", Type: "string"},
+			{Name: "username", Type: "string"},
+		},
+	},
+	{
+		Name: "Party",
+		Fields: []Field{
+			{Name: "id", Type: "string"},
+			{Name: "leaderId", Type: "string"},
+			{Name: "members", Type: "[]string"},
+			{Name: "createdAt", Type: "timestamp"},
+		},
+	},
+	{
+		Name: "WebhookEndpoint",
+		Fields: []Field{
+			{Name: "id", Type: "string"},
+			{Name: "url", Type: "string"},
+			{Name: "secret", Type: "string", Doc: "Signs deliveries; shown only to the host who added it."},
+			{Name: "events", Type: "[]string", Optional: true},
+			{Name: "createdAt", Type: "timestamp"},
+			{Name: "untrusted", Type: "boolean", Optional: true},
+		},
+	},
+}
+
+var Messages = []MessageType{
+	// Server to client.
+	{
+		Name: "INIT", Direction: ServerToClient,
+		Doc: "First message on a new connection.",
+		Fields: []Field{
+			{Name: "playerID", Type: "string"},
+			{Name: "roomID", Type: "string"},
+			{Name: "isReconnect", Type: "boolean"},
+			{Name: "authenticated", Type: "boolean"},
+			{Name: "observer", Type: "boolean", Doc: "The connection was made with ?spectate=1 and only watches, even mid-game: everything it gets has roles stripped until GAME_ENDED, its JOIN only sets the name it chats under, and its CHAT goes to the other observers as OBSERVER_CHAT. Anything else it sends gets an ERROR. Open the editor with ?spectate=1 too for a read-only view."},
+			{Name: "guestToken", Type: "string", Optional: true, Doc: "Lets a guest claim their matches after signing up. Guests reconnecting with ?userId= send it back as ?guestToken= to prove the ID is theirs."},
+			{Name: "protocolVersion", Type: "integer", Doc: "The protocol version the server speaks. Clients say which they speak with ?protocol= on connect; those that don't are taken to speak 1."},
+			{Name: "outdated", Type: "boolean", Doc: "The client speaks an older protocol than the server. It can still play, but should ask the player to refresh."},
+		},
+	},
+	{
+		Name: "SESSION", Direction: ServerToClient,
+		Doc: "Sent after JOIN to a connection that has shown the player ID is its own: signed in, given a new guest ID, or reconnecting with ?guestToken=. A player already seated with a session keeps it. Reconnect with ?userId=<playerID>&session=<token> to resume a game in progress.",
+		Fields: []Field{
+			{Name: "token", Type: "string"},
+			{Name: "playerID", Type: "string"},
+			{Name: "roomID", Type: "string"},
+		},
+	},
+	{Name: "SELF", Direction: ServerToClient, Doc: "The joining player's own record, including their role.", Data: "Player"},
+	{Name: "PLAYER_LIST", Direction: ServerToClient, Doc: "Players keyed by ID.", Data: "map:Player"},
+	{Name: "GAME_STATE", Direction: ServerToClient, Data: "GameState"},
+	{Name: "CHAT", Direction: ServerToClient, Doc: "A chat line, or a system notice when system is true. System notices carry a key (system.voted_out, system.freeze, ...) and params.", Data: "ChatLine"},
+	{
+		Name: "CHAT_HISTORY", Direction: ServerToClient,
+		Doc:    "Sent after SETTINGS_UPDATED on every JOIN, including reconnections: the room's latest CHATs, up to the server's CHAT_HISTORY_SIZE (50 by default). Ghost chat isn't kept.",
+		Fields: []Field{{Name: "messages", Type: "[]ChatLine", Doc: "Oldest first."}},
+	},
+	{
+		Name: "GHOST_CHAT", Direction: ServerToClient,
+		Doc: "A ghost's chat line. Only eliminated players receive these.",
+		Fields: []Field{
+			{Name: "messageId", Type: "string"},
+			{Name: "playerId", Type: "string"},
+			{Name: "username", Type: "string"},
+			{Name: "text", Type: "string"},
+			{Name: "timestamp", Type: "integer", Doc: "Unix milliseconds."},
+		},
+	},
+	{
+		Name: "OBSERVER_CHAT", Direction: ServerToClient,
+		Doc: "An observer's chat line. Only observers receive these; the players never see them.",
+		Fields: []Field{
+			{Name: "messageId", Type: "string"},
+			{Name: "username", Type: "string", Doc: "Spectator for an observer who hasn't sent JOIN."},
+			{Name: "text", Type: "string"},
+			{Name: "timestamp", Type: "integer", Doc: "Unix milliseconds."},
+		},
+	},
+	{
+		Name: "IMPOSTOR_CHAT", Direction: ServerToClient,
+		Doc: "An impostor's line to their team. Only living impostors receive these, and ghosts when the room's ghostsHearImpostors is set; they aren't part of CHAT_HISTORY.",
+		Fields: []Field{
+			{Name: "messageId", Type: "string"},
+			{Name: "playerId", Type: "string"},
+			{Name: "username", Type: "string"},
+			{Name: "text", Type: "string"},
+			{Name: "timestamp", Type: "integer", Doc: "Unix milliseconds."},
+		},
+	},
+	{
+		Name: "TYPING_START", Direction: ServerToClient,
+		Doc:    "During a meeting, another living player started typing. Only living players receive these.",
+		Fields: []Field{{Name: "playerId", Type: "string"}},
+	},
+	{
+		Name: "TYPING_STOP", Direction: ServerToClient,
+		Doc:    "A player shown as typing stopped: they sent their line, stopped, left, were muted, or weren't heard from for a few seconds. Forget everyone's typing when the phase changes; no TYPING_STOP comes then.",
+		Fields: []Field{{Name: "playerId", Type: "string"}},
+	},
+	{Name: "SYNC_TIMER", Direction: ServerToClient, Doc: "The game clock started, paused, resumed or was drained by a sabotage, or a periodic resync while it runs.", Data: "Timer"},
+	{
+		Name: "CHANGE_SCENE", Direction: ServerToClient,
+		Doc: "A stage was completed; the next one starts after delay.",
+		Fields: []Field{
+			{Name: "fromStage", Type: "integer"},
+			{Name: "toStage", Type: "integer"},
+			{Name: "delay", Type: "integer", Doc: "Milliseconds."},
+		},
+	},
+	{
+		Name: "TEST_LOCKED", Direction: ServerToClient,
+		Doc: "Someone is running the tests; the editor is locked until TEST_COMPLETE. An impostor's own runs are decoys only they are told about.",
+		Fields: []Field{
+			{Name: "runner", Type: "string"},
+			{Name: "runnerID", Type: "string"},
+			{Name: "stage", Type: "integer"},
+		},
+	},
+	{
+		Name: "ASSIGNED_TASK", Direction: ServerToClient,
+		Doc: "In a personal-tasks game, the player's own task. Sent after every GAME_STATE and on rejoining.",
+		Fields: []Field{
+			{Name: "task", Type: "Task"},
+			{Name: "done", Type: "boolean", Doc: "Whether the player has passed it."},
+		},
+	},
+	{Name: "TASK_PROGRESS", Direction: ServerToClient, Doc: "The task bar moved.", Data: "TaskProgress"},
+	{
+		Name: "TEST_COMPLETE", Direction: ServerToClient,
+		Doc: "A run finished. An impostor's decoy run always passes and changes nothing.",
+		Fields: []Field{
+			{Name: "passed", Type: "boolean"},
+			{Name: "stage", Type: "integer"},
+			{Name: "runner", Type: "string"},
+			{Name: "compiled", Type: "boolean", Doc: "False when the code didn't compile; compilerOutput says why."},
+			{Name: "compilerOutput", Type: "string", Doc: "javac's errors, blank when it compiled."},
+			{Name: "tests", Type: "[]TestResult"},
+			{Name: "timedOut", Type: "boolean", Doc: "The run hit the sandbox's time limit."},
+		},
+	},
+	{
+		Name: "TEST_STATUS", Direction: ServerToClient,
+		Doc: "Progress of the running tests, between TEST_LOCKED and TEST_COMPLETE.",
+		Fields: []Field{
+			{Name: "stage", Type: "integer"},
+			{Name: "status", Type: "string", Enum: []string{"QUEUED", "RUNNING"}},
+		},
+	},
+	{Name: "TEST_CANCELLED", Direction: ServerToClient, Fields: []Field{{Name: "reason", Type: "string"}}},
+	{
+		Name: "ERROR_BUSY", Direction: ServerToClient,
+		Doc: "Tests are already running.",
+		Fields: []Field{
+			{Name: "message", Type: "string"},
+			{Name: "runner", Type: "string"},
+		},
+	},
+	{Name: "ERROR", Direction: ServerToClient, Doc: "Something the client asked for was refused, or a message it sent didn't match this schema or wasn't one the server knows.", Fields: []Field{{Name: "message", Type: "string"}}},
+	{
+		Name: "SHUTDOWN_NOTICE", Direction: ServerToClient,
+		Doc: "The server is going down; the connection closes after drainSeconds. The room is saved, so reconnecting with the session token carries on.",
+		Fields: []Field{
+			{Name: "message", Type: "string"},
+			{Name: "drainSeconds", Type: "integer"},
+		},
+	},
+	{
+		Name: "SLOW_CONNECTION", Direction: ServerToClient,
+		Doc: "The connection is falling behind on messages and SLOW_CLIENT_POLICY is dropping or holding some. Sent at most every few seconds. Under the disconnect policy the connection is instead closed with code 1013 and reason SLOW_CONNECTION.",
+		Fields: []Field{
+			{Name: "policy", Type: "string", Enum: []string{"drop-oldest", "grow"}},
+			{Name: "dropped", Type: "integer", Doc: "Messages dropped on this connection so far."},
+			{Name: "message", Type: "string"},
+		},
+	},
+	{
+		Name: "ERROR_RATE_LIMITED", Direction: ServerToClient,
+		Doc: "A message was dropped for going over the connection's budget for its type.",
+		Fields: []Field{
+			{Name: "messageType", Type: "string"},
+			{Name: "message", Type: "string"},
+			{Name: "retryAfterMs", Type: "integer", Optional: true, Doc: "Absent when waiting won't help."},
+		},
+	},
+	{
+		Name: "EMERGENCY_UNAVAILABLE", Direction: ServerToClient,
+		Doc: "An EMERGENCY that didn't start a meeting.",
+		Fields: []Field{
+			{Name: "reason", Type: "string", Enum: []string{"NOT_ALLOWED", "WRONG_PHASE", "SABOTAGE_ACTIVE", "STAGE_STARTING", "COOLDOWN", "LIMIT_REACHED"}},
+			{Name: "message", Type: "string"},
+			{Name: "callsLeft", Type: "integer", Doc: "Meetings the player may still call this game."},
+			{Name: "retryAfterMs", Type: "integer", Optional: true, Doc: "How long until a call could be allowed; absent when waiting won't help."},
+		},
+	},
+	{
+		Name: "ERROR_ACCESS_DENIED", Direction: ServerToClient,
+		Doc: "The connection was refused or ended; the server closes it after this message.",
+		Fields: []Field{
+			{Name: "reason", Type: "string", Enum: []string{"INVALID_TOKEN", "AUTH_REQUIRED", "BANNED", "GAME_IN_PROGRESS", "ROOM_NOT_FOUND", "SPECTATORS_FULL", "KICKED", "AFK", "IDLE", "ROOM_CLOSED", "OUTDATED_CLIENT", "ROOM_UNAVAILABLE", "PLAYER_ID_IN_USE"}},
+			{Name: "message", Type: "string"},
+			{Name: "banReason", Type: "string", Optional: true},
+			{Name: "expiresAt", Type: "timestamp", Optional: true, Doc: "Absent for permanent bans."},
+			{Name: "phase", Type: "string", Optional: true, Enum: phases},
+		},
+	},
+	{Name: "VOTING_TIMER", Direction: ServerToClient, Fields: []Field{{Name: "seconds", Type: "integer"}}},
+	{
+		Name: "VOTE_TIE", Direction: ServerToClient,
+		Doc: "The meeting tied and votes again, between candidates or SKIP only. Earlier ballots are cleared; VOTING_TIMER counts down from seconds.",
+		Fields: []Field{
+			{Name: "candidates", Type: "[]Candidate"},
+			{Name: "seconds", Type: "integer"},
+		},
+	},
+	{
+		Name: "VOTE_REJECTED", Direction: ServerToClient,
+		Doc: "A VOTE that wasn't counted. An earlier ballot from the same player still stands.",
+		Fields: []Field{
+			{Name: "reason", Type: "string", Enum: []string{"VOTING_CLOSED", "NOT_ALLOWED", "UNKNOWN_TARGET", "TARGET_ELIMINATED", "SELF_VOTE", "VOTE_LOCKED", "NOT_A_CANDIDATE"}},
+			{Name: "message", Type: "string"},
+			{Name: "targetID", Type: "string"},
+		},
+	},
+	{Name: "VOTE_UPDATE", Direction: ServerToClient, Doc: "Who has voted, never for whom.", Fields: []Field{{Name: "hasVoted", Type: "map:boolean"}}},
+	{Name: "ALL_VOTES_IN", Direction: ServerToClient, Fields: []Field{{Name: "message", Type: "string"}}},
+	{
+		Name: "PLAYER_ELIMINATED", Direction: ServerToClient,
+		Fields: []Field{
+			{Name: "playerID", Type: "string"},
+			{Name: "username", Type: "string"},
+			{Name: "reason", Type: "string", Optional: true, Enum: []string{"DISCONNECTED"}},
+		},
+	},
+	{
+		Name: "GAME_ENDED", Direction: ServerToClient,
+		Fields: []Field{
+			{Name: "reason", Type: "string", Enum: endReasons},
+			{Name: "imposterIDs", Type: "[]string"},
+			{Name: "finalState", Type: "GameState"},
+			{Name: "summaryToken", Type: "string", Doc: "Empty when the summary couldn't be saved."},
+			{Name: "scores", Type: "[]PlayerScore", Doc: "Every player, best score first."},
+			{Name: "mvp", Type: "PlayerScore", Optional: true, Doc: "The top score; left out if nobody scored."},
+			{Name: "editShare", Type: "[]EditShare", Doc: "Every player, most editing first."},
+		},
+	},
+	{
+		Name: "REMATCH_STARTED", Direction: ServerToClient,
+		Doc:    "The room is back in the lobby with the same players; a GAME_STATE follows.",
+		Fields: []Field{{Name: "hostName", Type: "string"}},
+	},
+	{
+		Name: "NEW_HOST_ASSIGNED", Direction: ServerToClient,
+		Fields: []Field{
+			{Name: "newHostID", Type: "string"},
+			{Name: "newHostName", Type: "string"},
+			{Name: "canStart", Type: "boolean"},
+		},
+	},
+	{Name: "SABOTAGE_COOLDOWN", Direction: ServerToClient, Fields: []Field{{Name: "remainingSeconds", Type: "integer"}}},
+	{
+		Name: "SABOTAGE_STARTED", Direction: ServerToClient,
+		Doc: "Until a FREEZE ends the server drops crewmates' editor updates and answers their CHAT and RUN_TESTS with an ERROR. A client resuming its seat mid-sabotage is sent one too, for what is left of it.",
+		Fields: []Field{
+			{Name: "type", Type: "string", Enum: []string{"FREEZE", "SWAP", "CRITICAL"}},
+			{Name: "duration", Type: "integer", Doc: "Milliseconds left."},
+		},
+	},
+	{
+		Name: "SABOTAGE_SWAP", Direction: ServerToClient,
+		Doc: "Sent to each of the two crewmates a SWAP sabotage picked, after SABOTAGE_STARTED. Until SABOTAGE_ENDED their editor follows the partner's cursor.",
+		Fields: []Field{
+			{Name: "partnerID", Type: "string"},
+			{Name: "partnerName", Type: "string"},
+			{Name: "duration", Type: "integer", Doc: "Milliseconds."},
+		},
+	},
+	{Name: "SABOTAGE_ENDED", Direction: ServerToClient, Doc: "For CRITICAL, sent once the crew has repaired it; an unrepaired one ends the game instead.", Fields: []Field{{Name: "type", Type: "string", Enum: []string{"FREEZE", "SWAP", "CRITICAL"}}}},
+	{
+		Name: "CRITICAL_COUNTDOWN", Direction: ServerToClient,
+		Doc: "Sent every second while a CRITICAL sabotage is on, and after each REPAIR.",
+		Fields: []Field{
+			{Name: "remainingSeconds", Type: "integer"},
+			{Name: "repairs", Type: "integer", Doc: "Different crewmates who have repaired so far."},
+			{Name: "repairsNeeded", Type: "integer"},
+		},
+	},
+	{
+		Name: "DETECTIVE_RESULT", Direction: ServerToClient,
+		Doc: "Sent to the detective only, in answer to their DETECTIVE_QUERY.",
+		Fields: []Field{
+			{Name: "targetID", Type: "string"},
+			{Name: "username", Type: "string"},
+			{Name: "suspicious", Type: "boolean", Doc: "The player has failed their test runs suspiciously often."},
+		},
+	},
+	{
+		Name: "SABOTAGE_CORRUPT", Direction: ServerToClient,
+		Fields: []Field{
+			{Name: "malware", Type: "string"},
+			{Name: "action", Type: "string", Enum: []string{"INJECT_AT_TOP"}},
+		},
+	},
+	{
+		Name: "SABOTAGE_RESOLVED", Direction: ServerToClient,
+		Doc:    "Sent when RUN_TESTS arrives with every CORRUPT marker taken out. Until then RUN_TESTS with a marker still in the code gets an ERROR.",
+		Fields: []Field{{Name: "type", Type: "string", Enum: []string{"CORRUPT"}}},
+	},
+	{Name: "MODERATION_WARNING", Direction: ServerToClient, Fields: []Field{{Name: "message", Type: "string"}}},
+	{Name: "ANNOUNCEMENT", Direction: ServerToClient, Doc: "A message from the server operators, sent to every room.", Fields: []Field{{Name: "message", Type: "string"}}},
+	{
+		Name: "MODERATION_MUTED", Direction: ServerToClient,
+		Fields: []Field{
+			{Name: "message", Type: "string"},
+			{Name: "until", Type: "timestamp"},
+		},
+	},
+	{
+		Name: "HOST_MUTED", Direction: ServerToClient,
+		Doc: "The host muted or unmuted this player. While muted, their CHAT, QUICK_CHAT, GHOST_CHAT and IMPOSTOR_CHAT get an ERROR; it lasts until the host unmutes them, across reconnects and rematches.",
+		Fields: []Field{
+			{Name: "muted", Type: "boolean"},
+			{Name: "message", Type: "string"},
+		},
+	},
+	{Name: "WEBHOOK_ADDED", Direction: ServerToClient, Data: "WebhookEndpoint"},
+	{Name: "WEBHOOK_REMOVED", Direction: ServerToClient, Fields: []Field{{Name: "id", Type: "string"}}},
+	{Name: "FRIEND_ACCEPTED", Direction: ServerToClient, Fields: []Field{{Name: "userId", Type: "string"}}},
+	{
+		Name: "ROOM_INVITE", Direction: ServerToClient,
+		Fields: []Field{
+			{Name: "fromId", Type: "string"},
+			{Name: "fromName", Type: "string"},
+			{Name: "roomId", Type: "string"},
+		},
+	},
+	{
+		Name: "COSMETICS_UNLOCKED", Direction: ServerToClient,
+		Doc:    "Sent to a signed-in player after a match that earned them something.",
+		Fields: []Field{{Name: "cosmetics", Type: "[]Cosmetic"}},
+	},
+	{
+		Name: "HINT", Direction: ServerToClient,
+		Doc: "A hint for the current task, sent to the room, or in a personal-tasks game to the player who asked. A SYNC_TIMER with hintPenaltySeconds follows.",
+		Fields: []Field{
+			{Name: "stage", Type: "integer"},
+			{Name: "tier", Type: "integer", Doc: "How specific the hint is: 1 points at where the bug is, 2 says what is wrong, 3 all but gives the fix."},
+			{Name: "text", Type: "string"},
+			{Name: "requestedBy", Type: "string", Doc: "Username of the player who asked."},
+			{Name: "hintsLeft", Type: "integer", Doc: "Hints left for this task."},
+			{Name: "penaltySeconds", Type: "integer", Doc: "What the hint took off the clock."},
+		},
+	},
+	{Name: "SETTINGS_UPDATED", Direction: ServerToClient, Doc: "Sent on join and to the whole lobby whenever the rules change.", Data: "Settings"},
+	{
+		Name: "IMPOSTOR_TEAM", Direction: ServerToClient,
+		Doc:    "Sent only to impostors during ROLE_REVEAL, listing the whole impostor team including themselves.",
+		Fields: []Field{{Name: "impostors", Type: "[]Teammate"}},
+	},
+	{
+		Name: "CHEAT_WARNING", Direction: ServerToClient,
+		Doc: "Sent to the host when anti-cheat flags a player, if the cheat_warnings feature flag is on.",
+		Fields: []Field{
+			{Name: "detection", Type: "string", Enum: []string{"ROLE_LEAK", "MASS_DELETION", "LOCKSTEP_VOTING"}},
+			{Name: "playerId", Type: "string"},
+			{Name: "username", Type: "string"},
+			{Name: "message", Type: "string"},
+		},
+	},
+	{Name: "PARTY_UPDATE", Direction: ServerToClient, Data: "Party"},
+	{
+		Name: "PARTY_CHAT", Direction: ServerToClient,
+		Fields: []Field{
+			{Name: "partyId", Type: "string"},
+			{Name: "messageId", Type: "string"},
+			{Name: "playerID", Type: "string"},
+			{Name: "username", Type: "string"},
+			{Name: "text", Type: "string"},
+			{Name: "timestamp", Type: "integer", Doc: "Unix milliseconds."},
+		},
+	},
+	{Name: "PARTY_KICKED", Direction: ServerToClient, Fields: []Field{{Name: "partyId", Type: "string"}}},
+	{
+		Name: "PARTY_JOIN_ROOM", Direction: ServerToClient,
+		Doc: "The party leader moved the party; members should join roomId.",
+		Fields: []Field{
+			{Name: "partyId", Type: "string"},
+			{Name: "roomId", Type: "string"},
+		},
+	},
+	{
+		Name: "QUEUE_STATUS", Direction: ServerToClient,
+		Doc: "Answers QUEUE and LEAVE_QUEUE. A queued party's members all receive it.",
+		Fields: []Field{
+			{Name: "status", Type: "string", Enum: []string{"queued", "left"}},
+			{Name: "language", Type: "string", Optional: true, Doc: "Blank means any."},
+			{Name: "mmr", Type: "number", Optional: true},
+		},
+	},
+	{
+		Name: "MATCH_FOUND", Direction: ServerToClient,
+		Doc: "Quick match placed the player in a new room; the client should join roomId.",
+		Fields: []Field{
+			{Name: "roomId", Type: "string"},
+			{Name: "joinUrl", Type: "string"},
+			{Name: "language", Type: "string", Doc: "Blank means the default."},
+			{Name: "players", Type: "integer"},
+		},
+	},
+	{
+		Name: "SPECTATOR_INIT", Direction: ServerToClient,
+		Doc: "First message on a spectator connection; everything after it runs delaySeconds behind.",
+		Fields: []Field{
+			{Name: "roomID", Type: "string"},
+			{Name: "delaySeconds", Type: "integer"},
+		},
+	},
+
+	// Client to server.
+	{
+		Name: "JOIN", Direction: ClientToServer,
+		Doc: "The username is cleaned like CHAT and must be 1-24 characters; the server's USERNAME_FILTER_ACTION may refuse one with a blocked word in it with an ERROR. A name someone else in the room already goes by, in any case, gets a number after it.",
+		Fields: []Field{
+			{Name: "username", Type: "string"},
+			{Name: "language", Type: "string", Optional: true, Doc: "The locale code to read chat in, like de or pt-BR; absent means en. Each CHAT then carries only that translation."},
+		},
+	},
+	{Name: "START_GAME", Direction: ClientToServer, Doc: "Host only."},
+	{Name: "REMATCH", Direction: ClientToServer, Doc: "Host only, once the game is over. Takes the room back to the lobby."},
+	{Name: "ADD_BOT", Direction: ClientToServer, Doc: "Host only, in an unranked lobby. Adds a practice bot, named with a 🤖 prefix, up to the server's limit; it speaks and votes in meetings but leaves the code alone. Kick it like any player. Refused with an ERROR when bots are off or the room has its share."},
+	{Name: "SET_RANKED", Direction: ClientToServer, Doc: "Host only, in the lobby.", Fields: []Field{{Name: "ranked", Type: "boolean"}}},
+	{Name: "ROOM_SETTINGS", Direction: ClientToServer, Doc: "Host only, in the lobby. Fields left out keep their value.", Data: "Settings"},
+	{Name: "SET_SPECTATOR_DELAY", Direction: ClientToServer, Doc: "Host only, in the lobby.", Fields: []Field{{Name: "seconds", Type: "integer"}}},
+	{Name: "RUN_TESTS", Direction: ClientToServer, Fields: []Field{{Name: "code", Type: "string"}}},
+	{Name: "HINT", Direction: ClientToServer, Doc: "Players still in the game, during a task phase of a game with hints on. Asks the hint service about the task and this code; answered with HINT, or an ERROR when hints are off, used up or already on their way, or the service doesn't answer.", Fields: []Field{{Name: "code", Type: "string", Doc: "The editor's contents."}}},
+	{Name: "CHAT", Direction: ClientToServer, Doc: "Control and invisible formatting characters are dropped and runs of whitespace become one line break or space; a line longer than the server's CHAT_MAX_LENGTH (300 by default) is refused with an ERROR. Depending on the server's CHAT_FILTER_ACTION, a line with a blocked word in it has the word masked with asterisks (the default) or is refused with an ERROR. The CHAT sent back carries the server's message ID and timestamp.", Fields: []Field{{Name: "text", Type: "string"}}},
+	{Name: "IMPOSTOR_CHAT", Direction: ClientToServer, Doc: "Impostors still in the game only, while it is under way, in games with more than one impostor; anything else gets an ERROR. Cleaned and limited like CHAT.", Fields: []Field{{Name: "text", Type: "string"}}},
+	{Name: "QUICK_CHAT", Direction: ClientToServer, Doc: "A preset line from GET /api/quick-chat, sent as a CHAT with the preset's translation key and no translations. Allowed and limited like CHAT; an unknown preset gets an ERROR.", Fields: []Field{{Name: "presetId", Type: "string"}}},
+	{Name: "TYPING_START", Direction: ClientToServer, Doc: "Living players, during a meeting. Send when the player starts typing and every few seconds while they keep at it; a player not heard from for 6 seconds stops typing. Anything else is ignored. Typing messages have their own rate limit of 40 a minute."},
+	{Name: "TYPING_STOP", Direction: ClientToServer, Doc: "The player cleared their line or left the chat box. Sending a CHAT stops typing too."},
+	{Name: "GHOST_CHAT", Direction: ClientToServer, Doc: "Eliminated players only; goes to the other ghosts. Cleaned and limited like CHAT.", Fields: []Field{{Name: "text", Type: "string"}}},
+	{Name: "SABOTAGE", Direction: ClientToServer, Doc: "Imposter only. SWAP needs two crewmates online and TIMER_DRAIN a use left; either is refused with an ERROR otherwise.", Fields: []Field{{Name: "type", Type: "string", Enum: []string{"FREEZE", "CORRUPT", "SWAP", "TIMER_DRAIN", "CRITICAL"}}}},
+	{Name: "REPAIR", Direction: ClientToServer, Doc: "Non-impostors still in the game only, during a CRITICAL sabotage. Each player's first REPAIR counts; anything else gets an ERROR."},
+	{Name: "DETECTIVE_QUERY", Direction: ClientToServer, Doc: "The detective only, once per game while it is under way. Answered with DETECTIVE_RESULT, or an ERROR.", Fields: []Field{{Name: "targetID", Type: "string"}}},
+	{Name: "EMERGENCY", Direction: ClientToServer, Doc: "Calls a meeting."},
+	{Name: "VOTE", Direction: ClientToServer, Fields: []Field{{Name: "targetID", Type: "string", Doc: "A player ID, or SKIP."}}},
+	{
+		Name: "REPORT_PLAYER", Direction: ClientToServer,
+		Doc: "A player can report each other player once a day and file up to 10 reports an hour; past that the report is refused with an ERROR.",
+		Fields: []Field{
+			{Name: "targetID", Type: "string"},
+			{Name: "reason", Type: "string"},
+			{Name: "details", Type: "string", Optional: true, Doc: "Cut to 500 characters."},
+		},
+	},
+	{
+		Name: "MUTE_PLAYER", Direction: ClientToServer, Doc: "Host only. The player and everyone else see it through PLAYER_LIST, and the player also gets HOST_MUTED.",
+		Fields: []Field{
+			{Name: "targetID", Type: "string"},
+			{Name: "muted", Type: "boolean"},
+		},
+	},
+	{Name: "SLOW_MODE", Direction: ClientToServer, Doc: "Host only, any time. Sets slowModeSec, 0 to 60; SETTINGS_UPDATED goes to everyone. A CHAT or QUICK_CHAT sent sooner gets an ERROR.", Fields: []Field{{Name: "seconds", Type: "integer"}}},
+	{Name: "KICK_PLAYER", Direction: ClientToServer, Doc: "Host only. Removes a player, who may come back.", Fields: []Field{{Name: "targetID", Type: "string"}}},
+	{Name: "BAN_PLAYER", Direction: ClientToServer, Doc: "Host only. Removes a player and keeps them out of the room.", Fields: []Field{{Name: "targetID", Type: "string"}}},
+	{
+		Name: "ADD_WEBHOOK", Direction: ClientToServer, Doc: "Host only.",
+		Fields: []Field{
+			{Name: "url", Type: "string"},
+			{Name: "events", Type: "[]string", Optional: true, Doc: "Empty subscribes to every event."},
+		},
+	},
+	{Name: "REMOVE_WEBHOOK", Direction: ClientToServer, Doc: "Host only.", Fields: []Field{{Name: "id", Type: "string"}}},
+	{Name: "PING", Direction: ClientToServer, Doc: "Does nothing, but like any message keeps the player from being removed as AFK outside a game."},
+	{Name: "PARTY_CHAT", Direction: ClientToServer, Doc: "Signed-in players only. Cleaned and limited like CHAT.", Fields: []Field{{Name: "text", Type: "string"}}},
+	{Name: "QUEUE", Direction: ClientToServer, Doc: "Signed-in players only. Joins the quick-match queue, replacing any earlier ticket. In a party only the leader can queue, and the whole party is queued and matched together. The ticket is dropped once the player has no connection left.", Fields: []Field{{Name: "language", Type: "string", Optional: true, Doc: "Blank means any."}}},
+	{Name: "LEAVE_QUEUE", Direction: ClientToServer, Doc: "Signed-in players only. Any party member can take the party out."},
+}
+
+// Names returns the message types sent in direction d.
+func Names(d Direction) map[string]bool {
+	names := make(map[string]bool)
+	for _, m := range Messages {
+		if m.Direction == d {
+			names[m.Name] = true
+		}
+	}
+	return names
+}
+
+// Validate reports fields that refer to types that don't exist.
+func Validate() error {
+	known := make(map[string]bool)
+	for _, t := range Types {
+		known[t.Name] = true
+	}
+	check := func(where, typ string) error {
+		if name := parseType(typ).name; !isScalar(name) && !known[name] {
+			return fmt.Errorf("%s: unknown type %q", where, name)
+		}
+		return nil
+	}
+
+	for _, t := range Types {
+		for _, f := range t.Fields {
+			if err := check(t.Name+"."+f.Name, f.Type); err != nil {
+				return err
+			}
+		}
+	}
+	for _, m := range Messages {
+		if m.Data != "" {
+			if err := check(m.Name, m.Data); err != nil {
+				return err
+			}
+		}
+		for _, f := range m.Fields {
+			if err := check(m.Name+"."+f.Name, f.Type); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,132 @@
+package wsschema
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// TypeScript renders the protocol as declarations for the frontend:
+// an interface per payload plus ServerMessage and ClientMessage unions
+// discriminated on "type".
+func TypeScript() []byte {
+	var b bytes.Buffer
+	b.WriteString("// Code generated by `main wsschema` from backend/wsschema. DO NOT EDIT.\n")
+	b.WriteString("// Run `go generate` in backend/ after changing the protocol.\n")
+
+	for _, t := range Types {
+		b.WriteString("\n")
+		writeInterface(&b, t.Name, t.Doc, t.Fields)
+	}
+
+	var server, client []string
+	for _, m := range Messages {
+		data := "Record<string, never>"
+		switch {
+		case m.Data != "":
+			data = tsType(parseType(m.Data))
+		case len(m.Fields) > 0:
+			data = payloadName(m)
+			b.WriteString("\n")
+			writeInterface(&b, data, m.Doc, m.Fields)
+		}
+
+		if m.Direction == ClientToServer {
+			optional := ""
+			if len(m.Fields) == 0 && m.Data == "" {
+				optional = "?"
+			}
+			client = append(client, fmt.Sprintf("{ type: '%s'; data%s: %s }", m.Name, optional, data))
+		} else {
+			server = append(server, fmt.Sprintf("{ type: '%s'; data: %s }", m.Name, data))
+		}
+	}
+
+	writeUnion(&b, "ServerMessage", "Everything the server sends on /ws and /ws/spectate.", server)
+	writeUnion(&b, "ClientMessage", "Everything a client may send on /ws.", client)
+	b.WriteString("\nexport type ServerMessageType = ServerMessage['type'];\n")
+	b.WriteString("export type ClientMessageType = ClientMessage['type'];\n")
+	return b.Bytes()
+}
+
+// payloadName names a message's payload interface. Server payloads end in
+// Data and client ones in Request, since some types (CHAT) go both ways.
+func payloadName(m MessageType) string {
+	var name strings.Builder
+	for _, part := range strings.Split(strings.ToLower(m.Name), "_") {
+		if part != "" {
+			name.WriteString(strings.ToUpper(part[:1]) + part[1:])
+		}
+	}
+	if m.Direction == ClientToServer {
+		name.WriteString("Request")
+	} else {
+		name.WriteString("Data")
+	}
+	return name.String()
+}
+
+func writeInterface(b *bytes.Buffer, name, doc string, fields []Field) {
+	writeDoc(b, "", doc)
+	fmt.Fprintf(b, "export interface %s {\n", name)
+	for _, f := range fields {
+		writeDoc(b, "  ", f.Doc)
+		optional := ""
+		if f.Optional {
+			optional = "?"
+		}
+		typ := tsType(parseType(f.Type))
+		if len(f.Enum) > 0 {
+			values := make([]string, len(f.Enum))
+			for i, v := range f.Enum {
+				values[i] = "'" + v + "'"
+			}
+			typ = strings.Join(values, " | ")
+		}
+		fmt.Fprintf(b, "  %s%s: %s;\n", f.Name, optional, typ)
+	}
+	b.WriteString("}\n")
+}
+
+func writeUnion(b *bytes.Buffer, name, doc string, members []string) {
+	b.WriteString("\n")
+	writeDoc(b, "", doc)
+	fmt.Fprintf(b, "export type %s =\n", name)
+	for i, m := range members {
+		end := ""
+		if i == len(members)-1 {
+			end = ";"
+		}
+		fmt.Fprintf(b, "  | %s%s\n", m, end)
+	}
+}
+
+func writeDoc(b *bytes.Buffer, indent, doc string) {
+	if doc != "" {
+		fmt.Fprintf(b, "%s/** %s */\n", indent, doc)
+	}
+}
+
+func tsType(e typeExpr) string {
+	var t string
+	switch e.name {
+	case "string", "boolean":
+		t = e.name
+	case "integer", "number":
+		t = "number"
+	case "timestamp":
+		t = "string"
+	default:
+		t = e.name
+	}
+
+	if e.list {
+		t += "[]"
+	} else if e.dict {
+		t = "Record<string, " + t + ">"
+	}
+	if e.nullable {
+		t += " | null"
+	}
+	return t
+}
@@ -0,0 +1,166 @@
+package wsschema
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+// CheckPayload checks the data of a message a client sent against the
+// schema, returning an error that says what is wrong with it in terms a
+// client developer can act on. Fields the schema doesn't know are left
+// alone, as are payloads of types it doesn't list. When the payload is a
+// shared Data type, as ROOM_SETTINGS is, every field is optional.
+func CheckPayload(name string, data []byte) error {
+	m, ok := clientMessages[name]
+	if !ok {
+		return nil
+	}
+	if len(m.Fields) == 0 && m.Data == "" {
+		return nil
+	}
+
+	var payload interface{}
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &payload); err != nil {
+			return fmt.Errorf("data is not valid JSON")
+		}
+	}
+	if payload == nil {
+		payload = map[string]interface{}{}
+	}
+	obj, ok := payload.(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("data must be an object")
+	}
+
+	if m.Data != "" {
+		t, ok := types[parseType(m.Data).name]
+		if !ok {
+			return nil
+		}
+		return checkObject("", obj, t.Fields, true)
+	}
+	return checkObject("", obj, m.Fields, false)
+}
+
+var (
+	clientMessages = make(map[string]MessageType)
+	types          = make(map[string]Type)
+)
+
+func init() {
+	for _, m := range Messages {
+		if m.Direction == ClientToServer {
+			clientMessages[m.Name] = m
+		}
+	}
+	for _, t := range Types {
+		types[t.Name] = t
+	}
+}
+
+func checkObject(prefix string, obj map[string]interface{}, fields []Field, partial bool) error {
+	for _, f := range fields {
+		path := prefix + f.Name
+		v, present := obj[f.Name]
+		if !present {
+			if !f.Optional && !partial {
+				return fmt.Errorf("%s is required", path)
+			}
+			continue
+		}
+		if err := checkValue(path, v, f.Type); err != nil {
+			return err
+		}
+		if len(f.Enum) > 0 {
+			s, _ := v.(string)
+			if !contains(f.Enum, s) {
+				return fmt.Errorf("%s must be one of %s", path, strings.Join(f.Enum, ", "))
+			}
+		}
+	}
+	return nil
+}
+
+func checkValue(path string, v interface{}, typ string) error {
+	e := parseType(typ)
+	if v == nil {
+		if e.nullable {
+			return nil
+		}
+		return fmt.Errorf("%s must not be null", path)
+	}
+
+	switch {
+	case e.list:
+		list, ok := v.([]interface{})
+		if !ok {
+			return fmt.Errorf("%s must be a list", path)
+		}
+		for i, item := range list {
+			if err := checkValue(fmt.Sprintf("%s[%d]", path, i), item, e.name); err != nil {
+				return err
+			}
+		}
+		return nil
+	case e.dict:
+		dict, ok := v.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("%s must be an object", path)
+		}
+		for key, item := range dict {
+			if err := checkValue(path+"."+key, item, e.name); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	switch e.name {
+	case "string":
+		if _, ok := v.(string); !ok {
+			return fmt.Errorf("%s must be a string", path)
+		}
+	case "timestamp":
+		s, ok := v.(string)
+		if _, err := time.Parse(time.RFC3339, s); !ok || err != nil {
+			return fmt.Errorf("%s must be an RFC 3339 timestamp", path)
+		}
+	case "boolean":
+		if _, ok := v.(bool); !ok {
+			return fmt.Errorf("%s must be true or false", path)
+		}
+	case "number":
+		if _, ok := v.(float64); !ok {
+			return fmt.Errorf("%s must be a number", path)
+		}
+	case "integer":
+		n, ok := v.(float64)
+		if !ok || n != math.Trunc(n) {
+			return fmt.Errorf("%s must be a whole number", path)
+		}
+	default:
+		t, ok := types[e.name]
+		if !ok {
+			return nil
+		}
+		obj, ok := v.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("%s must be an object", path)
+		}
+		return checkObject(path+".", obj, t.Fields, false)
+	}
+	return nil
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
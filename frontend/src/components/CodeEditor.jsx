@@ -3,7 +3,7 @@ import Editor from '@monaco-editor/react';
 import { useGame } from '../context/GameContext';
 import { motion, AnimatePresence } from 'framer-motion';
 import Starfield from './Starfield';
-import { Clock, Loader2, AlertTriangle, Snowflake } from 'lucide-react';
+import { Clock, Loader2, AlertTriangle, Snowflake, Shuffle, Radiation, Wrench } from 'lucide-react';
 import * as Y from 'yjs';
 import { WebsocketProvider } from 'y-websocket';
 import { MonacoBinding } from 'y-monaco';
@@ -13,11 +13,13 @@ import ControlPanel from './game/ControlPanel';
 import SabotagePanel from './game/SabotagePanel';
 import ChatPanel from './game/ChatPanel';
 import PlayersList from './game/PlayerList';
+import { useCountdown } from '../hooks/useCountdown';
 
 export default function CodeEditor({ onEmergency }) {
   const { state } = useGame();
   const editorRef = useRef(null);
   const [chatMessage, setChatMessage] = useState('');
+  const [teamChat, setTeamChat] = useState(false);
   const chatEndRef = useRef(null);
   const terminalEndRef = useRef(null);
   
@@ -31,16 +33,23 @@ export default function CodeEditor({ onEmergency }) {
   const [isFrozen, setIsFrozen] = useState(false);
   const [sabotageType, setSabotageType] = useState(null);
   const [freezeTimeLeft, setFreezeTimeLeft] = useState(0);
+  const [swapPartner, setSwapPartner] = useState(null);
+  const swapTimerRef = useRef(null);
+  const [critical, setCritical] = useState(null);
+  const [hasRepaired, setHasRepaired] = useState(false);
 
   const playerList = Object.values(state.players || {});
   const isImpostor = state.role === 'IMPOSTER';
+  const canTeamChat = isImpostor && !state.isEliminated && (state.impostorTeam?.length || 0) > 1;
   const isTerminalBusy = state.isTerminalBusy;
   const currentRunner = state.currentRunner;
   const terminalLogs = state.terminalLogs;
   const isMyTest = state.currentRunnerID === state.playerId;
   const currentStage = state.currentStage;
-  const timerSeconds = state.timerSeconds;
+  const timerSeconds = useCountdown(state.timer, state.timerSeconds);
   const tasksComplete = state.tasksComplete;
+  const taskProgress = state.taskProgress;
+  const personalTasks = !!taskProgress;
   const userLang = state.language || 'en'; // 🔥 NEW: Get user language
 
 
@@ -98,6 +107,45 @@ export default function CodeEditor({ onEmergency }) {
           setFreezeTimeLeft(0);
         }
 
+        if (message.type === 'SABOTAGE_SWAP') {
+          console.log('🔀 SWAP sabotage - following', message.data.partnerName);
+          const { partnerID, partnerName, duration } = message.data;
+          setSwapPartner(partnerName);
+          jumpToCursorOf(partnerID);
+
+          clearInterval(swapTimerRef.current);
+          swapTimerRef.current = setInterval(() => jumpToCursorOf(partnerID), 3000);
+          setTimeout(() => {
+            clearInterval(swapTimerRef.current);
+            setSwapPartner(null);
+          }, duration);
+        }
+
+        if (message.type === 'SABOTAGE_ENDED' && message.data.type === 'SWAP') {
+          console.log('✅ SWAP sabotage ended');
+          clearInterval(swapTimerRef.current);
+          setSwapPartner(null);
+        }
+
+        if (message.type === 'SABOTAGE_STARTED' && message.data.type === 'CRITICAL') {
+          console.log('☢️ CRITICAL sabotage - reactor meltdown!');
+          setHasRepaired(false);
+          setCritical({
+            remainingSeconds: Math.floor(message.data.duration / 1000),
+            repairs: 0,
+            repairsNeeded: 2,
+          });
+        }
+
+        if (message.type === 'CRITICAL_COUNTDOWN') {
+          setCritical(message.data);
+        }
+
+        if (message.type === 'SABOTAGE_ENDED' && message.data.type === 'CRITICAL') {
+          console.log('✅ CRITICAL sabotage repaired');
+          setCritical(null);
+        }
+
         if (message.type === 'SABOTAGE_CORRUPT') {
           console.log('🦠 CORRUPT sabotage - injecting malware');
           
@@ -118,9 +166,35 @@ export default function CodeEditor({ onEmergency }) {
     };
 
     state.ws.addEventListener('message', handleSabotage);
-    return () => state.ws?.removeEventListener('message', handleSabotage);
+    return () => {
+      state.ws?.removeEventListener('message', handleSabotage);
+      clearInterval(swapTimerRef.current);
+    };
   }, [state.ws]);
 
+  // Moves this player's cursor to where another player's is, going by the
+  // selection y-monaco shares through awareness.
+  const jumpToCursorOf = (playerId) => {
+    const provider = yjsProviderRef.current;
+    const doc = yjsDocRef.current;
+    const editor = editorRef.current;
+    if (!provider || !doc || !editor) return;
+
+    for (const peer of provider.awareness.getStates().values()) {
+      if (peer.user?.id !== playerId || !peer.selection?.head) continue;
+      const head = Y.createAbsolutePositionFromRelativePosition(
+        Y.createRelativePositionFromJSON(peer.selection.head),
+        doc
+      );
+      if (!head) return;
+      const position = editor.getModel().getPositionAt(head.index);
+      editor.setPosition(position);
+      editor.revealPositionInCenter(position);
+      editor.focus();
+      return;
+    }
+  };
+
   // Yjs initialization with stage handling
   useEffect(() => {
     if (!state.roomId || !editorReady || !editorRef.current || !state.task) {
@@ -150,8 +224,16 @@ export default function CodeEditor({ onEmergency }) {
     const doc = new Y.Doc();
     yjsDocRef.current = doc;
     
-    const yjsRoomId = `${state.roomId}-stage${currentStage}`;
+    // Personal tasks are edited alone, in a document of the player's own
+    const yjsRoomId = personalTasks
+      ? `${state.roomId}-task-${state.playerId}`
+      : `${state.roomId}-stage${currentStage}`;
     const wsUrl = `${WS_BASE}/yjs`;
+    // The server only takes edits from a connection that proves which player it is
+    const saved = JSON.parse(sessionStorage.getItem(`session:${state.roomId}`) || 'null');
+    const guest = JSON.parse(localStorage.getItem('guestToken') || 'null');
+    const session = saved && saved.playerID === state.playerId ? saved.token : '';
+    const guestToken = guest && guest.playerID === state.playerId ? guest.token : '';
     
     // Create provider for this stage
     if (yjsProviderRef.current) {
@@ -166,7 +248,7 @@ export default function CodeEditor({ onEmergency }) {
       doc,
       {
         connect: true,
-        params: { room: yjsRoomId }
+        params: { room: yjsRoomId, userId: state.playerId || '', session, guestToken }
       }
     );
     yjsProviderRef.current = provider;
@@ -212,6 +294,7 @@ export default function CodeEditor({ onEmergency }) {
     const userColor = getPlayerColor(playerIndex);
     
     provider.awareness.setLocalStateField('user', {
+      id: state.playerId,
       name: state.username || 'Anonymous',
       color: userColor,
       colorLight: userColor + '80',
@@ -224,6 +307,7 @@ export default function CodeEditor({ onEmergency }) {
     awarenessTimerRef.current = setInterval(() => {
       if (provider && provider.awareness && !state.isEliminated) {
         provider.awareness.setLocalStateField('user', {
+          id: state.playerId,
           name: state.username || 'Anonymous',
           color: userColor,
           colorLight: userColor + '80',
@@ -259,7 +343,7 @@ export default function CodeEditor({ onEmergency }) {
         yjsDocRef.current = null;
       }
     };
-  }, [state.roomId, editorReady, state.task?.id, currentStage, state.playerId, state.username]);
+  }, [state.roomId, editorReady, state.task?.id, currentStage, personalTasks, state.playerId, state.username]);
 
   // Update editor read-only state
   useEffect(() => {
@@ -287,11 +371,11 @@ export default function CodeEditor({ onEmergency }) {
   };
 
   const handleSendMessage = () => {
-    if (!chatMessage.trim() || state.isEliminated) return;
+    if (!chatMessage.trim()) return;
 
     if (state.ws && state.ws.readyState === WebSocket.OPEN) {
       state.ws.send(JSON.stringify({
-        type: 'CHAT',
+        type: state.isEliminated ? 'GHOST_CHAT' : canTeamChat && teamChat ? 'IMPOSTOR_CHAT' : 'CHAT',
         data: {
           username: state.username,
           text: chatMessage,
@@ -315,6 +399,28 @@ export default function CodeEditor({ onEmergency }) {
     }
   };
 
+  const handleRepair = () => {
+    if (isImpostor || state.isEliminated || hasRepaired) return;
+
+    if (state.ws && state.ws.readyState === WebSocket.OPEN) {
+      state.ws.send(JSON.stringify({ type: 'REPAIR' }));
+      setHasRepaired(true);
+    }
+  };
+
+  const canInvestigate = state.role === 'DETECTIVE' && !state.isEliminated && !state.detectiveResult;
+
+  const handleInvestigate = (targetID) => {
+    if (!canInvestigate) return;
+
+    if (state.ws && state.ws.readyState === WebSocket.OPEN) {
+      state.ws.send(JSON.stringify({
+        type: 'DETECTIVE_QUERY',
+        data: { targetID }
+      }));
+    }
+  };
+
   const handleRunTests = () => {
     if (isTerminalBusy || state.isEliminated || isFrozen) return;
 
@@ -329,6 +435,19 @@ export default function CodeEditor({ onEmergency }) {
     }
   };
 
+  const handleHint = () => {
+    if (state.isEliminated) return;
+
+    const code = editorRef.current?.getValue() || '';
+
+    if (state.ws && state.ws.readyState === WebSocket.OPEN) {
+      state.ws.send(JSON.stringify({
+        type: 'HINT',
+        data: { code }
+      }));
+    }
+  };
+
   const getPlayerColor = (index) => {
     const colors = ['#ff6b6b', '#6ba3ff', '#6ee06e', '#ffb366', '#a78bfa'];
     return colors[index % colors.length];
@@ -397,6 +516,56 @@ export default function CodeEditor({ onEmergency }) {
         )}
       </AnimatePresence>
       
+      {/* SWAP BANNER */}
+      <AnimatePresence>
+        {swapPartner && (
+          <motion.div
+            initial={{ y: -100, opacity: 0 }}
+            animate={{ y: 0, opacity: 1 }}
+            exit={{ y: -100, opacity: 0 }}
+            className="fixed top-0 left-0 right-0 z-50 bg-purple-600 border-b-4 border-brown-dark px-6 py-4 shadow-pixel"
+          >
+            <div className="flex items-center justify-center gap-3">
+              <Shuffle className="w-6 h-6 text-white" />
+              <span className="font-pixel text-sm text-white">
+                CROSSED WIRES - You're at {swapPartner}'s terminal!
+              </span>
+            </div>
+          </motion.div>
+        )}
+      </AnimatePresence>
+
+      {/* CRITICAL BANNER */}
+      <AnimatePresence>
+        {critical && (
+          <motion.div
+            initial={{ y: -100, opacity: 0 }}
+            animate={{ y: 0, opacity: 1 }}
+            exit={{ y: -100, opacity: 0 }}
+            className="fixed top-0 left-0 right-0 z-50 bg-red-700 border-b-4 border-brown-dark px-6 py-4 shadow-pixel"
+          >
+            <div className="flex items-center justify-center gap-3">
+              <Radiation className="w-6 h-6 text-white animate-pulse" />
+              <span className="font-pixel text-sm text-white">
+                REACTOR MELTDOWN IN {critical.remainingSeconds}s - REPAIRS {critical.repairs}/{critical.repairsNeeded}
+              </span>
+              {!isImpostor && !state.isEliminated && (
+                <button
+                  onClick={handleRepair}
+                  disabled={hasRepaired}
+                  className={`btn-space green text-xs flex items-center gap-2 ${
+                    hasRepaired ? 'opacity-50 cursor-not-allowed' : ''
+                  }`}
+                >
+                  <Wrench className="w-4 h-4" />
+                  {hasRepaired ? 'REPAIRED' : 'REPAIR'}
+                </button>
+              )}
+            </div>
+          </motion.div>
+        )}
+      </AnimatePresence>
+
       {/* Top Banner - Compilation in Progress */}
       <AnimatePresence>
         {isTerminalBusy && !isMyTest && (
@@ -420,27 +589,46 @@ export default function CodeEditor({ onEmergency }) {
         {/* Header */}
         <div className="flex justify-between items-center mb-4">
           <div className="flex gap-4 items-center">
-            <div className="panel-space-sm px-6 py-3">
-              <div className="flex items-center gap-3">
-                <span className="font-pixel text-xl text-gray-900">
-                  STAGE {currentStage}/3
-                </span>
-                <div className="flex gap-1">
-                  {[1, 2, 3].map(stage => (
-                    <div
-                      key={stage}
-                      className={`w-3 h-3 rounded-full border-2 border-brown-dark ${
-                        tasksComplete[stage] 
-                          ? 'bg-green-500' 
-                          : stage === currentStage 
-                            ? 'bg-orange animate-pulse' 
-                            : 'bg-gray-400'
-                      }`}
+            {personalTasks ? (
+              <div className="panel-space-sm px-6 py-3">
+                <div className="flex items-center gap-3">
+                  <span className="font-pixel text-xl text-gray-900">
+                    TASKS {taskProgress.complete}/{taskProgress.total}
+                  </span>
+                  <div className="w-32 h-3 bg-gray-400 border-2 border-brown-dark rounded-full overflow-hidden">
+                    <motion.div
+                      className="h-full bg-green-500"
+                      animate={{ width: `${taskProgress.total ? (taskProgress.complete / taskProgress.total) * 100 : 0}%` }}
                     />
-                  ))}
+                  </div>
+                  {state.taskDone && (
+                    <span className="font-pixel text-xs text-green-700">YOURS DONE</span>
+                  )}
                 </div>
               </div>
-            </div>
+            ) : (
+              <div className="panel-space-sm px-6 py-3">
+                <div className="flex items-center gap-3">
+                  <span className="font-pixel text-xl text-gray-900">
+                    STAGE {currentStage}/3
+                  </span>
+                  <div className="flex gap-1">
+                    {[1, 2, 3].map(stage => (
+                      <div
+                        key={stage}
+                        className={`w-3 h-3 rounded-full border-2 border-brown-dark ${
+                          tasksComplete[stage] 
+                            ? 'bg-green-500' 
+                            : stage === currentStage 
+                              ? 'bg-orange animate-pulse' 
+                              : 'bg-gray-400'
+                        }`}
+                      />
+                    ))}
+                  </div>
+                </div>
+              </div>
+            )}
 
             {/* Timer */}
             <motion.div
@@ -503,30 +691,36 @@ export default function CodeEditor({ onEmergency }) {
         <div className="grid grid-cols-4 gap-4 h-[calc(100vh-120px)]">
           {/* Left Sidebar */}
           <div className="col-span-1 flex flex-col gap-4">
-            {isImpostor ? (
+            {isImpostor && (
               <SabotagePanel 
                 onSabotage={handleSabotage} 
                 isFrozen={isFrozen} 
                 ws={state.ws} 
               />
-            ) : (
-              <ControlPanel
-                stageTitle={getStageTitle(currentStage)}
-                onRunTests={handleRunTests}
-                isBusy={isTerminalBusy}
-                isFrozen={isFrozen}
-                isEliminated={state.isEliminated}
-                runnerName={currentRunner}
-                isMyTest={isMyTest}
-                terminalLogs={terminalLogs}
-                terminalEndRef={terminalEndRef}
-                currentStage={currentStage}
-              />
             )}
 
+            {/* Impostors get the same panel so they can fake their runs */}
+            <ControlPanel
+              stageTitle={getStageTitle(currentStage)}
+              onRunTests={handleRunTests}
+              onHint={state.settings?.hints ? handleHint : null}
+              hintsLeft={state.hintsLeft ?? state.settings?.hintsPerStage}
+              hintPenalty={state.settings?.hintPenaltySec}
+              isBusy={isTerminalBusy}
+              isFrozen={isFrozen}
+              isEliminated={state.isEliminated}
+              runnerName={currentRunner}
+              isMyTest={isMyTest}
+              terminalLogs={terminalLogs}
+              terminalEndRef={terminalEndRef}
+              currentStage={currentStage}
+            />
+
             <PlayersList 
               players={state.players} 
               currentPlayerId={state.playerId} 
+              onInvestigate={canInvestigate ? handleInvestigate : null}
+              investigation={state.detectiveResult}
             />
           </div>
 
@@ -548,7 +742,7 @@ export default function CodeEditor({ onEmergency }) {
               <Editor
                 key={state.task?.id}
                 height="100%"
-                defaultLanguage="java"
+                defaultLanguage={state.task?.language || 'java'}
                 theme="vs-dark"
                 defaultValue={state.task?.template || ''}
                 onMount={handleEditorDidMount}
@@ -572,6 +766,9 @@ export default function CodeEditor({ onEmergency }) {
               isFrozen={isFrozen}
               chatEndRef={chatEndRef}
               userLang={userLang}
+              canTeamChat={canTeamChat}
+              teamChat={canTeamChat && teamChat}
+              onToggleTeamChat={() => setTeamChat(on => !on)}
             />
           </div>
         </div>
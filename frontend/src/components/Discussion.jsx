@@ -4,13 +4,23 @@ import { useGame } from '../context/GameContext';
 import { motion, AnimatePresence } from 'framer-motion';
 import Starfield from './Starfield';
 import Ship, { getShipType } from './Ship';
+import { translate, chatText } from '../utils/translations';
+
+// Quick chat presets offered in meetings; the server's catalog is at
+// GET /api/quick-chat.
+const QUICK_CHAT = [
+  { id: 'sus', key: 'quickchat.sus' },
+  { id: 'not-me', key: 'quickchat.notMe' },
+  { id: 'agree', key: 'quickchat.agree' },
+  { id: 'skip', key: 'quickchat.skip' },
+];
 
 // 🔥 ChatBubble component with translation animation
 const ChatBubble = ({ message, userLang }) => {
   return (
-    <div className="mb-2 p-2 bg-white/50 rounded border-2 border-brown-dark">
-      <span className="font-game text-base font-bold text-orange block mb-1">
-        {message.username}:
+    <div className={`mb-2 p-2 rounded border-2 ${message.ghost ? 'bg-purple-100/60 border-purple-400 italic' : 'bg-white/50 border-brown-dark'}`}>
+      <span className={`font-game text-base font-bold block mb-1 ${message.ghost ? 'text-purple-600' : 'text-orange'}`}>
+        {message.ghost && '👻 '}{message.username}:
       </span>
       
       <AnimatePresence mode='wait'>
@@ -22,24 +32,29 @@ const ChatBubble = ({ message, userLang }) => {
           transition={{ duration: 0.4 }}
           className="font-game text-base text-gray-900 block"
         >
-          {message.translations && message.translations[userLang] 
-            ? message.translations[userLang] 
-            : message.text}
+          {chatText(message, userLang)}
         </motion.span>
       </AnimatePresence>
     </div>
   );
 };
 
-export default function Discussion({ onVote }) {
+// Slow mode gaps a host can pick, in seconds.
+const SLOW_MODE_OPTIONS = [0, 5, 10, 30];
+
+export default function Discussion({ onVote, onSlowMode }) {
   const { state, dispatch } = useGame();
   const [selectedTarget, setSelectedTarget] = useState(null);
   const [timeLeft, setTimeLeft] = useState(30);
   const [hasVoted, setHasVoted] = useState(false);
+  const [candidates, setCandidates] = useState(null);
   const [chatMessage, setChatMessage] = useState('');
+  const [typingIds, setTypingIds] = useState([]);
   const chatEndRef = useRef(null);
+  const lastTypingSent = useRef(0);
 
-  const playerList = Object.values(state.players || {}).filter(p => !p.isEliminated);
+  const playerList = Object.values(state.players || {})
+    .filter(p => !p.isEliminated && (!candidates || candidates.includes(p.id)));
   const currentPlayer = state.players?.[state.playerId];
   const canVote = !hasVoted && currentPlayer && !currentPlayer.isEliminated;
   const userLang = state.language || 'en';
@@ -65,6 +80,55 @@ export default function Discussion({ onVote }) {
           }
         }
 
+        // A tie: everyone votes again, between the tied players only.
+        if (message.type === 'VOTE_TIE') {
+          setCandidates(message.data.candidates.map(c => c.id));
+          setHasVoted(false);
+          setSelectedTarget(null);
+          setTimeLeft(message.data.seconds);
+          dispatch({
+            type: 'ADD_MESSAGE',
+            payload: {
+              messageId: `vote-tie-${Date.now()}`,
+              username: 'System',
+              text: `⚖️ Tie! Revote between ${message.data.candidates.map(c => c.username).join(' and ')}`,
+              translations: {},
+              timestamp: Date.now(),
+              system: true,
+              translationId: Date.now(),
+            }
+          });
+        }
+
+        // The server turned the ballot away; let the player pick again
+        // unless their first vote was final.
+        if (message.type === 'VOTE_REJECTED') {
+          if (message.data.reason !== 'VOTE_LOCKED') {
+            setHasVoted(false);
+          }
+          dispatch({
+            type: 'ADD_MESSAGE',
+            payload: {
+              messageId: `vote-rejected-${Date.now()}`,
+              username: 'System',
+              text: message.data.message,
+              translations: {},
+              timestamp: Date.now(),
+              system: true,
+              translationId: Date.now(),
+            }
+          });
+        }
+
+        // Who else is composing; the server stops anyone it stops
+        // hearing from.
+        if (message.type === 'TYPING_START') {
+          setTypingIds(ids => ids.includes(message.data.playerId) ? ids : [...ids, message.data.playerId]);
+        }
+        if (message.type === 'TYPING_STOP') {
+          setTypingIds(ids => ids.filter(id => id !== message.data.playerId));
+        }
+
         // Handle translation updates
         if (message.type === 'TRANSLATION_UPDATE') {
           dispatch({
@@ -91,8 +155,10 @@ export default function Discussion({ onVote }) {
     if (state.phase === 'DISCUSSION') {
       setHasVoted(false);
       setSelectedTarget(null);
+      setCandidates(null);
       setTimeLeft(30);
     }
+    setTypingIds([]);
   }, [state.phase]);
 
   const handleVoteSubmit = (targetID) => {
@@ -105,12 +171,28 @@ export default function Discussion({ onVote }) {
     return state.votesStatus?.[playerId] || false;
   };
 
+  // Living players tell the others they are typing, again every few
+  // seconds while they keep at it so the server doesn't time them out.
+  const sendTyping = (typing) => {
+    if (state.isEliminated || !state.ws || state.ws.readyState !== WebSocket.OPEN) return;
+    if (typing && Date.now() - lastTypingSent.current < 3000) return;
+    if (!typing && lastTypingSent.current === 0) return;
+
+    state.ws.send(JSON.stringify({ type: typing ? 'TYPING_START' : 'TYPING_STOP' }));
+    lastTypingSent.current = typing ? Date.now() : 0;
+  };
+
+  const handleChatChange = (e) => {
+    setChatMessage(e.target.value);
+    sendTyping(e.target.value.trim() !== '');
+  };
+
   const handleSendMessage = () => {
     if (!chatMessage.trim()) return;
 
     if (state.ws && state.ws.readyState === WebSocket.OPEN) {
       state.ws.send(JSON.stringify({
-        type: 'CHAT',
+        type: state.isEliminated ? 'GHOST_CHAT' : 'CHAT',
         data: {
           username: state.username,
           text: chatMessage,
@@ -118,9 +200,21 @@ export default function Discussion({ onVote }) {
         }
       }));
       setChatMessage('');
+      // The server stops our typing when the line arrives.
+      lastTypingSent.current = 0;
     }
   };
 
+  const handleQuickChat = (presetId) => {
+    if (state.ws && state.ws.readyState === WebSocket.OPEN) {
+      state.ws.send(JSON.stringify({ type: 'QUICK_CHAT', data: { presetId } }));
+    }
+  };
+
+  const typingNames = typingIds
+    .map(id => state.players?.[id]?.username)
+    .filter(Boolean);
+
   return (
     <div className="min-h-screen relative">
       <Starfield frozen={true} />
@@ -154,8 +248,8 @@ export default function Discussion({ onVote }) {
                   {playerList.map((player, index) => (
                     <motion.button
                       key={player.id}
-                      onClick={() => canVote && setSelectedTarget(player.id)}
-                      disabled={!canVote}
+                      onClick={() => canVote && player.id !== state.playerId && setSelectedTarget(player.id)}
+                      disabled={!canVote || player.id === state.playerId}
                       className={`w-full player-card-space cursor-pointer transition-all flex items-center justify-between ${
                         selectedTarget === player.id ? 'ring-4 ring-red-500 scale-102' : ''
                       } ${!canVote ? 'opacity-60 cursor-default' : 'hover:scale-102'}`}
@@ -216,7 +310,25 @@ export default function Discussion({ onVote }) {
             {/* Right - Chat Panel with Translations - 🔥 IMPROVED SIZE */}
             <div className="col-span-1">
               <div className="panel-space h-[700px] flex flex-col"> {/* 🔥 Increased from h-[600px] */}
-                <h3 className="font-pixel text-lg mb-3 text-gray-900">DISCUSSION</h3>
+                <div className="flex items-center justify-between mb-3">
+                  <h3 className="font-pixel text-lg text-gray-900">DISCUSSION</h3>
+                  {currentPlayer?.isHost ? (
+                    <select
+                      value={state.settings?.slowModeSec || 0}
+                      onChange={(e) => onSlowMode(Number(e.target.value))}
+                      className="input-space text-xs py-1"
+                      title="Slow mode"
+                    >
+                      {SLOW_MODE_OPTIONS.map(seconds => (
+                        <option key={seconds} value={seconds}>
+                          {seconds ? `🐢 ${seconds}s` : 'Slow mode off'}
+                        </option>
+                      ))}
+                    </select>
+                  ) : state.settings?.slowModeSec > 0 && (
+                    <span className="font-game text-sm text-gray-700">🐢 {state.settings.slowModeSec}s</span>
+                  )}
+                </div>
                 
                 {/* Messages with Translation Animation - 🔥 NO DUPLICATES */}
                 <div className="flex-1 overflow-y-auto mb-3 space-y-1 min-h-0 bg-white/30 p-3 rounded border-2 border-brown-dark">
@@ -224,7 +336,7 @@ export default function Discussion({ onVote }) {
                     <div key={msg.messageId || msg.timestamp || Math.random()}>
                       {msg.system ? (
                         <div className="mb-2 p-2 bg-gray-100 rounded border-2 border-gray-400">
-                          <span className="font-game text-sm italic text-gray-700">{msg.text}</span>
+                          <span className="font-game text-sm italic text-gray-700">{chatText(msg, userLang)}</span>
                         </div>
                       ) : (
                         <ChatBubble message={msg} userLang={userLang} />
@@ -234,14 +346,34 @@ export default function Discussion({ onVote }) {
                   <div ref={chatEndRef} />
                 </div>
 
+                <div className="h-5 mb-1 font-game text-sm italic text-gray-700">
+                  {typingNames.length === 1 && `${typingNames[0]} is typing...`}
+                  {typingNames.length > 1 && `${typingNames.join(', ')} are typing...`}
+                </div>
+
+                {!state.isEliminated && (
+                  <div className="flex flex-wrap gap-1 mb-2">
+                    {QUICK_CHAT.map(preset => (
+                      <button
+                        key={preset.id}
+                        onClick={() => handleQuickChat(preset.id)}
+                        className="btn-space text-xs px-2 py-1"
+                      >
+                        {translate(preset.key, userLang)}
+                      </button>
+                    ))}
+                  </div>
+                )}
+
                 {/* Chat Input */}
                 <div className="flex gap-2">
                   <input
                     type="text"
                     value={chatMessage}
-                    onChange={(e) => setChatMessage(e.target.value)}
+                    onChange={handleChatChange}
+                    onBlur={() => sendTyping(false)}
                     onKeyPress={(e) => e.key === 'Enter' && handleSendMessage()}
-                    placeholder="Discuss..."
+                    placeholder={state.isEliminated ? 'Whisper to other ghosts...' : 'Discuss...'}
                     className="input-space flex-1 text-base py-2" 
                   />
                   <button
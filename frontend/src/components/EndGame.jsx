@@ -5,7 +5,7 @@ import { motion } from 'framer-motion';
 import Starfield from './Starfield';
 import Ship, { getShipType } from './Ship';
 
-export default function EndGame({ reason, impostorId }) {
+export default function EndGame({ reason, impostorId, onRematch }) {
   const { state } = useGame();
   
   const getWinMessage = (reason) => {
@@ -42,6 +42,30 @@ export default function EndGame({ reason, impostorId }) {
           color: 'red'
         };
       
+      case 'IMPOSTER_WIN_SABOTAGE':
+        return {
+          title: '☢️ MELTDOWN',
+          subtitle: 'IMPOSTER wins!',
+          message: 'The crew failed to repair the reactor in time!',
+          color: 'red'
+        };
+      
+      case 'JESTER_WIN_VOTED_OUT':
+        return {
+          title: '🃏 JESTER WINS',
+          subtitle: 'The crew fell for it!',
+          message: 'The JESTER wanted to be voted out all along.',
+          color: 'purple'
+        };
+      
+      case 'ADMIN_ENDED':
+        return {
+          title: '🛑 MISSION ABORTED',
+          subtitle: 'Ended by the server operators',
+          message: 'This game was stopped early and does not count for either side.',
+          color: 'gray'
+        };
+      
       default:
         return {
           title: 'GAME OVER',
@@ -55,11 +79,15 @@ export default function EndGame({ reason, impostorId }) {
   const winInfo = getWinMessage(reason);
   const playerList = Object.values(state.players || {});
   const impostor = playerList.find(p => p.id === impostorId);
+  const isHost = state.players?.[state.playerId]?.isHost;
+  const scoreOf = (id) => state.scores.find((s) => s.playerID === id)?.score ?? 0;
+  const editShareOf = (id) => state.editShare.find((s) => s.playerID === id)?.percent ?? 0;
 
   const getColorClasses = (color) => {
     const classes = {
       green: 'text-green-400 bg-green-900',
       red: 'text-red-400 bg-red-900',
+      purple: 'text-purple-400 bg-purple-900',
       gray: 'text-gray-400 bg-gray-900'
     };
     return classes[color] || classes.gray;
@@ -126,6 +154,18 @@ export default function EndGame({ reason, impostorId }) {
               </div>
             </div>
           )}
+
+          {state.mvp && (
+            <div className="mt-6 p-4 bg-yellow-100 border-3 border-yellow-500">
+              <p className="font-pixel text-sm mb-2 text-yellow-700">⭐ MVP</p>
+              <p className="font-game text-3xl text-gray-900">
+                {state.mvp.username} - {state.mvp.score} pts
+              </p>
+              <p className="font-game text-lg text-gray-700">
+                {state.mvp.stagesPassed} stages passed · {state.mvp.testsRun} test runs · {state.mvp.correctVotes} correct votes · {state.mvp.sabotagesSurvived} sabotages survived
+              </p>
+            </div>
+          )}
         </motion.div>
 
         {/* Players Summary */}
@@ -150,10 +190,16 @@ export default function EndGame({ reason, impostorId }) {
                     {player.username}
                   </p>
                   <p className={`font-pixel text-xs ${
-                    player.role === 'IMPOSTER' ? 'text-red-600' : 'text-green-600'
+                    player.role === 'IMPOSTER' ? 'text-red-600' : player.role === 'JESTER' ? 'text-purple-600' : 'text-green-600'
                   }`}>
                     {player.role}
                   </p>
+                  <p className="font-game text-sm text-gray-700">
+                    {scoreOf(player.id)} pts · wrote {editShareOf(player.id)}% of the code
+                  </p>
+                  <div className="h-1 bg-gray-300 mt-1">
+                    <div className="h-1 bg-green-600" style={{ width: `${editShareOf(player.id)}%` }} />
+                  </div>
                 </div>
               </div>
             ))}
@@ -170,6 +216,18 @@ export default function EndGame({ reason, impostorId }) {
         >
           Return to Lobby
         </motion.button>
+
+        {isHost && (
+          <motion.button
+            initial={{ opacity: 0 }}
+            animate={{ opacity: 1 }}
+            transition={{ delay: 1.5 }}
+            onClick={onRematch}
+            className="btn-space mt-8 ml-4"
+          >
+            Play Again
+          </motion.button>
+        )}
       </div>
     </div>
   );
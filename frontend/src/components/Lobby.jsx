@@ -5,14 +5,41 @@ import { motion } from 'framer-motion';
 import Ship, { getShipType } from './Ship';
 import Starfield from './Starfield';
 
-export default function Lobby({ onStartGame }) {
+const SETTING_FIELDS = [
+  { key: 'timerSeconds', label: 'Timer (seconds)', min: 60, max: 900 },
+  { key: 'votingSeconds', label: 'Voting (seconds)', min: 10, max: 120 },
+  { key: 'sabotageCooldownSec', label: 'Sabotage cooldown (seconds)', min: 5, max: 300 },
+  { key: 'impostorCount', label: 'Imposters (0 = auto)', min: 0, max: 3 },
+  { key: 'minPlayers', label: 'Minimum players', min: 3, max: 15 },
+  { key: 'hintsPerStage', label: 'Hints per stage', min: 1, max: 5 },
+  { key: 'hintPenaltySec', label: 'Hint penalty (seconds)', min: 5, max: 120 },
+];
+
+const TASK_FIELDS = [
+  { key: 'taskCategory', label: 'Task category', options: ['DSA', 'OOPS', 'DEBUGGING'] },
+  { key: 'taskDifficulty', label: 'Task difficulty', options: ['EASY', 'MEDIUM', 'HARD'] },
+  { key: 'taskLanguage', label: 'Code language', options: ['java', 'python', 'javascript', 'go'], required: true },
+];
+
+const TOGGLE_FIELDS = [
+  { key: 'lockVotes', label: 'Lock votes' },
+  { key: 'tieRevote', label: 'Revote on ties' },
+  { key: 'personalTasks', label: 'Personal tasks' },
+  { key: 'detective', label: 'Detective role' },
+  { key: 'jester', label: 'Jester role' },
+  { key: 'ghostsHearImpostors', label: 'Ghosts hear impostors' },
+  { key: 'hints', label: 'Hints' },
+];
+
+export default function Lobby({ onStartGame, onUpdateSettings, onRemovePlayer, onMutePlayer, onAddBot }) {
   const { state } = useGame();
   const [isStarting, setIsStarting] = useState(false);
   
   const playerList = Object.values(state.players || {});
   const currentPlayer = state.players?.[state.playerId];
   const isHost = currentPlayer?.isHost;
-  const canStart = playerList.length >= 3;
+  const minPlayers = state.settings?.minPlayers || 3;
+  const canStart = playerList.length >= minPlayers;
 
   console.log('🎮 Lobby Debug:', {
     playerId: state.playerId,
@@ -87,6 +114,7 @@ export default function Lobby({ onStartGame }) {
                   <span className="font-game text-2xl text-gray-900">
                     {player.username}
                     {player.id === state.playerId && " (You)"}
+                    {player.muted && " 🔇"}
                   </span>
                 </div>
                 {player.isHost && (
@@ -94,11 +122,103 @@ export default function Lobby({ onStartGame }) {
                     <span className="font-pixel text-xs text-gray-900">HOST</span>
                   </div>
                 )}
+                {isHost && player.id !== state.playerId && (
+                  <div className="flex gap-2">
+                    <button
+                      onClick={() => onMutePlayer(player.id, !player.muted)}
+                      className="btn-space text-sm px-3 py-1"
+                    >
+                      {player.muted ? 'UNMUTE' : 'MUTE'}
+                    </button>
+                    <button
+                      onClick={() => onRemovePlayer(player.id, false)}
+                      className="btn-space text-sm px-3 py-1"
+                    >
+                      KICK
+                    </button>
+                    <button
+                      onClick={() => {
+                        if (window.confirm(`Ban ${player.username} from this room?`)) {
+                          onRemovePlayer(player.id, true);
+                        }
+                      }}
+                      className="btn-space text-sm px-3 py-1"
+                    >
+                      BAN
+                    </button>
+                  </div>
+                )}
               </motion.div>
             ))}
           </div>
         </div>
 
+        {/* Game Settings */}
+        {state.settings && (
+          <div className="mb-8">
+            <h2 className="font-game text-3xl mb-4 text-gray-900">Rules</h2>
+            <div className="grid grid-cols-2 gap-3">
+              {SETTING_FIELDS.map(({ key, label, min, max }) => (
+                <label key={key} className="font-game text-xl text-gray-900 flex items-center justify-between gap-2">
+                  {label}
+                  {isHost ? (
+                    <input
+                      type="number"
+                      min={min}
+                      max={max}
+                      defaultValue={state.settings[key] || 0}
+                      key={state.settings[key]}
+                      onBlur={(e) => {
+                        const value = parseInt(e.target.value, 10);
+                        if (!Number.isNaN(value) && value !== (state.settings[key] || 0)) {
+                          onUpdateSettings({ [key]: value });
+                        }
+                      }}
+                      className="w-24 border-2 border-brown-dark px-2"
+                    />
+                  ) : (
+                    <span>{state.settings[key] || 'auto'}</span>
+                  )}
+                </label>
+              ))}
+              {TASK_FIELDS.map(({ key, label, options, required }) => (
+                <label key={key} className="font-game text-xl text-gray-900 flex items-center justify-between gap-2">
+                  {label}
+                  {isHost ? (
+                    <select
+                      value={state.settings[key] || ''}
+                      onChange={(e) => onUpdateSettings({ [key]: e.target.value })}
+                      className="w-32 border-2 border-brown-dark px-2"
+                    >
+                      {!required && <option value="">Any</option>}
+                      {options.map((option) => (
+                        <option key={option} value={option}>{option}</option>
+                      ))}
+                    </select>
+                  ) : (
+                    <span>{state.settings[key] || 'any'}</span>
+                  )}
+                </label>
+              ))}
+              {TOGGLE_FIELDS.map(({ key, label }) => (
+                <label key={key} className="font-game text-xl text-gray-900 flex items-center justify-between gap-2">
+                  {label}
+                  {isHost ? (
+                    <input
+                      type="checkbox"
+                      checked={!!state.settings[key]}
+                      onChange={(e) => onUpdateSettings({ [key]: e.target.checked })}
+                      className="w-6 h-6"
+                    />
+                  ) : (
+                    <span>{state.settings[key] ? 'yes' : 'no'}</span>
+                  )}
+                </label>
+              ))}
+            </div>
+          </div>
+        )}
+
         {/* Start Button or Waiting */}
         {isHost ? (
           <div>
@@ -108,9 +228,15 @@ export default function Lobby({ onStartGame }) {
                 animate={{ opacity: [1, 0.5, 1] }}
                 transition={{ duration: 1.5, repeat: Infinity }}
               >
-                Need at least 3 players to start
+                Need at least {minPlayers} players to start
               </motion.p>
             )}
+            <button
+              onClick={onAddBot}
+              className="btn-space w-full mb-3"
+            >
+              🤖 ADD PRACTICE BOT
+            </button>
             <button
               onClick={handleStartGame}
               disabled={!canStart || isStarting}
@@ -5,11 +5,20 @@ import { useGame } from '../context/GameContext';
 import { motion } from 'framer-motion';
 import Starfield from './Starfield';
 
+const ROLE_TITLES = {
+  CIVILIAN: 'CREWMATE',
+  IMPOSTER: 'IMPOSTER',
+  DETECTIVE: 'DETECTIVE',
+  JESTER: 'JESTER',
+};
+
 export default function RoleReveal() {
   const { state } = useGame();
   // const { t } = useTranslation(state.language);
   
-  const isCivilian = state.role === 'CIVILIAN';
+  const isCivilian = state.role !== 'IMPOSTER';
+  const roleTitle = ROLE_TITLES[state.role] || 'CREWMATE';
+  const teammates = (state.impostorTeam || []).filter((p) => p.id !== state.playerId);
 
   return (
     <div className="min-h-screen relative flex items-center justify-center p-4">
@@ -34,7 +43,7 @@ export default function RoleReveal() {
               : '0 0 20px #ef4444, 0 0 40px #dc2626'
           }}
         >
-          {isCivilian ? "CREWMATE" : "IMPOSTER"}
+          {roleTitle}
         </motion.h1>
 
         {/* Description Panel */}
@@ -45,8 +54,23 @@ export default function RoleReveal() {
           className="panel-space max-w-2xl mx-auto"
         >
           <p className="font-game text-3xl leading-relaxed text-gray-900">
-            {isCivilian ? "CREWMATE" : "IMPOSTER" }
+            {roleTitle}
           </p>
+          {state.role === 'DETECTIVE' && (
+            <p className="font-game text-xl text-green-700 mt-4">
+              You're with the crew, and once this game you can investigate a player's test runs.
+            </p>
+          )}
+          {state.role === 'JESTER' && (
+            <p className="font-game text-xl text-purple-700 mt-4">
+              You're on no one's side. Get yourself voted out to win.
+            </p>
+          )}
+          {!isCivilian && teammates.length > 0 && (
+            <p className="font-game text-xl text-red-700 mt-4">
+              Fellow imposters: {teammates.map((p) => p.username).join(', ')}
+            </p>
+          )}
         </motion.div>
 
         {/* Starting Soon with Spinner */}
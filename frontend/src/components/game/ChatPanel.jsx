@@ -1,13 +1,14 @@
 'use i18n';
 import React from 'react';
 import { motion, AnimatePresence } from 'framer-motion';
+import { chatText } from '../../utils/translations';
 
 // 🔥 Separate ChatBubble component with translation animation
 const ChatBubble = ({ message, userLang }) => {
   return (
-    <div className="chat-message-space relative">
-      <span className="font-game text-base font-bold text-orange">
-        {message.username}:
+    <div className={`chat-message-space relative ${message.ghost ? 'opacity-70 italic' : ''}`}>
+      <span className={`font-game text-base font-bold ${message.ghost ? 'text-purple-600' : message.impostor ? 'text-red-600' : 'text-orange'}`}>
+        {message.ghost && '👻 '}{message.impostor && '🔪 '}{message.username}:
       </span>
       
       {/* AnimatePresence for smooth translation morphing */}
@@ -22,9 +23,7 @@ const ChatBubble = ({ message, userLang }) => {
             className="font-game text-base text-gray-900"
           >
             {/* Show translation if available and matches user lang, else show original */}
-            {message.translations && message.translations[userLang] 
-              ? message.translations[userLang] 
-              : message.text}
+            {chatText(message, userLang)}
           </motion.span>
         </AnimatePresence>
       </div>
@@ -40,7 +39,10 @@ export default function ChatPanel({
   isEliminated, 
   isFrozen,
   chatEndRef,
-  userLang = 'en' // 🔥 NEW: Pass user language from parent
+  userLang = 'en', // 🔥 NEW: Pass user language from parent
+  canTeamChat = false,
+  teamChat = false,
+  onToggleTeamChat,
 }) {
   return (
     <div className="panel-space h-64 flex flex-col"> {/* 🔥 CHANGED: Increased height from h-48 to h-64 */}
@@ -51,7 +53,7 @@ export default function ChatPanel({
           <div key={msg.messageId || index}> {/* 🔥 Use messageId to prevent duplicates */}
             {msg.system ? (
               <div className="chat-message-space">
-                <span className="font-game text-sm italic text-gray-600">{msg.text}</span>
+                <span className="font-game text-sm italic text-gray-600">{chatText(msg, userLang)}</span>
               </div>
             ) : (
               <ChatBubble message={msg} userLang={userLang} />
@@ -61,26 +63,35 @@ export default function ChatPanel({
         <div ref={chatEndRef} />
       </div>
 
-      {!isEliminated && (
-        <div className="flex gap-2">
-          <input
-            type="text"
-            value={chatMessage}
-            onChange={onMessageChange}
-            onKeyPress={(e) => e.key === 'Enter' && onSendMessage()}
-            placeholder="Type message..."
-            className="input-space flex-1 text-base py-2" 
-            disabled={isFrozen}
-          />
+      {/* Sabotage freezes the living; ghosts keep whispering to each other */}
+      <div className="flex gap-2">
+        {/* With more than one impostor, the team can talk privately */}
+        {canTeamChat && (
           <button
-            onClick={onSendMessage}
-            disabled={isFrozen}
-            className={`btn-space green text-xs px-4 ${isFrozen ? 'opacity-50 cursor-not-allowed' : ''}`}
+            onClick={onToggleTeamChat}
+            className={`btn-space ${teamChat ? 'red' : ''} text-xs px-2`}
+            title="Talk to your impostor team only"
           >
-            SEND
+            🔪
           </button>
-        </div>
-      )}
+        )}
+        <input
+          type="text"
+          value={chatMessage}
+          onChange={onMessageChange}
+          onKeyPress={(e) => e.key === 'Enter' && onSendMessage()}
+          placeholder={isEliminated ? 'Whisper to other ghosts...' : teamChat ? 'Plot with your team...' : 'Type message...'}
+          className="input-space flex-1 text-base py-2" 
+          disabled={isFrozen && !isEliminated}
+        />
+        <button
+          onClick={onSendMessage}
+          disabled={isFrozen && !isEliminated}
+          className={`btn-space ${isEliminated ? 'purple' : 'green'} text-xs px-4 ${isFrozen && !isEliminated ? 'opacity-50 cursor-not-allowed' : ''}`}
+        >
+          {isEliminated ? '👻 SEND' : 'SEND'}
+        </button>
+      </div>
     </div>
   );
 }
\ No newline at end of file
@@ -1,10 +1,13 @@
 'use i18n';
 import React from 'react';
-import { Loader2, Terminal, Snowflake } from 'lucide-react';
+import { Loader2, Terminal, Snowflake, Lightbulb } from 'lucide-react';
 
 export default function ControlPanel({ 
   stageTitle, 
   onRunTests, 
+  onHint,
+  hintsLeft,
+  hintPenalty,
   isBusy, 
   isFrozen, 
   isEliminated, 
@@ -44,6 +47,19 @@ export default function ControlPanel({
         )}
       </button>
 
+      {onHint && (
+        <button
+          onClick={onHint}
+          disabled={isEliminated || hintsLeft === 0}
+          className={`btn-space w-full text-sm flex items-center justify-center gap-2 mt-2 ${
+            isEliminated || hintsLeft === 0 ? 'opacity-50 cursor-not-allowed' : ''
+          }`}
+        >
+          <Lightbulb className="w-4 h-4" />
+          Hint ({hintsLeft} left, -{hintPenalty}s)
+        </button>
+      )}
+
       {/* Terminal Output */}
       <div className="mt-4 bg-black border-4 border-brown-dark p-3 h-64 overflow-y-auto font-mono text-xs flex-1">
         {terminalLogs.length === 0 ? (
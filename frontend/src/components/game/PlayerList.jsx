@@ -1,8 +1,9 @@
 'use i18n';
 import React from 'react';
+import { Search } from 'lucide-react';
 import Ship, { getShipType } from '../Ship';
 
-export default function PlayersList({ players, currentPlayerId }) {
+export default function PlayersList({ players, currentPlayerId, onInvestigate, investigation }) {
   const playerList = Object.values(players || {});
   const alivePlayers = playerList.filter(p => p.isAlive);
   const eliminatedPlayers = playerList.filter(p => p.isEliminated);
@@ -14,12 +15,27 @@ export default function PlayersList({ players, currentPlayerId }) {
       <div className="space-y-2">
         <p className="font-game text-lg text-green-600">ALIVE</p>
         {alivePlayers.map((player, index) => (
-          <div key={player.id} className="flex items-center gap-2">
+          <div key={player.id} className={`flex items-center gap-2 ${player.disconnected ? 'opacity-50' : ''}`}>
             <Ship type={getShipType(playerList.indexOf(player))} size="sm" />
             <span className="font-game text-sm text-gray-900">
               {player.username}
               {player.id === currentPlayerId && ' (You)'}
+              {player.disconnected && ' (reconnecting...)'}
             </span>
+            {investigation?.targetID === player.id && (
+              <span className={`font-pixel text-xs ml-auto ${investigation.suspicious ? 'text-red-600' : 'text-green-600'}`}>
+                {investigation.suspicious ? 'SUSPICIOUS' : 'CLEAR'}
+              </span>
+            )}
+            {onInvestigate && player.id !== currentPlayerId && (
+              <button
+                onClick={() => onInvestigate(player.id)}
+                className="ml-auto text-gray-700 hover:text-gray-900"
+                title="Investigate (once per game)"
+              >
+                <Search className="w-4 h-4" />
+              </button>
+            )}
           </div>
         ))}
       </div>
@@ -1,11 +1,14 @@
 'use i18n';
 import React, { useState, useEffect } from 'react';
 import { motion } from 'framer-motion';
-import { Snowflake, Bug, Clock } from 'lucide-react';
+import { Snowflake, Bug, Clock, Shuffle, Hourglass, Radiation } from 'lucide-react';
 
 export default function SabotagePanel({ onSabotage, isFrozen, ws }) {
   const [freezeCooldown, setFreezeCooldown] = useState(0);
   const [corruptCooldown, setCorruptCooldown] = useState(0);
+  const [swapCooldown, setSwapCooldown] = useState(0);
+  const [drainCooldown, setDrainCooldown] = useState(0);
+  const [criticalCooldown, setCriticalCooldown] = useState(0);
   const [activeSabotage, setActiveSabotage] = useState(null);
 
   // 🔥 NEW: Listen for cooldown messages from server
@@ -23,6 +26,12 @@ export default function SabotagePanel({ onSabotage, isFrozen, ws }) {
             setFreezeCooldown(remaining);
           } else if (activeSabotage === 'CORRUPT') {
             setCorruptCooldown(remaining);
+          } else if (activeSabotage === 'SWAP') {
+            setSwapCooldown(remaining);
+          } else if (activeSabotage === 'TIMER_DRAIN') {
+            setDrainCooldown(remaining);
+          } else if (activeSabotage === 'CRITICAL') {
+            setCriticalCooldown(remaining);
           }
         }
       } catch (error) {
@@ -53,6 +62,33 @@ export default function SabotagePanel({ onSabotage, isFrozen, ws }) {
     }
   }, [corruptCooldown]);
 
+  useEffect(() => {
+    if (swapCooldown > 0) {
+      const timer = setInterval(() => {
+        setSwapCooldown(prev => Math.max(0, prev - 1));
+      }, 1000);
+      return () => clearInterval(timer);
+    }
+  }, [swapCooldown]);
+
+  useEffect(() => {
+    if (drainCooldown > 0) {
+      const timer = setInterval(() => {
+        setDrainCooldown(prev => Math.max(0, prev - 1));
+      }, 1000);
+      return () => clearInterval(timer);
+    }
+  }, [drainCooldown]);
+
+  useEffect(() => {
+    if (criticalCooldown > 0) {
+      const timer = setInterval(() => {
+        setCriticalCooldown(prev => Math.max(0, prev - 1));
+      }, 1000);
+      return () => clearInterval(timer);
+    }
+  }, [criticalCooldown]);
+
   const handleFreeze = () => {
     if (freezeCooldown > 0 || isFrozen) return;
     setActiveSabotage('FREEZE');
@@ -67,6 +103,27 @@ export default function SabotagePanel({ onSabotage, isFrozen, ws }) {
     onSabotage('CORRUPT');
   };
 
+  const handleSwap = () => {
+    if (swapCooldown > 0) return;
+    setActiveSabotage('SWAP');
+    setSwapCooldown(25); // 10s cooldown + 15s active = 25s total
+    onSabotage('SWAP');
+  };
+
+  const handleDrain = () => {
+    if (drainCooldown > 0) return;
+    setActiveSabotage('TIMER_DRAIN');
+    setDrainCooldown(10); // 10s cooldown
+    onSabotage('TIMER_DRAIN');
+  };
+
+  const handleCritical = () => {
+    if (criticalCooldown > 0) return;
+    setActiveSabotage('CRITICAL');
+    setCriticalCooldown(40); // 10s cooldown + up to 30s active = 40s total
+    onSabotage('CRITICAL');
+  };
+
   return (
     <motion.div
       className="panel-space flex-1"
@@ -136,6 +193,99 @@ export default function SabotagePanel({ onSabotage, isFrozen, ws }) {
             />
           )}
         </div>
+
+        {/* SWAP Button with Cooldown */}
+        <div className="relative">
+          <button
+            onClick={handleSwap}
+            disabled={swapCooldown > 0}
+            className={`w-full btn-space red text-sm flex items-center justify-center gap-2 ${
+              swapCooldown > 0 ? 'opacity-50 cursor-not-allowed' : ''
+            }`}
+          >
+            <Shuffle className="w-4 h-4" />
+            {swapCooldown > 0 ? (
+              <>
+                <Clock className="w-4 h-4 animate-spin" />
+                {swapCooldown}s
+              </>
+            ) : (
+              'Cross Wires (15s)'
+            )}
+          </button>
+
+          {/* Cooldown Progress Bar */}
+          {swapCooldown > 0 && (
+            <motion.div
+              className="absolute bottom-0 left-0 h-1 bg-blue-500 rounded-b"
+              initial={{ width: '100%' }}
+              animate={{ width: '0%' }}
+              transition={{ duration: swapCooldown, ease: 'linear' }}
+            />
+          )}
+        </div>
+
+        {/* TIMER_DRAIN Button with Cooldown */}
+        <div className="relative">
+          <button
+            onClick={handleDrain}
+            disabled={drainCooldown > 0}
+            className={`w-full btn-space red text-sm flex items-center justify-center gap-2 ${
+              drainCooldown > 0 ? 'opacity-50 cursor-not-allowed' : ''
+            }`}
+          >
+            <Hourglass className="w-4 h-4" />
+            {drainCooldown > 0 ? (
+              <>
+                <Clock className="w-4 h-4 animate-spin" />
+                {drainCooldown}s
+              </>
+            ) : (
+              'Drain Power'
+            )}
+          </button>
+
+          {/* Cooldown Progress Bar */}
+          {drainCooldown > 0 && (
+            <motion.div
+              className="absolute bottom-0 left-0 h-1 bg-blue-500 rounded-b"
+              initial={{ width: '100%' }}
+              animate={{ width: '0%' }}
+              transition={{ duration: drainCooldown, ease: 'linear' }}
+            />
+          )}
+        </div>
+
+        {/* CRITICAL Button with Cooldown */}
+        <div className="relative">
+          <button
+            onClick={handleCritical}
+            disabled={criticalCooldown > 0}
+            className={`w-full btn-space red text-sm flex items-center justify-center gap-2 ${
+              criticalCooldown > 0 ? 'opacity-50 cursor-not-allowed' : ''
+            }`}
+          >
+            <Radiation className="w-4 h-4" />
+            {criticalCooldown > 0 ? (
+              <>
+                <Clock className="w-4 h-4 animate-spin" />
+                {criticalCooldown}s
+              </>
+            ) : (
+              'Reactor Meltdown (30s)'
+            )}
+          </button>
+
+          {/* Cooldown Progress Bar */}
+          {criticalCooldown > 0 && (
+            <motion.div
+              className="absolute bottom-0 left-0 h-1 bg-blue-500 rounded-b"
+              initial={{ width: '100%' }}
+              animate={{ width: '0%' }}
+              transition={{ duration: criticalCooldown, ease: 'linear' }}
+            />
+          )}
+        </div>
       </div>
       
       <div className="mt-4 p-3 bg-red-100 border-2 border-red-500 rounded">
@@ -145,6 +295,12 @@ export default function SabotagePanel({ onSabotage, isFrozen, ws }) {
           <br />
           • Corrupt: Adds code errors
           <br />
+          • Cross Wires: Swaps two crewmates' cursors for 15s
+          <br />
+          • Drain Power: Takes time off the clock (limited uses)
+          <br />
+          • Meltdown: Win unless 2 crewmates repair within 30s
+          <br />
           • 10s cooldown between uses
         </p>
       </div>
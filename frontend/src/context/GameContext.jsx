@@ -2,6 +2,12 @@ import React, { createContext, useContext, useReducer } from 'react';
 
 const GameContext = createContext();
 
+// Remember how far the server's clock is from ours when the timer arrives,
+// so countdowns to its deadline line up across players.
+function withClockOffset(timer) {
+  return { ...timer, clockOffset: timer.serverTime - Date.now() };
+}
+
 const initialState = {
   // Connection
   ws: null,
@@ -15,6 +21,7 @@ const initialState = {
   // Room info
   roomId: null,
   players: {},
+  settings: null,
   votes: {},
   votesStatus: {},
   
@@ -22,12 +29,23 @@ const initialState = {
   phase: 'LOBBY',
   currentStage: 0,
   timerSeconds: 120,
+  timer: null,
   tasksComplete: {},
   role: null,
+  impostorTeam: [],
+  detectiveResult: null,
   isEliminated: false,
+  scores: [],
+  mvp: null,
+  editShare: [],
   
   // Current task data
   task: null,
+
+  // Personal-tasks games: the crew's task bar and whether this player's
+  // own task is done
+  taskProgress: null,
+  taskDone: false,
   
   // Test Execution State
   isTerminalBusy: false,
@@ -81,6 +99,23 @@ function gameReducer(state, action) {
     case 'SET_ROLE':
       return { ...state, role: action.payload };
     
+    case 'SET_SETTINGS':
+      return { ...state, settings: action.payload };
+    
+    case 'SET_IMPOSTOR_TEAM':
+      return { ...state, impostorTeam: action.payload };
+    
+    case 'SET_DETECTIVE_RESULT':
+      return { ...state, detectiveResult: action.payload };
+    
+    case 'SET_SCORES':
+      return {
+        ...state,
+        scores: action.payload.scores || [],
+        mvp: action.payload.mvp || null,
+        editShare: action.payload.editShare || [],
+      };
+    
     case 'SET_ELIMINATED':
       return { ...state, isEliminated: action.payload };
     
@@ -95,7 +130,9 @@ function gameReducer(state, action) {
         task, 
         currentStage, 
         timerSeconds, 
+        timer,
         tasksComplete,
+        taskProgress,
         testRunning,
         testRunner 
       } = action.payload;
@@ -113,7 +150,9 @@ function gameReducer(state, action) {
         task: task || state.task,
         currentStage: currentStage !== undefined ? currentStage : state.currentStage,
         timerSeconds: timerSeconds !== undefined ? timerSeconds : state.timerSeconds,
+        timer: timer ? withClockOffset(timer) : state.timer,
         tasksComplete: tasksComplete || state.tasksComplete,
+        taskProgress: taskProgress || state.taskProgress,
         role: currentPlayer?.role || state.role,
         isEliminated: currentPlayer?.isEliminated || state.isEliminated,
         isTerminalBusy: testRunning || false,
@@ -130,7 +169,18 @@ function gameReducer(state, action) {
       return {
         ...state,
         timerSeconds: action.payload.timerSeconds,
+        timer: withClockOffset(action.payload),
+      };
+    
+    case 'SET_ASSIGNED_TASK':
+      return {
+        ...state,
+        task: action.payload.task,
+        taskDone: action.payload.done,
       };
+
+    case 'SET_TASK_PROGRESS':
+      return { ...state, taskProgress: action.payload };
     
     case 'CHANGE_SCENE':
       return {
@@ -139,6 +189,7 @@ function gameReducer(state, action) {
         transitionFrom: action.payload.fromStage,
         transitionTo: action.payload.toStage,
         terminalLogs: [],
+        hintsLeft: undefined,
       };
     
     case 'TRANSITION_COMPLETE':
@@ -161,9 +212,42 @@ function gameReducer(state, action) {
         ],
       };
     
+    case 'HINT':
+      return {
+        ...state,
+        hintsLeft: action.payload.hintsLeft,
+        terminalLogs: [
+          ...state.terminalLogs.slice(-50),
+          `💡 Hint ${action.payload.tier} for ${action.payload.requestedBy} (-${action.payload.penaltySeconds}s): ${action.payload.text}`,
+        ],
+      };
+
+    case 'TEST_STATUS':
+      return {
+        ...state,
+        terminalLogs: [
+          ...state.terminalLogs.slice(-50),
+          action.payload.status === 'QUEUED'
+            ? '⏳ Waiting for a free test runner...'
+            : '⚙️ Compiling and running tests...',
+        ],
+      };
+
     case 'TEST_COMPLETE':
       const passed = action.payload.passed;
       const stage = action.payload.stage;
+      const details = [];
+
+      if (action.payload.compiled === false) {
+        details.push('🛠️ Compilation failed:');
+        details.push(...(action.payload.compilerOutput || '').split('\n').slice(0, 20));
+      }
+      (action.payload.tests || []).forEach((test) => {
+        details.push(`  ${test.passed ? '✔' : '✘'} ${test.name}${test.message ? ` - ${test.message}` : ''}`);
+      });
+      if (action.payload.timedOut) {
+        details.push('⏱️ Execution hit the time limit');
+      }
       
       return {
         ...state,
@@ -172,6 +256,7 @@ function gameReducer(state, action) {
         currentRunnerID: null,
         terminalLogs: [
           ...state.terminalLogs.slice(-50),
+          ...details,
           `${passed ? '✅' : '❌'} Stage ${stage} test ${passed ? 'PASSED' : 'FAILED'}`,
           passed ? `🚀 Advancing to Stage ${stage + 1}...` : '🔄 Try again!',
         ],
@@ -241,6 +326,35 @@ function gameReducer(state, action) {
         )
       };
     
+    case 'REMATCH_STARTED':
+      // Back to the lobby: the connection, room, players and chat stay.
+      return {
+        ...state,
+        phase: 'LOBBY',
+        votes: {},
+        votesStatus: {},
+        currentStage: 0,
+        timer: null,
+        tasksComplete: {},
+        role: null,
+        impostorTeam: [],
+        detectiveResult: null,
+        isEliminated: false,
+        scores: [],
+        mvp: null,
+        editShare: [],
+        task: null,
+        taskProgress: null,
+        taskDone: false,
+        isTerminalBusy: false,
+        currentRunner: null,
+        currentRunnerID: null,
+        terminalLogs: [],
+        isTransitioning: false,
+        transitionFrom: null,
+        transitionTo: null,
+      };
+
     case 'RESET':
       return {
         ...initialState,
@@ -0,0 +1,32 @@
+import { useEffect, useState } from 'react';
+
+/** @typedef {import('../types/messages').Timer} Timer */
+
+// The server only sends the game clock when it starts, pauses or resumes,
+// so the countdown runs here from the deadline. clockOffset corrects for
+// this machine's clock disagreeing with the server's.
+function secondsLeft(timer, fallbackSeconds) {
+  if (!timer) return fallbackSeconds;
+  if (timer.paused || !timer.deadline) {
+    return Math.ceil(timer.remainingMs / 1000);
+  }
+  const now = Date.now() + (timer.clockOffset || 0);
+  return Math.max(0, Math.ceil((timer.deadline - now) / 1000));
+}
+
+/** @param {Timer & { clockOffset?: number }} timer */
+export function useCountdown(timer, fallbackSeconds) {
+  const [seconds, setSeconds] = useState(() => secondsLeft(timer, fallbackSeconds));
+
+  useEffect(() => {
+    setSeconds(secondsLeft(timer, fallbackSeconds));
+    if (!timer || timer.paused || !timer.deadline) return;
+
+    const interval = setInterval(() => {
+      setSeconds(secondsLeft(timer, fallbackSeconds));
+    }, 250);
+    return () => clearInterval(interval);
+  }, [timer, fallbackSeconds]);
+
+  return seconds;
+}
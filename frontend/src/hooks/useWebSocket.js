@@ -1,6 +1,9 @@
 import { useEffect } from 'react';
 import { useGame } from '../context/GameContext';
 
+// The game protocol this client speaks; see protocolVersion in the backend
+const PROTOCOL_VERSION = 2;
+
 export function useWebSocket(roomId) {
   const { state, dispatch } = useGame();
 
@@ -8,7 +11,16 @@ export function useWebSocket(roomId) {
     if (!roomId) return;
 
     const WS_BASE = import.meta.env.VITE_WS_URL || 'ws://localhost:8080';
-    const wsUrl = `${WS_BASE}/ws?room=${roomId}&userId=${state.playerId || ''}`;
+
+    // A saved session lets a refreshed tab take its seat back mid-game
+    const sessionKey = `session:${roomId}`;
+    const saved = JSON.parse(sessionStorage.getItem(sessionKey) || 'null');
+    const userId = state.playerId || saved?.playerID || '';
+    const session = saved && saved.playerID === userId ? saved.token : '';
+    // A guest's token proves the guest ID is ours, so the server issues a session for it
+    const guest = JSON.parse(localStorage.getItem('guestToken') || 'null');
+    const guestToken = guest && guest.playerID === userId ? guest.token : '';
+    const wsUrl = `${WS_BASE}/ws?room=${roomId}&userId=${encodeURIComponent(userId)}&session=${encodeURIComponent(session)}&guestToken=${encodeURIComponent(guestToken)}&protocol=${PROTOCOL_VERSION}`;
 
     console.log('🔌 Connecting to WebSocket:', wsUrl);
 
@@ -22,6 +34,7 @@ export function useWebSocket(roomId) {
 
     ws.onmessage = (event) => {
       try {
+        /** @type {import('../types/messages').ServerMessage} */
         const message = JSON.parse(event.data);
         console.log('📨 Received:', message.type, message.data);
 
@@ -29,20 +42,42 @@ export function useWebSocket(roomId) {
           case 'INIT':
             console.log('🎯 Player initialized:', message.data.playerID);
             dispatch({ type: 'SET_PLAYER_ID', payload: message.data.playerID });
+            if (message.data.guestToken) {
+              localStorage.setItem('guestToken', JSON.stringify({ playerID: message.data.playerID, token: message.data.guestToken }));
+            }
+            if (message.data.outdated) {
+              console.warn('⚠️ Server speaks protocol', message.data.protocolVersion, '- this client is out of date');
+            }
             
             // Send JOIN message
             ws.send(JSON.stringify({
               type: 'JOIN',
-              data: { username: state.username }
+              data: { username: state.username, language: state.language || 'en' }
             }));
             break;
 
+          case 'SESSION':
+            sessionStorage.setItem(sessionKey, JSON.stringify(message.data));
+            break;
+
           case 'SELF':
             console.log('👤 Self data received:', message.data);
             dispatch({ type: 'SET_ROLE', payload: message.data.role });
             dispatch({ type: 'SET_ELIMINATED', payload: message.data.isEliminated });
             break;
 
+          case 'SETTINGS_UPDATED':
+            dispatch({ type: 'SET_SETTINGS', payload: message.data });
+            break;
+
+          case 'IMPOSTOR_TEAM':
+            dispatch({ type: 'SET_IMPOSTOR_TEAM', payload: message.data.impostors });
+            break;
+
+          case 'DETECTIVE_RESULT':
+            dispatch({ type: 'SET_DETECTIVE_RESULT', payload: message.data });
+            break;
+
           case 'PLAYER_LIST':
             console.log('👥 Player list updated');
             dispatch({ type: 'SET_PLAYERS', payload: message.data });
@@ -68,6 +103,8 @@ export function useWebSocket(roomId) {
                 text: chatData.text,
                 playerId: chatData.playerId,
                 translations: chatData.translations || {},
+                key: chatData.key,
+                params: chatData.params,
                 timestamp: chatData.timestamp || Date.now(),
                 system: chatData.system || false,
                 translationId: Date.now(), // For animation trigger
@@ -75,6 +112,58 @@ export function useWebSocket(roomId) {
             });
             break;
 
+          // Sent on every join; lines already on screen are skipped as
+          // duplicates.
+          case 'CHAT_HISTORY':
+            message.data.messages.forEach(line => {
+              dispatch({
+                type: 'ADD_MESSAGE',
+                payload: {
+                  messageId: line.messageId,
+                  username: line.username,
+                  text: line.text,
+                  playerId: line.playerId,
+                  translations: line.translations || {},
+                  key: line.key,
+                  params: line.params,
+                  timestamp: line.timestamp || Date.now(),
+                  system: line.system || false,
+                }
+              });
+            });
+            break;
+
+          // Only the impostor team (and ghosts, if the host allows it)
+          // receive these.
+          case 'IMPOSTOR_CHAT':
+            dispatch({
+              type: 'ADD_MESSAGE',
+              payload: {
+                messageId: message.data.messageId,
+                username: message.data.username,
+                text: message.data.text,
+                playerId: message.data.playerId,
+                timestamp: message.data.timestamp,
+                impostor: true,
+              }
+            });
+            break;
+
+          // Only ghosts receive these; they sit in the same chat log.
+          case 'GHOST_CHAT':
+            dispatch({
+              type: 'ADD_MESSAGE',
+              payload: {
+                messageId: message.data.messageId,
+                username: message.data.username,
+                text: message.data.text,
+                playerId: message.data.playerId,
+                timestamp: message.data.timestamp,
+                ghost: true,
+              }
+            });
+            break;
+
           // 🔥 REMOVED: TRANSLATION_UPDATE is no longer needed
           // Messages come with translations already included
           
@@ -96,6 +185,14 @@ export function useWebSocket(roomId) {
             dispatch({ type: 'TEST_LOCKED', payload: message.data });
             break;
 
+          case 'TEST_STATUS':
+            dispatch({ type: 'TEST_STATUS', payload: message.data });
+            break;
+
+          case 'HINT':
+            dispatch({ type: 'HINT', payload: message.data });
+            break;
+
           case 'TEST_COMPLETE':
             console.log('✅ Tests complete:', message.data.passed);
             dispatch({ type: 'TEST_COMPLETE', payload: message.data });
@@ -111,6 +208,75 @@ export function useWebSocket(roomId) {
             dispatch({ type: 'ERROR_BUSY', payload: message.data });
             break;
 
+          case 'SHUTDOWN_NOTICE':
+            console.log('🔧 Server shutting down in', message.data.drainSeconds, 's');
+            dispatch({
+              type: 'ADD_MESSAGE',
+              payload: {
+                messageId: `shutdown-${Date.now()}`,
+                username: 'System',
+                text: message.data.message,
+                translations: {},
+                timestamp: Date.now(),
+                system: true,
+                translationId: Date.now(),
+              }
+            });
+            break;
+
+          case 'EMERGENCY_UNAVAILABLE': {
+            const { message: reason, retryAfterMs, callsLeft } = message.data;
+            const wait = retryAfterMs ? ` (try again in ${Math.ceil(retryAfterMs / 1000)}s)` : '';
+            dispatch({
+              type: 'ADD_MESSAGE',
+              payload: {
+                messageId: `emergency-${Date.now()}`,
+                username: 'System',
+                text: `🚨 ${reason}${wait} - ${callsLeft} emergency meeting${callsLeft === 1 ? '' : 's'} left`,
+                translations: {},
+                timestamp: Date.now(),
+                system: true,
+                translationId: Date.now(),
+              }
+            });
+            break;
+          }
+
+          case 'ANNOUNCEMENT':
+            console.log('📢 Announcement:', message.data.message);
+            dispatch({
+              type: 'ADD_MESSAGE',
+              payload: {
+                messageId: `announcement-${Date.now()}`,
+                username: 'System',
+                text: '📢 ' + message.data.message,
+                translations: {},
+                timestamp: Date.now(),
+                system: true,
+                translationId: Date.now(),
+              }
+            });
+            break;
+
+          case 'HOST_MUTED':
+            dispatch({
+              type: 'ADD_MESSAGE',
+              payload: {
+                messageId: `host-muted-${Date.now()}`,
+                username: 'System',
+                text: '🔇 ' + message.data.message,
+                translations: {},
+                timestamp: Date.now(),
+                system: true,
+                translationId: Date.now(),
+              }
+            });
+            break;
+
+          case 'ERROR_RATE_LIMITED':
+            console.log('⏳ Rate limited:', message.data.messageType);
+            break;
+
           case 'CHANGE_SCENE':
             console.log('🎬 Scene transition:', message.data);
             dispatch({ type: 'CHANGE_SCENE', payload: message.data });
@@ -124,15 +290,33 @@ export function useWebSocket(roomId) {
             dispatch({ type: 'SYNC_TIMER', payload: message.data });
             break;
 
+          case 'ASSIGNED_TASK':
+            dispatch({ type: 'SET_ASSIGNED_TASK', payload: message.data });
+            break;
+
+          case 'TASK_PROGRESS':
+            dispatch({ type: 'SET_TASK_PROGRESS', payload: message.data });
+            break;
+
           case 'VOTE_UPDATE':
             dispatch({ type: 'UPDATE_VOTES', payload: message.data });
             break;
 
           case 'GAME_ENDED':
             console.log('🏁 Game ended:', message.data.reason);
+            // Roles are hidden until now; the final state reveals them.
+            if (message.data.finalState?.players) {
+              dispatch({ type: 'SET_PLAYERS', payload: message.data.finalState.players });
+            }
+            dispatch({ type: 'SET_SCORES', payload: message.data });
             dispatch({ type: 'SET_PHASE', payload: 'GAME_OVER' });
             break;
 
+          case 'REMATCH_STARTED':
+            console.log('🔁 Rematch started by', message.data.hostName);
+            dispatch({ type: 'REMATCH_STARTED' });
+            break;
+
           case 'ERROR_ACCESS_DENIED':
             console.log('🚫 Access denied:', message.data.reason);
             alert(message.data.message);
@@ -165,6 +349,10 @@ export function useWebSocket(roomId) {
     };
   }, [roomId]);
 
+  /**
+   * @param {import('../types/messages').ClientMessageType} type
+   * @param {object} [data]
+   */
   const sendMessage = (type, data) => {
     if (state.ws && state.ws.readyState === WebSocket.OPEN) {
       console.log('📤 Sending:', type, data);
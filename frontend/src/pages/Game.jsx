@@ -142,7 +142,15 @@ export default function Game() {
     
     switch (state.phase) {
       case 'LOBBY':
-        return <Lobby onStartGame={handleStartGame} />;
+        return (
+          <Lobby
+            onStartGame={handleStartGame}
+            onUpdateSettings={(settings) => sendMessage('ROOM_SETTINGS', settings)}
+            onRemovePlayer={(targetID, ban) => sendMessage(ban ? 'BAN_PLAYER' : 'KICK_PLAYER', { targetID })}
+            onMutePlayer={(targetID, muted) => sendMessage('MUTE_PLAYER', { targetID, muted })}
+            onAddBot={() => sendMessage('ADD_BOT', {})}
+          />
+        );
       
       case 'ROLE_REVEAL':
         return <RoleReveal />;
@@ -153,10 +161,16 @@ export default function Game() {
         return <CodeEditor onEmergency={handleEmergency} />;
       
       case 'DISCUSSION':
-        return <Discussion onVote={handleVote} />;
+        return <Discussion onVote={handleVote} onSlowMode={(seconds) => sendMessage('SLOW_MODE', { seconds })} />;
       
       case 'GAME_OVER':
-        return <EndGame reason={endReason} impostorId={endImpostorId} />;
+        return (
+          <EndGame
+            reason={endReason}
+            impostorId={endImpostorId}
+            onRematch={() => sendMessage('REMATCH', {})}
+          />
+        );
       
       default:
         return (
@@ -15,14 +15,25 @@ export default function Home() {
   const [roomInput, setRoomInput] = useState('');
   const { setLocale } = useLingoContext();
 
-  const handleCreateRoom = () => {
+  const handleCreateRoom = async () => {
     if (!state.username.trim()) {
       alert('Please enter your name!');
       return;
     }
-    
-    const roomId = Math.random().toString(36).substring(2, 8).toUpperCase();
-    navigate(`/room/${roomId}`);
+
+    const API_BASE = import.meta.env.VITE_API_URL || 'http://localhost:8080';
+    try {
+      const res = await fetch(`${API_BASE}/api/rooms`, { method: 'POST' });
+      const body = await res.json();
+      if (!res.ok) {
+        alert(body.error || 'Could not create a room, please try again');
+        return;
+      }
+      navigate(`/room/${body.code}`);
+    } catch (err) {
+      console.error('❌ Failed to create room:', err);
+      alert('Could not reach the server, please try again');
+    }
   };
 
   const handleJoinRoom = () => {
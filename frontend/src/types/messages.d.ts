@@ -0,0 +1,825 @@
+// Code generated by `main wsschema` from backend/wsschema. DO NOT EDIT.
+// Run `go generate` in backend/ after changing the protocol.
+
+export interface Player {
+  id: string;
+  username: string;
+  /** Blank until roles are dealt, and for spectators. UNKNOWN to non-impostors for everyone but themselves until GAME_ENDED; impostors see a DETECTIVE or JESTER as CIVILIAN. */
+  role: '' | 'CIVILIAN' | 'IMPOSTER' | 'DETECTIVE' | 'JESTER' | 'UNKNOWN';
+  isHost: boolean;
+  isEliminated: boolean;
+  isAlive: boolean;
+  /** Set while a player who dropped mid-game has their seat held. */
+  disconnected?: boolean;
+  /** Set while the host has the player muted. */
+  muted?: boolean;
+  /** The locale the player reads chat in, from JOIN. */
+  language?: string;
+  /** Equipped cosmetics of signed-in players. */
+  cosmetics?: Cosmetics;
+}
+
+/** Render-ready values; a missing slot means the default look. */
+export interface Cosmetics {
+  /** A CSS color. */
+  nameColor?: string;
+  avatar?: string;
+  /** A Monaco theme name. */
+  editorTheme?: string;
+}
+
+export interface Cosmetic {
+  id: string;
+  slot: 'name_color' | 'avatar' | 'editor_theme';
+  name: string;
+  value: string;
+  requirement?: string;
+  unlock: Unlock;
+}
+
+/** Thresholds a player must reach; absent fields aren't required. */
+export interface Unlock {
+  xp?: number;
+  gamesPlayed?: number;
+  gamesWon?: number;
+  impostorWins?: number;
+  correctVotes?: number;
+  rankedGames?: number;
+}
+
+export interface Task {
+  id: string;
+  stage: number;
+  title: string;
+  description: string;
+  template: string;
+  /** What the template is written in. Absent means Java. */
+  language?: 'java' | 'python' | 'javascript' | 'go';
+  category?: 'DSA' | 'OOPS' | 'DEBUGGING';
+  difficulty?: 'EASY' | 'MEDIUM' | 'HARD';
+  titleTranslations?: Record<string, string>;
+  descriptionTranslations?: Record<string, string>;
+}
+
+/** The game clock. Clients count down from it; the server sends it when the clock starts, pauses or resumes, and every 5 seconds while it runs. Times are Unix milliseconds. */
+export interface Timer {
+  /** When the clock runs out if it keeps running. Absent before the game clock starts. */
+  deadline?: number;
+  paused: boolean;
+  /** When the clock was stopped, while paused. */
+  pausedAt?: number;
+  /** Time left as of serverTime. */
+  remainingMs: number;
+  /** The server's clock when this was sent, for correcting skew. */
+  serverTime: number;
+  /** Set in the SYNC_TIMER sent when a TIMER_DRAIN sabotage took this much off the clock. */
+  drainedSeconds?: number;
+  /** Set in the SYNC_TIMER sent when a HINT took this much off the clock. */
+  hintPenaltySeconds?: number;
+  /** Whole seconds left as of serverTime. */
+  timerSeconds: number;
+}
+
+/** Full room state, sent on every phase change. */
+export interface GameState {
+  phase: 'LOBBY' | 'ROLE_REVEAL' | 'TASK_1' | 'TASK_2' | 'TASK_3' | 'DISCUSSION' | 'GAME_OVER';
+  currentStage: number;
+  /** Snapshot of timer.timerSeconds. */
+  timerSeconds: number;
+  timer: Timer;
+  /** Keyed by stage number. */
+  tasksComplete: Record<string, boolean>;
+  /** Keyed by player ID. */
+  players: Record<string, Player>;
+  testRunning: boolean;
+  testRunner: string;
+  /** The current stage's task, null outside task phases. Absent in a personal-tasks game, where each player gets theirs in ASSIGNED_TASK. */
+  task?: Task | null;
+  /** Only in a personal-tasks game. */
+  taskProgress?: TaskProgress;
+  ranked: boolean;
+  spectatorDelaySeconds: number;
+}
+
+/** The task bar of a personal-tasks game. The crew wins when complete reaches total. */
+export interface TaskProgress {
+  /** Crewmates who have passed their task. */
+  complete: number;
+  /** Crewmates whose task counts: those who passed it and those still in the game. */
+  total: number;
+}
+
+/** Lobby rules. In SETTINGS_UPDATED every field is filled in with the value the next game will use. */
+export interface Settings {
+  timerSeconds?: number;
+  votingSeconds?: number;
+  sabotageCooldownSec?: number;
+  /** 0 or absent picks one impostor per five players. */
+  impostorCount?: number;
+  minPlayers?: number;
+  /** Blank or absent draws from every category. Stages with no matching task draw from all of them. */
+  taskCategory?: '' | 'DSA' | 'OOPS' | 'DEBUGGING';
+  /** Blank or absent draws from every difficulty. */
+  taskDifficulty?: '' | 'EASY' | 'MEDIUM' | 'HARD';
+  /** The language tasks are written in; blank or absent means java. It counts for more than the category and difficulty, and stages with no task in it fall back to another language. */
+  taskLanguage?: '' | 'java' | 'python' | 'javascript' | 'go';
+  /** When true a player's first vote in a meeting can't be changed. */
+  lockVotes?: boolean;
+  /** When true a tied meeting votes again between the tied players; a second tie ejects no one. */
+  tieRevote?: boolean;
+  /** When true each player gets a task of their own, edited in the Yjs document <room>-task-<playerID>, and the crew wins by filling the task bar instead of finishing three stages. */
+  personalTasks?: boolean;
+  /** Deal one crewmate the DETECTIVE role, who can send one DETECTIVE_QUERY per game. */
+  detective?: boolean;
+  /** Deal the JESTER role, who wins alone by being voted out, when there are at least three non-impostors. */
+  jester?: boolean;
+  /** Eliminated players also receive IMPOSTOR_CHAT. */
+  ghostsHearImpostors?: boolean;
+  /** When true the lobby is listed by GET /api/lobbies until its game starts. */
+  public?: boolean;
+  /** When true players can send HINT during tasks. Needs the server's hint service. */
+  hints?: boolean;
+  /** Hints per stage, or per player's task in a personal-tasks game; 1 to 5, absent means 2. */
+  hintsPerStage?: number;
+  /** Seconds each hint takes off the clock; 5 to 120, absent means 20. */
+  hintPenaltySec?: number;
+  /** Seconds each player must wait between chat lines in a meeting; absent or 0 is off. Set with SLOW_MODE, not ROOM_SETTINGS. */
+  slowModeSec?: number;
+}
+
+/** A CHAT as broadcast. */
+export interface ChatLine {
+  username: string;
+  text: string;
+  system: boolean;
+  messageId?: string;
+  playerId?: string;
+  /** The translation into the reader's JOIN language, keyed by that locale code; empty when there is none. */
+  translations?: Record<string, string>;
+  /** Unix milliseconds. */
+  timestamp?: number;
+  /** Set on QUICK_CHAT lines and system notices: an i18n key to show in the reader's language in place of text, which is the English. */
+  key?: string;
+  /** Values for the key's {placeholders}, on system notices that have any. */
+  params?: Record<string, string>;
+  /** Set on QUICK_CHAT lines. */
+  presetId?: string;
+}
+
+/** One unit test of a RUN_TESTS run. */
+export interface TestResult {
+  name: string;
+  passed: boolean;
+  /** Why it failed. */
+  message?: string;
+}
+
+/** What one player did for the game, in GAME_ENDED. score is 1 per test run, 10 per stage (or personal task) their run passed, 5 per vote for an impostor and 2 per sabotage they were still aboard at the end of. */
+export interface PlayerScore {
+  playerID: string;
+  username: string;
+  testsRun: number;
+  stagesPassed: number;
+  correctVotes: number;
+  sabotagesSurvived: number;
+  score: number;
+}
+
+/** How much of the game's editing one player did, in GAME_ENDED, measured by the size of their Yjs updates. */
+export interface EditShare {
+  playerID: string;
+  username: string;
+  bytes: number;
+  /** Rounded, so the shares may not add up to exactly 100. */
+  percent: number;
+}
+
+export interface Teammate {
+  id: string;
+  username: string;
+}
+
+/** A player on the ballot of a revote. */
+export interface Candidate {
+  id: string;
+  username: string;
+}
+
+export interface Party {
+  id: string;
+  leaderId: string;
+  members: string[];
+  createdAt: string;
+}
+
+export interface WebhookEndpoint {
+  id: string;
+  url: string;
+  /** Signs deliveries; shown only to the host who added it. */
+  secret: string;
+  events?: string[];
+  createdAt: string;
+  untrusted?: boolean;
+}
+
+/** First message on a new connection. */
+export interface InitData {
+  playerID: string;
+  roomID: string;
+  isReconnect: boolean;
+  authenticated: boolean;
+  /** The connection was made with ?spectate=1 and only watches, even mid-game: everything it gets has roles stripped until GAME_ENDED, its JOIN only sets the name it chats under, and its CHAT goes to the other observers as OBSERVER_CHAT. Anything else it sends gets an ERROR. Open the editor with ?spectate=1 too for a read-only view. */
+  observer: boolean;
+  /** Lets a guest claim their matches after signing up. Guests reconnecting with ?userId= send it back as ?guestToken= to prove the ID is theirs. */
+  guestToken?: string;
+  /** The protocol version the server speaks. Clients say which they speak with ?protocol= on connect; those that don't are taken to speak 1. */
+  protocolVersion: number;
+  /** The client speaks an older protocol than the server. It can still play, but should ask the player to refresh. */
+  outdated: boolean;
+}
+
+/** Sent after JOIN to a connection that has shown the player ID is its own: signed in, given a new guest ID, or reconnecting with ?guestToken=. A player already seated with a session keeps it. Reconnect with ?userId=<playerID>&session=<token> to resume a game in progress. */
+export interface SessionData {
+  token: string;
+  playerID: string;
+  roomID: string;
+}
+
+/** Sent after SETTINGS_UPDATED on every JOIN, including reconnections: the room's latest CHATs, up to the server's CHAT_HISTORY_SIZE (50 by default). Ghost chat isn't kept. */
+export interface ChatHistoryData {
+  /** Oldest first. */
+  messages: ChatLine[];
+}
+
+/** A ghost's chat line. Only eliminated players receive these. */
+export interface GhostChatData {
+  messageId: string;
+  playerId: string;
+  username: string;
+  text: string;
+  /** Unix milliseconds. */
+  timestamp: number;
+}
+
+/** An observer's chat line. Only observers receive these; the players never see them. */
+export interface ObserverChatData {
+  messageId: string;
+  /** Spectator for an observer who hasn't sent JOIN. */
+  username: string;
+  text: string;
+  /** Unix milliseconds. */
+  timestamp: number;
+}
+
+/** An impostor's line to their team. Only living impostors receive these, and ghosts when the room's ghostsHearImpostors is set; they aren't part of CHAT_HISTORY. */
+export interface ImpostorChatData {
+  messageId: string;
+  playerId: string;
+  username: string;
+  text: string;
+  /** Unix milliseconds. */
+  timestamp: number;
+}
+
+/** During a meeting, another living player started typing. Only living players receive these. */
+export interface TypingStartData {
+  playerId: string;
+}
+
+/** A player shown as typing stopped: they sent their line, stopped, left, were muted, or weren't heard from for a few seconds. Forget everyone's typing when the phase changes; no TYPING_STOP comes then. */
+export interface TypingStopData {
+  playerId: string;
+}
+
+/** A stage was completed; the next one starts after delay. */
+export interface ChangeSceneData {
+  fromStage: number;
+  toStage: number;
+  /** Milliseconds. */
+  delay: number;
+}
+
+/** Someone is running the tests; the editor is locked until TEST_COMPLETE. An impostor's own runs are decoys only they are told about. */
+export interface TestLockedData {
+  runner: string;
+  runnerID: string;
+  stage: number;
+}
+
+/** In a personal-tasks game, the player's own task. Sent after every GAME_STATE and on rejoining. */
+export interface AssignedTaskData {
+  task: Task;
+  /** Whether the player has passed it. */
+  done: boolean;
+}
+
+/** A run finished. An impostor's decoy run always passes and changes nothing. */
+export interface TestCompleteData {
+  passed: boolean;
+  stage: number;
+  runner: string;
+  /** False when the code didn't compile; compilerOutput says why. */
+  compiled: boolean;
+  /** javac's errors, blank when it compiled. */
+  compilerOutput: string;
+  tests: TestResult[];
+  /** The run hit the sandbox's time limit. */
+  timedOut: boolean;
+}
+
+/** Progress of the running tests, between TEST_LOCKED and TEST_COMPLETE. */
+export interface TestStatusData {
+  stage: number;
+  status: 'QUEUED' | 'RUNNING';
+}
+
+export interface TestCancelledData {
+  reason: string;
+}
+
+/** Tests are already running. */
+export interface ErrorBusyData {
+  message: string;
+  runner: string;
+}
+
+/** Something the client asked for was refused, or a message it sent didn't match this schema or wasn't one the server knows. */
+export interface ErrorData {
+  message: string;
+}
+
+/** The server is going down; the connection closes after drainSeconds. The room is saved, so reconnecting with the session token carries on. */
+export interface ShutdownNoticeData {
+  message: string;
+  drainSeconds: number;
+}
+
+/** The connection is falling behind on messages and SLOW_CLIENT_POLICY is dropping or holding some. Sent at most every few seconds. Under the disconnect policy the connection is instead closed with code 1013 and reason SLOW_CONNECTION. */
+export interface SlowConnectionData {
+  policy: 'drop-oldest' | 'grow';
+  /** Messages dropped on this connection so far. */
+  dropped: number;
+  message: string;
+}
+
+/** A message was dropped for going over the connection's budget for its type. */
+export interface ErrorRateLimitedData {
+  messageType: string;
+  message: string;
+  /** Absent when waiting won't help. */
+  retryAfterMs?: number;
+}
+
+/** An EMERGENCY that didn't start a meeting. */
+export interface EmergencyUnavailableData {
+  reason: 'NOT_ALLOWED' | 'WRONG_PHASE' | 'SABOTAGE_ACTIVE' | 'STAGE_STARTING' | 'COOLDOWN' | 'LIMIT_REACHED';
+  message: string;
+  /** Meetings the player may still call this game. */
+  callsLeft: number;
+  /** How long until a call could be allowed; absent when waiting won't help. */
+  retryAfterMs?: number;
+}
+
+/** The connection was refused or ended; the server closes it after this message. */
+export interface ErrorAccessDeniedData {
+  reason: 'INVALID_TOKEN' | 'AUTH_REQUIRED' | 'BANNED' | 'GAME_IN_PROGRESS' | 'ROOM_NOT_FOUND' | 'SPECTATORS_FULL' | 'KICKED' | 'AFK' | 'IDLE' | 'ROOM_CLOSED' | 'OUTDATED_CLIENT' | 'ROOM_UNAVAILABLE' | 'PLAYER_ID_IN_USE';
+  message: string;
+  banReason?: string;
+  /** Absent for permanent bans. */
+  expiresAt?: string;
+  phase?: 'LOBBY' | 'ROLE_REVEAL' | 'TASK_1' | 'TASK_2' | 'TASK_3' | 'DISCUSSION' | 'GAME_OVER';
+}
+
+export interface VotingTimerData {
+  seconds: number;
+}
+
+/** The meeting tied and votes again, between candidates or SKIP only. Earlier ballots are cleared; VOTING_TIMER counts down from seconds. */
+export interface VoteTieData {
+  candidates: Candidate[];
+  seconds: number;
+}
+
+/** A VOTE that wasn't counted. An earlier ballot from the same player still stands. */
+export interface VoteRejectedData {
+  reason: 'VOTING_CLOSED' | 'NOT_ALLOWED' | 'UNKNOWN_TARGET' | 'TARGET_ELIMINATED' | 'SELF_VOTE' | 'VOTE_LOCKED' | 'NOT_A_CANDIDATE';
+  message: string;
+  targetID: string;
+}
+
+/** Who has voted, never for whom. */
+export interface VoteUpdateData {
+  hasVoted: Record<string, boolean>;
+}
+
+export interface AllVotesInData {
+  message: string;
+}
+
+export interface PlayerEliminatedData {
+  playerID: string;
+  username: string;
+  reason?: 'DISCONNECTED';
+}
+
+export interface GameEndedData {
+  reason: 'CIVILIAN_WIN_TASKS' | 'CIVILIAN_WIN_VOTE' | 'CIVILIAN_WIN_DISCONNECT' | 'IMPOSTER_WIN' | 'IMPOSTER_WIN_TIMEOUT' | 'IMPOSTER_WIN_SABOTAGE' | 'JESTER_WIN_VOTED_OUT' | 'ADMIN_ENDED';
+  imposterIDs: string[];
+  finalState: GameState;
+  /** Empty when the summary couldn't be saved. */
+  summaryToken: string;
+  /** Every player, best score first. */
+  scores: PlayerScore[];
+  /** The top score; left out if nobody scored. */
+  mvp?: PlayerScore;
+  /** Every player, most editing first. */
+  editShare: EditShare[];
+}
+
+/** The room is back in the lobby with the same players; a GAME_STATE follows. */
+export interface RematchStartedData {
+  hostName: string;
+}
+
+export interface NewHostAssignedData {
+  newHostID: string;
+  newHostName: string;
+  canStart: boolean;
+}
+
+export interface SabotageCooldownData {
+  remainingSeconds: number;
+}
+
+/** Until a FREEZE ends the server drops crewmates' editor updates and answers their CHAT and RUN_TESTS with an ERROR. A client resuming its seat mid-sabotage is sent one too, for what is left of it. */
+export interface SabotageStartedData {
+  type: 'FREEZE' | 'SWAP' | 'CRITICAL';
+  /** Milliseconds left. */
+  duration: number;
+}
+
+/** Sent to each of the two crewmates a SWAP sabotage picked, after SABOTAGE_STARTED. Until SABOTAGE_ENDED their editor follows the partner's cursor. */
+export interface SabotageSwapData {
+  partnerID: string;
+  partnerName: string;
+  /** Milliseconds. */
+  duration: number;
+}
+
+/** For CRITICAL, sent once the crew has repaired it; an unrepaired one ends the game instead. */
+export interface SabotageEndedData {
+  type: 'FREEZE' | 'SWAP' | 'CRITICAL';
+}
+
+/** Sent every second while a CRITICAL sabotage is on, and after each REPAIR. */
+export interface CriticalCountdownData {
+  remainingSeconds: number;
+  /** Different crewmates who have repaired so far. */
+  repairs: number;
+  repairsNeeded: number;
+}
+
+/** Sent to the detective only, in answer to their DETECTIVE_QUERY. */
+export interface DetectiveResultData {
+  targetID: string;
+  username: string;
+  /** The player has failed their test runs suspiciously often. */
+  suspicious: boolean;
+}
+
+export interface SabotageCorruptData {
+  malware: string;
+  action: 'INJECT_AT_TOP';
+}
+
+/** Sent when RUN_TESTS arrives with every CORRUPT marker taken out. Until then RUN_TESTS with a marker still in the code gets an ERROR. */
+export interface SabotageResolvedData {
+  type: 'CORRUPT';
+}
+
+export interface ModerationWarningData {
+  message: string;
+}
+
+/** A message from the server operators, sent to every room. */
+export interface AnnouncementData {
+  message: string;
+}
+
+export interface ModerationMutedData {
+  message: string;
+  until: string;
+}
+
+/** The host muted or unmuted this player. While muted, their CHAT, QUICK_CHAT, GHOST_CHAT and IMPOSTOR_CHAT get an ERROR; it lasts until the host unmutes them, across reconnects and rematches. */
+export interface HostMutedData {
+  muted: boolean;
+  message: string;
+}
+
+export interface WebhookRemovedData {
+  id: string;
+}
+
+export interface FriendAcceptedData {
+  userId: string;
+}
+
+export interface RoomInviteData {
+  fromId: string;
+  fromName: string;
+  roomId: string;
+}
+
+/** Sent to a signed-in player after a match that earned them something. */
+export interface CosmeticsUnlockedData {
+  cosmetics: Cosmetic[];
+}
+
+/** A hint for the current task, sent to the room, or in a personal-tasks game to the player who asked. A SYNC_TIMER with hintPenaltySeconds follows. */
+export interface HintData {
+  stage: number;
+  /** How specific the hint is: 1 points at where the bug is, 2 says what is wrong, 3 all but gives the fix. */
+  tier: number;
+  text: string;
+  /** Username of the player who asked. */
+  requestedBy: string;
+  /** Hints left for this task. */
+  hintsLeft: number;
+  /** What the hint took off the clock. */
+  penaltySeconds: number;
+}
+
+/** Sent only to impostors during ROLE_REVEAL, listing the whole impostor team including themselves. */
+export interface ImpostorTeamData {
+  impostors: Teammate[];
+}
+
+/** Sent to the host when anti-cheat flags a player, if the cheat_warnings feature flag is on. */
+export interface CheatWarningData {
+  detection: 'ROLE_LEAK' | 'MASS_DELETION' | 'LOCKSTEP_VOTING';
+  playerId: string;
+  username: string;
+  message: string;
+}
+
+export interface PartyChatData {
+  partyId: string;
+  messageId: string;
+  playerID: string;
+  username: string;
+  text: string;
+  /** Unix milliseconds. */
+  timestamp: number;
+}
+
+export interface PartyKickedData {
+  partyId: string;
+}
+
+/** The party leader moved the party; members should join roomId. */
+export interface PartyJoinRoomData {
+  partyId: string;
+  roomId: string;
+}
+
+/** Answers QUEUE and LEAVE_QUEUE. A queued party's members all receive it. */
+export interface QueueStatusData {
+  status: 'queued' | 'left';
+  /** Blank means any. */
+  language?: string;
+  mmr?: number;
+}
+
+/** Quick match placed the player in a new room; the client should join roomId. */
+export interface MatchFoundData {
+  roomId: string;
+  joinUrl: string;
+  /** Blank means the default. */
+  language: string;
+  players: number;
+}
+
+/** First message on a spectator connection; everything after it runs delaySeconds behind. */
+export interface SpectatorInitData {
+  roomID: string;
+  delaySeconds: number;
+}
+
+/** The username is cleaned like CHAT and must be 1-24 characters; the server's USERNAME_FILTER_ACTION may refuse one with a blocked word in it with an ERROR. A name someone else in the room already goes by, in any case, gets a number after it. */
+export interface JoinRequest {
+  username: string;
+  /** The locale code to read chat in, like de or pt-BR; absent means en. Each CHAT then carries only that translation. */
+  language?: string;
+}
+
+/** Host only, in the lobby. */
+export interface SetRankedRequest {
+  ranked: boolean;
+}
+
+/** Host only, in the lobby. */
+export interface SetSpectatorDelayRequest {
+  seconds: number;
+}
+
+export interface RunTestsRequest {
+  code: string;
+}
+
+/** Players still in the game, during a task phase of a game with hints on. Asks the hint service about the task and this code; answered with HINT, or an ERROR when hints are off, used up or already on their way, or the service doesn't answer. */
+export interface HintRequest {
+  /** The editor's contents. */
+  code: string;
+}
+
+/** Control and invisible formatting characters are dropped and runs of whitespace become one line break or space; a line longer than the server's CHAT_MAX_LENGTH (300 by default) is refused with an ERROR. Depending on the server's CHAT_FILTER_ACTION, a line with a blocked word in it has the word masked with asterisks (the default) or is refused with an ERROR. The CHAT sent back carries the server's message ID and timestamp. */
+export interface ChatRequest {
+  text: string;
+}
+
+/** Impostors still in the game only, while it is under way, in games with more than one impostor; anything else gets an ERROR. Cleaned and limited like CHAT. */
+export interface ImpostorChatRequest {
+  text: string;
+}
+
+/** A preset line from GET /api/quick-chat, sent as a CHAT with the preset's translation key and no translations. Allowed and limited like CHAT; an unknown preset gets an ERROR. */
+export interface QuickChatRequest {
+  presetId: string;
+}
+
+/** Eliminated players only; goes to the other ghosts. Cleaned and limited like CHAT. */
+export interface GhostChatRequest {
+  text: string;
+}
+
+/** Imposter only. SWAP needs two crewmates online and TIMER_DRAIN a use left; either is refused with an ERROR otherwise. */
+export interface SabotageRequest {
+  type: 'FREEZE' | 'CORRUPT' | 'SWAP' | 'TIMER_DRAIN' | 'CRITICAL';
+}
+
+/** The detective only, once per game while it is under way. Answered with DETECTIVE_RESULT, or an ERROR. */
+export interface DetectiveQueryRequest {
+  targetID: string;
+}
+
+export interface VoteRequest {
+  /** A player ID, or SKIP. */
+  targetID: string;
+}
+
+/** A player can report each other player once a day and file up to 10 reports an hour; past that the report is refused with an ERROR. */
+export interface ReportPlayerRequest {
+  targetID: string;
+  reason: string;
+  /** Cut to 500 characters. */
+  details?: string;
+}
+
+/** Host only. The player and everyone else see it through PLAYER_LIST, and the player also gets HOST_MUTED. */
+export interface MutePlayerRequest {
+  targetID: string;
+  muted: boolean;
+}
+
+/** Host only, any time. Sets slowModeSec, 0 to 60; SETTINGS_UPDATED goes to everyone. A CHAT or QUICK_CHAT sent sooner gets an ERROR. */
+export interface SlowModeRequest {
+  seconds: number;
+}
+
+/** Host only. Removes a player, who may come back. */
+export interface KickPlayerRequest {
+  targetID: string;
+}
+
+/** Host only. Removes a player and keeps them out of the room. */
+export interface BanPlayerRequest {
+  targetID: string;
+}
+
+/** Host only. */
+export interface AddWebhookRequest {
+  url: string;
+  /** Empty subscribes to every event. */
+  events?: string[];
+}
+
+/** Host only. */
+export interface RemoveWebhookRequest {
+  id: string;
+}
+
+/** Signed-in players only. Cleaned and limited like CHAT. */
+export interface PartyChatRequest {
+  text: string;
+}
+
+/** Signed-in players only. Joins the quick-match queue, replacing any earlier ticket. In a party only the leader can queue, and the whole party is queued and matched together. The ticket is dropped once the player has no connection left. */
+export interface QueueRequest {
+  /** Blank means any. */
+  language?: string;
+}
+
+/** Everything the server sends on /ws and /ws/spectate. */
+export type ServerMessage =
+  | { type: 'INIT'; data: InitData }
+  | { type: 'SESSION'; data: SessionData }
+  | { type: 'SELF'; data: Player }
+  | { type: 'PLAYER_LIST'; data: Record<string, Player> }
+  | { type: 'GAME_STATE'; data: GameState }
+  | { type: 'CHAT'; data: ChatLine }
+  | { type: 'CHAT_HISTORY'; data: ChatHistoryData }
+  | { type: 'GHOST_CHAT'; data: GhostChatData }
+  | { type: 'OBSERVER_CHAT'; data: ObserverChatData }
+  | { type: 'IMPOSTOR_CHAT'; data: ImpostorChatData }
+  | { type: 'TYPING_START'; data: TypingStartData }
+  | { type: 'TYPING_STOP'; data: TypingStopData }
+  | { type: 'SYNC_TIMER'; data: Timer }
+  | { type: 'CHANGE_SCENE'; data: ChangeSceneData }
+  | { type: 'TEST_LOCKED'; data: TestLockedData }
+  | { type: 'ASSIGNED_TASK'; data: AssignedTaskData }
+  | { type: 'TASK_PROGRESS'; data: TaskProgress }
+  | { type: 'TEST_COMPLETE'; data: TestCompleteData }
+  | { type: 'TEST_STATUS'; data: TestStatusData }
+  | { type: 'TEST_CANCELLED'; data: TestCancelledData }
+  | { type: 'ERROR_BUSY'; data: ErrorBusyData }
+  | { type: 'ERROR'; data: ErrorData }
+  | { type: 'SHUTDOWN_NOTICE'; data: ShutdownNoticeData }
+  | { type: 'SLOW_CONNECTION'; data: SlowConnectionData }
+  | { type: 'ERROR_RATE_LIMITED'; data: ErrorRateLimitedData }
+  | { type: 'EMERGENCY_UNAVAILABLE'; data: EmergencyUnavailableData }
+  | { type: 'ERROR_ACCESS_DENIED'; data: ErrorAccessDeniedData }
+  | { type: 'VOTING_TIMER'; data: VotingTimerData }
+  | { type: 'VOTE_TIE'; data: VoteTieData }
+  | { type: 'VOTE_REJECTED'; data: VoteRejectedData }
+  | { type: 'VOTE_UPDATE'; data: VoteUpdateData }
+  | { type: 'ALL_VOTES_IN'; data: AllVotesInData }
+  | { type: 'PLAYER_ELIMINATED'; data: PlayerEliminatedData }
+  | { type: 'GAME_ENDED'; data: GameEndedData }
+  | { type: 'REMATCH_STARTED'; data: RematchStartedData }
+  | { type: 'NEW_HOST_ASSIGNED'; data: NewHostAssignedData }
+  | { type: 'SABOTAGE_COOLDOWN'; data: SabotageCooldownData }
+  | { type: 'SABOTAGE_STARTED'; data: SabotageStartedData }
+  | { type: 'SABOTAGE_SWAP'; data: SabotageSwapData }
+  | { type: 'SABOTAGE_ENDED'; data: SabotageEndedData }
+  | { type: 'CRITICAL_COUNTDOWN'; data: CriticalCountdownData }
+  | { type: 'DETECTIVE_RESULT'; data: DetectiveResultData }
+  | { type: 'SABOTAGE_CORRUPT'; data: SabotageCorruptData }
+  | { type: 'SABOTAGE_RESOLVED'; data: SabotageResolvedData }
+  | { type: 'MODERATION_WARNING'; data: ModerationWarningData }
+  | { type: 'ANNOUNCEMENT'; data: AnnouncementData }
+  | { type: 'MODERATION_MUTED'; data: ModerationMutedData }
+  | { type: 'HOST_MUTED'; data: HostMutedData }
+  | { type: 'WEBHOOK_ADDED'; data: WebhookEndpoint }
+  | { type: 'WEBHOOK_REMOVED'; data: WebhookRemovedData }
+  | { type: 'FRIEND_ACCEPTED'; data: FriendAcceptedData }
+  | { type: 'ROOM_INVITE'; data: RoomInviteData }
+  | { type: 'COSMETICS_UNLOCKED'; data: CosmeticsUnlockedData }
+  | { type: 'HINT'; data: HintData }
+  | { type: 'SETTINGS_UPDATED'; data: Settings }
+  | { type: 'IMPOSTOR_TEAM'; data: ImpostorTeamData }
+  | { type: 'CHEAT_WARNING'; data: CheatWarningData }
+  | { type: 'PARTY_UPDATE'; data: Party }
+  | { type: 'PARTY_CHAT'; data: PartyChatData }
+  | { type: 'PARTY_KICKED'; data: PartyKickedData }
+  | { type: 'PARTY_JOIN_ROOM'; data: PartyJoinRoomData }
+  | { type: 'QUEUE_STATUS'; data: QueueStatusData }
+  | { type: 'MATCH_FOUND'; data: MatchFoundData }
+  | { type: 'SPECTATOR_INIT'; data: SpectatorInitData };
+
+/** Everything a client may send on /ws. */
+export type ClientMessage =
+  | { type: 'JOIN'; data: JoinRequest }
+  | { type: 'START_GAME'; data?: Record<string, never> }
+  | { type: 'REMATCH'; data?: Record<string, never> }
+  | { type: 'ADD_BOT'; data?: Record<string, never> }
+  | { type: 'SET_RANKED'; data: SetRankedRequest }
+  | { type: 'ROOM_SETTINGS'; data: Settings }
+  | { type: 'SET_SPECTATOR_DELAY'; data: SetSpectatorDelayRequest }
+  | { type: 'RUN_TESTS'; data: RunTestsRequest }
+  | { type: 'HINT'; data: HintRequest }
+  | { type: 'CHAT'; data: ChatRequest }
+  | { type: 'IMPOSTOR_CHAT'; data: ImpostorChatRequest }
+  | { type: 'QUICK_CHAT'; data: QuickChatRequest }
+  | { type: 'TYPING_START'; data?: Record<string, never> }
+  | { type: 'TYPING_STOP'; data?: Record<string, never> }
+  | { type: 'GHOST_CHAT'; data: GhostChatRequest }
+  | { type: 'SABOTAGE'; data: SabotageRequest }
+  | { type: 'REPAIR'; data?: Record<string, never> }
+  | { type: 'DETECTIVE_QUERY'; data: DetectiveQueryRequest }
+  | { type: 'EMERGENCY'; data?: Record<string, never> }
+  | { type: 'VOTE'; data: VoteRequest }
+  | { type: 'REPORT_PLAYER'; data: ReportPlayerRequest }
+  | { type: 'MUTE_PLAYER'; data: MutePlayerRequest }
+  | { type: 'SLOW_MODE'; data: SlowModeRequest }
+  | { type: 'KICK_PLAYER'; data: KickPlayerRequest }
+  | { type: 'BAN_PLAYER'; data: BanPlayerRequest }
+  | { type: 'ADD_WEBHOOK'; data: AddWebhookRequest }
+  | { type: 'REMOVE_WEBHOOK'; data: RemoveWebhookRequest }
+  | { type: 'PING'; data?: Record<string, never> }
+  | { type: 'PARTY_CHAT'; data: PartyChatRequest }
+  | { type: 'QUEUE'; data: QueueRequest }
+  | { type: 'LEAVE_QUEUE'; data?: Record<string, never> };
+
+export type ServerMessageType = ServerMessage['type'];
+export type ClientMessageType = ClientMessage['type'];
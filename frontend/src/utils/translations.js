@@ -54,6 +54,39 @@ const translations = {
     'common.you': 'You',
     'common.send': 'Send',
     'common.loading': 'Loading...',
+
+    // Quick chat
+    'quickchat.where': 'Where is everyone?',
+    'quickchat.sus': "That's sus.",
+    'quickchat.notMe': "It wasn't me!",
+    'quickchat.agree': 'Agreed.',
+    'quickchat.skip': "Let's skip this one.",
+    'quickchat.help': 'I need help with this task.',
+    'quickchat.testsPass': 'The tests pass for me.',
+    'quickchat.testsFail': 'The tests fail for me.',
+    'quickchat.fixedTask1': 'I fixed task 1.',
+    'quickchat.fixedTask2': 'I fixed task 2.',
+    'quickchat.fixedTask3': 'I fixed task 3.',
+
+    // System chat notices, with {placeholders} from the server's params
+    'system.left_lobby': '{name} left the lobby',
+    'system.new_host': '👑 {name} is now the host',
+    'system.disconnected': '⚠️ COMMUNICATION LOST: {name} has disconnected - waiting for them to reconnect',
+    'system.reconnected': '📡 {name} reconnected',
+    'system.connection_lost': '⚠️ COMMUNICATION LOST: {name} did not reconnect',
+    'system.no_elimination': 'No one was eliminated. The crew continues...',
+    'system.voted_out': '🗳️ {name} was voted out!',
+    'system.not_impostor': '{name} was not the impostor...',
+    'system.impostor_caught': '{name} was an impostor! {remaining} more still aboard...',
+    'system.freeze': '⚠️ SYSTEM JAMMED - Communications frozen!',
+    'system.freeze_ended': '✅ Systems restored - Communications online',
+    'system.corrupt': '🦠 MALWARE DETECTED - Code corrupted!',
+    'system.corrupt_cleaned': '✅ Malware purged - Code integrity restored',
+    'system.swap': "🔀 CROSSED WIRES - Two crewmates' terminals have been swapped!",
+    'system.swap_ended': '✅ Wiring repaired - Terminals back in the right hands',
+    'system.timer_drain': '⏳ POWER DRAIN - {seconds} seconds ripped off the clock!',
+    'system.meltdown': '☢️ REACTOR MELTDOWN - {repairs} crewmates must REPAIR within {seconds} seconds!',
+    'system.meltdown_averted': '✅ Reactor stabilised - Meltdown averted',
   },
   
   hi: {
@@ -110,6 +143,39 @@ const translations = {
     'common.you': 'आप',
     'common.send': 'भेजें',
     'common.loading': 'लोड हो रहा है...',
+
+    // Quick chat
+    'quickchat.where': 'सब कहाँ हैं?',
+    'quickchat.sus': 'यह संदिग्ध है।',
+    'quickchat.notMe': 'मैंने नहीं किया!',
+    'quickchat.agree': 'सहमत।',
+    'quickchat.skip': 'इस बार स्किप करते हैं।',
+    'quickchat.help': 'मुझे इस टास्क में मदद चाहिए।',
+    'quickchat.testsPass': 'मेरे लिए टेस्ट पास हो रहे हैं।',
+    'quickchat.testsFail': 'मेरे लिए टेस्ट फेल हो रहे हैं।',
+    'quickchat.fixedTask1': 'मैंने टास्क 1 ठीक कर दिया।',
+    'quickchat.fixedTask2': 'मैंने टास्क 2 ठीक कर दिया।',
+    'quickchat.fixedTask3': 'मैंने टास्क 3 ठीक कर दिया।',
+
+    // System chat notices
+    'system.left_lobby': '{name} ने लॉबी छोड़ दी',
+    'system.new_host': '👑 {name} अब होस्ट है',
+    'system.disconnected': '⚠️ संपर्क टूटा: {name} डिस्कनेक्ट हो गया - उसके लौटने का इंतज़ार है',
+    'system.reconnected': '📡 {name} फिर से जुड़ गया',
+    'system.connection_lost': '⚠️ संपर्क टूटा: {name} वापस नहीं लौटा',
+    'system.no_elimination': 'कोई बाहर नहीं हुआ। क्रू आगे बढ़ता है...',
+    'system.voted_out': '🗳️ {name} को वोट से बाहर किया गया!',
+    'system.not_impostor': '{name} धोखेबाज़ नहीं था...',
+    'system.impostor_caught': '{name} धोखेबाज़ था! {remaining} और अभी भी जहाज़ पर हैं...',
+    'system.freeze': '⚠️ सिस्टम जाम - संचार ठप!',
+    'system.freeze_ended': '✅ सिस्टम बहाल - संचार चालू',
+    'system.corrupt': '🦠 मैलवेयर मिला - कोड खराब हो गया!',
+    'system.corrupt_cleaned': '✅ मैलवेयर हटाया गया - कोड ठीक है',
+    'system.swap': '🔀 तार उलझे - दो क्रू सदस्यों के टर्मिनल बदल दिए गए!',
+    'system.swap_ended': '✅ तार ठीक हुए - टर्मिनल सही हाथों में',
+    'system.timer_drain': '⏳ पावर ड्रेन - घड़ी से {seconds} सेकंड कट गए!',
+    'system.meltdown': '☢️ रिएक्टर मेल्टडाउन - {repairs} क्रू सदस्यों को {seconds} सेकंड में REPAIR करना होगा!',
+    'system.meltdown_averted': '✅ रिएक्टर स्थिर - मेल्टडाउन टल गया',
   },
   
   de: {
@@ -166,6 +232,39 @@ const translations = {
     'common.you': 'Du',
     'common.send': 'Senden',
     'common.loading': 'Laden...',
+
+    // Quick chat
+    'quickchat.where': 'Wo sind alle?',
+    'quickchat.sus': 'Das ist verdächtig.',
+    'quickchat.notMe': 'Ich war es nicht!',
+    'quickchat.agree': 'Einverstanden.',
+    'quickchat.skip': 'Lasst uns diesmal überspringen.',
+    'quickchat.help': 'Ich brauche Hilfe bei dieser Aufgabe.',
+    'quickchat.testsPass': 'Bei mir laufen die Tests durch.',
+    'quickchat.testsFail': 'Bei mir schlagen die Tests fehl.',
+    'quickchat.fixedTask1': 'Ich habe Aufgabe 1 repariert.',
+    'quickchat.fixedTask2': 'Ich habe Aufgabe 2 repariert.',
+    'quickchat.fixedTask3': 'Ich habe Aufgabe 3 repariert.',
+
+    // System chat notices
+    'system.left_lobby': '{name} hat die Lobby verlassen',
+    'system.new_host': '👑 {name} ist jetzt der Gastgeber',
+    'system.disconnected': '⚠️ VERBINDUNG VERLOREN: {name} ist getrennt - wir warten auf die Rückkehr',
+    'system.reconnected': '📡 {name} ist wieder verbunden',
+    'system.connection_lost': '⚠️ VERBINDUNG VERLOREN: {name} ist nicht zurückgekehrt',
+    'system.no_elimination': 'Niemand wurde eliminiert. Die Crew macht weiter...',
+    'system.voted_out': '🗳️ {name} wurde rausgewählt!',
+    'system.not_impostor': '{name} war nicht der Betrüger...',
+    'system.impostor_caught': '{name} war ein Betrüger! {remaining} weitere noch an Bord...',
+    'system.freeze': '⚠️ SYSTEM BLOCKIERT - Kommunikation eingefroren!',
+    'system.freeze_ended': '✅ Systeme wiederhergestellt - Kommunikation online',
+    'system.corrupt': '🦠 MALWARE ENTDECKT - Code beschädigt!',
+    'system.corrupt_cleaned': '✅ Malware entfernt - Code-Integrität wiederhergestellt',
+    'system.swap': '🔀 VERTAUSCHTE KABEL - Die Terminals zweier Crewmitglieder wurden getauscht!',
+    'system.swap_ended': '✅ Verkabelung repariert - Terminals wieder in den richtigen Händen',
+    'system.timer_drain': '⏳ STROMAUSFALL - {seconds} Sekunden von der Uhr abgezogen!',
+    'system.meltdown': '☢️ REAKTORSCHMELZE - {repairs} Crewmitglieder müssen innerhalb von {seconds} Sekunden REPARIEREN!',
+    'system.meltdown_averted': '✅ Reaktor stabilisiert - Kernschmelze abgewendet',
   },
 };
 
@@ -177,6 +276,20 @@ export function useTranslation(language = 'en') {
   return { t };
 }
 
-export function translate(key, language = 'en') {
-  return translations[language]?.[key] || translations.en[key] || key;
+export function translate(key, language = 'en', params = {}) {
+  const text = translations[language]?.[key] || translations.en[key] || key;
+  return Object.entries(params || {}).reduce(
+    (out, [name, value]) => out.replaceAll(`{${name}}`, value),
+    text,
+  );
+}
+
+// chatText is what a chat line reads as in language: its i18n key if the
+// server sent one and it is known here, else its translation, else the
+// text as sent.
+export function chatText(message, language = 'en') {
+  if (message.key && (translations[language]?.[message.key] || translations.en[message.key])) {
+    return translate(message.key, language, message.params);
+  }
+  return message.translations?.[language] || message.text;
 }
\ No newline at end of file
@@ -7,6 +7,8 @@ const REDIS_PASSWORD = process.env.REDIS_PASSWORD || '';
 const LINGODOTDEV_API_KEY = process.env.LINGODOTDEV_API_KEY;
 const ENVIRONMENT = process.env.ENVIRONMENT || 'development';
 const PORT = process.env.PORT || 3001;
+// Must match the backend's REDIS_KEY_PREFIX: every channel is prefixed with it.
+const REDIS_KEY_PREFIX = (process.env.REDIS_KEY_PREFIX || '').replace(/^(.+?):?$/, '$1:');
 
 const TARGET_LANGUAGES = ['hi', 'de', 'fr', 'es'];
 const SOURCE_LANGUAGE = 'en';
@@ -39,10 +41,10 @@ if (ENVIRONMENT !== 'development' && !REDIS_URL.includes('localhost') && !REDIS_
 const subscriber = new Redis(redisUrl, redisOptions);
 const publisher = new Redis(redisUrl, redisOptions);
 
-const CHAT_PROCESSING_CHANNEL = 'chat:processing';
-const CHAT_TRANSLATIONS_CHANNEL = 'chat:translations';
-const TASK_TRANSLATE_CHANNEL = 'task:translate';
-const TASK_TRANSLATIONS_CHANNEL = 'task:translations';
+const CHAT_PROCESSING_CHANNEL = `${REDIS_KEY_PREFIX}chat:processing`;
+const CHAT_TRANSLATIONS_CHANNEL = `${REDIS_KEY_PREFIX}chat:translations`;
+const TASK_TRANSLATE_CHANNEL = `${REDIS_KEY_PREFIX}task:translate`;
+const TASK_TRANSLATIONS_CHANNEL = `${REDIS_KEY_PREFIX}task:translations`;
 
 const translationCache = new Map();
 const CACHE_TTL = 3600000;